@@ -0,0 +1,133 @@
+// Command archive inspects and restores event partitions exported to cold
+// storage by the retention job.
+//
+//	archive list    -table click_events -from 2024-01-01 -to 2024-02-01
+//	archive restore -table click_events -from 2024-01-01 -to 2024-01-08
+//	archive run     -older-than 2160h
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/config"
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/migrations"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/secrets"
+	"ad-tracking-system/internal/services"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	command := os.Args[1]
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	table := flags.String("table", "click_events", "event table: click_events or impression_events")
+	from := flags.String("from", "", "first day, YYYY-MM-DD (inclusive)")
+	to := flags.String("to", "", "last day, YYYY-MM-DD (exclusive)")
+	olderThan := flags.Duration("older-than", 90*24*time.Hour, "run: archive complete days older than this")
+	flags.Parse(os.Args[2:])
+
+	log := logger.SetupLogger(config.GetEnv("LOG_LEVEL", "info"))
+	if _, err := secrets.LoadEnv(30 * time.Second); err != nil {
+		log.WithError(err).Fatal("Failed to resolve secrets")
+	}
+
+	archiveURL := config.GetEnv("ARCHIVE_URL", "")
+	if archiveURL == "" {
+		log.Fatal("ARCHIVE_URL is required")
+	}
+	store, err := archive.OpenStore(archiveURL, archive.StoreConfigFromEnv())
+	if err != nil {
+		log.WithError(err).Fatal("Failed to open archive store")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg, err := config.Load("")
+	if err != nil {
+		log.WithError(err).Fatal("Invalid configuration")
+	}
+	db, err := database.ConnectConfig(ctx, cfg.Database, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to database")
+	}
+	if err := migrations.New(db, log).Check(); err != nil {
+		log.WithError(err).Fatal("Database schema check failed")
+	}
+	archiver := services.NewArchiver(repositories.NewArchiveRepository(db), store, log)
+
+	switch command {
+	case "list":
+		start, end := dayRange(*from, *to)
+		partitions, err := archiver.Partitions(*table, start, end)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to list partitions")
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(partitions)
+
+	case "restore":
+		if *from == "" || *to == "" {
+			log.Fatal("restore requires -from and -to")
+		}
+		start, end := dayRange(*from, *to)
+		restored, err := archiver.Restore(ctx, *table, start, end)
+		if err != nil {
+			log.WithError(err).WithField("restored", restored).Fatal("Restore failed")
+		}
+		fmt.Printf("restored %d rows into %s\n", restored, *table)
+
+	case "run":
+		for _, name := range services.ArchivedTables {
+			through, err := archiver.ArchiveThrough(ctx, name, time.Now().UTC().Add(-*olderThan))
+			if err != nil {
+				log.WithError(err).WithField("table", name).Fatal("Archive failed")
+			}
+			fmt.Printf("%s archived through %s\n", name, through.Format("2006-01-02"))
+		}
+
+	default:
+		usage()
+	}
+}
+
+// dayRange parses YYYY-MM-DD bounds; missing bounds mean unbounded.
+func dayRange(from, to string) (time.Time, time.Time) {
+	start := time.Time{}
+	end := time.Now().UTC().AddDate(0, 0, 1)
+	if from != "" {
+		start = mustDay(from)
+	}
+	if to != "" {
+		end = mustDay(to)
+	}
+	return start, end
+}
+
+func mustDay(value string) time.Time {
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid day %q, expected YYYY-MM-DD\n", value)
+		os.Exit(2)
+	}
+	return day
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: archive list|restore|run [-table name] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-older-than duration]")
+	os.Exit(2)
+}
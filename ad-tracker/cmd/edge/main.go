@@ -0,0 +1,132 @@
+// Command edge is the ingest-only build deployed at PoPs. It validates
+// tracking requests, buffers them on local disk and forwards signed batches
+// to Kafka or the central API. It has no database dependency.
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ad-tracking-system/internal/buildinfo"
+	"ad-tracking-system/internal/config"
+	"ad-tracking-system/internal/edge"
+	"ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/middleware"
+	"ad-tracking-system/internal/pii"
+	"ad-tracking-system/internal/sanitize"
+	"ad-tracking-system/internal/secrets"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	log := logger.SetupLogger(config.GetEnv("LOG_LEVEL", "info"))
+	if _, err := secrets.LoadEnv(30 * time.Second); err != nil {
+		log.WithError(err).Fatal("Failed to resolve secrets")
+	}
+
+	hostname, _ := os.Hostname()
+	edgeID := config.GetEnv("EDGE_ID", hostname)
+
+	signingKey := config.GetEnv("EDGE_SIGNING_KEY", "")
+	if signingKey == "" {
+		log.Fatal("EDGE_SIGNING_KEY is required")
+	}
+
+	spool, err := edge.NewSpool(
+		config.GetEnv("EDGE_SPOOL_DIR", "spool"),
+		int64(config.GetEnvInt("EDGE_SPOOL_MAX_MB", 1024))<<20,
+		config.GetEnvInt("EDGE_SEGMENT_RECORDS", 1000),
+	)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to open spool")
+	}
+
+	var forwarder edge.Forwarder
+	if centralURL := config.GetEnv("EDGE_CENTRAL_URL", ""); centralURL != "" {
+		forwarder = edge.NewHTTPForwarder(centralURL, []byte(signingKey))
+	} else {
+		forwarder = edge.NewKafkaForwarder(
+			config.GetEnv("KAFKA_BROKER", "localhost:9092"),
+			config.GetEnv("EDGE_KAFKA_TOPIC", "ad-edge-events"),
+			[]byte(signingKey),
+		)
+	}
+
+	server := edge.NewServer(spool, forwarder, edgeID, log)
+	// Addresses are minimized before they are spooled, with the central settings
+	ips, err := pii.NewIPMinimizer(config.GetEnv("IP_STORAGE_MODE", pii.IPRaw), config.GetEnv("IP_HASH_SALT", ""))
+	if err != nil {
+		log.WithError(err).Fatal("Invalid IP storage configuration")
+	}
+	server.SetIPMinimizer(ips)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go server.RunForwarder(ctx, config.GetEnvDuration("EDGE_FLUSH_INTERVAL", time.Second))
+
+	if config.GetEnv("GIN_MODE", "debug") == "release" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	r := gin.New()
+	r.Use(middleware.RecoveryMiddleware(log))
+	r.Use(middleware.RequestIDMiddleware())
+	r.Use(middleware.LoggingMiddleware(log))
+	maxBodyBytes := int64(config.GetEnvInt("MAX_BODY_BYTES", 1<<20))
+	r.Use(middleware.BodyLimitMiddleware(maxBodyBytes, maxBodyBytes))
+	r.Use(middleware.UserAgentMiddleware(config.GetEnvInt("MAX_USER_AGENT_LENGTH", sanitize.MaxUserAgentLength)))
+
+	api := r.Group("/api/v1")
+	{
+		api.POST("/ads/click", server.PostClick)
+		api.POST("/ads/impression", server.PostImpression)
+	}
+	r.GET("/health", server.Health)
+	r.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, buildinfo.Get())
+	})
+
+	port := config.GetEnv("PORT", "8080")
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: r,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Fatal("Failed to start edge server")
+		}
+	}()
+
+	log.WithFields(logrus.Fields{"port": port, "edge_id": edgeID}).Info("Edge ingest started")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down edge server...")
+
+	ctxShutdown, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelShutdown()
+
+	if err := srv.Shutdown(ctxShutdown); err != nil {
+		log.WithError(err).Error("Edge server forced to shutdown")
+	}
+
+	// Anything not yet forwarded stays on disk for the next start
+	cancel()
+	if err := spool.Close(); err != nil {
+		log.WithError(err).Error("Failed to close spool")
+	}
+	if err := forwarder.Close(); err != nil {
+		log.WithError(err).Error("Failed to close forwarder")
+	}
+
+	log.Info("Edge server exited")
+}
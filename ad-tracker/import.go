@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"ad-tracking-system/internal/config"
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/migrations"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/services"
+)
+
+// runImport implements the import subcommand, the CLI form of
+// POST /api/v1/admin/imports/catalog:
+//
+//	ad-tracker import -file advertiser.csv -dry-run
+//	ad-tracker import -file advertiser.csv
+//
+// The report is printed as JSON; the exit status is 1 when lines were
+// rejected and nothing was imported. Running servers pick up the changes
+// when their ad cache expires.
+func runImport(cfg config.Config, args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	file := flags.String("file", "", "catalog CSV to import, - for stdin; columns: account_id, campaign_name, ad_ref, ... (see readme)")
+	dryRun := flags.Bool("dry-run", false, "report what would change without writing")
+	flags.Parse(args)
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "import: -file is required")
+		flags.Usage()
+		os.Exit(2)
+	}
+	var input io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "import:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	log := logger.SetupLogger(cfg.Server.LogLevel)
+	db, err := database.ConnectConfig(context.Background(), cfg.Database, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to database")
+	}
+	if err := migrations.New(db, log).Check(); err != nil {
+		log.WithError(err).Fatal("Database schema check failed")
+	}
+
+	report, err := services.NewCatalogImporter(repositories.NewCatalogRepository(db)).Import(input, *dryRun)
+	if err != nil {
+		log.WithError(err).Fatal("Catalog import failed")
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
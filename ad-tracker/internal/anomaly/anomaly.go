@@ -0,0 +1,135 @@
+// Package anomaly finds unusual points in evenly spaced series, such as a
+// campaign's hourly clicks. Detectors are interchangeable: each looks at a
+// whole series and returns the points it considers anomalous.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Point is one bucket of a series.
+type Point struct {
+	At    time.Time
+	Value float64
+}
+
+// Finding is a point a detector considers anomalous, with the value it
+// expected there. Score is the deviation in units of the detector's noise
+// estimate: positive for spikes, negative for drops.
+type Finding struct {
+	At       time.Time
+	Value    float64
+	Expected float64
+	Score    float64
+}
+
+// Detector finds anomalies in a series ordered by time with no gaps.
+type Detector interface {
+	Name() string
+	Detect(series []Point) []Finding
+}
+
+// Names lists the detectors New knows.
+var Names = []string{"ewma", "seasonal_naive"}
+
+// New returns the named detector with its default settings.
+func New(name string) (Detector, error) {
+	switch name {
+	case "ewma":
+		return EWMA{Alpha: 0.3, Threshold: 3, Warmup: 24}, nil
+	case "seasonal_naive":
+		return SeasonalNaive{Period: 24, Threshold: 3}, nil
+	}
+	return nil, fmt.Errorf("unknown anomaly detector %q, expected one of %s", name, strings.Join(Names, ", "))
+}
+
+// countNoise is the smallest noise assumed around an expected count: the
+// Poisson standard deviation, and at least 1. It keeps flat series, whose
+// measured spread is zero, from flagging every small change.
+func countNoise(expected float64) float64 {
+	return math.Sqrt(math.Max(expected, 1))
+}
+
+// EWMA tracks an exponentially weighted mean and variance and flags points
+// more than Threshold standard deviations from the mean seen so far. Alpha
+// weighs the newest point; the first Warmup points are never flagged.
+type EWMA struct {
+	Alpha     float64
+	Threshold float64
+	Warmup    int
+}
+
+func (EWMA) Name() string { return "ewma" }
+
+func (d EWMA) Detect(series []Point) []Finding {
+	if len(series) == 0 {
+		return nil
+	}
+	var findings []Finding
+	mean, variance := series[0].Value, 0.0
+	for i := 1; i < len(series); i++ {
+		diff := series[i].Value - mean
+		sd := math.Max(math.Sqrt(variance), countNoise(mean))
+		if i >= d.Warmup && math.Abs(diff) > d.Threshold*sd {
+			findings = append(findings, Finding{At: series[i].At, Value: series[i].Value, Expected: mean, Score: diff / sd})
+		}
+		// Anomalies still update the estimate, so a lasting shift in level
+		// stops being flagged once it is the new normal
+		increment := d.Alpha * diff
+		mean += increment
+		variance = (1 - d.Alpha) * (variance + diff*increment)
+	}
+	return findings
+}
+
+// SeasonalNaive expects each point to repeat the one a Period earlier, e.g.
+// 24 for the same hour yesterday, and flags points whose difference from it
+// exceeds Threshold times the typical difference so far (the scaled median
+// absolute deviation). Points are only judged once a full period of
+// differences is known.
+type SeasonalNaive struct {
+	Period    int
+	Threshold float64
+}
+
+func (SeasonalNaive) Name() string { return "seasonal_naive" }
+
+func (d SeasonalNaive) Detect(series []Point) []Finding {
+	var findings []Finding
+	var residuals []float64
+	for i := d.Period; i < len(series); i++ {
+		expected := series[i-d.Period].Value
+		residual := series[i].Value - expected
+		if len(residuals) >= d.Period {
+			scale := math.Max(1.4826*medianAbsoluteDeviation(residuals), countNoise(expected))
+			if math.Abs(residual) > d.Threshold*scale {
+				findings = append(findings, Finding{At: series[i].At, Value: series[i].Value, Expected: expected, Score: residual / scale})
+			}
+		}
+		residuals = append(residuals, residual)
+	}
+	return findings
+}
+
+func medianAbsoluteDeviation(values []float64) float64 {
+	center := median(values)
+	deviations := make([]float64, len(values))
+	for i, v := range values {
+		deviations[i] = math.Abs(v - center)
+	}
+	return median(deviations)
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
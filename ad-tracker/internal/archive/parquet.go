@@ -0,0 +1,56 @@
+package archive
+
+import (
+	"errors"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/zstd"
+)
+
+// Writer appends rows to a zstd-compressed Parquet file.
+type Writer[T any] struct {
+	w    *parquet.GenericWriter[T]
+	rows int64
+}
+
+func NewWriter[T any](output io.Writer) *Writer[T] {
+	return &Writer[T]{w: parquet.NewGenericWriter[T](output, parquet.Compression(&zstd.Codec{}))}
+}
+
+func (w *Writer[T]) Write(rows []T) error {
+	n, err := w.w.Write(rows)
+	w.rows += int64(n)
+	return err
+}
+
+func (w *Writer[T]) Rows() int64 {
+	return w.rows
+}
+
+// Close flushes the footer; the file is unreadable until it returns.
+func (w *Writer[T]) Close() error {
+	return w.w.Close()
+}
+
+// ReadAll streams rows from a Parquet file to fn in batches.
+func ReadAll[T any](input io.ReaderAt, batchSize int, fn func([]T) error) error {
+	reader := parquet.NewGenericReader[T](input)
+	defer reader.Close()
+
+	batch := make([]T, batchSize)
+	for {
+		n, err := reader.Read(batch)
+		if n > 0 {
+			if fnErr := fn(batch[:n]); fnErr != nil {
+				return fnErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ObjectReader reads an object of known size as an io.ReadSeeker, so it can
+// be served with http.ServeContent and its Range support. Reading after a
+// seek that moved the position reopens the object there.
+type ObjectReader struct {
+	ctx    context.Context
+	store  ObjectStore
+	key    string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+	bodyAt int64
+}
+
+// OpenObject opens key, whose size the caller recorded when writing it, so
+// a missing object is reported before anything is served.
+func OpenObject(ctx context.Context, store ObjectStore, key string, size int64) (*ObjectReader, error) {
+	body, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectReader{ctx: ctx, store: store, key: key, size: size, body: body}, nil
+}
+
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body != nil && r.bodyAt != r.offset {
+		r.Close()
+	}
+	if r.body == nil {
+		body, err := r.store.GetFrom(r.ctx, r.key, r.offset)
+		if err != nil {
+			return 0, err
+		}
+		r.body, r.bodyAt = body, r.offset
+	}
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	r.bodyAt += int64(n)
+	return n, err
+}
+
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	}
+	if offset < 0 {
+		return 0, errors.New("archive: seek before start of object")
+	}
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *ObjectReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
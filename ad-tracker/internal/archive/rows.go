@@ -0,0 +1,177 @@
+// Package archive holds the cold-storage formats and object stores used to
+// move old events out of Postgres.
+package archive
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+)
+
+// ClickRow is the Parquet schema for archived click events. Times are stored
+// as Unix milliseconds so the files read cleanly in any engine.
+type ClickRow struct {
+	ID                int64   `parquet:"id"`
+	ClickID           string  `parquet:"click_id"`
+	AdID              int64   `parquet:"ad_id"`
+	TimestampMS       int64   `parquet:"timestamp_ms"`
+	ReceivedAtMS      int64   `parquet:"received_at_ms"`
+	UserID            string  `parquet:"user_id"`
+	IPAddress         string  `parquet:"ip_address"`
+	UserAgent         string  `parquet:"user_agent"`
+	VideoPlaybackTime int64   `parquet:"video_playback_time"`
+	FraudScore        float64 `parquet:"fraud_score"`
+	FraudReasons      string  `parquet:"fraud_reasons"`
+	Invalid           bool    `parquet:"invalid"`
+	ConsentState      string  `parquet:"consent_state"`
+	ConsentString     string  `parquet:"consent_string"`
+	CreativeID        *int64  `parquet:"creative_id,optional"`
+	PlacementID       *int64  `parquet:"placement_id,optional"`
+	SessionID         string  `parquet:"session_id"`
+	Processed         bool    `parquet:"processed"`
+	CreatedAtMS       int64   `parquet:"created_at_ms"`
+}
+
+func NewClickRow(click models.ClickEvent) ClickRow {
+	return ClickRow{
+		ID:                int64(click.ID),
+		ClickID:           click.ClickID,
+		AdID:              int64(click.AdID),
+		TimestampMS:       click.Timestamp.UnixMilli(),
+		ReceivedAtMS:      click.ReceivedAt.UnixMilli(),
+		UserID:            click.UserID,
+		IPAddress:         click.IPAddress,
+		UserAgent:         click.UserAgent,
+		VideoPlaybackTime: click.VideoPlaybackTime,
+		FraudScore:        click.FraudScore,
+		FraudReasons:      click.FraudReasons,
+		Invalid:           click.Invalid,
+		ConsentState:      click.ConsentState,
+		ConsentString:     click.ConsentString,
+		CreativeID:        optionalID(click.CreativeID),
+		PlacementID:       optionalID(click.PlacementID),
+		SessionID:         click.SessionID,
+		Processed:         click.Processed,
+		CreatedAtMS:       click.CreatedAt.UnixMilli(),
+	}
+}
+
+func (r ClickRow) Event() models.ClickEvent {
+	return models.ClickEvent{
+		ID:                uint(r.ID),
+		ClickID:           r.ClickID,
+		AdID:              uint(r.AdID),
+		Timestamp:         time.UnixMilli(r.TimestampMS).UTC(),
+		ReceivedAt:        receivedAt(r.ReceivedAtMS, r.CreatedAtMS),
+		UserID:            r.UserID,
+		IPAddress:         r.IPAddress,
+		UserAgent:         r.UserAgent,
+		VideoPlaybackTime: r.VideoPlaybackTime,
+		FraudScore:        r.FraudScore,
+		FraudReasons:      r.FraudReasons,
+		Invalid:           r.Invalid,
+		ConsentState:      r.ConsentState,
+		ConsentString:     r.ConsentString,
+		CreativeID:        modelID(r.CreativeID),
+		PlacementID:       modelID(r.PlacementID),
+		SessionID:         r.SessionID,
+		Processed:         r.Processed,
+		CreatedAt:         time.UnixMilli(r.CreatedAtMS).UTC(),
+	}
+}
+
+// ImpressionRow is the Parquet schema for archived impression events.
+type ImpressionRow struct {
+	ID            int64   `parquet:"id"`
+	AdID          int64   `parquet:"ad_id"`
+	TimestampMS   int64   `parquet:"timestamp_ms"`
+	ReceivedAtMS  int64   `parquet:"received_at_ms"`
+	UserID        string  `parquet:"user_id"`
+	IPAddress     string  `parquet:"ip_address"`
+	UserAgent     string  `parquet:"user_agent"`
+	TimeInViewMS  int64   `parquet:"time_in_view_ms"`
+	PercentInView float64 `parquet:"percent_in_view"`
+	FraudScore    float64 `parquet:"fraud_score"`
+	FraudReasons  string  `parquet:"fraud_reasons"`
+	Invalid       bool    `parquet:"invalid"`
+	ConsentState  string  `parquet:"consent_state"`
+	ConsentString string  `parquet:"consent_string"`
+	CreativeID    *int64  `parquet:"creative_id,optional"`
+	PlacementID   *int64  `parquet:"placement_id,optional"`
+	SessionID     string  `parquet:"session_id"`
+	CreatedAtMS   int64   `parquet:"created_at_ms"`
+}
+
+func NewImpressionRow(impression models.ImpressionEvent) ImpressionRow {
+	return ImpressionRow{
+		ID:            int64(impression.ID),
+		AdID:          int64(impression.AdID),
+		TimestampMS:   impression.Timestamp.UnixMilli(),
+		ReceivedAtMS:  impression.ReceivedAt.UnixMilli(),
+		UserID:        impression.UserID,
+		IPAddress:     impression.IPAddress,
+		UserAgent:     impression.UserAgent,
+		TimeInViewMS:  impression.TimeInViewMS,
+		PercentInView: impression.PercentInView,
+		FraudScore:    impression.FraudScore,
+		FraudReasons:  impression.FraudReasons,
+		Invalid:       impression.Invalid,
+		ConsentState:  impression.ConsentState,
+		ConsentString: impression.ConsentString,
+		CreativeID:    optionalID(impression.CreativeID),
+		PlacementID:   optionalID(impression.PlacementID),
+		SessionID:     impression.SessionID,
+		CreatedAtMS:   impression.CreatedAt.UnixMilli(),
+	}
+}
+
+func (r ImpressionRow) Event() models.ImpressionEvent {
+	return models.ImpressionEvent{
+		ID:            uint(r.ID),
+		AdID:          uint(r.AdID),
+		Timestamp:     time.UnixMilli(r.TimestampMS).UTC(),
+		ReceivedAt:    receivedAt(r.ReceivedAtMS, r.CreatedAtMS),
+		UserID:        r.UserID,
+		IPAddress:     r.IPAddress,
+		UserAgent:     r.UserAgent,
+		TimeInViewMS:  r.TimeInViewMS,
+		PercentInView: r.PercentInView,
+		FraudScore:    r.FraudScore,
+		FraudReasons:  r.FraudReasons,
+		Invalid:       r.Invalid,
+		ConsentState:  r.ConsentState,
+		ConsentString: r.ConsentString,
+		CreativeID:    modelID(r.CreativeID),
+		PlacementID:   modelID(r.PlacementID),
+		SessionID:     r.SessionID,
+		CreatedAt:     time.UnixMilli(r.CreatedAtMS).UTC(),
+	}
+}
+
+// optionalID converts a nullable model id to its Parquet form.
+func optionalID(id *uint) *int64 {
+	if id == nil {
+		return nil
+	}
+	value := int64(*id)
+	return &value
+}
+
+// modelID converts a nullable Parquet id back to its model form.
+func modelID(id *int64) *uint {
+	if id == nil {
+		return nil
+	}
+	value := uint(*id)
+	return &value
+}
+
+// receivedAt restores the receipt time. Files archived before it was kept
+// read as zero and fall back to the insert time, as the column's migration
+// did for existing rows.
+func receivedAt(receivedAtMS, createdAtMS int64) time.Time {
+	if receivedAtMS == 0 {
+		receivedAtMS = createdAtMS
+	}
+	return time.UnixMilli(receivedAtMS).UTC()
+}
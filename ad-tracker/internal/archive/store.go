@@ -0,0 +1,154 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("archive object not found")
+
+// ObjectStore is the minimal blob API the archiver and exports need.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// GetFrom reads an object from offset to its end.
+	GetFrom(ctx context.Context, key string, offset int64) (io.ReadCloser, error)
+	// Delete removes an object; a missing one is not an error.
+	Delete(ctx context.Context, key string) error
+	// Location renders a key as a URL for manifests and logs.
+	Location(key string) string
+}
+
+// StoreConfig carries credentials for S3-compatible stores.
+type StoreConfig struct {
+	Endpoint     string
+	Region       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// OpenStore opens an archive location:
+//
+//	s3://bucket/prefix   Amazon S3 (or any S3-compatible endpoint)
+//	gs://bucket/prefix   Google Cloud Storage through its S3-compatible XML API with HMAC keys
+//	file:///path         a local directory, for development
+func OpenStore(rawURL string, config StoreConfig) (ObjectStore, error) {
+	location, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive URL: %w", err)
+	}
+	prefix := strings.Trim(location.Path, "/")
+
+	switch location.Scheme {
+	case "s3":
+		if config.Region == "" {
+			config.Region = "us-east-1"
+		}
+		if config.Endpoint == "" {
+			config.Endpoint = "https://s3." + config.Region + ".amazonaws.com"
+		}
+		return NewS3Store(config, location.Host, prefix), nil
+	case "gs":
+		if config.Region == "" {
+			config.Region = "auto"
+		}
+		if config.Endpoint == "" {
+			config.Endpoint = "https://storage.googleapis.com"
+		}
+		return NewS3Store(config, location.Host, prefix), nil
+	case "file":
+		return NewDirStore(filepath.Join(location.Host, location.Path)), nil
+	default:
+		return nil, fmt.Errorf("unsupported archive scheme %q", location.Scheme)
+	}
+}
+
+// DirStore keeps archive objects on the local filesystem.
+type DirStore struct {
+	root string
+}
+
+func NewDirStore(root string) *DirStore {
+	return &DirStore{root: root}
+}
+
+func (d *DirStore) Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error {
+	path := filepath.Join(d.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (d *DirStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return d.GetFrom(ctx, key, 0)
+}
+
+func (d *DirStore) GetFrom(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(d.root, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+func (d *DirStore) Delete(ctx context.Context, key string) error {
+	err := os.Remove(filepath.Join(d.root, filepath.FromSlash(key)))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (d *DirStore) Location(key string) string {
+	return "file://" + filepath.ToSlash(filepath.Join(d.root, key))
+}
+
+// StoreConfigFromEnv reads S3-compatible credentials, preferring the
+// ARCHIVE_* variables and falling back to the standard AWS ones.
+func StoreConfigFromEnv() StoreConfig {
+	return StoreConfig{
+		Endpoint:     os.Getenv("ARCHIVE_ENDPOINT"),
+		Region:       firstEnv("ARCHIVE_REGION", "AWS_REGION"),
+		AccessKey:    firstEnv("ARCHIVE_ACCESS_KEY", "AWS_ACCESS_KEY_ID"),
+		SecretKey:    firstEnv("ARCHIVE_SECRET_KEY", "AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
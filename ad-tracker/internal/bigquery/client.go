@@ -0,0 +1,270 @@
+// Package bigquery streams events into BigQuery over its REST API, so no
+// Google SDK is needed. Rows are written with tabledata.insertAll; the
+// Storage Write API only speaks gRPC, which this service does not carry.
+package bigquery
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultEndpoint = "https://bigquery.googleapis.com/bigquery/v2"
+
+	scope            = "https://www.googleapis.com/auth/bigquery"
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// TokenSource supplies OAuth access tokens for API calls.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// Client calls the BigQuery API for one project.
+type Client struct {
+	http     *http.Client
+	endpoint string
+	project  string
+	tokens   TokenSource
+}
+
+// NewClient talks to endpoint (DefaultEndpoint, or an emulator). A nil
+// TokenSource sends requests unauthenticated, which only emulators accept.
+func NewClient(endpoint, project string, tokens TokenSource) *Client {
+	return &Client{
+		http:     &http.Client{Timeout: time.Minute},
+		endpoint: strings.TrimRight(endpoint, "/"),
+		project:  project,
+		tokens:   tokens,
+	}
+}
+
+// APIError is an error response from BigQuery.
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bigquery: %d: %s", e.Code, e.Message)
+}
+
+func isNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// do sends body as JSON to the project-relative path and decodes the
+// response into dst when it is not nil.
+func (c *Client) do(ctx context.Context, method, path string, body, dst interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint+"/projects/"+url.PathEscape(c.project)+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.tokens != nil {
+		token, err := c.tokens.Token(ctx)
+		if err != nil {
+			return fmt.Errorf("bigquery: token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(detail, &failure) != nil || failure.Error.Message == "" {
+			failure.Error.Message = strings.TrimSpace(string(detail))
+		}
+		return &APIError{Code: resp.StatusCode, Message: failure.Error.Message}
+	}
+	if dst == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(dst)
+}
+
+// cachedToken reuses a token until a minute before it expires.
+type cachedToken struct {
+	fetch func(ctx context.Context) (string, time.Duration, error)
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (t *cachedToken) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Now().Before(t.expires) {
+		return t.token, nil
+	}
+	token, lifetime, err := t.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token, t.expires = token, time.Now().Add(lifetime-time.Minute)
+	return token, nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func decodeToken(resp *http.Response) (string, time.Duration, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", 0, fmt.Errorf("token endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", 0, err
+	}
+	return token.AccessToken, time.Duration(token.ExpiresIn) * time.Second, nil
+}
+
+// MetadataTokens gets tokens for the instance's service account from the
+// GCE metadata server, as on GKE with workload identity.
+func MetadataTokens() TokenSource {
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &cachedToken{fetch: func(ctx context.Context) (string, time.Duration, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL, nil)
+		if err != nil {
+			return "", 0, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", 0, err
+		}
+		return decodeToken(resp)
+	}}
+}
+
+// ServiceAccountTokens exchanges signed JWTs for tokens using a service
+// account key file as downloaded from the console.
+func ServiceAccountTokens(path string) (TokenSource, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var account struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(raw, &account); err != nil {
+		return nil, fmt.Errorf("parse service account key: %w", err)
+	}
+	key, err := parsePrivateKey(account.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &cachedToken{fetch: func(ctx context.Context) (string, time.Duration, error) {
+		assertion, err := signJWT(key, map[string]interface{}{
+			"iss":   account.ClientEmail,
+			"scope": scope,
+			"aud":   account.TokenURI,
+			"iat":   time.Now().Unix(),
+			"exp":   time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			return "", 0, err
+		}
+		form := url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, account.TokenURI, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", 0, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", 0, err
+		}
+		return decodeToken(resp)
+	}}, nil
+}
+
+func parsePrivateKey(encoded string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil {
+		return nil, errors.New("service account key has no PEM private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse service account key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account key is not an RSA key")
+	}
+	return key, nil
+}
+
+func signJWT(key *rsa.PrivateKey, claims map[string]interface{}) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
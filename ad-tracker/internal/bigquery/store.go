@@ -0,0 +1,375 @@
+package bigquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+)
+
+type field struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Mode string `json:"mode,omitempty"`
+}
+
+type tableSchema struct {
+	name   string
+	fields []field
+	// partition is the TIMESTAMP column tables are day-partitioned on.
+	partition string
+	cluster   []string
+}
+
+// Like the ClickHouse mirror, tables keep only the columns analytics
+// needs: user ids, IPs, user agents and consent strings stay in the
+// primary database, so privacy erasure has nothing to remove here. New
+// columns are appended to the list; EnsureSchema adds them to existing
+// tables but never changes or drops columns.
+var schema = []tableSchema{
+	{
+		name: "click_events",
+		fields: []field{
+			{"click_id", "STRING", "REQUIRED"},
+			{"ad_id", "INTEGER", "REQUIRED"},
+			{"timestamp", "TIMESTAMP", "REQUIRED"},
+			{"video_playback_time", "INTEGER", ""},
+			{"creative_id", "INTEGER", ""},
+			{"placement_id", "INTEGER", ""},
+			{"session_id", "STRING", ""},
+			{"fraud_score", "FLOAT", ""},
+			{"invalid", "BOOLEAN", ""},
+			{"consent_state", "STRING", ""},
+		},
+		partition: "timestamp",
+		cluster:   []string{"ad_id"},
+	},
+	{
+		name: "impression_events",
+		fields: []field{
+			{"id", "INTEGER", "REQUIRED"},
+			{"ad_id", "INTEGER", "REQUIRED"},
+			{"timestamp", "TIMESTAMP", "REQUIRED"},
+			{"time_in_view_ms", "INTEGER", ""},
+			{"percent_in_view", "FLOAT", ""},
+			{"creative_id", "INTEGER", ""},
+			{"placement_id", "INTEGER", ""},
+			{"session_id", "STRING", ""},
+			{"fraud_score", "FLOAT", ""},
+			{"invalid", "BOOLEAN", ""},
+			{"consent_state", "STRING", ""},
+		},
+		partition: "timestamp",
+		cluster:   []string{"ad_id"},
+	},
+	{
+		name: deadLetterTable,
+		fields: []field{
+			{"table_name", "STRING", "REQUIRED"},
+			{"insert_id", "STRING", ""},
+			{"row", "STRING", ""},
+			{"error", "STRING", ""},
+			{"failed_at", "TIMESTAMP", "REQUIRED"},
+		},
+		partition: "failed_at",
+	},
+}
+
+// deadLetterTable keeps rows BigQuery rejected, as JSON with the reason,
+// so one bad row does not stall the sink.
+const deadLetterTable = "dead_letters"
+
+// insertBatch stays under the insertAll request size limits.
+const insertBatch = 500
+
+// Store writes events to one dataset. It implements events.Sink.
+type Store struct {
+	client   *Client
+	dataset  string
+	location string
+}
+
+var _ events.Sink = (*Store)(nil)
+
+// NewStore writes to dataset, created in location (e.g. "EU") when missing;
+// an empty location uses the BigQuery default.
+func NewStore(client *Client, dataset, location string) *Store {
+	return &Store{client: client, dataset: dataset, location: location}
+}
+
+func (s *Store) datasetPath() string {
+	return "/datasets/" + url.PathEscape(s.dataset)
+}
+
+// EnsureSchema creates the dataset and tables if they do not exist and
+// adds columns missing from existing tables.
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	err := s.client.do(ctx, http.MethodGet, s.datasetPath(), nil, nil)
+	if isNotFound(err) {
+		dataset := map[string]interface{}{
+			"datasetReference": map[string]string{"projectId": s.client.project, "datasetId": s.dataset},
+		}
+		if s.location != "" {
+			dataset["location"] = s.location
+		}
+		err = s.client.do(ctx, http.MethodPost, "/datasets", dataset, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, table := range schema {
+		if err := s.ensureTable(ctx, table); err != nil {
+			return fmt.Errorf("table %s: %w", table.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ensureTable(ctx context.Context, table tableSchema) error {
+	path := s.datasetPath() + "/tables/" + url.PathEscape(table.name)
+
+	var existing struct {
+		Schema struct {
+			Fields []field `json:"fields"`
+		} `json:"schema"`
+	}
+	err := s.client.do(ctx, http.MethodGet, path, nil, &existing)
+	if isNotFound(err) {
+		definition := map[string]interface{}{
+			"tableReference":   map[string]string{"projectId": s.client.project, "datasetId": s.dataset, "tableId": table.name},
+			"schema":           map[string]interface{}{"fields": table.fields},
+			"timePartitioning": map[string]string{"type": "DAY", "field": table.partition},
+		}
+		if len(table.cluster) > 0 {
+			definition["clustering"] = map[string]interface{}{"fields": table.cluster}
+		}
+		return s.client.do(ctx, http.MethodPost, s.datasetPath()+"/tables", definition, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	have := make(map[string]bool, len(existing.Schema.Fields))
+	for _, column := range existing.Schema.Fields {
+		have[column.Name] = true
+	}
+	fields := existing.Schema.Fields
+	for _, column := range table.fields {
+		if !have[column.Name] {
+			// Columns added to a table with rows must be nullable
+			column.Mode = "NULLABLE"
+			fields = append(fields, column)
+		}
+	}
+	if len(fields) == len(existing.Schema.Fields) {
+		return nil
+	}
+	return s.client.do(ctx, http.MethodPatch, path, map[string]interface{}{"schema": map[string]interface{}{"fields": fields}}, nil)
+}
+
+type clickRow struct {
+	ClickID           string  `json:"click_id"`
+	AdID              uint    `json:"ad_id"`
+	Timestamp         string  `json:"timestamp"`
+	VideoPlaybackTime int64   `json:"video_playback_time"`
+	CreativeID        *uint   `json:"creative_id"`
+	PlacementID       *uint   `json:"placement_id"`
+	SessionID         string  `json:"session_id"`
+	FraudScore        float64 `json:"fraud_score"`
+	Invalid           bool    `json:"invalid"`
+	ConsentState      string  `json:"consent_state"`
+}
+
+type impressionRow struct {
+	ID            uint    `json:"id"`
+	AdID          uint    `json:"ad_id"`
+	Timestamp     string  `json:"timestamp"`
+	TimeInViewMS  int64   `json:"time_in_view_ms"`
+	PercentInView float64 `json:"percent_in_view"`
+	CreativeID    *uint   `json:"creative_id"`
+	PlacementID   *uint   `json:"placement_id"`
+	SessionID     string  `json:"session_id"`
+	FraudScore    float64 `json:"fraud_score"`
+	Invalid       bool    `json:"invalid"`
+	ConsentState  string  `json:"consent_state"`
+}
+
+type insertRow struct {
+	InsertID string      `json:"insertId"`
+	JSON     interface{} `json:"json"`
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// SaveClicks streams clicks, deduplicated by click id within BigQuery's
+// best-effort window.
+func (s *Store) SaveClicks(ctx context.Context, clicks []models.ClickEvent) error {
+	rows := make([]insertRow, len(clicks))
+	for i, click := range clicks {
+		rows[i] = insertRow{InsertID: click.ClickID, JSON: clickRow{
+			ClickID:           click.ClickID,
+			AdID:              click.AdID,
+			Timestamp:         formatTime(click.Timestamp),
+			VideoPlaybackTime: click.VideoPlaybackTime,
+			CreativeID:        click.CreativeID,
+			PlacementID:       click.PlacementID,
+			SessionID:         click.SessionID,
+			FraudScore:        click.FraudScore,
+			Invalid:           click.Invalid,
+			ConsentState:      click.ConsentState,
+		}}
+	}
+	return s.insert(ctx, "click_events", rows)
+}
+
+func (s *Store) SaveImpressions(ctx context.Context, impressions []models.ImpressionEvent) error {
+	rows := make([]insertRow, len(impressions))
+	for i, impression := range impressions {
+		rows[i] = insertRow{InsertID: strconv.FormatUint(uint64(impression.ID), 10), JSON: impressionRow{
+			ID:            impression.ID,
+			AdID:          impression.AdID,
+			Timestamp:     formatTime(impression.Timestamp),
+			TimeInViewMS:  impression.TimeInViewMS,
+			PercentInView: impression.PercentInView,
+			CreativeID:    impression.CreativeID,
+			PlacementID:   impression.PlacementID,
+			SessionID:     impression.SessionID,
+			FraudScore:    impression.FraudScore,
+			Invalid:       impression.Invalid,
+			ConsentState:  impression.ConsentState,
+		}}
+	}
+	return s.insert(ctx, "impression_events", rows)
+}
+
+// insert streams rows in batches. Rows BigQuery rejects are written to
+// the dead letter table; an error means a whole batch may be missing and
+// should be retried.
+func (s *Store) insert(ctx context.Context, table string, rows []insertRow) error {
+	for start := 0; start < len(rows); start += insertBatch {
+		batch := rows[start:min(start+insertBatch, len(rows))]
+		rejected, err := s.insertAll(ctx, table, batch, true)
+		if err != nil {
+			return err
+		}
+		if len(rejected) > 0 {
+			if err := s.deadLetter(ctx, table, batch, rejected); err != nil {
+				return fmt.Errorf("dead letter: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// insertAll returns the reason for each rejected row by index. With
+// skipInvalid the other rows are stored; without it they are not.
+func (s *Store) insertAll(ctx context.Context, table string, rows []insertRow, skipInvalid bool) (map[int]string, error) {
+	var response struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Reason   string `json:"reason"`
+				Location string `json:"location"`
+				Message  string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}
+	body := map[string]interface{}{"rows": rows, "skipInvalidRows": skipInvalid}
+	path := s.datasetPath() + "/tables/" + url.PathEscape(table) + "/insertAll"
+	if err := s.client.do(ctx, http.MethodPost, path, body, &response); err != nil {
+		return nil, err
+	}
+
+	rejected := make(map[int]string, len(response.InsertErrors))
+	for _, insertError := range response.InsertErrors {
+		reasons := make([]string, 0, len(insertError.Errors))
+		for _, e := range insertError.Errors {
+			reason := e.Reason + ": " + e.Message
+			if e.Location != "" {
+				reason = e.Location + ": " + reason
+			}
+			reasons = append(reasons, reason)
+		}
+		rejected[insertError.Index] = strings.Join(reasons, "; ")
+	}
+	return rejected, nil
+}
+
+func (s *Store) deadLetter(ctx context.Context, table string, batch []insertRow, rejected map[int]string) error {
+	now := formatTime(time.Now())
+	rows := make([]insertRow, 0, len(rejected))
+	for index, reason := range rejected {
+		if index < 0 || index >= len(batch) {
+			continue
+		}
+		raw, err := json.Marshal(batch[index].JSON)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, insertRow{
+			InsertID: table + ":" + batch[index].InsertID,
+			JSON: map[string]interface{}{
+				"table_name": table,
+				"insert_id":  batch[index].InsertID,
+				"row":        string(raw),
+				"error":      reason,
+				"failed_at":  now,
+			},
+		})
+	}
+
+	failed, err := s.insertAll(ctx, deadLetterTable, rows, false)
+	if err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return errors.New("dead letter rows rejected")
+	}
+	metrics.SinkRows.WithLabelValues("bigquery", table, "dead_lettered").Add(float64(len(rows)))
+	return nil
+}
+
+// MaxImpressionID is the sink's resume point for impressions. Streamed
+// rows are visible to queries right away.
+func (s *Store) MaxImpressionID(ctx context.Context) (uint, error) {
+	query := map[string]interface{}{
+		"query":        fmt.Sprintf("SELECT MAX(id) FROM `%s.%s.impression_events`", s.client.project, s.dataset),
+		"useLegacySql": false,
+		"timeoutMs":    30000,
+	}
+	if s.location != "" {
+		query["location"] = s.location
+	}
+
+	var response struct {
+		JobComplete bool `json:"jobComplete"`
+		Rows        []struct {
+			F []struct {
+				V *string `json:"v"`
+			} `json:"f"`
+		} `json:"rows"`
+	}
+	if err := s.client.do(ctx, http.MethodPost, "/queries", query, &response); err != nil {
+		return 0, err
+	}
+	if !response.JobComplete {
+		return 0, errors.New("bigquery: max impression id query did not finish in time")
+	}
+	if len(response.Rows) == 0 || len(response.Rows[0].F) == 0 || response.Rows[0].F[0].V == nil {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(*response.Rows[0].F[0].V, 10, 64)
+	return uint(id), err
+}
@@ -0,0 +1,168 @@
+// Package breaker implements circuit breakers for the database and the
+// event bus. After a run of consecutive failures the breaker opens and
+// rejects calls immediately; once the open timeout passes it lets a few
+// probe calls through (half-open) and closes again if they all succeed.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+)
+
+// ErrOpen is returned instead of calling a dependency whose breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	Closed State = iota
+	HalfOpen
+	Open
+)
+
+func (s State) String() string {
+	switch s {
+	case HalfOpen:
+		return "half_open"
+	case Open:
+		return "open"
+	}
+	return "closed"
+}
+
+// Settings tune a breaker. Zero values take the defaults.
+type Settings struct {
+	// Failures is the number of consecutive failures that opens the breaker.
+	Failures int
+	// OpenTimeout is how long the breaker stays open before probing.
+	OpenTimeout time.Duration
+	// Probes is the number of calls let through while half-open; all must
+	// succeed to close the breaker.
+	Probes int
+}
+
+var DefaultSettings = Settings{Failures: 5, OpenTimeout: 10 * time.Second, Probes: 1}
+
+type Breaker struct {
+	name     string
+	settings Settings
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	probing   int
+	succeeded int
+}
+
+func New(name string, settings Settings) *Breaker {
+	if settings.Failures <= 0 {
+		settings.Failures = DefaultSettings.Failures
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = DefaultSettings.OpenTimeout
+	}
+	if settings.Probes <= 0 {
+		settings.Probes = DefaultSettings.Probes
+	}
+	b := &Breaker{name: name, settings: settings}
+	metrics.CircuitBreakerState.WithLabelValues(name).Set(float64(Closed))
+	return b
+}
+
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State reports the current state, moving an expired open breaker to
+// half-open.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expire()
+	return b.state
+}
+
+// Allow asks to make one call. On success the caller must report the
+// outcome through done; when the breaker rejects the call it returns ErrOpen.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.expire()
+	switch b.state {
+	case Open:
+		metrics.CircuitBreakerRejections.WithLabelValues(b.name).Inc()
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probing >= b.settings.Probes {
+			metrics.CircuitBreakerRejections.WithLabelValues(b.name).Inc()
+			return nil, ErrOpen
+		}
+		b.probing++
+	}
+
+	var once sync.Once
+	return func(success bool) {
+		once.Do(func() { b.record(success) })
+	}, nil
+}
+
+// Execute runs fn through the breaker. Every error counts as a failure.
+func (b *Breaker) Execute(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	done(err == nil)
+	return err
+}
+
+func (b *Breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.settings.Failures {
+			b.setState(Open)
+		}
+	case HalfOpen:
+		if !success {
+			b.setState(Open)
+			return
+		}
+		b.succeeded++
+		if b.succeeded >= b.settings.Probes {
+			b.setState(Closed)
+		}
+	}
+	// Outcomes of calls started before the breaker opened are ignored
+}
+
+// expire moves an open breaker to half-open once its timeout has passed.
+// Callers hold mu.
+func (b *Breaker) expire() {
+	if b.state == Open && time.Since(b.openedAt) >= b.settings.OpenTimeout {
+		b.setState(HalfOpen)
+	}
+}
+
+func (b *Breaker) setState(state State) {
+	b.state = state
+	b.failures, b.probing, b.succeeded = 0, 0, 0
+	if state == Open {
+		b.openedAt = time.Now()
+	}
+	metrics.CircuitBreakerState.WithLabelValues(b.name).Set(float64(state))
+	metrics.CircuitBreakerTransitions.WithLabelValues(b.name, state.String()).Inc()
+}
@@ -0,0 +1,76 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+
+	"ad-tracking-system/internal/events"
+
+	"gorm.io/gorm"
+)
+
+const gormDoneKey = "breaker:done"
+
+// GuardWrites registers GORM callbacks that route every create, update,
+// delete and raw statement through b. Reads are left alone so dashboards
+// keep working while writes are failing fast. Record-not-found does not
+// count as a failure.
+func GuardWrites(db *gorm.DB, b *Breaker) error {
+	before := func(tx *gorm.DB) {
+		done, err := b.Allow()
+		if err != nil {
+			tx.AddError(err)
+			return
+		}
+		tx.InstanceSet(gormDoneKey, done)
+	}
+	after := func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(gormDoneKey)
+		if !ok {
+			return
+		}
+		done := value.(func(bool))
+		done(tx.Error == nil || errors.Is(tx.Error, gorm.ErrRecordNotFound))
+	}
+
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("breaker:before_create", before); err != nil {
+		return err
+	}
+	if err := callbacks.Create().After("gorm:create").Register("breaker:after_create", after); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("breaker:before_update", before); err != nil {
+		return err
+	}
+	if err := callbacks.Update().After("gorm:update").Register("breaker:after_update", after); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("breaker:before_delete", before); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().After("gorm:delete").Register("breaker:after_delete", after); err != nil {
+		return err
+	}
+	if err := callbacks.Raw().Before("gorm:raw").Register("breaker:before_raw", before); err != nil {
+		return err
+	}
+	return callbacks.Raw().After("gorm:raw").Register("breaker:after_raw", after)
+}
+
+// EventBus publishes through a breaker so a struggling broker fails fast
+// instead of tying up a goroutine per event until the write times out.
+type EventBus struct {
+	events.EventBus
+	breaker *Breaker
+}
+
+func WrapEventBus(bus events.EventBus, b *Breaker) *EventBus {
+	return &EventBus{EventBus: bus, breaker: b}
+}
+
+func (e *EventBus) Publish(ctx context.Context, key, value []byte) error {
+	return e.breaker.Execute(func() error {
+		return e.EventBus.Publish(ctx, key, value)
+	})
+}
@@ -0,0 +1,60 @@
+// Package buildinfo describes the running binary. Release builds set the
+// variables with ldflags:
+//
+//	go build -ldflags "-X ad-tracking-system/internal/buildinfo.Version=v1.4.0 \
+//	  -X ad-tracking-system/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X ad-tracking-system/internal/buildinfo.BuildTime=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Without them the commit and time come from the VCS stamp go build embeds.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"ad-tracking-system/internal/metrics"
+)
+
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info is the JSON shape served at /version.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information, filling gaps from the embedded VCS
+// stamp.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime, GoVersion: runtime.Version()}
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = "unknown"
+	}
+	return info
+}
+
+// Register sets the build_info gauge, which is always 1 and carries the
+// build as labels.
+func Register() {
+	info := Get()
+	metrics.BuildInfo.WithLabelValues(info.Version, info.Commit, info.BuildTime, info.GoVersion).Set(1)
+}
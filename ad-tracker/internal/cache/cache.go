@@ -0,0 +1,119 @@
+// Package cache provides a small TTL key/value cache backed by Redis, or by
+// process memory when Redis is not configured.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Cache stores opaque values with a time to live. Get reports a miss with
+// ok=false and a nil error.
+type Cache interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Counter keeps running numeric totals, such as campaign spend, that
+// several requests add to at once.
+type Counter interface {
+	// AddFloat adds delta to key, starting from initial when the key is
+	// missing, and returns the new total. The key expires ttl after the
+	// last addition.
+	AddFloat(ctx context.Context, key string, initial, delta float64, ttl time.Duration) (float64, error)
+}
+
+// GetJSON decodes a cached JSON value into dst.
+func GetJSON(ctx context.Context, c Cache, key string, dst interface{}) (bool, error) {
+	raw, ok, err := c.Get(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	return true, json.Unmarshal(raw, dst)
+}
+
+// SetJSON stores value encoded as JSON.
+func SetJSON(ctx context.Context, c Cache, key string, value interface{}, ttl time.Duration) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return c.Set(ctx, key, raw, ttl)
+}
+
+type memoryEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// Memory is an in-process Cache. Entries are only shared within one
+// replica, so invalidation does not reach other pods.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry)}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop expired entries now and then so unused keys don't pile up
+	if len(m.entries) > 10000 {
+		now := time.Now()
+		for k, entry := range m.entries {
+			if now.After(entry.expires) {
+				delete(m.entries, k)
+			}
+		}
+	}
+	m.entries[key] = memoryEntry{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *Memory) AddFloat(ctx context.Context, key string, initial, delta float64, ttl time.Duration) (float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	total := initial
+	if entry, ok := m.entries[key]; ok && time.Now().Before(entry.expires) {
+		if current, err := strconv.ParseFloat(string(entry.value), 64); err == nil {
+			total = current
+		}
+	}
+	total += delta
+	m.entries[key] = memoryEntry{value: []byte(strconv.FormatFloat(total, 'f', -1, 64)), expires: time.Now().Add(ttl)}
+	return total, nil
+}
+
+func (m *Memory) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, key := range keys {
+		delete(m.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Cache shared by every replica. Keys are namespaced with prefix.
+type Redis struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedis connects to a redis:// or rediss:// URL.
+func NewRedis(url, prefix string) (*Redis, error) {
+	options, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	return &Redis{client: redis.NewClient(options), prefix: prefix}, nil
+}
+
+// Client exposes the connection for features that need more than get/set.
+func (r *Redis) Client() *redis.Client {
+	return r.client
+}
+
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+// addFloat seeds a missing key before adding, so a flushed Redis restarts
+// from the caller's last known total instead of zero.
+var addFloat = redis.NewScript(`
+redis.call("SET", KEYS[1], ARGV[1], "NX")
+local total = redis.call("INCRBYFLOAT", KEYS[1], ARGV[2])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return total
+`)
+
+func (r *Redis) AddFloat(ctx context.Context, key string, initial, delta float64, ttl time.Duration) (float64, error) {
+	return addFloat.Run(ctx, r.client, []string{r.prefix + key}, initial, delta, ttl.Milliseconds()).Float64()
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefix + key
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
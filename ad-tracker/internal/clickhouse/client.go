@@ -0,0 +1,140 @@
+// Package clickhouse mirrors events into ClickHouse and answers analytics
+// queries from it. It speaks the HTTP interface, so no native driver is
+// needed.
+package clickhouse
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client runs queries against one ClickHouse database.
+type Client struct {
+	http     *http.Client
+	endpoint string
+	database string
+	user     string
+	password string
+}
+
+// NewClient parses http(s)://user:password@host:8123/database. The database
+// defaults to "default".
+func NewClient(rawURL string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("clickhouse URL must be http or https, got %q", u.Scheme)
+	}
+
+	client := &Client{
+		http:     &http.Client{Timeout: time.Minute},
+		endpoint: u.Scheme + "://" + u.Host + "/",
+		database: strings.Trim(u.Path, "/"),
+		user:     u.User.Username(),
+	}
+	client.password, _ = u.User.Password()
+	if client.database == "" {
+		client.database = "default"
+	}
+	return client, nil
+}
+
+// Exec runs a statement that returns no rows. params bind {name:Type}
+// placeholders in the query.
+func (c *Client) Exec(ctx context.Context, query string, params map[string]string) error {
+	resp, err := c.do(ctx, nil, params, strings.NewReader(query))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// Query runs a SELECT and decodes its rows into dst, a pointer to a slice of
+// structs with json tags matching the column names.
+func (c *Client) Query(ctx context.Context, query string, params map[string]string, dst interface{}) error {
+	resp, err := c.do(ctx, nil, params, strings.NewReader(query+" FORMAT JSON"))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode clickhouse response: %w", err)
+	}
+	return json.Unmarshal(result.Data, dst)
+}
+
+// Insert writes rows, a slice of structs with json tags, to table.
+func (c *Client) Insert(ctx context.Context, table string, rows interface{}) error {
+	body, err := jsonEachRow(rows)
+	if err != nil {
+		return err
+	}
+	query := url.Values{"query": {"INSERT INTO " + table + " FORMAT JSONEachRow"}}
+	resp, err := c.do(ctx, query, nil, body)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+func (c *Client) do(ctx context.Context, query url.Values, params map[string]string, body io.Reader) (*http.Response, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("database", c.database)
+	// Counts come back as numbers rather than quoted strings
+	query.Set("output_format_json_quote_64bit_integers", "0")
+	for name, value := range params {
+		query.Set("param_"+name, value)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"?"+query.Encode(), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.user != "" {
+		req.Header.Set("X-ClickHouse-User", c.user)
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("clickhouse: %s: %s", resp.Status, strings.TrimSpace(string(detail)))
+	}
+	return resp, nil
+}
+
+func jsonEachRow(rows interface{}) (io.Reader, error) {
+	var list []json.RawMessage
+	raw, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, err
+	}
+
+	var body strings.Builder
+	for _, row := range list {
+		body.Write(row)
+		body.WriteByte('\n')
+	}
+	return strings.NewReader(body.String()), nil
+}
@@ -0,0 +1,345 @@
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/models"
+)
+
+// The mirror keeps only the columns analytics reads. User ids, IPs, user
+// agents and consent strings stay in Postgres, so privacy erasure there
+// needs no counterpart here. ReplacingMergeTree collapses rows delivered
+// twice by the at-least-once sink.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS click_events (
+		click_id String,
+		ad_id UInt32,
+		timestamp DateTime64(3, 'UTC'),
+		video_playback_time Int64,
+		fraud_score Float64,
+		invalid Bool,
+		consent_state LowCardinality(String),
+		session_id String
+	) ENGINE = ReplacingMergeTree
+	PARTITION BY toYYYYMM(timestamp)
+	ORDER BY (ad_id, timestamp, click_id)`,
+	`CREATE TABLE IF NOT EXISTS impression_events (
+		id UInt64,
+		ad_id UInt32,
+		timestamp DateTime64(3, 'UTC'),
+		time_in_view_ms Int64,
+		percent_in_view Float64,
+		fraud_score Float64,
+		invalid Bool,
+		consent_state LowCardinality(String),
+		session_id String
+	) ENGINE = ReplacingMergeTree
+	PARTITION BY toYYYYMM(timestamp)
+	ORDER BY (ad_id, timestamp, id)`,
+	// Columns added after the tables were first created
+	`ALTER TABLE click_events ADD COLUMN IF NOT EXISTS session_id String`,
+	`ALTER TABLE impression_events ADD COLUMN IF NOT EXISTS session_id String`,
+}
+
+// ErrReadOnly is returned for writes the mirror cannot apply; they belong
+// to the primary store.
+var ErrReadOnly = errors.New("clickhouse: operation not supported by the analytics mirror")
+
+const timeLayout = "2006-01-02 15:04:05.000"
+
+// Store implements events.EventStore over the ClickHouse mirror. It is meant
+// as the analytics read side; events are written by the sink.
+type Store struct {
+	client *Client
+}
+
+var (
+	_ events.EventStore = (*Store)(nil)
+	_ events.Sink       = (*Store)(nil)
+)
+
+func NewStore(client *Client) *Store {
+	return &Store{client: client}
+}
+
+// EnsureSchema creates the mirror tables if they do not exist.
+func (s *Store) EnsureSchema(ctx context.Context) error {
+	for _, statement := range schema {
+		if err := s.client.Exec(ctx, statement, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type clickRow struct {
+	ClickID           string  `json:"click_id"`
+	AdID              uint    `json:"ad_id"`
+	Timestamp         string  `json:"timestamp"`
+	VideoPlaybackTime int64   `json:"video_playback_time"`
+	FraudScore        float64 `json:"fraud_score"`
+	Invalid           bool    `json:"invalid"`
+	ConsentState      string  `json:"consent_state"`
+	SessionID         string  `json:"session_id"`
+}
+
+type impressionRow struct {
+	ID            uint    `json:"id"`
+	AdID          uint    `json:"ad_id"`
+	Timestamp     string  `json:"timestamp"`
+	TimeInViewMS  int64   `json:"time_in_view_ms"`
+	PercentInView float64 `json:"percent_in_view"`
+	FraudScore    float64 `json:"fraud_score"`
+	Invalid       bool    `json:"invalid"`
+	ConsentState  string  `json:"consent_state"`
+	SessionID     string  `json:"session_id"`
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(timeLayout)
+}
+
+func (s *Store) SaveClicks(ctx context.Context, clicks []models.ClickEvent) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	rows := make([]clickRow, len(clicks))
+	for i, click := range clicks {
+		rows[i] = clickRow{
+			ClickID:           click.ClickID,
+			AdID:              click.AdID,
+			Timestamp:         formatTime(click.Timestamp),
+			VideoPlaybackTime: click.VideoPlaybackTime,
+			FraudScore:        click.FraudScore,
+			Invalid:           click.Invalid,
+			ConsentState:      click.ConsentState,
+			SessionID:         click.SessionID,
+		}
+	}
+	return s.client.Insert(ctx, "click_events", rows)
+}
+
+func (s *Store) SaveImpressions(ctx context.Context, impressions []models.ImpressionEvent) error {
+	if len(impressions) == 0 {
+		return nil
+	}
+	rows := make([]impressionRow, len(impressions))
+	for i, impression := range impressions {
+		rows[i] = impressionRow{
+			ID:            impression.ID,
+			AdID:          impression.AdID,
+			Timestamp:     formatTime(impression.Timestamp),
+			TimeInViewMS:  impression.TimeInViewMS,
+			PercentInView: impression.PercentInView,
+			FraudScore:    impression.FraudScore,
+			Invalid:       impression.Invalid,
+			ConsentState:  impression.ConsentState,
+			SessionID:     impression.SessionID,
+		}
+	}
+	return s.client.Insert(ctx, "impression_events", rows)
+}
+
+// MaxImpressionID is the sink's resume point for impressions.
+func (s *Store) MaxImpressionID(ctx context.Context) (uint, error) {
+	var rows []struct {
+		ID uint `json:"id"`
+	}
+	if err := s.client.Query(ctx, "SELECT max(id) AS id FROM impression_events", nil, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].ID, nil
+}
+
+// FlagIP cannot be applied: the mirror has no IP addresses.
+func (s *Store) FlagIP(ctx context.Context, ipAddress string, since time.Time, reason string) (int64, error) {
+	return 0, ErrReadOnly
+}
+
+// where renders the query filters as bound parameters.
+func where(query events.Query, extra ...string) (string, map[string]string) {
+	conditions := extra
+	params := map[string]string{}
+	if query.AdID != 0 {
+		conditions = append(conditions, "ad_id = {ad_id:UInt32}")
+		params["ad_id"] = strconv.FormatUint(uint64(query.AdID), 10)
+	}
+	if !query.Since.IsZero() {
+		conditions = append(conditions, "timestamp >= {since:DateTime64(3, 'UTC')}")
+		params["since"] = formatTime(query.Since)
+	}
+	if !query.Until.IsZero() {
+		conditions = append(conditions, "timestamp < {until:DateTime64(3, 'UTC')}")
+		params["until"] = formatTime(query.Until)
+	}
+	if query.ValidOnly {
+		conditions = append(conditions, "NOT invalid")
+	}
+	if len(conditions) == 0 {
+		return "", params
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), params
+}
+
+func (s *Store) count(ctx context.Context, table string, query events.Query) (int64, error) {
+	clause, params := where(query)
+	var rows []struct {
+		Count int64 `json:"count"`
+	}
+	if err := s.client.Query(ctx, "SELECT count() AS count FROM "+table+" FINAL"+clause, params, &rows); err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	return rows[0].Count, nil
+}
+
+func (s *Store) CountClicks(ctx context.Context, query events.Query) (int64, error) {
+	return s.count(ctx, "click_events", query)
+}
+
+func (s *Store) CountImpressions(ctx context.Context, query events.Query) (int64, error) {
+	return s.count(ctx, "impression_events", query)
+}
+
+func (s *Store) ListClicks(ctx context.Context, query events.Query) ([]models.ClickEvent, error) {
+	clause, params := where(query)
+	sql := "SELECT click_id, ad_id, timestamp, video_playback_time, fraud_score, invalid, consent_state, session_id" +
+		" FROM click_events FINAL" + clause + " ORDER BY timestamp DESC"
+	if query.Limit > 0 {
+		sql += " LIMIT " + strconv.Itoa(query.Limit)
+	}
+
+	var rows []clickRow
+	if err := s.client.Query(ctx, sql, params, &rows); err != nil {
+		return nil, err
+	}
+	clicks := make([]models.ClickEvent, len(rows))
+	for i, row := range rows {
+		timestamp, _ := time.Parse(timeLayout, row.Timestamp)
+		clicks[i] = models.ClickEvent{
+			ClickID:           row.ClickID,
+			AdID:              row.AdID,
+			Timestamp:         timestamp,
+			VideoPlaybackTime: row.VideoPlaybackTime,
+			FraudScore:        row.FraudScore,
+			Invalid:           row.Invalid,
+			ConsentState:      row.ConsentState,
+			SessionID:         row.SessionID,
+		}
+	}
+	return clicks, nil
+}
+
+func (s *Store) DistinctAdIDs(ctx context.Context, since time.Time) ([]uint, error) {
+	clause, params := where(events.Query{Since: since})
+	var rows []struct {
+		AdID uint `json:"ad_id"`
+	}
+	if err := s.client.Query(ctx, "SELECT DISTINCT ad_id FROM click_events"+clause, params, &rows); err != nil {
+		return nil, err
+	}
+	adIDs := make([]uint, len(rows))
+	for i, row := range rows {
+		adIDs[i] = row.AdID
+	}
+	return adIDs, nil
+}
+
+// PlaybackStats matches the Postgres percentile_cont figures with the
+// inclusive exact quantiles.
+func (s *Store) PlaybackStats(ctx context.Context, query events.Query, completeAfter int64) (models.PlaybackStats, error) {
+	clause, params := where(query, "video_playback_time > 0")
+	params["complete_after"] = strconv.FormatInt(completeAfter, 10)
+
+	var rows []struct {
+		Samples   int64   `json:"samples"`
+		Avg       float64 `json:"avg"`
+		Median    float64 `json:"median"`
+		P90       float64 `json:"p90"`
+		Completed int64   `json:"completed"`
+	}
+	err := s.client.Query(ctx, `SELECT
+			count() AS samples,
+			ifNotFinite(avg(video_playback_time), 0) AS avg,
+			ifNotFinite(quantileExactInclusive(0.5)(toFloat64(video_playback_time)), 0) AS median,
+			ifNotFinite(quantileExactInclusive(0.9)(toFloat64(video_playback_time)), 0) AS p90,
+			countIf({complete_after:Int64} > 0 AND video_playback_time >= {complete_after:Int64}) AS completed
+		FROM click_events FINAL`+clause, params, &rows)
+	if err != nil || len(rows) == 0 {
+		return models.PlaybackStats{}, err
+	}
+
+	result := rows[0]
+	stats := models.PlaybackStats{
+		Samples:       result.Samples,
+		AvgSeconds:    result.Avg,
+		MedianSeconds: result.Median,
+		P90Seconds:    result.P90,
+	}
+	if completeAfter > 0 && result.Samples > 0 {
+		stats.CompletionRate = float64(result.Completed) / float64(result.Samples)
+	}
+	return stats, nil
+}
+
+func (s *Store) ViewabilityStats(ctx context.Context, query events.Query, minPercent float64, minMS int64) (models.ViewabilityStats, error) {
+	clause, params := where(query, "(time_in_view_ms > 0 OR percent_in_view > 0)")
+	params["min_percent"] = strconv.FormatFloat(minPercent, 'f', -1, 64)
+	params["min_ms"] = strconv.FormatInt(minMS, 10)
+
+	var rows []struct {
+		Measured int64 `json:"measured"`
+		Viewable int64 `json:"viewable"`
+	}
+	err := s.client.Query(ctx, `SELECT
+			count() AS measured,
+			countIf(percent_in_view >= {min_percent:Float64} AND time_in_view_ms >= {min_ms:Int64}) AS viewable
+		FROM impression_events FINAL`+clause, params, &rows)
+	if err != nil || len(rows) == 0 {
+		return models.ViewabilityStats{}, err
+	}
+
+	stats := models.ViewabilityStats{Measured: rows[0].Measured, Viewable: rows[0].Viewable}
+	if stats.Measured > 0 {
+		stats.ViewableRate = float64(stats.Viewable) / float64(stats.Measured)
+	}
+	return stats, nil
+}
+
+func (s *Store) SessionStats(ctx context.Context, query events.Query) (models.SessionStats, error) {
+	clause, params := where(query, "session_id != ''")
+
+	var rows []struct {
+		Sessions int64 `json:"sessions"`
+		Events   int64 `json:"events"`
+		Bounced  int64 `json:"bounced"`
+	}
+	err := s.client.Query(ctx, `SELECT
+			count() AS sessions,
+			sum(events) AS events,
+			countIf(events = 1) AS bounced
+		FROM (
+			SELECT session_id, count() AS events FROM (
+				SELECT session_id FROM click_events FINAL`+clause+`
+				UNION ALL
+				SELECT session_id FROM impression_events FINAL`+clause+`
+			) GROUP BY session_id
+		)`, params, &rows)
+	if err != nil || len(rows) == 0 {
+		return models.SessionStats{}, err
+	}
+
+	stats := models.SessionStats{Sessions: rows[0].Sessions, Events: rows[0].Events, Bounced: rows[0].Bounced}
+	stats.Finish()
+	return stats, nil
+}
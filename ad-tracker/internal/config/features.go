@@ -0,0 +1,239 @@
+package config
+
+import "time"
+
+// BreakerConfig sets the circuit breakers on database writes and Kafka
+// publishes: they open after Failures consecutive errors and let Probes
+// calls through again after OpenTimeout.
+type BreakerConfig struct {
+	Failures    int           `yaml:"failures" env:"BREAKER_FAILURES"`
+	OpenTimeout time.Duration `yaml:"open_timeout" env:"BREAKER_OPEN_TIMEOUT"`
+	Probes      int           `yaml:"probes" env:"BREAKER_PROBES"`
+}
+
+// CacheConfig caches ad lookups, sessions and analytics results in Redis
+// when RedisURL is set, otherwise per replica.
+type CacheConfig struct {
+	RedisURL string        `yaml:"redis_url" env:"REDIS_URL"`
+	AdTTL    time.Duration `yaml:"ad_ttl" env:"AD_CACHE_TTL"`
+	// AdsMaxAge is the Cache-Control max-age on the public ad list.
+	AdsMaxAge time.Duration `yaml:"ads_max_age" env:"ADS_MAX_AGE"`
+	// Analytics results are reused for AnalyticsTTL, then served stale for
+	// up to AnalyticsStale while recomputed; zero disables either.
+	AnalyticsTTL   time.Duration `yaml:"analytics_ttl" env:"ANALYTICS_CACHE_TTL"`
+	AnalyticsStale time.Duration `yaml:"analytics_stale" env:"ANALYTICS_CACHE_STALE"`
+}
+
+// TrackingConfig covers how tracking calls are recorded and what they feed:
+// signed links, sessions, client timestamps, attribution, consent and
+// budgets.
+type TrackingConfig struct {
+	// LinkSigningSecret signs links of ads without an account; empty
+	// accepts unsigned links for those ads.
+	LinkSigningSecret string        `yaml:"link_signing_secret" env:"LINK_SIGNING_SECRET"`
+	LinkTTL           time.Duration `yaml:"link_ttl" env:"LINK_TTL"`
+	// CountryHeader carries the viewer's ISO country code, set by the CDN
+	// or load balancer.
+	CountryHeader      string        `yaml:"country_header" env:"GEO_COUNTRY_HEADER"`
+	SessionTimeout     time.Duration `yaml:"session_timeout" env:"SESSION_TIMEOUT"`
+	SessionizeInterval time.Duration `yaml:"sessionize_interval" env:"SESSIONIZE_INTERVAL"`
+	// Client timestamps more than TimestampMaxSkew ahead of the server clock
+	// or TimestampMaxAge behind it are clamped to the receipt time or
+	// rejected, per TimestampPolicy; a zero max age accepts any age.
+	TimestampPolicy        string        `yaml:"timestamp_policy" env:"TIMESTAMP_POLICY"`
+	TimestampMaxAge        time.Duration `yaml:"timestamp_max_age" env:"TIMESTAMP_MAX_AGE"`
+	TimestampMaxSkew       time.Duration `yaml:"timestamp_max_skew" env:"TIMESTAMP_MAX_SKEW"`
+	AttributionClickWindow time.Duration `yaml:"attribution_click_window" env:"ATTRIBUTION_CLICK_WINDOW"`
+	AttributionViewWindow  time.Duration `yaml:"attribution_view_window" env:"ATTRIBUTION_VIEW_WINDOW"`
+	// IdentityGraph credits conversions across the devices of a user.
+	IdentityGraph bool `yaml:"identity_graph" env:"IDENTITY_GRAPH"`
+	// TCFVendorID additionally requires vendor consent in TC strings.
+	TCFVendorID            int           `yaml:"tcf_vendor_id" env:"TCF_VENDOR_ID"`
+	ConsentRefreshInterval time.Duration `yaml:"consent_refresh_interval" env:"CONSENT_REFRESH_INTERVAL"`
+	// PacingBurst is how much of a paced campaign's daily share can go out
+	// at once.
+	PacingBurst        time.Duration `yaml:"pacing_burst" env:"PACING_BURST"`
+	BudgetSyncInterval time.Duration `yaml:"budget_sync_interval" env:"BUDGET_SYNC_INTERVAL"`
+	// Experiments and placements changed on other replicas are picked up on
+	// these intervals.
+	ExperimentRefreshInterval time.Duration `yaml:"experiment_refresh_interval" env:"EXPERIMENT_REFRESH_INTERVAL"`
+	PlacementRefreshInterval  time.Duration `yaml:"placement_refresh_interval" env:"PLACEMENT_REFRESH_INTERVAL"`
+}
+
+// ViewabilityConfig is the viewable impression rule, which analytics and
+// the rollups must agree on.
+type ViewabilityConfig struct {
+	MinPercent float64 `yaml:"min_percent" env:"VIEWABILITY_MIN_PERCENT"`
+	DisplayMS  int64   `yaml:"display_ms" env:"VIEWABILITY_DISPLAY_MS"`
+	VideoMS    int64   `yaml:"video_ms" env:"VIEWABILITY_VIDEO_MS"`
+}
+
+// PrivacyConfig minimizes identifiers before they are stored and signs the
+// download links of data subject exports.
+type PrivacyConfig struct {
+	// IPStorageMode is raw, truncate or hash; hash needs IPHashSalt.
+	IPStorageMode  string `yaml:"ip_storage_mode" env:"IP_STORAGE_MODE"`
+	IPHashSalt     string `yaml:"ip_hash_salt" env:"IP_HASH_SALT"`
+	UserIDHashSalt string `yaml:"user_id_hash_salt" env:"USER_ID_HASH_SALT"`
+	// ExportLinkSecret defaults to tracking.link_signing_secret.
+	ExportLinkSecret string        `yaml:"export_link_secret" env:"DSAR_LINK_SECRET"`
+	ExportLinkTTL    time.Duration `yaml:"export_link_ttl" env:"DSAR_LINK_TTL"`
+}
+
+// CaptureConfig records sample traffic while a click storm, or an operator,
+// opens a capture incident.
+type CaptureConfig struct {
+	Duration   time.Duration `yaml:"duration" env:"CAPTURE_DURATION"`
+	MaxSamples int64         `yaml:"max_samples" env:"CAPTURE_MAX_SAMPLES"`
+	// A storm is at least StormMinPerMinute clicks a minute and StormFactor
+	// times the recent rate.
+	StormMinPerMinute int64   `yaml:"storm_min_per_minute" env:"CLICK_STORM_MIN_PER_MINUTE"`
+	StormFactor       float64 `yaml:"storm_factor" env:"CLICK_STORM_FACTOR"`
+}
+
+// JobsConfig runs singleton background jobs on the replica holding the
+// Lease when in a Kubernetes cluster.
+type JobsConfig struct {
+	LeaderElection         bool          `yaml:"leader_election" env:"LEADER_ELECTION"`
+	LeaderElectionLease    string        `yaml:"leader_election_lease" env:"LEADER_ELECTION_LEASE"`
+	LeaderElectionDuration time.Duration `yaml:"leader_election_duration" env:"LEADER_ELECTION_DURATION"`
+}
+
+// RetentionConfig ages out rows per Policies, formatted
+// table=maxAge[:delete|anonymize] and comma separated; empty disables it.
+type RetentionConfig struct {
+	Policies  string        `yaml:"policies" env:"RETENTION_POLICIES"`
+	BatchSize int           `yaml:"batch_size" env:"RETENTION_BATCH_SIZE"`
+	Interval  time.Duration `yaml:"interval" env:"RETENTION_INTERVAL"`
+	DryRun    bool          `yaml:"dry_run" env:"RETENTION_DRY_RUN"`
+}
+
+// ArchiveConfig names the cold archive and the export store. Object store
+// credentials stay in the ARCHIVE_* and AWS_* variables.
+type ArchiveConfig struct {
+	// URL is s3://bucket/prefix, gs://bucket/prefix or file:///path.
+	URL string `yaml:"url" env:"ARCHIVE_URL"`
+	// ExportStoreURL holds exports in the same forms; empty uses an
+	// exports/ prefix of the archive, else the local ExportDir.
+	ExportStoreURL string `yaml:"export_store_url" env:"EXPORT_STORE_URL"`
+	ExportDir      string `yaml:"export_dir" env:"EXPORT_DIR"`
+}
+
+// ReportsConfig delivers scheduled reports over SMTP or to object storage.
+type ReportsConfig struct {
+	SMTPAddr     string        `yaml:"smtp_addr" env:"SMTP_ADDR"`
+	SMTPFrom     string        `yaml:"smtp_from" env:"SMTP_FROM"`
+	SMTPUsername string        `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string        `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"REPORT_MAX_ATTEMPTS"`
+	RetryBackoff time.Duration `yaml:"retry_backoff" env:"REPORT_RETRY_BACKOFF"`
+}
+
+// WebhooksConfig delivers event webhooks and evaluates alert rules.
+type WebhooksConfig struct {
+	// AllowPrivate lets webhook and alert hook URLs reach loopback,
+	// link-local and private addresses; for local development only.
+	AllowPrivate      bool          `yaml:"allow_private" env:"OUTBOUND_ALLOW_PRIVATE"`
+	MaxAttempts       int           `yaml:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS"`
+	RetryBackoff      time.Duration `yaml:"retry_backoff" env:"WEBHOOK_RETRY_BACKOFF"`
+	RefreshInterval   time.Duration `yaml:"refresh_interval" env:"WEBHOOK_REFRESH_INTERVAL"`
+	AlertEvalInterval time.Duration `yaml:"alert_eval_interval" env:"ALERT_EVAL_INTERVAL"`
+}
+
+// IntegrationsConfig forwards clicks and conversions to Segment and GA4 in
+// batches, retrying failed sends with doubling backoff.
+type IntegrationsConfig struct {
+	BatchSize       int           `yaml:"batch_size" env:"FORWARDER_BATCH_SIZE"`
+	FlushInterval   time.Duration `yaml:"flush_interval" env:"FORWARDER_FLUSH_INTERVAL"`
+	MaxAttempts     int           `yaml:"max_attempts" env:"FORWARDER_MAX_ATTEMPTS"`
+	RetryBackoff    time.Duration `yaml:"retry_backoff" env:"FORWARDER_RETRY_BACKOFF"`
+	SegmentURL      string        `yaml:"segment_url" env:"SEGMENT_API_URL"`
+	GA4URL          string        `yaml:"ga4_url" env:"GA4_API_URL"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"INTEGRATION_REFRESH_INTERVAL"`
+}
+
+// SinksConfig copies events to analytics stores, each consuming the event
+// topic under its own group.
+type SinksConfig struct {
+	// AnalyticsBackend is postgres, or clickhouse to serve analytics from
+	// the ClickHouse mirror.
+	AnalyticsBackend string `yaml:"analytics_backend" env:"ANALYTICS_BACKEND"`
+	// ImpressionLookback is how long impressions whose insert had not
+	// committed are retried, and how far back a new copier starts.
+	ImpressionLookback time.Duration    `yaml:"impression_lookback" env:"SINK_IMPRESSION_LOOKBACK"`
+	ClickHouse         ClickHouseConfig `yaml:"clickhouse"`
+	BigQuery           BigQueryConfig   `yaml:"bigquery"`
+	Realtime           RealtimeConfig   `yaml:"realtime"`
+}
+
+// ClickHouseConfig mirrors events into ClickHouse when URL is set.
+type ClickHouseConfig struct {
+	URL           string        `yaml:"url" env:"CLICKHOUSE_URL"`
+	GroupID       string        `yaml:"group_id" env:"CLICKHOUSE_GROUP_ID"`
+	BatchSize     int           `yaml:"batch_size" env:"CLICKHOUSE_BATCH_SIZE"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"CLICKHOUSE_FLUSH_INTERVAL"`
+}
+
+// BigQueryConfig streams events into Dataset when it is set. Without
+// CredentialsFile, tokens come from the metadata server; URL can point at
+// an emulator.
+type BigQueryConfig struct {
+	Project         string        `yaml:"project" env:"BIGQUERY_PROJECT"`
+	Dataset         string        `yaml:"dataset" env:"BIGQUERY_DATASET"`
+	Location        string        `yaml:"location" env:"BIGQUERY_LOCATION"`
+	CredentialsFile string        `yaml:"credentials_file" env:"BIGQUERY_CREDENTIALS_FILE"`
+	URL             string        `yaml:"url" env:"BIGQUERY_URL"`
+	GroupID         string        `yaml:"group_id" env:"BIGQUERY_GROUP_ID"`
+	BatchSize       int           `yaml:"batch_size" env:"BIGQUERY_BATCH_SIZE"`
+	FlushInterval   time.Duration `yaml:"flush_interval" env:"BIGQUERY_FLUSH_INTERVAL"`
+}
+
+// RealtimeConfig keeps rolling counters and daily leaderboards in Redis,
+// with Redis and Kafka both configured.
+type RealtimeConfig struct {
+	GroupID       string        `yaml:"group_id" env:"REALTIME_GROUP_ID"`
+	BatchSize     int           `yaml:"batch_size" env:"REALTIME_BATCH_SIZE"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"REALTIME_FLUSH_INTERVAL"`
+	// LeaderboardMinImpressions is how many impressions an ad needs in a
+	// day before the leaderboard ranks it by CTR.
+	LeaderboardMinImpressions int64 `yaml:"leaderboard_min_impressions" env:"LEADERBOARD_MIN_IMPRESSIONS"`
+}
+
+// RollupsConfig rebuilds hourly and daily rollups every Interval and runs
+// the anomaly detectors over them.
+type RollupsConfig struct {
+	Interval time.Duration `yaml:"interval" env:"ROLLUP_INTERVAL"`
+	// Lookback is how far before the covered hour each run re-aggregates;
+	// it is raised to tracking.timestamp_max_age plus an hour.
+	Lookback time.Duration `yaml:"lookback" env:"ROLLUP_LOOKBACK"`
+	// Analytics windows of at least MinWindow read the rollups.
+	MinWindow        time.Duration `yaml:"min_window" env:"ROLLUP_MIN_WINDOW"`
+	AnomalyDetectors []string      `yaml:"anomaly_detectors" env:"ANOMALY_DETECTORS"`
+	AnomalyInterval  time.Duration `yaml:"anomaly_interval" env:"ANOMALY_INTERVAL"`
+	AnomalyLookback  time.Duration `yaml:"anomaly_lookback" env:"ANOMALY_LOOKBACK"`
+	AnomalyRecent    time.Duration `yaml:"anomaly_recent" env:"ANOMALY_RECENT"`
+}
+
+// ReplicationConfig copies the event topic to StandbyBroker when set.
+type ReplicationConfig struct {
+	StandbyBroker string `yaml:"standby_broker" env:"STANDBY_KAFKA_BROKER"`
+	Region        string `yaml:"region" env:"REGION"`
+	// Role is primary or standby, used until the replication state is
+	// first saved.
+	Role    string `yaml:"role" env:"REGION_ROLE"`
+	GroupID string `yaml:"group_id" env:"REPLICATION_GROUP_ID"`
+}
+
+// AccessLogConfig mirrors access-log records of Groups to Topic when set.
+type AccessLogConfig struct {
+	Topic  string   `yaml:"topic" env:"ACCESS_LOG_TOPIC"`
+	Groups []string `yaml:"groups" env:"ACCESS_LOG_GROUPS"`
+}
+
+// EdgeConfig accepts envelopes signed with SigningKey from edge PoPs, over
+// HTTP and from Topic.
+type EdgeConfig struct {
+	SigningKey string `yaml:"signing_key" env:"EDGE_SIGNING_KEY"`
+	Topic      string `yaml:"topic" env:"EDGE_KAFKA_TOPIC"`
+	GroupID    string `yaml:"group_id" env:"EDGE_GROUP_ID"`
+}
@@ -0,0 +1,500 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the server. Load starts from Default,
+// applies the YAML file named by CONFIG_FILE and then any environment
+// variable from the env tags, so existing deployments configured purely
+// through the environment keep working. New settings belong in these
+// structs, with their checks in validate, rather than read from the
+// environment where they are used.
+//
+// SIGHUP reloads the log level, queue batching, fraud limits and bot
+// signatures; the rest only changes on restart.
+type Config struct {
+	Server       ServerConfig       `yaml:"server"`
+	Logging      LoggingConfig      `yaml:"logging"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Kafka        KafkaConfig        `yaml:"kafka"`
+	Queue        QueueConfig        `yaml:"queue"`
+	Middleware   MiddlewareConfig   `yaml:"middleware"`
+	Fraud        FraudConfig        `yaml:"fraud"`
+	Breaker      BreakerConfig      `yaml:"breaker"`
+	Cache        CacheConfig        `yaml:"cache"`
+	Tracking     TrackingConfig     `yaml:"tracking"`
+	Viewability  ViewabilityConfig  `yaml:"viewability"`
+	Privacy      PrivacyConfig      `yaml:"privacy"`
+	Capture      CaptureConfig      `yaml:"capture"`
+	Jobs         JobsConfig         `yaml:"jobs"`
+	Retention    RetentionConfig    `yaml:"retention"`
+	Archive      ArchiveConfig      `yaml:"archive"`
+	Reports      ReportsConfig      `yaml:"reports"`
+	Webhooks     WebhooksConfig     `yaml:"webhooks"`
+	Integrations IntegrationsConfig `yaml:"integrations"`
+	Sinks        SinksConfig        `yaml:"sinks"`
+	Rollups      RollupsConfig      `yaml:"rollups"`
+	Replication  ReplicationConfig  `yaml:"replication"`
+	AccessLog    AccessLogConfig    `yaml:"access_log"`
+	Edge         EdgeConfig         `yaml:"edge"`
+}
+
+type ServerConfig struct {
+	Port          int    `yaml:"port" env:"PORT"`
+	Mode          string `yaml:"mode" env:"GIN_MODE"`
+	LogLevel      string `yaml:"log_level" env:"LOG_LEVEL"`
+	PublicBaseURL string `yaml:"public_base_url" env:"PUBLIC_BASE_URL"`
+	AdminToken    string `yaml:"admin_token" env:"ADMIN_TOKEN"`
+	// DrainDelay is how long the server keeps serving after SIGTERM, failing
+	// readiness, for load balancers to stop routing to the pod.
+	DrainDelay      time.Duration `yaml:"drain_delay" env:"PRESTOP_DRAIN_DELAY"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// MaxTimeframe caps the ?timeframe= of analytics and report queries;
+	// timeframe=all is exempt.
+	MaxTimeframe time.Duration `yaml:"max_timeframe" env:"MAX_TIMEFRAME"`
+	// DebugAnalytics lets any caller ask for the analytics debug block,
+	// which otherwise takes admin credentials; for development only.
+	DebugAnalytics bool      `yaml:"debug_analytics" env:"DEBUG_ANALYTICS"`
+	TLS            TLSConfig `yaml:"tls"`
+}
+
+// TLSConfig serves HTTPS directly, for deployments without a load balancer
+// terminating TLS. Certificates come from files or from ACME.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" env:"TLS_KEY_FILE"`
+	// ACMEDomains obtains and renews certificates for these hosts from
+	// Let's Encrypt instead of reading files.
+	ACMEDomains  []string `yaml:"acme_domains" env:"TLS_ACME_DOMAINS"`
+	ACMEEmail    string   `yaml:"acme_email" env:"TLS_ACME_EMAIL"`
+	ACMECacheDir string   `yaml:"acme_cache_dir" env:"TLS_ACME_CACHE_DIR"`
+	// RedirectPort serves plain HTTP that redirects to HTTPS (and answers
+	// ACME http-01 challenges); zero disables it.
+	RedirectPort int `yaml:"redirect_port" env:"TLS_REDIRECT_PORT"`
+	// ClientCAFile enables mutual TLS for the admin, privacy and metrics
+	// routes: a client certificate signed by this CA authenticates in place
+	// of the admin token, and is required for /metrics.
+	ClientCAFile string `yaml:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	// ClientNames restricts accepted client certificates to these common
+	// names, DNS or URI SANs; empty accepts any certificate from the CA.
+	ClientNames []string `yaml:"client_names" env:"TLS_CLIENT_NAMES"`
+}
+
+// Enabled reports whether the server should listen with TLS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || len(c.ACMEDomains) > 0
+}
+
+// LoggingConfig sends logs to the console (stderr), a rotated file, or
+// both; files are for hosts without a log shipper collecting the console.
+type LoggingConfig struct {
+	// Output is console, file or both.
+	Output string `yaml:"output" env:"LOG_OUTPUT"`
+	File   string `yaml:"file" env:"LOG_FILE"`
+	// The file is rotated once it grows past MaxSizeMB and at every
+	// RotateEvery boundary (UTC); zero disables either trigger.
+	MaxSizeMB   int           `yaml:"max_size_mb" env:"LOG_MAX_SIZE_MB"`
+	RotateEvery time.Duration `yaml:"rotate_every" env:"LOG_ROTATE_EVERY"`
+	// MaxBackups is how many rotated files are kept; zero keeps them all.
+	MaxBackups int `yaml:"max_backups" env:"LOG_MAX_BACKUPS"`
+	// Per-event log lines are sampled: per message and SamplePeriod, the
+	// first SampleFirst are written, then one in SampleThereafter. At debug
+	// level every line is kept unless SampleDebug is set. A zero period
+	// disables sampling.
+	SampleFirst      int           `yaml:"sample_first" env:"LOG_SAMPLE_FIRST"`
+	SampleThereafter int           `yaml:"sample_thereafter" env:"LOG_SAMPLE_THEREAFTER"`
+	SamplePeriod     time.Duration `yaml:"sample_period" env:"LOG_SAMPLE_PERIOD"`
+	SampleDebug      bool          `yaml:"sample_debug" env:"LOG_SAMPLE_DEBUG"`
+}
+
+type DatabaseConfig struct {
+	Driver string `yaml:"driver" env:"DB_DRIVER"`
+	// URL defaults per driver when empty.
+	URL               string        `yaml:"url" env:"DATABASE_URL"`
+	MaxOpenConns      int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns      int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime   time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime   time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	StatementTimeout  time.Duration `yaml:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+	ConnectAttempts   int           `yaml:"connect_attempts" env:"DB_CONNECT_ATTEMPTS"`
+	ConnectBackoff    time.Duration `yaml:"connect_backoff" env:"DB_CONNECT_BACKOFF"`
+	ConnectMaxBackoff time.Duration `yaml:"connect_max_backoff" env:"DB_CONNECT_MAX_BACKOFF"`
+	HealthInterval    time.Duration `yaml:"health_interval" env:"DB_HEALTH_INTERVAL"`
+	// AutoMigrate applies pending migrations at startup; unset means on
+	// for sqlite only.
+	AutoMigrate *bool `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+}
+
+type KafkaConfig struct {
+	Broker string `yaml:"broker" env:"KAFKA_BROKER"`
+	Topic  string `yaml:"topic" env:"KAFKA_TOPIC"`
+	// Bus is kafka, or memory to keep published events in process.
+	Bus          string        `yaml:"bus" env:"EVENT_BUS"`
+	BatchSize    int           `yaml:"batch_size" env:"KAFKA_BATCH_SIZE"`
+	BatchTimeout time.Duration `yaml:"batch_timeout" env:"KAFKA_BATCH_TIMEOUT"`
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"KAFKA_WRITE_TIMEOUT"`
+	// StartupTimeout is how long startup waits for the broker to accept a
+	// connection; zero skips the check.
+	StartupTimeout time.Duration `yaml:"startup_timeout" env:"KAFKA_STARTUP_TIMEOUT"`
+}
+
+// QueueConfig sizes the in-process click queue in front of the database.
+type QueueConfig struct {
+	BufferSize    int           `yaml:"buffer_size" env:"CLICK_QUEUE_SIZE"`
+	BatchSize     int           `yaml:"batch_size" env:"CLICK_BATCH_SIZE"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"CLICK_FLUSH_INTERVAL"`
+}
+
+type MiddlewareConfig struct {
+	// CORSAllowedOrigins lists the origins browsers may call from; "*"
+	// allows any.
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	// TrustedProxies limits which peers may set X-Forwarded-For. Empty
+	// keeps gin's default of trusting every peer.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+	// AdminAllowedIPs limits the admin, privacy, debug and metrics routes
+	// to these CIDRs or addresses; empty allows any client.
+	AdminAllowedIPs []string `yaml:"admin_allowed_ips" env:"ADMIN_ALLOWED_IPS"`
+	// MaxBodyBytes caps request bodies; larger requests get 413.
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"MAX_BODY_BYTES"`
+	// MaxStreamBytes caps NDJSON bodies, which are processed as they
+	// arrive instead of held in memory.
+	MaxStreamBytes int64 `yaml:"max_stream_bytes" env:"MAX_STREAM_BYTES"`
+	// MaxUserAgentLength truncates User-Agent headers before handlers,
+	// fraud rules or storage see them.
+	MaxUserAgentLength int `yaml:"max_user_agent_length" env:"MAX_USER_AGENT_LENGTH"`
+	// CompressionLevel is the gzip level of analytics and event list
+	// responses, 1 to 9, or -1 for the library default.
+	CompressionLevel int `yaml:"compression_level" env:"COMPRESSION_LEVEL"`
+}
+
+// FraudConfig holds the per-IP limits, bot list and rule weights used to
+// tag invalid traffic. Only the limits and the bot list are reloaded.
+type FraudConfig struct {
+	MaxClicksPerMinute int `yaml:"max_clicks_per_minute" env:"FRAUD_MAX_CLICKS_PER_MINUTE"`
+	MaxAgentsPerIP     int `yaml:"max_agents_per_ip" env:"FRAUD_MAX_AGENTS_PER_IP"`
+	// BotSignaturesFile adds signatures to the built-in bot list.
+	BotSignaturesFile string `yaml:"bot_signatures_file" env:"BOT_SIGNATURES_FILE"`
+	// BotFilterMode is drop to discard known bots' events, or flag to
+	// record them as invalid.
+	BotFilterMode string `yaml:"bot_filter_mode" env:"BOT_FILTER_MODE"`
+	// Events scoring at least Threshold, summing the weights of the rules
+	// they match, are tagged invalid.
+	Threshold        float64  `yaml:"threshold" env:"FRAUD_THRESHOLD"`
+	ClickRateWeight  float64  `yaml:"click_rate_weight" env:"FRAUD_CLICK_RATE_WEIGHT"`
+	UARotationWeight float64  `yaml:"ua_rotation_weight" env:"FRAUD_UA_ROTATION_WEIGHT"`
+	MissingUAWeight  float64  `yaml:"missing_ua_weight" env:"FRAUD_MISSING_UA_WEIGHT"`
+	DatacenterWeight float64  `yaml:"datacenter_weight" env:"FRAUD_DATACENTER_WEIGHT"`
+	DatacenterCIDRs  []string `yaml:"datacenter_cidrs" env:"FRAUD_DATACENTER_CIDRS"`
+	// Clicks on honeypot ads block the address for HoneypotBlockTTL and
+	// flag its events of the last HoneypotFlagLookback.
+	HoneypotBlockTTL     time.Duration `yaml:"honeypot_block_ttl" env:"HONEYPOT_BLOCK_TTL"`
+	HoneypotFlagLookback time.Duration `yaml:"honeypot_flag_lookback" env:"HONEYPOT_FLAG_LOOKBACK"`
+	// BlocklistRefresh picks up blocked ranges added on other replicas.
+	BlocklistRefresh time.Duration `yaml:"blocklist_refresh_interval" env:"BLOCKLIST_REFRESH_INTERVAL"`
+}
+
+// Default returns the settings used when neither the file nor the
+// environment says otherwise.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:            8080,
+			Mode:            "debug",
+			LogLevel:        "info",
+			DrainDelay:      5 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+			MaxTimeframe:    90 * 24 * time.Hour,
+			TLS: TLSConfig{
+				ACMECacheDir: "certs",
+			},
+		},
+		Logging: LoggingConfig{
+			Output:           "console",
+			File:             "logs/ad-tracker.log",
+			MaxSizeMB:        100,
+			RotateEvery:      24 * time.Hour,
+			MaxBackups:       7,
+			SampleFirst:      10,
+			SampleThereafter: 100,
+			SamplePeriod:     time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:            "postgres",
+			MaxOpenConns:      100,
+			MaxIdleConns:      10,
+			ConnMaxLifetime:   time.Hour,
+			ConnectAttempts:   30,
+			ConnectBackoff:    time.Second,
+			ConnectMaxBackoff: 30 * time.Second,
+			HealthInterval:    5 * time.Second,
+		},
+		Kafka: KafkaConfig{
+			Broker:         "localhost:9092",
+			Topic:          "ad-events",
+			Bus:            "kafka",
+			BatchSize:      100,
+			BatchTimeout:   10 * time.Millisecond,
+			WriteTimeout:   10 * time.Second,
+			StartupTimeout: 30 * time.Second,
+		},
+		Queue: QueueConfig{
+			BufferSize:    10000,
+			BatchSize:     100,
+			FlushInterval: 5 * time.Second,
+		},
+		Middleware: MiddlewareConfig{
+			CORSAllowedOrigins: []string{"*"},
+			MaxBodyBytes:       1 << 20,
+			MaxStreamBytes:     64 << 20,
+			MaxUserAgentLength: 512,
+			CompressionLevel:   -1,
+		},
+		Fraud: FraudConfig{
+			MaxClicksPerMinute:   30,
+			MaxAgentsPerIP:       5,
+			BotFilterMode:        "drop",
+			Threshold:            0.5,
+			ClickRateWeight:      0.8,
+			UARotationWeight:     0.4,
+			MissingUAWeight:      0.4,
+			DatacenterWeight:     0.6,
+			HoneypotBlockTTL:     time.Hour,
+			HoneypotFlagLookback: 24 * time.Hour,
+			BlocklistRefresh:     30 * time.Second,
+		},
+		Breaker: BreakerConfig{
+			Failures:    5,
+			OpenTimeout: 10 * time.Second,
+			Probes:      1,
+		},
+		Cache: CacheConfig{
+			AdTTL:          10 * time.Second,
+			AdsMaxAge:      30 * time.Second,
+			AnalyticsTTL:   15 * time.Second,
+			AnalyticsStale: time.Minute,
+		},
+		Tracking: TrackingConfig{
+			LinkTTL:                   30 * 24 * time.Hour,
+			CountryHeader:             "CF-IPCountry",
+			SessionTimeout:            30 * time.Minute,
+			SessionizeInterval:        5 * time.Minute,
+			TimestampPolicy:           "clamp",
+			TimestampMaxAge:           24 * time.Hour,
+			TimestampMaxSkew:          5 * time.Minute,
+			AttributionClickWindow:    7 * 24 * time.Hour,
+			AttributionViewWindow:     24 * time.Hour,
+			ConsentRefreshInterval:    30 * time.Second,
+			PacingBurst:               5 * time.Minute,
+			BudgetSyncInterval:        5 * time.Second,
+			ExperimentRefreshInterval: 30 * time.Second,
+			PlacementRefreshInterval:  30 * time.Second,
+		},
+		Viewability: ViewabilityConfig{
+			MinPercent: 50,
+			DisplayMS:  1000,
+			VideoMS:    2000,
+		},
+		Privacy: PrivacyConfig{
+			IPStorageMode: "raw",
+			ExportLinkTTL: 7 * 24 * time.Hour,
+		},
+		Capture: CaptureConfig{
+			Duration:          10 * time.Minute,
+			MaxSamples:        1000,
+			StormMinPerMinute: 100,
+			StormFactor:       5,
+		},
+		Jobs: JobsConfig{
+			LeaderElection:         true,
+			LeaderElectionLease:    "ad-tracker-jobs",
+			LeaderElectionDuration: 15 * time.Second,
+		},
+		Retention: RetentionConfig{
+			BatchSize: 5000,
+			Interval:  time.Hour,
+		},
+		Archive: ArchiveConfig{
+			ExportDir: "exports",
+		},
+		Reports: ReportsConfig{
+			SMTPFrom:     "reports@localhost",
+			MaxAttempts:  3,
+			RetryBackoff: 5 * time.Minute,
+		},
+		Webhooks: WebhooksConfig{
+			MaxAttempts:       8,
+			RetryBackoff:      30 * time.Second,
+			RefreshInterval:   30 * time.Second,
+			AlertEvalInterval: time.Minute,
+		},
+		Integrations: IntegrationsConfig{
+			BatchSize:       100,
+			FlushInterval:   5 * time.Second,
+			MaxAttempts:     5,
+			RetryBackoff:    2 * time.Second,
+			SegmentURL:      "https://api.segment.io/v1/batch",
+			GA4URL:          "https://www.google-analytics.com/mp/collect",
+			RefreshInterval: 30 * time.Second,
+		},
+		Sinks: SinksConfig{
+			AnalyticsBackend:   "postgres",
+			ImpressionLookback: 5 * time.Minute,
+			ClickHouse: ClickHouseConfig{
+				GroupID:       "ad-tracker-clickhouse",
+				BatchSize:     5000,
+				FlushInterval: 5 * time.Second,
+			},
+			BigQuery: BigQueryConfig{
+				URL:           "https://bigquery.googleapis.com/bigquery/v2",
+				GroupID:       "ad-tracker-bigquery",
+				BatchSize:     500,
+				FlushInterval: 5 * time.Second,
+			},
+			Realtime: RealtimeConfig{
+				GroupID:                   "ad-tracker-realtime",
+				BatchSize:                 500,
+				FlushInterval:             500 * time.Millisecond,
+				LeaderboardMinImpressions: 100,
+			},
+		},
+		Rollups: RollupsConfig{
+			Interval:         5 * time.Minute,
+			Lookback:         3 * time.Hour,
+			MinWindow:        48 * time.Hour,
+			AnomalyDetectors: []string{"ewma", "seasonal_naive"},
+			AnomalyInterval:  15 * time.Minute,
+			AnomalyLookback:  8 * 24 * time.Hour,
+			AnomalyRecent:    6 * time.Hour,
+		},
+		Replication: ReplicationConfig{
+			Region:  "default",
+			Role:    "primary",
+			GroupID: "ad-tracker-replicator",
+		},
+		AccessLog: AccessLogConfig{
+			Groups: []string{"api", "admin", "privacy", "internal", "public"},
+		},
+		Edge: EdgeConfig{
+			Topic:   "ad-edge-events",
+			GroupID: "ad-tracker-edge",
+		},
+	}
+}
+
+// Load builds the configuration and validates it. path may be empty, in
+// which case CONFIG_FILE is used if set. Problems with individual settings
+// are returned together as a *ValidationError alongside the configuration.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return cfg, fmt.Errorf("read config file: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+			return cfg, fmt.Errorf("parse config file %s: %w", path, err)
+		}
+	}
+	report := &ValidationError{}
+	applyEnv(reflect.ValueOf(&cfg).Elem(), report)
+	cfg.validate(report)
+	return cfg, report.Err()
+}
+
+// AutoMigrateEnabled resolves the driver-dependent default of AutoMigrate.
+func (c DatabaseConfig) AutoMigrateEnabled() bool {
+	if c.AutoMigrate != nil {
+		return *c.AutoMigrate
+	}
+	return c.Driver == "sqlite"
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// applyEnv overrides fields tagged env:"NAME" with non-empty variables.
+// Unparseable values are reported and leave the field unchanged.
+func applyEnv(v reflect.Value, report *ValidationError) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			applyEnv(value, report)
+			continue
+		}
+		name := field.Tag.Get("env")
+		raw := os.Getenv(name)
+		if name == "" || raw == "" {
+			continue
+		}
+		if err := setField(value, raw); err != nil {
+			report.Add(name, "cannot parse %q: %v", raw, err)
+		}
+	}
+}
+
+func setField(value reflect.Value, raw string) error {
+	if value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		value.SetInt(int64(d))
+		return nil
+	}
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Ptr:
+		target := reflect.New(value.Type().Elem())
+		if err := setField(target.Elem(), raw); err != nil {
+			return err
+		}
+		value.Set(target)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
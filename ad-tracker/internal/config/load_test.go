@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExampleConfig checks that config.example.yaml loads cleanly and
+// documents the defaults.
+func TestExampleConfig(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	cfg, err := Load("../../config.example.yaml")
+	if err != nil {
+		t.Fatalf("load example: %v", err)
+	}
+	want := Default()
+	want.Server.PublicBaseURL = cfg.Server.PublicBaseURL
+	want.Database.URL = cfg.Database.URL
+	// Printed, so the example's empty lists match the nil default ones
+	if got, want := fmt.Sprintf("%+v", cfg), fmt.Sprintf("%+v", want); got != want {
+		t.Errorf("example differs from the defaults:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestLoadEnvOverrides(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("ROLLUP_LOOKBACK", "30h")
+	t.Setenv("ANOMALY_DETECTORS", "ewma, ")
+	t.Setenv("FRAUD_THRESHOLD", "0.7")
+	t.Setenv("IDENTITY_GRAPH", "true")
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Rollups.Lookback != 30*time.Hour || !reflect.DeepEqual(cfg.Rollups.AnomalyDetectors, []string{"ewma"}) ||
+		cfg.Fraud.Threshold != 0.7 || !cfg.Tracking.IdentityGraph {
+		t.Errorf("overrides not applied: rollups %+v, threshold %v, identity graph %v",
+			cfg.Rollups, cfg.Fraud.Threshold, cfg.Tracking.IdentityGraph)
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("WEBHOOK_MAX_ATTEMPTS", "many")
+	t.Setenv("IP_STORAGE_MODE", "hash")
+	t.Setenv("TIMESTAMP_POLICY", "ignore")
+	t.Setenv("ANALYTICS_BACKEND", "clickhouse")
+	t.Setenv("ANOMALY_DETECTORS", "ewma,prophet")
+	t.Setenv("FRAUD_DATACENTER_CIDRS", "10.0.0.0/8,10.0.0.1")
+	_, err := Load("")
+	if err == nil {
+		t.Fatal("Load succeeded, want problems")
+	}
+	for _, setting := range []string{
+		"WEBHOOK_MAX_ATTEMPTS",
+		"privacy.ip_hash_salt (IP_HASH_SALT)",
+		"tracking.timestamp_policy (TIMESTAMP_POLICY)",
+		"clickhouse requires sinks.clickhouse.url",
+		`rollups.anomaly_detectors (ANOMALY_DETECTORS): must list ewma or seasonal_naive, got "prophet"`,
+		`"10.0.0.1" is not a CIDR`,
+	} {
+		if !strings.Contains(err.Error(), setting) {
+			t.Errorf("error does not mention %s:\n%v", setting, err)
+		}
+	}
+}
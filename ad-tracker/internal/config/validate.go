@@ -0,0 +1,409 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationError lists every configuration problem found at startup, so
+// they can be fixed in one pass instead of one restart at a time.
+type ValidationError struct {
+	Problems []string
+}
+
+// Add records a problem with setting, e.g. "server.port (PORT)".
+func (e *ValidationError) Add(setting, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, setting+": "+fmt.Sprintf(format, args...))
+}
+
+// Check records a problem unless ok.
+func (e *ValidationError) Check(ok bool, setting, format string, args ...interface{}) {
+	if !ok {
+		e.Add(setting, format, args...)
+	}
+}
+
+// Err returns e, or nil when nothing was recorded.
+func (e *ValidationError) Err() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e.Problems, "\n  ")
+}
+
+// Validate reports every invalid setting at once, naming both the file key
+// and the environment variable.
+func (c Config) Validate() error {
+	report := &ValidationError{}
+	c.validate(report)
+	return report.Err()
+}
+
+func (c Config) validate(report *ValidationError) {
+	check := report.Check
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port (PORT)", "must be between 1 and 65535, got %d", c.Server.Port)
+	check(oneOf(c.Server.Mode, "debug", "release", "test"), "server.mode (GIN_MODE)", "must be debug, release or test, got %q", c.Server.Mode)
+	check(oneOf(c.Server.LogLevel, "debug", "info", "warn", "error"), "server.log_level (LOG_LEVEL)", "must be debug, info, warn or error, got %q", c.Server.LogLevel)
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout (SHUTDOWN_TIMEOUT)", "must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay (PRESTOP_DRAIN_DELAY)", "must not be negative")
+	check(c.Server.MaxTimeframe > 0, "server.max_timeframe (MAX_TIMEFRAME)", "must be positive")
+	if c.Server.PublicBaseURL != "" {
+		check(isHTTPURL(c.Server.PublicBaseURL), "server.public_base_url (PUBLIC_BASE_URL)",
+			"must be an absolute http(s) URL such as https://track.example.com, got %q", c.Server.PublicBaseURL)
+	}
+
+	c.validateTLS(report)
+
+	check(oneOf(c.Logging.Output, "console", "file", "both"), "logging.output (LOG_OUTPUT)", "must be console, file or both, got %q", c.Logging.Output)
+	check(c.Logging.Output == "console" || c.Logging.File != "", "logging.file (LOG_FILE)", "is required when logging.output is %s", c.Logging.Output)
+	check(c.Logging.MaxSizeMB >= 0, "logging.max_size_mb (LOG_MAX_SIZE_MB)", "must not be negative (0 disables size rotation)")
+	check(c.Logging.RotateEvery >= 0, "logging.rotate_every (LOG_ROTATE_EVERY)", "must not be negative (0 disables time rotation)")
+	check(c.Logging.MaxBackups >= 0, "logging.max_backups (LOG_MAX_BACKUPS)", "must not be negative (0 keeps every file)")
+
+	check(oneOf(c.Database.Driver, "postgres", "mysql", "sqlite"), "database.driver (DB_DRIVER)", "must be postgres, mysql or sqlite, got %q", c.Database.Driver)
+	check(c.Server.Mode != "release" || c.Database.URL != "", "database.url (DATABASE_URL)", "is required when server.mode is release")
+	if c.Database.URL != "" {
+		if problem := databaseURLProblem(c.Database.Driver, c.Database.URL); problem != "" {
+			report.Add("database.url (DATABASE_URL)", "%s", problem)
+		}
+	}
+	check(c.Database.MaxOpenConns >= 0, "database.max_open_conns (DB_MAX_OPEN_CONNS)", "must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns,
+		"database.max_idle_conns (DB_MAX_IDLE_CONNS)", "must not exceed max_open_conns (%d)", c.Database.MaxOpenConns)
+	check(c.Database.ConnectAttempts >= 0, "database.connect_attempts (DB_CONNECT_ATTEMPTS)", "must not be negative (0 retries forever)")
+	check(c.Database.ConnectBackoff > 0, "database.connect_backoff (DB_CONNECT_BACKOFF)", "must be positive")
+	check(c.Database.HealthInterval > 0, "database.health_interval (DB_HEALTH_INTERVAL)", "must be positive")
+
+	check(oneOf(c.Kafka.Bus, "kafka", "memory"), "kafka.bus (EVENT_BUS)", "must be kafka or memory, got %q", c.Kafka.Bus)
+	if c.Kafka.Bus == "kafka" {
+		check(isHostPort(c.Kafka.Broker), "kafka.broker (KAFKA_BROKER)", "must be host:port, got %q", c.Kafka.Broker)
+	}
+	check(c.Kafka.Topic != "", "kafka.topic (KAFKA_TOPIC)", "is required")
+	check(c.Kafka.BatchSize > 0, "kafka.batch_size (KAFKA_BATCH_SIZE)", "must be positive")
+	check(c.Kafka.StartupTimeout >= 0, "kafka.startup_timeout (KAFKA_STARTUP_TIMEOUT)", "must not be negative (0 skips the check)")
+
+	check(c.Queue.BufferSize > 0, "queue.buffer_size (CLICK_QUEUE_SIZE)", "must be positive")
+	check(c.Queue.BatchSize > 0 && c.Queue.BatchSize <= c.Queue.BufferSize,
+		"queue.batch_size (CLICK_BATCH_SIZE)", "must be between 1 and buffer_size (%d)", c.Queue.BufferSize)
+	check(c.Queue.FlushInterval > 0, "queue.flush_interval (CLICK_FLUSH_INTERVAL)", "must be positive")
+
+	for _, proxy := range c.Middleware.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "middleware.trusted_proxies (TRUSTED_PROXIES)", "%q is not an IP or CIDR", proxy)
+	}
+	for _, allowed := range c.Middleware.AdminAllowedIPs {
+		_, _, cidrErr := net.ParseCIDR(allowed)
+		check(cidrErr == nil || net.ParseIP(allowed) != nil, "middleware.admin_allowed_ips (ADMIN_ALLOWED_IPS)", "%q is not an IP or CIDR", allowed)
+	}
+	for _, origin := range c.Middleware.CORSAllowedOrigins {
+		check(origin == "*" || isHTTPURL(origin), "middleware.cors_allowed_origins (CORS_ALLOWED_ORIGINS)",
+			"%q must be * or an origin such as https://app.example.com", origin)
+	}
+
+	check(c.Middleware.MaxBodyBytes > 0, "middleware.max_body_bytes (MAX_BODY_BYTES)", "must be positive")
+	check(c.Middleware.MaxStreamBytes > 0, "middleware.max_stream_bytes (MAX_STREAM_BYTES)", "must be positive")
+	check(c.Middleware.MaxUserAgentLength > 0, "middleware.max_user_agent_length (MAX_USER_AGENT_LENGTH)", "must be positive")
+
+	check(c.Fraud.MaxClicksPerMinute > 0, "fraud.max_clicks_per_minute (FRAUD_MAX_CLICKS_PER_MINUTE)", "must be positive")
+	check(c.Fraud.MaxAgentsPerIP > 0, "fraud.max_agents_per_ip (FRAUD_MAX_AGENTS_PER_IP)", "must be positive")
+
+	c.validateFeatures(report)
+}
+
+func (c Config) validateTLS(report *ValidationError) {
+	t := c.Server.TLS
+	check := report.Check
+
+	check((t.CertFile == "") == (t.KeyFile == ""), "server.tls.cert_file, server.tls.key_file (TLS_CERT_FILE, TLS_KEY_FILE)",
+		"must be set together")
+	check(t.CertFile == "" || len(t.ACMEDomains) == 0, "server.tls.acme_domains (TLS_ACME_DOMAINS)",
+		"cannot be combined with cert_file; use either certificate files or ACME")
+	for _, file := range []string{t.CertFile, t.KeyFile} {
+		if file != "" {
+			_, err := os.Stat(file)
+			check(err == nil, "server.tls (TLS_CERT_FILE, TLS_KEY_FILE)", "cannot read %s: %v", file, err)
+		}
+	}
+	if len(t.ACMEDomains) > 0 {
+		check(t.ACMECacheDir != "", "server.tls.acme_cache_dir (TLS_ACME_CACHE_DIR)",
+			"is required with ACME so certificates survive restarts instead of hitting rate limits")
+	}
+	if t.ClientCAFile != "" {
+		check(t.Enabled(), "server.tls.client_ca_file (TLS_CLIENT_CA_FILE)", "requires TLS to be enabled")
+		_, err := os.Stat(t.ClientCAFile)
+		check(err == nil, "server.tls.client_ca_file (TLS_CLIENT_CA_FILE)", "cannot read %s: %v", t.ClientCAFile, err)
+	}
+	check(len(t.ClientNames) == 0 || t.ClientCAFile != "", "server.tls.client_names (TLS_CLIENT_NAMES)", "requires client_ca_file")
+	if t.RedirectPort != 0 {
+		check(t.Enabled(), "server.tls.redirect_port (TLS_REDIRECT_PORT)", "requires TLS to be enabled")
+		check(t.RedirectPort > 0 && t.RedirectPort <= 65535 && t.RedirectPort != c.Server.Port,
+			"server.tls.redirect_port (TLS_REDIRECT_PORT)", "must be a port between 1 and 65535 other than server.port, got %d", t.RedirectPort)
+	}
+}
+
+// validateFeatures checks the settings of the optional features and the
+// options that cannot be combined.
+func (c Config) validateFeatures(report *ValidationError) {
+	check := report.Check
+
+	check(c.Middleware.CompressionLevel == -1 || (c.Middleware.CompressionLevel >= 1 && c.Middleware.CompressionLevel <= 9),
+		"middleware.compression_level (COMPRESSION_LEVEL)", "must be between 1 and 9, or -1 for the default, got %d", c.Middleware.CompressionLevel)
+	check(c.Logging.SampleFirst >= 0, "logging.sample_first (LOG_SAMPLE_FIRST)", "must not be negative")
+	check(c.Logging.SampleThereafter >= 0, "logging.sample_thereafter (LOG_SAMPLE_THEREAFTER)", "must not be negative (0 drops the rest of the period)")
+	check(c.Logging.SamplePeriod >= 0, "logging.sample_period (LOG_SAMPLE_PERIOD)", "must not be negative (0 disables sampling)")
+
+	check(c.Breaker.Failures > 0, "breaker.failures (BREAKER_FAILURES)", "must be positive")
+	check(c.Breaker.OpenTimeout > 0, "breaker.open_timeout (BREAKER_OPEN_TIMEOUT)", "must be positive")
+	check(c.Breaker.Probes > 0, "breaker.probes (BREAKER_PROBES)", "must be positive")
+
+	if c.Cache.RedisURL != "" {
+		u, err := url.Parse(c.Cache.RedisURL)
+		check(err == nil && (u.Scheme == "redis" || u.Scheme == "rediss") && u.Host != "", "cache.redis_url (REDIS_URL)",
+			"must look like redis://host:6379/0, got %q", c.Cache.RedisURL)
+	}
+	check(c.Cache.AdTTL >= 0, "cache.ad_ttl (AD_CACHE_TTL)", "must not be negative")
+	check(c.Cache.AdsMaxAge >= 0, "cache.ads_max_age (ADS_MAX_AGE)", "must not be negative")
+	check(c.Cache.AnalyticsTTL >= 0, "cache.analytics_ttl (ANALYTICS_CACHE_TTL)", "must not be negative (0 disables caching)")
+	check(c.Cache.AnalyticsStale >= 0, "cache.analytics_stale (ANALYTICS_CACHE_STALE)", "must not be negative")
+
+	check(oneOf(c.Fraud.BotFilterMode, "drop", "flag"), "fraud.bot_filter_mode (BOT_FILTER_MODE)", "must be drop or flag, got %q", c.Fraud.BotFilterMode)
+	check(c.Fraud.Threshold > 0, "fraud.threshold (FRAUD_THRESHOLD)", "must be positive")
+	for _, weight := range []struct {
+		setting string
+		value   float64
+	}{
+		{"fraud.click_rate_weight (FRAUD_CLICK_RATE_WEIGHT)", c.Fraud.ClickRateWeight},
+		{"fraud.ua_rotation_weight (FRAUD_UA_ROTATION_WEIGHT)", c.Fraud.UARotationWeight},
+		{"fraud.missing_ua_weight (FRAUD_MISSING_UA_WEIGHT)", c.Fraud.MissingUAWeight},
+		{"fraud.datacenter_weight (FRAUD_DATACENTER_WEIGHT)", c.Fraud.DatacenterWeight},
+	} {
+		check(weight.value >= 0, weight.setting, "must not be negative")
+	}
+	for _, cidr := range c.Fraud.DatacenterCIDRs {
+		_, _, err := net.ParseCIDR(cidr)
+		check(err == nil, "fraud.datacenter_cidrs (FRAUD_DATACENTER_CIDRS)", "%q is not a CIDR", cidr)
+	}
+	check(c.Fraud.BlocklistRefresh > 0, "fraud.blocklist_refresh_interval (BLOCKLIST_REFRESH_INTERVAL)", "must be positive")
+
+	t := c.Tracking
+	check(oneOf(t.TimestampPolicy, "clamp", "reject"), "tracking.timestamp_policy (TIMESTAMP_POLICY)", "must be clamp or reject, got %q", t.TimestampPolicy)
+	check(t.TimestampMaxAge >= 0, "tracking.timestamp_max_age (TIMESTAMP_MAX_AGE)", "must not be negative (0 accepts any age)")
+	check(t.TimestampMaxSkew >= 0, "tracking.timestamp_max_skew (TIMESTAMP_MAX_SKEW)", "must not be negative")
+	check(t.LinkTTL > 0, "tracking.link_ttl (LINK_TTL)", "must be positive")
+	check(t.SessionTimeout > 0, "tracking.session_timeout (SESSION_TIMEOUT)", "must be positive")
+	check(t.AttributionClickWindow > 0, "tracking.attribution_click_window (ATTRIBUTION_CLICK_WINDOW)", "must be positive")
+	check(t.AttributionViewWindow >= 0, "tracking.attribution_view_window (ATTRIBUTION_VIEW_WINDOW)", "must not be negative")
+	check(t.TCFVendorID >= 0, "tracking.tcf_vendor_id (TCF_VENDOR_ID)", "must not be negative (0 skips the vendor check)")
+	check(t.PacingBurst > 0, "tracking.pacing_burst (PACING_BURST)", "must be positive")
+	for _, interval := range []struct {
+		setting string
+		value   time.Duration
+	}{
+		{"tracking.sessionize_interval (SESSIONIZE_INTERVAL)", t.SessionizeInterval},
+		{"tracking.consent_refresh_interval (CONSENT_REFRESH_INTERVAL)", t.ConsentRefreshInterval},
+		{"tracking.budget_sync_interval (BUDGET_SYNC_INTERVAL)", t.BudgetSyncInterval},
+		{"tracking.experiment_refresh_interval (EXPERIMENT_REFRESH_INTERVAL)", t.ExperimentRefreshInterval},
+		{"tracking.placement_refresh_interval (PLACEMENT_REFRESH_INTERVAL)", t.PlacementRefreshInterval},
+	} {
+		check(interval.value > 0, interval.setting, "must be positive")
+	}
+
+	check(c.Viewability.MinPercent > 0 && c.Viewability.MinPercent <= 100, "viewability.min_percent (VIEWABILITY_MIN_PERCENT)",
+		"must be above 0 and at most 100, got %g", c.Viewability.MinPercent)
+	check(c.Viewability.DisplayMS >= 0, "viewability.display_ms (VIEWABILITY_DISPLAY_MS)", "must not be negative")
+	check(c.Viewability.VideoMS >= 0, "viewability.video_ms (VIEWABILITY_VIDEO_MS)", "must not be negative")
+
+	// Secrets a feature cannot run without
+	p := c.Privacy
+	check(oneOf(p.IPStorageMode, "raw", "truncate", "hash"), "privacy.ip_storage_mode (IP_STORAGE_MODE)", "must be raw, truncate or hash, got %q", p.IPStorageMode)
+	check(p.IPStorageMode != "hash" || p.IPHashSalt != "", "privacy.ip_hash_salt (IP_HASH_SALT)", "is required when ip_storage_mode is hash")
+	check(p.ExportLinkTTL > 0, "privacy.export_link_ttl (DSAR_LINK_TTL)", "must be positive")
+	if c.Server.Mode == "release" {
+		check(c.Server.AdminToken == "" || len(c.Server.AdminToken) >= 16, "server.admin_token (ADMIN_TOKEN)",
+			"must be at least 16 characters in release mode (or empty to disable the admin API)")
+	}
+
+	check(c.Capture.Duration > 0, "capture.duration (CAPTURE_DURATION)", "must be positive")
+	check(c.Capture.MaxSamples > 0, "capture.max_samples (CAPTURE_MAX_SAMPLES)", "must be positive")
+	check(c.Capture.StormMinPerMinute > 0, "capture.storm_min_per_minute (CLICK_STORM_MIN_PER_MINUTE)", "must be positive")
+	check(c.Capture.StormFactor > 1, "capture.storm_factor (CLICK_STORM_FACTOR)", "must be above 1, got %g", c.Capture.StormFactor)
+
+	if c.Jobs.LeaderElection {
+		check(c.Jobs.LeaderElectionLease != "", "jobs.leader_election_lease (LEADER_ELECTION_LEASE)", "is required with leader election")
+		check(c.Jobs.LeaderElectionDuration > 0, "jobs.leader_election_duration (LEADER_ELECTION_DURATION)", "must be positive")
+	}
+
+	check(c.Retention.BatchSize > 0, "retention.batch_size (RETENTION_BATCH_SIZE)", "must be positive")
+	check(c.Retention.Interval > 0, "retention.interval (RETENTION_INTERVAL)", "must be positive")
+
+	for _, store := range []struct {
+		setting string
+		value   string
+	}{
+		{"archive.url (ARCHIVE_URL)", c.Archive.URL},
+		{"archive.export_store_url (EXPORT_STORE_URL)", c.Archive.ExportStoreURL},
+	} {
+		if store.value == "" {
+			continue
+		}
+		u, err := url.Parse(store.value)
+		check(err == nil && oneOf(u.Scheme, "s3", "gs", "file"), store.setting,
+			"must be s3://bucket/prefix, gs://bucket/prefix or file:///path, got %q", store.value)
+		if err == nil && (u.Scheme == "s3" || u.Scheme == "gs") {
+			check(GetEnv("ARCHIVE_ACCESS_KEY", "") != "" && GetEnv("ARCHIVE_SECRET_KEY", "") != "",
+				"ARCHIVE_ACCESS_KEY, ARCHIVE_SECRET_KEY", "are required for %s stores", u.Scheme)
+		}
+	}
+	check(c.Archive.ExportStoreURL != "" || c.Archive.URL != "" || c.Archive.ExportDir != "", "archive.export_dir (EXPORT_DIR)",
+		"is required without export_store_url or an archive")
+
+	check(c.Reports.MaxAttempts > 0, "reports.max_attempts (REPORT_MAX_ATTEMPTS)", "must be positive")
+	check(c.Reports.RetryBackoff > 0, "reports.retry_backoff (REPORT_RETRY_BACKOFF)", "must be positive")
+	check(c.Reports.SMTPAddr == "" || isHostPort(c.Reports.SMTPAddr), "reports.smtp_addr (SMTP_ADDR)", "must be host:port, got %q", c.Reports.SMTPAddr)
+
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts (WEBHOOK_MAX_ATTEMPTS)", "must be positive")
+	check(c.Webhooks.RetryBackoff > 0, "webhooks.retry_backoff (WEBHOOK_RETRY_BACKOFF)", "must be positive")
+	check(c.Webhooks.RefreshInterval > 0, "webhooks.refresh_interval (WEBHOOK_REFRESH_INTERVAL)", "must be positive")
+	check(c.Webhooks.AlertEvalInterval > 0, "webhooks.alert_eval_interval (ALERT_EVAL_INTERVAL)", "must be positive")
+
+	i := c.Integrations
+	check(i.BatchSize > 0, "integrations.batch_size (FORWARDER_BATCH_SIZE)", "must be positive")
+	check(i.FlushInterval > 0, "integrations.flush_interval (FORWARDER_FLUSH_INTERVAL)", "must be positive")
+	check(i.MaxAttempts > 0, "integrations.max_attempts (FORWARDER_MAX_ATTEMPTS)", "must be positive")
+	check(i.RetryBackoff > 0, "integrations.retry_backoff (FORWARDER_RETRY_BACKOFF)", "must be positive")
+	check(i.RefreshInterval > 0, "integrations.refresh_interval (INTEGRATION_REFRESH_INTERVAL)", "must be positive")
+	check(isHTTPURL(i.SegmentURL), "integrations.segment_url (SEGMENT_API_URL)", "must be an http(s) URL, got %q", i.SegmentURL)
+	check(isHTTPURL(i.GA4URL), "integrations.ga4_url (GA4_API_URL)", "must be an http(s) URL, got %q", i.GA4URL)
+
+	// Options that cannot be combined
+	sinks := c.Sinks
+	check(oneOf(sinks.AnalyticsBackend, "postgres", "clickhouse"), "sinks.analytics_backend (ANALYTICS_BACKEND)",
+		"must be postgres or clickhouse, got %q", sinks.AnalyticsBackend)
+	check(sinks.AnalyticsBackend != "clickhouse" || sinks.ClickHouse.URL != "", "sinks.analytics_backend (ANALYTICS_BACKEND)",
+		"clickhouse requires sinks.clickhouse.url (CLICKHOUSE_URL)")
+	check(sinks.ImpressionLookback > 0, "sinks.impression_lookback (SINK_IMPRESSION_LOOKBACK)", "must be positive")
+	if sinks.ClickHouse.URL != "" {
+		check(isHTTPURL(sinks.ClickHouse.URL), "sinks.clickhouse.url (CLICKHOUSE_URL)", "must be the http(s) interface URL, got %q", sinks.ClickHouse.URL)
+	}
+	if sinks.BigQuery.Dataset != "" {
+		check(sinks.BigQuery.Project != "", "sinks.bigquery.project (BIGQUERY_PROJECT)", "is required with sinks.bigquery.dataset (BIGQUERY_DATASET)")
+		check(isHTTPURL(sinks.BigQuery.URL), "sinks.bigquery.url (BIGQUERY_URL)", "must be an http(s) URL, got %q", sinks.BigQuery.URL)
+	}
+	for _, sink := range []struct {
+		name      string
+		groupID   string
+		batchSize int
+		interval  time.Duration
+	}{
+		{"clickhouse", sinks.ClickHouse.GroupID, sinks.ClickHouse.BatchSize, sinks.ClickHouse.FlushInterval},
+		{"bigquery", sinks.BigQuery.GroupID, sinks.BigQuery.BatchSize, sinks.BigQuery.FlushInterval},
+		{"realtime", sinks.Realtime.GroupID, sinks.Realtime.BatchSize, sinks.Realtime.FlushInterval},
+	} {
+		check(sink.groupID != "", "sinks."+sink.name+".group_id", "is required")
+		check(sink.batchSize > 0, "sinks."+sink.name+".batch_size", "must be positive")
+		check(sink.interval > 0, "sinks."+sink.name+".flush_interval", "must be positive")
+	}
+	check(sinks.Realtime.LeaderboardMinImpressions >= 0, "sinks.realtime.leaderboard_min_impressions (LEADERBOARD_MIN_IMPRESSIONS)", "must not be negative")
+
+	r := c.Rollups
+	check(r.Interval > 0, "rollups.interval (ROLLUP_INTERVAL)", "must be positive")
+	check(r.Lookback >= 0, "rollups.lookback (ROLLUP_LOOKBACK)", "must not be negative")
+	check(r.MinWindow > 0, "rollups.min_window (ROLLUP_MIN_WINDOW)", "must be positive")
+	for _, detector := range r.AnomalyDetectors {
+		check(oneOf(detector, "ewma", "seasonal_naive"), "rollups.anomaly_detectors (ANOMALY_DETECTORS)",
+			"must list ewma or seasonal_naive, got %q", detector)
+	}
+	if len(r.AnomalyDetectors) > 0 {
+		check(r.AnomalyInterval > 0, "rollups.anomaly_interval (ANOMALY_INTERVAL)", "must be positive")
+		check(r.AnomalyRecent > 0 && r.AnomalyRecent < r.AnomalyLookback, "rollups.anomaly_recent (ANOMALY_RECENT)",
+			"must be positive and shorter than anomaly_lookback (%s)", r.AnomalyLookback)
+	}
+
+	if c.Kafka.Bus == "memory" {
+		for _, consumer := range []struct{ setting, value string }{
+			{"sinks.clickhouse.url (CLICKHOUSE_URL)", sinks.ClickHouse.URL},
+			{"replication.standby_broker (STANDBY_KAFKA_BROKER)", c.Replication.StandbyBroker},
+		} {
+			check(consumer.value == "", consumer.setting, "consumes the Kafka topic and cannot be used with kafka.bus (EVENT_BUS) memory")
+		}
+	}
+	if a := c.AccessLog; a.Topic != "" {
+		check(c.Kafka.Bus == "kafka", "access_log.topic (ACCESS_LOG_TOPIC)", "publishes to Kafka and cannot be used with kafka.bus (EVENT_BUS) memory")
+		check(a.Topic != c.Kafka.Topic, "access_log.topic (ACCESS_LOG_TOPIC)", "must differ from kafka.topic (KAFKA_TOPIC)")
+		for _, group := range a.Groups {
+			check(oneOf(group, "api", "admin", "privacy", "internal", "public"), "access_log.groups (ACCESS_LOG_GROUPS)",
+				"must list api, admin, privacy, internal or public, got %q", group)
+		}
+	}
+	if standby := c.Replication.StandbyBroker; standby != "" {
+		check(isHostPort(standby), "replication.standby_broker (STANDBY_KAFKA_BROKER)", "must be host:port, got %q", standby)
+		check(standby != c.Kafka.Broker, "replication.standby_broker (STANDBY_KAFKA_BROKER)", "must differ from kafka.broker (KAFKA_BROKER)")
+		check(c.Replication.Region != "", "replication.region (REGION)", "is required with a standby broker")
+		check(oneOf(c.Replication.Role, "primary", "standby"), "replication.role (REGION_ROLE)", "must be primary or standby, got %q", c.Replication.Role)
+		check(c.Replication.GroupID != "", "replication.group_id (REPLICATION_GROUP_ID)", "is required with a standby broker")
+	}
+	if c.Edge.SigningKey != "" && c.Kafka.Bus == "kafka" {
+		check(c.Edge.Topic != "" && c.Edge.Topic != c.Kafka.Topic, "edge.topic (EDGE_KAFKA_TOPIC)", "must be set and differ from kafka.topic (KAFKA_TOPIC)")
+		check(c.Edge.GroupID != "", "edge.group_id (EDGE_GROUP_ID)", "is required with an edge signing key")
+	}
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, candidate := range allowed {
+		if value == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isHostPort(address string) bool {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		return false
+	}
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
+
+// databaseURLProblem catches DSNs the driver would only reject, or worse
+// misinterpret, at connect time.
+func databaseURLProblem(driver, dsn string) string {
+	switch driver {
+	case "postgres":
+		if strings.Contains(dsn, "://") {
+			u, err := url.Parse(dsn)
+			if err != nil {
+				return "is not a valid URL: " + err.Error()
+			}
+			if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+				return fmt.Sprintf("has scheme %q, expected postgres://", u.Scheme)
+			}
+		} else if !strings.Contains(dsn, "=") {
+			return "must be a postgres:// URL or key=value DSN"
+		}
+	case "mysql":
+		if strings.Contains(dsn, "://") {
+			return "must be a MySQL DSN such as user:password@tcp(host:3306)/adtracker?parseTime=true&loc=UTC, not a URL"
+		}
+		if !strings.Contains(dsn, "parseTime=true") || !strings.Contains(dsn, "loc=UTC") {
+			return "must set parseTime=true and loc=UTC so timestamps scan into time.Time in UTC"
+		}
+	}
+	return ""
+}
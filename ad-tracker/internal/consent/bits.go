@@ -0,0 +1,82 @@
+package consent
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var (
+	errTruncated = errors.New("consent string truncated")
+	errOverflow  = errors.New("consent string integer too large")
+)
+
+// bitReader reads big-endian bit fields from a base64url segment.
+type bitReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func newBitReader(segment string) (*bitReader, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return nil, err
+	}
+	return &bitReader{data: data}, nil
+}
+
+func (r *bitReader) bit() bool {
+	if r.err != nil {
+		return false
+	}
+	if r.pos >= len(r.data)*8 {
+		r.err = errTruncated
+		return false
+	}
+	set := r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0
+	r.pos++
+	return set
+}
+
+func (r *bitReader) uint(bits int) int {
+	value := 0
+	for i := 0; i < bits; i++ {
+		value <<= 1
+		if r.bit() {
+			value |= 1
+		}
+	}
+	return value
+}
+
+func (r *bitReader) skip(bits int) {
+	for i := 0; i < bits && r.err == nil; i++ {
+		r.bit()
+	}
+}
+
+// fibonacci reads a Fibonacci-coded integer as used by GPP: each set bit i
+// adds F(i+2) and the code ends at the first pair of consecutive ones.
+// Codes longer than 44 bits, past F(45), are rejected before int overflow.
+func (r *bitReader) fibonacci() int {
+	value := 0
+	a, b := 1, 2
+	previous := false
+	for i := 0; r.err == nil; i++ {
+		if i > 44 {
+			r.err = errOverflow
+			break
+		}
+		set := r.bit()
+		if set && previous {
+			return value
+		}
+		if set {
+			value += a
+		}
+		previous = set
+		a, b = b, a+b
+	}
+	return value
+}
@@ -0,0 +1,211 @@
+// Package consent interprets IAB TCF v2 and GPP consent signals sent on
+// tracking requests.
+package consent
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	Granted       = "granted"
+	Denied        = "denied"
+	NotApplicable = "not_applicable"
+	Missing       = "missing"
+	Invalid       = "invalid"
+	Unknown       = "unknown"
+)
+
+// gppTCFEUSection is the GPP section id carrying a TCF EU v2 string.
+const gppTCFEUSection = 2
+
+// maxGPPSection bounds the section ids a GPP header may list. Assigned ids
+// are far below it; the bound keeps a crafted range from expanding into
+// billions of ids.
+const maxGPPSection = 1024
+
+// Signals are the consent parameters as received, using the OpenRTB names.
+type Signals struct {
+	GDPR        string // "1" when GDPR applies, "0" when not, "" if unknown
+	GDPRConsent string // TCF v2 TC string
+	GPP         string
+	GPPSID      string // comma separated applicable GPP section ids
+}
+
+// Present reports whether the request carried any consent signal.
+func (s Signals) Present() bool {
+	return s.GDPR != "" || s.GDPRConsent != "" || s.GPP != "" || s.GPPSID != ""
+}
+
+// Evaluator decides the consent state for a request. VendorID, when set,
+// additionally requires consent for our vendor id in the TC string.
+type Evaluator struct {
+	VendorID int
+}
+
+// Permits reports whether identifiers may be stored for a state.
+func Permits(state string) bool {
+	return state == Granted || state == NotApplicable
+}
+
+// State resolves the signals to one of the consent states. Only TCF EU is
+// interpreted; other GPP sections are recorded but treated as not
+// applicable.
+func (e Evaluator) State(signals Signals) string {
+	tcString := signals.GDPRConsent
+	applies := signals.GDPR == "1"
+
+	if signals.GPP != "" {
+		sections, err := gppSections(signals.GPP)
+		if err != nil {
+			return Invalid
+		}
+		if signals.GPPSID != "" {
+			applies = applies || containsSection(signals.GPPSID, gppTCFEUSection)
+		} else if _, ok := sections[gppTCFEUSection]; ok {
+			applies = true
+		}
+		if section, ok := sections[gppTCFEUSection]; ok && tcString == "" {
+			tcString = section
+		}
+	}
+
+	switch {
+	case tcString != "":
+		return e.evaluateTCString(tcString)
+	case applies:
+		return Missing
+	case signals.GDPR == "0" || signals.GPP != "":
+		return NotApplicable
+	default:
+		return Unknown
+	}
+}
+
+// evaluateTCString checks purpose 1 (store and access information on a
+// device) and, if configured, our vendor consent in the core segment.
+func (e Evaluator) evaluateTCString(tcString string) string {
+	core, _, _ := strings.Cut(tcString, ".")
+	r, err := newBitReader(core)
+	if err != nil {
+		return Invalid
+	}
+
+	if version := r.uint(6); version != 2 {
+		return Invalid
+	}
+	// Created, LastUpdated, CmpId, CmpVersion, ConsentScreen,
+	// ConsentLanguage, VendorListVersion, TcfPolicyVersion,
+	// IsServiceSpecific, UseNonStandardTexts, SpecialFeatureOptIns
+	r.skip(36 + 36 + 12 + 12 + 6 + 12 + 12 + 6 + 1 + 1 + 12)
+
+	purposes := r.uint(24)
+	purposeOne := purposes&(1<<23) != 0
+
+	// PurposesLITransparency, PurposeOneTreatment, PublisherCC
+	r.skip(24 + 1 + 12)
+
+	vendorOK := true
+	if e.VendorID > 0 {
+		vendorOK = vendorConsent(r, e.VendorID)
+	}
+
+	if r.err != nil {
+		return Invalid
+	}
+	if purposeOne && vendorOK {
+		return Granted
+	}
+	return Denied
+}
+
+// vendorConsent reads the vendor consent section, bitfield or range encoded.
+func vendorConsent(r *bitReader, vendorID int) bool {
+	maxVendorID := r.uint(16)
+	if r.bit() {
+		entries := r.uint(12)
+		found := false
+		for i := 0; i < entries; i++ {
+			isRange := r.bit()
+			start := r.uint(16)
+			end := start
+			if isRange {
+				end = r.uint(16)
+			}
+			if vendorID >= start && vendorID <= end {
+				found = true
+			}
+		}
+		return found
+	}
+
+	if vendorID > maxVendorID {
+		return false
+	}
+	r.skip(vendorID - 1)
+	consented := r.bit()
+	r.skip(maxVendorID - vendorID)
+	return consented
+}
+
+// gppSections decodes the GPP header and maps section ids to their encoded
+// section strings. The header must list exactly one id per section, so
+// expansion stops as soon as it lists more.
+func gppSections(gpp string) (map[int]string, error) {
+	parts := strings.Split(gpp, "~")
+	r, err := newBitReader(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	if headerType := r.uint(6); headerType != 3 {
+		return nil, errors.New("not a GPP header")
+	}
+	r.skip(6) // version
+
+	var ids []int
+	last := 0
+	entries := r.uint(12)
+	for i := 0; i < entries; i++ {
+		isRange := r.bit()
+		start := last + r.fibonacci()
+		end := start
+		if isRange {
+			end = start + r.fibonacci()
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		if start < 1 || end < start || end > maxGPPSection {
+			return nil, errors.New("GPP section id out of range")
+		}
+		for id := start; id <= end; id++ {
+			if len(ids) == len(parts)-1 {
+				return nil, errors.New("GPP section count mismatch")
+			}
+			ids = append(ids, id)
+		}
+		last = end
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(ids) != len(parts)-1 {
+		return nil, errors.New("GPP section count mismatch")
+	}
+
+	sections := make(map[int]string, len(ids))
+	for i, id := range ids {
+		sections[id] = parts[i+1]
+	}
+	return sections, nil
+}
+
+func containsSection(sids string, section int) bool {
+	for _, sid := range strings.Split(sids, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(sid)); err == nil && id == section {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,260 @@
+package consent
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// bitWriter builds consent strings bit by bit.
+type bitWriter struct {
+	bits []bool
+}
+
+func (w *bitWriter) uint(value, bits int) *bitWriter {
+	for i := bits - 1; i >= 0; i-- {
+		w.bits = append(w.bits, value&(1<<i) != 0)
+	}
+	return w
+}
+
+func (w *bitWriter) bit(set bool) *bitWriter {
+	w.bits = append(w.bits, set)
+	return w
+}
+
+// fibonacci writes value's Zeckendorf representation, lowest term first,
+// followed by the terminating one.
+func (w *bitWriter) fibonacci(value int) *bitWriter {
+	fibs := []int{1, 2}
+	for fibs[len(fibs)-1] <= value {
+		fibs = append(fibs, fibs[len(fibs)-1]+fibs[len(fibs)-2])
+	}
+	code := make([]bool, len(fibs))
+	last := 0
+	for i := len(fibs) - 1; i >= 0; i-- {
+		if fibs[i] <= value {
+			value -= fibs[i]
+			code[i] = true
+			if last < i {
+				last = i
+			}
+		}
+	}
+	w.bits = append(w.bits, code[:last+1]...)
+	w.bits = append(w.bits, true)
+	return w
+}
+
+func (w *bitWriter) String() string {
+	data := make([]byte, (len(w.bits)+7)/8)
+	for i, set := range w.bits {
+		if set {
+			data[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// tcString builds a TCF v2 core segment with the given purpose 1 consent,
+// followed by the vendor section vendors writes.
+func tcString(version int, purposeOne bool, vendors func(*bitWriter)) string {
+	w := (&bitWriter{}).uint(version, 6)
+	w.uint(0, 36+36+12+12+6+12+12+6+1+1+12)
+	purposes := 0
+	if purposeOne {
+		purposes = 1 << 23
+	}
+	w.uint(purposes, 24)
+	w.uint(0, 24+1+12)
+	if vendors != nil {
+		vendors(w)
+	} else {
+		w.uint(0, 16).bit(false)
+	}
+	return w.String()
+}
+
+// vendorBitfield consents the given vendors of maxVendorID.
+func vendorBitfield(maxVendorID int, consented ...int) func(*bitWriter) {
+	return func(w *bitWriter) {
+		w.uint(maxVendorID, 16).bit(false)
+		for id := 1; id <= maxVendorID; id++ {
+			set := false
+			for _, c := range consented {
+				set = set || c == id
+			}
+			w.bit(set)
+		}
+	}
+}
+
+// vendorRanges consents the vendors in the given [start, end] ranges; a
+// range with start == end is written as a single id.
+func vendorRanges(maxVendorID int, ranges ...[2]int) func(*bitWriter) {
+	return func(w *bitWriter) {
+		w.uint(maxVendorID, 16).bit(true).uint(len(ranges), 12)
+		for _, r := range ranges {
+			if r[0] == r[1] {
+				w.bit(false).uint(r[0], 16)
+				continue
+			}
+			w.bit(true).uint(r[0], 16).uint(r[1], 16)
+		}
+	}
+}
+
+// gppHeader builds a GPP header listing the given section ids, encoding
+// runs of consecutive ids as ranges.
+func gppHeader(ids ...int) string {
+	type entry struct{ start, end int }
+	var entries []entry
+	for _, id := range ids {
+		if n := len(entries); n > 0 && entries[n-1].end == id-1 {
+			entries[n-1].end = id
+			continue
+		}
+		entries = append(entries, entry{id, id})
+	}
+
+	w := (&bitWriter{}).uint(3, 6).uint(1, 6).uint(len(entries), 12)
+	last := 0
+	for _, e := range entries {
+		w.bit(e.end > e.start).fibonacci(e.start - last)
+		if e.end > e.start {
+			w.fibonacci(e.end - e.start)
+		}
+		last = e.end
+	}
+	return w.String()
+}
+
+func TestStateTCF(t *testing.T) {
+	granted, denied := tcString(2, true, nil), tcString(2, false, nil)
+	tests := []struct {
+		name    string
+		signals Signals
+		want    string
+	}{
+		{"purpose one granted", Signals{GDPR: "1", GDPRConsent: granted}, Granted},
+		{"purpose one denied", Signals{GDPR: "1", GDPRConsent: denied}, Denied},
+		{"later segments ignored", Signals{GDPR: "1", GDPRConsent: granted + ".YAAAAAAAAAA"}, Granted},
+		{"wrong version", Signals{GDPR: "1", GDPRConsent: tcString(1, true, nil)}, Invalid},
+		{"truncated", Signals{GDPR: "1", GDPRConsent: granted[:20]}, Invalid},
+		{"not base64", Signals{GDPR: "1", GDPRConsent: "not a tc string!"}, Invalid},
+		{"applies without string", Signals{GDPR: "1"}, Missing},
+		{"does not apply", Signals{GDPR: "0"}, NotApplicable},
+		{"no signals", Signals{}, Unknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Evaluator{}).State(tt.signals); got != tt.want {
+				t.Errorf("State(%+v) = %q, want %q", tt.signals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStateVendorConsent(t *testing.T) {
+	const vendorID = 42
+	tests := []struct {
+		name    string
+		vendors func(*bitWriter)
+		want    string
+	}{
+		{"bitfield consented", vendorBitfield(50, 1, vendorID), Granted},
+		{"bitfield not consented", vendorBitfield(50, 1, 43), Denied},
+		{"bitfield last vendor", vendorBitfield(vendorID, vendorID), Granted},
+		{"bitfield below our id", vendorBitfield(10, 1, 2, 3), Denied},
+		{"bitfield truncated", func(w *bitWriter) { w.uint(50, 16).bit(false).uint(0, 10) }, Invalid},
+		{"range covering us", vendorRanges(100, [2]int{1, 5}, [2]int{40, 60}), Granted},
+		{"single id", vendorRanges(100, [2]int{vendorID, vendorID}), Granted},
+		{"ranges missing us", vendorRanges(100, [2]int{1, 41}, [2]int{43, 100}), Denied},
+		{"no ranges", vendorRanges(100), Denied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signals := Signals{GDPR: "1", GDPRConsent: tcString(2, true, tt.vendors)}
+			if got := (Evaluator{VendorID: vendorID}).State(signals); got != tt.want {
+				t.Errorf("State = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Purpose 1 is still required when the vendor consented
+	signals := Signals{GDPR: "1", GDPRConsent: tcString(2, false, vendorBitfield(50, vendorID))}
+	if got := (Evaluator{VendorID: vendorID}).State(signals); got != Denied {
+		t.Errorf("State without purpose one = %q, want %q", got, Denied)
+	}
+}
+
+func TestGPPSections(t *testing.T) {
+	sections, err := gppSections(gppHeader(2, 6, 7, 8) + "~tcf~usnat~usca~usva")
+	if err != nil {
+		t.Fatalf("gppSections: %v", err)
+	}
+	want := map[int]string{2: "tcf", 6: "usnat", 7: "usca", 8: "usva"}
+	if len(sections) != len(want) {
+		t.Fatalf("sections = %v, want %v", sections, want)
+	}
+	for id, section := range want {
+		if sections[id] != section {
+			t.Errorf("section %d = %q, want %q", id, sections[id], section)
+		}
+	}
+}
+
+func TestGPPSectionsMalformed(t *testing.T) {
+	tests := map[string]string{
+		"not base64":         "!!~x",
+		"not a header":       (&bitWriter{}).uint(1, 6).uint(1, 6).uint(0, 12).String(),
+		"truncated header":   gppHeader(2, 6)[:3] + "~x~y",
+		"too few sections":   gppHeader(2, 6) + "~x",
+		"too many sections":  gppHeader(2) + "~x~y",
+		"section id too big": (&bitWriter{}).uint(3, 6).uint(1, 6).uint(1, 12).bit(false).fibonacci(maxGPPSection+1).String() + "~x",
+		// A range of billions of ids over a single section
+		"huge range":  "DBAB4AAAAAAw~x",
+		"wide range":  (&bitWriter{}).uint(3, 6).uint(1, 6).uint(1, 12).bit(true).fibonacci(1).fibonacci(maxGPPSection).String() + "~x",
+		"long number": (&bitWriter{}).uint(3, 6).uint(1, 6).uint(1, 12).bit(false).uint(0, 200).String() + "~x",
+	}
+	for name, gpp := range tests {
+		t.Run(name, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() {
+				_, err := gppSections(gpp)
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				if err == nil {
+					t.Errorf("gppSections(%q) succeeded, want an error", gpp)
+				}
+			case <-time.After(time.Second):
+				t.Fatalf("gppSections(%q) did not return", gpp)
+			}
+		})
+	}
+}
+
+func TestStateGPP(t *testing.T) {
+	tcf := tcString(2, true, nil)
+	tests := []struct {
+		name    string
+		signals Signals
+		want    string
+	}{
+		{"TCF section", Signals{GPP: gppHeader(2) + "~" + tcf}, Granted},
+		{"TCF section denied", Signals{GPP: gppHeader(2, 7) + "~" + tcString(2, false, nil) + "~usca"}, Denied},
+		{"other sections only", Signals{GPP: gppHeader(7) + "~usca"}, NotApplicable},
+		{"TCF applicable but absent", Signals{GPP: gppHeader(7) + "~usca", GPPSID: "2, 7"}, Missing},
+		{"gdpr_consent wins", Signals{GPP: gppHeader(2) + "~" + tcString(2, false, nil), GDPRConsent: tcf}, Granted},
+		{"malformed", Signals{GPP: "DBAB4AAAAAAw~x"}, Invalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Evaluator{}).State(tt.signals); got != tt.want {
+				t.Errorf("State(%+v) = %q, want %q", tt.signals, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/cron"
+)
+
+// at parses a UTC time written as "2006-01-02 15:04".
+func at(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse("2006-01-02 15:04", value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return parsed
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		from string
+		want string
+	}{
+		// Fields
+		{"every minute", "* * * * *", "2026-03-10 10:07", "2026-03-10 10:08"},
+		{"strictly after", "7 10 * * *", "2026-03-10 10:07", "2026-03-11 10:07"},
+		{"list", "30 8,12,18 * * *", "2026-03-10 12:30", "2026-03-10 18:30"},
+		{"range", "0 9-11 * * *", "2026-03-10 11:00", "2026-03-11 09:00"},
+		{"step", "*/15 * * * *", "2026-03-10 10:07", "2026-03-10 10:15"},
+		{"step over range", "0 9-17/4 * * *", "2026-03-10 10:00", "2026-03-10 13:00"},
+		{"step from value", "5/20 * * * *", "2026-03-10 10:26", "2026-03-10 10:45"},
+		{"list of ranges", "0 1-2,22-23 * * *", "2026-03-10 03:00", "2026-03-10 22:00"},
+		{"weekdays", "0 9 * * 1-5", "2026-03-06 10:00", "2026-03-09 09:00"},
+		{"sunday as 7", "0 0 * * 7", "2026-03-02 00:00", "2026-03-08 00:00"},
+		{"weekly", "@weekly", "2026-03-11 12:00", "2026-03-15 00:00"},
+		{"hourly", "@hourly", "2026-03-10 10:00", "2026-03-10 11:00"},
+
+		// Day of month and day of week
+		{"day of month only", "0 0 11 * *", "2026-03-01 00:00", "2026-03-11 00:00"},
+		{"day of week only", "0 0 * * 5", "2026-03-07 00:00", "2026-03-13 00:00"},
+		{"either day, weekday first", "0 0 11 * 5", "2026-03-01 00:00", "2026-03-06 00:00"},
+		{"either day, date first", "0 0 11 * 5", "2026-03-07 00:00", "2026-03-11 00:00"},
+		{"either day, both", "0 0 13 * 5", "2026-02-06 00:00", "2026-02-13 00:00"},
+
+		// Month and year boundaries
+		{"next month", "0 0 1 * *", "2026-01-31 12:00", "2026-02-01 00:00"},
+		{"monthly", "@monthly", "2026-12-15 00:00", "2027-01-01 00:00"},
+		{"skips short months", "0 0 31 * *", "2026-04-01 00:00", "2026-05-31 00:00"},
+		{"last minute of the year", "59 23 31 12 *", "2026-12-31 23:59", "2027-12-31 23:59"},
+		{"into next year", "0 0 1 1 *", "2026-06-01 00:00", "2027-01-01 00:00"},
+		{"month list", "0 12 15 3,9 *", "2026-09-15 12:00", "2027-03-15 12:00"},
+		{"leap day", "0 0 29 2 *", "2026-03-01 00:00", "2028-02-29 00:00"},
+		{"never", "0 0 30 2 *", "2026-03-01 00:00", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.expr, err)
+			}
+			var want time.Time
+			if tt.want != "" {
+				want = at(t, tt.want)
+			}
+			if got := schedule.Next(at(t, tt.from)); !got.Equal(want) {
+				t.Errorf("Next(%s) = %v, want %v", tt.from, got, want)
+			}
+		})
+	}
+}
+
+// TestNextLocation checks that schedules fire in the location of the time
+// they are given.
+func TestNextLocation(t *testing.T) {
+	india := time.FixedZone("IST", 5*60*60+30*60)
+	schedule, err := cron.Parse("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 3, 10, 9, 30, 0, 0, india)
+	want := time.Date(2026, 3, 11, 9, 0, 0, 0, india)
+	if got := schedule.Next(from); !got.Equal(want) || got.Location() != india {
+		t.Errorf("Next(%v) = %v, want %v", from, got, want)
+	}
+
+	// Seconds are dropped before looking for the next minute
+	from = time.Date(2026, 3, 10, 8, 59, 59, 0, india)
+	if got := schedule.Next(from); !got.Equal(time.Date(2026, 3, 10, 9, 0, 0, 0, india)) {
+		t.Errorf("Next(%v) = %v, want 09:00 the same day", from, got)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"@yearly",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 0 *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/-1 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"1,,2 * * * *",
+		"1- * * * *",
+	} {
+		if _, err := cron.Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -1,73 +1,35 @@
 package database
 
 import (
-	"fmt"
 	"time"
 
-	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/config"
 
-	"gorm.io/driver/postgres"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-func SetupDatabase(databaseURL string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
-	fmt.Println("Using DSN:", databaseURL)
-
+func SetupDatabase(driver, databaseURL string, pool PoolConfig, logger *logrus.Logger) (*gorm.DB, error) {
+	dialect, err := dialector(driver, databaseURL, pool)
 	if err != nil {
 		return nil, err
 	}
-
-	// Auto-migrate schemas
-	if err := db.AutoMigrate(&models.Ad{}, &models.ClickEvent{}); err != nil {
+	db, err := gorm.Open(dialect, &gorm.Config{
+		Logger: NewGormLogger(logger, config.GetEnvDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond)),
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	// The schema is managed by internal/migrations (`migrate up`)
+
 	// Set connection pool settings
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	pool.forDriver(driver).Apply(sqlDB)
 
 	return db, nil
 }
-
-func SeedDatabase(db *gorm.DB) error {
-	// Check if we already have ads
-	var count int64
-	db.Model(&models.Ad{}).Count(&count)
-	if count > 0 {
-		return nil
-	}
-
-	// Create sample ads
-	sampleAds := []models.Ad{
-		{
-			ImageURL:  "https://example.com/ad1.jpg",
-			TargetURL: "https://example.com/product1",
-			Title:     "Amazing Product 1",
-			Active:    true,
-		},
-		{
-			ImageURL:  "https://example.com/ad2.jpg",
-			TargetURL: "https://example.com/product2",
-			Title:     "Great Service 2",
-			Active:    true,
-		},
-		{
-			ImageURL:  "https://example.com/ad3.jpg",
-			TargetURL: "https://example.com/product3",
-			Title:     "Special Offer 3",
-			Active:    true,
-		},
-	}
-
-	return db.Create(&sampleAds).Error
-}
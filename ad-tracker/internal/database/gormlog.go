@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	applog "ad-tracking-system/internal/logger"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// gormLogger sends GORM's log through logrus as JSON: failed statements at
+// error, statements slower than slow at warn and the rest at debug, tagged
+// with the request id when the query carries the request context.
+// Statements are logged with placeholders, never bound values, which may
+// hold user ids or IP addresses.
+type gormLogger struct {
+	logger *logrus.Logger
+	slow   time.Duration
+	level  gormlogger.LogLevel
+}
+
+var (
+	_ gormlogger.Interface = (*gormLogger)(nil)
+	_ gorm.ParamsFilter    = (*gormLogger)(nil)
+)
+
+// NewGormLogger logs GORM statements through logger; slow <= 0 disables
+// the slow statement warning.
+func NewGormLogger(logger *logrus.Logger, slow time.Duration) gormlogger.Interface {
+	return &gormLogger{logger: logger, slow: slow, level: gormlogger.Info}
+}
+
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		applog.FromContext(ctx, l.logger).Infof(msg, args...)
+	}
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		applog.FromContext(ctx, l.logger).Warnf(msg, args...)
+	}
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		applog.FromContext(ctx, l.logger).Errorf(msg, args...)
+	}
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	fields := func() logrus.Fields {
+		sql, rows := fc()
+		return logrus.Fields{"sql": sql, "rows": rows, "duration": elapsed}
+	}
+
+	switch {
+	case l.level >= gormlogger.Error && err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		applog.FromContext(ctx, l.logger).WithError(err).WithFields(fields()).Error("Database statement failed")
+	case l.level >= gormlogger.Warn && l.slow > 0 && elapsed > l.slow:
+		applog.FromContext(ctx, l.logger).WithFields(fields()).Warn("Slow database statement")
+	case l.level >= gormlogger.Info && l.logger.IsLevelEnabled(logrus.DebugLevel):
+		applog.FromContext(ctx, l.logger).WithFields(fields()).Debug("Database statement")
+	}
+}
+
+// ParamsFilter drops bound values from logged statements.
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
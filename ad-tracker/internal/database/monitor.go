@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Monitor pings the database periodically so readiness can report an
+// outage. database/sql replaces broken connections on its own; the monitor
+// only tracks and logs the state.
+type Monitor struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+	down   atomic.Bool
+}
+
+func NewMonitor(db *gorm.DB, logger *logrus.Logger) *Monitor {
+	return &Monitor{db: db, logger: logger}
+}
+
+// Healthy reports whether the last ping succeeded.
+func (m *Monitor) Healthy() bool {
+	return !m.down.Load()
+}
+
+// Check pings once and records the result.
+func (m *Monitor) Check(ctx context.Context) error {
+	sqlDB, err := m.db.DB()
+	if err == nil {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		err = sqlDB.PingContext(pingCtx)
+		cancel()
+	}
+
+	wasDown := m.down.Swap(err != nil)
+	switch {
+	case err != nil && !wasDown:
+		m.logger.WithError(err).Error("Database connection lost")
+	case err == nil && wasDown:
+		m.logger.Info("Database connection restored")
+	}
+	if err != nil {
+		metrics.DatabaseUp.Set(0)
+	} else {
+		metrics.DatabaseUp.Set(1)
+	}
+	return err
+}
+
+// Run checks every interval until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		m.Check(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
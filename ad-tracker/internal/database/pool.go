@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/config"
+)
+
+// PoolConfig tunes the connection pool behind GORM's database/sql handle.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout makes the server cancel statements running longer than
+	// this; zero leaves the server default.
+	StatementTimeout time.Duration
+}
+
+// PoolConfigFrom takes the pool settings from the database configuration.
+func PoolConfigFrom(c config.DatabaseConfig) PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:     c.MaxOpenConns,
+		MaxIdleConns:     c.MaxIdleConns,
+		ConnMaxLifetime:  c.ConnMaxLifetime,
+		ConnMaxIdleTime:  c.ConnMaxIdleTime,
+		StatementTimeout: c.StatementTimeout,
+	}
+}
+
+// Apply sets the pool limits on sqlDB.
+func (c PoolConfig) Apply(sqlDB *sql.DB) {
+	sqlDB.SetMaxOpenConns(c.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(c.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(c.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+}
+
+// withStatementTimeout adds statement_timeout as a connection runtime
+// parameter to a URL or keyword/value DSN, unless the DSN already sets one.
+func withStatementTimeout(dsn string, timeout time.Duration) string {
+	if timeout <= 0 || strings.Contains(dsn, "statement_timeout") {
+		return dsn
+	}
+	ms := strconv.FormatInt(timeout.Milliseconds(), 10)
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn
+		}
+		query := u.Query()
+		query.Set("statement_timeout", ms)
+		u.RawQuery = query.Encode()
+		return u.String()
+	}
+	return dsn + " statement_timeout=" + ms
+}
+
+// withMaxExecutionTime is the MySQL counterpart of withStatementTimeout. The
+// driver passes unknown DSN parameters through as session variables;
+// max_execution_time only applies to SELECT statements.
+func withMaxExecutionTime(dsn string, timeout time.Duration) string {
+	if timeout <= 0 || strings.Contains(dsn, "max_execution_time") {
+		return dsn
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "max_execution_time=" + strconv.FormatInt(timeout.Milliseconds(), 10)
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var keywordPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// RedactDSN masks the password in a Postgres URL or keyword/value DSN, or
+// a MySQL DSN, so the result is safe to log.
+func RedactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "[unparseable DSN]"
+		}
+		query := u.Query()
+		if query.Has("password") {
+			query.Set("password", "xxxxx")
+			u.RawQuery = query.Encode()
+		}
+		return u.Redacted()
+	}
+	return redactMySQL(keywordPassword.ReplaceAllString(dsn, "${1}xxxxx"))
+}
+
+// redactMySQL splits user:password@protocol(address)/dbname the way the
+// driver does: the last @ before the last / of the path ends the password.
+func redactMySQL(dsn string) string {
+	path, _, _ := strings.Cut(dsn, "?")
+	slash := strings.LastIndex(path, "/")
+	if slash < 0 {
+		return dsn
+	}
+	at := strings.LastIndex(dsn[:slash], "@")
+	if at < 0 {
+		return dsn
+	}
+	user, _, hasPassword := strings.Cut(dsn[:at], ":")
+	if !hasPassword {
+		return dsn
+	}
+	return user + ":xxxxx" + dsn[at:]
+}
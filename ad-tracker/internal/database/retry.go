@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"ad-tracking-system/internal/config"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RetryConfig controls how long startup waits for the database.
+type RetryConfig struct {
+	// Attempts is the number of connection attempts; zero retries until the
+	// context is cancelled.
+	Attempts   int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// RetryConfigFrom takes the connect retry settings from the database
+// configuration.
+func RetryConfigFrom(c config.DatabaseConfig) RetryConfig {
+	return RetryConfig{
+		Attempts:   c.ConnectAttempts,
+		Backoff:    c.ConnectBackoff,
+		MaxBackoff: c.ConnectMaxBackoff,
+	}
+}
+
+// ConnectConfig is Connect with every setting taken from c; an empty URL
+// falls back to the driver's development default.
+func ConnectConfig(ctx context.Context, c config.DatabaseConfig, logger *logrus.Logger) (*gorm.DB, error) {
+	databaseURL := c.URL
+	if databaseURL == "" {
+		databaseURL = DefaultURL(c.Driver)
+	}
+	return Connect(ctx, c.Driver, databaseURL, PoolConfigFrom(c), RetryConfigFrom(c), logger)
+}
+
+// Connect runs SetupDatabase until it succeeds, doubling the wait between
+// attempts, so the service can start before Postgres is up.
+func Connect(ctx context.Context, driver, databaseURL string, pool PoolConfig, retry RetryConfig, logger *logrus.Logger) (*gorm.DB, error) {
+	logger.WithFields(logrus.Fields{"driver": driver, "dsn": RedactDSN(databaseURL)}).Info("Connecting to database")
+	backoff := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		db, err := SetupDatabase(driver, databaseURL, pool, logger)
+		if err == nil {
+			return db, nil
+		}
+		if retry.Attempts > 0 && attempt >= retry.Attempts {
+			return nil, err
+		}
+		logger.WithError(err).WithFields(logrus.Fields{
+			"attempt":  attempt,
+			"retry_in": backoff,
+		}).Warn("Database not reachable, retrying")
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, retry.MaxBackoff)
+	}
+}
@@ -0,0 +1,8 @@
+// Package e2e holds the end-to-end pipeline test: it starts the stack in
+// deployments/e2e with Docker, sends events through HTTP ingest, the click
+// queue, the event topic, the ClickHouse consumer and the Postgres rollups,
+// and checks what comes out the other end. It only builds with the
+// integration tag:
+//
+//	go test -tags integration ./internal/e2e/
+package e2e
@@ -0,0 +1,396 @@
+//go:build integration
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/clickhouse"
+	"ad-tracking-system/internal/config"
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/events"
+	adkafka "ad-tracking-system/internal/kafka"
+	"ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/signing"
+
+	"github.com/segmentio/kafka-go"
+	"gorm.io/gorm"
+)
+
+// TestPipeline starts the stack in deployments/e2e and checks each stage in
+// pipeline order, stopping at the first failure. E2E_CLICKS,
+// E2E_IMPRESSIONS and E2E_TIMEOUT tune the run.
+func TestPipeline(t *testing.T) {
+	stack := startStack(t)
+	ctx := context.Background()
+
+	log := logger.SetupLogger(config.GetEnv("LOG_LEVEL", "warn"))
+	db, err := database.Connect(ctx, database.DriverPostgres, stack.DatabaseURL, database.PoolConfig{},
+		database.RetryConfig{Attempts: 30, Backoff: time.Second, MaxBackoff: 5 * time.Second}, log)
+	if err != nil {
+		t.Fatalf("connect to database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	s := newScenario(scenarioConfig{
+		Target:        stack.Target,
+		AdminToken:    adminToken,
+		Broker:        stack.Broker,
+		Topic:         "ad-events",
+		ClickHouseURL: stack.ClickHouseURL,
+		DB:            db,
+		Clicks:        config.GetEnvInt("E2E_CLICKS", 50),
+		Impressions:   config.GetEnvInt("E2E_IMPRESSIONS", 200),
+		Backdate:      2 * time.Hour,
+		Timeout:       config.GetEnvDuration("E2E_TIMEOUT", 3*time.Minute),
+	})
+	steps := []struct {
+		name string
+		run  func(context.Context) error
+	}{
+		{"ready", s.waitReady},
+		{"onboard", s.onboard},
+		{"ingest", s.ingest},
+		{"database", s.checkDatabase},
+		{"topic", s.checkTopic},
+		{"clickhouse", s.checkClickHouse},
+		{"rollups", s.checkRollups},
+		{"analytics", s.checkAnalytics},
+	}
+	for _, step := range steps {
+		passed := t.Run(step.name, func(t *testing.T) {
+			if err := step.run(ctx); err != nil {
+				t.Fatal(err)
+			}
+		})
+		if !passed {
+			return
+		}
+	}
+}
+
+// scenarioConfig points the scenario at the stack under test.
+type scenarioConfig struct {
+	Target        string
+	AdminToken    string
+	Broker        string
+	Topic         string
+	ClickHouseURL string
+	DB            *gorm.DB
+
+	Clicks      int
+	Impressions int
+	// Backdate places clicks in an earlier, complete hour so the rollup
+	// aggregator picks them up without waiting for the hour to close.
+	Backdate time.Duration
+	// Timeout bounds each wait for an asynchronous stage.
+	Timeout time.Duration
+}
+
+// scenario drives the stack through the pipeline, one method per stage.
+type scenario struct {
+	cfg  scenarioConfig
+	http *http.Client
+
+	adID     uint
+	secret   string
+	clickIDs map[string]bool
+	started  time.Time
+}
+
+func newScenario(cfg scenarioConfig) *scenario {
+	cfg.Target = strings.TrimRight(cfg.Target, "/")
+	return &scenario{
+		cfg:  cfg,
+		http: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *scenario) waitReady(ctx context.Context) error {
+	return s.eventually(ctx, func() error {
+		status, _, err := s.do(ctx, http.MethodGet, "/readyz", nil, nil)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("readyz answered %d", status)
+		}
+		return nil
+	})
+}
+
+func (s *scenario) onboard(ctx context.Context) error {
+	s.started = time.Now().UTC()
+	request := map[string]interface{}{
+		"account_name": "e2e " + s.started.Format(time.RFC3339Nano),
+		"ad": map[string]interface{}{
+			"image_url":  "https://example.com/e2e.jpg",
+			"target_url": "https://example.com/e2e",
+			"title":      "E2E ad",
+		},
+	}
+	var response struct {
+		Ad            models.Ad `json:"ad"`
+		SigningSecret string    `json:"signing_secret"`
+	}
+	status, body, err := s.do(ctx, http.MethodPost, "/api/v1/admin/onboarding", request, &response)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusCreated || response.Ad.ID == 0 {
+		return fmt.Errorf("onboarding answered %d: %s", status, body)
+	}
+	s.adID, s.secret = response.Ad.ID, response.SigningSecret
+	return nil
+}
+
+// sign adds the ts/sig of the ad's tracking link of kind for userID to an
+// event body.
+func (s *scenario) sign(request map[string]interface{}, kind, userID string) {
+	query := signing.Query(s.secret, kind, s.adID, time.Now(), userID)
+	request["ts"], request["sig"] = query.Get("ts"), query.Get("sig")
+}
+
+// ingest posts the clicks and impressions, each from its own address so
+// per-address limits and fraud rules leave them alone.
+func (s *scenario) ingest(ctx context.Context) error {
+	s.clickIDs = make(map[string]bool, s.cfg.Clicks)
+	clickTime := s.clickTime().Unix()
+	for i := 0; i < s.cfg.Clicks; i++ {
+		var response struct {
+			ClickID string `json:"click_id"`
+		}
+		userID := "e2e-user-" + strconv.Itoa(i)
+		request := map[string]interface{}{
+			"ad_id":     s.adID,
+			"user_id":   userID,
+			"timestamp": clickTime,
+		}
+		s.sign(request, signing.KindClick, userID)
+		status, body, err := s.do(ctx, http.MethodPost, "/api/v1/ads/click", request, &response, clientAddress(i))
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK || response.ClickID == "" {
+			return fmt.Errorf("click %d answered %d: %s", i, status, body)
+		}
+		s.clickIDs[response.ClickID] = true
+	}
+	for i := 0; i < s.cfg.Impressions; i++ {
+		userID := "e2e-user-" + strconv.Itoa(i)
+		request := map[string]interface{}{
+			"ad_id":           s.adID,
+			"user_id":         userID,
+			"time_in_view_ms": 2000,
+			"percent_in_view": 80,
+		}
+		s.sign(request, signing.KindPixel, userID)
+		status, body, err := s.do(ctx, http.MethodPost, "/api/v1/ads/impression", request, nil, clientAddress(s.cfg.Clicks+i))
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("impression %d answered %d: %s", i, status, body)
+		}
+	}
+	return nil
+}
+
+// checkDatabase waits for the click queue to flush every click.
+func (s *scenario) checkDatabase(ctx context.Context) error {
+	ids := make([]string, 0, len(s.clickIDs))
+	for id := range s.clickIDs {
+		ids = append(ids, id)
+	}
+	store := repositories.NewEventStore(s.cfg.DB)
+	return s.eventually(ctx, func() error {
+		stored, err := store.StoredClickIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+		return expectCount("stored clicks", int64(len(stored)), int64(len(ids)))
+	})
+}
+
+// checkTopic reads the clicks back from the event topic and checks they
+// were published with the current schema version.
+func (s *scenario) checkTopic(ctx context.Context) error {
+	admin := adkafka.NewOffsetAdmin(s.cfg.Broker, s.cfg.Topic)
+	return s.eventually(ctx, func() error {
+		seen := 0
+		err := admin.ReadRange(ctx, s.started.Add(-time.Minute), time.Now().Add(time.Minute), 500, func(messages []kafka.Message) error {
+			for _, msg := range messages {
+				var header struct {
+					SchemaVersion int `json:"schema_version"`
+				}
+				if json.Unmarshal(msg.Value, &header) != nil {
+					continue
+				}
+				click, err := events.DecodeClick(msg.Value)
+				if err != nil || !s.clickIDs[click.ClickID] {
+					continue
+				}
+				if header.SchemaVersion != events.ClickSchemaVersion {
+					return fmt.Errorf("click %s published with schema version %d, want %d", click.ClickID, header.SchemaVersion, events.ClickSchemaVersion)
+				}
+				seen++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return expectCount("published clicks", int64(seen), int64(len(s.clickIDs)))
+	})
+}
+
+// checkClickHouse waits for the ClickHouse sink to consume the clicks from
+// the topic and copy the impressions from the database.
+func (s *scenario) checkClickHouse(ctx context.Context) error {
+	client, err := clickhouse.NewClient(s.cfg.ClickHouseURL)
+	if err != nil {
+		return err
+	}
+	store := clickhouse.NewStore(client)
+	query := events.Query{AdID: s.adID}
+	return s.eventually(ctx, func() error {
+		clicks, err := store.CountClicks(ctx, query)
+		if err != nil {
+			return err
+		}
+		if err := expectCount("ClickHouse clicks", clicks, int64(s.cfg.Clicks)); err != nil {
+			return err
+		}
+		impressions, err := store.CountImpressions(ctx, query)
+		if err != nil {
+			return err
+		}
+		return expectCount("ClickHouse impressions", impressions, int64(s.cfg.Impressions))
+	})
+}
+
+// checkRollups waits for the aggregator to roll up the backdated hour.
+func (s *scenario) checkRollups(ctx context.Context) error {
+	rollups := repositories.NewRollupRepository(s.cfg.DB)
+	hour := s.clickTime().Truncate(time.Hour)
+	return s.eventually(ctx, func() error {
+		totals, err := rollups.Totals(s.adID, hour, hour.Add(time.Hour))
+		if err != nil {
+			return err
+		}
+		return expectCount("rolled up clicks", totals.Clicks, int64(s.cfg.Clicks))
+	})
+}
+
+// checkAnalytics reads the ad's figures through the public API, which
+// combines the rollups with raw events since they were last built.
+func (s *scenario) checkAnalytics(ctx context.Context) error {
+	path := "/api/v1/ads/analytics?timeframe=24h&ad_id=" + strconv.FormatUint(uint64(s.adID), 10)
+	return s.eventually(ctx, func() error {
+		var response struct {
+			Analytics models.AnalyticsResponse `json:"analytics"`
+		}
+		status, body, err := s.do(ctx, http.MethodGet, path, nil, &response)
+		if err != nil {
+			return err
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("analytics answered %d: %s", status, body)
+		}
+		if err := expectCount("analytics clicks", response.Analytics.ClickCount, int64(s.cfg.Clicks)); err != nil {
+			return err
+		}
+		return expectCount("analytics impressions", response.Analytics.Impressions, int64(s.cfg.Impressions))
+	})
+}
+
+func (s *scenario) clickTime() time.Time {
+	return s.started.Add(-s.cfg.Backdate)
+}
+
+// eventually retries check every second until it passes or the timeout
+// runs out, returning its last error.
+func (s *scenario) eventually(ctx context.Context, check func() error) error {
+	deadline := time.Now().Add(s.cfg.Timeout)
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// do sends a JSON request, decoding a JSON answer into response when given.
+// The raw body is returned for error messages.
+func (s *scenario) do(ctx context.Context, method, path string, request, response interface{}, forwardedFor ...string) (int, string, error) {
+	var body io.Reader
+	if request != nil {
+		encoded, err := json.Marshal(request)
+		if err != nil {
+			return 0, "", err
+		}
+		body = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.cfg.Target+path, body)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36")
+	if strings.HasPrefix(path, "/api/v1/admin/") {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.AdminToken)
+	}
+	if len(forwardedFor) > 0 {
+		req.Header.Set("X-Forwarded-For", forwardedFor[0])
+	}
+
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, "", err
+	}
+	if response != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(raw, response); err != nil {
+			return resp.StatusCode, string(raw), err
+		}
+	}
+	return resp.StatusCode, string(raw), nil
+}
+
+func clientAddress(i int) string {
+	return fmt.Sprintf("203.0.%d.%d", 113+i/250%10, 1+i%250)
+}
+
+func expectCount(what string, got, want int64) error {
+	if got != want {
+		return fmt.Errorf("%s: got %d, want %d", what, got, want)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+//go:build integration
+
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// composeFile is the stack under test, relative to this package.
+const composeFile = "../../deployments/e2e/docker-compose.yml"
+
+// adminToken matches ADMIN_TOKEN in the compose file.
+const adminToken = "e2e-admin-token-0123456789"
+
+// stack is a throwaway compose project with the tracker, Postgres, Kafka
+// and ClickHouse published on loopback ports. It is torn down, volumes
+// included, when the test ends.
+type stack struct {
+	t       *testing.T
+	project string
+	env     []string
+
+	Target        string
+	DatabaseURL   string
+	Broker        string
+	ClickHouseURL string
+}
+
+// startStack builds the tracker image and starts the stack, failing the
+// test when Docker is unavailable.
+func startStack(t *testing.T) *stack {
+	t.Helper()
+	if _, err := exec.LookPath("docker"); err != nil {
+		t.Fatalf("the integration tests need the docker CLI: %v", err)
+	}
+
+	// Kafka advertises its host port to clients, so it is picked here
+	// rather than by Docker
+	kafkaPort := freePort(t)
+	s := &stack{
+		t:       t,
+		project: "ad-tracking-e2e-" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		env:     append(os.Environ(), "E2E_KAFKA_PORT="+strconv.Itoa(kafkaPort)),
+		Broker:  net.JoinHostPort("127.0.0.1", strconv.Itoa(kafkaPort)),
+	}
+	t.Cleanup(s.stop)
+
+	t.Logf("Starting compose project %s", s.project)
+	if out, err := s.compose("up", "--detach", "--build"); err != nil {
+		t.Fatalf("start stack: %v\n%s", err, out)
+	}
+	s.Target = "http://" + s.address("tracker", 8080)
+	s.DatabaseURL = "postgresql://ad_user:ad_password@" + s.address("postgres", 5432) + "/ad_tracking_db?sslmode=disable"
+	s.ClickHouseURL = "http://" + s.address("clickhouse", 8123) + "/default"
+	return s
+}
+
+// stop prints the tracker's logs when the test failed and removes the
+// project.
+func (s *stack) stop() {
+	if s.t.Failed() {
+		if out, err := s.compose("logs", "--no-color", "--tail", "200", "tracker"); err == nil {
+			s.t.Logf("tracker logs:\n%s", out)
+		}
+	}
+	if out, err := s.compose("down", "--volumes", "--remove-orphans"); err != nil {
+		s.t.Errorf("remove stack: %v\n%s", err, out)
+	}
+}
+
+// address returns the loopback address a service's port is published on.
+func (s *stack) address(service string, port int) string {
+	s.t.Helper()
+	out, err := s.compose("port", service, strconv.Itoa(port))
+	if err != nil {
+		s.t.Fatalf("find %s port %d: %v\n%s", service, port, err, out)
+	}
+	return strings.TrimSpace(out)
+}
+
+func (s *stack) compose(args ...string) (string, error) {
+	cmd := exec.Command("docker", append([]string{"compose", "-p", s.project, "-f", composeFile}, args...)...)
+	cmd.Env = s.env
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	if err := cmd.Run(); err != nil {
+		return out.String(), fmt.Errorf("docker compose %s: %w", args[0], err)
+	}
+	return out.String(), nil
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("find a free port: %v", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
@@ -0,0 +1,46 @@
+package edge
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+const (
+	EventClick      = "click"
+	EventImpression = "impression"
+
+	SignatureHeader = "X-Edge-Signature"
+	EdgeIDHeader    = "X-Edge-ID"
+
+	// EnvelopePath is where the central API accepts envelopes over HTTP.
+	EnvelopePath = "/api/v1/edge/envelopes"
+)
+
+// ValidEventType reports whether t is an event type the edge accepts.
+func ValidEventType(t string) bool {
+	return t == EventClick || t == EventImpression
+}
+
+// Envelope wraps a validated tracking request with the context only the edge
+// knows (client address, receipt time) so it survives buffering intact. The
+// address is already in its stored form, so spool files and the Kafka topic
+// never hold more than the database would.
+type Envelope struct {
+	Type       string          `json:"type"`
+	AdID       uint            `json:"ad_id"`
+	EdgeID     string          `json:"edge_id"`
+	ReceivedAt time.Time       `json:"received_at"`
+	IPAddress  string          `json:"ip_address"`
+	UserAgent  string          `json:"user_agent"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Sign returns the hex HMAC-SHA256 of body under key.
+func Sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
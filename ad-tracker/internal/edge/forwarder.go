@@ -0,0 +1,115 @@
+package edge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Forwarder ships signed envelopes to the central pipeline.
+type Forwarder interface {
+	Forward(ctx context.Context, envelopes []Envelope) error
+	Close() error
+}
+
+// KafkaForwarder publishes envelopes to a topic, keyed by ad id, with the
+// signature and edge id as message headers.
+type KafkaForwarder struct {
+	writer *kafka.Writer
+	key    []byte
+}
+
+func NewKafkaForwarder(broker, topic string, key []byte) *KafkaForwarder {
+	return &KafkaForwarder{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(broker),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
+		},
+		key: key,
+	}
+}
+
+func (f *KafkaForwarder) Forward(ctx context.Context, envelopes []Envelope) error {
+	messages := make([]kafka.Message, 0, len(envelopes))
+	for _, envelope := range envelopes {
+		body, err := json.Marshal(envelope)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafka.Message{
+			Key:   []byte(strconv.FormatUint(uint64(envelope.AdID), 10)),
+			Value: body,
+			Headers: []kafka.Header{
+				{Key: SignatureHeader, Value: []byte(Sign(f.key, body))},
+				{Key: EdgeIDHeader, Value: []byte(envelope.EdgeID)},
+			},
+		})
+	}
+	return f.writer.WriteMessages(ctx, messages...)
+}
+
+func (f *KafkaForwarder) Close() error {
+	return f.writer.Close()
+}
+
+// HTTPForwarder posts each envelope to the central API's edge endpoint,
+// which verifies the signature and replays the event from the client
+// address and user agent the edge saw.
+type HTTPForwarder struct {
+	client  *http.Client
+	baseURL string
+	key     []byte
+}
+
+func NewHTTPForwarder(baseURL string, key []byte) *HTTPForwarder {
+	return &HTTPForwarder{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		baseURL: baseURL,
+		key:     key,
+	}
+}
+
+func (f *HTTPForwarder) Forward(ctx context.Context, envelopes []Envelope) error {
+	for _, envelope := range envelopes {
+		body, err := json.Marshal(envelope)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.baseURL+EnvelopePath, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(EdgeIDHeader, envelope.EdgeID)
+		req.Header.Set(SignatureHeader, Sign(f.key, body))
+
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		// A signature central rejects fails every envelope alike
+		if resp.StatusCode == http.StatusUnauthorized {
+			return fmt.Errorf("central API rejected the edge signature")
+		}
+		// Other 4xx means central rejected the event itself; retrying won't help
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("central API returned %d", resp.StatusCode)
+		}
+	}
+	return nil
+}
+
+func (f *HTTPForwarder) Close() error {
+	return nil
+}
@@ -0,0 +1,194 @@
+package edge
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrBadSignature is returned for envelopes not signed with the shared key.
+var ErrBadSignature = errors.New("edge signature invalid")
+
+// Receiver is the central end of edge forwarding. It verifies each
+// envelope's signature and replays the event against the central tracking
+// handler in process, from the client address and user agent the edge saw,
+// so edge events get the same validation, fraud scoring and storage as
+// direct traffic.
+type Receiver struct {
+	key     []byte
+	handler http.Handler
+	logger  *logrus.Logger
+}
+
+// NewReceiver replays verified envelopes into handler, normally the central
+// router.
+func NewReceiver(key []byte, handler http.Handler, logger *logrus.Logger) *Receiver {
+	return &Receiver{key: key, handler: handler, logger: logger}
+}
+
+// Ingest verifies body against signature and replays the envelope, returning
+// the status the tracking endpoint answered with.
+func (r *Receiver) Ingest(ctx context.Context, body []byte, signature string) (int, error) {
+	if !hmac.Equal([]byte(Sign(r.key, body)), []byte(signature)) {
+		return 0, ErrBadSignature
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return 0, fmt.Errorf("decode envelope: %w", err)
+	}
+	if !ValidEventType(envelope.Type) {
+		return 0, fmt.Errorf("unknown event type %q", envelope.Type)
+	}
+
+	path := "/api/v1/ads/click"
+	if envelope.Type == EventImpression {
+		path = "/api/v1/ads/impression"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(envelope.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", envelope.UserAgent)
+	req.Header.Set(EdgeIDHeader, envelope.EdgeID)
+	// No forwarding headers, so the client address is the one the edge saw
+	req.RemoteAddr = net.JoinHostPort(replayAddress(envelope.IPAddress), "0")
+	req = req.WithContext(context.WithValue(ctx, storedIPKey{}, envelope.IPAddress))
+
+	w := &statusWriter{header: http.Header{}, status: http.StatusOK}
+	r.handler.ServeHTTP(w, req)
+	return w.status, nil
+}
+
+// PostEnvelope accepts one envelope per request from an HTTPForwarder and
+// answers with the status of the replayed event.
+func (r *Receiver) PostEnvelope(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read envelope"})
+		return
+	}
+	status, err := r.Ingest(c.Request.Context(), body, c.GetHeader(SignatureHeader))
+	if errors.Is(err, ErrBadSignature) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid edge signature"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(status, gin.H{"status": http.StatusText(status)})
+}
+
+// Consume replays envelopes from reader until ctx is cancelled. Offsets are
+// committed once an event was stored or rejected for good; envelopes with
+// bad signatures are dropped, and server errors retried with backoff.
+func (r *Receiver) Consume(ctx context.Context, reader *kafka.Reader) {
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			r.logger.WithError(err).Warn("Failed to fetch edge envelope")
+			continue
+		}
+		if !r.replay(ctx, msg) {
+			return
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			r.logger.WithError(err).Warn("Failed to commit edge offset")
+		}
+	}
+}
+
+// replay ingests one message, retrying while the central API fails. It
+// returns false when ctx was cancelled first.
+func (r *Receiver) replay(ctx context.Context, msg kafka.Message) bool {
+	var signature string
+	for _, header := range msg.Headers {
+		if header.Key == SignatureHeader {
+			signature = string(header.Value)
+		}
+	}
+	log := r.logger.WithFields(logrus.Fields{"partition": msg.Partition, "offset": msg.Offset})
+
+	backoff := time.Second
+	for {
+		status, err := r.Ingest(ctx, msg.Value, signature)
+		switch {
+		case err != nil:
+			log.WithError(err).Warn("Dropping edge envelope")
+			return true
+		case status >= http.StatusInternalServerError || status == http.StatusTooManyRequests:
+			log.WithFields(logrus.Fields{"status": status, "retry_in": backoff}).Warn("Central API failed edge event")
+		case status >= http.StatusBadRequest:
+			log.WithField("status", status).Debug("Central API rejected edge event")
+			return true
+		default:
+			return true
+		}
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+type storedIPKey struct{}
+
+// StoredIP returns the client address of a replayed edge event in the form
+// the edge minimized it to, which is stored as is.
+func StoredIP(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(storedIPKey{}).(string)
+	return ip, ok
+}
+
+// replayAddress is the remote address an event is replayed from. A hashed
+// address is mapped to a stable IPv6 unique local address, so per-client
+// fraud rules still tell clients apart.
+func replayAddress(stored string) string {
+	if net.ParseIP(stored) != nil {
+		return stored
+	}
+	sum := sha256.Sum256([]byte(stored))
+	ip := make(net.IP, net.IPv6len)
+	ip[0] = 0xfd
+	copy(ip[1:], sum[:])
+	return ip.String()
+}
+
+// statusWriter discards a replayed response, keeping its status.
+type statusWriter struct {
+	header http.Header
+	status int
+	wrote  bool
+}
+
+func (w *statusWriter) Header() http.Header { return w.header }
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.status, w.wrote = status, true
+	}
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return len(p), nil
+}
@@ -0,0 +1,151 @@
+package edge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/pii"
+	"ad-tracking-system/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Server accepts tracking requests at the edge, validates them and buffers
+// them to the spool. It never talks to the database.
+type Server struct {
+	spool     *Spool
+	forwarder Forwarder
+	edgeID    string
+	ips       *pii.IPMinimizer
+	logger    *logrus.Logger
+}
+
+func NewServer(spool *Spool, forwarder Forwarder, edgeID string, logger *logrus.Logger) *Server {
+	ips, _ := pii.NewIPMinimizer(pii.IPRaw, "")
+	return &Server{spool: spool, forwarder: forwarder, edgeID: edgeID, ips: ips, logger: logger}
+}
+
+// SetIPMinimizer controls the form client addresses take in envelopes. It
+// must match the central IP_STORAGE_MODE, which stores edge addresses as
+// received.
+func (s *Server) SetIPMinimizer(ips *pii.IPMinimizer) {
+	s.ips = ips
+}
+
+func (s *Server) PostClick(c *gin.Context) {
+	var req models.ClickRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+	// The central side only sees the body, so a signature sent in the
+	// query string moves into it
+	req.LinkSignature = req.LinkSignature.OrQuery(c.Request.URL.Query())
+	if req.Timestamp.IsZero() {
+		req.Timestamp = models.ClientTime{Time: time.Now()}
+	}
+	s.accept(c, EventClick, req.AdID, req)
+}
+
+func (s *Server) PostImpression(c *gin.Context) {
+	var req models.ImpressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+	req.LinkSignature = req.LinkSignature.OrQuery(c.Request.URL.Query())
+	if req.Timestamp.IsZero() {
+		req.Timestamp = models.ClientTime{Time: time.Now()}
+	}
+	s.accept(c, EventImpression, req.AdID, req)
+}
+
+func (s *Server) Health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "healthy",
+		"edge_id":     s.edgeID,
+		"spool_bytes": s.spool.Size(),
+	})
+}
+
+func (s *Server) accept(c *gin.Context, eventType string, adID uint, req interface{}) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode event"})
+		return
+	}
+
+	record, err := json.Marshal(Envelope{
+		Type:       eventType,
+		AdID:       adID,
+		EdgeID:     s.edgeID,
+		ReceivedAt: time.Now().UTC(),
+		IPAddress:  s.ips.Apply(c.ClientIP()),
+		UserAgent:  c.GetHeader("User-Agent"),
+		Payload:    payload,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode event"})
+		return
+	}
+
+	if err := s.spool.Append(record); err != nil {
+		if errors.Is(err, ErrSpoolFull) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Edge buffer full"})
+			return
+		}
+		s.logger.WithError(err).Error("Failed to spool event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record event"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "accepted"})
+}
+
+// RunForwarder rotates and drains the spool every interval, backing off
+// exponentially while the central pipeline is unavailable.
+func (s *Server) RunForwarder(ctx context.Context, interval time.Duration) {
+	backoff := interval
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		if err := s.spool.Rotate(); err != nil {
+			s.logger.WithError(err).Error("Failed to rotate spool segment")
+		}
+
+		err := s.spool.Drain(func(records [][]byte) error {
+			envelopes := make([]Envelope, 0, len(records))
+			for _, record := range records {
+				var envelope Envelope
+				if err := json.Unmarshal(record, &envelope); err != nil {
+					s.logger.WithError(err).Warn("Dropping corrupt spool record")
+					continue
+				}
+				if !ValidEventType(envelope.Type) {
+					s.logger.WithField("type", envelope.Type).Warn("Dropping spool record of unknown event type")
+					continue
+				}
+				envelopes = append(envelopes, envelope)
+			}
+
+			forwardCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			defer cancel()
+			return s.forwarder.Forward(forwardCtx, envelopes)
+		})
+		if err != nil {
+			backoff = min(backoff*2, 5*time.Minute)
+			s.logger.WithError(err).WithField("retry_in", backoff).Warn("Failed to forward spooled events")
+			continue
+		}
+		backoff = interval
+	}
+}
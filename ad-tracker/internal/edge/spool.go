@@ -0,0 +1,219 @@
+package edge
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrSpoolFull = errors.New("spool full")
+
+// Spool is an append-only on-disk buffer of newline-delimited records split
+// into segment files. Closed segments are handed to the drainer oldest first
+// and deleted once every record in them has been forwarded.
+type Spool struct {
+	dir          string
+	maxBytes     int64
+	segmentLimit int
+
+	mu       sync.Mutex
+	current  *os.File
+	writer   *bufio.Writer
+	records  int
+	size     int64
+	sequence int64
+}
+
+func NewSpool(dir string, maxBytes int64, segmentLimit int) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	s := &Spool{dir: dir, maxBytes: maxBytes, segmentLimit: segmentLimit}
+	if err := s.recoverOpen(); err != nil {
+		return nil, err
+	}
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range segments {
+		if info, err := os.Stat(segment); err == nil {
+			s.size += info.Size()
+		}
+	}
+	return s, nil
+}
+
+// Append durably buffers one record.
+func (s *Spool) Append(record []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxBytes > 0 && s.size+int64(len(record))+1 > s.maxBytes {
+		return ErrSpoolFull
+	}
+
+	if s.current == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if _, err := s.writer.Write(append(record, '\n')); err != nil {
+		return err
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	s.records++
+	s.size += int64(len(record)) + 1
+
+	if s.records >= s.segmentLimit {
+		return s.rotate()
+	}
+	return nil
+}
+
+// Rotate closes the active segment so it becomes eligible for draining.
+func (s *Spool) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotate()
+}
+
+// Drain forwards every closed segment, oldest first, stopping at the first
+// failure so ordering is preserved and the segment is retried later.
+func (s *Spool) Drain(forward func(records [][]byte) error) error {
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+
+	for _, segment := range segments {
+		records, err := readSegment(segment)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			if err := forward(records); err != nil {
+				return err
+			}
+		}
+
+		info, statErr := os.Stat(segment)
+		if err := os.Remove(segment); err != nil {
+			return err
+		}
+		if statErr == nil {
+			s.mu.Lock()
+			s.size -= info.Size()
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// Size returns the bytes currently buffered on disk.
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotate()
+}
+
+func (s *Spool) open() error {
+	s.sequence++
+	name := filepath.Join(s.dir, fmt.Sprintf("segment-%d-%06d.open", time.Now().UnixNano(), s.sequence))
+	file, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	s.current = file
+	s.writer = bufio.NewWriter(file)
+	s.records = 0
+	return nil
+}
+
+func (s *Spool) rotate() error {
+	if s.current == nil {
+		return nil
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if err := s.current.Sync(); err != nil {
+		return err
+	}
+	if err := s.current.Close(); err != nil {
+		return err
+	}
+	name := s.current.Name()
+	s.current = nil
+	s.writer = nil
+	return os.Rename(name, strings.TrimSuffix(name, ".open")+".ndjson")
+}
+
+// recoverOpen closes segments left named .open by a crash. It only runs
+// before the spool accepts writes, so none of them can be in use.
+func (s *Spool) recoverOpen() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := filepath.Join(s.dir, entry.Name())
+		if strings.HasSuffix(name, ".open") {
+			if err := os.Rename(name, strings.TrimSuffix(name, ".open")+".ndjson"); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// segments lists closed segments oldest first. Segments still named .open
+// are being written and are never drained.
+func (s *Spool) segments() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []string
+	for _, entry := range entries {
+		if name := filepath.Join(s.dir, entry.Name()); strings.HasSuffix(name, ".ndjson") {
+			segments = append(segments, name)
+		}
+	}
+	sort.Strings(segments)
+	return segments, nil
+}
+
+func readSegment(path string) ([][]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records [][]byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 {
+			records = append(records, append([]byte(nil), line...))
+		}
+	}
+	return records, scanner.Err()
+}
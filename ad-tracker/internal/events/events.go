@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"ad-tracking-system/internal/models"
+)
+
+// EventBus publishes serialized events to the streaming backend.
+type EventBus interface {
+	Publish(ctx context.Context, key, value []byte) error
+	Close() error
+}
+
+// EventStore persists click and impression events and answers the queries
+// analytics needs.
+type EventStore interface {
+	SaveClicks(ctx context.Context, events []models.ClickEvent) error
+	CountClicks(ctx context.Context, query Query) (int64, error)
+	ListClicks(ctx context.Context, query Query) ([]models.ClickEvent, error)
+	DistinctAdIDs(ctx context.Context, since time.Time) ([]uint, error)
+	// PlaybackStats aggregates video playback time over matching clicks.
+	// Clicks watched for at least completeAfter seconds count as completed;
+	// completeAfter <= 0 disables the completion rate.
+	PlaybackStats(ctx context.Context, query Query, completeAfter int64) (models.PlaybackStats, error)
+
+	// FlagIP tags every click and impression from ipAddress since the given
+	// time as invalid with the reason appended, returning the rows changed.
+	FlagIP(ctx context.Context, ipAddress string, since time.Time, reason string) (int64, error)
+
+	SaveImpressions(ctx context.Context, impressions []models.ImpressionEvent) error
+	CountImpressions(ctx context.Context, query Query) (int64, error)
+	// ViewabilityStats counts measured impressions that were in view for at
+	// least minPercent of pixels and minMS milliseconds.
+	ViewabilityStats(ctx context.Context, query Query, minPercent float64, minMS int64) (models.ViewabilityStats, error)
+	// SessionStats counts the sessions with matching clicks or impressions
+	// and how many events each had. Events without a session are ignored.
+	SessionStats(ctx context.Context, query Query) (models.SessionStats, error)
+}
+
+// BatchSaver is implemented by stores that can write clicks and
+// impressions together, all or nothing.
+type BatchSaver interface {
+	SaveBatch(ctx context.Context, clicks []models.ClickEvent, impressions []models.ImpressionEvent) error
+}
+
+// Sink receives copies of stored events for an external warehouse or
+// analytics database. Writes may be repeated after a failure, so sinks
+// must tolerate duplicates.
+type Sink interface {
+	SaveClicks(ctx context.Context, events []models.ClickEvent) error
+	SaveImpressions(ctx context.Context, impressions []models.ImpressionEvent) error
+	// MaxImpressionID is the highest impression id the sink holds; copying
+	// resumes after it.
+	MaxImpressionID(ctx context.Context) (uint, error)
+}
+
+// Query filters stored events. Zero values are ignored.
+type Query struct {
+	AdID  uint
+	Since time.Time
+	Until time.Time
+	Limit int
+	// ValidOnly excludes events tagged invalid by fraud scoring.
+	ValidOnly bool
+}
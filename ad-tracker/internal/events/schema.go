@@ -0,0 +1,101 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+)
+
+// ClickSchemaVersion is stamped on clicks published to the event topic as
+// schema_version. Payloads without one are version 1, published before
+// versioning. Consumers upcast older payloads one version at a time, so a
+// consumer can run ahead of or behind the producers.
+//
+// Bump the version with an upcaster from the previous one. Changes must
+// stay readable by the previous consumer release: payloads newer than a
+// consumer knows are decoded as its current version, ignoring fields it
+// does not know.
+const ClickSchemaVersion = 2
+
+// clickUpcasters convert a payload of the key version to the next one.
+var clickUpcasters = map[int]func(fields map[string]json.RawMessage) error{
+	1: clickV1ToV2,
+}
+
+type versionedClick struct {
+	SchemaVersion int `json:"schema_version"`
+	models.ClickEvent
+}
+
+// EncodeClick serializes a click for the event topic.
+func EncodeClick(click models.ClickEvent) ([]byte, error) {
+	return json.Marshal(versionedClick{SchemaVersion: ClickSchemaVersion, ClickEvent: click})
+}
+
+// DecodeClick reads a click of any schema version, upcasting it to the
+// current one.
+func DecodeClick(data []byte) (models.ClickEvent, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return models.ClickEvent{}, err
+	}
+
+	version := 1
+	if raw, ok := fields["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return models.ClickEvent{}, fmt.Errorf("schema_version: %w", err)
+		}
+	}
+	metrics.EventSchemaVersions.WithLabelValues("click", strconv.Itoa(version)).Inc()
+
+	if version < ClickSchemaVersion {
+		for v := version; v < ClickSchemaVersion; v++ {
+			upcast, ok := clickUpcasters[v]
+			if !ok {
+				return models.ClickEvent{}, fmt.Errorf("no upcaster for click schema version %d", v)
+			}
+			if err := upcast(fields); err != nil {
+				return models.ClickEvent{}, fmt.Errorf("upcast click schema version %d: %w", v, err)
+			}
+		}
+		upcasted, err := json.Marshal(fields)
+		if err != nil {
+			return models.ClickEvent{}, err
+		}
+		data = upcasted
+	}
+
+	var click models.ClickEvent
+	err := json.Unmarshal(data, &click)
+	return click, err
+}
+
+// clickV1ToV2 gives clicks published before click ids existed a stable id
+// derived from their content, so replays of them are deduplicated like
+// any other click.
+func clickV1ToV2(fields map[string]json.RawMessage) error {
+	var clickID string
+	if raw, ok := fields["click_id"]; ok {
+		if err := json.Unmarshal(raw, &clickID); err != nil {
+			return err
+		}
+	}
+	if clickID == "" {
+		digest := sha256.New()
+		for _, name := range []string{"ad_id", "timestamp", "user_id", "ip_address", "user_agent"} {
+			digest.Write(fields[name])
+			digest.Write([]byte{0})
+		}
+		encoded, err := json.Marshal(hex.EncodeToString(digest.Sum(nil))[:32])
+		if err != nil {
+			return err
+		}
+		fields["click_id"] = encoded
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package fakes
+
+import (
+	"sort"
+	"sync"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/targeting"
+
+	"gorm.io/gorm"
+)
+
+// AdStore is an in-memory repositories.AdStore. Active leaves out inactive
+// ads and honeypots like the GORM implementation; budget pauses are not
+// modelled, so set Active false to take an ad out of serving.
+type AdStore struct {
+	mu  sync.Mutex
+	ads map[uint]models.Ad
+
+	// Err, when set, is returned from every method.
+	Err error
+}
+
+var _ repositories.AdStore = (*AdStore)(nil)
+
+func NewAdStore(ads ...models.Ad) *AdStore {
+	s := &AdStore{ads: make(map[uint]models.Ad)}
+	for _, ad := range ads {
+		s.Put(ad)
+	}
+	return s
+}
+
+// Put adds or replaces an ad, assigning the next id when ad.ID is zero.
+func (s *AdStore) Put(ad models.Ad) models.Ad {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ad.ID == 0 {
+		for id := range s.ads {
+			ad.ID = max(ad.ID, id)
+		}
+		ad.ID++
+	}
+	s.ads[ad.ID] = ad
+	return ad
+}
+
+func (s *AdStore) Active() ([]models.Ad, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	var ads []models.Ad
+	for _, ad := range s.ads {
+		if ad.Active && !ad.Honeypot {
+			ads = append(ads, ad)
+		}
+	}
+	sort.Slice(ads, func(i, j int) bool { return ads[i].ID < ads[j].ID })
+	return ads, nil
+}
+
+func (s *AdStore) Get(id uint) (models.Ad, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return models.Ad{}, s.Err
+	}
+	ad, ok := s.ads[id]
+	if !ok {
+		return models.Ad{}, gorm.ErrRecordNotFound
+	}
+	return ad, nil
+}
+
+func (s *AdStore) UpdateTargeting(id uint, rule *targeting.Rule) (models.Ad, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return models.Ad{}, s.Err
+	}
+	ad, ok := s.ads[id]
+	if !ok {
+		return models.Ad{}, gorm.ErrRecordNotFound
+	}
+	ad.Targeting = rule
+	s.ads[id] = ad
+	return ad, nil
+}
@@ -0,0 +1,107 @@
+package fakes
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+)
+
+// AnalyticsQuery is a call recorded by Analytics. AdID is zero for
+// GetAllAnalytics, CampaignID is only set for campaign summaries and Until
+// and Daily only for GetCampaignRange.
+type AnalyticsQuery struct {
+	AdID       uint
+	CampaignID uint
+	Since      time.Time
+	Until      time.Time
+	ValidOnly  bool
+	Daily      bool
+}
+
+// Analytics is a repositories.AnalyticsReader answering with canned
+// figures and recording every query. Ads without figures read as zero.
+type Analytics struct {
+	mu        sync.Mutex
+	ads       map[uint]models.AnalyticsResponse
+	campaigns map[uint]models.CampaignSummary
+	queries   []AnalyticsQuery
+}
+
+var _ repositories.AnalyticsReader = (*Analytics)(nil)
+
+func NewAnalytics() *Analytics {
+	return &Analytics{
+		ads:       make(map[uint]models.AnalyticsResponse),
+		campaigns: make(map[uint]models.CampaignSummary),
+	}
+}
+
+// SetAd sets the figures returned for analytics.AdID.
+func (a *Analytics) SetAd(analytics models.AnalyticsResponse) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ads[analytics.AdID] = analytics
+}
+
+// SetCampaign sets the summary returned for campaignID.
+func (a *Analytics) SetCampaign(campaignID uint, summary models.CampaignSummary) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.campaigns[campaignID] = summary
+}
+
+func (a *Analytics) GetAdAnalytics(adID uint, since time.Time, validOnly bool) models.AnalyticsResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.queries = append(a.queries, AnalyticsQuery{AdID: adID, Since: since, ValidOnly: validOnly})
+	analytics := a.ads[adID]
+	analytics.AdID = adID
+	analytics.ValidOnly = validOnly
+	return analytics
+}
+
+// GetAllAnalytics returns every ad with figures set, by ad id.
+func (a *Analytics) GetAllAnalytics(since time.Time, validOnly bool) []models.AnalyticsResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.queries = append(a.queries, AnalyticsQuery{Since: since, ValidOnly: validOnly})
+	all := make([]models.AnalyticsResponse, 0, len(a.ads))
+	for _, analytics := range a.ads {
+		analytics.ValidOnly = validOnly
+		all = append(all, analytics)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].AdID < all[j].AdID })
+	return all
+}
+
+func (a *Analytics) GetCampaignSummary(campaign models.Campaign, adIDs []uint, since time.Time, validOnly bool) models.CampaignSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.queries = append(a.queries, AnalyticsQuery{CampaignID: campaign.ID, Since: since, ValidOnly: validOnly})
+	return a.campaigns[campaign.ID]
+}
+
+// GetCampaignRange returns the summary set for the campaign with the
+// window filled in.
+func (a *Analytics) GetCampaignRange(campaign models.Campaign, adIDs []uint, from, until time.Time, validOnly, daily bool) models.CampaignSummary {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.queries = append(a.queries, AnalyticsQuery{CampaignID: campaign.ID, Since: from, Until: until, ValidOnly: validOnly, Daily: daily})
+	summary := a.campaigns[campaign.ID]
+	summary.Since, summary.Until = from, &until
+	return summary
+}
+
+// Queries returns a copy of every query answered so far.
+func (a *Analytics) Queries() []AnalyticsQuery {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AnalyticsQuery(nil), a.queries...)
+}
@@ -0,0 +1,61 @@
+package fakes
+
+import (
+	"sync"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+)
+
+// ClickQueue is a services.ClickEnqueuer that keeps every click instead of
+// writing it. With Capacity set it fills up and drops clicks like the real
+// queue.
+type ClickQueue struct {
+	mu     sync.Mutex
+	clicks []models.ClickEvent
+
+	// Capacity, when positive, is how many clicks fit before Enqueue
+	// starts returning false.
+	Capacity int
+}
+
+var _ services.ClickEnqueuer = (*ClickQueue)(nil)
+
+func NewClickQueue() *ClickQueue {
+	return &ClickQueue{}
+}
+
+func (q *ClickQueue) Enqueue(event models.ClickEvent) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.Capacity > 0 && len(q.clicks) >= q.Capacity {
+		return false
+	}
+	q.clicks = append(q.clicks, event)
+	return true
+}
+
+func (q *ClickQueue) Saturation() float64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.Capacity <= 0 {
+		return 0
+	}
+	return float64(len(q.clicks)) / float64(q.Capacity)
+}
+
+// Clicks returns a copy of everything enqueued so far.
+func (q *ClickQueue) Clicks() []models.ClickEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]models.ClickEvent(nil), q.clicks...)
+}
+
+// Reset empties the queue.
+func (q *ClickQueue) Reset() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.clicks = nil
+}
@@ -0,0 +1,69 @@
+package fakes
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"ad-tracking-system/internal/events"
+)
+
+// Message is a record captured by the in-memory EventBus.
+type Message struct {
+	Key   []byte
+	Value []byte
+}
+
+// EventBus is an in-memory events.EventBus that records every publish.
+type EventBus struct {
+	mu       sync.Mutex
+	messages []Message
+	closed   bool
+
+	// Err, when set, is returned from Publish instead of recording the message.
+	Err error
+}
+
+var _ events.EventBus = (*EventBus)(nil)
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+func (b *EventBus) Publish(ctx context.Context, key, value []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return errors.New("event bus closed")
+	}
+	if b.Err != nil {
+		return b.Err
+	}
+	b.messages = append(b.messages, Message{
+		Key:   append([]byte(nil), key...),
+		Value: append([]byte(nil), value...),
+	})
+	return nil
+}
+
+func (b *EventBus) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+// Messages returns a copy of everything published so far.
+func (b *EventBus) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.messages...)
+}
+
+// Reset drops all recorded messages.
+func (b *EventBus) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = nil
+}
@@ -0,0 +1,312 @@
+package fakes
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/models"
+)
+
+// EventStore is an in-memory events.EventStore with the same query semantics
+// as the GORM implementation.
+type EventStore struct {
+	mu          sync.Mutex
+	clicks      []models.ClickEvent
+	impressions []models.ImpressionEvent
+	nextID      uint
+
+	// Err, when set, is returned from every method.
+	Err error
+}
+
+var _ events.EventStore = (*EventStore)(nil)
+
+func NewEventStore() *EventStore {
+	return &EventStore{nextID: 1}
+}
+
+func (s *EventStore) SaveClicks(ctx context.Context, clicks []models.ClickEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	for _, click := range clicks {
+		if click.ID == 0 {
+			click.ID = s.nextID
+			s.nextID++
+		}
+		if click.CreatedAt.IsZero() {
+			click.CreatedAt = time.Now()
+		}
+		s.clicks = append(s.clicks, click)
+	}
+	return nil
+}
+
+func (s *EventStore) CountClicks(ctx context.Context, query events.Query) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	return int64(len(s.match(query))), nil
+}
+
+func (s *EventStore) ListClicks(ctx context.Context, query events.Query) ([]models.ClickEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	matched := s.match(query)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp.After(matched[j].Timestamp)
+	})
+	if query.Limit > 0 && len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+	}
+	return matched, nil
+}
+
+func (s *EventStore) DistinctAdIDs(ctx context.Context, since time.Time) ([]uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	seen := make(map[uint]bool)
+	var adIDs []uint
+	for _, click := range s.match(events.Query{Since: since}) {
+		if !seen[click.AdID] {
+			seen[click.AdID] = true
+			adIDs = append(adIDs, click.AdID)
+		}
+	}
+	sort.Slice(adIDs, func(i, j int) bool { return adIDs[i] < adIDs[j] })
+	return adIDs, nil
+}
+
+func (s *EventStore) PlaybackStats(ctx context.Context, query events.Query, completeAfter int64) (models.PlaybackStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return models.PlaybackStats{}, s.Err
+	}
+
+	var samples []int64
+	var total, completed int64
+	for _, click := range s.match(query) {
+		if click.VideoPlaybackTime <= 0 {
+			continue
+		}
+		samples = append(samples, click.VideoPlaybackTime)
+		total += click.VideoPlaybackTime
+		if completeAfter > 0 && click.VideoPlaybackTime >= completeAfter {
+			completed++
+		}
+	}
+	if len(samples) == 0 {
+		return models.PlaybackStats{}, nil
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+	stats := models.PlaybackStats{
+		Samples:       int64(len(samples)),
+		AvgSeconds:    float64(total) / float64(len(samples)),
+		MedianSeconds: percentile(samples, 0.5),
+		P90Seconds:    percentile(samples, 0.9),
+	}
+	if completeAfter > 0 {
+		stats.CompletionRate = float64(completed) / float64(len(samples))
+	}
+	return stats, nil
+}
+
+func (s *EventStore) FlagIP(ctx context.Context, ipAddress string, since time.Time, reason string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return 0, s.Err
+	}
+
+	var flagged int64
+	for i := range s.clicks {
+		click := &s.clicks[i]
+		if click.IPAddress == ipAddress && !click.Timestamp.Before(since) && !click.Invalid {
+			click.Invalid, click.FraudScore = true, 1
+			click.FraudReasons = appendReason(click.FraudReasons, reason)
+			flagged++
+		}
+	}
+	for i := range s.impressions {
+		impression := &s.impressions[i]
+		if impression.IPAddress == ipAddress && !impression.Timestamp.Before(since) && !impression.Invalid {
+			impression.Invalid, impression.FraudScore = true, 1
+			impression.FraudReasons = appendReason(impression.FraudReasons, reason)
+			flagged++
+		}
+	}
+	return flagged, nil
+}
+
+func (s *EventStore) SaveImpressions(ctx context.Context, impressions []models.ImpressionEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+	for _, impression := range impressions {
+		if impression.ID == 0 {
+			impression.ID = s.nextID
+			s.nextID++
+		}
+		if impression.CreatedAt.IsZero() {
+			impression.CreatedAt = time.Now()
+		}
+		s.impressions = append(s.impressions, impression)
+	}
+	return nil
+}
+
+func (s *EventStore) CountImpressions(ctx context.Context, query events.Query) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	return int64(len(s.matchImpressions(query))), nil
+}
+
+func (s *EventStore) ViewabilityStats(ctx context.Context, query events.Query, minPercent float64, minMS int64) (models.ViewabilityStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return models.ViewabilityStats{}, s.Err
+	}
+
+	var stats models.ViewabilityStats
+	for _, impression := range s.matchImpressions(query) {
+		if impression.TimeInViewMS <= 0 && impression.PercentInView <= 0 {
+			continue
+		}
+		stats.Measured++
+		if impression.PercentInView >= minPercent && impression.TimeInViewMS >= minMS {
+			stats.Viewable++
+		}
+	}
+	if stats.Measured > 0 {
+		stats.ViewableRate = float64(stats.Viewable) / float64(stats.Measured)
+	}
+	return stats, nil
+}
+
+func (s *EventStore) SessionStats(ctx context.Context, query events.Query) (models.SessionStats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.Err != nil {
+		return models.SessionStats{}, s.Err
+	}
+
+	perSession := map[string]int64{}
+	for _, click := range s.match(query) {
+		if click.SessionID != "" {
+			perSession[click.SessionID]++
+		}
+	}
+	for _, impression := range s.matchImpressions(query) {
+		if impression.SessionID != "" {
+			perSession[impression.SessionID]++
+		}
+	}
+
+	var stats models.SessionStats
+	for _, events := range perSession {
+		stats.Sessions++
+		stats.Events += events
+		if events == 1 {
+			stats.Bounced++
+		}
+	}
+	stats.Finish()
+	return stats, nil
+}
+
+// Clicks returns a copy of every stored click in insertion order.
+func (s *EventStore) Clicks() []models.ClickEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.ClickEvent(nil), s.clicks...)
+}
+
+func (s *EventStore) match(query events.Query) []models.ClickEvent {
+	var matched []models.ClickEvent
+	for _, click := range s.clicks {
+		if query.AdID != 0 && click.AdID != query.AdID {
+			continue
+		}
+		if !query.Since.IsZero() && click.Timestamp.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && !click.Timestamp.Before(query.Until) {
+			continue
+		}
+		if query.ValidOnly && click.Invalid {
+			continue
+		}
+		matched = append(matched, click)
+	}
+	return matched
+}
+
+func (s *EventStore) matchImpressions(query events.Query) []models.ImpressionEvent {
+	var matched []models.ImpressionEvent
+	for _, impression := range s.impressions {
+		if query.AdID != 0 && impression.AdID != query.AdID {
+			continue
+		}
+		if !query.Since.IsZero() && impression.Timestamp.Before(query.Since) {
+			continue
+		}
+		if !query.Until.IsZero() && !impression.Timestamp.Before(query.Until) {
+			continue
+		}
+		if query.ValidOnly && impression.Invalid {
+			continue
+		}
+		matched = append(matched, impression)
+	}
+	return matched
+}
+
+func appendReason(reasons, reason string) string {
+	if reasons == "" {
+		return reason
+	}
+	return reasons + "," + reason
+}
+
+// percentile interpolates linearly between the closest ranks, matching
+// Postgres percentile_cont. sorted must be ascending and non-empty.
+func percentile(sorted []int64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return float64(sorted[lower])
+	}
+	frac := rank - float64(lower)
+	return float64(sorted[lower]) + frac*float64(sorted[lower+1]-sorted[lower])
+}
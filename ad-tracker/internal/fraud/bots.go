@@ -0,0 +1,108 @@
+package fraud
+
+import (
+	"bufio"
+	_ "embed"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed bots.txt
+var defaultBotSignatures string
+
+// BotList matches User-Agents against known bot signatures.
+type BotList struct {
+	mu         sync.RWMutex
+	signatures []string
+}
+
+// LoadBotList reads one signature per line, skipping blanks and # comments.
+func LoadBotList(r io.Reader) (*BotList, error) {
+	list := &BotList{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list.signatures = append(list.signatures, strings.ToLower(line))
+	}
+	return list, scanner.Err()
+}
+
+// DefaultBotList is the signature list shipped with the binary.
+func DefaultBotList() *BotList {
+	list, _ := LoadBotList(strings.NewReader(defaultBotSignatures))
+	return list
+}
+
+// LoadBotListFile loads the default list plus the signatures in path.
+func LoadBotListFile(path string) (*BotList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	extra, err := LoadBotList(file)
+	if err != nil {
+		return nil, err
+	}
+	list := DefaultBotList()
+	list.signatures = append(list.signatures, extra.signatures...)
+	return list, nil
+}
+
+// Match returns the first signature contained in userAgent.
+func (l *BotList) Match(userAgent string) (string, bool) {
+	if userAgent == "" {
+		return "", false
+	}
+	userAgent = strings.ToLower(userAgent)
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, signature := range l.signatures {
+		if strings.Contains(userAgent, signature) {
+			return signature, true
+		}
+	}
+	return "", false
+}
+
+func (l *BotList) Len() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return len(l.signatures)
+}
+
+// Replace swaps in the signatures of other, so rules and handlers holding l
+// pick up a reloaded list.
+func (l *BotList) Replace(other *BotList) {
+	other.mu.RLock()
+	signatures := other.signatures
+	other.mu.RUnlock()
+
+	l.mu.Lock()
+	l.signatures = signatures
+	l.mu.Unlock()
+}
+
+// KnownBotRule fires for User-Agents on the bot list.
+type KnownBotRule struct {
+	weight float64
+	list   *BotList
+}
+
+func NewKnownBotRule(weight float64, list *BotList) *KnownBotRule {
+	return &KnownBotRule{weight: weight, list: list}
+}
+
+func (r *KnownBotRule) Name() string    { return "known_bot" }
+func (r *KnownBotRule) Weight() float64 { return r.weight }
+
+func (r *KnownBotRule) Match(signal Signal) bool {
+	_, ok := r.list.Match(signal.UserAgent)
+	return ok
+}
@@ -0,0 +1,89 @@
+// Package fraud scores tracking events in the ingestion path. Each Rule
+// contributes a weight when it fires; events whose combined score reaches the
+// threshold are tagged invalid and can be excluded from analytics.
+package fraud
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	EventClick      = "click"
+	EventImpression = "impression"
+)
+
+// Signal is what the rules see of an incoming event.
+type Signal struct {
+	Type      string
+	AdID      uint
+	UserID    string
+	IPAddress string
+	UserAgent string
+	Timestamp time.Time
+}
+
+// Rule inspects a signal and reports whether it looks fraudulent. Rules may
+// keep state across calls and must be safe for concurrent use.
+type Rule interface {
+	Name() string
+	Weight() float64
+	Match(signal Signal) bool
+}
+
+// Result is the outcome of scoring a single event.
+type Result struct {
+	Score   float64
+	Reasons []string
+	Invalid bool
+}
+
+// ReasonString joins the fired rule names for storage.
+func (r Result) ReasonString() string {
+	return strings.Join(r.Reasons, ",")
+}
+
+type Scorer struct {
+	rules     []Rule
+	threshold float64
+}
+
+// NewScorer combines rules; an event is invalid once the summed weight of
+// the rules it trips reaches threshold. Scores are capped at 1.
+func NewScorer(threshold float64, rules ...Rule) *Scorer {
+	return &Scorer{rules: rules, threshold: threshold}
+}
+
+func (s *Scorer) Score(signal Signal) Result {
+	var result Result
+	for _, rule := range s.rules {
+		if rule.Match(signal) {
+			result.Score += rule.Weight()
+			result.Reasons = append(result.Reasons, rule.Name())
+		}
+	}
+	if result.Score > 1 {
+		result.Score = 1
+	}
+	result.Invalid = len(result.Reasons) > 0 && result.Score >= s.threshold
+	return result
+}
+
+func (s *Scorer) Rules() []string {
+	names := make([]string, 0, len(s.rules))
+	for _, rule := range s.rules {
+		names = append(names, rule.Name())
+	}
+	return names
+}
+
+// DefaultScorer applies the stateless and rate rules with conservative
+// limits. Datacenter ranges are deployment specific and configured in main.
+func DefaultScorer() *Scorer {
+	return NewScorer(0.5,
+		NewKnownBotRule(1, DefaultBotList()),
+		NewMissingUserAgentRule(0.4),
+		NewClickRateRule(0.8, 30),
+		NewUserAgentRotationRule(0.4, 5, 10*time.Minute),
+	)
+}
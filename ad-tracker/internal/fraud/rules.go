@@ -0,0 +1,178 @@
+package fraud
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MissingUserAgentRule fires for requests without a User-Agent header, which
+// real browsers always send.
+type MissingUserAgentRule struct {
+	weight float64
+}
+
+func NewMissingUserAgentRule(weight float64) *MissingUserAgentRule {
+	return &MissingUserAgentRule{weight: weight}
+}
+
+func (r *MissingUserAgentRule) Name() string    { return "missing_user_agent" }
+func (r *MissingUserAgentRule) Weight() float64 { return r.weight }
+
+func (r *MissingUserAgentRule) Match(signal Signal) bool {
+	return strings.TrimSpace(signal.UserAgent) == ""
+}
+
+// DatacenterRule fires for source addresses inside known hosting provider
+// ranges, where human traffic is rare.
+type DatacenterRule struct {
+	weight float64
+	ranges []*net.IPNet
+}
+
+func NewDatacenterRule(weight float64, cidrs []string) (*DatacenterRule, error) {
+	rule := &DatacenterRule{weight: weight}
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid datacenter range %q: %w", cidr, err)
+		}
+		rule.ranges = append(rule.ranges, network)
+	}
+	return rule, nil
+}
+
+func (r *DatacenterRule) Name() string    { return "datacenter_ip" }
+func (r *DatacenterRule) Weight() float64 { return r.weight }
+
+func (r *DatacenterRule) Match(signal Signal) bool {
+	ip := net.ParseIP(signal.IPAddress)
+	if ip == nil {
+		return false
+	}
+	for _, network := range r.ranges {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClickRateRule fires when a single IP sends more clicks in a minute than a
+// person plausibly could.
+type ClickRateRule struct {
+	weight       float64
+	maxPerMinute int
+
+	mu      sync.Mutex
+	minute  int64
+	counter map[string]int
+}
+
+func NewClickRateRule(weight float64, maxPerMinute int) *ClickRateRule {
+	return &ClickRateRule{weight: weight, maxPerMinute: maxPerMinute, counter: make(map[string]int)}
+}
+
+// SetLimit changes the threshold without resetting the current minute.
+func (r *ClickRateRule) SetLimit(maxPerMinute int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxPerMinute = maxPerMinute
+}
+
+func (r *ClickRateRule) Name() string    { return "click_rate" }
+func (r *ClickRateRule) Weight() float64 { return r.weight }
+
+func (r *ClickRateRule) Match(signal Signal) bool {
+	if signal.Type != EventClick || signal.IPAddress == "" {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Counters reset every wall-clock minute, which also bounds memory.
+	minute := signal.Timestamp.Unix() / 60
+	if minute != r.minute {
+		r.minute = minute
+		r.counter = make(map[string]int)
+	}
+	r.counter[signal.IPAddress]++
+	return r.counter[signal.IPAddress] > r.maxPerMinute
+}
+
+// UserAgentRotationRule fires when one IP presents more distinct User-Agents
+// within the window than a household would, the signature of scripts that
+// spoof a random browser per request.
+type UserAgentRotationRule struct {
+	weight    float64
+	maxAgents int
+	window    time.Duration
+
+	mu        sync.Mutex
+	seen      map[string]map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewUserAgentRotationRule(weight float64, maxAgents int, window time.Duration) *UserAgentRotationRule {
+	return &UserAgentRotationRule{
+		weight:    weight,
+		maxAgents: maxAgents,
+		window:    window,
+		seen:      make(map[string]map[string]time.Time),
+	}
+}
+
+// SetLimit changes the threshold, keeping the agents already seen.
+func (r *UserAgentRotationRule) SetLimit(maxAgents int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxAgents = maxAgents
+}
+
+func (r *UserAgentRotationRule) Name() string    { return "user_agent_ip_mismatch" }
+func (r *UserAgentRotationRule) Weight() float64 { return r.weight }
+
+func (r *UserAgentRotationRule) Match(signal Signal) bool {
+	if signal.IPAddress == "" || signal.UserAgent == "" {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := signal.Timestamp
+	cutoff := now.Add(-r.window)
+	if now.Sub(r.lastSweep) > r.window {
+		for ip, agents := range r.seen {
+			prune(agents, cutoff)
+			if len(agents) == 0 {
+				delete(r.seen, ip)
+			}
+		}
+		r.lastSweep = now
+	}
+
+	agents := r.seen[signal.IPAddress]
+	if agents == nil {
+		agents = make(map[string]time.Time)
+		r.seen[signal.IPAddress] = agents
+	}
+	prune(agents, cutoff)
+	agents[signal.UserAgent] = now
+	return len(agents) > r.maxAgents
+}
+
+func prune(agents map[string]time.Time, cutoff time.Time) {
+	for agent, at := range agents {
+		if at.Before(cutoff) {
+			delete(agents, agent)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (s *Server) ListAccounts(c *gin.Context) {
+	accounts, err := s.accountRepository.List()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list accounts")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list accounts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+}
+
+// CreateAccount creates an account with a fresh link signing secret. The
+// secret is only returned in this response.
+func (s *Server) CreateAccount(c *gin.Context) {
+	var req models.AccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := newSigningSecret()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to generate signing secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
+		return
+	}
+
+	account := models.Account{Name: req.Name, SigningSecret: secret, Active: true, PrivacyMode: req.PrivacyMode}
+	if account.PrivacyMode == "" {
+		account.PrivacyMode = models.PrivacyModeStandard
+	}
+	if err := s.accountRepository.Create(&account); err != nil {
+		s.logger.WithError(err).Error("Failed to create account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create account"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"account": account, "signing_secret": secret})
+}
+
+// RotateSigningSecret replaces an account's signing secret. Links signed with
+// the previous secret stop working immediately.
+func (s *Server) RotateSigningSecret(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account id"})
+		return
+	}
+
+	secret, err := newSigningSecret()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to generate signing secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate signing secret"})
+		return
+	}
+
+	if err := s.accountRepository.UpdateSigningSecret(uint(id), secret); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		s.logger.WithError(err).Error("Failed to rotate signing secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate signing secret"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"signing_secret": secret})
+}
+
+// UpdateAccount changes an account's privacy mode. Strict accounts stop
+// storing identifiers for unconsented traffic once the consent policy
+// refreshes.
+func (s *Server) UpdateAccount(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account id"})
+		return
+	}
+
+	var req models.AccountUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.accountRepository.UpdatePrivacyMode(uint(id), req.PrivacyMode); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		s.logger.WithError(err).Error("Failed to update account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+		return
+	}
+	if err := s.consent.Refresh(); err != nil {
+		s.logger.WithError(err).Warn("Failed to refresh consent policy")
+	}
+
+	account, err := s.accountRepository.Get(uint(id))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+		return
+	}
+	c.JSON(http.StatusOK, account)
+}
+
+func newSigningSecret() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func (s *Server) accountFromParam(c *gin.Context) (*models.Account, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account id"})
+		return nil, false
+	}
+
+	account, err := s.accountRepository.Get(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+		return nil, false
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load account"})
+		return nil, false
+	}
+	return account, true
+}
@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"ad-tracking-system/internal/cache"
+	"ad-tracking-system/internal/services"
+)
+
+const defaultAdCacheTTL = 10 * time.Second
+
+// SetAdCache moves ad lookups onto store, typically Redis so that
+// invalidation reaches every replica. A ttl of zero disables caching.
+func (s *Server) SetAdCache(store cache.Cache, ttl time.Duration) {
+	s.ads = services.NewAdCache(s.adRepository, store, ttl, s.logger)
+}
+
+// invalidateAds is called after ads are created or changed.
+func (s *Server) invalidateAds(ctx context.Context, ids ...uint) {
+	s.ads.Invalidate(ctx, ids...)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ListAdGroups returns all ad groups, or one campaign's with ?campaign_id=.
+func (s *Server) ListAdGroups(c *gin.Context) {
+	var campaignID uint64
+	if raw := c.Query("campaign_id"); raw != "" {
+		var err error
+		if campaignID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign_id"})
+			return
+		}
+	}
+
+	groups, err := s.adGroupRepository.List(uint(campaignID))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list ad groups")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list ad groups"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ad_groups": groups})
+}
+
+func (s *Server) CreateAdGroup(c *gin.Context) {
+	var req models.AdGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err := s.campaignRepository.Get(req.CampaignID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown campaign"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ad group"})
+		return
+	}
+
+	group := models.AdGroup{CampaignID: req.CampaignID, Name: req.Name, Active: true, AdIDs: []uint{}}
+	if req.Active != nil {
+		group.Active = *req.Active
+	}
+	if err := s.adGroupRepository.Create(&group); err != nil {
+		s.logger.WithError(err).Error("Failed to create ad group")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ad group"})
+		return
+	}
+	c.JSON(http.StatusCreated, group)
+}
+
+func (s *Server) GetAdGroup(c *gin.Context) {
+	group, ok := s.adGroupFromParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// UpdateAdGroup renames a group or pauses and resumes serving its ads.
+func (s *Server) UpdateAdGroup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad group id"})
+		return
+	}
+
+	var req models.AdGroupUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	group, err := s.adGroupRepository.Update(uint(id), req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad group not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to update ad group")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ad group"})
+		return
+	}
+	if req.Active != nil {
+		s.invalidateAds(c.Request.Context(), group.AdIDs...)
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// DeleteAdGroup removes a group; its ads stay in the campaign ungrouped.
+func (s *Server) DeleteAdGroup(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad group id"})
+		return
+	}
+
+	adIDs, err := s.adGroupRepository.Delete(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad group not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to delete ad group")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete ad group"})
+		return
+	}
+	s.invalidateAds(c.Request.Context(), adIDs...)
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// SetAdGroupAds replaces the group's ads with the listed ones, which must
+// belong to the group's campaign.
+func (s *Server) SetAdGroupAds(c *gin.Context) {
+	group, ok := s.adGroupFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.AdGroupAdsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changed, err := s.adGroupRepository.SetAds(*group, req.AdIDs)
+	if errors.Is(err, repositories.ErrAdOutsideCampaign) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ad_ids must be ads of campaign %d", group.CampaignID)})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to set ad group ads")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ad group"})
+		return
+	}
+	if len(changed) > 0 {
+		s.invalidateAds(c.Request.Context(), changed...)
+	}
+
+	if group.AdIDs, err = s.adGroupRepository.AdIDs(group.ID); err != nil {
+		s.logger.WithError(err).Error("Failed to load ad group ads")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load ad group"})
+		return
+	}
+	c.JSON(http.StatusOK, group)
+}
+
+// GetAdGroupSummary aggregates analytics across the group's current ads,
+// like the campaign summary. It takes the same timeframe, valid_only and
+// format=csv parameters.
+func (s *Server) GetAdGroupSummary(c *gin.Context) {
+	group, ok := s.adGroupFromParam(c)
+	if !ok {
+		return
+	}
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
+	since := time.Now().UTC().Add(-duration)
+
+	summary, err := s.adGroupSummary(c, *group, timeframe, since, c.Query("valid_only") == "true")
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load ad group summary")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load summary"})
+		return
+	}
+	if csvRequested(c) {
+		s.writeAnalyticsCSV(c, fmt.Sprintf("ad-group-%d-summary.csv", group.ID), summary.Ads)
+		return
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	if notModified(c, etagFor(summary)) {
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+func (s *Server) adGroupSummary(c *gin.Context, group models.AdGroup, timeframe string, since time.Time, validOnly bool) (models.AdGroupSummary, error) {
+	var summary models.AdGroupSummary
+	key := services.AnalyticsKey("ad_group:"+strconv.FormatUint(uint64(group.ID), 10), timeframe, "total", validOnly)
+	err := s.cachedAnalytics(c, key, &summary, func() (interface{}, error) {
+		summary := models.AdGroupSummary{
+			AdGroupID:  group.ID,
+			CampaignID: group.CampaignID,
+			Name:       group.Name,
+			Since:      since,
+			Ads:        make([]models.AnalyticsResponse, 0, len(group.AdIDs)),
+		}
+		for _, adID := range group.AdIDs {
+			analytics := s.analyticsRepository.GetAdAnalytics(adID, since, validOnly)
+			summary.ClickCount += analytics.ClickCount
+			summary.LastHour += analytics.LastHour
+			summary.LastDay += analytics.LastDay
+			summary.Ads = append(summary.Ads, analytics)
+		}
+		return summary, nil
+	})
+	return summary, err
+}
+
+func (s *Server) adGroupFromParam(c *gin.Context) (*models.AdGroup, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad group id"})
+		return nil, false
+	}
+
+	group, err := s.adGroupRepository.Get(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad group not found"})
+		return nil, false
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load ad group")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load ad group"})
+		return nil, false
+	}
+	return group, true
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (s *Server) GetAlertEvaluator() *services.AlertEvaluator {
+	return s.alerts
+}
+
+func (s *Server) ListAlertRules(c *gin.Context) {
+	rules, err := s.alertRepository.ListRules()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list alert rules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alert rules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (s *Server) CreateAlertRule(c *gin.Context) {
+	var req models.AlertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.AlertRule{
+		Name:        req.Name,
+		AccountID:   req.AccountID,
+		CampaignID:  req.CampaignID,
+		AdID:        req.AdID,
+		Metric:      req.Metric,
+		Operator:    req.Operator,
+		Threshold:   req.Threshold,
+		Window:      req.Window,
+		Baseline:    req.Baseline,
+		MinBaseline: req.MinBaseline,
+		Channel:     req.Channel,
+		URL:         req.URL,
+		Template:    req.Template,
+		Cooldown:    req.Cooldown,
+		Active:      true,
+		State:       models.AlertResolved,
+	}
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+	if err := services.ValidateRule(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.alerts.CheckURL(c.Request.Context(), rule.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.alertRepository.CreateRule(&rule); err != nil {
+		s.logger.WithError(err).Error("Failed to create alert rule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert rule"})
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+func (s *Server) DeleteAlertRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule id"})
+		return
+	}
+
+	err = s.alertRepository.DeleteRule(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to delete alert rule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete alert rule"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListAlertEvents returns the rule's recent firing and resolution history.
+func (s *Server) ListAlertEvents(c *gin.Context) {
+	rule, ok := s.alertRuleFromParam(c)
+	if !ok {
+		return
+	}
+
+	events, err := s.alertRepository.ListEvents(rule.ID, 100)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list alert events")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list alert events"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// TestAlertRule sends a sample notification without changing the rule state.
+func (s *Server) TestAlertRule(c *gin.Context) {
+	rule, ok := s.alertRuleFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := s.alerts.Notify(c.Request.Context(), rule, services.TestNotification(rule)); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "delivered"})
+}
+
+func (s *Server) alertRuleFromParam(c *gin.Context) (*models.AlertRule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alert rule id"})
+		return nil, false
+	}
+
+	rule, err := s.alertRepository.GetRule(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+		return nil, false
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load alert rule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load alert rule"})
+		return nil, false
+	}
+	return rule, true
+}
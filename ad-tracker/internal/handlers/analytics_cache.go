@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/cache"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAnalyticsCacheTTL   = 15 * time.Second
+	defaultAnalyticsCacheStale = time.Minute
+)
+
+// SetAnalyticsCache configures how long analytics results are reused and how
+// long past that they may be served while being recomputed. A ttl of zero
+// disables caching.
+func (s *Server) SetAnalyticsCache(store cache.Cache, ttl, stale time.Duration) {
+	s.analyticsCache = services.NewAnalyticsCache(store, ttl, stale, s.logger)
+}
+
+// cachedAnalytics fetches through the analytics cache and reports the
+// outcome in the X-Cache header.
+func (s *Server) cachedAnalytics(c *gin.Context, key string, dst interface{}, load func() (interface{}, error)) error {
+	result, err := s.analyticsCache.Fetch(c.Request.Context(), key, dst, load)
+	if err == nil {
+		c.Header("X-Cache", result)
+	}
+	return err
+}
+
+func (s *Server) adAnalytics(c *gin.Context, adID uint, timeframe string, since time.Time, validOnly bool) (models.AnalyticsResponse, error) {
+	var analytics models.AnalyticsResponse
+	key := services.AnalyticsKey("ad:"+strconv.FormatUint(uint64(adID), 10), timeframe, "total", validOnly)
+	err := s.cachedAnalytics(c, key, &analytics, func() (interface{}, error) {
+		return s.analyticsRepository.GetAdAnalytics(adID, since, validOnly), nil
+	})
+	return analytics, err
+}
+
+func (s *Server) allAnalytics(c *gin.Context, timeframe string, since time.Time, validOnly bool) ([]models.AnalyticsResponse, error) {
+	var analytics []models.AnalyticsResponse
+	key := services.AnalyticsKey("all", timeframe, "total", validOnly)
+	err := s.cachedAnalytics(c, key, &analytics, func() (interface{}, error) {
+		return s.analyticsRepository.GetAllAnalytics(since, validOnly), nil
+	})
+	return analytics, err
+}
+
+// campaignSummary reads the campaign's summary over window, from the
+// precomputed counters when the analytics reader keeps them. fresh counts
+// raw events and skips the cache.
+func (s *Server) campaignSummary(c *gin.Context, campaign models.Campaign, window summaryRange, validOnly, fresh bool) (models.CampaignSummary, error) {
+	scope := "campaign:" + strconv.FormatUint(uint64(campaign.ID), 10)
+	key := services.AnalyticsKey(scope, window.timeframe, "total", validOnly)
+	if window.ranged {
+		granularity := "total"
+		if window.daily {
+			granularity = "day"
+		}
+		key = services.AnalyticsKey(scope, window.from.Format(time.RFC3339)+"/"+window.until.Format(time.RFC3339), granularity, validOnly)
+	}
+
+	load := func() (interface{}, error) {
+		adIDs, err := s.campaignRepository.AdIDs(campaign.ID)
+		if err != nil {
+			return nil, err
+		}
+		if precomputed, ok := s.analyticsRepository.(repositories.PrecomputedSummaries); ok && !fresh {
+			var until time.Time
+			if window.ranged {
+				until = window.until
+			}
+			if summary, ok := precomputed.GetPrecomputedSummary(campaign, adIDs, window.from, until, validOnly, window.daily); ok {
+				return summary, nil
+			}
+		}
+		if window.ranged {
+			return s.analyticsRepository.GetCampaignRange(campaign, adIDs, window.from, window.until, validOnly, window.daily), nil
+		}
+		return s.analyticsRepository.GetCampaignSummary(campaign, adIDs, window.from, validOnly), nil
+	}
+
+	var summary models.CampaignSummary
+	if !fresh {
+		return summary, s.cachedAnalytics(c, key, &summary, load)
+	}
+	result, err := s.analyticsCache.Bypass(&summary, load)
+	if err == nil {
+		c.Header("X-Cache", result)
+	}
+	return summary, err
+}
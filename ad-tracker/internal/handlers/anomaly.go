@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/anomaly"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultAnomalyLimit = 100
+	maxAnomalyLimit     = 1000
+)
+
+var anomalyMetrics = []string{models.AlertMetricClicks, models.AlertMetricImpressions}
+
+// GetAnomalies lists the unusual hours anomaly detection recorded within
+// ?timeframe= (default 7d), newest first, optionally for one campaign,
+// metric or detector.
+func (s *Server) GetAnomalies(c *gin.Context) {
+	filter := repositories.AnomalyFilter{
+		Metric:   c.Query("metric"),
+		Detector: c.Query("detector"),
+		Limit:    defaultAnomalyLimit,
+	}
+	if value := c.Query("campaign_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign_id"})
+			return
+		}
+		filter.CampaignID = uint(id)
+	}
+	if filter.Metric != "" && !slices.Contains(anomalyMetrics, filter.Metric) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "metric must be one of " + strings.Join(anomalyMetrics, ", ")})
+		return
+	}
+	if filter.Detector != "" && !slices.Contains(anomaly.Names, filter.Detector) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "detector must be one of " + strings.Join(anomaly.Names, ", ")})
+		return
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAnomalyLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxAnomalyLimit)})
+			return
+		}
+		filter.Limit = limit
+	}
+	timeframe, err := s.parseTimeframe(c.DefaultQuery("timeframe", "7d"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filter.Since = time.Now().UTC().Add(-timeframe)
+
+	anomalies, err := s.anomalyRepository.List(filter)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list anomalies")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list anomalies"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"anomalies": anomalies})
+}
@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/fraud"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/middleware"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/signing"
+	"ad-tracking-system/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// eventBatch collects checked batch entries until they are stored.
+// results has one entry per line or array element seen; the pending clicks
+// and impressions point into it by position.
+type eventBatch struct {
+	results     []models.BatchEventResult
+	clicks      []batchClick
+	impressions []batchImpression
+}
+
+// batchClick and batchImpression are accepted batch entries waiting to be
+// stored, with what their follow-up needs.
+type batchClick struct {
+	position  int
+	ad        models.Ad
+	req       models.ClickRequest
+	event     models.ClickEvent
+	permitted bool
+}
+
+type batchImpression struct {
+	position int
+	ad       models.Ad
+	req      models.ImpressionRequest
+	event    models.ImpressionEvent
+}
+
+func (b *eventBatch) recorded() int {
+	return len(b.clicks) + len(b.impressions)
+}
+
+// PostEventBatch records up to MaxBatchEvents clicks and impressions from
+// one client. Each entry is checked like a single click or impression;
+// refused entries are reported by index and the rest are stored in one
+// transaction, all or nothing. Clicks skip the click queue.
+//
+// An application/x-ndjson body is streamed instead: see streamEventBatch.
+func (s *Server) PostEventBatch(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		s.status.ObserveIngest(time.Since(start))
+	}()
+
+	if c.ContentType() == middleware.NDJSONContentType {
+		s.streamEventBatch(c)
+		return
+	}
+
+	var req models.EventBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+	if s.dropBot(c, fraud.EventClick) {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	batch := eventBatch{results: make([]models.BatchEventResult, 0, len(req.Events))}
+	for i, raw := range req.Events {
+		s.addBatchEntry(c, &batch, i, raw)
+	}
+	if err := s.commitBatch(c, &batch); err != nil {
+		s.logger.WithError(err).Error("Failed to save event batch")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recorded": batch.recorded(),
+		"rejected": len(batch.results) - batch.recorded(),
+		"results":  batch.results,
+	})
+}
+
+// streamEventBatch takes one entry per line and answers in NDJSON as well:
+// entries are stored MaxBatchEvents at a time, each chunk in its own
+// transaction, and the chunk's results are written as soon as it commits.
+// Blank lines are skipped and do not count towards the index. A final
+// {"recorded":n,"rejected":m} line means the whole body was processed; an
+// {"error":...} line instead means the entries after the last result were
+// not stored.
+func (s *Server) streamEventBatch(c *gin.Context) {
+	if s.dropBot(c, fraud.EventClick) {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+	// HTTP/1.x stops reading the body once results are flushed unless full
+	// duplex is on; HTTP/2 always is, and reports it as unsupported
+	_ = http.NewResponseController(c.Writer).EnableFullDuplex()
+
+	c.Header("Content-Type", middleware.NDJSONContentType)
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+
+	var batch eventBatch
+	recorded, rejected := 0, 0
+	flush := func() bool {
+		if err := s.commitBatch(c, &batch); err != nil {
+			s.logger.WithError(err).Error("Failed to save event batch")
+			encoder.Encode(gin.H{"error": "Failed to record events"})
+			return false
+		}
+		for _, result := range batch.results {
+			encoder.Encode(result)
+		}
+		c.Writer.Flush()
+		recorded += batch.recorded()
+		rejected += len(batch.results) - batch.recorded()
+		batch = eventBatch{}
+		return true
+	}
+
+	scanner := bufio.NewScanner(c.Request.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxBatchLine)
+	for index := 0; scanner.Scan(); {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		s.addBatchEntry(c, &batch, index, line)
+		index++
+		if len(batch.results) == models.MaxBatchEvents && !flush() {
+			return
+		}
+	}
+	// Entries read before a failure are still stored
+	if !flush() {
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		encoder.Encode(gin.H{"error": "Failed to read events: " + err.Error()})
+		return
+	}
+	encoder.Encode(gin.H{"recorded": recorded, "rejected": rejected})
+}
+
+// addBatchEntry checks one entry and adds it to the batch, recording why
+// when it is refused.
+func (s *Server) addBatchEntry(c *gin.Context, batch *eventBatch, index int, raw json.RawMessage) {
+	position := len(batch.results)
+	batch.results = append(batch.results, models.BatchEventResult{Index: index, Status: models.BatchEventRejected})
+
+	var problem *eventProblem
+	switch batchEventType(raw) {
+	case fraud.EventClick:
+		var click batchClick
+		if click, problem = s.prepareBatchClick(c, raw); problem == nil {
+			click.position = position
+			batch.clicks = append(batch.clicks, click)
+		}
+	case fraud.EventImpression:
+		var impression batchImpression
+		if impression, problem = s.prepareBatchImpression(c, raw); problem == nil {
+			impression.position = position
+			batch.impressions = append(batch.impressions, impression)
+		}
+	default:
+		problem = fieldProblem("type", "must be one of: click, impression")
+	}
+	if problem != nil {
+		batch.results[position].Error, batch.results[position].Fields = problem.message, problem.fields
+	}
+}
+
+// commitBatch stores the batch's accepted entries, runs their follow-up
+// and marks them recorded.
+func (s *Server) commitBatch(c *gin.Context, batch *eventBatch) error {
+	if err := s.saveBatch(c, batch.clicks, batch.impressions); err != nil {
+		return err
+	}
+	for _, click := range batch.clicks {
+		s.clickRecorded(c, click.ad, click.req, click.event, click.permitted)
+		batch.results[click.position].Status = models.BatchEventRecorded
+		batch.results[click.position].ClickID = click.event.ClickID
+	}
+	for _, impression := range batch.impressions {
+		s.impressionRecorded(c, impression.ad, impression.req, impression.event)
+		batch.results[impression.position].Status = models.BatchEventRecorded
+	}
+	return nil
+}
+
+// maxBatchLine bounds one NDJSON entry; single events are far smaller.
+const maxBatchLine = 64 << 10
+
+// batchEventType reads the "type" of a batch entry; malformed entries have
+// none.
+func batchEventType(raw json.RawMessage) string {
+	var entry struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return ""
+	}
+	return entry.Type
+}
+
+func (s *Server) prepareBatchClick(c *gin.Context, raw json.RawMessage) (batchClick, *eventProblem) {
+	var req models.ClickRequest
+	if problem := decodeBatchEntry(raw, &req); problem != nil {
+		return batchClick{}, problem
+	}
+	ad, problem := s.batchAd(c, fraud.EventClick, req.AdID)
+	if problem != nil {
+		return batchClick{}, problem
+	}
+	if problem := s.signatureProblem(c, signing.KindClick, req.AdID, req.LinkSignature.Params(req.UserID)); problem != nil {
+		return batchClick{}, problem
+	}
+	event, permitted, problem := s.prepareClick(c, ad, req)
+	if problem != nil {
+		return batchClick{}, problem
+	}
+	return batchClick{ad: ad, req: req, event: event, permitted: permitted}, nil
+}
+
+func (s *Server) prepareBatchImpression(c *gin.Context, raw json.RawMessage) (batchImpression, *eventProblem) {
+	var req models.ImpressionRequest
+	if problem := decodeBatchEntry(raw, &req); problem != nil {
+		return batchImpression{}, problem
+	}
+	ad, problem := s.batchAd(c, fraud.EventImpression, req.AdID)
+	if problem != nil {
+		return batchImpression{}, problem
+	}
+	if problem := s.signatureProblem(c, signing.KindPixel, req.AdID, req.LinkSignature.Params(req.UserID)); problem != nil {
+		return batchImpression{}, problem
+	}
+	event, problem := s.prepareImpression(c, ad, req)
+	if problem != nil {
+		return batchImpression{}, problem
+	}
+	return batchImpression{ad: ad, req: req, event: event}, nil
+}
+
+// decodeBatchEntry decodes and validates one entry as binding would.
+func decodeBatchEntry(raw json.RawMessage, req interface{}) *eventProblem {
+	err := json.Unmarshal(raw, req)
+	if err == nil {
+		err = binding.Validator.ValidateStruct(req)
+	}
+	if err == nil {
+		return nil
+	}
+	if fields := validation.Fields(err); fields != nil {
+		return &eventProblem{status: http.StatusBadRequest, message: "Invalid request", fields: fields}
+	}
+	return &eventProblem{status: http.StatusBadRequest, message: err.Error()}
+}
+
+// batchAd applies the blocklist and looks up the entry's ad.
+func (s *Server) batchAd(c *gin.Context, eventType string, adID uint) (models.Ad, *eventProblem) {
+	if s.blocklist.Blocked(c.ClientIP(), adID) {
+		metrics.BlockedRequests.WithLabelValues(eventType).Inc()
+		return models.Ad{}, &eventProblem{status: http.StatusForbidden, message: "Forbidden"}
+	}
+	ad, err := s.ads.Get(c.Request.Context(), adID)
+	if err != nil {
+		return models.Ad{}, &eventProblem{status: http.StatusNotFound, message: "Ad not found"}
+	}
+	return ad, nil
+}
+
+// saveBatch stores the accepted entries atomically when the event store
+// supports it.
+func (s *Server) saveBatch(c *gin.Context, clicks []batchClick, impressions []batchImpression) error {
+	clickEvents := make([]models.ClickEvent, len(clicks))
+	for i, click := range clicks {
+		clickEvents[i] = click.event
+	}
+	impressionEvents := make([]models.ImpressionEvent, len(impressions))
+	for i, impression := range impressions {
+		impressionEvents[i] = impression.event
+	}
+
+	ctx := c.Request.Context()
+	if saver, ok := s.eventStore.(events.BatchSaver); ok {
+		return saver.SaveBatch(ctx, clickEvents, impressionEvents)
+	}
+	if err := s.eventStore.SaveClicks(ctx, clickEvents); err != nil {
+		return err
+	}
+	return s.eventStore.SaveImpressions(ctx, impressionEvents)
+}
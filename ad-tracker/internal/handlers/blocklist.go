@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (s *Server) GetBlocklist() *services.Blocklist {
+	return s.blocklist
+}
+
+// rejectBlocked answers 403 for addresses on the global blocklist or on the
+// blocklist of the account owning the ad.
+func (s *Server) rejectBlocked(c *gin.Context, eventType string, adID uint) bool {
+	if !s.blocklist.Blocked(c.ClientIP(), adID) {
+		return false
+	}
+	metrics.BlockedRequests.WithLabelValues(eventType).Inc()
+	c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+	return true
+}
+
+func (s *Server) ListBlockedRanges(c *gin.Context) {
+	var accountID *uint
+	if raw := c.Query("account_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account_id"})
+			return
+		}
+		value := uint(id)
+		accountID = &value
+	}
+
+	ranges, err := s.blocklistRepository.List(accountID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list blocked ranges")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list blocked ranges"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"blocked_ranges": ranges})
+}
+
+func (s *Server) CreateBlockedRange(c *gin.Context) {
+	var req models.BlockedRangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	network, err := services.ParseRange(req.CIDR)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.AccountID != nil {
+		if _, err := s.accountRepository.Get(*req.AccountID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Account not found"})
+				return
+			}
+			s.logger.WithError(err).Error("Failed to load account")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create blocked range"})
+			return
+		}
+	}
+
+	blocked := models.BlockedRange{
+		AccountID: req.AccountID,
+		CIDR:      network.String(),
+		Reason:    req.Reason,
+		Source:    models.BlockSourceManual,
+	}
+	if err := s.blocklistRepository.Create(&blocked); err != nil {
+		s.logger.WithError(err).Error("Failed to create blocked range")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create blocked range"})
+		return
+	}
+	s.refreshBlocklist()
+
+	c.JSON(http.StatusCreated, blocked)
+}
+
+func (s *Server) DeleteBlockedRange(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blocked range id"})
+		return
+	}
+
+	err = s.blocklistRepository.Delete(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Blocked range not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to delete blocked range")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete blocked range"})
+		return
+	}
+	s.refreshBlocklist()
+
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// refreshBlocklist applies an admin change on this replica right away; other
+// replicas pick it up on their next periodic refresh.
+func (s *Server) refreshBlocklist() {
+	if err := s.blocklist.Refresh(); err != nil {
+		s.logger.WithError(err).Error("Failed to refresh IP blocklist")
+	}
+}
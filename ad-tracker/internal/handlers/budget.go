@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/cache"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (s *Server) GetBudgetTracker() *services.BudgetTracker {
+	return s.budgets
+}
+
+// SetBudgetCounter keeps running spend in counter, typically Redis so all
+// replicas pause a campaign at the same spend.
+func (s *Server) SetBudgetCounter(counter cache.Counter) {
+	s.budgets.SetCounter(counter)
+}
+
+// SetPacingBurst sets how much of a paced campaign's daily target, as a
+// share of the day, can be served at once.
+func (s *Server) SetPacingBurst(burst time.Duration) {
+	s.budgets.SetPacingBurst(burst)
+}
+
+// viewerCookie may carry a first-party id set by the publisher's page.
+const viewerCookie = "adt_uid"
+
+// viewer identifies the user for frequency caps: ?user_id=, the viewer
+// cookie, or failing both the (minimized) client IP.
+func (s *Server) viewer(c *gin.Context) string {
+	if user := c.Query("user_id"); user != "" {
+		return "user:" + user
+	}
+	if cookie, err := c.Cookie(viewerCookie); err == nil && cookie != "" {
+		return "cookie:" + cookie
+	}
+	if ip := s.storedIP(c); ip != "" {
+		return "ip:" + ip
+	}
+	return ""
+}
+
+// UpdateFrequencyCap changes how often one user may be served a campaign
+// per hour and per day.
+func (s *Server) UpdateFrequencyCap(c *gin.Context) {
+	campaign, ok := s.campaignFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.FrequencyCapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hour, day := campaign.FrequencyCapHour, campaign.FrequencyCapDay
+	if req.Hour != nil {
+		hour = *req.Hour
+	}
+	if req.Day != nil {
+		day = *req.Day
+	}
+
+	updated, err := s.campaignRepository.UpdateFrequencyCap(campaign.ID, hour, day)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to update frequency cap")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update frequency cap"})
+		return
+	}
+	s.budgets.Track(*updated)
+	c.JSON(http.StatusOK, updated)
+}
+
+// UpdateCampaignBudget changes a campaign's budgets and impression target.
+// Raising an exhausted budget resumes serving right away.
+func (s *Server) UpdateCampaignBudget(c *gin.Context) {
+	campaign, ok := s.campaignFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.BudgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	daily, lifetime, target := campaign.DailyBudget, campaign.LifetimeBudget, campaign.DailyImpressionTarget
+	if req.DailyBudget != nil {
+		daily = *req.DailyBudget
+	}
+	if req.LifetimeBudget != nil {
+		lifetime = *req.LifetimeBudget
+	}
+	if req.DailyImpressionTarget != nil {
+		target = *req.DailyImpressionTarget
+	}
+
+	// Save spend so far first, so the resume decision sees it
+	if err := s.budgets.Flush(); err != nil {
+		s.logger.WithError(err).Warn("Failed to save campaign spend before budget change")
+	}
+	updated, err := s.campaignRepository.UpdateBudget(campaign.ID, daily, lifetime, target, time.Now().UTC().Format("2006-01-02"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to update campaign budget")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update campaign budget"})
+		return
+	}
+	s.budgets.Track(*updated)
+	if campaign.BudgetPausedAt != nil && updated.BudgetPausedAt == nil {
+		s.invalidateAds(c.Request.Context())
+	}
+
+	if err := s.auditRepository.Record("admin", "campaign.budget_updated", "campaign", strconv.FormatUint(uint64(campaign.ID), 10), gin.H{
+		"daily_budget":            daily,
+		"lifetime_budget":         lifetime,
+		"daily_impression_target": target,
+	}); err != nil {
+		s.logger.WithError(err).Warn("Failed to record budget update audit event")
+	}
+	c.JSON(http.StatusOK, updated)
+}
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const defaultShareTokenTTL = 30 * 24 * time.Hour
+
+func (s *Server) ListCampaigns(c *gin.Context) {
+	campaigns, err := s.campaignRepository.List()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list campaigns")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list campaigns"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"campaigns": campaigns})
+}
+
+func (s *Server) CreateCampaign(c *gin.Context) {
+	var req models.CampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	campaign := models.Campaign{
+		AccountID:      req.AccountID,
+		Name:           req.Name,
+		Active:         true,
+		CostPerClick:   req.CostPerClick,
+		CostPerMille:   req.CostPerMille,
+		DailyBudget:    req.DailyBudget,
+		LifetimeBudget: req.LifetimeBudget,
+
+		DailyImpressionTarget: req.DailyImpressionTarget,
+		FrequencyCapHour:      req.FrequencyCapHour,
+		FrequencyCapDay:       req.FrequencyCapDay,
+	}
+	if req.Active != nil {
+		campaign.Active = *req.Active
+	}
+
+	if err := s.campaignRepository.Create(&campaign); err != nil {
+		s.logger.WithError(err).Error("Failed to create campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create campaign"})
+		return
+	}
+	s.budgets.Track(campaign)
+	c.JSON(http.StatusCreated, campaign)
+}
+
+// CreateShareToken issues an expiring read-only token for a campaign. The raw
+// token is only returned in this response.
+func (s *Server) CreateShareToken(c *gin.Context) {
+	campaign, ok := s.campaignFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.ShareTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl := defaultShareTokenTTL
+	if req.TTLHours > 0 {
+		ttl = time.Duration(req.TTLHours) * time.Hour
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		s.logger.WithError(err).Error("Failed to generate share token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share token"})
+		return
+	}
+	rawToken := hex.EncodeToString(raw)
+
+	token := models.ShareToken{
+		CampaignID: campaign.ID,
+		Label:      req.Label,
+		ExpiresAt:  time.Now().UTC().Add(ttl),
+	}
+	if err := s.campaignRepository.CreateShareToken(&token, rawToken); err != nil {
+		s.logger.WithError(err).Error("Failed to store share token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"share_token": token,
+		"token":       rawToken,
+		"url":         "/share/" + rawToken + "/summary",
+	})
+}
+
+func (s *Server) RevokeShareToken(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share token id"})
+		return
+	}
+
+	err = s.campaignRepository.RevokeShareToken(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share token not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to revoke share token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share token"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "revoked"})
+}
+
+// GetSharedSummary serves a campaign summary to holders of a share token.
+func (s *Server) GetSharedSummary(c *gin.Context) {
+	token, err := s.campaignRepository.ResolveShareToken(c.Param("token"))
+	if errors.Is(err, repositories.ErrShareTokenInvalid) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link invalid or expired"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to resolve share token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load summary"})
+		return
+	}
+
+	campaign, err := s.campaignRepository.Get(token.CampaignID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load shared campaign")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return
+	}
+
+	s.respondCampaignSummary(c, *campaign)
+}
+
+// GetCampaignSummary totals a campaign's ads for admins, like the shared
+// summary.
+func (s *Server) GetCampaignSummary(c *gin.Context) {
+	campaign, ok := s.campaignFromParam(c)
+	if !ok {
+		return
+	}
+	s.respondCampaignSummary(c, *campaign)
+}
+
+// respondCampaignSummary covers ?timeframe= up to now, or a date range
+// when ?from=, ?to= or ?breakdown=day is given: see summaryRangeQuery.
+// Counts come from the rollups where they are kept; ?fresh=true counts raw
+// events instead, with playback, viewability and session figures.
+func (s *Server) respondCampaignSummary(c *gin.Context, campaign models.Campaign) {
+	window, ok := s.summaryRangeQuery(c)
+	if !ok {
+		return
+	}
+
+	summary, err := s.campaignSummary(c, campaign, window, c.Query("valid_only") == "true", c.Query("fresh") == "true")
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load campaign summary")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load summary"})
+		return
+	}
+	if csvRequested(c) {
+		s.writeAnalyticsCSV(c, fmt.Sprintf("campaign-%d-summary.csv", campaign.ID), summary.Ads)
+		return
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	if notModified(c, etagFor(summary)) {
+		return
+	}
+	c.JSON(http.StatusOK, summary)
+}
+
+// maxSummaryDays bounds ?breakdown=day, which counts every day separately.
+const maxSummaryDays = 92
+
+// summaryRange is the window a summary covers.
+type summaryRange struct {
+	timeframe   string
+	from, until time.Time
+	// ranged is set when from, to or breakdown were given; daily when the
+	// breakdown is by day.
+	ranged, daily bool
+}
+
+// summaryRangeQuery reads the summary window: ?timeframe= up to now by
+// default, or ?from= to ?to= (RFC 3339 timestamps or YYYY-MM-DD dates in
+// UTC; to is exclusive and defaults to now). Given only ?to=, the window is
+// the timeframe ending there. ?breakdown=day adds a row per UTC day.
+func (s *Server) summaryRangeQuery(c *gin.Context) (summaryRange, bool) {
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return summaryRange{}, false
+	}
+	now := time.Now().UTC()
+	window := summaryRange{timeframe: timeframe, from: now.Add(-duration), until: now}
+
+	switch breakdown := c.Query("breakdown"); breakdown {
+	case "":
+	case "day":
+		window.ranged, window.daily = true, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "breakdown must be day"})
+		return summaryRange{}, false
+	}
+
+	var err error
+	if raw := c.Query("to"); raw != "" {
+		if window.until, err = parseSummaryBound(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+			return summaryRange{}, false
+		}
+		window.from, window.ranged = window.until.Add(-duration), true
+	}
+	if raw := c.Query("from"); raw != "" {
+		if window.from, err = parseSummaryBound(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be an RFC 3339 timestamp or a YYYY-MM-DD date"})
+			return summaryRange{}, false
+		}
+		window.ranged = true
+	}
+	if !window.ranged {
+		return window, true
+	}
+
+	if !window.from.Before(window.until) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return summaryRange{}, false
+	}
+	if window.until.Sub(window.from) > s.maxTimeframe {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("range exceeds the maximum of %s", s.maxTimeframe)})
+		return summaryRange{}, false
+	}
+	if window.daily && window.until.Sub(window.from.Truncate(24*time.Hour)) > maxSummaryDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("breakdown=day covers at most %d days", maxSummaryDays)})
+		return summaryRange{}, false
+	}
+	// Whole seconds keep the cache key stable for ranges ending now
+	window.from, window.until = window.from.Truncate(time.Second), window.until.Truncate(time.Second)
+	return window, true
+}
+
+// parseSummaryBound reads an RFC 3339 timestamp or a date, which stands for
+// its start in UTC.
+func parseSummaryBound(raw string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	return t.UTC(), err
+}
+
+func (s *Server) campaignFromParam(c *gin.Context) (*models.Campaign, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign id"})
+		return nil, false
+	}
+
+	campaign, err := s.campaignRepository.Get(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Campaign not found"})
+		return nil, false
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load campaign")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load campaign"})
+		return nil, false
+	}
+	return campaign, true
+}
+
+const (
+	eventTypeClicks      = "clicks"
+	eventTypeImpressions = "impressions"
+
+	campaignEventPageSize = 500
+	campaignEventBatch    = 2000
+)
+
+// ListCampaignEvents pages through a campaign's raw clicks or impressions by
+// id. With ?format=csv the whole range is streamed instead.
+func (s *Server) ListCampaignEvents(c *gin.Context) {
+	if csvRequested(c) {
+		s.ExportCampaignEvents(c)
+		return
+	}
+
+	cohort, eventType, ok := s.campaignEventCohort(c)
+	if !ok {
+		return
+	}
+
+	var afterID uint64
+	if after := c.Query("after_id"); after != "" {
+		var err error
+		if afterID, err = strconv.ParseUint(after, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid after_id"})
+			return
+		}
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(campaignEventPageSize)))
+	if err != nil || limit <= 0 || limit > campaignEventPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", campaignEventPageSize)})
+		return
+	}
+
+	var events interface{}
+	var nextAfter uint
+	if eventType == eventTypeImpressions {
+		impressions, err := s.exportRepository.ImpressionPage(cohort, uint(afterID), limit)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to list campaign impressions")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list events"})
+			return
+		}
+		if len(impressions) == limit {
+			nextAfter = impressions[len(impressions)-1].ID
+		}
+		events = impressions
+	} else {
+		clicks, err := s.exportRepository.ClickPage(cohort, uint(afterID), limit)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to list campaign clicks")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list events"})
+			return
+		}
+		if len(clicks) == limit {
+			nextAfter = clicks[len(clicks)-1].ID
+		}
+		events = clicks
+	}
+
+	response := gin.H{"type": eventType, "events": events}
+	if nextAfter > 0 {
+		response["next_after_id"] = nextAfter
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportCampaignEvents streams a campaign's clicks or impressions as CSV in
+// id order, flushing a chunk per database batch.
+func (s *Server) ExportCampaignEvents(c *gin.Context) {
+	cohort, eventType, ok := s.campaignEventCohort(c)
+	if !ok {
+		return
+	}
+
+	name := fmt.Sprintf("campaign-%d-%s.csv", *cohort.CampaignID, eventType)
+	var err error
+	if eventType == eventTypeImpressions {
+		w := startCSV(c, name, impressionCSVColumns)
+		err = s.exportRepository.StreamImpressions(cohort, campaignEventBatch, func(batch []models.ImpressionEvent) error {
+			for _, impression := range batch {
+				w.Write(impressionRecord(impression))
+			}
+			return flushCSV(c, w)
+		})
+	} else {
+		w := startCSV(c, name, clickCSVColumns)
+		err = s.exportRepository.StreamClicks(cohort, campaignEventBatch, func(batch []models.ClickEvent) error {
+			for _, click := range batch {
+				w.Write(clickRecord(click))
+			}
+			return flushCSV(c, w)
+		})
+	}
+
+	// Headers are already sent, so a failure can only cut the stream short
+	if err != nil {
+		s.logger.WithError(err).WithField("campaign_id", *cohort.CampaignID).Error("Campaign event export aborted")
+	}
+}
+
+// eventTypeParams maps ?event_type= values to the ?type= they stand for.
+var eventTypeParams = map[string]string{
+	"click":      eventTypeClicks,
+	"impression": eventTypeImpressions,
+}
+
+// campaignEventCohort reads the campaign, ?type=clicks|impressions (or
+// ?event_type=click|impression), the optional ?ad_id= and ?user_id=
+// filters and the optional RFC 3339 from/to bounds. The filters become
+// part of the query, which the ad and user timestamp indexes serve.
+func (s *Server) campaignEventCohort(c *gin.Context) (*models.Export, string, bool) {
+	campaign, ok := s.campaignFromParam(c)
+	if !ok {
+		return nil, "", false
+	}
+
+	eventType := c.DefaultQuery("type", eventTypeClicks)
+	if raw, ok := c.GetQuery("event_type"); ok {
+		if eventType, ok = eventTypeParams[raw]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "event_type must be click or impression"})
+			return nil, "", false
+		}
+	}
+	if eventType != eventTypeClicks && eventType != eventTypeImpressions {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be clicks or impressions"})
+		return nil, "", false
+	}
+
+	cohort := &models.Export{CampaignID: &campaign.ID}
+	if raw := c.Query("ad_id"); raw != "" {
+		adID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil || adID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad_id"})
+			return nil, "", false
+		}
+		cohort.AdIDs = strconv.FormatUint(adID, 10)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		cohort.UserID = s.storedUserID(userID)
+	}
+	for param, bound := range map[string]**time.Time{"from": &cohort.From, "to": &cohort.To} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 timestamp"})
+			return nil, "", false
+		}
+		*bound = &t
+	}
+	if cohort.From != nil && cohort.To != nil && !cohort.From.Before(*cohort.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return nil, "", false
+	}
+	return cohort, eventType, true
+}
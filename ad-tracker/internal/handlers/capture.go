@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SetCaptureManager replaces the anomaly-triggered capture configuration.
+func (s *Server) SetCaptureManager(capture *services.CaptureManager) {
+	s.capture = capture
+}
+
+func (s *Server) GetCaptureManager() *services.CaptureManager {
+	return s.capture
+}
+
+// capturedHeaders are the request headers kept in captures: enough to tell
+// bot traffic apart, without credentials, cookies or forwarded addresses.
+var capturedHeaders = []string{
+	"Accept", "Accept-Encoding", "Accept-Language", "Content-Length", "Content-Type",
+	"Dnt", "Origin", "Referer", "Sec-Ch-Ua", "Sec-Ch-Ua-Mobile", "Sec-Ch-Ua-Platform",
+	"Sec-Fetch-Dest", "Sec-Fetch-Mode", "Sec-Fetch-Site", "User-Agent",
+}
+
+// observeIngest runs the anomaly detectors for an event and, while a capture
+// incident is open for the ad, stores the request with its allowlisted
+// headers and logs it loudly.
+func (s *Server) observeIngest(c *gin.Context, adID uint, payload interface{}) {
+	s.capture.Observe(adID, time.Now())
+
+	incidentID, ok := s.capture.Active(adID)
+	if !ok {
+		return
+	}
+
+	kept := make(http.Header, len(capturedHeaders))
+	for _, name := range capturedHeaders {
+		if values := c.Request.Header.Values(name); len(values) > 0 {
+			kept[name] = values
+		}
+	}
+	headers, _ := json.Marshal(kept)
+	body, _ := json.Marshal(payload)
+
+	s.capture.Capture(models.CapturedRequest{
+		AdID:       adID,
+		ReceivedAt: time.Now().UTC(),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Query:      c.Request.URL.RawQuery,
+		IPAddress:  s.storedIP(c),
+		Headers:    string(headers),
+		Payload:    string(body),
+	})
+
+	s.logger.WithFields(logrus.Fields{
+		"incident_id": incidentID,
+		"ad_id":       adID,
+		"client_ip":   s.storedIP(c),
+		"user_agent":  c.GetHeader("User-Agent"),
+		"referer":     c.GetHeader("Referer"),
+		"path":        c.Request.URL.Path,
+	}).Warn("Captured request for anomalous ad")
+}
+
+func (s *Server) ListCaptureIncidents(c *gin.Context) {
+	incidents, err := s.captureRepository.ListIncidents(100)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list capture incidents")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list capture incidents"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"incidents": incidents})
+}
+
+func (s *Server) GetCaptureIncident(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid incident id"})
+		return
+	}
+
+	incident, err := s.captureRepository.GetIncident(uint(id), 1000)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load capture incident")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load capture incident"})
+		return
+	}
+	c.JSON(http.StatusOK, incident)
+}
+
+// StartCapture lets an operator open a capture window by hand.
+func (s *Server) StartCapture(c *gin.Context) {
+	var req models.CaptureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	minutes := req.Minutes
+	if minutes == 0 {
+		minutes = 10
+	}
+
+	incident, err := s.capture.Start(req.AdID, "manual", req.Reason, time.Duration(minutes)*time.Minute)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to start capture")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start capture"})
+		return
+	}
+	c.JSON(http.StatusCreated, incident)
+}
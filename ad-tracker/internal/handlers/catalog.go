@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportCatalog upserts campaigns and ads from a CSV body, one ad per line
+// (see services.CatalogColumns). ?dry_run=true reports what would change
+// without writing. Any bad line fails the whole import with 422 and a
+// report of every problem.
+func (s *Server) ImportCatalog(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	report, err := s.catalog.Import(c.Request.Body, dryRun)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to import catalog")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import catalog"})
+		return
+	}
+	if len(report.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	if report.Applied {
+		s.catalogImported(c, report)
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// catalogImported refreshes the budget tracker and ad cache for what the
+// import changed, and audits it.
+func (s *Server) catalogImported(c *gin.Context, report models.CatalogImportReport) {
+	for _, item := range report.Campaigns {
+		if item.Action == models.CatalogUnchanged {
+			continue
+		}
+		campaign, err := s.campaignRepository.Get(item.ID)
+		if err != nil {
+			s.logger.WithError(err).WithField("campaign_id", item.ID).Warn("Failed to reload imported campaign")
+			continue
+		}
+		s.budgets.Track(*campaign)
+	}
+	var adIDs []uint
+	for _, item := range report.Ads {
+		if item.Action != models.CatalogUnchanged {
+			adIDs = append(adIDs, item.ID)
+		}
+	}
+	// Budget pauses lifted by new budgets change the active ads as well
+	if len(adIDs) > 0 || report.CampaignsUpdated > 0 {
+		s.invalidateAds(c.Request.Context(), adIDs...)
+	}
+
+	actor := "admin"
+	if name := c.GetString("client_cert"); name != "" {
+		actor = name
+	}
+	if err := s.auditRepository.Record(actor, "catalog.imported", "catalog", "", gin.H{
+		"rows":              report.Rows,
+		"campaigns_created": report.CampaignsCreated,
+		"campaigns_updated": report.CampaignsUpdated,
+		"ads_created":       report.AdsCreated,
+		"ads_updated":       report.AdsUpdated,
+		"ip":                c.ClientIP(),
+	}); err != nil {
+		s.logger.WithError(err).Warn("Failed to record catalog import audit event")
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxCompareCampaigns bounds ?campaigns= on the comparison endpoint.
+const maxCompareCampaigns = 10
+
+var compareCSVColumns = []string{"campaign_id", "name", "clicks", "impressions", "ctr", "last_hour", "last_day"}
+
+// CompareCampaigns returns the totals of the campaigns in
+// ?campaigns=1,2,3 over one window, in the order given, for side-by-side
+// dashboards. The window parameters, breakdown=day, valid_only and fresh
+// work as for campaign summaries; format=csv returns one row per campaign.
+func (s *Server) CompareCampaigns(c *gin.Context) {
+	ids, ok := compareCampaignIDs(c)
+	if !ok {
+		return
+	}
+	window, ok := s.summaryRangeQuery(c)
+	if !ok {
+		return
+	}
+
+	campaigns := make([]models.Campaign, 0, len(ids))
+	for _, id := range ids {
+		campaign, err := s.campaignRepository.Get(id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Campaign %d not found", id)})
+			return
+		}
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to load campaign")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load campaigns"})
+			return
+		}
+		campaigns = append(campaigns, *campaign)
+	}
+
+	validOnly := c.Query("valid_only") == "true"
+	comparison := models.CampaignComparison{
+		Since:     window.from,
+		Until:     window.until,
+		ValidOnly: validOnly,
+		Campaigns: make([]models.CampaignMetrics, 0, len(campaigns)),
+	}
+	for _, campaign := range campaigns {
+		summary, err := s.campaignSummary(c, campaign, window, validOnly, c.Query("fresh") == "true")
+		if err != nil {
+			s.logger.WithError(err).WithField("campaign_id", campaign.ID).Error("Failed to load campaign summary")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load comparison"})
+			return
+		}
+		comparison.Campaigns = append(comparison.Campaigns, campaignMetrics(summary))
+	}
+
+	if csvRequested(c) {
+		w := startCSV(c, "campaign-comparison.csv", compareCSVColumns)
+		for _, metrics := range comparison.Campaigns {
+			w.Write([]string{
+				strconv.FormatUint(uint64(metrics.CampaignID), 10),
+				csvText(metrics.Name),
+				strconv.FormatInt(metrics.Clicks, 10),
+				strconv.FormatInt(metrics.Impressions, 10),
+				csvFloat(metrics.CTR),
+				strconv.FormatInt(metrics.LastHour, 10),
+				strconv.FormatInt(metrics.LastDay, 10),
+			})
+		}
+		if err := flushCSV(c, w); err != nil {
+			s.logger.WithError(err).Warn("Failed to write comparison CSV")
+		}
+		return
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	if notModified(c, etagFor(comparison)) {
+		return
+	}
+	c.JSON(http.StatusOK, comparison)
+}
+
+// compareCampaignIDs reads ?campaigns=, a comma separated list of distinct
+// campaign ids.
+func compareCampaignIDs(c *gin.Context) ([]uint, bool) {
+	raw := c.Query("campaigns")
+	if raw == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "campaigns is required, e.g. campaigns=1,2,3"})
+		return nil, false
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) > maxCompareCampaigns {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d campaigns can be compared", maxCompareCampaigns)})
+		return nil, false
+	}
+	ids := make([]uint, 0, len(parts))
+	seen := make(map[uint64]bool, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid campaign id %q", part)})
+			return nil, false
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, uint(id))
+	}
+	return ids, true
+}
+
+// campaignMetrics flattens a summary into comparison totals.
+func campaignMetrics(summary models.CampaignSummary) models.CampaignMetrics {
+	metrics := models.CampaignMetrics{
+		CampaignID: summary.CampaignID,
+		Name:       summary.Name,
+		Clicks:     summary.ClickCount,
+		LastHour:   summary.LastHour,
+		LastDay:    summary.LastDay,
+		Days:       summary.Days,
+	}
+	for _, ad := range summary.Ads {
+		metrics.Impressions += ad.Impressions
+	}
+	if metrics.Impressions > 0 {
+		metrics.CTR = float64(metrics.Clicks) / float64(metrics.Impressions)
+	}
+	return metrics
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"strconv"
+
+	"ad-tracking-system/internal/consent"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/sanitize"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetConsentPolicy replaces the consent policy, e.g. to check a TCF vendor id.
+func (s *Server) SetConsentPolicy(policy *services.ConsentPolicy) {
+	s.consent = policy
+}
+
+// GetConsentPolicy exposes the policy so main can run its refresh loop.
+func (s *Server) GetConsentPolicy() *services.ConsentPolicy {
+	return s.consent
+}
+
+// consentQuery reads consent parameters from a tracking link's query string.
+func consentQuery(c *gin.Context) models.ConsentParams {
+	params := models.ConsentParams{
+		GDPRConsent: c.Query("gdpr_consent"),
+		GPP:         c.Query("gpp"),
+		GPPSID:      c.Query("gpp_sid"),
+	}
+	if gdpr, err := strconv.Atoi(c.Query("gdpr")); err == nil && (gdpr == 0 || gdpr == 1) {
+		params.GDPR = &gdpr
+	}
+	return params
+}
+
+// storedConsent is the TCF consent string kept with an event.
+func storedConsent(params models.ConsentParams) string {
+	return sanitize.String(params.GDPRConsent, sanitize.MaxConsentLength)
+}
+
+// applyConsent evaluates the request's consent signals for the ad and
+// reports the resulting state and whether identifiers may be stored.
+func (s *Server) applyConsent(eventType string, adID uint, params models.ConsentParams) (string, bool) {
+	signals := consent.Signals{
+		GDPRConsent: params.GDPRConsent,
+		GPP:         params.GPP,
+		GPPSID:      params.GPPSID,
+	}
+	if params.GDPR != nil {
+		signals.GDPR = strconv.Itoa(*params.GDPR)
+	}
+
+	state, permitted := s.consent.Evaluate(adID, signals)
+	metrics.ConsentStates.WithLabelValues(eventType, state).Inc()
+	if !permitted {
+		metrics.IdentifiersWithheld.WithLabelValues(eventType).Inc()
+	}
+	return state, permitted
+}
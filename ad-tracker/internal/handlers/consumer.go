@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+
+	"ad-tracking-system/internal/kafka"
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetConsumerGroups enables the consumer offset endpoints for the named
+// consumers of the event topic, e.g. "clickhouse" to its group id.
+func (s *Server) SetConsumerGroups(admin *kafka.OffsetAdmin, groups map[string]string) {
+	s.offsetAdmin = admin
+	s.consumerGroups = groups
+}
+
+// ListConsumerGroups reports the committed offsets and lag of every
+// consumer this service runs on the event topic.
+func (s *Server) ListConsumerGroups(c *gin.Context) {
+	names := make([]string, 0, len(s.consumerGroups))
+	for name := range s.consumerGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	consumers := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		consumer := gin.H{"name": name, "group": s.consumerGroups[name]}
+		offsets, err := s.offsetAdmin.Describe(c.Request.Context(), s.consumerGroups[name])
+		if err != nil {
+			s.logger.WithError(err).WithField("group", s.consumerGroups[name]).Warn("Failed to describe consumer group")
+			consumer["error"] = err.Error()
+		} else {
+			consumer["offsets"] = offsets
+		}
+		consumers = append(consumers, consumer)
+	}
+	c.JSON(http.StatusOK, gin.H{"consumers": consumers})
+}
+
+func (s *Server) GetConsumerGroup(c *gin.Context) {
+	group, ok := s.consumerGroupParam(c)
+	if !ok {
+		return
+	}
+
+	offsets, err := s.offsetAdmin.Describe(c.Request.Context(), group)
+	if err != nil {
+		s.logger.WithError(err).WithField("group", group).Error("Failed to describe consumer group")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to describe consumer group"})
+		return
+	}
+	c.JSON(http.StatusOK, offsets)
+}
+
+// SeekConsumerGroup moves a consumer's committed offsets so it reprocesses
+// (or skips) events, e.g. after fixing a bug in a sink. The consumer must
+// be stopped on every replica first; Kafka rejects offsets for a group with
+// members, answered here with 409.
+func (s *Server) SeekConsumerGroup(c *gin.Context) {
+	group, ok := s.consumerGroupParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.ConsumerSeekRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	target := kafka.SeekTarget{
+		Time:      req.Timestamp,
+		Offset:    req.Offset,
+		Partition: req.Partition,
+		Earliest:  req.Position == "earliest",
+		Latest:    req.Position == "latest",
+	}
+
+	offsets, err := s.offsetAdmin.Seek(c.Request.Context(), group, target, req.DryRun)
+	if errors.Is(err, kafka.ErrGroupActive) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "members": offsets.Members})
+		return
+	}
+	var invalid *kafka.SeekError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).WithField("group", group).Error("Failed to seek consumer group")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to seek consumer group"})
+		return
+	}
+
+	if !req.DryRun {
+		actor := "admin"
+		if name := c.GetString("client_cert"); name != "" {
+			actor = name
+		}
+		if err := s.auditRepository.Record(actor, "consumer.seek", "consumer_group", group, gin.H{
+			"request":    req,
+			"partitions": offsets.Partitions,
+			"ip":         c.ClientIP(),
+		}); err != nil {
+			s.logger.WithError(err).Warn("Failed to record consumer seek audit event")
+		}
+	}
+	c.JSON(http.StatusOK, offsets)
+}
+
+func (s *Server) consumerGroupParam(c *gin.Context) (string, bool) {
+	group, ok := s.consumerGroups[c.Param("name")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown consumer"})
+		return "", false
+	}
+	return group, true
+}
@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+	"ad-tracking-system/internal/signing"
+	"ad-tracking-system/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PostConversion records a conversion and attributes it to a prior click or
+// impression from the same user within the configured windows.
+func (s *Server) PostConversion(c *gin.Context) {
+	var req models.ConversionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+
+	timestamp, ok := s.eventTime(c, "conversion", req.Timestamp, time.Now())
+	if !ok {
+		return
+	}
+
+	conversion := models.Conversion{
+		UserID:    s.storedUserID(req.UserID),
+		IPAddress: s.storedIP(c),
+		Value:     req.Value,
+		Currency:  req.Currency,
+		Timestamp: timestamp,
+	}
+
+	s.observeIdentity(conversion.UserID, req.IdentityHints, conversion.Timestamp)
+	if err := s.attributor.Attribute(&conversion, req.IdentityHints); err != nil {
+		s.logger.WithError(err).Warn("Failed to attribute conversion, storing unattributed")
+	}
+
+	if err := s.conversionRepository.Create(&conversion); err != nil {
+		s.logger.WithError(err).Error("Failed to save conversion")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record conversion"})
+		return
+	}
+	s.publishConversion(conversion)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":           "recorded",
+		"conversion_id":    conversion.ID,
+		"attribution_type": conversion.AttributionType,
+		"attributed_ad_id": conversion.AttributedAdID,
+	})
+}
+
+// Postback records a server-to-server conversion for a known click_id. The
+// conversion is attributed to that click directly, without any window. The
+// request carries ts and a sig over signing.PostbackSubject made with the
+// ad account's signing secret, and is recorded once per click_id and txid.
+func (s *Server) Postback(c *gin.Context) {
+	var req models.PostbackRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+
+	click, err := s.conversionRepository.ClickByClickID(req.ClickID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to look up click for postback")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record conversion"})
+		return
+	}
+	if click == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown click_id"})
+		return
+	}
+
+	form := c.Request.Form
+	params := url.Values{
+		"ts":      {form.Get("ts")},
+		"sig":     {form.Get("sig")},
+		"user_id": {signing.PostbackSubject(req.ClickID, req.TransactionID, form.Get("value"), form.Get("currency"))},
+	}
+	if !s.verifySigned(c, signing.KindPostback, click.AdID, params) {
+		return
+	}
+
+	exists, err := s.conversionRepository.PostbackExists(req.ClickID, req.TransactionID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to check postback duplicate")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record conversion"})
+		return
+	}
+	if exists {
+		c.JSON(http.StatusOK, gin.H{"status": "duplicate"})
+		return
+	}
+
+	conversion := models.Conversion{
+		ClickID:           req.ClickID,
+		TransactionID:     req.TransactionID,
+		UserID:            click.UserID,
+		IPAddress:         click.IPAddress,
+		Value:             req.Value,
+		Currency:          req.Currency,
+		Timestamp:         time.Now(),
+		AttributedAdID:    &click.AdID,
+		AttributedEventID: &click.ID,
+		AttributedAt:      &click.Timestamp,
+		AttributionType:   models.AttributionClickThrough,
+	}
+
+	if err := s.conversionRepository.Create(&conversion); err != nil {
+		s.logger.WithError(err).Error("Failed to save postback conversion")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record conversion"})
+		return
+	}
+	s.publishConversion(conversion)
+
+	c.JSON(http.StatusOK, gin.H{"status": "recorded", "conversion_id": conversion.ID})
+}
+
+// publishConversion forwards attributed conversions to the webhooks and
+// integrations of the ad's account; unattributed ones have no account to
+// notify.
+func (s *Server) publishConversion(conversion models.Conversion) {
+	if conversion.AttributedAdID == nil {
+		return
+	}
+	s.webhooks.Publish(models.WebhookEventConversion, *conversion.AttributedAdID, strconv.FormatUint(uint64(conversion.ID), 10), conversion)
+	s.forwarder.Publish(services.ForwardedConversion(conversion))
+}
+
+// GetConversionReport splits conversions into click-through, view-through
+// and unattributed for the requested timeframe.
+func (s *Server) GetConversionReport(c *gin.Context) {
+	_, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
+	since := time.Now().UTC().Add(-duration)
+
+	report, err := s.conversionRepository.Report(since)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to build conversion report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build conversion report"})
+		return
+	}
+
+	windows := s.attributor.Windows()
+	report.ClickWindow = windows.Click.String()
+	report.ViewWindow = windows.View.String()
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetConversionLatency reports how long after the click click-through
+// conversions arrived, per campaign or for one ?campaign_id=, over
+// ?timeframe= of conversion time. View-through conversions have no click
+// and are left out.
+func (s *Server) GetConversionLatency(c *gin.Context) {
+	var campaignID uint64
+	if raw := c.Query("campaign_id"); raw != "" {
+		var err error
+		if campaignID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign_id"})
+			return
+		}
+	}
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
+
+	latencies, err := s.conversionRepository.ClickLatencies(time.Now().UTC().Add(-duration), uint(campaignID))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load conversion latencies")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build conversion latency report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timeframe": timeframe,
+		"campaigns": services.SummarizeLatencies(latencies),
+	})
+}
+
+// GetAttributionReport credits conversions to ads under the model chosen by
+// ?model= (last_click, first_click or linear).
+func (s *Server) GetAttributionReport(c *gin.Context) {
+	model := c.DefaultQuery("model", models.AttributionLastClick)
+	_, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
+	since := time.Now().UTC().Add(-duration)
+
+	switch model {
+	case models.AttributionLastClick, models.AttributionFirstClick, models.AttributionLinear:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model must be one of last_click, first_click, linear"})
+		return
+	}
+
+	report, err := s.attributor.Report(model, since)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to build attribution report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build attribution report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+var campaignAttributionCSVColumns = []string{"ad_id", "creative_id", "placement_id", "placement", "currency", "conversions", "value"}
+
+// GetCampaignAttribution reports the conversions credited to a campaign's
+// ads under ?model=, broken down by ad, creative, placement and currency so
+// payouts can be reconciled. The period is read as for the campaign summary;
+// ?window= overrides the click lookback (e.g. 7d). ?format=csv downloads
+// the rows.
+func (s *Server) GetCampaignAttribution(c *gin.Context) {
+	campaign, ok := s.campaignFromParam(c)
+	if !ok {
+		return
+	}
+	model := c.DefaultQuery("model", models.AttributionLastClick)
+	switch model {
+	case models.AttributionLastClick, models.AttributionFirstClick, models.AttributionLinear:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "model must be one of last_click, first_click, linear"})
+		return
+	}
+	var window time.Duration
+	if raw := c.Query("window"); raw != "" {
+		var err error
+		if window, err = services.ParseWindow(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window must be a duration such as 24h or 7d"})
+			return
+		}
+	}
+	period, ok := s.summaryRangeQuery(c)
+	if !ok {
+		return
+	}
+	if period.daily {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "breakdown is not supported for attribution"})
+		return
+	}
+
+	adIDs, err := s.campaignRepository.AdIDs(campaign.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load campaign ads")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build attribution report"})
+		return
+	}
+	report, err := s.attributor.CampaignReport(campaign.ID, adIDs, model, period.from, period.until, window)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to build campaign attribution report")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build attribution report"})
+		return
+	}
+	if err := s.nameAttributionPlacements(report.Rows); err != nil {
+		s.logger.WithError(err).Error("Failed to load placements")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build attribution report"})
+		return
+	}
+
+	if csvRequested(c) {
+		w := startCSV(c, fmt.Sprintf("campaign-%d-attribution.csv", campaign.ID), campaignAttributionCSVColumns)
+		for _, row := range report.Rows {
+			w.Write([]string{
+				strconv.FormatUint(uint64(row.AdID), 10),
+				optionalIDText(row.CreativeID),
+				optionalIDText(row.PlacementID),
+				csvText(row.Placement),
+				csvText(row.Currency),
+				csvFloat(row.Conversions),
+				csvFloat(row.Value),
+			})
+		}
+		if err := flushCSV(c, w); err != nil {
+			s.logger.WithError(err).Warn("Failed to write attribution CSV")
+		}
+		return
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	if notModified(c, etagFor(report)) {
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// nameAttributionPlacements fills in the placement keys of the rows.
+func (s *Server) nameAttributionPlacements(rows []models.AttributionRow) error {
+	placed := false
+	for _, row := range rows {
+		placed = placed || row.PlacementID != nil
+	}
+	if !placed {
+		return nil
+	}
+	placements, err := s.placementRepository.List("")
+	if err != nil {
+		return err
+	}
+	keys := make(map[uint]string, len(placements))
+	for _, placement := range placements {
+		keys[placement.ID] = placement.Key
+	}
+	for i, row := range rows {
+		if row.PlacementID != nil {
+			rows[i].Placement = keys[*row.PlacementID]
+		}
+	}
+	return nil
+}
+
+func optionalIDText(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// rotateCreatives picks one active creative per ad by weight and serves
+// its image in place of the ad's own. It reports whether any ad had more
+// than one creative to choose from, making the response vary per request.
+func rotateCreatives(ads []models.Ad) ([]models.Ad, bool) {
+	rotated := false
+	served := make([]models.Ad, len(ads))
+	for i, ad := range ads {
+		var active []models.Creative
+		for _, creative := range ad.Creatives {
+			if creative.Active {
+				active = append(active, creative)
+			}
+		}
+		if len(active) > 0 {
+			creative := pickCreative(active)
+			ad.Creative = &creative
+			ad.ImageURL = creative.ImageURL
+			rotated = rotated || len(active) > 1
+		}
+		ad.Creatives = nil
+		served[i] = ad
+	}
+	return served, rotated
+}
+
+func pickCreative(creatives []models.Creative) models.Creative {
+	total := 0
+	for _, creative := range creatives {
+		total += max(creative.Weight, 1)
+	}
+	n := rand.Intn(total)
+	for _, creative := range creatives {
+		n -= max(creative.Weight, 1)
+		if n < 0 {
+			return creative
+		}
+	}
+	return creatives[len(creatives)-1]
+}
+
+// hasCreative reports whether id, if set, is one of the ad's creatives.
+func hasCreative(ad models.Ad, id *uint) bool {
+	if id == nil {
+		return true
+	}
+	for _, creative := range ad.Creatives {
+		if creative.ID == *id {
+			return true
+		}
+	}
+	return false
+}
+
+// creativeQuery reads ?creative_id= from tracking links, which carry it
+// unsigned; recording the event still checks it against the ad.
+func creativeQuery(c *gin.Context) (*uint, bool) {
+	raw := c.Query("creative_id")
+	if raw == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(raw, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid creative_id"})
+		return nil, false
+	}
+	creativeID := uint(id)
+	return &creativeID, true
+}
+
+func (s *Server) ListCreatives(c *gin.Context) {
+	adID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad id"})
+		return
+	}
+
+	creatives, err := s.creativeRepository.ListForAd(uint(adID))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list creatives")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list creatives"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"creatives": creatives})
+}
+
+// CreateCreative adds a creative to an ad's rotation. Weight defaults to 1.
+func (s *Server) CreateCreative(c *gin.Context) {
+	adID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad id"})
+		return
+	}
+
+	var req models.CreativeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	creative := models.Creative{
+		AdID:     uint(adID),
+		Name:     req.Name,
+		ImageURL: req.ImageURL,
+		Width:    req.Width,
+		Height:   req.Height,
+		Weight:   max(req.Weight, 1),
+		Active:   true,
+	}
+	err = s.creativeRepository.Create(&creative)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to create creative")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create creative"})
+		return
+	}
+	s.invalidateAds(c.Request.Context(), creative.AdID)
+	c.JSON(http.StatusCreated, creative)
+}
+
+// UpdateCreative changes a creative's weight or takes it out of rotation.
+// Creatives are never deleted so past events keep their reference.
+func (s *Server) UpdateCreative(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid creative id"})
+		return
+	}
+
+	var req models.CreativeUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	creative, err := s.creativeRepository.Update(uint(id), req)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Creative not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to update creative")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update creative"})
+		return
+	}
+	s.invalidateAds(c.Request.Context(), creative.AdID)
+	c.JSON(http.StatusOK, creative)
+}
+
+// GetCreativeAnalytics breaks an ad's valid impressions and clicks down by
+// creative over ?timeframe=. It reads the primary database, so it is not
+// served from ClickHouse or the rollups.
+func (s *Server) GetCreativeAnalytics(c *gin.Context) {
+	adID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad id"})
+		return
+	}
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := s.creativeRepository.Stats(uint(adID), time.Now().UTC().Add(-duration))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load creative analytics")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load creative analytics"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"ad_id":     adID,
+		"timeframe": timeframe,
+		"creatives": stats,
+	})
+}
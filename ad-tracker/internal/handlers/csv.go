@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	analyticsCSVColumns = []string{
+		"ad_id", "click_count", "impressions", "ctr", "last_hour", "last_day",
+		"viewable", "viewability_measured", "viewable_rate",
+		"playback_samples", "playback_avg_seconds", "playback_median_seconds", "playback_p90_seconds", "completion_rate",
+		"valid_only",
+	}
+	clickCSVColumns = []string{
+		"id", "click_id", "ad_id", "timestamp", "user_id", "ip_address", "user_agent",
+		"video_playback_time", "fraud_score", "fraud_reasons", "invalid", "consent_state",
+	}
+	impressionCSVColumns = []string{
+		"id", "ad_id", "timestamp", "user_id", "ip_address", "user_agent",
+		"time_in_view_ms", "percent_in_view", "fraud_score", "fraud_reasons", "invalid", "consent_state",
+	}
+)
+
+func csvRequested(c *gin.Context) bool {
+	return strings.EqualFold(c.Query("format"), "csv")
+}
+
+// startCSV writes the download headers and returns a writer on the response.
+// No Content-Length is set, so the body goes out chunked as rows are flushed.
+func startCSV(c *gin.Context, name string, columns []string) *csv.Writer {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write(columns)
+	return w
+}
+
+// flushCSV pushes buffered rows to the client as a chunk.
+func flushCSV(c *gin.Context, w *csv.Writer) error {
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// csvText neutralizes values that spreadsheets would evaluate as formulas.
+// encoding/csv already handles quoting; this covers user-controlled text
+// such as user agents and user ids.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func csvFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func analyticsRecord(a models.AnalyticsResponse) []string {
+	record := []string{
+		strconv.FormatUint(uint64(a.AdID), 10),
+		strconv.FormatInt(a.ClickCount, 10),
+		strconv.FormatInt(a.Impressions, 10),
+		csvFloat(a.CTR),
+		strconv.FormatInt(a.LastHour, 10),
+		strconv.FormatInt(a.LastDay, 10),
+		"", "", "",
+		"", "", "", "", "",
+		strconv.FormatBool(a.ValidOnly),
+	}
+	if v := a.Viewability; v != nil {
+		record[6] = strconv.FormatInt(v.Viewable, 10)
+		record[7] = strconv.FormatInt(v.Measured, 10)
+		record[8] = csvFloat(v.ViewableRate)
+	}
+	if p := a.Playback; p != nil {
+		record[9] = strconv.FormatInt(p.Samples, 10)
+		record[10] = csvFloat(p.AvgSeconds)
+		record[11] = csvFloat(p.MedianSeconds)
+		record[12] = csvFloat(p.P90Seconds)
+		record[13] = csvFloat(p.CompletionRate)
+	}
+	return record
+}
+
+func clickRecord(click models.ClickEvent) []string {
+	return []string{
+		strconv.FormatUint(uint64(click.ID), 10),
+		click.ClickID,
+		strconv.FormatUint(uint64(click.AdID), 10),
+		click.Timestamp.UTC().Format(time.RFC3339Nano),
+		csvText(click.UserID),
+		csvText(click.IPAddress),
+		csvText(click.UserAgent),
+		strconv.FormatInt(click.VideoPlaybackTime, 10),
+		csvFloat(click.FraudScore),
+		click.FraudReasons,
+		strconv.FormatBool(click.Invalid),
+		click.ConsentState,
+	}
+}
+
+func impressionRecord(impression models.ImpressionEvent) []string {
+	return []string{
+		strconv.FormatUint(uint64(impression.ID), 10),
+		strconv.FormatUint(uint64(impression.AdID), 10),
+		impression.Timestamp.UTC().Format(time.RFC3339Nano),
+		csvText(impression.UserID),
+		csvText(impression.IPAddress),
+		csvText(impression.UserAgent),
+		strconv.FormatInt(impression.TimeInViewMS, 10),
+		csvFloat(impression.PercentInView),
+		csvFloat(impression.FraudScore),
+		impression.FraudReasons,
+		strconv.FormatBool(impression.Invalid),
+		impression.ConsentState,
+	}
+}
+
+// writeAnalyticsCSV renders one row per ad.
+func (s *Server) writeAnalyticsCSV(c *gin.Context, name string, rows []models.AnalyticsResponse) {
+	w := startCSV(c, name, analyticsCSVColumns)
+	for _, row := range rows {
+		w.Write(analyticsRecord(row))
+	}
+	if err := flushCSV(c, w); err != nil {
+		s.logger.WithError(err).Warn("Failed to write analytics CSV")
+	}
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// experimentConfidence and its z score set the interval reported for each
+// variant's rates.
+const (
+	experimentConfidence = 0.95
+	experimentZ          = 1.96
+)
+
+func (s *Server) GetExperimentAssigner() *services.ExperimentAssigner {
+	return s.experiments
+}
+
+// logExposures saves exposures off the request path.
+func (s *Server) logExposures(exposures []models.ExperimentExposure) {
+	if len(exposures) == 0 {
+		return
+	}
+	s.background(func() {
+		if err := s.experimentRepository.LogExposures(exposures); err != nil {
+			s.logger.WithError(err).Error("Failed to log experiment exposures")
+		}
+	})
+}
+
+func (s *Server) ListExperiments(c *gin.Context) {
+	experiments, err := s.experimentRepository.List()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list experiments")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list experiments"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"experiments": experiments})
+}
+
+// CreateExperiment starts splitting traffic across the variant ads right
+// away. Each ad can be in one running experiment at a time.
+func (s *Server) CreateExperiment(c *gin.Context) {
+	var req models.ExperimentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	experiment := models.Experiment{
+		Name:      req.Name,
+		Status:    models.ExperimentRunning,
+		StartedAt: time.Now().UTC(),
+	}
+	seen := make(map[uint]bool)
+	for _, variant := range req.Variants {
+		if seen[variant.AdID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Each variant needs a different ad"})
+			return
+		}
+		seen[variant.AdID] = true
+
+		weight := variant.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		experiment.Variants = append(experiment.Variants, models.ExperimentVariant{
+			Name:   variant.Name,
+			AdID:   variant.AdID,
+			Weight: weight,
+		})
+	}
+
+	err := s.experimentRepository.Create(&experiment)
+	if errors.Is(err, repositories.ErrVariantUnavailable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to create experiment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create experiment"})
+		return
+	}
+	s.refreshExperiments()
+	s.invalidateAds(c.Request.Context())
+	c.JSON(http.StatusCreated, experiment)
+}
+
+func (s *Server) StopExperiment(c *gin.Context) {
+	id, ok := experimentID(c)
+	if !ok {
+		return
+	}
+
+	err := s.experimentRepository.Stop(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Running experiment not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to stop experiment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop experiment"})
+		return
+	}
+	s.refreshExperiments()
+	c.JSON(http.StatusOK, gin.H{"status": models.ExperimentStopped})
+}
+
+// GetExperimentResults reports exposures, clicks and conversions per
+// variant with CTR and CVR and their 95% Wilson intervals. Variants whose
+// intervals do not overlap differ at that confidence.
+func (s *Server) GetExperimentResults(c *gin.Context) {
+	id, ok := experimentID(c)
+	if !ok {
+		return
+	}
+
+	experiment, err := s.experimentRepository.Get(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Experiment not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load experiment")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load experiment"})
+		return
+	}
+
+	variants, err := s.experimentRepository.VariantCounts(experiment)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to count experiment results")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load experiment results"})
+		return
+	}
+	for i := range variants {
+		v := &variants[i]
+		if v.Exposures > 0 {
+			v.CTR = float64(v.Clicks) / float64(v.Exposures)
+		}
+		if v.Clicks > 0 {
+			v.CVR = float64(v.Conversions) / float64(v.Clicks)
+		}
+		v.CTRLow, v.CTRHigh = services.WilsonInterval(min(v.Clicks, v.Exposures), v.Exposures, experimentZ)
+		v.CVRLow, v.CVRHigh = services.WilsonInterval(min(v.Conversions, v.Clicks), v.Clicks, experimentZ)
+	}
+
+	c.JSON(http.StatusOK, models.ExperimentResults{
+		Experiment: *experiment,
+		Confidence: experimentConfidence,
+		Variants:   variants,
+	})
+}
+
+func (s *Server) refreshExperiments() {
+	if err := s.experiments.Refresh(); err != nil {
+		s.logger.WithError(err).Error("Failed to refresh running experiments")
+	}
+}
+
+func experimentID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid experiment id"})
+		return 0, false
+	}
+	return uint(id), true
+}
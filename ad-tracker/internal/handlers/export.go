@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateExport queues a CSV export of the requested click cohort.
+func (s *Server) CreateExport(c *gin.Context) {
+	var req models.ExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+
+	adIDs := make([]string, len(req.AdIDs))
+	for i, id := range req.AdIDs {
+		adIDs[i] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	export := &models.Export{
+		Status:     models.ExportPending,
+		AdIDs:      strings.Join(adIDs, ","),
+		CampaignID: req.CampaignID,
+		From:       req.From,
+		To:         req.To,
+	}
+	if req.UserID != "" {
+		export.UserID = s.storedUserID(req.UserID)
+	}
+	if err := s.exportRepository.Create(export); err != nil {
+		s.logger.WithError(err).Error("Failed to create export")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create export"})
+		return
+	}
+
+	s.background(func() { s.exports.Run(export) })
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"export":       export,
+		"status_url":   fmt.Sprintf("/api/v1/admin/exports/%d", export.ID),
+		"download_url": fmt.Sprintf("/api/v1/admin/exports/%d/download", export.ID),
+	})
+}
+
+func (s *Server) GetExport(c *gin.Context) {
+	export, ok := s.exportFromParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+func (s *Server) GetExportManifest(c *gin.Context) {
+	export, ok := s.completedExport(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, s.exports.Manifest(export))
+}
+
+// DownloadExport serves the export file with Range and If-Range support so
+// interrupted downloads can resume where they stopped.
+func (s *Server) DownloadExport(c *gin.Context) {
+	export, ok := s.completedExport(c)
+	if !ok {
+		return
+	}
+
+	file, err := archive.OpenObject(c.Request.Context(), s.exports.Store(), export.FilePath, export.Bytes)
+	if errors.Is(err, archive.ErrNotFound) {
+		s.logger.WithField("export_id", export.ID).Error("Export file missing")
+		c.JSON(http.StatusGone, gin.H{"error": "Export file no longer available"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).WithField("export_id", export.ID).Error("Failed to open export")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read export"})
+		return
+	}
+	defer file.Close()
+
+	var modified time.Time
+	if export.CompletedAt != nil {
+		modified = *export.CompletedAt
+	}
+	name := fmt.Sprintf("export-%d.csv", export.ID)
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Header("ETag", `"`+export.SHA256+`"`)
+	c.Header("X-Content-SHA256", export.SHA256)
+	http.ServeContent(c.Writer, c.Request, name, modified, file)
+}
+
+func (s *Server) completedExport(c *gin.Context) (*models.Export, bool) {
+	export, ok := s.exportFromParam(c)
+	if !ok {
+		return nil, false
+	}
+	if export.Status != models.ExportCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Export not ready", "status": export.Status})
+		return nil, false
+	}
+	return export, true
+}
+
+func (s *Server) exportFromParam(c *gin.Context) (*models.Export, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export id"})
+		return nil, false
+	}
+
+	export, err := s.exportRepository.Get(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return nil, false
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load export")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load export"})
+		return nil, false
+	}
+	return export, true
+}
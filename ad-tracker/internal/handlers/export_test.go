@@ -0,0 +1,149 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/fakes"
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newReplicas starts two servers on one database and one export store, as
+// two pods behind a load balancer would run.
+func newReplicas(t *testing.T, linkTTL time.Duration) (*testServer, *testServer, archive.ObjectStore) {
+	t.Helper()
+	db := openTestDB(t)
+	store := archive.NewDirStore(t.TempDir())
+
+	replicas := []*testServer{{store: fakes.NewEventStore()}, {store: fakes.NewEventStore()}}
+	for _, ts := range replicas {
+		ts.start(t, db, ts.store)
+		ts.server.SetExportStore(store)
+		ts.server.SetPrivacyExports("privacy-link-secret", linkTTL)
+		ts.router.POST("/api/v1/admin/exports", ts.server.CreateExport)
+		ts.router.GET("/api/v1/admin/exports/:id/download", ts.server.DownloadExport)
+		ts.router.POST("/api/v1/privacy/users/:userId/export", ts.server.ExportUserData)
+		ts.router.GET("/api/v1/privacy/requests/:id", ts.server.GetPrivacyRequest)
+		ts.router.GET("/api/v1/privacy/downloads/:id", ts.server.DownloadPrivacyExport)
+	}
+	return replicas[0], replicas[1], store
+}
+
+// get sends a GET with the given headers and returns the raw response.
+func (ts *testServer) get(target string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	return w
+}
+
+func (ts *testServer) createClicks(t *testing.T, ad models.Ad, userID string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		click := models.ClickEvent{ClickID: "click-" + strconv.Itoa(i), AdID: ad.ID, UserID: userID, IPAddress: "203.0.113.7", Timestamp: time.Now().Add(-time.Duration(i) * time.Minute)}
+		if err := ts.db.Create(&click).Error; err != nil {
+			t.Fatalf("create click: %v", err)
+		}
+	}
+}
+
+func TestExportDownloadsFromAnotherReplica(t *testing.T) {
+	writer, reader, _ := newReplicas(t, time.Hour)
+	ad := writer.createAd(t)
+	writer.createClicks(t, ad, "user-1", 3)
+
+	var created struct {
+		Export models.Export `json:"export"`
+	}
+	if status := writer.do(t, http.MethodPost, "/api/v1/admin/exports", gin.H{"ad_ids": []uint{ad.ID}}, &created); status != http.StatusAccepted {
+		t.Fatalf("create export got %d, want 202", status)
+	}
+	writer.wait(t)
+	target := "/api/v1/admin/exports/" + strconv.Itoa(int(created.Export.ID)) + "/download"
+
+	full := reader.get(target, nil)
+	if full.Code != http.StatusOK {
+		t.Fatalf("download got %d: %s", full.Code, full.Body)
+	}
+	if lines := strings.Count(full.Body.String(), "\n"); lines != 4 {
+		t.Fatalf("export has %d lines, want a header and 3 clicks:\n%s", lines, full.Body)
+	}
+
+	partial := reader.get(target, http.Header{"Range": {"bytes=10-"}})
+	if partial.Code != http.StatusPartialContent {
+		t.Fatalf("ranged download got %d, want 206", partial.Code)
+	}
+	if got, want := partial.Body.String(), full.Body.String()[10:]; got != want {
+		t.Errorf("ranged download is %q, want %q", got, want)
+	}
+}
+
+func TestPrivacyExportDownloadsFromAnotherReplica(t *testing.T) {
+	writer, reader, _ := newReplicas(t, time.Hour)
+	ad := writer.createAd(t)
+	writer.createClicks(t, ad, "user-1", 2)
+
+	var queued struct {
+		Request models.PrivacyRequest `json:"request"`
+	}
+	if status := writer.do(t, http.MethodPost, "/api/v1/privacy/users/user-1/export", nil, &queued); status != http.StatusAccepted {
+		t.Fatalf("queue export got %d, want 202", status)
+	}
+	writer.wait(t)
+
+	var request models.PrivacyRequest
+	reader.do(t, http.MethodGet, "/api/v1/privacy/requests/"+strconv.Itoa(int(queued.Request.ID)), nil, &request)
+	if request.DownloadURL == "" {
+		t.Fatalf("completed request %+v has no download URL", request)
+	}
+	download := reader.get(request.DownloadURL, nil)
+	if download.Code != http.StatusOK {
+		t.Fatalf("download got %d: %s", download.Code, download.Body)
+	}
+	if body := download.Body.String(); !strings.Contains(body, `"click-0"`) || !strings.Contains(body, `"click-1"`) {
+		t.Errorf("export is missing the subject's clicks: %s", body)
+	}
+}
+
+func TestExpiredPrivacyExportIsDeleted(t *testing.T) {
+	writer, reader, store := newReplicas(t, time.Nanosecond)
+	ad := writer.createAd(t)
+	writer.createClicks(t, ad, "user-1", 1)
+
+	var queued struct {
+		Request models.PrivacyRequest `json:"request"`
+	}
+	writer.do(t, http.MethodPost, "/api/v1/privacy/users/user-1/export", nil, &queued)
+	writer.wait(t)
+
+	var request models.PrivacyRequest
+	reader.do(t, http.MethodGet, "/api/v1/privacy/requests/"+strconv.Itoa(int(queued.Request.ID)), nil, &request)
+	var stored models.PrivacyRequest
+	if err := reader.db.First(&stored, queued.Request.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	body, err := store.Get(context.Background(), stored.FilePath)
+	if err != nil {
+		t.Fatalf("export not in the store: %v", err)
+	}
+	body.Close()
+
+	if code := reader.get(request.DownloadURL, nil).Code; code != http.StatusGone {
+		t.Fatalf("expired download got %d, want 410", code)
+	}
+	if _, err := store.Get(context.Background(), stored.FilePath); !errors.Is(err, archive.ErrNotFound) {
+		t.Errorf("expired export still in the store: %v", err)
+	}
+}
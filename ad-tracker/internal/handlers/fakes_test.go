@@ -0,0 +1,175 @@
+package handlers_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/fakes"
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func testAd(id uint, active bool) models.Ad {
+	return models.Ad{ID: id, ImageURL: "https://cdn.example.com/ad.png", TargetURL: "https://example.com/", Active: active}
+}
+
+func TestGetAdsFromAdStore(t *testing.T) {
+	ts := newTestServer(t)
+	honeypot := testAd(3, true)
+	honeypot.Honeypot = true
+	ts.server.SetAdStore(fakes.NewAdStore(testAd(1, true), testAd(2, false), honeypot))
+
+	var resp struct {
+		Ads []models.Ad `json:"ads"`
+	}
+	if status := ts.do(t, http.MethodGet, "/api/v1/ads", nil, &resp); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	if len(resp.Ads) != 1 || resp.Ads[0].ID != 1 {
+		t.Errorf("served %+v, want ad 1 only", resp.Ads)
+	}
+}
+
+func TestGetAdsAdStoreFailure(t *testing.T) {
+	ts := newTestServer(t)
+	ads := fakes.NewAdStore(testAd(1, true))
+	ads.Err = errors.New("database down")
+	ts.server.SetAdStore(ads)
+
+	if status := ts.do(t, http.MethodGet, "/api/v1/ads", nil, nil); status != http.StatusInternalServerError {
+		t.Fatalf("got %d, want 500", status)
+	}
+}
+
+func TestPostClickUnknownAdInAdStore(t *testing.T) {
+	ts := newTestServer(t)
+	ts.server.SetAdStore(fakes.NewAdStore(testAd(1, true)))
+
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/click", gin.H{"ad_id": 2}, nil); status != http.StatusNotFound {
+		t.Fatalf("got %d, want 404", status)
+	}
+}
+
+func TestPostClickEnqueues(t *testing.T) {
+	ts := newTestServer(t)
+	ts.server.SetAdStore(fakes.NewAdStore(testAd(1, true)))
+	queue := fakes.NewClickQueue()
+	ts.server.SetClickQueue(queue)
+
+	var resp struct {
+		ClickID string `json:"click_id"`
+	}
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/click", gin.H{"ad_id": 1, "user_id": "user-1"}, &resp); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	clicks := queue.Clicks()
+	if len(clicks) != 1 || clicks[0].ClickID != resp.ClickID || clicks[0].UserID != "user-1" {
+		t.Fatalf("queued %+v, want the recorded click", clicks)
+	}
+	if n := len(ts.store.Clicks()); n != 0 {
+		t.Errorf("%d clicks written past the queue", n)
+	}
+}
+
+// A full queue falls back to writing the click synchronously.
+func TestPostClickQueueFull(t *testing.T) {
+	ts := newTestServer(t)
+	ts.server.SetAdStore(fakes.NewAdStore(testAd(1, true)))
+	queue := fakes.NewClickQueue()
+	queue.Capacity = 1
+	ts.server.SetClickQueue(queue)
+
+	for i := 0; i < 2; i++ {
+		if status := ts.do(t, http.MethodPost, "/api/v1/ads/click", gin.H{"ad_id": 1}, nil); status != http.StatusOK {
+			t.Fatalf("click %d got %d, want 200", i, status)
+		}
+	}
+	if n := len(queue.Clicks()); n != 1 {
+		t.Errorf("queued %d clicks, want 1", n)
+	}
+	if n := len(ts.store.Clicks()); n != 1 {
+		t.Errorf("stored %d clicks directly, want 1", n)
+	}
+
+	ts.store.Err = errors.New("store unavailable")
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/click", gin.H{"ad_id": 1}, nil); status != http.StatusInternalServerError {
+		t.Errorf("with the queue full and the store failing got %d, want 500", status)
+	}
+}
+
+func TestReadyzReportsQueueSaturation(t *testing.T) {
+	ts := newTestServer(t)
+	queue := fakes.NewClickQueue()
+	queue.Capacity = 2
+	ts.server.SetClickQueue(queue)
+
+	if status := ts.do(t, http.MethodGet, "/readyz", nil, nil); status != http.StatusOK {
+		t.Fatalf("empty queue got %d, want 200", status)
+	}
+	queue.Enqueue(models.ClickEvent{AdID: 1})
+	queue.Enqueue(models.ClickEvent{AdID: 1})
+	if status := ts.do(t, http.MethodGet, "/readyz", nil, nil); status != http.StatusServiceUnavailable {
+		t.Fatalf("full queue got %d, want 503", status)
+	}
+}
+
+func TestAnalyticsFromReader(t *testing.T) {
+	ts := newTestServer(t)
+	analytics := fakes.NewAnalytics()
+	analytics.SetAd(models.AnalyticsResponse{AdID: 7, ClickCount: 42, Impressions: 1000, CTR: 0.042})
+	ts.server.SetAnalyticsReader(analytics)
+
+	var resp struct {
+		Analytics models.AnalyticsResponse `json:"analytics"`
+	}
+	start := time.Now().UTC()
+	status := ts.do(t, http.MethodGet, "/api/v1/ads/analytics?timeframe=1h&valid_only=true&ad_id=7", nil, &resp)
+	if status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	if got := resp.Analytics; got.ClickCount != 42 || got.Impressions != 1000 || !got.ValidOnly {
+		t.Errorf("analytics = %+v, want the canned figures", got)
+	}
+
+	queries := analytics.Queries()
+	if len(queries) != 1 {
+		t.Fatalf("%d queries, want 1", len(queries))
+	}
+	query := queries[0]
+	if query.AdID != 7 || !query.ValidOnly {
+		t.Errorf("query = %+v, want ad 7, valid only", query)
+	}
+	if since := start.Add(-time.Hour); query.Since.Before(since.Add(-time.Second)) || query.Since.After(time.Now().UTC().Add(-time.Hour)) {
+		t.Errorf("since = %v, want an hour ago", query.Since)
+	}
+
+	// Repeats within the cache TTL do not reach the reader
+	ts.do(t, http.MethodGet, "/api/v1/ads/analytics?timeframe=1h&valid_only=true&ad_id=7", nil, nil)
+	if n := len(analytics.Queries()); n != 1 {
+		t.Errorf("%d queries after a repeat, want 1", n)
+	}
+}
+
+func TestAllAnalyticsFromReader(t *testing.T) {
+	ts := newTestServer(t)
+	analytics := fakes.NewAnalytics()
+	analytics.SetAd(models.AnalyticsResponse{AdID: 2, ClickCount: 5})
+	analytics.SetAd(models.AnalyticsResponse{AdID: 1, ClickCount: 3})
+	ts.server.SetAnalyticsReader(analytics)
+
+	var resp struct {
+		Analytics []models.AnalyticsResponse `json:"analytics"`
+	}
+	if status := ts.do(t, http.MethodGet, "/api/v1/ads/analytics?timeframe=1d", nil, &resp); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	if len(resp.Analytics) != 2 || resp.Analytics[0].AdID != 1 || resp.Analytics[1].ClickCount != 5 {
+		t.Errorf("analytics = %+v, want ads 1 and 2 in order", resp.Analytics)
+	}
+	if queries := analytics.Queries(); len(queries) != 1 || queries[0].AdID != 0 {
+		t.Errorf("queries = %+v, want one for all ads", queries)
+	}
+}
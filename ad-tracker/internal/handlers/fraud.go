@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/fraud"
+	"ad-tracking-system/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SetBotFilter configures known-bot handling. With drop set, events from
+// listed User-Agents are discarded at ingestion; otherwise they are recorded
+// and the known_bot fraud rule tags them invalid.
+func (s *Server) SetBotFilter(list *fraud.BotList, drop bool) {
+	s.botList = list
+	s.dropBots = drop
+}
+
+// dropBot reports whether the event comes from a known bot and should be
+// discarded without recording.
+func (s *Server) dropBot(c *gin.Context, eventType string) bool {
+	if !s.dropBots {
+		return false
+	}
+	signature, ok := s.botList.Match(c.GetHeader("User-Agent"))
+	if !ok {
+		return false
+	}
+	metrics.BotEventsDropped.WithLabelValues(eventType).Inc()
+	s.logSampler.Log(s.logger.WithFields(logrus.Fields{
+		"event_type": eventType,
+		"signature":  signature,
+	}), logrus.DebugLevel, "Dropped event from known bot")
+	return true
+}
+
+// scoreEvent runs the fraud rules for an incoming event. Events are always
+// stored; the verdict only tags them so analytics can filter.
+func (s *Server) scoreEvent(c *gin.Context, eventType string, adID uint, userID string) fraud.Result {
+	verdict := s.fraud.Score(fraud.Signal{
+		Type:      eventType,
+		AdID:      adID,
+		UserID:    userID,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Timestamp: time.Now(),
+	})
+
+	for _, reason := range verdict.Reasons {
+		metrics.FraudFlagged.WithLabelValues(eventType, reason).Inc()
+	}
+	if verdict.Invalid {
+		metrics.FraudInvalidEvents.WithLabelValues(eventType).Inc()
+		s.logSampler.Log(s.logger.WithFields(logrus.Fields{
+			"event_type": eventType,
+			"ad_id":      adID,
+			"client_ip":  s.storedIP(c),
+			"score":      verdict.Score,
+			"reasons":    verdict.ReasonString(),
+		}), logrus.WarnLevel, "Event tagged invalid by fraud scoring")
+	}
+	return verdict
+}
@@ -2,126 +2,502 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
+	"ad-tracking-system/internal/buildinfo"
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/fraud"
 	"ad-tracking-system/internal/metrics"
 	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+	"ad-tracking-system/internal/signing"
+	"ad-tracking-system/internal/validation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/segmentio/kafka-go"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/sirupsen/logrus"
 )
 
 func (s *Server) GetAds(c *gin.Context) {
-	start := time.Now()
-	defer func() {
-		metrics.ResponseTime.WithLabelValues("GET", "/ads", strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
-	}()
-
-	var ads []models.Ad
-	if err := s.db.Where("active = ?", true).Find(&ads).Error; err != nil {
+	ads, err := s.ads.Active(c.Request.Context())
+	if err != nil {
 		s.logger.WithError(err).Error("Failed to fetch ads")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ads"})
 		return
 	}
+	placement, ok := s.placementQuery(c)
+	if !ok {
+		return
+	}
+	if placement != nil {
+		ads = services.Fit(ads, *placement)
+	}
 
+	// A cached list would serve targeted ads to the wrong viewers, paced
+	// campaigns past their tokens, capped campaigns past their caps and
+	// experiment variants without assignment. Experiments go last so only
+	// ads actually served are logged as exposures; creatives rotate among
+	// what is left.
+	viewer := s.viewer(c)
+	ads, targeted := s.filterTargeted(c, ads)
+	ads, capped := s.budgets.CapFrequency(c.Request.Context(), ads, viewer)
+	ads, paced := s.budgets.Pace(c.Request.Context(), ads)
+	ads, exposures := s.experiments.Assign(ads, viewer)
+	s.logExposures(exposures)
+	ads, rotated := rotateCreatives(ads)
+	if targeted || paced || capped || rotated || len(exposures) > 0 {
+		c.Header("Cache-Control", "no-store")
+	} else {
+		c.Header("Cache-Control", publicMaxAge(s.adsMaxAge))
+	}
+	if notModified(c, etagFor(ads)) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"ads": ads})
 }
 
 func (s *Server) PostClick(c *gin.Context) {
 	start := time.Now()
 	defer func() {
-		metrics.ResponseTime.WithLabelValues("POST", "/ads/click", strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
+		s.status.ObserveIngest(time.Since(start))
 	}()
 
 	var req models.ClickRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+	if s.rejectBlocked(c, fraud.EventClick, req.AdID) {
+		return
+	}
+	if problem := s.verifyEvent(c, signing.KindClick, req.AdID, req.LinkSignature, req.UserID); problem != nil {
+		problem.write(c)
+		return
+	}
+	if s.dropBot(c, fraud.EventClick) {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
 		return
 	}
 
-	var ad models.Ad
-	if err := s.db.First(&ad, req.AdID).Error; err != nil {
+	ad, err := s.ads.Get(c.Request.Context(), req.AdID)
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ad not found"})
 		return
 	}
 
-	clickEvent := models.ClickEvent{
-		AdID:              req.AdID,
-		Timestamp:         time.Now(),
-		IPAddress:         c.ClientIP(),
-		VideoPlaybackTime: req.VideoPlaybackTime,
-		UserAgent:         c.GetHeader("User-Agent"),
+	clickEvent, ok := s.recordClick(c, ad, req)
+	if !ok {
+		return
 	}
 
-	if req.Timestamp > 0 {
-		clickEvent.Timestamp = time.Unix(req.Timestamp, 0)
+	c.JSON(http.StatusOK, gin.H{
+		"status":       "recorded",
+		"click_id":     clickEvent.ClickID,
+		"session_id":   clickEvent.SessionID,
+		"redirect_url": expandTargetURL(ad.TargetURL, clickEvent),
+	})
+}
+
+// RedirectClick records a click from a signed link and redirects the browser
+// to the ad's target URL with macros expanded.
+func (s *Server) RedirectClick(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		s.status.ObserveIngest(time.Since(start))
+	}()
+
+	adID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad id"})
+		return
+	}
+	if s.rejectBlocked(c, fraud.EventClick, uint(adID)) {
+		return
+	}
+	if !s.verifyLink(c, signing.KindClick, uint(adID)) {
+		return
+	}
+
+	ad, err := s.ads.Get(c.Request.Context(), uint(adID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad not found"})
+		return
+	}
+
+	// Crawlers following the link still land on the target, unrecorded
+	if s.dropBot(c, fraud.EventClick) {
+		c.Redirect(http.StatusFound, expandTargetURL(ad.TargetURL, models.ClickEvent{AdID: ad.ID}))
+		return
+	}
+
+	creativeID, ok := creativeQuery(c)
+	if !ok {
+		return
+	}
+
+	req := models.ClickRequest{
+		AdID:          ad.ID,
+		UserID:        c.Query("user_id"),
+		CreativeID:    creativeID,
+		Placement:     c.Query("placement"),
+		SessionID:     c.Query("session_id"),
+		ConsentParams: consentQuery(c),
+	}
+	// Query parameters skip binding, so hold them to the same rules
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+
+	clickEvent, ok := s.recordClick(c, ad, req)
+	if !ok {
+		return
+	}
+
+	c.Redirect(http.StatusFound, expandTargetURL(ad.TargetURL, clickEvent))
+}
+
+// eventProblem is why a tracking event was refused, with the status a
+// single-event endpoint answers with.
+type eventProblem struct {
+	status  int
+	message string
+	fields  map[string]string
+}
+
+func (p *eventProblem) write(c *gin.Context) {
+	body := gin.H{"error": p.message}
+	if p.fields != nil {
+		body["fields"] = p.fields
+	}
+	c.JSON(p.status, body)
+}
+
+// fieldProblem refuses an event over one field, in the shape of
+// validation.Response.
+func fieldProblem(field, message string) *eventProblem {
+	return &eventProblem{status: http.StatusBadRequest, message: "Invalid request", fields: map[string]string{field: message}}
+}
+
+// recordClick assigns a click_id, queues the click and publishes it. On
+// failure it writes the error response and returns false.
+func (s *Server) recordClick(c *gin.Context, ad models.Ad, req models.ClickRequest) (models.ClickEvent, bool) {
+	clickEvent, permitted, problem := s.prepareClick(c, ad, req)
+	if problem != nil {
+		problem.write(c)
+		return models.ClickEvent{}, false
 	}
 
 	if !s.clickQueue.Enqueue(clickEvent) {
-		if err := s.db.Create(&clickEvent).Error; err != nil {
+		if err := s.eventStore.SaveClicks(c.Request.Context(), []models.ClickEvent{clickEvent}); err != nil {
 			s.logger.WithError(err).Error("Failed to save click event")
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record click"})
-			return
+			return models.ClickEvent{}, false
 		}
 	}
 
+	s.clickRecorded(c, ad, req, clickEvent, permitted)
+	return clickEvent, true
+}
+
+// prepareClick checks a click request against its ad and builds the event
+// to store, reporting whether consent permits identifiers. It writes no
+// response.
+func (s *Server) prepareClick(c *gin.Context, ad models.Ad, req models.ClickRequest) (models.ClickEvent, bool, *eventProblem) {
+	if !hasCreative(ad, req.CreativeID) {
+		return models.ClickEvent{}, false, fieldProblem("creative_id", "is not a creative of this ad")
+	}
+	// Nobody watches a video for longer than it runs
+	if ad.DurationSeconds > 0 && req.VideoPlaybackTime > ad.DurationSeconds {
+		return models.ClickEvent{}, false, fieldProblem("video_playback_time",
+			fmt.Sprintf("must be at most the ad's duration of %d seconds", ad.DurationSeconds))
+	}
+
+	receivedAt := time.Now()
+	timestamp, problem := s.resolveEventTime("click", req.Timestamp, receivedAt)
+	if problem != nil {
+		return models.ClickEvent{}, false, problem
+	}
+	placementID, ok := s.lookupPlacement(req.Placement)
+	if !ok {
+		return models.ClickEvent{}, false, &eventProblem{status: http.StatusBadRequest, message: "Unknown placement"}
+	}
+
+	clickID, err := newClickID()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to generate click id")
+		return models.ClickEvent{}, false, &eventProblem{status: http.StatusInternalServerError, message: "Failed to record click"}
+	}
+
+	clickEvent := models.ClickEvent{
+		ClickID:           clickID,
+		AdID:              req.AdID,
+		Timestamp:         timestamp,
+		ReceivedAt:        receivedAt,
+		UserID:            s.storedUserID(req.UserID),
+		IPAddress:         s.storedIP(c),
+		VideoPlaybackTime: req.VideoPlaybackTime,
+		CreativeID:        req.CreativeID,
+		PlacementID:       placementID,
+		UserAgent:         c.GetHeader("User-Agent"),
+	}
+
+	verdict := s.scoreEvent(c, fraud.EventClick, req.AdID, req.UserID)
+	clickEvent.FraudScore = verdict.Score
+	clickEvent.FraudReasons = verdict.ReasonString()
+	clickEvent.Invalid = verdict.Invalid
+
+	state, permitted := s.applyConsent(fraud.EventClick, req.AdID, req.ConsentParams)
+	clickEvent.ConsentState = state
+	clickEvent.ConsentString = storedConsent(req.ConsentParams)
+	if permitted {
+		clickEvent.SessionID = s.session(c, req.SessionID)
+	} else {
+		clickEvent.UserID, clickEvent.IPAddress = "", ""
+	}
+
+	if ad.Honeypot {
+		s.springTrap(c, ad.ID)
+		clickEvent.Invalid, clickEvent.FraudScore = true, 1
+		clickEvent.FraudReasons = strings.TrimPrefix(clickEvent.FraudReasons+","+honeypotReason, ",")
+	}
+	return clickEvent, permitted, nil
+}
+
+// clickRecorded runs what follows a stored or queued click: metrics,
+// capture, budget, and publishing to Kafka, webhooks and forwarders.
+func (s *Server) clickRecorded(c *gin.Context, ad models.Ad, req models.ClickRequest, clickEvent models.ClickEvent, permitted bool) {
 	metrics.ClicksReceived.WithLabelValues(strconv.FormatUint(uint64(req.AdID), 10)).Inc()
+	metrics.EventsRecorded.WithLabelValues(fraud.EventClick, campaignLabel(ad)).Inc()
+	if !clickEvent.Invalid {
+		s.observeIdentity(clickEvent.UserID, req.IdentityHints, clickEvent.Timestamp)
+	}
+	req.UserID = clickEvent.UserID // captures keep the stored form
+	req.IdentityHints = models.IdentityHints{}
+	s.observeIngest(c, req.AdID, req)
+	if !clickEvent.Invalid {
+		s.budgets.ChargeClick(c.Request.Context(), ad)
+	}
 
-	go s.publishToKafka(clickEvent)
+	s.background(func() { s.publishToKafka(clickEvent) })
+	s.webhooks.Publish(models.WebhookEventClick, clickEvent.AdID, clickEvent.ClickID, clickEvent)
+	if permitted && !clickEvent.Invalid {
+		s.forwarder.Publish(services.ForwardedClick(clickEvent))
+	}
+}
 
-	c.JSON(http.StatusOK, gin.H{"status": "recorded"})
+func newClickID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// expandTargetURL substitutes {click_id} and {ad_id} macros in a target URL
+// so advertisers can pass the click id through to their postback.
+func expandTargetURL(target string, click models.ClickEvent) string {
+	return strings.NewReplacer(
+		"{click_id}", url.QueryEscape(click.ClickID),
+		"{ad_id}", strconv.FormatUint(uint64(click.AdID), 10),
+	).Replace(target)
+}
+
+func (s *Server) PostImpression(c *gin.Context) {
+	var req models.ImpressionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+	if s.rejectBlocked(c, fraud.EventImpression, req.AdID) {
+		return
+	}
+	if problem := s.verifyEvent(c, signing.KindPixel, req.AdID, req.LinkSignature, req.UserID); problem != nil {
+		problem.write(c)
+		return
+	}
+	if s.dropBot(c, fraud.EventImpression) {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	ad, err := s.ads.Get(c.Request.Context(), req.AdID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad not found"})
+		return
+	}
+	impression, problem := s.prepareImpression(c, ad, req)
+	if problem != nil {
+		problem.write(c)
+		return
+	}
+
+	if err := s.eventStore.SaveImpressions(c.Request.Context(), []models.ImpressionEvent{impression}); err != nil {
+		s.logger.WithError(err).Error("Failed to save impression event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record impression"})
+		return
+	}
+	s.impressionRecorded(c, ad, req, impression)
+
+	c.JSON(http.StatusOK, gin.H{"status": "recorded", "session_id": impression.SessionID})
+}
+
+// prepareImpression checks an impression request against its ad and builds
+// the event to store. It writes no response.
+func (s *Server) prepareImpression(c *gin.Context, ad models.Ad, req models.ImpressionRequest) (models.ImpressionEvent, *eventProblem) {
+	if !hasCreative(ad, req.CreativeID) {
+		return models.ImpressionEvent{}, fieldProblem("creative_id", "is not a creative of this ad")
+	}
+	receivedAt := time.Now()
+	timestamp, problem := s.resolveEventTime("impression", req.Timestamp, receivedAt)
+	if problem != nil {
+		return models.ImpressionEvent{}, problem
+	}
+	placementID, ok := s.lookupPlacement(req.Placement)
+	if !ok {
+		return models.ImpressionEvent{}, &eventProblem{status: http.StatusBadRequest, message: "Unknown placement"}
+	}
+
+	impression := models.ImpressionEvent{
+		AdID:          req.AdID,
+		Timestamp:     timestamp,
+		ReceivedAt:    receivedAt,
+		UserID:        s.storedUserID(req.UserID),
+		IPAddress:     s.storedIP(c),
+		UserAgent:     c.GetHeader("User-Agent"),
+		TimeInViewMS:  req.TimeInViewMS,
+		PercentInView: req.PercentInView,
+		CreativeID:    req.CreativeID,
+		PlacementID:   placementID,
+	}
+
+	verdict := s.scoreEvent(c, fraud.EventImpression, req.AdID, req.UserID)
+	impression.FraudScore = verdict.Score
+	impression.FraudReasons = verdict.ReasonString()
+	impression.Invalid = verdict.Invalid
+
+	state, permitted := s.applyConsent(fraud.EventImpression, req.AdID, req.ConsentParams)
+	impression.ConsentState = state
+	impression.ConsentString = storedConsent(req.ConsentParams)
+	if permitted {
+		impression.SessionID = s.session(c, req.SessionID)
+	} else {
+		impression.UserID, impression.IPAddress = "", ""
+	}
+	return impression, nil
+}
+
+// impressionRecorded runs what follows a stored impression: capture and
+// budget.
+func (s *Server) impressionRecorded(c *gin.Context, ad models.Ad, req models.ImpressionRequest, impression models.ImpressionEvent) {
+	metrics.EventsRecorded.WithLabelValues(fraud.EventImpression, campaignLabel(ad)).Inc()
+	if !impression.Invalid {
+		s.observeIdentity(impression.UserID, req.IdentityHints, impression.Timestamp)
+	}
+	req.UserID = impression.UserID // captures keep the stored form
+	req.IdentityHints = models.IdentityHints{}
+	s.observeIngest(c, req.AdID, req)
+	if !impression.Invalid {
+		s.budgets.ChargeImpression(c.Request.Context(), ad)
+	}
+}
+
+// campaignLabel is the campaign_id metric label for an ad's events.
+func campaignLabel(ad models.Ad) string {
+	if ad.CampaignID == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*ad.CampaignID), 10)
 }
 
 func (s *Server) publishToKafka(clickEvent models.ClickEvent) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	eventBytes, err := json.Marshal(clickEvent)
+	eventBytes, err := events.EncodeClick(clickEvent)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to serialize click event")
 		return
 	}
 
-	err = s.KafkaWriter.WriteMessages(
-		ctx,
-		kafka.Message{
-			Key:   []byte(strconv.Itoa(int(clickEvent.AdID))),
-			Value: eventBytes,
-		},
-	)
+	err = s.eventBus.Publish(ctx, []byte(strconv.Itoa(int(clickEvent.AdID))), eventBytes)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to publish click event to Kafka")
 	}
 }
 
+// GetAnalytics returns per-ad analytics as JSON, or as CSV with ?format=csv.
 func (s *Server) GetAnalytics(c *gin.Context) {
-	start := time.Now()
-	defer func() {
-		metrics.ResponseTime.WithLabelValues("GET", "/ads/analytics", strconv.Itoa(c.Writer.Status())).Observe(time.Since(start).Seconds())
-	}()
+	s.serveAnalytics(c, csvRequested(c))
+}
+
+// ExportAnalytics is GetAnalytics as a CSV download.
+func (s *Server) ExportAnalytics(c *gin.Context) {
+	s.serveAnalytics(c, true)
+}
 
+func (s *Server) serveAnalytics(c *gin.Context, asCSV bool) {
 	adIDStr := c.Query("ad_id")
-	timeframe := c.DefaultQuery("timeframe", "24h")
+	validOnly := c.Query("valid_only") == "true"
+
+	// The debug block exposes raw counts and timestamps, so it is opt-in
+	// and limited to admins unless DEBUG_ANALYTICS allows everyone
+	debug := c.Query("debug") == "true" && !asCSV
+	if debug && (s.debugAllowed == nil || !s.debugAllowed(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "debug=true requires admin credentials"})
+		return
+	}
 
-	duration := s.parseDuration(timeframe)
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
 	since := time.Now().UTC().Add(-duration)
 
 	// Use UTC for consistent timezone handling
 	beginningOfToday := time.Date(time.Now().UTC().Year(), time.Now().UTC().Month(), time.Now().UTC().Day(), 0, 0, 0, 0, time.UTC)
 
-	s.logger.WithFields(logrus.Fields{
+	s.logSampler.Log(s.logger.WithFields(logrus.Fields{
 		"timeframe": timeframe,
 		"duration":  duration,
 		"since":     since,
 		"now":       time.Now().UTC(),
 		"ad_id":     adIDStr,
-	}).Info("Analytics request parameters")
-
-	debugInfo := s.getDebugCounts(adIDStr, since, beginningOfToday)
+	}), logrus.InfoLevel, "Analytics request parameters")
+
+	if asCSV {
+		var rows []models.AnalyticsResponse
+		if adIDStr != "" {
+			adID, err := strconv.ParseUint(adIDStr, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad_id"})
+				return
+			}
+			analytics, err := s.adAnalytics(c, uint(adID), timeframe, since, validOnly)
+			if err != nil {
+				s.logger.WithError(err).Error("Failed to load analytics")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load analytics"})
+				return
+			}
+			rows = []models.AnalyticsResponse{analytics}
+		} else {
+			var err error
+			if rows, err = s.allAnalytics(c, timeframe, since, validOnly); err != nil {
+				s.logger.WithError(err).Error("Failed to load analytics")
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load analytics"})
+				return
+			}
+		}
+		s.writeAnalyticsCSV(c, "analytics-"+timeframe+".csv", rows)
+		return
+	}
 
 	if adIDStr != "" {
 		adID, err := strconv.ParseUint(adIDStr, 10, 32)
@@ -130,19 +506,39 @@ func (s *Server) GetAnalytics(c *gin.Context) {
 			return
 		}
 
-		analytics := s.analyticsRepository.GetAdAnalytics(uint(adID), since)
+		analytics, err := s.adAnalytics(c, uint(adID), timeframe, since, validOnly)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to load analytics")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load analytics"})
+			return
+		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"analytics": analytics,
-			"debug":     debugInfo,
-		})
+		c.Header("Cache-Control", "private, no-cache")
+		if notModified(c, etagFor(analytics)) {
+			return
+		}
+		response := gin.H{"analytics": analytics}
+		if debug {
+			response["debug"] = s.getDebugCounts(adIDStr, since, beginningOfToday)
+		}
+		c.JSON(http.StatusOK, response)
 	} else {
-		analytics := s.analyticsRepository.GetAllAnalytics(since)
+		analytics, err := s.allAnalytics(c, timeframe, since, validOnly)
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to load analytics")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load analytics"})
+			return
+		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"analytics": analytics,
-			"debug":     debugInfo,
-		})
+		c.Header("Cache-Control", "private, no-cache")
+		if notModified(c, etagFor(analytics)) {
+			return
+		}
+		response := gin.H{"analytics": analytics}
+		if debug {
+			response["debug"] = s.getDebugCounts(adIDStr, since, beginningOfToday)
+		}
+		c.JSON(http.StatusOK, response)
 	}
 }
 
@@ -155,7 +551,12 @@ func (s *Server) getDebugCounts(adIDStr string, since, beginningOfToday time.Tim
 
 	// Add timezone-aware debugging
 	var timezoneTestCount int64
-	s.db.Raw("SELECT COUNT(*) FROM click_events WHERE timestamp AT TIME ZONE 'UTC' >= ? AT TIME ZONE 'UTC'", since).Scan(&timezoneTestCount)
+	if s.db.Dialector.Name() == "postgres" {
+		s.db.Raw("SELECT COUNT(*) FROM click_events WHERE timestamp AT TIME ZONE 'UTC' >= ? AT TIME ZONE 'UTC'", since).Scan(&timezoneTestCount)
+	} else {
+		// Other drivers store timestamps in UTC already
+		s.db.Raw("SELECT COUNT(*) FROM click_events WHERE timestamp >= ?", since.UTC()).Scan(&timezoneTestCount)
+	}
 
 	if adIDStr != "" {
 		adID, err := strconv.ParseUint(adIDStr, 10, 32)
@@ -192,36 +593,44 @@ func (s *Server) getDebugCounts(adIDStr string, since, beginningOfToday time.Tim
 		"since":                since,
 		"beginning_of_today":   beginningOfToday,
 		"sample_timestamps":    sampleTimestamps,
-	}).Info("Record counts with timezone debugging")
+	}).Debug("Record counts with timezone debugging")
 
 	return debugInfo
 }
 
-func (s *Server) parseDuration(timeframe string) time.Duration {
-	switch timeframe {
-	case "1h":
-		return time.Hour
-	case "24h":
-		return 24 * time.Hour
-	case "7d":
-		return 7 * 24 * time.Hour
-	case "all":
-		return 10 * 365 * 24 * time.Hour // 10 years
-	default:
-		return 24 * time.Hour
-	}
-}
+// Sample sizes for DebugAnalytics; the cap keeps a support request from
+// dumping the click table.
+const (
+	debugSampleDefault = 10
+	debugSampleMax     = 100
+)
 
+// DebugAnalytics compares the analytics counts against raw queries and
+// returns recent click records, for support engineers investigating
+// mismatched numbers. It is mounted behind admin auth and every use is
+// audited.
 func (s *Server) DebugAnalytics(c *gin.Context) {
 	adIDStr := c.Query("ad_id")
-	timeframe := c.DefaultQuery("timeframe", "24h")
 
-	duration := s.parseDuration(timeframe)
+	sample := debugSampleDefault
+	if raw := c.Query("sample"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 || n > debugSampleMax {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("sample must be between 0 and %d", debugSampleMax)})
+			return
+		}
+		sample = n
+	}
+
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
 	since := time.Now().UTC().Add(-duration)
 
 	// Get sample data to understand what's in the database
-	var clickEvents []models.ClickEvent
-	query := s.db.Order("timestamp DESC").Limit(10)
+	clickEvents := []models.ClickEvent{}
+	query := s.db.Order("timestamp DESC").Limit(sample)
 
 	if adIDStr != "" {
 		adID, err := strconv.ParseUint(adIDStr, 10, 32)
@@ -232,7 +641,21 @@ func (s *Server) DebugAnalytics(c *gin.Context) {
 		query = query.Where("ad_id = ?", uint(adID))
 	}
 
-	query.Find(&clickEvents)
+	actor := "admin"
+	if name := c.GetString("client_cert"); name != "" {
+		actor = name
+	}
+	if err := s.auditRepository.Record(actor, "debug.analytics", "ad", adIDStr, gin.H{
+		"timeframe": timeframe,
+		"sample":    sample,
+		"ip":        c.ClientIP(),
+	}); err != nil {
+		s.logger.WithError(err).Warn("Failed to record debug analytics audit event")
+	}
+
+	if sample > 0 {
+		query.Find(&clickEvents)
+	}
 
 	// Get counts with different approaches
 	var totalCount int64
@@ -277,9 +700,9 @@ func (s *Server) DebugAnalytics(c *gin.Context) {
 	var analyticsResult interface{}
 	if adIDStr != "" {
 		adID, _ := strconv.ParseUint(adIDStr, 10, 32)
-		analyticsResult = s.analyticsRepository.GetAdAnalytics(uint(adID), since)
+		analyticsResult = s.analyticsRepository.GetAdAnalytics(uint(adID), since, false)
 	} else {
-		analyticsResult = s.analyticsRepository.GetAllAnalytics(since)
+		analyticsResult = s.analyticsRepository.GetAllAnalytics(since, false)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -308,10 +731,28 @@ func (s *Server) DebugAnalytics(c *gin.Context) {
 	})
 }
 
+// Health is the original combined probe, kept for existing monitors. It
+// reads the cached database state; Kubernetes should use /livez and /readyz.
 func (s *Server) Health(c *gin.Context) {
+	if s.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "draining",
+			"timestamp": time.Now().Unix(),
+		})
+		return
+	}
+	if !s.dbMonitor.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "unavailable",
+			"reason":    "database",
+			"timestamp": time.Now().Unix(),
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
 		"timestamp": time.Now().Unix(),
-		"version":   "1.0.0",
+		"version":   buildinfo.Version,
 	})
 }
@@ -0,0 +1,249 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/fakes"
+	"ad-tracking-system/internal/handlers"
+	"ad-tracking-system/internal/migrations"
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// testServer is a Server on a migrated in-memory SQLite database, with the
+// event bus, and unless a test sets another, the event store replaced by the
+// in-memory fakes.
+type testServer struct {
+	db     *gorm.DB
+	server *handlers.Server
+	store  *fakes.EventStore
+	bus    *fakes.EventBus
+	router *gin.Engine
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	ts := &testServer{store: fakes.NewEventStore()}
+	ts.start(t, openTestDB(t), ts.store)
+	return ts
+}
+
+// start wires the server to db and store and routes the serving, tracking,
+// analytics and readiness endpoints to it.
+func (ts *testServer) start(t *testing.T, db *gorm.DB, store events.EventStore) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	ts.db, ts.bus = db, fakes.NewEventBus()
+	ts.server = handlers.NewServer(db, logger, store, ts.bus)
+
+	gin.SetMode(gin.TestMode)
+	ts.router = gin.New()
+	ts.router.GET("/readyz", ts.server.Readyz)
+	api := ts.router.Group("/api/v1")
+	api.GET("/ads", ts.server.GetAds)
+	api.POST("/ads/click", ts.server.PostClick)
+	api.POST("/ads/impression", ts.server.PostImpression)
+	api.GET("/ads/:id/pixel", ts.server.TrackingPixel)
+	api.POST("/events/batch", ts.server.PostEventBatch)
+	api.GET("/ads/analytics", ts.server.GetAnalytics)
+}
+
+// openTestDB returns a migrated in-memory SQLite database, closed when the
+// test ends.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db, err := database.SetupDatabase(database.DriverSQLite, "file::memory:", database.PoolConfig{}, logger)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err := migrations.New(db, logger).Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// createAd inserts an active ad into the database.
+func (ts *testServer) createAd(t *testing.T) models.Ad {
+	t.Helper()
+	ad := models.Ad{ImageURL: "https://cdn.example.com/ad.png", TargetURL: "https://example.com/?c={click_id}", Title: "Test ad", Active: true}
+	if err := ts.db.Create(&ad).Error; err != nil {
+		t.Fatalf("create ad: %v", err)
+	}
+	return ad
+}
+
+// do sends a request from a browser user agent and decodes a JSON answer
+// into out, when given.
+func (ts *testServer) do(t *testing.T, method, target string, body interface{}, out interface{}) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64)")
+	req.RemoteAddr = "203.0.113.7:4321"
+
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decode %s %s response %q: %v", method, target, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// wait lets the server's background tasks finish.
+func (ts *testServer) wait(t *testing.T) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ts.server.Shutdown(ctx); err != nil {
+		t.Fatalf("wait for background tasks: %v", err)
+	}
+}
+
+func TestPostImpressionStoresEvent(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createAd(t)
+
+	var resp map[string]interface{}
+	status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", gin.H{
+		"ad_id":           ad.ID,
+		"user_id":         "user-1",
+		"time_in_view_ms": 1500,
+		"percent_in_view": 80,
+	}, &resp)
+	if status != http.StatusOK || resp["status"] != "recorded" {
+		t.Fatalf("got %d %v, want 200 recorded", status, resp)
+	}
+
+	count, err := ts.store.CountImpressions(context.Background(), events.Query{AdID: ad.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("stored %d impressions, want 1", count)
+	}
+	stats, err := ts.store.ViewabilityStats(context.Background(), events.Query{AdID: ad.ID}, 50, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Viewable != 1 {
+		t.Errorf("viewable impressions = %d, want 1", stats.Viewable)
+	}
+}
+
+func TestPostImpressionStoreFailure(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createAd(t)
+	ts.store.Err = errors.New("store unavailable")
+
+	status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", gin.H{"ad_id": ad.ID}, nil)
+	if status != http.StatusInternalServerError {
+		t.Fatalf("got %d, want 500", status)
+	}
+}
+
+func TestPostImpressionUnknownAd(t *testing.T) {
+	ts := newTestServer(t)
+
+	status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", gin.H{"ad_id": 999}, nil)
+	if status != http.StatusNotFound {
+		t.Fatalf("got %d, want 404", status)
+	}
+}
+
+func TestPostClickPublishesEvent(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createAd(t)
+
+	var resp struct {
+		Status      string `json:"status"`
+		ClickID     string `json:"click_id"`
+		RedirectURL string `json:"redirect_url"`
+	}
+	status := ts.do(t, http.MethodPost, "/api/v1/ads/click", gin.H{"ad_id": ad.ID, "user_id": "user-1"}, &resp)
+	if status != http.StatusOK || resp.Status != "recorded" {
+		t.Fatalf("got %d %+v, want 200 recorded", status, resp)
+	}
+	if resp.ClickID == "" {
+		t.Fatal("response has no click id")
+	}
+	if want := "https://example.com/?c=" + resp.ClickID; resp.RedirectURL != want {
+		t.Errorf("redirect_url = %q, want %q", resp.RedirectURL, want)
+	}
+
+	ts.wait(t)
+	messages := ts.bus.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("published %d messages, want 1", len(messages))
+	}
+	if key := string(messages[0].Key); key != strconv.Itoa(int(ad.ID)) {
+		t.Errorf("message key = %q, want the ad id", key)
+	}
+	click, err := events.DecodeClick(messages[0].Value)
+	if err != nil {
+		t.Fatalf("decode published click: %v", err)
+	}
+	if click.ClickID != resp.ClickID || click.AdID != ad.ID {
+		t.Errorf("published click %+v does not match the response", click)
+	}
+}
+
+func TestPostClickSurvivesBusFailure(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createAd(t)
+	ts.bus.Err = errors.New("broker down")
+
+	status := ts.do(t, http.MethodPost, "/api/v1/ads/click", gin.H{"ad_id": ad.ID}, nil)
+	if status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	ts.wait(t)
+	if n := len(ts.bus.Messages()); n != 0 {
+		t.Errorf("bus recorded %d messages while failing", n)
+	}
+}
+
+func TestPostClickRejectsInvalidBody(t *testing.T) {
+	ts := newTestServer(t)
+
+	status := ts.do(t, http.MethodPost, "/api/v1/ads/click", gin.H{"user_id": "user-1"}, nil)
+	if status != http.StatusBadRequest {
+		t.Fatalf("got %d, want 400", status)
+	}
+	if n := len(ts.store.Clicks()); n != 0 {
+		t.Errorf("stored %d clicks for an invalid request", n)
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const honeypotReason = "honeypot"
+
+type honeypotPolicy struct {
+	blockFor time.Duration
+	lookback time.Duration
+}
+
+// SetHoneypotPolicy controls how long an IP that clicked a trap ad stays
+// blocked and how far back its earlier events are flagged.
+func (s *Server) SetHoneypotPolicy(blockFor, lookback time.Duration) {
+	s.honeypot = honeypotPolicy{blockFor: blockFor, lookback: lookback}
+}
+
+// springTrap temporarily blocklists the client IP and flags its recent
+// events in the background, so the click is answered as fast as any other
+// and the bot learns nothing.
+func (s *Server) springTrap(c *gin.Context, adID uint) {
+	metrics.HoneypotTriggers.Inc()
+	clientIP, storedIP, userAgent := c.ClientIP(), s.storedIP(c), c.GetHeader("User-Agent")
+	s.background(func() { s.trapClient(adID, clientIP, storedIP, userAgent) })
+}
+
+// trapClient blocks clientIP for the account owning the honeypot, so a
+// shared NAT address caught on one account's trap still reaches the
+// others. Honeypots outside any account block it everywhere. Failures are
+// logged.
+func (s *Server) trapClient(adID uint, clientIP, storedIP, userAgent string) {
+	network, err := services.ParseRange(clientIP)
+	if err != nil {
+		s.logger.WithError(err).Warn("Honeypot click with unparseable client IP")
+		return
+	}
+
+	expiresAt := time.Now().UTC().Add(s.honeypot.blockFor)
+	blocked := models.BlockedRange{
+		CIDR:      network.String(),
+		Reason:    fmt.Sprintf("clicked honeypot ad %d", adID),
+		Source:    models.BlockSourceHoneypot,
+		ExpiresAt: &expiresAt,
+	}
+	if blocked.AccountID, err = s.blocklistRepository.AccountForAd(adID); err != nil {
+		s.logger.WithError(err).Error("Failed to resolve honeypot account")
+	} else if err := s.blocklistRepository.Create(&blocked); err != nil {
+		s.logger.WithError(err).Error("Failed to blocklist honeypot IP")
+	} else {
+		s.refreshBlocklist()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	flagged, err := s.eventStore.FlagIP(ctx, storedIP, time.Now().Add(-s.honeypot.lookback), honeypotReason)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to flag events from honeypot IP")
+	}
+
+	fields := logrus.Fields{
+		"ad_id":      adID,
+		"client_ip":  storedIP,
+		"user_agent": userAgent,
+		"flagged":    flagged,
+		"expires_at": expiresAt,
+	}
+	if blocked.AccountID != nil {
+		fields["account_id"] = *blocked.AccountID
+	}
+	s.logSampler.Log(s.logger.WithFields(fields), logrus.WarnLevel, "Honeypot ad clicked")
+}
+
+func (s *Server) ListHoneypots(c *gin.Context) {
+	var ads []models.Ad
+	if err := s.db.Where("honeypot = ?", true).Order("id").Find(&ads).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to list honeypot ads")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list honeypot ads"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"honeypots": ads})
+}
+
+// CreateHoneypot adds a trap ad and returns a snippet that renders its link
+// off-screen, where only bots walking the DOM will find it.
+func (s *Server) CreateHoneypot(c *gin.Context) {
+	var req models.HoneypotRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	targetURL := req.TargetURL
+	if targetURL == "" {
+		targetURL = s.linkSigning.baseURL + "/"
+	}
+	ad := models.Ad{
+		CampaignID: req.CampaignID,
+		TargetURL:  targetURL,
+		Title:      "Special offer",
+		Active:     true,
+		Honeypot:   true,
+	}
+	if err := s.db.Create(&ad).Error; err != nil {
+		s.logger.WithError(err).Error("Failed to create honeypot ad")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create honeypot ad"})
+		return
+	}
+	s.invalidateAds(c.Request.Context(), ad.ID)
+
+	secret, err := s.signingSecret(ad.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to resolve signing secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign links"})
+		return
+	}
+	links := s.trackingLinks(ad, secret, "")
+
+	c.JSON(http.StatusCreated, gin.H{
+		"ad_id": ad.ID,
+		"links": links,
+		"snippet": fmt.Sprintf(
+			`<a href="%s" rel="nofollow" tabindex="-1" aria-hidden="true" style="position:absolute;left:-9999px;width:1px;height:1px;overflow:hidden">%s</a>`,
+			html.EscapeString(links.RedirectURL), html.EscapeString(ad.Title)),
+	})
+}
@@ -0,0 +1,51 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHoneypotBlocksOnlyItsAccount(t *testing.T) {
+	ts := newTestServer(t)
+	trapped, other := ts.createSignedAd(t), ts.createSignedAd(t)
+	honeypot := models.Ad{CampaignID: trapped.CampaignID, TargetURL: "https://example.com/", Title: "Special offer", Active: true, Honeypot: true}
+	if err := ts.db.Create(&honeypot).Error; err != nil {
+		t.Fatalf("create honeypot: %v", err)
+	}
+	earlier := models.ClickEvent{ClickID: "earlier", AdID: other.ID, IPAddress: "203.0.113.7", Timestamp: time.Now().Add(-time.Hour)}
+	if err := ts.store.SaveClicks(context.Background(), []models.ClickEvent{earlier}); err != nil {
+		t.Fatal(err)
+	}
+
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/click", signed(honeypot, signing.KindClick, "", gin.H{}), nil); status != http.StatusOK {
+		t.Fatalf("honeypot click got %d, want 200", status)
+	}
+	ts.wait(t)
+
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/click", signed(trapped, signing.KindClick, "", gin.H{}), nil); status != http.StatusForbidden {
+		t.Errorf("click on the honeypot's account got %d, want 403", status)
+	}
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/click", signed(other, signing.KindClick, "", gin.H{}), nil); status != http.StatusOK {
+		t.Errorf("click on another account got %d, want 200", status)
+	}
+
+	var blocked models.BlockedRange
+	if err := ts.db.Where("source = ?", models.BlockSourceHoneypot).First(&blocked).Error; err != nil {
+		t.Fatalf("no honeypot block: %v", err)
+	}
+	if blocked.AccountID == nil || blocked.ExpiresAt == nil {
+		t.Errorf("honeypot block %+v is not temporary and scoped to an account", blocked)
+	}
+	for _, click := range ts.store.Clicks() {
+		if click.ClickID == earlier.ClickID && !click.Invalid {
+			t.Error("earlier click from the trapped address was not flagged")
+		}
+	}
+}
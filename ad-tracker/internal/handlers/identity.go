@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+	"ad-tracking-system/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetIdentityGraph links user ids through the hashed emails and device ids
+// sent with events, and lets attribution follow those links. Without it the
+// hints are validated and dropped.
+func (s *Server) SetIdentityGraph(graph *services.IdentityGraph) {
+	s.identities = graph
+	s.attributor.SetIdentities(graph)
+}
+
+// observeIdentity links a stored user id to the event's hints in the
+// background. Events whose identifiers consent withheld have no user id
+// and link nothing.
+func (s *Server) observeIdentity(userID string, hints models.IdentityHints, at time.Time) {
+	if s.identities == nil || userID == "" || hints == (models.IdentityHints{}) {
+		return
+	}
+	s.background(func() {
+		if err := s.identities.Observe(userID, hints, at); err != nil {
+			s.logger.WithError(err).Warn("Failed to link identity")
+		}
+	})
+}
+
+// OptOutIdentity removes a user id, hashed email or hashed device id from
+// the identity graph and keeps it out. Events already recorded are left
+// alone; erase them with a privacy request.
+func (s *Server) OptOutIdentity(c *gin.Context) {
+	if s.identities == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity graph not configured"})
+		return
+	}
+	var req models.IdentityOptOutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+	userID := s.storedUserID(req.UserID)
+	if userID == "" && req.IdentityHints == (models.IdentityHints{}) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "One of user_id, hashed_email or hashed_device_id is required"})
+		return
+	}
+
+	removed, err := s.identities.OptOut(userID, req.IdentityHints)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to opt out identity")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to opt out identity"})
+		return
+	}
+
+	// Hashes are recorded for user ids only, as for privacy requests
+	var kinds []string
+	details := gin.H{"links_removed": removed}
+	if userID != "" {
+		kinds = append(kinds, models.IdentifierUser)
+		details["subject_hash"] = services.HashSubject(models.SubjectUserID, userID)
+	}
+	if req.HashedEmail != "" {
+		kinds = append(kinds, models.IdentifierEmail)
+	}
+	if req.HashedDeviceID != "" {
+		kinds = append(kinds, models.IdentifierDevice)
+	}
+	details["kinds"] = kinds
+	if err := s.auditRepository.Record("admin@"+c.ClientIP(), "identity.opted_out", "identity", "", details); err != nil {
+		s.logger.WithError(err).Error("Failed to write identity audit record")
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "opted_out", "links_removed": removed})
+}
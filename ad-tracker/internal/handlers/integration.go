@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (s *Server) GetForwarder() *services.Forwarder {
+	return s.forwarder
+}
+
+func (s *Server) ListIntegrations(c *gin.Context) {
+	account, ok := s.accountFromParam(c)
+	if !ok {
+		return
+	}
+
+	integrations, err := s.integrationRepo.List(account.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list integrations")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list integrations"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"integrations": integrations})
+}
+
+// CreateIntegration starts forwarding the account's events to Segment or
+// GA4. Credentials are stored but never returned.
+func (s *Server) CreateIntegration(c *gin.Context) {
+	account, ok := s.accountFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.IntegrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	integration := models.Integration{
+		AccountID:  account.ID,
+		Provider:   req.Provider,
+		EventTypes: strings.Join(req.EventTypes, ","),
+		Active:     true,
+	}
+	if req.Provider == models.IntegrationSegment {
+		integration.WriteKey = req.WriteKey
+	} else {
+		integration.MeasurementID = req.MeasurementID
+		integration.APISecret = req.APISecret
+	}
+	if err := s.integrationRepo.Create(&integration); err != nil {
+		s.logger.WithError(err).Error("Failed to create integration")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create integration"})
+		return
+	}
+	s.refreshIntegrations()
+	c.JSON(http.StatusCreated, integration)
+}
+
+// UpdateIntegration pauses or resumes forwarding.
+func (s *Server) UpdateIntegration(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid integration id"})
+		return
+	}
+
+	var req models.IntegrationUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	integration, err := s.integrationRepo.SetActive(uint(id), *req.Active)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to update integration")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update integration"})
+		return
+	}
+	s.refreshIntegrations()
+	c.JSON(http.StatusOK, integration)
+}
+
+func (s *Server) DeleteIntegration(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid integration id"})
+		return
+	}
+
+	err = s.integrationRepo.Delete(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Integration not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to delete integration")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete integration"})
+		return
+	}
+	s.refreshIntegrations()
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+func (s *Server) refreshIntegrations() {
+	if err := s.forwarder.Refresh(); err != nil {
+		s.logger.WithError(err).Warn("Failed to refresh integrations")
+	}
+}
@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartDraining fails readiness checks from now on so endpoints stop routing
+// to the pod while it keeps serving. It is called on SIGTERM, which needs no
+// credentials from the kubelet.
+func (s *Server) StartDraining(delay time.Duration) {
+	if !s.draining.Swap(true) {
+		s.logger.WithField("delay", delay).Info("Draining, failing health checks")
+	}
+}
+
+// Drain starts draining on request and holds the request for delay, for
+// operators taking a pod out of rotation by hand.
+func (s *Server) Drain(delay time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s.StartDraining(delay)
+
+		select {
+		case <-time.After(delay):
+		case <-c.Request.Context().Done():
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": "draining"})
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Onboard creates an account with its signing secret, a default campaign, an
+// optional first ad and a reporting share token, and returns the tracking
+// snippet to hand to the advertiser. Secrets are only returned here.
+func (s *Server) Onboard(c *gin.Context) {
+	var req models.OnboardingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := newSigningSecret()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to generate signing secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to onboard account"})
+		return
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		s.logger.WithError(err).Error("Failed to generate share token")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to onboard account"})
+		return
+	}
+	rawToken := hex.EncodeToString(raw)
+
+	campaignName := req.CampaignName
+	if campaignName == "" {
+		campaignName = req.AccountName + " - Default"
+	}
+
+	account := models.Account{Name: req.AccountName, SigningSecret: secret, Active: true}
+	campaign := models.Campaign{Name: campaignName, Active: true}
+	token := models.ShareToken{
+		Label:     "onboarding",
+		ExpiresAt: time.Now().UTC().Add(defaultShareTokenTTL),
+	}
+	var ad *models.Ad
+	if req.Ad != nil {
+		ad = &models.Ad{
+			ImageURL:        req.Ad.ImageURL,
+			TargetURL:       req.Ad.TargetURL,
+			Title:           req.Ad.Title,
+			DurationSeconds: req.Ad.DurationSeconds,
+			Active:          true,
+		}
+	}
+
+	if err := s.accountRepository.Onboard(&account, &campaign, ad, &token, rawToken); err != nil {
+		s.logger.WithError(err).Error("Failed to onboard account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to onboard account"})
+		return
+	}
+	if ad != nil {
+		s.invalidateAds(c.Request.Context(), ad.ID)
+	}
+
+	response := gin.H{
+		"account":        account,
+		"campaign":       campaign,
+		"signing_secret": secret,
+		"share_token":    rawToken,
+		"share_url":      s.linkSigning.baseURL + "/share/" + rawToken + "/summary",
+		"integration":    "/api/v1/admin/accounts/" + strconv.FormatUint(uint64(account.ID), 10) + "/integration",
+	}
+	if ad != nil {
+		links := s.trackingLinks(*ad, secret, "")
+		response["ad"] = ad
+		response["links"] = links
+		response["snippet"] = trackingSnippet(*ad, links)
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetIntegrationStatus tells the solutions team whether a new account has
+// ads set up and whether its first events have arrived.
+func (s *Server) GetIntegrationStatus(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid account id"})
+		return
+	}
+
+	if _, err := s.accountRepository.Get(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Account not found"})
+			return
+		}
+		s.logger.WithError(err).Error("Failed to load account")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load integration status"})
+		return
+	}
+
+	status, err := s.accountRepository.IntegrationStatus(uint(id))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load integration status")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load integration status"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// trackingSnippet renders the clickable creative plus an impression pixel.
+func trackingSnippet(ad models.Ad, links trackingLinks) string {
+	return fmt.Sprintf(
+		`<a href="%s" rel="noopener"><img src="%s" alt="%s"></a>`+
+			`<img src="%s" width="1" height="1" alt="" style="display:none">`,
+		html.EscapeString(links.RedirectURL),
+		html.EscapeString(ad.ImageURL),
+		html.EscapeString(ad.Title),
+		html.EscapeString(links.PixelURL),
+	)
+}
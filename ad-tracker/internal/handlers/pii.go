@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"ad-tracking-system/internal/edge"
+	"ad-tracking-system/internal/pii"
+	"ad-tracking-system/internal/sanitize"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	rawIPs, _  = pii.NewIPMinimizer(pii.IPRaw, "")
+	rawUserIDs = pii.NewUserIDHasher("")
+)
+
+// SetIPMinimizer controls how client IPs are stored on events, conversions
+// and captured requests. Fraud rules and the blocklist still see the full
+// address in memory.
+func (s *Server) SetIPMinimizer(minimizer *pii.IPMinimizer) {
+	s.ipMinimizer = minimizer
+}
+
+// SetUserIDHasher controls how user ids are stored on events and
+// conversions. Frequency caps, experiments and fraud rules see the id as
+// sent; privacy requests and per-user lookups are hashed to match.
+func (s *Server) SetUserIDHasher(hasher *pii.UserIDHasher) {
+	s.userIDs = hasher
+}
+
+// storedUserID is a user id in the form allowed at rest.
+func (s *Server) storedUserID(id string) string {
+	return s.userIDs.Apply(sanitize.String(id, sanitize.MaxUserIDLength))
+}
+
+// storedIP is the client address in the form allowed at rest. Events
+// replayed from an edge arrive minimized already.
+func (s *Server) storedIP(c *gin.Context) string {
+	if ip, ok := edge.StoredIP(c.Request.Context()); ok {
+		return ip
+	}
+	return s.ipMinimizer.Apply(c.ClientIP())
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var placementKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func (s *Server) GetPlacementDirectory() *services.PlacementDirectory {
+	return s.placements
+}
+
+// placementQuery resolves ?placement= on a serve request. It returns nil
+// when the parameter is absent and answers 404 itself for unknown keys.
+func (s *Server) placementQuery(c *gin.Context) (*models.Placement, bool) {
+	key := c.Query("placement")
+	if key == "" {
+		return nil, true
+	}
+	placement, ok := s.placements.Lookup(key)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown placement"})
+		return nil, false
+	}
+	return &placement, true
+}
+
+// lookupPlacement resolves an event's placement key to its id; an empty
+// key is no placement, an unknown one is not ok.
+func (s *Server) lookupPlacement(key string) (*uint, bool) {
+	if key == "" {
+		return nil, true
+	}
+	placement, ok := s.placements.Lookup(key)
+	if !ok {
+		return nil, false
+	}
+	return &placement.ID, true
+}
+
+func (s *Server) refreshPlacements() {
+	if err := s.placements.Refresh(); err != nil {
+		s.logger.WithError(err).Error("Failed to refresh placements")
+	}
+}
+
+// ListPlacements returns all placements, or one publisher's with
+// ?publisher=.
+func (s *Server) ListPlacements(c *gin.Context) {
+	placements, err := s.placementRepository.List(c.Query("publisher"))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list placements")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list placements"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"placements": placements})
+}
+
+// CreatePlacement registers a publisher's slot. Width and height restrict
+// it to creatives of that size; leave them 0 to take any ad.
+func (s *Server) CreatePlacement(c *gin.Context) {
+	var req models.PlacementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !placementKeyPattern.MatchString(req.Key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key may only contain letters, digits, - and _"})
+		return
+	}
+	if (req.Width == 0) != (req.Height == 0) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "width and height must be set together"})
+		return
+	}
+
+	taken, err := s.placementRepository.KeyTaken(req.Key)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to check placement key")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create placement"})
+		return
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "Placement key already exists"})
+		return
+	}
+
+	placement := models.Placement{
+		Key:       req.Key,
+		Publisher: req.Publisher,
+		Kind:      req.Kind,
+		Site:      req.Site,
+		Slot:      req.Slot,
+		Width:     req.Width,
+		Height:    req.Height,
+		Active:    true,
+	}
+	if placement.Kind == "" {
+		placement.Kind = models.PlacementWeb
+	}
+	if err := s.placementRepository.Create(&placement); err != nil {
+		s.logger.WithError(err).Error("Failed to create placement")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create placement"})
+		return
+	}
+	s.refreshPlacements()
+	c.JSON(http.StatusCreated, placement)
+}
+
+// UpdatePlacement takes a placement in or out of service. Serve requests
+// and events for an inactive placement are rejected as unknown.
+func (s *Server) UpdatePlacement(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid placement id"})
+		return
+	}
+
+	var req models.PlacementUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	placement, err := s.placementRepository.SetActive(uint(id), *req.Active)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Placement not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to update placement")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update placement"})
+		return
+	}
+	s.refreshPlacements()
+	c.JSON(http.StatusOK, placement)
+}
+
+// GetPlacementAnalytics breaks valid impressions and clicks down by
+// placement over ?timeframe=, optionally for one ?ad_id=. Like the
+// creative breakdown it reads the primary database.
+func (s *Server) GetPlacementAnalytics(c *gin.Context) {
+	var adID uint64
+	if raw := c.Query("ad_id"); raw != "" {
+		var err error
+		if adID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad_id"})
+			return
+		}
+	}
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := s.placementRepository.Stats(uint(adID), time.Now().UTC().Add(-duration))
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load placement analytics")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load placement analytics"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"timeframe":  timeframe,
+		"placements": stats,
+	})
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/pii"
+	"ad-tracking-system/internal/services"
+	"ad-tracking-system/internal/signing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var sha256Hex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type privacyExports struct {
+	secret string
+	ttl    time.Duration
+}
+
+// SetPrivacyExports configures data subject access exports: the secret
+// signing their download links and how long a link stays valid after the
+// export completes. Without a secret a random one is used, so links do not
+// survive a restart. Files are kept in the export store.
+func (s *Server) SetPrivacyExports(secret string, ttl time.Duration) {
+	if secret == "" {
+		secret, _ = newSigningSecret()
+	}
+	s.privacyExports = privacyExports{secret: secret, ttl: ttl}
+}
+
+// DeleteUserData queues erasure of every event tied to a user id.
+func (s *Server) DeleteUserData(c *gin.Context) {
+	mode, ok := erasureMode(c)
+	if !ok {
+		return
+	}
+	s.queuePrivacyRequest(c, models.SubjectUserID, s.storedUserID(c.Param("userId")), mode, "")
+}
+
+// DeleteIPData queues erasure of every event from an IP. See ipSubject for
+// how the address is given.
+func (s *Server) DeleteIPData(c *gin.Context) {
+	ipHash, ok := s.ipSubject(c)
+	if !ok {
+		return
+	}
+	mode, ok := erasureMode(c)
+	if !ok {
+		return
+	}
+	s.queuePrivacyRequest(c, models.SubjectIPHash, ipHash, mode, "")
+}
+
+// ExportUserData queues an access export of every event tied to a user id.
+func (s *Server) ExportUserData(c *gin.Context) {
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	s.queuePrivacyRequest(c, models.SubjectUserID, s.storedUserID(c.Param("userId")), models.PrivacyExport, format)
+}
+
+// ExportIPData queues an access export of every event from an IP.
+func (s *Server) ExportIPData(c *gin.Context) {
+	ipHash, ok := s.ipSubject(c)
+	if !ok {
+		return
+	}
+	format, ok := exportFormat(c)
+	if !ok {
+		return
+	}
+	s.queuePrivacyRequest(c, models.SubjectIPHash, ipHash, models.PrivacyExport, format)
+}
+
+// ipSubject is the hex SHA-256 of an address as stored, which IP privacy
+// requests match. The path holds either the address, minimized as events
+// are, or in raw storage mode its digest, so callers need not send the raw
+// IP. Truncated or hashed addresses do not match a digest of the raw IP, so
+// outside raw mode the address itself is required; in truncate mode it
+// stands for every address in its /24 (IPv6 /48).
+func (s *Server) ipSubject(c *gin.Context) (string, bool) {
+	param := c.Param("ip")
+	if ip := net.ParseIP(param); ip != nil {
+		sum := sha256.Sum256([]byte(s.ipMinimizer.Apply(ip.String())))
+		return hex.EncodeToString(sum[:]), true
+	}
+
+	digest := strings.ToLower(param)
+	if !sha256Hex.MatchString(digest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ip must be an IP address or the hex SHA-256 digest of one"})
+		return "", false
+	}
+	if mode := s.ipMinimizer.Mode(); mode != pii.IPRaw {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("IPs are stored in %s mode, so a digest of the address matches no events; send the address itself", mode)})
+		return "", false
+	}
+	return digest, true
+}
+
+func erasureMode(c *gin.Context) (string, bool) {
+	mode := c.DefaultQuery("mode", models.PrivacyErase)
+	if mode != models.PrivacyErase && mode != models.PrivacyAnonymize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be erase or anonymize"})
+		return "", false
+	}
+	return mode, true
+}
+
+func exportFormat(c *gin.Context) (string, bool) {
+	format := c.DefaultQuery("format", models.ExportFormatJSON)
+	if format != models.ExportFormatJSON && format != models.ExportFormatCSV {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return "", false
+	}
+	return format, true
+}
+
+func (s *Server) queuePrivacyRequest(c *gin.Context, subjectType, subject, mode, format string) {
+	if subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subject is required"})
+		return
+	}
+
+	request := &models.PrivacyRequest{
+		SubjectType: subjectType,
+		Subject:     subject,
+		SubjectHash: services.HashSubject(subjectType, subject),
+		Mode:        mode,
+		Format:      format,
+		Status:      models.PrivacyPending,
+		RequestedBy: "admin@" + c.ClientIP(),
+	}
+	if err := s.privacyRepository.Create(request); err != nil {
+		s.logger.WithError(err).Error("Failed to create privacy request")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create privacy request"})
+		return
+	}
+
+	if err := s.auditRepository.Record(request.RequestedBy, "privacy.requested", "privacy_request", strconv.FormatUint(uint64(request.ID), 10), gin.H{
+		"subject_type": subjectType,
+		"subject_hash": request.SubjectHash,
+		"mode":         mode,
+	}); err != nil {
+		s.logger.WithError(err).Error("Failed to write privacy audit record")
+	}
+
+	s.background(func() { s.privacy.Run(request) })
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"request":    request,
+		"status_url": fmt.Sprintf("/api/v1/privacy/requests/%d", request.ID),
+	})
+}
+
+func (s *Server) GetPrivacyRequest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request id"})
+		return
+	}
+
+	request, err := s.privacyRepository.Get(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Privacy request not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load privacy request")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load privacy request"})
+		return
+	}
+	if request.Mode == models.PrivacyExport && request.Status == models.PrivacyCompleted && request.CompletedAt != nil {
+		query := signing.Query(s.privacyExports.secret, signing.KindExport, request.ID, *request.CompletedAt, "")
+		request.DownloadURL = fmt.Sprintf("%s/api/v1/privacy/downloads/%d?%s", s.linkSigning.baseURL, request.ID, query.Encode())
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// DownloadPrivacyExport serves a completed access export. The signed link is
+// the credential so it can be handed to the data subject; it expires a fixed
+// time after the export completed, after which the file is deleted.
+func (s *Server) DownloadPrivacyExport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request id"})
+		return
+	}
+
+	verifyErr := signing.Verify(s.privacyExports.secret, signing.KindExport, uint(id), c.Request.URL.Query(), s.privacyExports.ttl)
+	if verifyErr != nil && !errors.Is(verifyErr, signing.ErrExpired) {
+		c.JSON(http.StatusForbidden, gin.H{"error": verifyErr.Error()})
+		return
+	}
+
+	request, err := s.privacyRepository.Get(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && (request.Mode != models.PrivacyExport || request.FilePath == "")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Export not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load privacy request")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load export"})
+		return
+	}
+
+	store := s.privacy.ExportStore()
+	if errors.Is(verifyErr, signing.ErrExpired) {
+		if err := store.Delete(c.Request.Context(), request.FilePath); err != nil {
+			s.logger.WithError(err).WithField("privacy_request_id", request.ID).Error("Failed to remove expired export")
+		}
+		c.JSON(http.StatusGone, gin.H{"error": verifyErr.Error()})
+		return
+	}
+
+	body, err := store.Get(c.Request.Context(), request.FilePath)
+	if errors.Is(err, archive.ErrNotFound) {
+		c.JSON(http.StatusGone, gin.H{"error": "Export file no longer available"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).WithField("privacy_request_id", request.ID).Error("Failed to open export")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load export"})
+		return
+	}
+	defer body.Close()
+
+	if err := s.auditRepository.Record("subject@"+c.ClientIP(), "privacy.downloaded", "privacy_request", strconv.FormatUint(uint64(request.ID), 10), gin.H{
+		"subject_hash": request.SubjectHash,
+	}); err != nil {
+		s.logger.WithError(err).Error("Failed to write privacy audit record")
+	}
+
+	contentType := "application/json"
+	if request.Format == models.ExportFormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	c.Header("Cache-Control", "no-store")
+	c.DataFromReader(http.StatusOK, -1, contentType, body, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", path.Base(request.FilePath)),
+	})
+}
+
+func (s *Server) GetPrivacyService() *services.PrivacyService {
+	return s.privacy
+}
@@ -0,0 +1,86 @@
+package handlers_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"testing"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/pii"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestDeleteIPDataPerStorageMode erases the impressions recorded from the
+// test client's address under each IP storage mode.
+func TestDeleteIPDataPerStorageMode(t *testing.T) {
+	const clientIP = "203.0.113.7"
+	sum := sha256.Sum256([]byte(clientIP))
+	digest := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		mode       string
+		subject    string
+		wantStatus int
+	}{
+		{pii.IPRaw, clientIP, http.StatusAccepted},
+		{pii.IPRaw, digest, http.StatusAccepted},
+		{pii.IPTruncate, clientIP, http.StatusAccepted},
+		{pii.IPTruncate, digest, http.StatusBadRequest},
+		{pii.IPHash, clientIP, http.StatusAccepted},
+		{pii.IPHash, digest, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		name := tt.mode + " by address"
+		if tt.subject == digest {
+			name = tt.mode + " by digest"
+		}
+		t.Run(name, func(t *testing.T) {
+			ts := newSQLiteServer(t)
+			minimizer, err := pii.NewIPMinimizer(tt.mode, "salt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ts.server.SetIPMinimizer(minimizer)
+			ts.router.DELETE("/api/v1/privacy/ips/:ip", ts.server.DeleteIPData)
+			ad := ts.createAd(t)
+
+			if status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", gin.H{"ad_id": ad.ID}, nil); status != http.StatusOK {
+				t.Fatalf("impression got %d, want 200", status)
+			}
+			// Another client's impression, which must be kept
+			other := models.ImpressionEvent{AdID: ad.ID, IPAddress: minimizer.Apply("198.51.100.1")}
+			if err := ts.db.Create(&other).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			var resp struct {
+				Request models.PrivacyRequest `json:"request"`
+			}
+			status := ts.do(t, http.MethodDelete, "/api/v1/privacy/ips/"+tt.subject, nil, &resp)
+			if status != tt.wantStatus {
+				t.Fatalf("got %d, want %d", status, tt.wantStatus)
+			}
+			if status != http.StatusAccepted {
+				return
+			}
+			ts.wait(t)
+
+			var request models.PrivacyRequest
+			if err := ts.db.First(&request, resp.Request.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if request.Status != models.PrivacyCompleted {
+				t.Errorf("request status = %q, want %q", request.Status, models.PrivacyCompleted)
+			}
+			var left []models.ImpressionEvent
+			if err := ts.db.Find(&left).Error; err != nil {
+				t.Fatal(err)
+			}
+			if len(left) != 1 || left[0].ID != other.ID {
+				t.Errorf("impressions left = %d, want only the other client's (id %d)", len(left), other.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/buildinfo"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds each dependency check so a hung dependency fails
+// the probe instead of outliving the kubelet's timeout.
+const readinessTimeout = 2 * time.Second
+
+// AddReadinessCheck registers a dependency that must be reachable for
+// /readyz to report ready.
+func (s *Server) AddReadinessCheck(name string, check services.HealthCheck) {
+	s.readinessMu.Lock()
+	defer s.readinessMu.Unlock()
+	s.readiness[name] = check
+}
+
+// Livez only reports that the process is serving requests. It does not look
+// at dependencies, so an outage never gets healthy pods restarted.
+func (s *Server) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+type dependencyStatus struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Readyz runs every readiness check concurrently and returns 503 unless all
+// pass, with the status and latency of each dependency.
+func (s *Server) Readyz(c *gin.Context) {
+	if s.draining.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+		return
+	}
+
+	s.readinessMu.Lock()
+	checks := make(map[string]services.HealthCheck, len(s.readiness))
+	for name, check := range s.readiness {
+		checks[name] = check
+	}
+	s.readinessMu.Unlock()
+
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		ready   = true
+		results = make(map[string]dependencyStatus, len(checks))
+	)
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check services.HealthCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(ctx)
+			result := dependencyStatus{Status: "up", LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+			if err != nil {
+				result.Status, result.Error = "down", err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = result
+			ready = ready && err == nil
+		}(name, check)
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not_ready", http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{"status": status, "checks": results})
+}
+
+// Version reports the build of the running binary.
+func (s *Server) Version(c *gin.Context) {
+	c.JSON(http.StatusOK, buildinfo.Get())
+}
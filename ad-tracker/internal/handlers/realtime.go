@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+	// leaderboardHistory is how many days back a leaderboard can be read
+	leaderboardHistory = 7
+)
+
+// SetRealtimeCounters enables the realtime analytics endpoint.
+func (s *Server) SetRealtimeCounters(counters *services.RealtimeCounters) {
+	s.realtime = counters
+}
+
+// GetRealtimeAnalytics returns the clicks and impressions of one ad
+// (?ad_id=) or campaign (?campaign_id=) over the last minute, five minutes
+// and hour, read from Redis rather than the database.
+func (s *Server) GetRealtimeAnalytics(c *gin.Context) {
+	if s.realtime == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Realtime counters not configured"})
+		return
+	}
+	adIDStr, campaignIDStr := c.Query("ad_id"), c.Query("campaign_id")
+	if (adIDStr == "") == (campaignIDStr == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exactly one of ad_id and campaign_id is required"})
+		return
+	}
+
+	var counters models.RealtimeCounters
+	var err error
+	if adIDStr != "" {
+		adID, parseErr := strconv.ParseUint(adIDStr, 10, 32)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad_id"})
+			return
+		}
+		counters, err = s.realtime.AdCounts(c.Request.Context(), uint(adID))
+	} else {
+		campaignID, parseErr := strconv.ParseUint(campaignIDStr, 10, 32)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid campaign_id"})
+			return
+		}
+		counters, err = s.realtime.CampaignCounts(c.Request.Context(), uint(campaignID))
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to read realtime counters")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read realtime counters"})
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, counters)
+}
+
+// GetLeaderboard returns the top ads of a UTC day (?date=YYYY-MM-DD,
+// default today, at most a week back) by clicks or CTR (?by=), for a
+// trending ads widget. Ads are ranked as events are consumed, like the
+// realtime counters.
+func (s *Server) GetLeaderboard(c *gin.Context) {
+	if s.realtime == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Realtime counters not configured"})
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	day := today
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil || parsed.After(today) || today.Sub(parsed) >= leaderboardHistory*24*time.Hour {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("date must be a YYYY-MM-DD day within the last %d days", leaderboardHistory)})
+			return
+		}
+		day = parsed
+	}
+	order := c.DefaultQuery("by", "clicks")
+	if !slices.Contains(services.LeaderboardOrders, order) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "by must be one of " + strings.Join(services.LeaderboardOrders, ", ")})
+		return
+	}
+	limit := defaultLeaderboardLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxLeaderboardLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be between 1 and %d", maxLeaderboardLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	entries, err := s.realtime.Leaderboard(c.Request.Context(), day, order, limit)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to read leaderboard")
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read leaderboard"})
+		return
+	}
+
+	board := models.Leaderboard{Date: day.Format(time.DateOnly), By: order, Ads: make([]models.LeaderboardEntry, 0, len(entries))}
+	for _, entry := range entries {
+		// Deleted ads and honeypots are left off
+		ad, err := s.ads.Get(c.Request.Context(), entry.AdID)
+		if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && ad.Honeypot) {
+			continue
+		}
+		if err != nil {
+			s.logger.WithError(err).WithField("ad_id", entry.AdID).Error("Failed to load leaderboard ad")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read leaderboard"})
+			return
+		}
+		entry.Title = ad.Title
+		entry.Rank = len(board.Ads) + 1
+		board.Ads = append(board.Ads, entry)
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, board)
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetReplicator enables the replication admin endpoints.
+func (s *Server) SetReplicator(replicator *services.Replicator) {
+	s.replicator = replicator
+}
+
+func (s *Server) GetReplication(c *gin.Context) {
+	if s.replicator == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Replication not configured"})
+		return
+	}
+	c.JSON(http.StatusOK, s.replicator.Status())
+}
+
+// PromoteRegion makes this region primary. See services.Replicator for the
+// full failover procedure.
+func (s *Server) PromoteRegion(c *gin.Context) {
+	s.setRegionRole(c, models.RolePrimary)
+}
+
+func (s *Server) DemoteRegion(c *gin.Context) {
+	s.setRegionRole(c, models.RoleStandby)
+}
+
+func (s *Server) setRegionRole(c *gin.Context, role string) {
+	if s.replicator == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Replication not configured"})
+		return
+	}
+
+	if _, err := s.replicator.SetRole(role, "admin@"+c.ClientIP()); err != nil {
+		s.logger.WithError(err).Error("Failed to change replication role")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change replication role"})
+		return
+	}
+	c.JSON(http.StatusOK, s.replicator.Status())
+}
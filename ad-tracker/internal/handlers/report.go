@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (s *Server) GetReportScheduler() *services.ReportScheduler {
+	return s.reports
+}
+
+func (s *Server) ListReportSchedules(c *gin.Context) {
+	account, ok := s.accountFromParam(c)
+	if !ok {
+		return
+	}
+
+	schedules, err := s.reportRepository.ListSchedules(account.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list report schedules")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list report schedules"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"schedules": schedules})
+}
+
+// CreateReportSchedule validates the cron expression, timezone and
+// destination up front so a bad schedule fails here rather than at run time.
+func (s *Server) CreateReportSchedule(c *gin.Context) {
+	account, ok := s.accountFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReportScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule := models.ReportSchedule{
+		AccountID:   account.ID,
+		Name:        req.Name,
+		Cron:        req.Cron,
+		Timezone:    req.Timezone,
+		Timeframe:   req.Timeframe,
+		Format:      req.Format,
+		ValidOnly:   req.ValidOnly,
+		Destination: req.Destination,
+		Active:      true,
+	}
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if schedule.Timeframe == "" {
+		schedule.Timeframe = "24h"
+	}
+	if schedule.Format == "" {
+		schedule.Format = "csv"
+	}
+	if req.Active != nil {
+		schedule.Active = *req.Active
+	}
+
+	next, err := services.NextRun(&schedule, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	schedule.NextRunAt = &next
+	if err := s.reports.ValidateDestination(schedule.Destination); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.reportRepository.CreateSchedule(&schedule); err != nil {
+		s.logger.WithError(err).Error("Failed to create report schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report schedule"})
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+func (s *Server) DeleteReportSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule id"})
+		return
+	}
+
+	err = s.reportRepository.DeleteSchedule(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report schedule not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to delete report schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete report schedule"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListReportRuns returns the most recent runs of a schedule.
+func (s *Server) ListReportRuns(c *gin.Context) {
+	schedule, ok := s.reportScheduleFromParam(c)
+	if !ok {
+		return
+	}
+
+	runs, err := s.reportRepository.ListRuns(schedule.ID, 100)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list report runs")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list report runs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// RunReportSchedule delivers a report immediately, e.g. to test a new
+// destination.
+func (s *Server) RunReportSchedule(c *gin.Context) {
+	schedule, ok := s.reportScheduleFromParam(c)
+	if !ok {
+		return
+	}
+
+	run, err := s.reports.RunNow(schedule)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to start report run")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start report run"})
+		return
+	}
+	c.JSON(http.StatusAccepted, run)
+}
+
+func (s *Server) reportScheduleFromParam(c *gin.Context) (*models.ReportSchedule, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule id"})
+		return nil, false
+	}
+
+	schedule, err := s.reportRepository.GetSchedule(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report schedule not found"})
+		return nil, false
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load report schedule")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load report schedule"})
+		return nil, false
+	}
+	return schedule, true
+}
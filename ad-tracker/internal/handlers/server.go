@@ -1,48 +1,316 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/cache"
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/fraud"
+	"ad-tracking-system/internal/kafka"
+	applog "ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/pii"
 	repositories "ad-tracking-system/internal/repository"
 	"ad-tracking-system/internal/services"
 
-	"github.com/segmentio/kafka-go"
+	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type Server struct {
-	db                  *gorm.DB
-	logger              *logrus.Logger
-	clickQueue          *services.ClickQueue
-	analyticsRepository *repositories.AnalyticsRepository
-	KafkaWriter         *kafka.Writer
+	db                   *gorm.DB
+	logger               *logrus.Logger
+	logSampler           *applog.Sampler
+	clickQueue           services.ClickEnqueuer
+	analyticsRepository  repositories.AnalyticsReader
+	statusRepository     *repositories.StatusRepository
+	campaignRepository   *repositories.CampaignRepository
+	accountRepository    *repositories.AccountRepository
+	linkSigning          linkSigning
+	exportRepository     *repositories.ExportRepository
+	exports              *services.ExportService
+	conversionRepository *repositories.ConversionRepository
+	attributor           *services.Attributor
+	identities           *services.IdentityGraph
+	status               *services.StatusService
+	eventStore           events.EventStore
+	eventBus             events.EventBus
+	replicator           *services.Replicator
+	realtime             *services.RealtimeCounters
+	captureRepository    *repositories.CaptureRepository
+	capture              *services.CaptureManager
+	fraud                *fraud.Scorer
+	botList              *fraud.BotList
+	dropBots             bool
+	blocklistRepository  *repositories.BlocklistRepository
+	blocklist            *services.Blocklist
+	honeypot             honeypotPolicy
+	privacyRepository    *repositories.PrivacyRepository
+	privacy              *services.PrivacyService
+	privacyExports       privacyExports
+	auditRepository      *repositories.AuditRepository
+	ipMinimizer          *pii.IPMinimizer
+	userIDs              *pii.UserIDHasher
+	consent              *services.ConsentPolicy
+	reportRepository     *repositories.ReportRepository
+	reports              *services.ReportScheduler
+	alertRepository      *repositories.AlertRepository
+	alerts               *services.AlertEvaluator
+	webhookRepository    *repositories.WebhookRepository
+	webhooks             *services.WebhookDispatcher
+	integrationRepo      *repositories.IntegrationRepository
+	forwarder            *services.Forwarder
+	adRepository         repositories.AdStore
+	ads                  *services.AdCache
+	analyticsCache       *services.AnalyticsCache
+	adsMaxAge            time.Duration
+	dbMonitor            *database.Monitor
+	draining             atomic.Bool
+	readinessMu          sync.Mutex
+	tasks                sync.WaitGroup
+	debugAllowed         func(c *gin.Context) bool
+	maxTimeframe         time.Duration
+	timestamps           models.TimestampPolicy
+	budgets              *services.BudgetTracker
+	countryHeader        string
+	experimentRepository *repositories.ExperimentRepository
+	experiments          *services.ExperimentAssigner
+	creativeRepository   *repositories.CreativeRepository
+	placementRepository  *repositories.PlacementRepository
+	adGroupRepository    *repositories.AdGroupRepository
+	anomalyRepository    *repositories.AnomalyRepository
+	placements           *services.PlacementDirectory
+	catalog              *services.CatalogImporter
+	sessions             *services.SessionTracker
+	userRepository       *repositories.UserRepository
+	offsetAdmin          *kafka.OffsetAdmin
+	consumerGroups       map[string]string
+	readiness            map[string]services.HealthCheck
 }
 
-func NewServer(db *gorm.DB, logger *logrus.Logger, kafkaWriter *kafka.Writer) *Server {
-	clickQueue := services.NewClickQueue(db, logger, 10000)
-	analyticsRepo := repositories.NewAnalyticsRepository(db, logger)
+// NewServer wires the HTTP handlers. The event store and bus are injected so
+// tests can swap in the in-memory implementations from the fakes package;
+// SetAdStore, SetAnalyticsReader and SetClickQueue do the same for ads,
+// analytics and the click queue.
+func NewServer(db *gorm.DB, logger *logrus.Logger, store events.EventStore, bus events.EventBus) *Server {
+	clickQueue := services.NewClickQueue(store, logger, 10000)
+	analyticsRepo := repositories.NewAnalyticsRepository(db, store, logger)
+	statusRepo := repositories.NewStatusRepository(db)
+	exportRepo := repositories.NewExportRepository(db)
+	conversionRepo := repositories.NewConversionRepository(db)
+	captureRepo := repositories.NewCaptureRepository(db)
+	blocklistRepo := repositories.NewBlocklistRepository(db)
+	privacyRepo := repositories.NewPrivacyRepository(db)
+	auditRepo := repositories.NewAuditRepository(db)
+	accountRepo := repositories.NewAccountRepository(db)
+	campaignRepo := repositories.NewCampaignRepository(db)
+	reportRepo := repositories.NewReportRepository(db)
+	alertRepo := repositories.NewAlertRepository(db)
+	webhookRepo := repositories.NewWebhookRepository(db)
+	integrationRepo := repositories.NewIntegrationRepository(db)
+	adRepo := repositories.NewAdRepository(db)
+	experimentRepo := repositories.NewExperimentRepository(db)
+	placementRepo := repositories.NewPlacementRepository(db)
 
 	// Start background flusher for the queue
 	// clickQueue.StartBackgroundFlusher(30 * time.Second)
 
-	return &Server{
-		db:                  db,
-		logger:              logger,
-		clickQueue:          clickQueue,
-		analyticsRepository: analyticsRepo,
-		KafkaWriter:         kafkaWriter,
+	s := &Server{
+		db:                   db,
+		logger:               logger,
+		clickQueue:           clickQueue,
+		analyticsRepository:  analyticsRepo,
+		statusRepository:     statusRepo,
+		campaignRepository:   campaignRepo,
+		accountRepository:    accountRepo,
+		linkSigning:          linkSigning{ttl: defaultLinkTTL},
+		exportRepository:     exportRepo,
+		exports:              services.NewExportService(exportRepo, archive.NewDirStore("exports"), logger),
+		conversionRepository: conversionRepo,
+		attributor:           services.NewAttributor(conversionRepo, models.DefaultAttributionWindows),
+		timestamps:           models.DefaultTimestampPolicy,
+		captureRepository:    captureRepo,
+		capture:              services.NewCaptureManager(captureRepo, 10*time.Minute, 1000, logger, services.NewClickRateDetector(100, 5)),
+		status:               services.NewStatusService(statusRepo, logger),
+		fraud:                fraud.DefaultScorer(),
+		botList:              fraud.DefaultBotList(),
+		dropBots:             true,
+		blocklistRepository:  blocklistRepo,
+		blocklist:            services.NewBlocklist(blocklistRepo, logger),
+		honeypot:             honeypotPolicy{blockFor: 24 * time.Hour, lookback: 24 * time.Hour},
+		privacyRepository:    privacyRepo,
+		privacy:              services.NewPrivacyService(privacyRepo, auditRepo, logger),
+		auditRepository:      auditRepo,
+		ipMinimizer:          rawIPs,
+		userIDs:              rawUserIDs,
+		consent:              services.NewConsentPolicy(accountRepo, 0, logger),
+		reportRepository:     reportRepo,
+		reports:              services.NewReportScheduler(reportRepo, analyticsRepo, campaignRepo, logger),
+		alertRepository:      alertRepo,
+		alerts:               services.NewAlertEvaluator(alertRepo, logger),
+		webhookRepository:    webhookRepo,
+		webhooks:             services.NewWebhookDispatcher(webhookRepo, logger),
+		integrationRepo:      integrationRepo,
+		forwarder:            services.NewForwarder(integrationRepo, logger),
+		adRepository:         adRepo,
+		ads:                  services.NewAdCache(adRepo, cache.NewMemory(), defaultAdCacheTTL, logger),
+		analyticsCache:       services.NewAnalyticsCache(cache.NewMemory(), defaultAnalyticsCacheTTL, defaultAnalyticsCacheStale, logger),
+		adsMaxAge:            defaultAdsMaxAge,
+		maxTimeframe:         defaultMaxTimeframe,
+		countryHeader:        defaultCountryHeader,
+		experimentRepository: experimentRepo,
+		experiments:          services.NewExperimentAssigner(experimentRepo, logger),
+		creativeRepository:   repositories.NewCreativeRepository(db),
+		placementRepository:  placementRepo,
+		placements:           services.NewPlacementDirectory(placementRepo, logger),
+		adGroupRepository:    repositories.NewAdGroupRepository(db),
+		anomalyRepository:    repositories.NewAnomalyRepository(db),
+		catalog:              services.NewCatalogImporter(repositories.NewCatalogRepository(db)),
+		sessions:             services.NewSessionTracker(cache.NewMemory(), defaultSessionTimeout, logger),
+		userRepository:       repositories.NewUserRepository(db),
+		dbMonitor:            database.NewMonitor(db, logger),
+		eventStore:           store,
+		eventBus:             bus,
+	}
+	s.budgets = services.NewBudgetTracker(campaignRepo, auditRepo, cache.NewMemory(), s.invalidateAds, logger)
+	s.status.AddCheck("database", s.checkDatabase)
+	s.status.AddCheck("click_queue", s.checkClickQueue)
+	s.readiness = map[string]services.HealthCheck{
+		"database":    s.dbMonitor.Check,
+		"click_queue": s.checkClickQueue,
+	}
+
+	return s
+}
+
+// SetAdStore replaces the ad repository. Call it before SetAdCache, which
+// caches lookups from whichever store is set.
+func (s *Server) SetAdStore(store repositories.AdStore) {
+	s.adRepository = store
+	s.ads = services.NewAdCache(store, cache.NewMemory(), defaultAdCacheTTL, s.logger)
+}
+
+// SetAnalyticsReader replaces the repository analytics endpoints and
+// scheduled reports read from. It replaces the report scheduler too, so
+// call it before configuring that. The settings below only apply to the
+// database-backed repository.
+func (s *Server) SetAnalyticsReader(reader repositories.AnalyticsReader) {
+	s.analyticsRepository = reader
+	s.reports = services.NewReportScheduler(s.reportRepository, reader, s.campaignRepository, s.logger)
+}
+
+// analyticsDB is the database-backed analytics repository, nil when
+// another reader was set.
+func (s *Server) analyticsDB() *repositories.AnalyticsRepository {
+	repo, _ := s.analyticsRepository.(*repositories.AnalyticsRepository)
+	return repo
+}
+
+// SetLogSampler samples the log lines written per event or per analytics
+// request, here and in the analytics repository; nil logs every line.
+func (s *Server) SetLogSampler(sampler *applog.Sampler) {
+	s.logSampler = sampler
+	if repo := s.analyticsDB(); repo != nil {
+		repo.SetLogSampler(sampler)
+	}
+}
+
+// SetViewabilityThreshold configures the viewable impression rule used in analytics.
+func (s *Server) SetViewabilityThreshold(threshold models.ViewabilityThreshold) {
+	if repo := s.analyticsDB(); repo != nil {
+		repo.SetViewabilityThreshold(threshold)
+	}
+}
+
+// SetAnalyticsStore answers analytics queries from store instead of the
+// primary event store. Ingestion is unaffected.
+func (s *Server) SetAnalyticsStore(store events.EventStore) {
+	if repo := s.analyticsDB(); repo != nil {
+		repo.SetStore(store)
 	}
 }
 
-func (s *Server) GetClickQueue() *services.ClickQueue {
+// SetRollups reads analytics windows of at least minWindow from the rollup
+// tables.
+func (s *Server) SetRollups(rollups *repositories.RollupRepository, minWindow time.Duration) {
+	if repo := s.analyticsDB(); repo != nil {
+		repo.SetRollups(rollups, minWindow)
+	}
+}
+
+// SetAttributionWindows configures click-through and view-through lookback.
+func (s *Server) SetAttributionWindows(windows models.AttributionWindows) {
+	s.attributor = services.NewAttributor(s.conversionRepository, windows)
+	s.attributor.SetIdentities(s.identities)
+}
+
+// SetExportStore changes where cohort and data subject exports are kept.
+// Downloads are served from it by whichever replica gets the request, so
+// with more than one replica it must be shared object storage.
+func (s *Server) SetExportStore(store archive.ObjectStore) {
+	s.exports = services.NewExportService(s.exportRepository, store, s.logger)
+	s.privacy.SetExportStore(store)
+}
+
+// SetFraudScorer replaces the rules applied to incoming clicks and impressions.
+func (s *Server) SetFraudScorer(scorer *fraud.Scorer) {
+	s.fraud = scorer
+}
+
+// GetDatabaseMonitor exposes the database ping loop so main can start it.
+func (s *Server) GetDatabaseMonitor() *database.Monitor {
+	return s.dbMonitor
+}
+
+func (s *Server) GetClickQueue() services.ClickEnqueuer {
 	return s.clickQueue
 }
 
+// SetAnalyticsDebug decides which callers may add debug=true to analytics
+// requests; without it nobody can.
+func (s *Server) SetAnalyticsDebug(allowed func(c *gin.Context) bool) {
+	s.debugAllowed = allowed
+}
+
+// SetClickQueue replaces the default queue, e.g. with one sized from
+// configuration. Call before the processor is started.
+func (s *Server) SetClickQueue(queue services.ClickEnqueuer) {
+	s.clickQueue = queue
+}
+
 // Shutdown gracefully shuts down the server
-func (s *Server) Shutdown() {
-	s.logger.Info("Shutting down server...")
+// Shutdown marks the server as draining and waits for the work handlers
+// started in the background (Kafka publishes, exports, privacy requests)
+// to finish. Call it after the HTTP server has stopped accepting requests
+// and before closing the Kafka writer and database they use.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
 
-	// Stop the background flusher and do final flush
-	// s.clickQueue.Stop()
+	done := make(chan struct{})
+	go func() {
+		s.tasks.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background tasks still running: %w", ctx.Err())
+	}
+}
 
-	s.logger.Info("Server shutdown complete")
+// background runs task in a goroutine that Shutdown waits for.
+func (s *Server) background(task func()) {
+	s.tasks.Add(1)
+	go func() {
+		defer s.tasks.Done()
+		task()
+	}()
 }
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"ad-tracking-system/internal/cache"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSessionTimeout = 30 * time.Minute
+
+	// sessionCookie carries the session id between tracking calls from a
+	// browser; apps send session_id instead.
+	sessionCookie = "adt_sid"
+)
+
+// SetSessionStore keeps session activity in store, typically Redis so
+// that every replica continues the same sessions, and ends sessions after
+// timeout without events.
+func (s *Server) SetSessionStore(store cache.Cache, timeout time.Duration) {
+	s.sessions = services.NewSessionTracker(store, timeout, s.logger)
+}
+
+// session resolves the session of a tracking call from the id the client
+// sent, else the session cookie, and refreshes the cookie with the id the
+// event is recorded under. Tracking calls are cross-site, so over TLS the
+// cookie is SameSite=None; browsers only accept that when it is Secure.
+func (s *Server) session(c *gin.Context, sent string) string {
+	if sent == "" {
+		sent, _ = c.Cookie(sessionCookie)
+	}
+	id := s.sessions.Touch(c.Request.Context(), sent, time.Now())
+	if id != "" {
+		sameSite := http.SameSiteLaxMode
+		if c.Request.TLS != nil {
+			sameSite = http.SameSiteNoneMode
+		}
+		http.SetCookie(c.Writer, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    id,
+			Path:     "/",
+			MaxAge:   int(s.sessions.Timeout().Seconds()),
+			HttpOnly: true,
+			Secure:   c.Request.TLS != nil,
+			SameSite: sameSite,
+		})
+	}
+	return id
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/fraud"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/signing"
+	"ad-tracking-system/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultLinkTTL = 30 * 24 * time.Hour
+
+// transparentGIF is the 1x1 pixel served by the impression beacon.
+var transparentGIF, _ = base64.StdEncoding.DecodeString("R0lGODlhAQABAIAAAAAAAP///yH5BAEAAAAALAAAAAABAAEAAAIBRAA7")
+
+type linkSigning struct {
+	fallbackSecret string
+	ttl            time.Duration
+	baseURL        string
+}
+
+// SetLinkSigning configures tracking link verification. fallbackSecret signs
+// links for ads whose campaign has no account; when it is empty those links
+// are accepted unsigned. baseURL prefixes generated links.
+func (s *Server) SetLinkSigning(fallbackSecret string, ttl time.Duration, baseURL string) {
+	s.linkSigning = linkSigning{
+		fallbackSecret: fallbackSecret,
+		ttl:            ttl,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+	}
+}
+
+func (s *Server) signingSecret(adID uint) (string, error) {
+	secret, err := s.accountRepository.SigningSecretForAd(adID)
+	if err != nil {
+		return "", err
+	}
+	if secret == "" {
+		secret = s.linkSigning.fallbackSecret
+	}
+	return secret, nil
+}
+
+// verifyLink rejects tampered, expired or unsigned tracking links. On failure
+// it writes the error response and returns false.
+func (s *Server) verifyLink(c *gin.Context, kind string, adID uint) bool {
+	return s.verifySigned(c, kind, adID, c.Request.URL.Query())
+}
+
+// verifySigned checks the ts/sig/user_id in params against the ad's signing
+// secret, like verifyLink.
+func (s *Server) verifySigned(c *gin.Context, kind string, adID uint, params url.Values) bool {
+	if problem := s.signatureProblem(c, kind, adID, params); problem != nil {
+		problem.write(c)
+		return false
+	}
+	return true
+}
+
+// verifyEvent checks the link signature a JSON click or impression carries,
+// in its body or else in the query string, against the user id it names.
+func (s *Server) verifyEvent(c *gin.Context, kind string, adID uint, sig models.LinkSignature, userID string) *eventProblem {
+	return s.signatureProblem(c, kind, adID, sig.OrQuery(c.Request.URL.Query()).Params(userID))
+}
+
+// signatureProblem is verifySigned without the response, for batch entries.
+func (s *Server) signatureProblem(c *gin.Context, kind string, adID uint, params url.Values) *eventProblem {
+	secret, err := s.signingSecret(adID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to resolve signing secret")
+		return &eventProblem{status: http.StatusInternalServerError, message: "Failed to verify link"}
+	}
+	if secret == "" {
+		return nil
+	}
+
+	if err := signing.Verify(secret, kind, adID, params, s.linkSigning.ttl); err != nil {
+		reason := "invalid"
+		switch {
+		case errors.Is(err, signing.ErrMissing):
+			reason = "missing"
+		case errors.Is(err, signing.ErrExpired):
+			reason = "expired"
+		case errors.Is(err, signing.ErrFromFuture):
+			reason = "future"
+		}
+		metrics.SignedLinkRejections.WithLabelValues(kind, reason).Inc()
+		s.logSampler.Log(s.logger.WithFields(logrus.Fields{
+			"ad_id": adID,
+			"ip":    c.ClientIP(),
+		}).WithError(err), logrus.WarnLevel, "Rejected tracking link")
+		return &eventProblem{status: http.StatusForbidden, message: err.Error()}
+	}
+	return nil
+}
+
+// TrackingPixel records an impression from a signed <img> beacon and serves a
+// transparent GIF.
+func (s *Server) TrackingPixel(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		s.status.ObserveIngest(time.Since(start))
+	}()
+
+	adID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad id"})
+		return
+	}
+	if s.rejectBlocked(c, fraud.EventImpression, uint(adID)) {
+		return
+	}
+	if !s.verifyLink(c, signing.KindPixel, uint(adID)) {
+		return
+	}
+
+	ad, err := s.ads.Get(c.Request.Context(), uint(adID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad not found"})
+		return
+	}
+	creativeID, ok := creativeQuery(c)
+	if !ok {
+		return
+	}
+
+	if s.dropBot(c, fraud.EventImpression) {
+		c.Header("Cache-Control", "no-store")
+		c.Data(http.StatusOK, "image/gif", transparentGIF)
+		return
+	}
+
+	// The beacon takes the JSON endpoint's path, so it is scored, capped by
+	// consent and charged to the campaign budget the same way
+	req := models.ImpressionRequest{
+		AdID:          ad.ID,
+		UserID:        c.Query("user_id"),
+		CreativeID:    creativeID,
+		Placement:     c.Query("placement"),
+		SessionID:     c.Query("session_id"),
+		ConsentParams: consentQuery(c),
+	}
+	// Query parameters skip binding, so hold them to the same rules
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, validation.Response(err))
+		return
+	}
+	impression, problem := s.prepareImpression(c, ad, req)
+	if problem != nil {
+		problem.write(c)
+		return
+	}
+
+	if err := s.eventStore.SaveImpressions(c.Request.Context(), []models.ImpressionEvent{impression}); err != nil {
+		s.logger.WithError(err).Error("Failed to save impression event")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record impression"})
+		return
+	}
+	s.impressionRecorded(c, ad, req, impression)
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/gif", transparentGIF)
+}
+
+// GetAdLinks generates signed redirect and pixel URLs for an ad, optionally
+// bound to a user_id.
+func (s *Server) GetAdLinks(c *gin.Context) {
+	adID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad id"})
+		return
+	}
+
+	var ad models.Ad
+	if err := s.db.First(&ad, uint(adID)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad not found"})
+		return
+	}
+
+	secret, err := s.signingSecret(ad.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to resolve signing secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign links"})
+		return
+	}
+	if secret == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "No signing secret configured for this ad"})
+		return
+	}
+
+	c.JSON(http.StatusOK, s.trackingLinks(ad, secret, c.Query("user_id")))
+}
+
+type trackingLinks struct {
+	AdID        uint      `json:"ad_id"`
+	RedirectURL string    `json:"redirect_url"`
+	PixelURL    string    `json:"pixel_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func (s *Server) trackingLinks(ad models.Ad, secret, userID string) trackingLinks {
+	now := time.Now()
+	return trackingLinks{
+		AdID:        ad.ID,
+		RedirectURL: s.signedURL(ad.ID, "redirect", signing.Query(secret, signing.KindClick, ad.ID, now, userID)),
+		PixelURL:    s.signedURL(ad.ID, "pixel", signing.Query(secret, signing.KindPixel, ad.ID, now, userID)),
+		ExpiresAt:   now.Add(s.linkSigning.ttl).UTC(),
+	}
+}
+
+func (s *Server) signedURL(adID uint, endpoint string, query url.Values) string {
+	link := s.linkSigning.baseURL + "/api/v1/ads/" + strconv.FormatUint(uint64(adID), 10) + "/" + endpoint
+	if len(query) == 0 {
+		return link
+	}
+	return link + "?" + query.Encode()
+}
@@ -0,0 +1,178 @@
+package handlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testSecret = "account-signing-secret"
+
+// createSignedAd inserts an active ad in a campaign of an account signing
+// with testSecret.
+func (ts *testServer) createSignedAd(t *testing.T) models.Ad {
+	t.Helper()
+	account := models.Account{Name: "Signed", SigningSecret: testSecret, Active: true}
+	if err := ts.db.Create(&account).Error; err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	campaign := models.Campaign{AccountID: &account.ID, Name: "Signed", Active: true}
+	if err := ts.db.Create(&campaign).Error; err != nil {
+		t.Fatalf("create campaign: %v", err)
+	}
+	ad := models.Ad{CampaignID: &campaign.ID, ImageURL: "https://cdn.example.com/ad.png", TargetURL: "https://example.com/", Title: "Signed ad", Active: true}
+	if err := ts.db.Create(&ad).Error; err != nil {
+		t.Fatalf("create ad: %v", err)
+	}
+	return ad
+}
+
+// signed returns an event body for ad carrying the ts/sig of its tracking
+// link of kind for userID, and that user id unless body names another.
+func signed(ad models.Ad, kind, userID string, body gin.H) gin.H {
+	query := signing.Query(testSecret, kind, ad.ID, time.Now(), userID)
+	body["ad_id"], body["ts"], body["sig"] = ad.ID, query.Get("ts"), query.Get("sig")
+	if _, ok := body["user_id"]; !ok && userID != "" {
+		body["user_id"] = userID
+	}
+	return body
+}
+
+func TestPostClickRequiresSignature(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createSignedAd(t)
+
+	tests := []struct {
+		name   string
+		target string
+		body   gin.H
+		want   int
+	}{
+		{"unsigned", "/api/v1/ads/click", gin.H{"ad_id": ad.ID, "user_id": "user-1"}, http.StatusForbidden},
+		{"signed in body", "/api/v1/ads/click", signed(ad, signing.KindClick, "user-1", gin.H{}), http.StatusOK},
+		{"other user", "/api/v1/ads/click", signed(ad, signing.KindClick, "user-1", gin.H{"user_id": "user-2"}), http.StatusForbidden},
+		{"impression signature", "/api/v1/ads/click", signed(ad, signing.KindPixel, "", gin.H{}), http.StatusForbidden},
+		{
+			"signed in query",
+			"/api/v1/ads/click?" + signing.Query(testSecret, signing.KindClick, ad.ID, time.Now(), "user-1").Encode(),
+			gin.H{"ad_id": ad.ID, "user_id": "user-1"},
+			http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do(t, http.MethodPost, tt.target, tt.body, nil); status != tt.want {
+				t.Errorf("got %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostImpressionRequiresSignature(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createSignedAd(t)
+
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", gin.H{"ad_id": ad.ID}, nil); status != http.StatusForbidden {
+		t.Fatalf("unsigned impression got %d, want 403", status)
+	}
+	expired := signing.Query(testSecret, signing.KindPixel, ad.ID, time.Now().Add(-31*24*time.Hour), "")
+	body := gin.H{"ad_id": ad.ID, "ts": expired.Get("ts"), "sig": expired.Get("sig")}
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", body, nil); status != http.StatusForbidden {
+		t.Fatalf("expired signature got %d, want 403", status)
+	}
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", signed(ad, signing.KindPixel, "", gin.H{}), nil); status != http.StatusOK {
+		t.Fatalf("signed impression got %d, want 200", status)
+	}
+	count, err := ts.store.CountImpressions(context.Background(), events.Query{AdID: ad.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("stored %d impressions, want 1", count)
+	}
+}
+
+func TestEventBatchChecksEachSignature(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createSignedAd(t)
+
+	var resp struct {
+		Recorded int                       `json:"recorded"`
+		Results  []models.BatchEventResult `json:"results"`
+	}
+	status := ts.do(t, http.MethodPost, "/api/v1/events/batch", gin.H{"events": []gin.H{
+		signed(ad, signing.KindClick, "user-1", gin.H{"type": "click"}),
+		{"type": "click", "ad_id": ad.ID},
+		signed(ad, signing.KindPixel, "", gin.H{"type": "impression"}),
+		// A batch has no query string to fall back to
+		{"type": "impression", "ad_id": ad.ID},
+	}}, &resp)
+	if status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	want := []string{models.BatchEventRecorded, models.BatchEventRejected, models.BatchEventRecorded, models.BatchEventRejected}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(resp.Results), len(want))
+	}
+	for i, result := range resp.Results {
+		if result.Status != want[i] {
+			t.Errorf("entry %d is %s (%s), want %s", i, result.Status, result.Error, want[i])
+		}
+	}
+}
+
+func TestUnsignedAdsAcceptUnsignedEvents(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createAd(t)
+
+	query := url.Values{"ts": {"1"}, "sig": {"ignored"}}
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/click?"+query.Encode(), gin.H{"ad_id": ad.ID}, nil); status != http.StatusOK {
+		t.Fatalf("got %d, want 200 for an ad without a signing secret", status)
+	}
+}
+
+func TestTrackingPixelChargesBudget(t *testing.T) {
+	ts := newTestServer(t)
+	ad := ts.createSignedAd(t)
+	var campaign models.Campaign
+	if err := ts.db.First(&campaign, *ad.CampaignID).Error; err != nil {
+		t.Fatal(err)
+	}
+	// One unit per impression against a lifetime budget of two
+	campaign.CostPerMille, campaign.LifetimeBudget = 1000, 2
+	if err := ts.db.Save(&campaign).Error; err != nil {
+		t.Fatal(err)
+	}
+	budgets := ts.server.GetBudgetTracker()
+	budgets.Track(campaign)
+
+	target := fmt.Sprintf("/api/v1/ads/%d/pixel?%s", ad.ID, signing.Query(testSecret, signing.KindPixel, ad.ID, time.Now(), "").Encode())
+	header := http.Header{"User-Agent": {"Mozilla/5.0 (X11; Linux x86_64)"}}
+	for i := 0; i < 2; i++ {
+		if w := ts.get(target, header); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/gif" {
+			t.Fatalf("pixel %d: got %d %q, want 200 image/gif", i, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+	if err := budgets.Flush(); err != nil {
+		t.Fatalf("flush spend: %v", err)
+	}
+
+	if err := ts.db.First(&campaign, campaign.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if campaign.LifetimeSpend != 2 {
+		t.Errorf("lifetime spend = %v, want 2 for two pixel impressions", campaign.LifetimeSpend)
+	}
+	if campaign.BudgetPausedAt == nil {
+		t.Error("campaign not paused after the pixel exhausted its lifetime budget")
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adSnippetHTML is the script-free form of an ad: a linked image plus a
+// 1x1 pixel, for email bodies and <noscript> blocks. It is a text template
+// with attribute escaping only, because html/template would percent-encode
+// merge tags in the cache buster and mail platforms would not expand them.
+var adSnippetHTML = template.Must(template.New("snippet").Funcs(template.FuncMap{
+	"attr": html.EscapeString,
+}).Parse(
+	`{{if .NoScript}}<noscript>{{end}}` +
+		`<a href="{{attr .RedirectURL}}" target="_blank" rel="noopener sponsored">` +
+		`<img src="{{attr .ImageURL}}" alt="{{attr .Title}}"{{if .Width}} width="{{.Width}}" height="{{.Height}}"{{end}} border="0" style="border:0;display:block"></a>` +
+		`<img src="{{attr .PixelURL}}" width="1" height="1" alt="" border="0" style="border:0;width:1px;height:1px">` +
+		`{{if .NoScript}}</noscript>{{end}}`))
+
+type adSnippet struct {
+	adTag
+	NoScript bool
+}
+
+type snippetResponse struct {
+	AdID        uint      `json:"ad_id"`
+	CreativeID  *uint     `json:"creative_id,omitempty"`
+	Format      string    `json:"format"`
+	HTML        string    `json:"html"`
+	RedirectURL string    `json:"redirect_url"`
+	PixelURL    string    `json:"pixel_url"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// GetAdSnippet generates HTML for contexts without JavaScript:
+// ?format=email (default) or noscript. The links are signed, absolute and
+// carry a cache buster so mail clients and proxies do not reuse the
+// pixel. Pass ?cache_buster= an ESP merge tag such as *|UNIQID|* to vary
+// it per recipient; otherwise a random value is used. ?placement= and
+// ?user_id= work as on /tag.js. A creative is picked once, here.
+func (s *Server) GetAdSnippet(c *gin.Context) {
+	adID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad id"})
+		return
+	}
+	format := c.DefaultQuery("format", "email")
+	if format != "email" && format != "noscript" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be email or noscript"})
+		return
+	}
+	if s.linkSigning.baseURL == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "PUBLIC_BASE_URL is required for absolute snippet links"})
+		return
+	}
+	placement, ok := s.placementQuery(c)
+	if !ok {
+		return
+	}
+
+	ad, found, err := s.servableAd(c.Request.Context(), uint(adID), placement)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to fetch ads")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch ads"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad is not being served"})
+		return
+	}
+
+	secret, err := s.signingSecret(ad.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to resolve signing secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign links"})
+		return
+	}
+	if secret == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "No signing secret configured for this ad"})
+		return
+	}
+
+	cacheBuster := c.Query("cache_buster")
+	if cacheBuster == "" {
+		raw := make([]byte, 8)
+		if _, err := rand.Read(raw); err != nil {
+			s.logger.WithError(err).Error("Failed to generate cache buster")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate snippet"})
+			return
+		}
+		cacheBuster = hex.EncodeToString(raw)
+	}
+	extra := url.Values{}
+	if key := c.Query("placement"); key != "" {
+		extra.Set("placement", key)
+	}
+
+	snippet := adSnippet{
+		adTag:    adTag{ImageURL: ad.ImageURL, Title: ad.Title},
+		NoScript: format == "noscript",
+	}
+	if ad.Creative != nil {
+		snippet.Width, snippet.Height = ad.Creative.Width, ad.Creative.Height
+	}
+	snippet.RedirectURL, snippet.PixelURL = s.adEventLinks(ad, secret, c.Query("user_id"), extra)
+	// Appended unencoded so merge tags survive; the pixel ignores it.
+	snippet.PixelURL += "&cb=" + cacheBuster
+
+	var html bytes.Buffer
+	if err := adSnippetHTML.Execute(&html, snippet); err != nil {
+		s.logger.WithError(err).Error("Failed to render ad snippet")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate snippet"})
+		return
+	}
+
+	response := snippetResponse{
+		AdID:        ad.ID,
+		Format:      format,
+		HTML:        html.String(),
+		RedirectURL: snippet.RedirectURL,
+		PixelURL:    snippet.PixelURL,
+		ExpiresAt:   time.Now().Add(s.linkSigning.ttl).UTC(),
+	}
+	if ad.Creative != nil {
+		response.CreativeID = &ad.Creative.ID
+	}
+	c.JSON(http.StatusOK, response)
+}
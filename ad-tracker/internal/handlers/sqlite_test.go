@@ -0,0 +1,87 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newSQLiteServer stores events in the database, as DB_DRIVER=sqlite does.
+func newSQLiteServer(t *testing.T) *testServer {
+	t.Helper()
+	db := openTestDB(t)
+	ts := &testServer{}
+	ts.start(t, db, repositories.NewEventStore(db))
+	return ts
+}
+
+func TestAnalyticsOnSQLite(t *testing.T) {
+	ts := newSQLiteServer(t)
+	ad := ts.createAd(t)
+
+	for _, body := range []gin.H{
+		{"ad_id": ad.ID, "session_id": "s1", "time_in_view_ms": 2000, "percent_in_view": 100},
+		{"ad_id": ad.ID, "session_id": "s2", "time_in_view_ms": 100, "percent_in_view": 20},
+	} {
+		if status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", body, nil); status != http.StatusOK {
+			t.Fatalf("impression got %d, want 200", status)
+		}
+	}
+	// Clicks normally reach the database through the click queue
+	now := time.Now().UTC()
+	clicks := []models.ClickEvent{
+		{ClickID: "c1", AdID: ad.ID, Timestamp: now.Add(-2 * time.Hour), VideoPlaybackTime: 10, SessionID: "s1"},
+		{ClickID: "c2", AdID: ad.ID, Timestamp: now.Add(-time.Minute), VideoPlaybackTime: 30},
+	}
+	if err := ts.db.Create(&clicks).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var resp struct {
+		Analytics models.AnalyticsResponse `json:"analytics"`
+	}
+	if status := ts.do(t, http.MethodGet, "/api/v1/ads/analytics?timeframe=1d&ad_id="+strconv.Itoa(int(ad.ID)), nil, &resp); status != http.StatusOK {
+		t.Fatalf("analytics got %d, want 200", status)
+	}
+	analytics := resp.Analytics
+	if analytics.ClickCount != 2 || analytics.LastHour != 1 || analytics.Impressions != 2 || analytics.CTR != 1 {
+		t.Errorf("counts = %+v", analytics)
+	}
+	if analytics.Playback == nil || analytics.Playback.MedianSeconds != 20 {
+		t.Errorf("playback = %+v, want a median of 20s", analytics.Playback)
+	}
+	if analytics.Viewability == nil || analytics.Viewability.Viewable != 1 || analytics.Viewability.Measured != 2 {
+		t.Errorf("viewability = %+v, want 1 of 2 viewable", analytics.Viewability)
+	}
+	if analytics.Sessions == nil || analytics.Sessions.Sessions != 2 || analytics.Sessions.Bounced != 1 {
+		t.Errorf("sessions = %+v, want 2 sessions, 1 bounced", analytics.Sessions)
+	}
+}
+
+func TestPostImpressionOnSQLite(t *testing.T) {
+	ts := newSQLiteServer(t)
+	ad := ts.createAd(t)
+
+	if status := ts.do(t, http.MethodPost, "/api/v1/ads/impression", gin.H{"ad_id": ad.ID, "user_id": "user-1"}, nil); status != http.StatusOK {
+		t.Fatalf("got %d, want 200", status)
+	}
+	ts.wait(t)
+
+	var stored []models.ImpressionEvent
+	if err := ts.db.WithContext(context.Background()).Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].AdID != ad.ID || stored[0].UserID != "user-1" {
+		t.Fatalf("stored impressions = %+v", stored)
+	}
+	if stored[0].IPAddress != "203.0.113.7" {
+		t.Errorf("ip_address = %q, want the client address", stored[0].IPAddress)
+	}
+}
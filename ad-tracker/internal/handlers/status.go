@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (s *Server) GetStatusService() *services.StatusService {
+	return s.status
+}
+
+// GetStatus serves the public status page payload.
+func (s *Server) GetStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, s.status.Snapshot())
+}
+
+func (s *Server) ListIncidents(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+	incidents, err := s.statusRepository.ListIncidents(activeOnly, 100)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list incidents")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list incidents"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"incidents": incidents})
+}
+
+func (s *Server) CreateIncident(c *gin.Context) {
+	var req models.IncidentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	incident := models.Incident{
+		Title:     req.Title,
+		Message:   req.Message,
+		Component: req.Component,
+		Severity:  req.Severity,
+		Active:    true,
+		StartedAt: time.Now().UTC(),
+	}
+	if incident.Severity == "" {
+		incident.Severity = "minor"
+	}
+
+	if err := s.statusRepository.CreateIncident(&incident); err != nil {
+		s.logger.WithError(err).Error("Failed to create incident")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create incident"})
+		return
+	}
+	c.JSON(http.StatusCreated, incident)
+}
+
+func (s *Server) ResolveIncident(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid incident id"})
+		return
+	}
+
+	incident, err := s.statusRepository.ResolveIncident(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Incident not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to resolve incident")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve incident"})
+		return
+	}
+	c.JSON(http.StatusOK, incident)
+}
+
+func (s *Server) checkDatabase(ctx context.Context) error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (s *Server) checkClickQueue(ctx context.Context) error {
+	if saturation := s.clickQueue.Saturation(); saturation >= 0.9 {
+		return fmt.Errorf("click queue %.0f%% full", saturation*100)
+	}
+	return nil
+}
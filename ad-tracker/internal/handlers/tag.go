@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+	"ad-tracking-system/internal/signing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adTagScript renders the ad in place of the <script> that loaded it and
+// fires the impression pixel once the image is in the page. Links are
+// resolved against the script's own URL, so they reach this server even
+// without PUBLIC_BASE_URL. Values are JSON encoded, which also escapes <
+// and > so they cannot close the script.
+var adTagScript = template.Must(template.New("tag.js").Funcs(template.FuncMap{
+	"js": func(v interface{}) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+}).Parse(`(function () {
+  var script = document.currentScript;
+  if (!script || !script.parentNode) return;
+  var link = document.createElement("a");
+  link.href = new URL({{js .RedirectURL}}, script.src).href;
+  link.target = "_blank";
+  link.rel = "noopener sponsored";
+  var img = document.createElement("img");
+  img.src = {{js .ImageURL}};
+  img.alt = {{js .Title}};
+  img.style.border = "0";
+{{- if .Width}}
+  img.width = {{.Width}};
+  img.height = {{.Height}};
+{{- end}}
+  link.appendChild(img);
+  script.parentNode.insertBefore(link, script);
+  var pixel = new URL({{js .PixelURL}}, script.src);
+  pixel.searchParams.set("cb", String(Date.now()));
+  new Image().src = pixel.href;
+})();
+`))
+
+type adTag struct {
+	RedirectURL string
+	PixelURL    string
+	ImageURL    string
+	Title       string
+	Width       int
+	Height      int
+}
+
+// AdTag serves the JavaScript tag for one ad: publishers include
+// <script src=".../tag.js?ad_id=N"></script> where the ad should appear.
+// ?placement= narrows the creatives to those that fit and is recorded on
+// the events, as is ?user_id=. The ad must be servable, but targeting,
+// caps and pacing are not applied since the publisher picked it.
+func (s *Server) AdTag(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	adID, err := strconv.ParseUint(c.Query("ad_id"), 10, 32)
+	if err != nil {
+		c.String(http.StatusBadRequest, "/* invalid ad_id */\n")
+		return
+	}
+	placement, ok := s.placementQuery(c)
+	if !ok {
+		return
+	}
+
+	ad, found, err := s.servableAd(c.Request.Context(), uint(adID), placement)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to fetch ads")
+		c.String(http.StatusInternalServerError, "/* ad unavailable */\n")
+		return
+	}
+	if !found {
+		c.String(http.StatusNotFound, "/* ad not available */\n")
+		return
+	}
+	tag := adTag{ImageURL: ad.ImageURL, Title: ad.Title}
+	if ad.Creative != nil {
+		tag.Width, tag.Height = ad.Creative.Width, ad.Creative.Height
+	}
+	secret, err := s.signingSecret(ad.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to resolve signing secret")
+		c.String(http.StatusInternalServerError, "/* ad unavailable */\n")
+		return
+	}
+	extra := url.Values{}
+	if key := c.Query("placement"); key != "" {
+		extra.Set("placement", key)
+	}
+	tag.RedirectURL, tag.PixelURL = s.adEventLinks(ad, secret, c.Query("user_id"), extra)
+
+	var script bytes.Buffer
+	if err := adTagScript.Execute(&script, tag); err != nil {
+		s.logger.WithError(err).Error("Failed to render ad tag")
+		c.String(http.StatusInternalServerError, "/* ad unavailable */\n")
+		return
+	}
+	c.Data(http.StatusOK, "application/javascript; charset=utf-8", script.Bytes())
+}
+
+// servableAd finds the ad among those currently served, fitted to the
+// placement and with a creative picked. It reports false when the ad is
+// not served or nothing of it fits the placement.
+func (s *Server) servableAd(ctx context.Context, adID uint, placement *models.Placement) (models.Ad, bool, error) {
+	ads, err := s.ads.Active(ctx)
+	if err != nil {
+		return models.Ad{}, false, err
+	}
+	for _, ad := range ads {
+		if ad.ID != adID {
+			continue
+		}
+		candidates := []models.Ad{ad}
+		if placement != nil {
+			candidates = services.Fit(candidates, *placement)
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		served, _ := rotateCreatives(candidates)
+		return served[0], true, nil
+	}
+	return models.Ad{}, false, nil
+}
+
+// adEventLinks returns the redirect and pixel URLs for an ad as served,
+// carrying its creative and the extra parameters, and signed unless secret
+// is empty.
+func (s *Server) adEventLinks(ad models.Ad, secret, userID string, extra url.Values) (string, string) {
+	now := time.Now()
+	links := make([]string, 0, 2)
+	for _, link := range []struct{ kind, endpoint string }{
+		{signing.KindClick, "redirect"},
+		{signing.KindPixel, "pixel"},
+	} {
+		query := url.Values{}
+		if secret != "" {
+			query = signing.Query(secret, link.kind, ad.ID, now, userID)
+		} else if userID != "" {
+			query.Set("user_id", userID)
+		}
+		if ad.Creative != nil {
+			query.Set("creative_id", strconv.FormatUint(uint64(ad.Creative.ID), 10))
+		}
+		for key, values := range extra {
+			query[key] = values
+		}
+		links = append(links, s.signedURL(ad.ID, link.endpoint, query))
+	}
+	return links[0], links[1]
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/targeting"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const defaultCountryHeader = "CF-IPCountry"
+
+// SetCountryHeader names the header the CDN or load balancer puts the
+// viewer's country code in.
+func (s *Server) SetCountryHeader(header string) {
+	s.countryHeader = header
+}
+
+// targetingContext describes the viewer of a serve request. ?keywords= and
+// ?placement= come from the publisher's tag.
+func (s *Server) targetingContext(c *gin.Context) targeting.Context {
+	return targeting.NewContext(
+		c.GetHeader(s.countryHeader),
+		c.GetHeader("User-Agent"),
+		c.Query("keywords"),
+		c.Query("placement"),
+	)
+}
+
+// filterTargeted keeps the ads whose targeting matches the request, and
+// reports whether any ad was targeted, making the response request
+// specific.
+func (s *Server) filterTargeted(c *gin.Context, ads []models.Ad) ([]models.Ad, bool) {
+	var (
+		ctx      targeting.Context
+		targeted bool
+	)
+	eligible := make([]models.Ad, 0, len(ads))
+	for _, ad := range ads {
+		if ad.Targeting == nil {
+			eligible = append(eligible, ad)
+			continue
+		}
+		if !targeted {
+			ctx, targeted = s.targetingContext(c), true
+		}
+		if ad.Targeting.Match(ctx) {
+			eligible = append(eligible, ad)
+		}
+	}
+	return eligible, targeted
+}
+
+// UpdateAdTargeting replaces an ad's targeting rule. A body of null
+// removes it.
+func (s *Server) UpdateAdTargeting(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad id"})
+		return
+	}
+
+	var rule *targeting.Rule
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read body"})
+		return
+	}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && !bytes.Equal(trimmed, []byte("null")) {
+		rule = &targeting.Rule{}
+		if err := json.Unmarshal(trimmed, rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := rule.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ad, err := s.adRepository.UpdateTargeting(uint(id), rule)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ad not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to update ad targeting")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ad targeting"})
+		return
+	}
+	s.invalidateAds(c.Request.Context(), ad.ID)
+	c.JSON(http.StatusOK, ad)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTimeframe    = "24h"
+	defaultMaxTimeframe = 90 * 24 * time.Hour
+
+	// allTime is what timeframe=all covers; it is exempt from the maximum.
+	allTime = 10 * 365 * 24 * time.Hour
+)
+
+// SetMaxTimeframe caps the timeframes analytics and report queries accept.
+func (s *Server) SetMaxTimeframe(max time.Duration) {
+	s.maxTimeframe = max
+}
+
+// parseTimeframe reads a timeframe: "all", a Go duration such as 36h or
+// 15m, or a number of days or weeks such as 14d or 4w.
+func (s *Server) parseTimeframe(timeframe string) (time.Duration, error) {
+	if timeframe == "all" {
+		return allTime, nil
+	}
+	duration, err := services.ParseWindow(timeframe)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeframe %q: use a duration such as 1h, 36h, 14d or 4w, or all", timeframe)
+	}
+	if duration > s.maxTimeframe {
+		return 0, fmt.Errorf("timeframe %q exceeds the maximum of %s", timeframe, s.maxTimeframe)
+	}
+	return duration, nil
+}
+
+// timeframeQuery reads ?timeframe= (default 24h), answering 400 itself
+// when the value is invalid.
+func (s *Server) timeframeQuery(c *gin.Context) (string, time.Duration, bool) {
+	timeframe := c.DefaultQuery("timeframe", defaultTimeframe)
+	duration, err := s.parseTimeframe(timeframe)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", 0, false
+	}
+	return timeframe, duration, true
+}
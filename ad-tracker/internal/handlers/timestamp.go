@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SetTimestampPolicy changes how far client-supplied event timestamps may
+// stray from the server clock.
+func (s *Server) SetTimestampPolicy(policy models.TimestampPolicy) {
+	s.timestamps = policy
+}
+
+// eventTime resolves the time of an event received at receivedAt, writing
+// a 400 and returning false when the policy rejects the client timestamp.
+func (s *Server) eventTime(c *gin.Context, eventType string, client models.ClientTime, receivedAt time.Time) (time.Time, bool) {
+	timestamp, problem := s.resolveEventTime(eventType, client, receivedAt)
+	if problem != nil {
+		problem.write(c)
+		return time.Time{}, false
+	}
+	return timestamp, true
+}
+
+// resolveEventTime is eventTime without the response. A missing client
+// timestamp means the receipt time; one outside the policy is clamped to
+// it, or refused.
+func (s *Server) resolveEventTime(eventType string, client models.ClientTime, receivedAt time.Time) (time.Time, *eventProblem) {
+	if client.IsZero() {
+		return receivedAt, nil
+	}
+	claimed := client.Time
+
+	var problem string
+	switch {
+	case claimed.After(receivedAt.Add(s.timestamps.MaxSkew)):
+		problem = fmt.Sprintf("must not be more than %s in the future", s.timestamps.MaxSkew)
+	case s.timestamps.MaxAge > 0 && claimed.Before(receivedAt.Add(-s.timestamps.MaxAge)):
+		problem = fmt.Sprintf("must not be more than %s in the past", s.timestamps.MaxAge)
+	default:
+		return claimed, nil
+	}
+
+	if s.timestamps.Reject {
+		metrics.ClientTimestamps.WithLabelValues(eventType, "rejected").Inc()
+		return time.Time{}, fieldProblem("timestamp", problem)
+	}
+	metrics.ClientTimestamps.WithLabelValues(eventType, "clamped").Inc()
+	s.logSampler.Log(s.logger.WithFields(logrus.Fields{
+		"event":     eventType,
+		"timestamp": claimed.UTC(),
+	}), logrus.DebugLevel, "Replaced out-of-range client timestamp with receipt time")
+	return receivedAt, nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTimelineLimit = 100
+	maxTimelineLimit     = 1000
+)
+
+// GetUserAnalytics reports clicks per user and new versus returning users
+// over ?timeframe=, for all ads or one ?ad_id=. valid_only=true leaves out
+// clicks tagged invalid.
+func (s *Server) GetUserAnalytics(c *gin.Context) {
+	var adID uint64
+	if raw := c.Query("ad_id"); raw != "" {
+		var err error
+		if adID, err = strconv.ParseUint(raw, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ad_id"})
+			return
+		}
+	}
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
+
+	stats, err := s.userRepository.ClickStats(uint(adID), time.Now().UTC().Add(-duration), c.Query("valid_only") == "true")
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load user analytics")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user analytics"})
+		return
+	}
+	stats.Timeframe = timeframe
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetUserTimeline lists one user's clicks, impressions and conversions,
+// newest first, over ?timeframe= and up to ?limit= entries. The user id is
+// hashed like stored ids before the lookup. Each lookup is audited.
+func (s *Server) GetUserTimeline(c *gin.Context) {
+	userID := c.Param("userId")
+	if userID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User id is required"})
+		return
+	}
+	limit := defaultTimelineLimit
+	if raw := c.Query("limit"); raw != "" {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit < 1 || limit > maxTimelineLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+	}
+	timeframe, duration, ok := s.timeframeQuery(c)
+	if !ok {
+		return
+	}
+
+	stored := s.storedUserID(userID)
+	timeline, err := s.userRepository.Timeline(stored, time.Now().UTC().Add(-duration), limit)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load user timeline")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user timeline"})
+		return
+	}
+
+	actor := "admin"
+	if name := c.GetString("client_cert"); name != "" {
+		actor = name
+	}
+	if err := s.auditRepository.Record(actor, "user.timeline", "user", services.HashSubject(models.SubjectUserID, stored), gin.H{
+		"timeframe": timeframe,
+		"events":    len(timeline),
+		"ip":        c.ClientIP(),
+	}); err != nil {
+		s.logger.WithError(err).Warn("Failed to record user timeline audit event")
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timeframe": timeframe,
+		"events":    timeline,
+	})
+}
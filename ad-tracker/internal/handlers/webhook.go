@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func (s *Server) GetWebhookDispatcher() *services.WebhookDispatcher {
+	return s.webhooks
+}
+
+func (s *Server) ListWebhookEndpoints(c *gin.Context) {
+	account, ok := s.accountFromParam(c)
+	if !ok {
+		return
+	}
+
+	endpoints, err := s.webhookRepository.ListEndpoints(account.ID)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list webhook endpoints")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhook endpoints"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"endpoints": endpoints})
+}
+
+// CreateWebhookEndpoint registers an endpoint for the account. The signing
+// secret is only returned in this response.
+func (s *Server) CreateWebhookEndpoint(c *gin.Context) {
+	account, ok := s.accountFromParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.WebhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.webhooks.CheckURL(c.Request.Context(), req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, err := newSigningSecret()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to generate webhook secret")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+
+	endpoint := models.WebhookEndpoint{
+		AccountID:  account.ID,
+		URL:        req.URL,
+		EventTypes: strings.Join(req.EventTypes, ","),
+		Secret:     secret,
+		Active:     true,
+	}
+	if err := s.webhookRepository.CreateEndpoint(&endpoint); err != nil {
+		s.logger.WithError(err).Error("Failed to create webhook endpoint")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook endpoint"})
+		return
+	}
+	if err := s.webhooks.Refresh(); err != nil {
+		s.logger.WithError(err).Warn("Failed to refresh webhook endpoints")
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"endpoint": endpoint, "secret": secret})
+}
+
+func (s *Server) DeleteWebhookEndpoint(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	err = s.webhookRepository.DeleteEndpoint(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to delete webhook endpoint")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook endpoint"})
+		return
+	}
+	if err := s.webhooks.Refresh(); err != nil {
+		s.logger.WithError(err).Warn("Failed to refresh webhook endpoints")
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "deleted"})
+}
+
+// ListWebhookDeliveries returns an endpoint's delivery log, newest first,
+// optionally filtered by ?status=pending|delivered|failed.
+func (s *Server) ListWebhookDeliveries(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook id"})
+		return
+	}
+
+	deliveries, err := s.webhookRepository.ListDeliveries(uint(id), c.Query("status"), 200)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to list webhook deliveries")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list webhook deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// RedeliverWebhook sends a logged delivery again immediately, e.g. after the
+// receiver fixed an outage that exhausted the retries.
+func (s *Server) RedeliverWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery id"})
+		return
+	}
+
+	delivery, err := s.webhookRepository.GetDelivery(uint(id))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load webhook delivery")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load webhook delivery"})
+		return
+	}
+
+	s.webhooks.Redeliver(c.Request.Context(), delivery)
+	c.JSON(http.StatusOK, delivery)
+}
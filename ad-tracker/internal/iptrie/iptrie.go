@@ -0,0 +1,113 @@
+// Package iptrie is a binary radix tree over IP prefixes for fast
+// "is this address inside any of these ranges" lookups.
+package iptrie
+
+import "net"
+
+type node struct {
+	children [2]*node
+	terminal bool
+}
+
+// Trie holds IPv4 and IPv6 prefixes in separate trees. It is not safe for
+// concurrent mutation; build it once and swap it in behind a lock.
+type Trie struct {
+	v4   *node
+	v6   *node
+	size int
+}
+
+func New() *Trie {
+	return &Trie{v4: &node{}, v6: &node{}}
+}
+
+// Insert adds a prefix. Prefixes already covered by a shorter one are
+// absorbed. IPv4-mapped IPv6 prefixes such as ::ffff:10.0.0.0/104 go into
+// the IPv4 tree, as their addresses are matched there.
+func (t *Trie) Insert(network *net.IPNet) {
+	ip, root := t.root(network.IP)
+	if ip == nil {
+		return
+	}
+	ones, bits := network.Mask.Size()
+	if bits == 8*net.IPv6len && len(ip) == net.IPv4len {
+		ones -= 8 * (net.IPv6len - net.IPv4len)
+	}
+	if ones < 0 || ones > 8*len(ip) {
+		return
+	}
+
+	n := root
+	for i := 0; i < ones; i++ {
+		if n.terminal {
+			return
+		}
+		b := bit(ip, i)
+		if n.children[b] == nil {
+			n.children[b] = &node{}
+		}
+		n = n.children[b]
+	}
+	if !n.terminal {
+		t.size -= n.prefixes()
+		n.terminal = true
+		n.children = [2]*node{}
+		t.size++
+	}
+}
+
+// prefixes counts the terminal nodes below n, which a new prefix at n
+// absorbs.
+func (n *node) prefixes() int {
+	count := 0
+	for _, child := range n.children {
+		if child == nil {
+			continue
+		}
+		if child.terminal {
+			count++
+		} else {
+			count += child.prefixes()
+		}
+	}
+	return count
+}
+
+// Contains reports whether ip falls inside any inserted prefix.
+func (t *Trie) Contains(ip net.IP) bool {
+	ip, root := t.root(ip)
+	if ip == nil {
+		return false
+	}
+
+	n := root
+	for i := 0; n != nil; i++ {
+		if n.terminal {
+			return true
+		}
+		if i == len(ip)*8 {
+			return false
+		}
+		n = n.children[bit(ip, i)]
+	}
+	return false
+}
+
+// Len is the number of distinct prefixes stored.
+func (t *Trie) Len() int {
+	return t.size
+}
+
+func (t *Trie) root(ip net.IP) (net.IP, *node) {
+	if v4 := ip.To4(); v4 != nil {
+		return v4, t.v4
+	}
+	if v6 := ip.To16(); v6 != nil {
+		return v6, t.v6
+	}
+	return nil, nil
+}
+
+func bit(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
@@ -0,0 +1,108 @@
+package iptrie_test
+
+import (
+	"net"
+	"testing"
+
+	"ad-tracking-system/internal/iptrie"
+)
+
+func mustCIDR(t *testing.T, value string) *net.IPNet {
+	t.Helper()
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return network
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefixes []string
+		in       []string
+		out      []string
+	}{
+		{
+			name:     "IPv4",
+			prefixes: []string{"10.0.0.0/8", "192.168.1.0/24", "203.0.113.7/32"},
+			in:       []string{"10.0.0.1", "10.255.255.255", "192.168.1.200", "203.0.113.7", "::ffff:10.1.2.3"},
+			out:      []string{"11.0.0.1", "192.168.2.1", "203.0.113.8", "2001:db8::1"},
+		},
+		{
+			name:     "IPv6",
+			prefixes: []string{"2001:db8::/32", "fe80::1/128"},
+			in:       []string{"2001:db8::1", "2001:db8:ffff::1", "fe80::1"},
+			out:      []string{"2001:db9::1", "fe80::2", "10.0.0.1"},
+		},
+		{
+			name:     "IPv4-mapped prefix",
+			prefixes: []string{"::ffff:10.0.0.0/104"},
+			in:       []string{"10.0.0.1", "10.200.0.1", "::ffff:10.3.2.1"},
+			out:      []string{"11.0.0.1", "::1"},
+		},
+		{
+			name:     "IPv4 default route",
+			prefixes: []string{"0.0.0.0/0"},
+			in:       []string{"0.0.0.0", "255.255.255.255", "10.0.0.1"},
+			out:      []string{"2001:db8::1"},
+		},
+		{
+			name:     "IPv6 default route",
+			prefixes: []string{"::/0"},
+			in:       []string{"::1", "2001:db8::1"},
+			out:      []string{"10.0.0.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trie := iptrie.New()
+			for _, prefix := range tt.prefixes {
+				trie.Insert(mustCIDR(t, prefix))
+			}
+			for _, ip := range tt.in {
+				if !trie.Contains(net.ParseIP(ip)) {
+					t.Errorf("Contains(%s) = false, want true", ip)
+				}
+			}
+			for _, ip := range tt.out {
+				if trie.Contains(net.ParseIP(ip)) {
+					t.Errorf("Contains(%s) = true, want false", ip)
+				}
+			}
+		})
+	}
+}
+
+func TestInsertAbsorbsCoveredPrefixes(t *testing.T) {
+	trie := iptrie.New()
+	trie.Insert(mustCIDR(t, "10.1.2.0/24"))
+	trie.Insert(mustCIDR(t, "10.1.0.0/16"))
+	trie.Insert(mustCIDR(t, "10.1.3.0/24"))
+	trie.Insert(mustCIDR(t, "10.1.0.0/16"))
+	if trie.Len() != 1 {
+		t.Errorf("Len = %d, want 1 after inserting prefixes inside 10.1.0.0/16", trie.Len())
+	}
+
+	// The shorter prefix wins whichever order they arrive in
+	for _, ip := range []string{"10.1.2.3", "10.1.3.3", "10.1.200.1"} {
+		if !trie.Contains(net.ParseIP(ip)) {
+			t.Errorf("Contains(%s) = false, want true", ip)
+		}
+	}
+	if trie.Contains(net.ParseIP("10.2.0.1")) {
+		t.Error("Contains(10.2.0.1) = true, want false")
+	}
+}
+
+func TestContainsInvalid(t *testing.T) {
+	trie := iptrie.New()
+	trie.Insert(mustCIDR(t, "0.0.0.0/0"))
+	trie.Insert(mustCIDR(t, "::/0"))
+	if trie.Contains(nil) {
+		t.Error("Contains(nil) = true, want false")
+	}
+	if trie.Contains(net.IP{1, 2, 3}) {
+		t.Error("Contains of a 3-byte address = true, want false")
+	}
+}
@@ -0,0 +1,84 @@
+package k8s
+
+import (
+	"os"
+
+	"ad-tracking-system/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PodInfo is the pod metadata exposed through the downward API as env vars.
+type PodInfo struct {
+	Name      string
+	Namespace string
+	Node      string
+	IP        string
+}
+
+// PodInfoFromEnv reads POD_NAME, POD_NAMESPACE, NODE_NAME and POD_IP.
+func PodInfoFromEnv() PodInfo {
+	return PodInfo{
+		Name:      os.Getenv("POD_NAME"),
+		Namespace: os.Getenv("POD_NAMESPACE"),
+		Node:      os.Getenv("NODE_NAME"),
+		IP:        os.Getenv("POD_IP"),
+	}
+}
+
+// InCluster reports whether the process runs inside a Kubernetes pod.
+func InCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && os.Getenv("KUBERNETES_SERVICE_PORT") != ""
+}
+
+// Identity is a stable name for this replica, falling back to the hostname.
+func (p PodInfo) Identity() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// Fields returns the non-empty metadata as log fields.
+func (p PodInfo) Fields() logrus.Fields {
+	fields := logrus.Fields{}
+	if p.Name != "" {
+		fields["pod"] = p.Name
+	}
+	if p.Namespace != "" {
+		fields["namespace"] = p.Namespace
+	}
+	if p.Node != "" {
+		fields["node"] = p.Node
+	}
+	if p.IP != "" {
+		fields["pod_ip"] = p.IP
+	}
+	return fields
+}
+
+// Attach adds pod metadata to every log entry and publishes the pod_info metric.
+func (p PodInfo) Attach(logger *logrus.Logger) {
+	if fields := p.Fields(); len(fields) > 0 {
+		logger.AddHook(&fieldsHook{fields: fields})
+	}
+	metrics.PodInfo.WithLabelValues(p.Name, p.Namespace, p.Node).Set(1)
+}
+
+type fieldsHook struct {
+	fields logrus.Fields
+}
+
+func (h *fieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *fieldsHook) Fire(entry *logrus.Entry) error {
+	for key, value := range h.fields {
+		if _, ok := entry.Data[key]; !ok {
+			entry.Data[key] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	microTimeLayout   = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// Elector decides whether this replica should run singleton jobs.
+type Elector interface {
+	IsLeader() bool
+}
+
+// AlwaysLeader is used outside Kubernetes, where there is a single replica.
+type AlwaysLeader struct{}
+
+func (AlwaysLeader) IsLeader() bool { return true }
+
+// LeaseElector implements leader election on a coordination.k8s.io/v1 Lease
+// using the pod's service account, without depending on client-go.
+type LeaseElector struct {
+	client    *http.Client
+	apiServer string
+	token     string
+	namespace string
+	name      string
+	identity  string
+	duration  time.Duration
+	logger    *logrus.Logger
+	leader    atomic.Bool
+}
+
+type lease struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Metadata   leaseMetadata `json:"metadata"`
+	Spec       leaseSpec     `json:"spec"`
+}
+
+type leaseMetadata struct {
+	Name            string `json:"name"`
+	Namespace       string `json:"namespace"`
+	ResourceVersion string `json:"resourceVersion,omitempty"`
+}
+
+type leaseSpec struct {
+	HolderIdentity       string `json:"holderIdentity"`
+	LeaseDurationSeconds int    `json:"leaseDurationSeconds"`
+	AcquireTime          string `json:"acquireTime,omitempty"`
+	RenewTime            string `json:"renewTime,omitempty"`
+	LeaseTransitions     int    `json:"leaseTransitions"`
+}
+
+// NewLeaseElector builds an elector from the in-cluster service account.
+func NewLeaseElector(name string, pod PodInfo, duration time.Duration, logger *logrus.Logger) (*LeaseElector, error) {
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("read service account token: %w", err)
+	}
+	caCert, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("invalid service account CA")
+	}
+
+	namespace := pod.Namespace
+	if namespace == "" {
+		ns, err := os.ReadFile(serviceAccountDir + "/namespace")
+		if err != nil {
+			return nil, fmt.Errorf("read namespace: %w", err)
+		}
+		namespace = string(ns)
+	}
+
+	return &LeaseElector{
+		client: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+		},
+		apiServer: fmt.Sprintf("https://%s:%s", os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")),
+		token:     string(token),
+		namespace: namespace,
+		name:      name,
+		identity:  pod.Identity(),
+		duration:  duration,
+		logger:    logger,
+	}, nil
+}
+
+func (e *LeaseElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run tries to acquire or renew the lease every third of its duration until
+// ctx is cancelled.
+func (e *LeaseElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.duration / 3)
+	defer ticker.Stop()
+
+	for {
+		e.setLeader(e.tryAcquire(ctx))
+
+		select {
+		case <-ctx.Done():
+			e.setLeader(false)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (e *LeaseElector) setLeader(leader bool) {
+	if e.leader.Swap(leader) != leader {
+		e.logger.WithFields(logrus.Fields{
+			"lease":    e.name,
+			"identity": e.identity,
+			"leader":   leader,
+		}).Info("Leader election state changed")
+	}
+	value := 0.0
+	if leader {
+		value = 1
+	}
+	metrics.LeaderElection.WithLabelValues(e.name).Set(value)
+}
+
+func (e *LeaseElector) tryAcquire(ctx context.Context) bool {
+	now := time.Now().UTC()
+	current, status, err := e.get(ctx)
+	if err != nil {
+		e.logger.WithError(err).Warn("Failed to read lease")
+		return false
+	}
+
+	if status == http.StatusNotFound {
+		created := e.newLease(now)
+		created.Spec.AcquireTime = now.Format(microTimeLayout)
+		return e.write(ctx, http.MethodPost, e.collectionURL(), created)
+	}
+
+	if current.Spec.HolderIdentity != e.identity {
+		renewed, err := time.Parse(microTimeLayout, current.Spec.RenewTime)
+		expiry := time.Duration(current.Spec.LeaseDurationSeconds) * time.Second
+		if err == nil && current.Spec.HolderIdentity != "" && now.Before(renewed.Add(expiry)) {
+			return false
+		}
+		current.Spec.HolderIdentity = e.identity
+		current.Spec.AcquireTime = now.Format(microTimeLayout)
+		current.Spec.LeaseTransitions++
+	}
+
+	current.Spec.LeaseDurationSeconds = int(e.duration.Seconds())
+	current.Spec.RenewTime = now.Format(microTimeLayout)
+	// resourceVersion makes this a compare-and-swap; a 409 means we lost
+	return e.write(ctx, http.MethodPut, e.collectionURL()+"/"+e.name, current)
+}
+
+func (e *LeaseElector) newLease(now time.Time) *lease {
+	return &lease{
+		APIVersion: "coordination.k8s.io/v1",
+		Kind:       "Lease",
+		Metadata:   leaseMetadata{Name: e.name, Namespace: e.namespace},
+		Spec: leaseSpec{
+			HolderIdentity:       e.identity,
+			LeaseDurationSeconds: int(e.duration.Seconds()),
+			RenewTime:            now.Format(microTimeLayout),
+		},
+	}
+}
+
+func (e *LeaseElector) collectionURL() string {
+	return fmt.Sprintf("%s/apis/coordination.k8s.io/v1/namespaces/%s/leases", e.apiServer, e.namespace)
+}
+
+func (e *LeaseElector) get(ctx context.Context) (*lease, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.collectionURL()+"/"+e.name, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+e.token)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, resp.StatusCode, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, fmt.Errorf("get lease: unexpected status %d", resp.StatusCode)
+	}
+
+	var current lease
+	if err := json.NewDecoder(resp.Body).Decode(&current); err != nil {
+		return nil, resp.StatusCode, err
+	}
+	return &current, resp.StatusCode, nil
+}
+
+func (e *LeaseElector) write(ctx context.Context, method, url string, body *lease) bool {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return false
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payload))
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Authorization", "Bearer "+e.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		e.logger.WithError(err).Warn("Failed to write lease")
+		return false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return false
+	}
+	if resp.StatusCode >= 300 {
+		e.logger.WithField("status", resp.StatusCode).Warn("Lease update rejected")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,326 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ErrGroupActive is returned by Seek while the group has members: Kafka
+// only accepts offsets for a group nobody is consuming with, and a running
+// consumer would overwrite them on its next commit anyway.
+var ErrGroupActive = errors.New("consumer group has active members; stop its consumers first")
+
+// NoOffset is reported for partitions the group has not committed.
+const NoOffset = -1
+
+// PartitionOffsets is a group's position on one partition. Lag counts the
+// messages between the committed offset and the end of the partition.
+type PartitionOffsets struct {
+	Partition int   `json:"partition"`
+	Committed int64 `json:"committed"`
+	First     int64 `json:"first"`
+	Last      int64 `json:"last"`
+	Lag       int64 `json:"lag"`
+}
+
+// GroupOffsets is a consumer group's position on the topic.
+type GroupOffsets struct {
+	Group      string             `json:"group"`
+	Topic      string             `json:"topic"`
+	State      string             `json:"state"`
+	Members    int                `json:"members"`
+	Lag        int64              `json:"lag"`
+	Partitions []PartitionOffsets `json:"partitions"`
+}
+
+// SeekTarget says where to move a group. Exactly one of Time, Offset,
+// Earliest or Latest is set. Partition limits an Offset seek to one
+// partition; other targets apply to every partition.
+type SeekTarget struct {
+	Time      *time.Time
+	Offset    *int64
+	Partition *int
+	Earliest  bool
+	Latest    bool
+}
+
+// SeekError reports a seek target that cannot be applied.
+type SeekError struct {
+	Reason string
+}
+
+func (e *SeekError) Error() string {
+	return e.Reason
+}
+
+func (t SeekTarget) validate() error {
+	set := 0
+	for _, ok := range []bool{t.Time != nil, t.Offset != nil, t.Earliest, t.Latest} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return &SeekError{"seek needs exactly one of time, offset, earliest or latest"}
+	}
+	if t.Partition != nil && t.Offset == nil {
+		return &SeekError{"partition only applies to an offset seek"}
+	}
+	return nil
+}
+
+// OffsetAdmin inspects and moves consumer group offsets on one topic and
+// reads time ranges of it, so events can be reprocessed without the Kafka
+// command line tools.
+type OffsetAdmin struct {
+	client *kafka.Client
+	broker string
+	topic  string
+}
+
+func NewOffsetAdmin(brokerURL, topic string) *OffsetAdmin {
+	return &OffsetAdmin{
+		client: &kafka.Client{Addr: kafka.TCP(brokerURL), Timeout: 10 * time.Second},
+		broker: brokerURL,
+		topic:  topic,
+	}
+}
+
+// Describe reports the group's committed offsets, the partition bounds and
+// the lag.
+func (a *OffsetAdmin) Describe(ctx context.Context, group string) (GroupOffsets, error) {
+	result := GroupOffsets{Group: group, Topic: a.topic}
+
+	groups, err := a.client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{group}})
+	if err != nil {
+		return result, err
+	}
+	if len(groups.Groups) > 0 {
+		if err := groups.Groups[0].Error; err != nil {
+			return result, err
+		}
+		result.State = groups.Groups[0].GroupState
+		result.Members = len(groups.Groups[0].Members)
+	}
+
+	partitions, err := a.partitions(ctx)
+	if err != nil {
+		return result, err
+	}
+	first, err := a.listOffsets(ctx, partitions, kafka.FirstOffsetOf)
+	if err != nil {
+		return result, err
+	}
+	last, err := a.listOffsets(ctx, partitions, kafka.LastOffsetOf)
+	if err != nil {
+		return result, err
+	}
+	committed, err := a.committed(ctx, group, partitions)
+	if err != nil {
+		return result, err
+	}
+
+	for _, partition := range partitions {
+		offsets := PartitionOffsets{
+			Partition: partition,
+			Committed: committed[partition],
+			First:     first[partition],
+			Last:      last[partition],
+		}
+		// A group that never committed starts from the beginning (the
+		// sinks read from the first offset)
+		from := offsets.Committed
+		if from < offsets.First {
+			from = offsets.First
+		}
+		offsets.Lag = max(offsets.Last-from, 0)
+		result.Lag += offsets.Lag
+		result.Partitions = append(result.Partitions, offsets)
+	}
+	return result, nil
+}
+
+// Seek commits new offsets for the group and returns its position
+// afterwards. With dryRun nothing is committed and the planned offsets are
+// returned in Committed instead.
+func (a *OffsetAdmin) Seek(ctx context.Context, group string, target SeekTarget, dryRun bool) (GroupOffsets, error) {
+	if err := target.validate(); err != nil {
+		return GroupOffsets{}, err
+	}
+	current, err := a.Describe(ctx, group)
+	if err != nil {
+		return current, err
+	}
+	if current.Members > 0 && !dryRun {
+		return current, ErrGroupActive
+	}
+
+	planned, err := a.plan(ctx, current, target)
+	if err != nil {
+		return current, err
+	}
+	if dryRun {
+		for i, partition := range current.Partitions {
+			if offset, ok := planned[partition.Partition]; ok {
+				current.Partitions[i].Committed = offset
+				current.Partitions[i].Lag = max(partition.Last-offset, 0)
+			}
+		}
+		current.Lag = 0
+		for _, partition := range current.Partitions {
+			current.Lag += partition.Lag
+		}
+		return current, nil
+	}
+
+	commits := make([]kafka.OffsetCommit, 0, len(planned))
+	for partition, offset := range planned {
+		commits = append(commits, kafka.OffsetCommit{Partition: partition, Offset: offset})
+	}
+	resp, err := a.client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      group,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{a.topic: commits},
+	})
+	if err != nil {
+		return current, err
+	}
+	for _, partition := range resp.Topics[a.topic] {
+		if partition.Error != nil {
+			return current, fmt.Errorf("partition %d: %w", partition.Partition, partition.Error)
+		}
+	}
+	return a.Describe(ctx, group)
+}
+
+// plan resolves the target to an offset per partition, clamped to what the
+// partition still holds.
+func (a *OffsetAdmin) plan(ctx context.Context, current GroupOffsets, target SeekTarget) (map[int]int64, error) {
+	planned := make(map[int]int64, len(current.Partitions))
+
+	var atTime map[int]int64
+	if target.Time != nil {
+		partitions := make([]int, len(current.Partitions))
+		for i, partition := range current.Partitions {
+			partitions[i] = partition.Partition
+		}
+		var err error
+		atTime, err = a.listOffsets(ctx, partitions, func(partition int) kafka.OffsetRequest {
+			return kafka.TimeOffsetOf(partition, *target.Time)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	found := false
+	for _, partition := range current.Partitions {
+		var offset int64
+		switch {
+		case target.Earliest:
+			offset = partition.First
+		case target.Latest:
+			offset = partition.Last
+		case target.Time != nil:
+			offset = atTime[partition.Partition]
+			if offset < 0 {
+				// Nothing at or after the time yet
+				offset = partition.Last
+			}
+		case target.Partition != nil && *target.Partition != partition.Partition:
+			continue
+		default:
+			offset = *target.Offset
+		}
+		planned[partition.Partition] = min(max(offset, partition.First), partition.Last)
+		found = true
+	}
+	if !found {
+		return nil, &SeekError{fmt.Sprintf("topic %s has no partition %d", a.topic, *target.Partition)}
+	}
+	return planned, nil
+}
+
+func (a *OffsetAdmin) partitions(ctx context.Context) ([]int, error) {
+	meta, err := a.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{a.topic}})
+	if err != nil {
+		return nil, err
+	}
+	for _, topic := range meta.Topics {
+		if topic.Name != a.topic {
+			continue
+		}
+		if topic.Error != nil {
+			return nil, topic.Error
+		}
+		partitions := make([]int, len(topic.Partitions))
+		for i, partition := range topic.Partitions {
+			partitions[i] = partition.ID
+		}
+		sort.Ints(partitions)
+		return partitions, nil
+	}
+	return nil, fmt.Errorf("topic %s not found", a.topic)
+}
+
+// listOffsets asks for one offset per partition. Requests for the same
+// partition cannot share a call, hence one call per kind.
+func (a *OffsetAdmin) listOffsets(ctx context.Context, partitions []int, request func(partition int) kafka.OffsetRequest) (map[int]int64, error) {
+	if len(partitions) == 0 {
+		return map[int]int64{}, nil
+	}
+	requests := make([]kafka.OffsetRequest, len(partitions))
+	for i, partition := range partitions {
+		requests[i] = request(partition)
+	}
+	resp, err := a.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{a.topic: requests}})
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[int]int64, len(partitions))
+	for _, partition := range resp.Topics[a.topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("partition %d: %w", partition.Partition, partition.Error)
+		}
+		switch requests[0].Timestamp {
+		case kafka.FirstOffset:
+			offsets[partition.Partition] = partition.FirstOffset
+		case kafka.LastOffset:
+			offsets[partition.Partition] = partition.LastOffset
+		default:
+			offsets[partition.Partition] = NoOffset
+			for offset := range partition.Offsets {
+				offsets[partition.Partition] = offset
+			}
+		}
+	}
+	return offsets, nil
+}
+
+func (a *OffsetAdmin) committed(ctx context.Context, group string, partitions []int) (map[int]int64, error) {
+	resp, err := a.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: group, Topics: map[string][]int{a.topic: partitions}})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	committed := make(map[int]int64, len(partitions))
+	for _, partition := range partitions {
+		committed[partition] = NoOffset
+	}
+	for _, partition := range resp.Topics[a.topic] {
+		if partition.Error != nil {
+			return nil, fmt.Errorf("partition %d: %w", partition.Partition, partition.Error)
+		}
+		committed[partition.Partition] = partition.CommittedOffset
+	}
+	return committed, nil
+}
@@ -2,7 +2,6 @@ package kafka
 
 import (
 	"context"
-	"log"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -15,14 +14,22 @@ func NewKafkaWriter(brokerURL, topic string) *kafka.Writer {
 	}
 }
 
-func PublishEvent(writer *kafka.Writer, key, value []byte) {
-	err := writer.WriteMessages(context.Background(),
-		kafka.Message{
-			Key:   key,
-			Value: value,
-		},
-	)
-	if err != nil {
-		log.Printf("Failed to write message to Kafka: %v", err)
-	}
+// Producer adapts a kafka.Writer to the events.EventBus interface.
+type Producer struct {
+	writer *kafka.Writer
+}
+
+func NewProducer(writer *kafka.Writer) *Producer {
+	return &Producer{writer: writer}
+}
+
+func (p *Producer) Publish(ctx context.Context, key, value []byte) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   key,
+		Value: value,
+	})
+}
+
+func (p *Producer) Close() error {
+	return p.writer.Close()
 }
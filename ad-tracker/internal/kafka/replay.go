@@ -0,0 +1,86 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// ReadRange passes handle the topic's messages written in [from, to), in
+// batches of up to batchSize per partition. It reads without a consumer
+// group, so no committed offsets move, and stops at the end each
+// partition had when the call started.
+func (a *OffsetAdmin) ReadRange(ctx context.Context, from, to time.Time, batchSize int, handle func([]kafka.Message) error) error {
+	partitions, err := a.partitions(ctx)
+	if err != nil {
+		return err
+	}
+	starts, err := a.listOffsets(ctx, partitions, func(partition int) kafka.OffsetRequest {
+		return kafka.TimeOffsetOf(partition, from)
+	})
+	if err != nil {
+		return err
+	}
+	ends, err := a.listOffsets(ctx, partitions, func(partition int) kafka.OffsetRequest {
+		return kafka.TimeOffsetOf(partition, to)
+	})
+	if err != nil {
+		return err
+	}
+	last, err := a.listOffsets(ctx, partitions, kafka.LastOffsetOf)
+	if err != nil {
+		return err
+	}
+
+	for _, partition := range partitions {
+		start, end := starts[partition], ends[partition]
+		if end < 0 {
+			// Nothing written at or after to yet
+			end = last[partition]
+		}
+		if start < 0 || start >= end {
+			continue
+		}
+		if err := a.readPartition(ctx, partition, start, end, from, to, batchSize, handle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *OffsetAdmin) readPartition(ctx context.Context, partition int, start, end int64, from, to time.Time, batchSize int, handle func([]kafka.Message) error) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   []string{a.broker},
+		Topic:     a.topic,
+		Partition: partition,
+		MaxBytes:  10e6,
+	})
+	defer reader.Close()
+	if err := reader.SetOffset(start); err != nil {
+		return err
+	}
+
+	batch := make([]kafka.Message, 0, batchSize)
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		// Producer timestamps are not strictly ordered; the offsets only
+		// bound the scan
+		if !msg.Time.Before(from) && msg.Time.Before(to) {
+			batch = append(batch, msg)
+		}
+		done := msg.Offset >= end-1
+		if len(batch) >= batchSize || (done && len(batch) > 0) {
+			if err := handle(batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+		if done {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,384 @@
+// Package loadgen fires synthetic clicks and impressions at a running
+// tracker and measures how fast it answers.
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/signing"
+)
+
+// Options configure a run. Ads, users and addresses are drawn from Zipf
+// distributions so a few of each account for most of the traffic, as in
+// production.
+type Options struct {
+	Target      string
+	RPS         float64
+	Duration    time.Duration
+	ClickRatio  float64
+	AdIDs       []uint
+	Users       int
+	IPs         int
+	Concurrency int
+	Timeout     time.Duration
+	Seed        int64
+	// SigningSecret signs every event as a tag copying the ts/sig of its
+	// tracking links would. Needed when the target requires signed events.
+	SigningSecret string
+}
+
+// DefaultOptions are used for any option the caller leaves zero.
+var DefaultOptions = Options{
+	Target:      "http://localhost:8080",
+	RPS:         100,
+	Duration:    30 * time.Second,
+	ClickRatio:  0.1,
+	Users:       10000,
+	IPs:         5000,
+	Concurrency: 64,
+	Timeout:     5 * time.Second,
+	Seed:        1,
+}
+
+// Report summarizes a run. Dropped counts requests that were due while
+// every worker was still waiting on the target, so a run that could not
+// hold its rate says so instead of quietly slowing down.
+type Report struct {
+	Target    string                 `json:"target"`
+	Duration  float64                `json:"duration_seconds"`
+	TargetRPS float64                `json:"target_rps"`
+	Sent      int                    `json:"sent"`
+	Dropped   int                    `json:"dropped"`
+	RPS       float64                `json:"achieved_rps"`
+	Kinds     map[string]*KindReport `json:"kinds"`
+}
+
+// KindReport covers one endpoint. Errors are requests that got no
+// response at all.
+type KindReport struct {
+	Requests int            `json:"requests"`
+	Errors   int            `json:"errors"`
+	Statuses map[string]int `json:"statuses"`
+	Latency  Percentiles    `json:"latency_ms"`
+
+	latencies []float64
+}
+
+// Percentiles are latencies in milliseconds.
+type Percentiles struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+const (
+	kindClick      = "click"
+	kindImpression = "impression"
+)
+
+// userAgents are weighted roughly by browser share; the tracker drops
+// obvious bots, so every entry is a real browser.
+var userAgents = []struct {
+	weight int
+	ua     string
+}{
+	{45, "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"},
+	{25, "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"},
+	{18, "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"},
+	{5, "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15"},
+	{4, "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.0.0"},
+	{3, "Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0"},
+}
+
+type request struct {
+	kind string
+	ip   string
+	ua   string
+	body []byte
+}
+
+// generator builds requests. It is only used from the pacing goroutine, so
+// its random source needs no locking.
+type generator struct {
+	rand       *rand.Rand
+	clickRatio float64
+	ads        []uint
+	adZipf     *rand.Zipf
+	userZipf   *rand.Zipf
+	ipZipf     *rand.Zipf
+	ips        []string
+	uaTotal    int
+	secret     string
+}
+
+func newGenerator(opts Options) *generator {
+	r := rand.New(rand.NewSource(opts.Seed))
+	g := &generator{
+		rand:       r,
+		clickRatio: opts.ClickRatio,
+		ads:        opts.AdIDs,
+		adZipf:     rand.NewZipf(r, 1.1, 1, uint64(len(opts.AdIDs)-1)),
+		userZipf:   rand.NewZipf(r, 1.05, 1, uint64(opts.Users-1)),
+		ipZipf:     rand.NewZipf(r, 1.05, 1, uint64(opts.IPs-1)),
+		ips:        make([]string, opts.IPs),
+		secret:     opts.SigningSecret,
+	}
+	for i := range g.ips {
+		g.ips[i] = g.publicIP()
+	}
+	for _, ua := range userAgents {
+		g.uaTotal += ua.weight
+	}
+	return g
+}
+
+// publicIP avoids private, loopback and multicast ranges, which the
+// tracker's geo and fraud checks treat differently from real visitors.
+func (g *generator) publicIP() string {
+	for {
+		first := 1 + g.rand.Intn(223)
+		switch first {
+		case 10, 100, 127, 169, 172, 192:
+			continue
+		}
+		return fmt.Sprintf("%d.%d.%d.%d", first, g.rand.Intn(256), g.rand.Intn(256), 1+g.rand.Intn(254))
+	}
+}
+
+func (g *generator) userAgent() string {
+	n := g.rand.Intn(g.uaTotal)
+	for _, ua := range userAgents {
+		if n -= ua.weight; n < 0 {
+			return ua.ua
+		}
+	}
+	return userAgents[0].ua
+}
+
+func (g *generator) next() request {
+	adID := g.ads[g.adZipf.Uint64()]
+	payload := map[string]interface{}{"ad_id": adID}
+	// A fifth of traffic is anonymous
+	var userID string
+	if g.rand.Intn(5) > 0 {
+		userID = "user-" + strconv.FormatUint(g.userZipf.Uint64(), 10)
+		payload["user_id"] = userID
+	}
+
+	req := request{kind: kindImpression, ip: g.ips[g.ipZipf.Uint64()], ua: g.userAgent()}
+	linkKind := signing.KindPixel
+	if g.rand.Float64() < g.clickRatio {
+		req.kind = kindClick
+		linkKind = signing.KindClick
+	} else {
+		payload["time_in_view_ms"] = g.rand.Int63n(5000)
+		payload["percent_in_view"] = float64(40 + g.rand.Intn(61))
+	}
+	if g.secret != "" {
+		query := signing.Query(g.secret, linkKind, adID, time.Now(), userID)
+		payload["ts"], payload["sig"] = query.Get("ts"), query.Get("sig")
+	}
+	req.body, _ = json.Marshal(payload)
+	return req
+}
+
+// Run sends requests at opts.RPS until opts.Duration passes or ctx is
+// cancelled. Without opts.AdIDs it targets the ads the tracker serves.
+// Client addresses are sent in X-Forwarded-For, which the tracker only
+// honours when the generator is a trusted proxy.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	opts = withDefaults(opts)
+	client := &http.Client{
+		Timeout: opts.Timeout,
+		Transport: &http.Transport{
+			MaxIdleConns:        opts.Concurrency,
+			MaxIdleConnsPerHost: opts.Concurrency,
+		},
+	}
+	target := strings.TrimRight(opts.Target, "/")
+
+	if len(opts.AdIDs) == 0 {
+		ids, err := activeAds(ctx, client, target)
+		if err != nil {
+			return Report{}, err
+		}
+		opts.AdIDs = ids
+	}
+	gen := newGenerator(opts)
+
+	report := Report{
+		Target:    target,
+		TargetRPS: opts.RPS,
+		Kinds: map[string]*KindReport{
+			kindClick:      {Statuses: map[string]int{}},
+			kindImpression: {Statuses: map[string]int{}},
+		},
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, opts.Concurrency)
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Duration)
+	defer cancel()
+
+	// Sending whatever is due on each tick holds high rates that a ticker
+	// per request could not
+	tick := time.Duration(float64(time.Second) / opts.RPS)
+	if tick < 10*time.Millisecond {
+		tick = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	due := 0
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case now := <-ticker.C:
+			dueBy := int(now.Sub(start).Seconds() * opts.RPS)
+			for ; due < dueBy; due++ {
+				req := gen.next()
+				select {
+				case workers <- struct{}{}:
+				default:
+					report.Dropped++
+					continue
+				}
+				report.Sent++
+				wg.Add(1)
+				go func() {
+					defer func() { <-workers; wg.Done() }()
+					status, latency, err := send(client, target, req)
+					mu.Lock()
+					defer mu.Unlock()
+					kind := report.Kinds[req.kind]
+					kind.Requests++
+					if err != nil {
+						kind.Errors++
+						return
+					}
+					kind.Statuses[strconv.Itoa(status)]++
+					kind.latencies = append(kind.latencies, float64(latency.Microseconds())/1000)
+				}()
+			}
+		}
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	report.Duration = elapsed.Seconds()
+	report.RPS = float64(report.Sent) / elapsed.Seconds()
+	for _, kind := range report.Kinds {
+		kind.Latency = percentiles(kind.latencies)
+	}
+	return report, nil
+}
+
+func withDefaults(opts Options) Options {
+	if opts.Target == "" {
+		opts.Target = DefaultOptions.Target
+	}
+	if opts.RPS <= 0 {
+		opts.RPS = DefaultOptions.RPS
+	}
+	if opts.Duration <= 0 {
+		opts.Duration = DefaultOptions.Duration
+	}
+	if opts.ClickRatio < 0 || opts.ClickRatio > 1 {
+		opts.ClickRatio = DefaultOptions.ClickRatio
+	}
+	if opts.Users <= 1 {
+		opts.Users = DefaultOptions.Users
+	}
+	if opts.IPs <= 1 {
+		opts.IPs = DefaultOptions.IPs
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultOptions.Concurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	return opts
+}
+
+func send(client *http.Client, target string, req request) (int, time.Duration, error) {
+	path := "/api/v1/ads/impression"
+	if req.kind == kindClick {
+		path = "/api/v1/ads/click"
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, target+path, bytes.NewReader(req.body))
+	if err != nil {
+		return 0, 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", req.ua)
+	httpReq.Header.Set("X-Forwarded-For", req.ip)
+
+	start := time.Now()
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, time.Since(start), nil
+}
+
+func activeAds(ctx context.Context, client *http.Client, target string) ([]uint, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target+"/api/v1/ads", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("list ads: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("list ads: %s", resp.Status)
+	}
+
+	var body struct {
+		Ads []struct {
+			ID uint `json:"id"`
+		} `json:"ads"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("list ads: %w", err)
+	}
+	ids := make([]uint, 0, len(body.Ads))
+	for _, ad := range body.Ads {
+		ids = append(ids, ad.ID)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("target serves no active ads; seed some or pass ad ids")
+	}
+	return ids, nil
+}
+
+func percentiles(latencies []float64) Percentiles {
+	if len(latencies) == 0 {
+		return Percentiles{}
+	}
+	sort.Float64s(latencies)
+	at := func(q float64) float64 {
+		return latencies[int(math.Ceil(q*float64(len(latencies))))-1]
+	}
+	return Percentiles{P50: at(0.5), P90: at(0.9), P95: at(0.95), P99: at(0.99), Max: latencies[len(latencies)-1]}
+}
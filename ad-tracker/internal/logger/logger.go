@@ -8,16 +8,22 @@ func SetupLogger(logLevel string) *logrus.Logger {
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
+	logger.SetLevel(ParseLevel(logLevel))
+
+	return logger
+}
+
+// ParseLevel maps debug, warn and error to their logrus levels and anything
+// else to info.
+func ParseLevel(logLevel string) logrus.Level {
 	switch logLevel {
 	case "debug":
-		logger.SetLevel(logrus.DebugLevel)
+		return logrus.DebugLevel
 	case "warn":
-		logger.SetLevel(logrus.WarnLevel)
+		return logrus.WarnLevel
 	case "error":
-		logger.SetLevel(logrus.ErrorLevel)
+		return logrus.ErrorLevel
 	default:
-		logger.SetLevel(logrus.InfoLevel)
+		return logrus.InfoLevel
 	}
-
-	return logger
 }
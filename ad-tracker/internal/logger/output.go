@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SetOutput points logger at the configured output. The returned closer
+// closes the log file; it does nothing for console output.
+func SetOutput(logger *logrus.Logger, c config.LoggingConfig) (io.Closer, error) {
+	if c.Output == "console" {
+		return io.NopCloser(nil), nil
+	}
+	file, err := OpenRotatingFile(c.File, int64(c.MaxSizeMB)<<20, c.RotateEvery, c.MaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	if c.Output == "both" {
+		// The console goes first: MultiWriter stops at the first failing writer
+		logger.SetOutput(io.MultiWriter(os.Stderr, file))
+	} else {
+		logger.SetOutput(file)
+	}
+	return file, nil
+}
+
+// backupTimeFormat names rotated files; it sorts chronologically.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile appends to a log file, renaming it to name-<time>.ext and
+// starting a new one once it grows past maxBytes or crosses a boundary of
+// every. Only the newest maxBackups rotated files are kept.
+type RotatingFile struct {
+	path       string
+	maxBytes   int64
+	every      time.Duration
+	maxBackups int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	period time.Time
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory
+// as needed. Zero maxBytes or every disables that trigger; zero maxBackups
+// keeps every rotated file.
+func OpenRotatingFile(path string, maxBytes int64, every time.Duration, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, maxBytes: maxBytes, every: every, maxBackups: maxBackups}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create log directory: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("stat log file: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	// A file left by an earlier run belongs to the period it was last
+	// written in, so it is rotated on the first write of a later one
+	f.period = f.periodOf(info.ModTime())
+	if info.Size() == 0 {
+		f.period = f.periodOf(time.Now())
+	}
+	return nil
+}
+
+func (f *RotatingFile) periodOf(t time.Time) time.Time {
+	if f.every <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(f.every)
+}
+
+// Write appends p, rotating first when p would overflow the file or a new
+// period has started. A single write is never split across files. When
+// rotation fails p is still appended to the current file and the rotation
+// error returned, so logrus reports it on stderr.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	var rotateErr error
+	full := f.maxBytes > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxBytes
+	if full || !f.periodOf(time.Now()).Equal(f.period) {
+		rotateErr = f.rotate()
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, rotateErr
+}
+
+// rotate moves the current file aside and opens a new one. If the rename
+// fails the current file is kept until the next period or size trigger.
+func (f *RotatingFile) rotate() error {
+	renameErr := os.Rename(f.path, f.backupName(time.Now()))
+	if renameErr != nil {
+		f.period = f.periodOf(time.Now())
+		f.size = 0
+		return fmt.Errorf("rotate log file: %w", renameErr)
+	}
+	// The open handle still points at the renamed file
+	closeErr := f.file.Close()
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.prune()
+	if closeErr != nil {
+		return fmt.Errorf("close rotated log file: %w", closeErr)
+	}
+	return nil
+}
+
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	return strings.TrimSuffix(f.path, ext) + "-" + t.UTC().Format(backupTimeFormat) + ext
+}
+
+// prune removes the oldest rotated files beyond maxBackups. Failures only
+// leave extra files behind.
+func (f *RotatingFile) prune() {
+	if f.maxBackups <= 0 {
+		return
+	}
+	ext := filepath.Ext(f.path)
+	backups, err := filepath.Glob(strings.TrimSuffix(f.path, ext) + "-[0-9]*" + ext)
+	if err != nil || len(backups) <= f.maxBackups {
+		return
+	}
+	sort.Strings(backups)
+	for _, name := range backups[:len(backups)-f.maxBackups] {
+		os.Remove(name)
+	}
+}
+
+// Close closes the current file; later writes fail.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sampler thins out log lines written once per event or per analytics row,
+// whose volume would otherwise grow with traffic. Within each period the
+// first lines with a given message are written, then one in every
+// thereafter; a written line carries how many before it were suppressed.
+// A nil Sampler writes every line.
+type Sampler struct {
+	first      int
+	thereafter int
+	period     time.Duration
+	inDebug    bool
+
+	mu       sync.Mutex
+	counters map[string]*sampleCounter
+}
+
+type sampleCounter struct {
+	start      time.Time
+	seen       int
+	suppressed int
+}
+
+// NewSampler writes the first lines of each message per period, then every
+// thereafter-th (none when thereafter is 0). While the logger is at debug
+// level every line is kept, unless inDebug samples there as well.
+func NewSampler(first, thereafter int, period time.Duration, inDebug bool) *Sampler {
+	return &Sampler{
+		first:      first,
+		thereafter: thereafter,
+		period:     period,
+		inDebug:    inDebug,
+		counters:   make(map[string]*sampleCounter),
+	}
+}
+
+// Log writes msg through entry at level when the sample lets it through.
+// Messages should be constant: they are the sampling key.
+func (s *Sampler) Log(entry *logrus.Entry, level logrus.Level, msg string) {
+	if !entry.Logger.IsLevelEnabled(level) {
+		return
+	}
+	if s == nil || (!s.inDebug && entry.Logger.IsLevelEnabled(logrus.DebugLevel)) {
+		entry.Log(level, msg)
+		return
+	}
+	write, suppressed := s.allow(msg, time.Now())
+	if !write {
+		return
+	}
+	if suppressed > 0 {
+		entry = entry.WithField("suppressed", suppressed)
+	}
+	entry.Log(level, msg)
+}
+
+func (s *Sampler) allow(msg string, now time.Time) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.counters[msg]
+	if !ok {
+		counter = &sampleCounter{start: now}
+		s.counters[msg] = counter
+	}
+	if now.Sub(counter.start) >= s.period {
+		counter.start, counter.seen = now, 0
+	}
+	counter.seen++
+	if counter.seen <= s.first || (s.thereafter > 0 && (counter.seen-s.first)%s.thereafter == 0) {
+		suppressed := counter.suppressed
+		counter.suppressed = 0
+		return true, suppressed
+	}
+	counter.suppressed++
+	return false, 0
+}
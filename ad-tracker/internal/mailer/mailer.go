@@ -0,0 +1,102 @@
+// Package mailer sends plain-text emails with an optional attachment over
+// SMTP.
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Addr     string // host:port
+	From     string
+	Username string
+	Password string
+}
+
+type Attachment struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+type Mailer struct {
+	config Config
+}
+
+func New(config Config) *Mailer {
+	return &Mailer{config: config}
+}
+
+// Send delivers the message to every recipient. STARTTLS is used when the
+// server offers it; credentials are only sent with PLAIN auth over TLS or to
+// localhost, as enforced by net/smtp.
+func (m *Mailer) Send(to []string, subject, body string, attachment *Attachment) error {
+	if m == nil || m.config.Addr == "" {
+		return errors.New("SMTP is not configured")
+	}
+	if len(to) == 0 {
+		return errors.New("no recipients")
+	}
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		host, _, err := net.SplitHostPort(m.config.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, host)
+	}
+
+	message, err := m.compose(to, subject, body, attachment)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(m.config.Addr, auth, m.config.From, to, message)
+}
+
+func (m *Mailer) compose(to []string, subject, body string, attachment *Attachment) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	if attachment == nil {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+		buf.WriteString(body)
+		return buf.Bytes(), nil
+	}
+
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	boundary := "b" + hex.EncodeToString(raw)
+
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", boundary)
+	fmt.Fprintf(&buf, "--%s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n", boundary, body)
+	fmt.Fprintf(&buf, "--%s\r\n", boundary)
+	fmt.Fprintf(&buf, "Content-Type: %s\r\n", attachment.ContentType)
+	fmt.Fprintf(&buf, "Content-Disposition: attachment; filename=%q\r\n", attachment.Name)
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
@@ -29,12 +29,328 @@ var (
 		[]string{"method", "endpoint", "status_code"},
 	)
 
+	EventsRecorded = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ad_events_recorded_total",
+			Help: "Clicks and impressions accepted for storage, by type and campaign (empty for ads without one)",
+		},
+		[]string{"type", "campaign_id"},
+	)
+
+	DBQueryDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Duration of database statements issued through GORM, by operation and table",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"operation", "table"},
+	)
+
 	QueueSize = prometheus.NewGauge(
 		prometheus.GaugeOpts{
 			Name: "click_queue_size",
 			Help: "Current size of the click processing queue",
 		},
 	)
+	PodInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "k8s_pod_info",
+			Help: "Kubernetes downward API metadata for this replica, always 1",
+		},
+		[]string{"pod", "namespace", "node"},
+	)
+
+	LeaderElection = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "leader_election_is_leader",
+			Help: "Whether this replica currently holds the lease (1) or not (0)",
+		},
+		[]string{"lease"},
+	)
+	ReplicationLag = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "replication_lag_messages",
+			Help: "Messages on the primary topic not yet copied to the standby region",
+		},
+	)
+
+	ReplicationLastTimestamp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "replication_last_message_timestamp_seconds",
+			Help: "Produce time of the most recent message copied to the standby region",
+		},
+	)
+
+	ReplicationMessages = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "replication_messages_total",
+			Help: "Total number of messages copied to the standby region",
+		},
+	)
+
+	ReplicationSkipped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "replication_skipped_total",
+			Help: "Total number of messages not replicated because another region produced them",
+		},
+	)
+
+	SignedLinkRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "signed_link_rejections_total",
+			Help: "Tracking link requests rejected by signature verification",
+		},
+		[]string{"kind", "reason"},
+	)
+
+	FraudFlagged = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fraud_rule_hits_total",
+			Help: "Fraud rule matches on ingested events",
+		},
+		[]string{"event_type", "rule"},
+	)
+
+	FraudInvalidEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fraud_invalid_events_total",
+			Help: "Ingested events tagged invalid by fraud scoring",
+		},
+		[]string{"event_type"},
+	)
+
+	BlockedRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "blocklist_rejections_total",
+			Help: "Tracking requests rejected because the client IP is blocklisted",
+		},
+		[]string{"event_type"},
+	)
+
+	BotEventsDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bot_events_dropped_total",
+			Help: "Tracking events discarded because the User-Agent matched the bot list",
+		},
+		[]string{"event_type"},
+	)
+
+	HoneypotTriggers = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "honeypot_triggers_total",
+			Help: "Clicks on honeypot trap ads",
+		},
+	)
+
+	RetentionRowsPurged = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "retention_rows_purged_total",
+			Help: "Rows deleted or anonymized by the retention job",
+		},
+		[]string{"table", "action"},
+	)
+
+	RetentionRowsEligible = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "retention_rows_eligible",
+			Help: "Rows a dry-run retention pass would purge",
+		},
+		[]string{"table"},
+	)
+
+	RetentionLastRun = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "retention_last_run_timestamp_seconds",
+			Help: "Completion time of the last retention pass",
+		},
+	)
+
+	ArchivedRows = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "archive_rows_total",
+			Help: "Event rows exported to cold storage",
+		},
+		[]string{"table"},
+	)
+
+	ConsentStates = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "consent_states_total",
+			Help: "Tracking events by evaluated consent state",
+		},
+		[]string{"event_type", "state"},
+	)
+
+	IdentifiersWithheld = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "consent_identifiers_withheld_total",
+			Help: "Events stored without user id or IP because consent was absent",
+		},
+		[]string{"event_type"},
+	)
+
+	ReportRuns = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "report_runs_total",
+			Help: "Scheduled report delivery attempts by outcome",
+		},
+		[]string{"status"},
+	)
+
+	AlertNotifications = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "alert_notifications_total",
+			Help: "Alert notifications sent by channel and outcome",
+		},
+		[]string{"channel", "outcome"},
+	)
+
+	WebhookDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_deliveries_total",
+			Help: "Outbound webhook delivery attempts by event type and resulting status",
+		},
+		[]string{"event_type", "status"},
+	)
+
+	ForwardedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "forwarded_events_total",
+			Help: "Events forwarded to Segment or GA4 integrations by provider and outcome (sent, failed, dropped)",
+		},
+		[]string{"provider", "outcome"},
+	)
+
+	AccessLogRecords = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "access_log_records_total",
+			Help: "Access-log records mirrored to Kafka by outcome (published, failed, dropped)",
+		},
+		[]string{"outcome"},
+	)
+
+	AdCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ad_cache_requests_total",
+			Help: "Ad cache lookups by kind (active, ad) and result (hit, miss, error)",
+		},
+		[]string{"kind", "result"},
+	)
+
+	AnalyticsCacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analytics_cache_requests_total",
+			Help: "Analytics cache lookups by result (hit, stale, miss)",
+		},
+		[]string{"result"},
+	)
+
+	RollupCoveredUntil = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "rollup_covered_until_timestamp_seconds",
+			Help: "End of the last hour included in the event rollups",
+		},
+	)
+
+	ClientTimestamps = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "client_timestamps_out_of_range_total",
+			Help: "Client-supplied event timestamps outside the accepted window, by event type and whether they were clamped or rejected",
+		},
+		[]string{"event", "outcome"},
+	)
+
+	EventSchemaVersions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "event_schema_versions_total",
+			Help: "Events decoded from the event topic by type and the schema version they were published with",
+		},
+		[]string{"type", "version"},
+	)
+
+	SinkRows = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sink_rows_total",
+			Help: "Events written to event sinks (clickhouse, bigquery) by table and status (ok, error, dead_lettered)",
+		},
+		[]string{"sink", "table", "status"},
+	)
+
+	DatabaseUp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "database_up",
+			Help: "Whether the last database ping succeeded",
+		},
+	)
+
+	CircuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state by dependency (0 closed, 1 half-open, 2 open)",
+		},
+		[]string{"name"},
+	)
+
+	CircuitBreakerTransitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "circuit_breaker_transitions_total",
+			Help: "Circuit breaker state changes by dependency and new state",
+		},
+		[]string{"name", "state"},
+	)
+
+	CircuitBreakerRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "circuit_breaker_rejections_total",
+			Help: "Calls rejected without reaching the dependency because its breaker was open",
+		},
+		[]string{"name"},
+	)
+
+	ConfigReloads = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_reloads_total",
+			Help: "Configuration reloads triggered by SIGHUP, by result (success, failure)",
+		},
+		[]string{"result"},
+	)
+
+	BudgetPauses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "campaign_budget_pauses_total",
+			Help: "Campaigns paused for an exhausted budget, by budget (daily_budget, lifetime_budget)",
+		},
+		[]string{"budget"},
+	)
+
+	BudgetCounterErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "campaign_budget_counter_errors_total",
+			Help: "Spend and pacing updates that fell back to local decisions because the shared counter failed",
+		},
+	)
+
+	PacingThrottled = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "campaign_pacing_throttled_total",
+			Help: "Ad list responses that left out a campaign serving ahead of its daily impression pace",
+		},
+	)
+
+	FrequencyCapped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "campaign_frequency_capped_total",
+			Help: "Ad list responses that left out a campaign the user had reached a frequency cap on",
+		},
+	)
+
+	BuildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "build_info",
+			Help: "Version, commit and build time of the running binary, always 1",
+		},
+		[]string{"version", "commit", "build_time", "go_version"},
+	)
 )
 
 func init() {
@@ -42,4 +358,45 @@ func init() {
 	prometheus.MustRegister(ClicksProcessed)
 	prometheus.MustRegister(ResponseTime)
 	prometheus.MustRegister(QueueSize)
+	prometheus.MustRegister(EventsRecorded)
+	prometheus.MustRegister(DBQueryDuration)
+	prometheus.MustRegister(PodInfo)
+	prometheus.MustRegister(LeaderElection)
+	prometheus.MustRegister(ReplicationLag)
+	prometheus.MustRegister(ReplicationLastTimestamp)
+	prometheus.MustRegister(ReplicationMessages)
+	prometheus.MustRegister(ReplicationSkipped)
+	prometheus.MustRegister(SignedLinkRejections)
+	prometheus.MustRegister(FraudFlagged)
+	prometheus.MustRegister(FraudInvalidEvents)
+	prometheus.MustRegister(BlockedRequests)
+	prometheus.MustRegister(BotEventsDropped)
+	prometheus.MustRegister(HoneypotTriggers)
+	prometheus.MustRegister(RetentionRowsPurged)
+	prometheus.MustRegister(RetentionRowsEligible)
+	prometheus.MustRegister(RetentionLastRun)
+	prometheus.MustRegister(ArchivedRows)
+	prometheus.MustRegister(ConsentStates)
+	prometheus.MustRegister(IdentifiersWithheld)
+	prometheus.MustRegister(ReportRuns)
+	prometheus.MustRegister(AlertNotifications)
+	prometheus.MustRegister(WebhookDeliveries)
+	prometheus.MustRegister(ForwardedEvents)
+	prometheus.MustRegister(AccessLogRecords)
+	prometheus.MustRegister(AdCacheRequests)
+	prometheus.MustRegister(AnalyticsCacheRequests)
+	prometheus.MustRegister(RollupCoveredUntil)
+	prometheus.MustRegister(SinkRows)
+	prometheus.MustRegister(EventSchemaVersions)
+	prometheus.MustRegister(ClientTimestamps)
+	prometheus.MustRegister(DatabaseUp)
+	prometheus.MustRegister(CircuitBreakerState)
+	prometheus.MustRegister(CircuitBreakerTransitions)
+	prometheus.MustRegister(CircuitBreakerRejections)
+	prometheus.MustRegister(ConfigReloads)
+	prometheus.MustRegister(BudgetPauses)
+	prometheus.MustRegister(BudgetCounterErrors)
+	prometheus.MustRegister(PacingThrottled)
+	prometheus.MustRegister(FrequencyCapped)
+	prometheus.MustRegister(BuildInfo)
 }
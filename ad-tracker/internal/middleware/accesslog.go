@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"strings"
+	"time"
+
+	applog "ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/pii"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccessLogSink receives access-log records for the route groups it
+// enables; services.AccessLogMirror publishes them to Kafka.
+type AccessLogSink interface {
+	Enabled(group string) bool
+	Record(record models.AccessLogRecord)
+}
+
+// AccessLogMirrorMiddleware hands a record of every request in an enabled
+// route group to sink, with the client address minimized by ips. Register it
+// after RequestIDMiddleware so records carry the request id.
+func AccessLogMirrorMiddleware(sink AccessLogSink, ips *pii.IPMinimizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := RouteGroup(c.Request.URL.Path)
+		if !sink.Enabled(group) {
+			c.Next()
+			return
+		}
+		start := time.Now()
+		c.Next()
+
+		bytes := c.Writer.Size()
+		if bytes < 0 {
+			bytes = 0
+		}
+		sink.Record(models.AccessLogRecord{
+			Time:      start.UTC(),
+			RequestID: applog.RequestID(c.Request.Context()),
+			Group:     group,
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Route:     c.FullPath(),
+			Status:    c.Writer.Status(),
+			LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:     bytes,
+			ClientIP:  ips.Apply(c.ClientIP()),
+			UserAgent: c.Request.UserAgent(),
+		})
+	}
+}
+
+// RouteGroup names the group a request path falls in, matching how the
+// routes are grouped in main; unmatched paths count as public.
+func RouteGroup(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/api/v1/admin/"):
+		return models.AccessGroupAdmin
+	case strings.HasPrefix(path, "/api/v1/privacy/"):
+		return models.AccessGroupPrivacy
+	case strings.HasPrefix(path, "/internal/"):
+		return models.AccessGroupInternal
+	case strings.HasPrefix(path, "/api/v1/"):
+		return models.AccessGroupAPI
+	default:
+		return models.AccessGroupPublic
+	}
+}
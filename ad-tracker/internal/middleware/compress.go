@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompressionMiddleware gzip or deflate encodes responses for clients that
+// accept either, at the given compress/gzip level. It suits large, textual
+// responses such as analytics and event lists; leave it off tracking
+// pixels and redirects, which are tiny and latency bound.
+func CompressionMiddleware(level int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, level: level}
+		c.Writer = writer
+		defer func() {
+			writer.Close()
+			c.Writer = writer.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header,
+// preferring gzip at equal weight, or "" for neither.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		var candidates []string
+		switch name {
+		case "gzip", "deflate":
+			candidates = []string{name}
+		case "*":
+			candidates = []string{"gzip", "deflate"}
+		}
+		for _, candidate := range candidates {
+			if q > bestQ || (q == bestQ && q > 0 && candidate == "gzip") {
+				best, bestQ = candidate, q
+			}
+		}
+	}
+	return best
+}
+
+// compressWriter starts encoding with the first body write, so responses
+// without a body (304s, HEADs, redirects) and ones a handler already
+// encoded pass through untouched.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string
+	level    int
+	encoder  io.WriteCloser
+	bypass   bool
+}
+
+func (w *compressWriter) start() {
+	header := w.Header()
+	status := w.Status()
+	if header.Get("Content-Encoding") != "" || status == http.StatusNoContent || status == http.StatusNotModified {
+		w.bypass = true
+		return
+	}
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+
+	var err error
+	if w.encoding == "gzip" {
+		w.encoder, err = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	} else {
+		w.encoder, err = zlib.NewWriterLevel(w.ResponseWriter, w.level)
+	}
+	if err != nil {
+		// An invalid level; send the response unencoded
+		header.Del("Content-Encoding")
+		w.bypass = true
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.encoder == nil && !w.bypass {
+		w.start()
+	}
+	if w.bypass {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.encoder.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush pushes what has been encoded so far, for streamed exports.
+func (w *compressWriter) Flush() {
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Close() error {
+	if w.encoder == nil {
+		return nil
+	}
+	return w.encoder.Close()
+}
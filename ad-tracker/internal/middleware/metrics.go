@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests no route matched, so scanners probing
+// random paths add one series rather than one per path.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware times every request into the ResponseTime histogram,
+// labelled by route template (/api/v1/ads/:id/pixel, not the raw path).
+// Register it before RecoveryMiddleware so panics are counted as the 500
+// they become.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.ResponseTime.WithLabelValues(metricMethod(c.Request.Method), route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// metricMethod keeps the method label to the standard methods; anything
+// else a client invents is "other".
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
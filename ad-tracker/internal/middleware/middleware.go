@@ -1,12 +1,87 @@
 package middleware
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"time"
 
+	"ad-tracking-system/internal/iptrie"
+	applog "ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/sanitize"
+	"ad-tracking-system/internal/services"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// RecoveryMiddleware answers a panicking request with 500 and logs the
+// panic and stack through logger, instead of gin's plain-text writer.
+func RecoveryMiddleware(logger *logrus.Logger) gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered interface{}) {
+		applog.FromContext(c.Request.Context(), logger).WithFields(logrus.Fields{
+			"panic": fmt.Sprint(recovered),
+			"stack": string(debug.Stack()),
+		}).Error("Recovered from panic")
+		c.AbortWithStatus(http.StatusInternalServerError)
+	})
+}
+
+// RequestIDHeader carries the request id to and from clients and proxies.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds ids accepted from clients.
+const maxRequestIDLength = 64
+
+// RequestIDMiddleware tags each request with an id: a well-formed
+// X-Request-ID from the client or a proxy, or a new random one. The id is
+// echoed in the response and carried in the request context, where
+// logger.FromContext and the database log pick it up.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(applog.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("-_.:", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(raw)
+}
+
+// LoggingMiddleware writes one access log line per request, with the
+// request id and the matched route template alongside the raw path.
+// Server errors are logged at error level with any errors handlers
+// attached to the context.
 func LoggingMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -22,19 +97,85 @@ func LoggingMiddleware(logger *logrus.Logger) gin.HandlerFunc {
 			path = path + "?" + raw
 		}
 
-		logger.WithFields(logrus.Fields{
+		entry := applog.FromContext(c.Request.Context(), logger).WithFields(logrus.Fields{
 			"status_code": c.Writer.Status(),
 			"latency":     latency,
 			"client_ip":   c.ClientIP(),
 			"method":      c.Request.Method,
 			"path":        path,
-		}).Info("Request processed")
+			"route":       c.FullPath(),
+		})
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("errors", c.Errors.String())
+		}
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			entry.Error("Request failed")
+			return
+		}
+		entry.Info("Request processed")
 	}
 }
 
-func CORSMiddleware() gin.HandlerFunc {
+// BodyLimitMiddleware answers 413 to requests declaring a body over
+// maxBytes. Bodies without a declared length are cut off at maxBytes,
+// which fails the handler's decoding. NDJSON bodies are read
+// incrementally rather than decoded whole, so they get streamBytes
+// instead.
+func BodyLimitMiddleware(maxBytes, streamBytes int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		maxBytes := maxBytes
+		if c.ContentType() == NDJSONContentType {
+			maxBytes = streamBytes
+		}
+		if c.Request.ContentLength > maxBytes {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("request body exceeds %d bytes", maxBytes),
+			})
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		}
+		c.Next()
+	}
+}
+
+// NDJSONContentType is newline-delimited JSON, one value per line.
+const NDJSONContentType = "application/x-ndjson"
+
+// UserAgentMiddleware cleans the User-Agent header with sanitize.String
+// before anything reads it.
+func UserAgentMiddleware(maxLength int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ua := c.Request.Header.Get("User-Agent"); ua != "" {
+			c.Request.Header.Set("User-Agent", sanitize.String(ua, maxLength))
+		}
+		c.Next()
+	}
+}
+
+// CORSMiddleware answers preflights and sets CORS headers for the allowed
+// origins; "*" allows any origin. Requests from other origins get no
+// Access-Control-Allow-Origin header, so browsers block the response.
+func CORSMiddleware(allowedOrigins []string) gin.HandlerFunc {
+	allowAny := false
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			allowAny = true
+		}
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	return func(c *gin.Context) {
+		if allowAny {
+			c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Writer.Header().Add("Vary", "Origin")
+			if origin := c.GetHeader("Origin"); allowed[origin] {
+				c.Writer.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+		}
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT")
@@ -47,3 +188,116 @@ func CORSMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// ClientCertMiddleware admits requests that presented a client certificate
+// verified during the TLS handshake, optionally limited to certificates
+// whose common name, DNS or URI SAN is in names. Other requests go to
+// fallback, such as the admin token check, or are rejected when it is nil.
+func ClientCertMiddleware(names []string, fallback gin.HandlerFunc) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+
+	return func(c *gin.Context) {
+		if name, ok := verifiedClient(c.Request, allowed); ok {
+			c.Set("client_cert", name)
+			c.Next()
+			return
+		}
+		if fallback != nil {
+			fallback(c)
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Client certificate required"})
+	}
+}
+
+// verifiedClient returns the name the verified client certificate matched.
+func verifiedClient(r *http.Request, allowed map[string]bool) (string, bool) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	if len(allowed) == 0 {
+		return cert.Subject.CommonName, true
+	}
+	candidates := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		candidates = append(candidates, uri.String())
+	}
+	for _, name := range candidates {
+		if allowed[name] {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// AdminAuthMiddleware requires "Authorization: Bearer <token>". An empty
+// token disables the protected routes entirely.
+func AdminAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin API disabled"})
+			return
+		}
+
+		if !bearerMatches(c, token) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// HasAdminCredentials reports whether a request on a public route carries
+// the admin token or a verified client certificate, for routes that show
+// admins more than other callers.
+func HasAdminCredentials(c *gin.Context, token string, clientNames []string) bool {
+	if token != "" && bearerMatches(c, token) {
+		return true
+	}
+	allowed := make(map[string]bool, len(clientNames))
+	for _, name := range clientNames {
+		allowed[name] = true
+	}
+	_, ok := verifiedClient(c.Request, allowed)
+	return ok
+}
+
+func bearerMatches(c *gin.Context, token string) bool {
+	provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// IPAllowlistMiddleware answers 403 to requests from outside ranges (CIDRs
+// or bare addresses); no ranges allows everyone. The client address comes
+// from X-Forwarded-For only when forwarded is set, which must mean trusted
+// proxies are configured, since gin otherwise believes any client's header.
+func IPAllowlistMiddleware(ranges []string, forwarded bool) (gin.HandlerFunc, error) {
+	if len(ranges) == 0 {
+		return func(c *gin.Context) { c.Next() }, nil
+	}
+	allowed := iptrie.New()
+	for _, value := range ranges {
+		network, err := services.ParseRange(value)
+		if err != nil {
+			return nil, err
+		}
+		allowed.Insert(network)
+	}
+
+	return func(c *gin.Context) {
+		client := c.RemoteIP()
+		if forwarded {
+			client = c.ClientIP()
+		}
+		if !allowed.Contains(net.ParseIP(client)) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden"})
+			return
+		}
+		c.Next()
+	}, nil
+}
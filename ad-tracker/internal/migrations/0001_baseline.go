@@ -0,0 +1,391 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// baselineModels is the schema the service auto-migrated before versioned
+// migrations existed, in dependency order. The structs are a snapshot of
+// the models at that point, declared here so the baseline stays fixed as
+// the models change; later columns and tables come from later migrations.
+// They are local types because gorm names tables, indexes and constraints
+// after the type name, which must match the models'.
+func baselineModels() []interface{} {
+	type Account struct {
+		ID            uint   `gorm:"primaryKey"`
+		Name          string `gorm:"not null"`
+		SigningSecret string `gorm:"not null"`
+		Active        bool   `gorm:"default:true"`
+		PrivacyMode   string `gorm:"not null;default:standard"`
+		CreatedAt     time.Time
+		UpdatedAt     time.Time
+	}
+
+	type Campaign struct {
+		ID        uint   `gorm:"primaryKey"`
+		AccountID *uint  `gorm:"index"`
+		Name      string `gorm:"not null"`
+		Active    bool   `gorm:"default:true"`
+		// Pricing used to derive spend: valid clicks at CostPerClick plus valid
+		// impressions at CostPerMille per thousand.
+		CostPerClick float64
+		CostPerMille float64
+		CreatedAt    time.Time
+		UpdatedAt    time.Time
+	}
+
+	type Ad struct {
+		ID              uint   `gorm:"primaryKey"`
+		CampaignID      *uint  `gorm:"index"`
+		ImageURL        string `gorm:"not null"`
+		TargetURL       string `gorm:"not null"`
+		Title           string
+		Active          bool  `gorm:"default:true"`
+		DurationSeconds int64 // video length, 0 for static ads
+		// Honeypot ads are rendered invisibly; only bots click them.
+		Honeypot  bool `gorm:"default:false;index"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+
+	type ClickEvent struct {
+		ID                uint      `gorm:"primaryKey"`
+		ClickID           string    `gorm:"index"`
+		AdID              uint      `gorm:"not null;index"`
+		Timestamp         time.Time `gorm:"not null;index"`
+		UserID            string    `gorm:"index"`
+		IPAddress         string    `gorm:"index"`
+		VideoPlaybackTime int64     // in seconds
+		UserAgent         string
+		FraudScore        float64
+		FraudReasons      string
+		Invalid           bool   `gorm:"default:false;index"`
+		ConsentState      string `gorm:"index"`
+		ConsentString     string
+		Processed         bool `gorm:"default:false;index"`
+		CreatedAt         time.Time
+	}
+
+	type ImpressionEvent struct {
+		ID            uint      `gorm:"primaryKey"`
+		AdID          uint      `gorm:"not null;index"`
+		Timestamp     time.Time `gorm:"not null;index"`
+		UserID        string    `gorm:"index"`
+		IPAddress     string    `gorm:"index"`
+		UserAgent     string
+		TimeInViewMS  int64
+		PercentInView float64 // 0-100, share of pixels in view
+		FraudScore    float64
+		FraudReasons  string
+		Invalid       bool   `gorm:"default:false;index"`
+		ConsentState  string `gorm:"index"`
+		ConsentString string
+		CreatedAt     time.Time
+	}
+
+	type Conversion struct {
+		ID                uint   `gorm:"primaryKey"`
+		ClickID           string `gorm:"index"`
+		TransactionID     string `gorm:"index"`
+		UserID            string `gorm:"index"`
+		IPAddress         string `gorm:"index"`
+		Value             float64
+		Currency          string
+		Timestamp         time.Time `gorm:"not null;index"`
+		AttributedAdID    *uint     `gorm:"index"`
+		AttributionType   string
+		AttributedEventID *uint
+		AttributedAt      *time.Time // time of the touch that won
+		CreatedAt         time.Time
+	}
+
+	type ShareToken struct {
+		ID         uint   `gorm:"primaryKey"`
+		CampaignID uint   `gorm:"not null;index"`
+		TokenHash  string `gorm:"size:64;not null;uniqueIndex"`
+		Label      string
+		ExpiresAt  time.Time
+		RevokedAt  *time.Time
+		CreatedAt  time.Time
+	}
+
+	type Export struct {
+		ID          uint   `gorm:"primaryKey"`
+		Status      string `gorm:"not null;index"`
+		AdIDs       string // comma separated, empty for all ads
+		CampaignID  *uint
+		From        *time.Time
+		To          *time.Time
+		FilePath    string
+		Rows        int64
+		Bytes       int64
+		SHA256      string
+		Error       string
+		CreatedAt   time.Time
+		CompletedAt *time.Time
+	}
+
+	type ReplicationState struct {
+		Region    string `gorm:"primaryKey"`
+		Role      string `gorm:"not null"`
+		ChangedAt time.Time
+		ChangedBy string
+	}
+
+	type CapturedRequest struct {
+		ID         uint `gorm:"primaryKey"`
+		IncidentID uint `gorm:"not null;index"`
+		AdID       uint
+		ReceivedAt time.Time
+		Method     string
+		Path       string
+		Query      string
+		IPAddress  string
+		Headers    string // JSON object
+		Payload    string // JSON of the parsed request
+	}
+
+	type CaptureIncident struct {
+		ID        uint   `gorm:"primaryKey"`
+		AdID      uint   `gorm:"not null;index"`
+		Trigger   string // detector name or "manual"
+		Reason    string
+		StartedAt time.Time
+		EndsAt    time.Time
+		Captured  int64
+		Requests  []CapturedRequest `gorm:"foreignKey:IncidentID"`
+		CreatedAt time.Time
+	}
+
+	type BlockedRange struct {
+		ID        uint   `gorm:"primaryKey"`
+		AccountID *uint  `gorm:"index"`
+		CIDR      string `gorm:"not null"`
+		Reason    string
+		Source    string `gorm:"default:manual"`
+		// ExpiresAt is set for temporary blocks such as honeypot hits.
+		ExpiresAt *time.Time `gorm:"index"`
+		CreatedAt time.Time
+	}
+
+	type ArchivePartition struct {
+		ID          uint      `gorm:"primaryKey"`
+		TableName   string    `gorm:"size:64;not null;uniqueIndex:idx_archive_partition"`
+		Day         time.Time `gorm:"not null;uniqueIndex:idx_archive_partition"`
+		ObjectKey   string
+		ManifestKey string
+		Rows        int64
+		Bytes       int64
+		SHA256      string
+		MinID       uint
+		MaxID       uint
+		ArchivedAt  time.Time
+	}
+
+	type PrivacyRequest struct {
+		ID           uint   `gorm:"primaryKey"`
+		SubjectType  string `gorm:"not null"`
+		Subject      string
+		SubjectHash  string `gorm:"not null;index"`
+		Mode         string `gorm:"not null"`
+		Status       string `gorm:"not null;index"`
+		RowsAffected int64
+		Details      string // JSON object of rows per table
+		Format       string // export format, json or csv
+		FilePath     string
+		DownloadURL  string `gorm:"-"`
+		Error        string
+		RequestedBy  string
+		CreatedAt    time.Time
+		CompletedAt  *time.Time
+	}
+
+	type AuditEvent struct {
+		ID         uint   `gorm:"primaryKey"`
+		Actor      string `gorm:"not null"`
+		Action     string `gorm:"not null;index"`
+		TargetType string
+		TargetID   string    `gorm:"index"`
+		Details    string    // JSON object
+		CreatedAt  time.Time `gorm:"index"`
+	}
+
+	type Incident struct {
+		ID         uint   `gorm:"primaryKey"`
+		Title      string `gorm:"not null"`
+		Message    string
+		Component  string
+		Severity   string `gorm:"not null;default:minor"` // minor, major, critical
+		Active     bool   `gorm:"default:true;index"`
+		StartedAt  time.Time
+		ResolvedAt *time.Time
+		CreatedAt  time.Time
+		UpdatedAt  time.Time
+	}
+
+	type UptimeDay struct {
+		Component string    `gorm:"primaryKey"`
+		Day       time.Time `gorm:"primaryKey;type:date"`
+		Checks    int64
+		Successes int64
+	}
+
+	type ReportSchedule struct {
+		ID          uint   `gorm:"primaryKey"`
+		AccountID   uint   `gorm:"not null;index"`
+		Name        string `gorm:"not null"`
+		Cron        string `gorm:"not null"`
+		Timezone    string `gorm:"not null;default:UTC"`
+		Timeframe   string `gorm:"not null;default:24h"` // summary window, e.g. 24h or 7d
+		Format      string `gorm:"not null;default:csv"` // csv or json
+		ValidOnly   bool
+		Destination string     `gorm:"not null"`
+		Active      bool       `gorm:"default:true"`
+		NextRunAt   *time.Time `gorm:"index"`
+		LastRunAt   *time.Time
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+	}
+
+	type ReportRun struct {
+		ID          uint   `gorm:"primaryKey"`
+		ScheduleID  uint   `gorm:"not null;index"`
+		Status      string `gorm:"not null;index"`
+		Attempts    int
+		ScheduledAt time.Time
+		RetryAt     *time.Time `gorm:"index"`
+		Location    string
+		Error       string
+		CreatedAt   time.Time
+		CompletedAt *time.Time
+	}
+
+	type AlertRule struct {
+		ID              uint   `gorm:"primaryKey"`
+		Name            string `gorm:"not null"`
+		AccountID       *uint  `gorm:"index"`
+		CampaignID      *uint  `gorm:"index"`
+		AdID            *uint  `gorm:"index"`
+		Metric          string `gorm:"not null"`
+		Operator        string `gorm:"not null"`
+		Threshold       float64
+		Window          string `gorm:"not null"`
+		Channel         string `gorm:"not null"`
+		URL             string `gorm:"not null"`
+		Template        string
+		Cooldown        string // minimum time between repeat notifications
+		Active          bool   `gorm:"default:true"`
+		State           string `gorm:"not null;default:resolved"`
+		LastValue       float64
+		LastEvaluatedAt *time.Time
+		LastNotifiedAt  *time.Time
+		CreatedAt       time.Time
+		UpdatedAt       time.Time
+	}
+
+	type AlertEvent struct {
+		ID        uint   `gorm:"primaryKey"`
+		RuleID    uint   `gorm:"not null;index"`
+		State     string `gorm:"not null"`
+		Value     float64
+		Threshold float64
+		Delivered bool
+		Error     string
+		CreatedAt time.Time
+	}
+
+	type WebhookEndpoint struct {
+		ID         uint   `gorm:"primaryKey"`
+		AccountID  uint   `gorm:"not null;index"`
+		URL        string `gorm:"not null"`
+		EventTypes string `gorm:"not null"`
+		Secret     string `gorm:"not null"`
+		Active     bool   `gorm:"default:true"`
+		CreatedAt  time.Time
+		UpdatedAt  time.Time
+	}
+
+	type WebhookDelivery struct {
+		ID             uint   `gorm:"primaryKey"`
+		EndpointID     uint   `gorm:"not null;index"`
+		EventType      string `gorm:"not null"`
+		EventID        string `gorm:"index"`
+		Payload        string
+		Status         string `gorm:"not null;index"`
+		Attempts       int
+		NextAttemptAt  *time.Time `gorm:"index"`
+		LastStatusCode int
+		LastError      string
+		CreatedAt      time.Time
+		DeliveredAt    *time.Time
+	}
+
+	type EventRollup struct {
+		ID                 uint      `gorm:"primaryKey"`
+		Granularity        string    `gorm:"size:8;not null;uniqueIndex:idx_event_rollup_bucket,priority:1"`
+		BucketStart        time.Time `gorm:"not null;uniqueIndex:idx_event_rollup_bucket,priority:2"`
+		AdID               uint      `gorm:"not null;uniqueIndex:idx_event_rollup_bucket,priority:3"`
+		CampaignID         *uint     `gorm:"index"`
+		Clicks             int64
+		InvalidClicks      int64
+		Impressions        int64
+		InvalidImpressions int64
+		Conversions        int64
+		UpdatedAt          time.Time
+	}
+
+	type RollupCoverage struct {
+		ID           uint      `gorm:"primaryKey"`
+		CoveredUntil time.Time `gorm:"not null"`
+		UpdatedAt    time.Time
+	}
+
+	return []interface{}{
+		&Account{},
+		&Campaign{},
+		&Ad{},
+		&ClickEvent{},
+		&ImpressionEvent{},
+		&Conversion{},
+		&ShareToken{},
+		&Export{},
+		&ReplicationState{},
+		&CaptureIncident{},
+		&CapturedRequest{},
+		&BlockedRange{},
+		&ArchivePartition{},
+		&PrivacyRequest{},
+		&AuditEvent{},
+		&Incident{},
+		&UptimeDay{},
+		&ReportSchedule{},
+		&ReportRun{},
+		&AlertRule{},
+		&AlertEvent{},
+		&WebhookEndpoint{},
+		&WebhookDelivery{},
+		&EventRollup{},
+		&RollupCoverage{},
+	}
+}
+
+// baseline creates the tables, or brings a database created by the old
+// startup AutoMigrate up to the same shape, which is why it is not plain DDL.
+var baseline = Migration{
+	Version: 1,
+	Name:    "baseline",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(baselineModels()...)
+	},
+	Down: func(tx *gorm.DB) error {
+		models := baselineModels()
+		for i := len(models) - 1; i >= 0; i-- {
+			if err := tx.Migrator().DropTable(models[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// budgetColumns are the Campaign fields added for budgets and auto-pause.
+var budgetColumns = []string{
+	"DailyBudget",
+	"LifetimeBudget",
+	"DailySpend",
+	"SpendDate",
+	"LifetimeSpend",
+	"BudgetPausedAt",
+	"BudgetPauseReason",
+}
+
+// campaignBudgets adds daily and lifetime budgets with accumulated spend.
+var campaignBudgets = Migration{
+	Version: 2,
+	Name:    "campaign_budgets",
+	Up: func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+		for _, column := range budgetColumns {
+			if migrator.HasColumn(&models.Campaign{}, column) {
+				continue
+			}
+			if err := migrator.AddColumn(&models.Campaign{}, column); err != nil {
+				return err
+			}
+		}
+		if !migrator.HasIndex(&models.Campaign{}, "BudgetPausedAt") {
+			return migrator.CreateIndex(&models.Campaign{}, "BudgetPausedAt")
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+		if migrator.HasIndex(&models.Campaign{}, "BudgetPausedAt") {
+			if err := migrator.DropIndex(&models.Campaign{}, "BudgetPausedAt"); err != nil {
+				return err
+			}
+		}
+		for i := len(budgetColumns) - 1; i >= 0; i-- {
+			if err := migrator.DropColumn(&models.Campaign{}, budgetColumns[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
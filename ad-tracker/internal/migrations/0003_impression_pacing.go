@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// impressionPacing adds the daily impression target serving is paced
+// against.
+var impressionPacing = Migration{
+	Version: 3,
+	Name:    "impression_pacing",
+	Up: func(tx *gorm.DB) error {
+		if tx.Migrator().HasColumn(&models.Campaign{}, "DailyImpressionTarget") {
+			return nil
+		}
+		return tx.Migrator().AddColumn(&models.Campaign{}, "DailyImpressionTarget")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&models.Campaign{}, "DailyImpressionTarget")
+	},
+}
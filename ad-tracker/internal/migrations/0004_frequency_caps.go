@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var frequencyCapColumns = []string{"FrequencyCapHour", "FrequencyCapDay"}
+
+// frequencyCaps adds the per-user hourly and daily serving caps.
+var frequencyCaps = Migration{
+	Version: 4,
+	Name:    "frequency_caps",
+	Up: func(tx *gorm.DB) error {
+		for _, column := range frequencyCapColumns {
+			if tx.Migrator().HasColumn(&models.Campaign{}, column) {
+				continue
+			}
+			if err := tx.Migrator().AddColumn(&models.Campaign{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, column := range frequencyCapColumns {
+			if err := tx.Migrator().DropColumn(&models.Campaign{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
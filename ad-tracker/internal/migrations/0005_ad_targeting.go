@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// adTargeting adds the per-ad targeting rule, JSONB on Postgres.
+var adTargeting = Migration{
+	Version: 5,
+	Name:    "ad_targeting",
+	Up: func(tx *gorm.DB) error {
+		if tx.Migrator().HasColumn(&models.Ad{}, "Targeting") {
+			return nil
+		}
+		return tx.Migrator().AddColumn(&models.Ad{}, "Targeting")
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropColumn(&models.Ad{}, "Targeting")
+	},
+}
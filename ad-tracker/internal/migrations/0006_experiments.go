@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var experimentModels = []interface{}{
+	&models.Experiment{},
+	&models.ExperimentVariant{},
+	&models.ExperimentExposure{},
+}
+
+// experiments adds A/B experiments over ad variants and their exposure log.
+var experiments = Migration{
+	Version: 6,
+	Name:    "experiments",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(experimentModels...)
+	},
+	Down: func(tx *gorm.DB) error {
+		for i := len(experimentModels) - 1; i >= 0; i-- {
+			if err := tx.Migrator().DropTable(experimentModels[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
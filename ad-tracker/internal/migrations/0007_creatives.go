@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// creatives lets an ad rotate several creatives and records the one shown
+// on clicks and impressions.
+var creatives = Migration{
+	Version: 7,
+	Name:    "creatives",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.Creative{}); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			if tx.Migrator().HasColumn(model, "CreativeID") {
+				continue
+			}
+			if err := tx.Migrator().AddColumn(model, "CreativeID"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(model, "CreativeID"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			if tx.Migrator().HasIndex(model, "CreativeID") {
+				if err := tx.Migrator().DropIndex(model, "CreativeID"); err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().DropColumn(model, "CreativeID"); err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropTable(&models.Creative{})
+	},
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// placements adds publisher placements and records the one an event came
+// from on clicks and impressions.
+var placements = Migration{
+	Version: 8,
+	Name:    "placements",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.Placement{}); err != nil {
+			return err
+		}
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			if tx.Migrator().HasColumn(model, "PlacementID") {
+				continue
+			}
+			if err := tx.Migrator().AddColumn(model, "PlacementID"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(model, "PlacementID"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			if tx.Migrator().HasIndex(model, "PlacementID") {
+				if err := tx.Migrator().DropIndex(model, "PlacementID"); err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().DropColumn(model, "PlacementID"); err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropTable(&models.Placement{})
+	},
+}
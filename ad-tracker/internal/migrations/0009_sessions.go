@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// sessions records the session each click and impression belongs to.
+var sessions = Migration{
+	Version: 9,
+	Name:    "sessions",
+	Up: func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			if tx.Migrator().HasColumn(model, "SessionID") {
+				continue
+			}
+			if err := tx.Migrator().AddColumn(model, "SessionID"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().CreateIndex(model, "SessionID"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			if tx.Migrator().HasIndex(model, "SessionID") {
+				if err := tx.Migrator().DropIndex(model, "SessionID"); err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().DropColumn(model, "SessionID"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// integrations adds the per-account Segment and GA4 forwarding settings.
+var integrations = Migration{
+	Version: 10,
+	Name:    "integrations",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Integration{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&models.Integration{})
+	},
+}
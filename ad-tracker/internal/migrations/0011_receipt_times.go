@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// receiptTimes records when the server received each click and impression,
+// apart from the client-supplied event time. Existing rows take their
+// insert time.
+var receiptTimes = Migration{
+	Version: 11,
+	Name:    "receipt_times",
+	Up: func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			if tx.Migrator().HasColumn(model, "ReceivedAt") {
+				continue
+			}
+			if err := tx.Migrator().AddColumn(model, "ReceivedAt"); err != nil {
+				return err
+			}
+			if err := tx.Model(model).Where("received_at IS NULL").Update("received_at", gorm.Expr("created_at")).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			if err := tx.Migrator().DropColumn(model, "ReceivedAt"); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// adExternalRefs lets catalog imports find an advertiser's ads again by
+// their own id.
+var adExternalRefs = Migration{
+	Version: 12,
+	Name:    "ad_external_refs",
+	Up: func(tx *gorm.DB) error {
+		if !tx.Migrator().HasColumn(&models.Ad{}, "ExternalRef") {
+			if err := tx.Migrator().AddColumn(&models.Ad{}, "ExternalRef"); err != nil {
+				return err
+			}
+		}
+		if !tx.Migrator().HasIndex(&models.Ad{}, "ExternalRef") {
+			return tx.Migrator().CreateIndex(&models.Ad{}, "ExternalRef")
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		if tx.Migrator().HasIndex(&models.Ad{}, "ExternalRef") {
+			if err := tx.Migrator().DropIndex(&models.Ad{}, "ExternalRef"); err != nil {
+				return err
+			}
+		}
+		return tx.Migrator().DropColumn(&models.Ad{}, "ExternalRef")
+	},
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// adGroups adds ad groups within campaigns and records each ad's group.
+var adGroups = Migration{
+	Version: 13,
+	Name:    "ad_groups",
+	Up: func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(&models.AdGroup{}); err != nil {
+			return err
+		}
+		if !tx.Migrator().HasColumn(&models.Ad{}, "AdGroupID") {
+			if err := tx.Migrator().AddColumn(&models.Ad{}, "AdGroupID"); err != nil {
+				return err
+			}
+		}
+		if !tx.Migrator().HasIndex(&models.Ad{}, "AdGroupID") {
+			return tx.Migrator().CreateIndex(&models.Ad{}, "AdGroupID")
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		if tx.Migrator().HasIndex(&models.Ad{}, "AdGroupID") {
+			if err := tx.Migrator().DropIndex(&models.Ad{}, "AdGroupID"); err != nil {
+				return err
+			}
+		}
+		if err := tx.Migrator().DropColumn(&models.Ad{}, "AdGroupID"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable(&models.AdGroup{})
+	},
+}
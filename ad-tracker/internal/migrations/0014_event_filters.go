@@ -0,0 +1,50 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// eventFilterIndexes serve the campaign events endpoint's ad and user
+// filters over a time range.
+var eventFilterIndexes = map[interface{}][]string{
+	&models.ClickEvent{}:      {"idx_click_events_ad_timestamp", "idx_click_events_user_timestamp"},
+	&models.ImpressionEvent{}: {"idx_impression_events_ad_timestamp", "idx_impression_events_user_timestamp"},
+}
+
+// eventFilters indexes events by ad and by user with their timestamp, and
+// lets exports be limited to one user.
+var eventFilters = Migration{
+	Version: 14,
+	Name:    "event_filters",
+	Up: func(tx *gorm.DB) error {
+		for model, indexes := range eventFilterIndexes {
+			for _, index := range indexes {
+				if tx.Migrator().HasIndex(model, index) {
+					continue
+				}
+				if err := tx.Migrator().CreateIndex(model, index); err != nil {
+					return err
+				}
+			}
+		}
+		if tx.Migrator().HasColumn(&models.Export{}, "UserID") {
+			return nil
+		}
+		return tx.Migrator().AddColumn(&models.Export{}, "UserID")
+	},
+	Down: func(tx *gorm.DB) error {
+		for model, indexes := range eventFilterIndexes {
+			for _, index := range indexes {
+				if !tx.Migrator().HasIndex(model, index) {
+					continue
+				}
+				if err := tx.Migrator().DropIndex(model, index); err != nil {
+					return err
+				}
+			}
+		}
+		return tx.Migrator().DropColumn(&models.Export{}, "UserID")
+	},
+}
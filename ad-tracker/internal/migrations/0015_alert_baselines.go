@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var alertBaselineColumns = []string{"Baseline", "MinBaseline"}
+
+// alertBaselines lets alert rules compare a metric with the prior window.
+var alertBaselines = Migration{
+	Version: 15,
+	Name:    "alert_baselines",
+	Up: func(tx *gorm.DB) error {
+		for _, column := range alertBaselineColumns {
+			if tx.Migrator().HasColumn(&models.AlertRule{}, column) {
+				continue
+			}
+			if err := tx.Migrator().AddColumn(&models.AlertRule{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, column := range alertBaselineColumns {
+			if err := tx.Migrator().DropColumn(&models.AlertRule{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// anomalies stores the unusual hours found by anomaly detection.
+var anomalies = Migration{
+	Version: 16,
+	Name:    "anomalies",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Anomaly{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&models.Anomaly{})
+	},
+}
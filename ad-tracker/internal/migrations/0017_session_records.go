@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// sessionRecords adds the session summaries built by the sessionization
+// job.
+var sessionRecords = Migration{
+	Version: 17,
+	Name:    "session_records",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.Session{}, &models.SessionCoverage{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&models.SessionCoverage{}, &models.Session{})
+	},
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// identityGraph adds the links between user ids and hashed identifiers used
+// for cross-device attribution, and the opt-outs that keep them out.
+var identityGraph = Migration{
+	Version: 18,
+	Name:    "identity_graph",
+	Up: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&models.IdentityLink{}, &models.IdentityOptOut{})
+	},
+	Down: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable(&models.IdentityOptOut{}, &models.IdentityLink{})
+	},
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// rollupViewabilityColumns are the measured and viewable impression counts
+// that let long analytics windows read viewability from the rollups.
+var rollupViewabilityColumns = []string{"Measured", "InvalidMeasured", "Viewable", "InvalidViewable"}
+
+// rollupViewability adds viewability counts to the event rollups. Coverage
+// is reset so the next rollup run backfills them, and analytics reads raw
+// events until it has.
+var rollupViewability = Migration{
+	Version: 19,
+	Name:    "rollup_viewability",
+	Up: func(tx *gorm.DB) error {
+		for _, column := range rollupViewabilityColumns {
+			if tx.Migrator().HasColumn(&models.EventRollup{}, column) {
+				continue
+			}
+			if err := tx.Migrator().AddColumn(&models.EventRollup{}, column); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&models.RollupCoverage{}, 1).Error
+	},
+	Down: func(tx *gorm.DB) error {
+		for _, column := range rollupViewabilityColumns {
+			if err := tx.Migrator().DropColumn(&models.EventRollup{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
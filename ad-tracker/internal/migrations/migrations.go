@@ -0,0 +1,257 @@
+// Package migrations versions the database schema. Every migration has an up
+// and a down step and lives in its own file named after its version; the
+// versions applied to a database are recorded in schema_migrations.
+//
+// Schema changes go in a new migration, never only in the models: the
+// service refuses to start against a database that is behind. A migration
+// that creates tables from the live models must describe the same schema
+// forever, so when one of those models later changes, the migration keeps
+// a snapshot of the old struct, as the baseline does.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ErrOutOfDate is returned by Check when migrations are pending.
+var ErrOutOfDate = errors.New("database schema is out of date")
+
+// Migration is one schema change. Up and Down run inside a transaction
+// where the database supports transactional DDL; elsewhere, such as on
+// MySQL, a failed step may have applied part of its DDL, so steps skip
+// columns and indexes that already exist to be safe to re-run.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// all lists every migration; append new ones here.
+var all = []Migration{
+	baseline,
+	campaignBudgets,
+	impressionPacing,
+	frequencyCaps,
+	adTargeting,
+	experiments,
+	creatives,
+	placements,
+	sessions,
+	integrations,
+	receiptTimes,
+	adExternalRefs,
+	adGroups,
+	eventFilters,
+	alertBaselines,
+	anomalies,
+	sessionRecords,
+	identityGraph,
+	rollupViewability,
+}
+
+// schemaMigration records an applied migration.
+type schemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status describes one migration for `migrate status`.
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+	logger     *logrus.Logger
+}
+
+func New(db *gorm.DB, logger *logrus.Logger) *Migrator {
+	migrations := append([]Migration(nil), all...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return &Migrator{db: db, migrations: migrations, logger: logger}
+}
+
+// Latest is the version this binary expects.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current returns the highest applied version, 0 for a fresh database.
+func (m *Migrator) Current() (int, error) {
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return 0, nil
+	}
+	var version int
+	err := m.db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Check returns ErrOutOfDate when the database is behind this binary. A
+// schema ahead of the binary is accepted so old replicas keep serving
+// during a rollout.
+func (m *Migrator) Check() error {
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+	if current < m.Latest() {
+		return fmt.Errorf("%w: at version %d, want %d; run `migrate up`", ErrOutOfDate, current, m.Latest())
+	}
+	return nil
+}
+
+// Status lists every known migration with its applied time, if any.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			appliedAt := record.AppliedAt
+			statuses[i].AppliedAt = &appliedAt
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies every pending migration in order and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	ran := 0
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+			return err
+		}
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(db, migration, true); err != nil {
+				return err
+			}
+			ran++
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// Down reverts the last steps applied migrations and returns how many ran.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	ran := 0
+	err := m.locked(ctx, func(db *gorm.DB) error {
+		applied, err := m.applied(db)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && ran < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.run(db, migration, false); err != nil {
+				return err
+			}
+			ran++
+		}
+		return nil
+	})
+	return ran, err
+}
+
+func (m *Migrator) run(db *gorm.DB, migration Migration, up bool) error {
+	direction, step := "up", migration.Up
+	if !up {
+		direction, step = "down", migration.Down
+	}
+	log := m.logger.WithFields(logrus.Fields{"version": migration.Version, "name": migration.Name, "direction": direction})
+	start := time.Now()
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := step(tx); err != nil {
+			return err
+		}
+		if up {
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+		}
+		return tx.Delete(&schemaMigration{}, migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d %s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+	log.WithField("duration", time.Since(start)).Info("Migration applied")
+	return nil
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int]schemaMigration, error) {
+	applied := make(map[int]schemaMigration)
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+	var records []schemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+// locked runs fn holding a database-wide lock so replicas starting together
+// do not apply the same migration twice. The lock is tied to a session, so
+// fn gets a single pinned connection.
+func (m *Migrator) locked(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return m.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		conn = conn.Session(&gorm.Session{NewDB: true})
+		var lock, unlock string
+		switch conn.Dialector.Name() {
+		case "postgres":
+			lock, unlock = "SELECT pg_advisory_lock(?)", "SELECT pg_advisory_unlock(?)"
+		case "mysql":
+			lock, unlock = "SELECT GET_LOCK(?, -1)", "SELECT RELEASE_LOCK(?)"
+		default:
+			return fn(conn)
+		}
+
+		key := lockKey(conn.Dialector.Name())
+		if err := conn.Exec(lock, key).Error; err != nil {
+			return fmt.Errorf("acquire migration lock: %w", err)
+		}
+		defer conn.Exec(unlock, key)
+		return fn(conn)
+	})
+}
+
+// lockKey is a bigint for pg_advisory_lock and a name for MySQL GET_LOCK.
+func lockKey(dialect string) interface{} {
+	if dialect == "postgres" {
+		return int64(0x61645f6d6967) // "ad_mig"
+	}
+	return "ad_tracker_migrations"
+}
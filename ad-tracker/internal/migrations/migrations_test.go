@@ -0,0 +1,188 @@
+package migrations_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/migrations"
+	"ad-tracking-system/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// currentModels are every table the service reads and writes.
+var currentModels = []interface{}{
+	&models.Account{}, &models.Campaign{}, &models.AdGroup{}, &models.Ad{}, &models.Creative{}, &models.Placement{},
+	&models.ClickEvent{}, &models.ImpressionEvent{}, &models.Conversion{}, &models.ShareToken{}, &models.Export{},
+	&models.ReplicationState{}, &models.CaptureIncident{}, &models.CapturedRequest{}, &models.BlockedRange{},
+	&models.ArchivePartition{}, &models.PrivacyRequest{}, &models.AuditEvent{}, &models.Incident{}, &models.UptimeDay{},
+	&models.ReportSchedule{}, &models.ReportRun{}, &models.AlertRule{}, &models.AlertEvent{},
+	&models.WebhookEndpoint{}, &models.WebhookDelivery{}, &models.EventRollup{}, &models.RollupCoverage{},
+	&models.Experiment{}, &models.ExperimentVariant{}, &models.ExperimentExposure{}, &models.Integration{},
+	&models.Anomaly{}, &models.Session{}, &models.SessionCoverage{}, &models.IdentityLink{}, &models.IdentityOptOut{},
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.SetupDatabase(database.DriverSQLite, "file::memory:", database.PoolConfig{}, discard())
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func discard() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
+
+// schema describes every table's columns and indexes, one line each, in a
+// form that ignores their order.
+func schema(t *testing.T, db *gorm.DB) map[string][]string {
+	t.Helper()
+	var tables []string
+	if err := db.Raw(`SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' AND name <> 'schema_migrations'`).
+		Scan(&tables).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	described := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		var columns []struct {
+			Name      string
+			Type      string
+			NotNull   bool `gorm:"column:notnull"`
+			DfltValue *string
+			PK        int `gorm:"column:pk"`
+		}
+		if err := db.Raw("PRAGMA table_info(" + table + ")").Scan(&columns).Error; err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, column := range columns {
+			dflt := "<nil>"
+			if column.DfltValue != nil {
+				dflt = *column.DfltValue
+			}
+			lines = append(lines, fmt.Sprintf("column %s %s notnull=%v default=%s pk=%d",
+				column.Name, strings.ToLower(column.Type), column.NotNull, dflt, column.PK))
+		}
+
+		var indexes []struct {
+			Name   string
+			Unique bool
+		}
+		if err := db.Raw("PRAGMA index_list(" + table + ")").Scan(&indexes).Error; err != nil {
+			t.Fatal(err)
+		}
+		for _, index := range indexes {
+			if strings.HasPrefix(index.Name, "sqlite_autoindex_") {
+				continue
+			}
+			var indexColumns []string
+			if err := db.Raw("SELECT name FROM pragma_index_info(?) ORDER BY seqno", index.Name).Scan(&indexColumns).Error; err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, fmt.Sprintf("index %s unique=%v (%s)", index.Name, index.Unique, strings.Join(indexColumns, ", ")))
+		}
+		sort.Strings(lines)
+		described[table] = lines
+	}
+	return described
+}
+
+// TestMigrationsMatchModels checks that migrating a fresh database yields
+// the schema of the current models, so no model change lacks a migration.
+func TestMigrationsMatchModels(t *testing.T) {
+	migrated := openDB(t)
+	if _, err := migrations.New(migrated, discard()).Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	modeled := openDB(t)
+	if err := modeled.AutoMigrate(currentModels...); err != nil {
+		t.Fatalf("auto-migrate models: %v", err)
+	}
+
+	got, want := schema(t, migrated), schema(t, modeled)
+	for table, wantLines := range want {
+		gotLines, ok := got[table]
+		if !ok {
+			t.Errorf("migrations do not create table %s", table)
+			continue
+		}
+		if diff := lineDiff(gotLines, wantLines); diff != "" {
+			t.Errorf("table %s differs from its model:\n%s", table, diff)
+		}
+	}
+	for table := range got {
+		if _, ok := want[table]; !ok {
+			t.Errorf("migrations create table %s, which no model describes", table)
+		}
+	}
+}
+
+// TestMigrationsDownAndUp reverts every migration and applies them again.
+func TestMigrationsDownAndUp(t *testing.T) {
+	db := openDB(t)
+	migrator := migrations.New(db, discard())
+	ctx := context.Background()
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	before := schema(t, db)
+
+	if _, err := migrator.Down(ctx, migrator.Latest()); err != nil {
+		t.Fatalf("migrate down: %v", err)
+	}
+	if left := schema(t, db); len(left) != 0 {
+		tables := make([]string, 0, len(left))
+		for table := range left {
+			tables = append(tables, table)
+		}
+		t.Errorf("tables left after migrating down: %v", tables)
+	}
+
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate up again: %v", err)
+	}
+	after := schema(t, db)
+	for table, lines := range before {
+		if diff := lineDiff(after[table], lines); diff != "" {
+			t.Errorf("table %s differs after down and up:\n%s", table, diff)
+		}
+	}
+}
+
+// lineDiff lists the lines only in got (+) or only in want (-).
+func lineDiff(got, want []string) string {
+	seen := make(map[string]int)
+	for _, line := range want {
+		seen[line]--
+	}
+	for _, line := range got {
+		seen[line]++
+	}
+	var diff []string
+	for line, n := range seen {
+		switch {
+		case n > 0:
+			diff = append(diff, "+ "+line)
+		case n < 0:
+			diff = append(diff, "- "+line)
+		}
+	}
+	sort.Strings(diff)
+	return strings.Join(diff, "\n")
+}
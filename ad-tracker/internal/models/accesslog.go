@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Access-log route groups, which select the requests mirrored to Kafka.
+const (
+	AccessGroupAPI      = "api"
+	AccessGroupAdmin    = "admin"
+	AccessGroupPrivacy  = "privacy"
+	AccessGroupInternal = "internal"
+	AccessGroupPublic   = "public"
+)
+
+// AccessLogGroups lists every route group, in the default mirroring order.
+var AccessLogGroups = []string{AccessGroupAPI, AccessGroupAdmin, AccessGroupPrivacy, AccessGroupInternal, AccessGroupPublic}
+
+// AccessLogRecord is one served request as published to the access-log
+// topic. The query string is left out: it carries link signatures and
+// share tokens.
+type AccessLogRecord struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Group     string    `json:"group"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Route     string    `json:"route"` // empty when no route matched
+	Status    int       `json:"status"`
+	LatencyMS float64   `json:"latency_ms"`
+	Bytes     int       `json:"bytes"`
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+}
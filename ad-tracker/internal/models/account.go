@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Account is an advertiser tenant. Campaigns belong to an account and its
+// signing secret authenticates the tracking links generated for its ads.
+type Account struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Name          string    `json:"name" gorm:"not null"`
+	SigningSecret string    `json:"-" gorm:"not null"`
+	Active        bool      `json:"active" gorm:"default:true"`
+	PrivacyMode   string    `json:"privacy_mode" gorm:"not null;default:standard"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Account privacy modes. Strict accounts never store user ids or IP
+// addresses for events that lack storage consent.
+const (
+	PrivacyModeStandard = "standard"
+	PrivacyModeStrict   = "strict"
+)
+
+type AccountRequest struct {
+	Name        string `json:"name" binding:"required"`
+	PrivacyMode string `json:"privacy_mode" binding:"omitempty,oneof=standard strict"`
+}
+
+type AccountUpdateRequest struct {
+	PrivacyMode string `json:"privacy_mode" binding:"required,oneof=standard strict"`
+}
@@ -1,32 +1,108 @@
 package models
 
-import "time"
+import (
+	"net/url"
+	"time"
+
+	"ad-tracking-system/internal/targeting"
+)
 
 type Ad struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	ImageURL  string    `json:"image_url" gorm:"not null"`
-	TargetURL string    `json:"target_url" gorm:"not null"`
-	Title     string    `json:"title"`
-	Active    bool      `json:"active" gorm:"default:true"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint   `json:"id" gorm:"primaryKey"`
+	CampaignID      *uint  `json:"campaign_id,omitempty" gorm:"index"`
+	AdGroupID       *uint  `json:"ad_group_id,omitempty" gorm:"index"`
+	ImageURL        string `json:"image_url" gorm:"not null"`
+	TargetURL       string `json:"target_url" gorm:"not null"`
+	Title           string `json:"title"`
+	Active          bool   `json:"active" gorm:"default:true"`
+	DurationSeconds int64  `json:"duration_seconds"` // video length, 0 for static ads
+	// ExternalRef is the advertiser's own id for the ad, unique within its
+	// campaign; catalog imports update the ad carrying it.
+	ExternalRef string `json:"external_ref,omitempty" gorm:"size:128;index"`
+	// Honeypot ads are rendered invisibly; only bots click them.
+	Honeypot bool `json:"-" gorm:"default:false;index"`
+	// Targeting limits which serve requests the ad is eligible for; nil
+	// serves it everywhere.
+	Targeting *targeting.Rule `json:"targeting,omitempty"`
+	// Creatives are rotated at serve time by weight; Creative is the one
+	// picked for a response.
+	Creatives []Creative `json:"creatives,omitempty" gorm:"foreignKey:AdID"`
+	Creative  *Creative  `json:"creative,omitempty" gorm:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
 
 type ClickEvent struct {
 	ID                uint      `json:"id" gorm:"primaryKey"`
-	AdID              uint      `json:"ad_id" gorm:"not null;index"`
-	Timestamp         time.Time `json:"timestamp" gorm:"not null;index"`
-	IPAddress         string    `json:"ip_address"`
+	ClickID           string    `json:"click_id" gorm:"index"`
+	AdID              uint      `json:"ad_id" gorm:"not null;index;index:idx_click_events_ad_timestamp,priority:1"`
+	Timestamp         time.Time `json:"timestamp" gorm:"not null;index;index:idx_click_events_ad_timestamp,priority:2;index:idx_click_events_user_timestamp,priority:2"`
+	ReceivedAt        time.Time `json:"received_at"` // server receipt; Timestamp may be the client's
+	UserID            string    `json:"user_id,omitempty" gorm:"index;index:idx_click_events_user_timestamp,priority:1"`
+	IPAddress         string    `json:"ip_address" gorm:"index"`
 	VideoPlaybackTime int64     `json:"video_playback_time"` // in seconds
+	CreativeID        *uint     `json:"creative_id,omitempty" gorm:"index"`
+	PlacementID       *uint     `json:"placement_id,omitempty" gorm:"index"`
+	SessionID         string    `json:"session_id,omitempty" gorm:"size:64;index"`
 	UserAgent         string    `json:"user_agent"`
+	FraudScore        float64   `json:"fraud_score"`
+	FraudReasons      string    `json:"fraud_reasons,omitempty"`
+	Invalid           bool      `json:"invalid" gorm:"default:false;index"`
+	ConsentState      string    `json:"consent_state,omitempty" gorm:"index"`
+	ConsentString     string    `json:"consent_string,omitempty"`
 	Processed         bool      `json:"processed" gorm:"default:false;index"`
 	CreatedAt         time.Time `json:"created_at"`
 }
 
 type ClickRequest struct {
-	AdID              uint  `json:"ad_id" binding:"required"`
-	Timestamp         int64 `json:"timestamp"`
-	VideoPlaybackTime int64 `json:"video_playback_time"`
+	AdID              uint       `json:"ad_id" binding:"required"`
+	Timestamp         ClientTime `json:"timestamp"`
+	UserID            string     `json:"user_id" binding:"max=256"`
+	VideoPlaybackTime int64      `json:"video_playback_time" binding:"min=0,max=86400"`
+	CreativeID        *uint      `json:"creative_id" binding:"omitempty,min=1"`
+	Placement         string     `json:"placement" binding:"omitempty,max=64,identifier"`
+	SessionID         string     `json:"session_id" binding:"omitempty,max=64,identifier"`
+	ConsentParams
+	IdentityHints
+	LinkSignature
+}
+
+// LinkSignature is the ts/sig pair of a signed tracking link. JSON tags copy
+// it from the ad's redirect link for clicks and from its pixel link for
+// impressions, so they cannot record events for ads they were not served.
+type LinkSignature struct {
+	TS  string `json:"ts,omitempty" binding:"max=20"`
+	Sig string `json:"sig,omitempty" binding:"max=128"`
+}
+
+// OrQuery returns s, or the ts and sig of query when the body carried
+// neither.
+func (s LinkSignature) OrQuery(query url.Values) LinkSignature {
+	if s.TS == "" && s.Sig == "" {
+		return LinkSignature{TS: query.Get("ts"), Sig: query.Get("sig")}
+	}
+	return s
+}
+
+// Params returns the signature with the user id it binds, in the form
+// signing.Verify reads.
+func (s LinkSignature) Params(userID string) url.Values {
+	params := url.Values{}
+	params.Set("ts", s.TS)
+	params.Set("sig", s.Sig)
+	if userID != "" {
+		params.Set("user_id", userID)
+	}
+	return params
+}
+
+// ConsentParams are the OpenRTB-style consent signals a tag may send with a
+// tracking request.
+type ConsentParams struct {
+	GDPR        *int   `json:"gdpr" form:"gdpr" binding:"omitempty,oneof=0 1"`
+	GDPRConsent string `json:"gdpr_consent" form:"gdpr_consent" binding:"max=4096"`
+	GPP         string `json:"gpp" form:"gpp" binding:"max=4096"`
+	GPPSID      string `json:"gpp_sid" form:"gpp_sid" binding:"max=64"`
 }
 
 type AnalyticsResponse struct {
@@ -35,4 +111,24 @@ type AnalyticsResponse struct {
 	CTR        float64 `json:"ctr,omitempty"`
 	LastHour   int64   `json:"last_hour"`
 	LastDay    int64   `json:"last_day"`
+
+	Impressions int64             `json:"impressions"`
+	Viewability *ViewabilityStats `json:"viewability,omitempty"`
+	Playback    *PlaybackStats    `json:"playback,omitempty"`
+	Sessions    *SessionStats     `json:"sessions,omitempty"`
+
+	// ValidOnly is set when events tagged invalid by fraud scoring were
+	// excluded from every figure above.
+	ValidOnly bool `json:"valid_only,omitempty"`
+}
+
+// PlaybackStats aggregates VideoPlaybackTime over clicks that reported one.
+type PlaybackStats struct {
+	Samples       int64   `json:"samples"`
+	AvgSeconds    float64 `json:"avg_seconds"`
+	MedianSeconds float64 `json:"median_seconds"`
+	P90Seconds    float64 `json:"p90_seconds"`
+	// CompletionRate is the share of samples that watched the full ad
+	// duration. Omitted when the ad has no duration configured.
+	CompletionRate float64 `json:"completion_rate,omitempty"`
 }
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// AdGroup groups a campaign's ads the way buyers structure them, for
+// example by audience or theme. Pausing a group stops serving its ads.
+type AdGroup struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	CampaignID uint      `json:"campaign_id" gorm:"not null;index"`
+	Name       string    `json:"name" gorm:"not null"`
+	Active     bool      `json:"active" gorm:"default:true"`
+	AdIDs      []uint    `json:"ad_ids" gorm:"-"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type AdGroupRequest struct {
+	CampaignID uint   `json:"campaign_id" binding:"required"`
+	Name       string `json:"name" binding:"required,max=255"`
+	Active     *bool  `json:"active"`
+}
+
+// AdGroupUpdateRequest renames a group or takes it in or out of service;
+// omitted fields are kept.
+type AdGroupUpdateRequest struct {
+	Name   *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Active *bool   `json:"active"`
+}
+
+// AdGroupAdsRequest replaces a group's ads. Every ad must belong to the
+// group's campaign; ads in another group are moved.
+type AdGroupAdsRequest struct {
+	AdIDs []uint `json:"ad_ids" binding:"required,max=1000,dive,min=1"`
+}
+
+type AdGroupSummary struct {
+	AdGroupID  uint                `json:"ad_group_id"`
+	CampaignID uint                `json:"campaign_id"`
+	Name       string              `json:"name"`
+	Since      time.Time           `json:"since"`
+	ClickCount int64               `json:"click_count"`
+	LastHour   int64               `json:"last_hour"`
+	LastDay    int64               `json:"last_day"`
+	Ads        []AnalyticsResponse `json:"ads"`
+}
@@ -0,0 +1,111 @@
+package models
+
+import "time"
+
+const (
+	AlertMetricCTR         = "ctr"
+	AlertMetricClicks      = "clicks"
+	AlertMetricImpressions = "impressions"
+	AlertMetricConversions = "conversions"
+	AlertMetricSpend       = "spend"
+	AlertMetricInvalidRate = "invalid_rate"
+
+	AlertBelow = "below"
+	AlertAbove = "above"
+
+	AlertBaselinePrevious = "previous"
+	AlertBaselineLastWeek = "last_week"
+
+	AlertChannelSlack   = "slack"
+	AlertChannelWebhook = "webhook"
+
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// AlertRule compares a metric over a trailing window with a threshold, e.g.
+// "ctr below 0.005 over 24h" or "clicks below 1 over 6h" for zero traffic.
+// The scope is one ad, one campaign, one account or, with none set, all
+// traffic.
+//
+// With a Baseline the rule compares the metric with the comparable prior
+// window instead, the one just before ("previous") or the same window a
+// week earlier ("last_week"), and Threshold is a percentage change: "clicks
+// below -99 over 6h against previous" flags a campaign whose traffic
+// stopped, e.g. after its tag broke, and "ctr below -50" one whose CTR
+// halved. Baselines under MinBaseline are too small to compare and the rule
+// is skipped. Template is an optional Go text/template rendered with an
+// AlertNotification; for Slack it becomes the message text, for webhooks the
+// request body.
+type AlertRule struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	Name            string     `json:"name" gorm:"not null"`
+	AccountID       *uint      `json:"account_id,omitempty" gorm:"index"`
+	CampaignID      *uint      `json:"campaign_id,omitempty" gorm:"index"`
+	AdID            *uint      `json:"ad_id,omitempty" gorm:"index"`
+	Metric          string     `json:"metric" gorm:"not null"`
+	Operator        string     `json:"operator" gorm:"not null"`
+	Threshold       float64    `json:"threshold"`
+	Window          string     `json:"window" gorm:"not null"`
+	Baseline        string     `json:"baseline,omitempty"`
+	MinBaseline     float64    `json:"min_baseline,omitempty"`
+	Channel         string     `json:"channel" gorm:"not null"`
+	URL             string     `json:"url" gorm:"not null"`
+	Template        string     `json:"template,omitempty"`
+	Cooldown        string     `json:"cooldown,omitempty"` // minimum time between repeat notifications
+	Active          bool       `json:"active" gorm:"default:true"`
+	State           string     `json:"state" gorm:"not null;default:resolved"`
+	LastValue       float64    `json:"last_value"`
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	LastNotifiedAt  *time.Time `json:"last_notified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type AlertRuleRequest struct {
+	Name        string  `json:"name" binding:"required"`
+	AccountID   *uint   `json:"account_id"`
+	CampaignID  *uint   `json:"campaign_id"`
+	AdID        *uint   `json:"ad_id"`
+	Metric      string  `json:"metric" binding:"required,oneof=ctr clicks impressions conversions spend invalid_rate"`
+	Operator    string  `json:"operator" binding:"required,oneof=below above"`
+	Threshold   float64 `json:"threshold"`
+	Window      string  `json:"window" binding:"required"`
+	Baseline    string  `json:"baseline" binding:"omitempty,oneof=previous last_week"`
+	MinBaseline float64 `json:"min_baseline" binding:"min=0"`
+	Channel     string  `json:"channel" binding:"required,oneof=slack webhook"`
+	URL         string  `json:"url" binding:"required,url"`
+	Template    string  `json:"template"`
+	Cooldown    string  `json:"cooldown"`
+	Active      *bool   `json:"active"`
+}
+
+// AlertEvent records each state change of a rule and its delivery outcome.
+type AlertEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	RuleID    uint      `json:"rule_id" gorm:"not null;index"`
+	State     string    `json:"state" gorm:"not null"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Delivered bool      `json:"delivered"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AlertNotification is the data available to alert templates.
+type AlertNotification struct {
+	Rule      AlertRule `json:"rule"`
+	State     string    `json:"state"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Window    string    `json:"window"`
+	// Current and BaselineValue are the metric over the window and over the
+	// baseline window of rules with a Baseline; Value is then their change
+	// in percent
+	Current       *float64  `json:"current,omitempty"`
+	BaselineValue *float64  `json:"baseline_value,omitempty"`
+	Scope         string    `json:"scope"`
+	At            time.Time `json:"at"`
+	Test          bool      `json:"test,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	AnomalySpike = "spike"
+	AnomalyDrop  = "drop"
+)
+
+// Anomaly is an hour in which a campaign's valid clicks or impressions
+// departed from what a detector expected. Each detector reports an hour at
+// most once per campaign and metric.
+type Anomaly struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	CampaignID  uint      `json:"campaign_id" gorm:"not null;uniqueIndex:idx_anomaly_bucket,priority:1"`
+	Metric      string    `json:"metric" gorm:"size:16;not null;uniqueIndex:idx_anomaly_bucket,priority:2"`
+	Detector    string    `json:"detector" gorm:"size:32;not null;uniqueIndex:idx_anomaly_bucket,priority:3"`
+	BucketStart time.Time `json:"bucket_start" gorm:"not null;uniqueIndex:idx_anomaly_bucket,priority:4;index"`
+	Direction   string    `json:"direction" gorm:"size:8;not null"`
+	Value       float64   `json:"value"`
+	Expected    float64   `json:"expected"`
+	Score       float64   `json:"score"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// ArchivePartition records one day of one event table exported to cold
+// storage. A partition with no rows has no object.
+type ArchivePartition struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TableName   string    `json:"table" gorm:"size:64;not null;uniqueIndex:idx_archive_partition"`
+	Day         time.Time `json:"day" gorm:"not null;uniqueIndex:idx_archive_partition"`
+	ObjectKey   string    `json:"object_key,omitempty"`
+	ManifestKey string    `json:"manifest_key,omitempty"`
+	Rows        int64     `json:"rows"`
+	Bytes       int64     `json:"bytes"`
+	SHA256      string    `json:"sha256,omitempty"`
+	MinID       uint      `json:"min_id,omitempty"`
+	MaxID       uint      `json:"max_id,omitempty"`
+	ArchivedAt  time.Time `json:"archived_at"`
+}
+
+// ArchiveManifest is written next to every Parquet object so the archive is
+// self-describing without the database.
+type ArchiveManifest struct {
+	Table     string    `json:"table"`
+	Day       string    `json:"day"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Object    string    `json:"object"`
+	Format    string    `json:"format"`
+	Rows      int64     `json:"rows"`
+	Bytes     int64     `json:"bytes"`
+	SHA256    string    `json:"sha256"`
+	MinID     uint      `json:"min_id"`
+	MaxID     uint      `json:"max_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// AuditEvent is an append-only record of a sensitive administrative action.
+type AuditEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Actor      string    `json:"actor" gorm:"not null"`
+	Action     string    `json:"action" gorm:"not null;index"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id" gorm:"index"`
+	Details    string    `json:"details,omitempty"` // JSON object
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
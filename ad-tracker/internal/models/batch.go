@@ -0,0 +1,28 @@
+package models
+
+import "encoding/json"
+
+// MaxBatchEvents is the most entries POST /events/batch takes at once.
+const MaxBatchEvents = 500
+
+// EventBatchRequest carries clicks and impressions a client collected
+// before sending, as mobile SDKs do while offline. Each entry is a
+// ClickRequest or ImpressionRequest with a "type" of click or impression.
+type EventBatchRequest struct {
+	Events []json.RawMessage `json:"events" binding:"required,min=1,max=500"`
+}
+
+// BatchEventResult reports what became of one batch entry, by position.
+// Rejected entries will be rejected again and should not be retried.
+type BatchEventResult struct {
+	Index   int               `json:"index"`
+	Status  string            `json:"status"` // recorded or rejected
+	ClickID string            `json:"click_id,omitempty"`
+	Error   string            `json:"error,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
+const (
+	BatchEventRecorded = "recorded"
+	BatchEventRejected = "rejected"
+)
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// BlockedRange is a blocklisted IP range. A nil AccountID blocks the range
+// for every account.
+type BlockedRange struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	AccountID *uint  `json:"account_id,omitempty" gorm:"index"`
+	CIDR      string `json:"cidr" gorm:"not null"`
+	Reason    string `json:"reason"`
+	Source    string `json:"source" gorm:"default:manual"`
+	// ExpiresAt is set for temporary blocks such as honeypot hits.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+const (
+	BlockSourceManual   = "manual"
+	BlockSourceHoneypot = "honeypot"
+)
+
+// HoneypotRequest creates a trap ad. Clicks on it are treated as bot traffic.
+type HoneypotRequest struct {
+	CampaignID *uint  `json:"campaign_id"`
+	TargetURL  string `json:"target_url" binding:"omitempty,url"`
+}
+
+type BlockedRangeRequest struct {
+	AccountID *uint  `json:"account_id"`
+	CIDR      string `json:"cidr" binding:"required"` // a CIDR or a single address
+	Reason    string `json:"reason"`
+}
@@ -0,0 +1,144 @@
+package models
+
+import "time"
+
+type Campaign struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	AccountID *uint  `json:"account_id,omitempty" gorm:"index"`
+	Name      string `json:"name" gorm:"not null"`
+	Active    bool   `json:"active" gorm:"default:true"`
+	// Pricing used to derive spend: valid clicks at CostPerClick plus valid
+	// impressions at CostPerMille per thousand.
+	CostPerClick float64 `json:"cost_per_click"`
+	CostPerMille float64 `json:"cost_per_mille"`
+	// Budgets cap that spend; zero means unlimited. Serving pauses when one
+	// is exhausted, until the next UTC day for the daily budget or until
+	// the lifetime budget is raised.
+	DailyBudget    float64 `json:"daily_budget"`
+	LifetimeBudget float64 `json:"lifetime_budget"`
+	// DailySpend covers the UTC day in SpendDate (YYYY-MM-DD).
+	DailySpend        float64    `json:"daily_spend"`
+	SpendDate         string     `json:"spend_date,omitempty" gorm:"size:10"`
+	LifetimeSpend     float64    `json:"lifetime_spend"`
+	BudgetPausedAt    *time.Time `json:"budget_paused_at,omitempty" gorm:"index"`
+	BudgetPauseReason string     `json:"budget_pause_reason,omitempty"`
+	// DailyImpressionTarget spreads serving evenly over the UTC day; zero
+	// serves without pacing.
+	DailyImpressionTarget int64 `json:"daily_impression_target"`
+	// Frequency caps limit how often one user is served the campaign per
+	// UTC hour and day; zero means uncapped.
+	FrequencyCapHour int       `json:"frequency_cap_hour"`
+	FrequencyCapDay  int       `json:"frequency_cap_day"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+const (
+	BudgetPauseDaily    = "daily_budget"
+	BudgetPauseLifetime = "lifetime_budget"
+)
+
+// ExhaustedBudget returns which budget the given spend exhausts, or "".
+func (c Campaign) ExhaustedBudget(daily, lifetime float64) string {
+	switch {
+	case c.LifetimeBudget > 0 && lifetime >= c.LifetimeBudget:
+		return BudgetPauseLifetime
+	case c.DailyBudget > 0 && daily >= c.DailyBudget:
+		return BudgetPauseDaily
+	}
+	return ""
+}
+
+type CampaignRequest struct {
+	AccountID      *uint   `json:"account_id"`
+	Name           string  `json:"name" binding:"required"`
+	Active         *bool   `json:"active"`
+	CostPerClick   float64 `json:"cost_per_click" binding:"min=0"`
+	CostPerMille   float64 `json:"cost_per_mille" binding:"min=0"`
+	DailyBudget    float64 `json:"daily_budget" binding:"min=0"`
+	LifetimeBudget float64 `json:"lifetime_budget" binding:"min=0"`
+
+	DailyImpressionTarget int64 `json:"daily_impression_target" binding:"min=0"`
+	FrequencyCapHour      int   `json:"frequency_cap_hour" binding:"min=0"`
+	FrequencyCapDay       int   `json:"frequency_cap_day" binding:"min=0"`
+}
+
+// FrequencyCapRequest changes a campaign's frequency caps; omitted fields
+// are kept.
+type FrequencyCapRequest struct {
+	Hour *int `json:"frequency_cap_hour" binding:"omitempty,min=0"`
+	Day  *int `json:"frequency_cap_day" binding:"omitempty,min=0"`
+}
+
+// BudgetRequest changes a campaign's budgets and pacing; omitted fields are
+// kept.
+type BudgetRequest struct {
+	DailyBudget           *float64 `json:"daily_budget" binding:"omitempty,min=0"`
+	LifetimeBudget        *float64 `json:"lifetime_budget" binding:"omitempty,min=0"`
+	DailyImpressionTarget *int64   `json:"daily_impression_target" binding:"omitempty,min=0"`
+}
+
+// ShareToken grants read-only access to one campaign's analytics. Only the
+// SHA-256 of the token is stored; the raw value is shown once at creation.
+type ShareToken struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	CampaignID uint       `json:"campaign_id" gorm:"not null;index"`
+	TokenHash  string     `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	Label      string     `json:"label"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ShareTokenRequest struct {
+	Label    string `json:"label"`
+	TTLHours int    `json:"ttl_hours" binding:"omitempty,min=1,max=8760"`
+}
+
+type CampaignSummary struct {
+	CampaignID uint      `json:"campaign_id"`
+	Name       string    `json:"name"`
+	Since      time.Time `json:"since"`
+	// Until ends a summary over a date range; it is exclusive and unset
+	// for summaries up to now.
+	Until      *time.Time          `json:"until,omitempty"`
+	ClickCount int64               `json:"click_count"`
+	LastHour   int64               `json:"last_hour"`
+	LastDay    int64               `json:"last_day"`
+	Ads        []AnalyticsResponse `json:"ads"`
+	// Days breaks a date range down by UTC day when requested.
+	Days []SummaryDay `json:"days,omitempty"`
+	// Precomputed summaries are read from the rollups and carry counts
+	// only, without playback, viewability or session figures.
+	Precomputed bool `json:"precomputed,omitempty"`
+}
+
+// CampaignComparison lines up several campaigns' figures over one window.
+type CampaignComparison struct {
+	Since     time.Time         `json:"since"`
+	Until     time.Time         `json:"until"`
+	ValidOnly bool              `json:"valid_only,omitempty"`
+	Campaigns []CampaignMetrics `json:"campaigns"`
+}
+
+// CampaignMetrics is one campaign's totals in a comparison. With a daily
+// breakdown every campaign has the same days, zero-filled.
+type CampaignMetrics struct {
+	CampaignID  uint         `json:"campaign_id"`
+	Name        string       `json:"name"`
+	Clicks      int64        `json:"clicks"`
+	Impressions int64        `json:"impressions"`
+	CTR         float64      `json:"ctr"`
+	LastHour    int64        `json:"last_hour"`
+	LastDay     int64        `json:"last_day"`
+	Days        []SummaryDay `json:"days,omitempty"`
+}
+
+// SummaryDay is one UTC day of a summary. The first and last days only
+// count the part inside the range.
+type SummaryDay struct {
+	Date        string  `json:"date"` // YYYY-MM-DD
+	Clicks      int64   `json:"clicks"`
+	Impressions int64   `json:"impressions"`
+	CTR         float64 `json:"ctr,omitempty"`
+}
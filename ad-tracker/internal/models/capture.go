@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// CaptureIncident bundles the evidence collected while traffic capture was
+// enabled for an ad after an anomaly fired.
+type CaptureIncident struct {
+	ID        uint              `json:"id" gorm:"primaryKey"`
+	AdID      uint              `json:"ad_id" gorm:"not null;index"`
+	Trigger   string            `json:"trigger"` // detector name or "manual"
+	Reason    string            `json:"reason"`
+	StartedAt time.Time         `json:"started_at"`
+	EndsAt    time.Time         `json:"ends_at"`
+	Captured  int64             `json:"captured"`
+	Requests  []CapturedRequest `json:"requests,omitempty" gorm:"foreignKey:IncidentID"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// CapturedRequest is the full payload of one tracking request seen while an
+// incident was open.
+type CapturedRequest struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	IncidentID uint      `json:"incident_id" gorm:"not null;index"`
+	AdID       uint      `json:"ad_id"`
+	ReceivedAt time.Time `json:"received_at"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query"`
+	IPAddress  string    `json:"ip_address"`
+	Headers    string    `json:"headers"` // JSON object
+	Payload    string    `json:"payload"` // JSON of the parsed request
+}
+
+type CaptureRequest struct {
+	AdID    uint   `json:"ad_id" binding:"required"`
+	Minutes int    `json:"minutes" binding:"omitempty,min=1,max=1440"`
+	Reason  string `json:"reason"`
+}
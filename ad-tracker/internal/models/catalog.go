@@ -0,0 +1,65 @@
+package models
+
+// MaxCatalogRows bounds one catalog import.
+const MaxCatalogRows = 5000
+
+// CatalogRow is one line of a campaign and ad import. Nil fields were left
+// blank: they keep the stored value on update and take the default on
+// create. Rows without an AdRef only upsert their campaign.
+type CatalogRow struct {
+	Line           int
+	AccountID      *uint
+	CampaignName   string
+	CampaignActive *bool
+	CostPerClick   *float64
+	CostPerMille   *float64
+	DailyBudget    *float64
+	LifetimeBudget *float64
+
+	AdRef             string
+	AdTitle           *string
+	AdImageURL        *string
+	AdTargetURL       *string
+	AdDurationSeconds *int64
+	AdActive          *bool
+}
+
+// Actions reported per imported campaign and ad.
+const (
+	CatalogCreated   = "created"
+	CatalogUpdated   = "updated"
+	CatalogUnchanged = "unchanged"
+)
+
+// CatalogImportReport describes what an import did, or in a dry run would
+// do. With errors nothing is applied.
+type CatalogImportReport struct {
+	DryRun           bool                 `json:"dry_run"`
+	Applied          bool                 `json:"applied"`
+	Rows             int                  `json:"rows"`
+	CampaignsCreated int                  `json:"campaigns_created"`
+	CampaignsUpdated int                  `json:"campaigns_updated"`
+	AdsCreated       int                  `json:"ads_created"`
+	AdsUpdated       int                  `json:"ads_updated"`
+	Campaigns        []CatalogImportItem  `json:"campaigns"`
+	Ads              []CatalogImportItem  `json:"ads"`
+	Errors           []CatalogImportError `json:"errors,omitempty"`
+}
+
+// CatalogImportItem is one campaign or ad the import touched, by the first
+// line naming it. IDs of records a dry run would create are left out.
+type CatalogImportItem struct {
+	Line       int    `json:"line"`
+	ID         uint   `json:"id,omitempty"`
+	CampaignID uint   `json:"campaign_id,omitempty"`
+	Name       string `json:"name"`
+	Action     string `json:"action"`
+}
+
+// CatalogImportError points at the line, and the column when known, that
+// cannot be imported. Line 1 is the header.
+type CatalogImportError struct {
+	Line   int    `json:"line"`
+	Column string `json:"column,omitempty"`
+	Error  string `json:"error"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// ConsumerSeekRequest moves a consumer group to a time (the first message
+// at or after it), an offset, or either end of the topic. Partition limits
+// an offset seek to one partition. DryRun returns the offsets the seek
+// would commit without committing them.
+type ConsumerSeekRequest struct {
+	Timestamp *time.Time `json:"timestamp"`
+	Offset    *int64     `json:"offset" binding:"omitempty,min=0"`
+	Partition *int       `json:"partition" binding:"omitempty,min=0"`
+	Position  string     `json:"position" binding:"omitempty,oneof=earliest latest"`
+	DryRun    bool       `json:"dry_run"`
+}
@@ -0,0 +1,150 @@
+package models
+
+import "time"
+
+const (
+	AttributionClickThrough = "click_through"
+	AttributionViewThrough  = "view_through"
+)
+
+// Conversion is a downstream goal completion. Attribution fields are filled
+// at ingestion when a prior click or impression from the same user falls
+// inside the configured window.
+type Conversion struct {
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	ClickID           string     `json:"click_id,omitempty" gorm:"index"`
+	TransactionID     string     `json:"transaction_id,omitempty" gorm:"index"`
+	UserID            string     `json:"user_id,omitempty" gorm:"index"`
+	IPAddress         string     `json:"ip_address" gorm:"index"`
+	Value             float64    `json:"value"`
+	Currency          string     `json:"currency,omitempty"`
+	Timestamp         time.Time  `json:"timestamp" gorm:"not null;index"`
+	AttributedAdID    *uint      `json:"attributed_ad_id,omitempty" gorm:"index"`
+	AttributionType   string     `json:"attribution_type,omitempty"`
+	AttributedEventID *uint      `json:"attributed_event_id,omitempty"`
+	AttributedAt      *time.Time `json:"attributed_at,omitempty"` // time of the touch that won
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// PostbackRequest is what affiliate networks send server-to-server, as query
+// parameters or a form body.
+type PostbackRequest struct {
+	ClickID       string  `form:"click_id" binding:"required"`
+	Value         float64 `form:"value" binding:"min=0"`
+	Currency      string  `form:"currency" binding:"omitempty,len=3"`
+	TransactionID string  `form:"txid"`
+}
+
+type ConversionRequest struct {
+	UserID    string     `json:"user_id" binding:"max=256"`
+	Value     float64    `json:"value" binding:"min=0"`
+	Currency  string     `json:"currency" binding:"omitempty,len=3"`
+	Timestamp ClientTime `json:"timestamp"`
+	IdentityHints
+}
+
+// AttributionWindows bounds how far back a touch may precede a conversion.
+type AttributionWindows struct {
+	Click time.Duration `json:"-"`
+	View  time.Duration `json:"-"`
+}
+
+var DefaultAttributionWindows = AttributionWindows{
+	Click: 7 * 24 * time.Hour,
+	View:  24 * time.Hour,
+}
+
+type AdConversions struct {
+	AdID         uint    `json:"ad_id"`
+	ClickThrough int64   `json:"click_through"`
+	ViewThrough  int64   `json:"view_through"`
+	Value        float64 `json:"value"`
+}
+
+type ConversionReport struct {
+	Since        time.Time       `json:"since"`
+	ClickWindow  string          `json:"click_window"`
+	ViewWindow   string          `json:"view_window"`
+	Total        int64           `json:"total"`
+	ClickThrough int64           `json:"click_through"`
+	ViewThrough  int64           `json:"view_through"`
+	Unattributed int64           `json:"unattributed"`
+	Ads          []AdConversions `json:"ads"`
+}
+
+// ConversionLatency is the time between a click and the conversion it was
+// attributed to.
+type ConversionLatency struct {
+	CampaignID  *uint
+	ClickedAt   time.Time
+	ConvertedAt time.Time
+}
+
+// LatencyBucket counts conversions whose click came at most UpTo before
+// them; the last bucket has no bound.
+type LatencyBucket struct {
+	UpTo        string `json:"up_to,omitempty"`
+	Conversions int64  `json:"conversions"`
+}
+
+// CampaignLatency is the click-to-conversion time distribution of one
+// campaign. CampaignID is nil for ads outside any campaign.
+type CampaignLatency struct {
+	CampaignID    *uint           `json:"campaign_id"`
+	Conversions   int64           `json:"conversions"`
+	AvgSeconds    float64         `json:"avg_seconds"`
+	MedianSeconds float64         `json:"median_seconds"`
+	P90Seconds    float64         `json:"p90_seconds"`
+	MaxSeconds    float64         `json:"max_seconds"`
+	Buckets       []LatencyBucket `json:"buckets"`
+}
+
+const (
+	AttributionLastClick  = "last_click"
+	AttributionFirstClick = "first_click"
+	AttributionLinear     = "linear"
+)
+
+// AdAttribution is the fractional conversion credit an ad earned under a model.
+type AdAttribution struct {
+	AdID        uint    `json:"ad_id"`
+	Conversions float64 `json:"conversions"`
+	Value       float64 `json:"value"`
+}
+
+type AttributionReport struct {
+	Model        string          `json:"model"`
+	Since        time.Time       `json:"since"`
+	Window       string          `json:"window"`
+	Conversions  int64           `json:"conversions"`
+	Attributed   int64           `json:"attributed"`
+	Unattributed int64           `json:"unattributed"`
+	Ads          []AdAttribution `json:"ads"`
+}
+
+// AttributionRow is the credit a campaign's ad earned through one creative
+// and placement, in one currency. Conversions are fractional under the
+// linear model.
+type AttributionRow struct {
+	AdID        uint    `json:"ad_id"`
+	CreativeID  *uint   `json:"creative_id,omitempty"`
+	PlacementID *uint   `json:"placement_id,omitempty"`
+	Placement   string  `json:"placement,omitempty"`
+	Currency    string  `json:"currency,omitempty"`
+	Conversions float64 `json:"conversions"`
+	Value       float64 `json:"value"`
+}
+
+// CampaignAttributionReport is the conversion credit a campaign earned over
+// a period. Conversions counts those crediting it at all, Credit their
+// share of them.
+type CampaignAttributionReport struct {
+	CampaignID  uint             `json:"campaign_id"`
+	Model       string           `json:"model"`
+	Since       time.Time        `json:"since"`
+	Until       time.Time        `json:"until"`
+	Window      string           `json:"window"`
+	Conversions int64            `json:"conversions"`
+	Credit      float64          `json:"credit"`
+	Rows        []AttributionRow `json:"rows"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Creative is one rendition of an ad, such as an image size or a copy
+// variant. When an ad has active creatives one is picked per serve by
+// weight, and events report which one was shown.
+type Creative struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	AdID      uint      `json:"ad_id" gorm:"not null;index"`
+	Name      string    `json:"name"`
+	ImageURL  string    `json:"image_url" gorm:"not null"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	Weight    int       `json:"weight" gorm:"not null;default:1"`
+	Active    bool      `json:"active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreativeRequest struct {
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url" binding:"required,url"`
+	Width    int    `json:"width" binding:"min=0"`
+	Height   int    `json:"height" binding:"min=0"`
+	Weight   int    `json:"weight" binding:"omitempty,min=1,max=10000"`
+}
+
+// CreativeUpdateRequest changes rotation; omitted fields are kept.
+type CreativeUpdateRequest struct {
+	Weight *int  `json:"weight" binding:"omitempty,min=1,max=10000"`
+	Active *bool `json:"active"`
+}
+
+// CreativeStats is one creative's performance. Events recorded without a
+// creative, e.g. before the ad had any, are reported with a nil id.
+type CreativeStats struct {
+	CreativeID  *uint   `json:"creative_id"`
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	CTR         float64 `json:"ctr"`
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+const (
+	ExperimentRunning = "running"
+	ExperimentStopped = "stopped"
+)
+
+// Experiment splits the traffic of its variant ads: each viewer is
+// assigned one variant by weight and keeps it for the whole experiment,
+// and only that variant's ad is served to them while it runs.
+type Experiment struct {
+	ID        uint                `json:"id" gorm:"primaryKey"`
+	Name      string              `json:"name" gorm:"not null"`
+	Status    string              `json:"status" gorm:"not null;default:running;index"`
+	StartedAt time.Time           `json:"started_at"`
+	StoppedAt *time.Time          `json:"stopped_at,omitempty"`
+	Variants  []ExperimentVariant `json:"variants" gorm:"foreignKey:ExperimentID"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+type ExperimentVariant struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	ExperimentID uint   `json:"experiment_id" gorm:"not null;index"`
+	Name         string `json:"name" gorm:"not null"`
+	AdID         uint   `json:"ad_id" gorm:"not null;index"`
+	Weight       int    `json:"weight" gorm:"not null;default:1"`
+}
+
+// ExperimentExposure records that a viewer was served their variant.
+// Viewer is a hash, never the raw user id, cookie or IP.
+type ExperimentExposure struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ExperimentID uint      `json:"experiment_id" gorm:"not null;index"`
+	VariantID    uint      `json:"variant_id" gorm:"not null;index"`
+	AdID         uint      `json:"ad_id" gorm:"not null"`
+	Viewer       string    `json:"viewer" gorm:"size:24;index"`
+	Timestamp    time.Time `json:"timestamp" gorm:"not null;index"`
+}
+
+type ExperimentRequest struct {
+	Name     string                     `json:"name" binding:"required"`
+	Variants []ExperimentVariantRequest `json:"variants" binding:"required,min=2,dive"`
+}
+
+type ExperimentVariantRequest struct {
+	Name   string `json:"name" binding:"required"`
+	AdID   uint   `json:"ad_id" binding:"required"`
+	Weight int    `json:"weight" binding:"omitempty,min=1,max=10000"`
+}
+
+// VariantResult compares one variant against the others. CTR is clicks
+// per exposure and CVR conversions per click, each with the bounds of its
+// confidence interval.
+type VariantResult struct {
+	VariantID   uint    `json:"variant_id"`
+	Name        string  `json:"name"`
+	AdID        uint    `json:"ad_id"`
+	Weight      int     `json:"weight"`
+	Exposures   int64   `json:"exposures"`
+	Viewers     int64   `json:"viewers"`
+	Clicks      int64   `json:"clicks"`
+	Conversions int64   `json:"conversions"`
+	CTR         float64 `json:"ctr"`
+	CTRLow      float64 `json:"ctr_low"`
+	CTRHigh     float64 `json:"ctr_high"`
+	CVR         float64 `json:"cvr"`
+	CVRLow      float64 `json:"cvr_low"`
+	CVRHigh     float64 `json:"cvr_high"`
+}
+
+type ExperimentResults struct {
+	Experiment Experiment      `json:"experiment"`
+	Confidence float64         `json:"confidence"`
+	Variants   []VariantResult `json:"variants"`
+}
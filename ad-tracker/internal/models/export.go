@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+)
+
+// Export is an asynchronously generated CSV of click events for a cohort.
+// Rows are written in id order so a resumed download sees identical bytes.
+type Export struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Status      string     `json:"status" gorm:"not null;index"`
+	AdIDs       string     `json:"ad_ids"` // comma separated, empty for all ads
+	CampaignID  *uint      `json:"campaign_id,omitempty"`
+	UserID      string     `json:"user_id,omitempty"` // as stored, empty for all users
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	FilePath    string     `json:"-"`
+	Rows        int64      `json:"rows"`
+	Bytes       int64      `json:"bytes"`
+	SHA256      string     `json:"sha256,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+type ExportRequest struct {
+	AdIDs      []uint     `json:"ad_ids"`
+	CampaignID *uint      `json:"campaign_id"`
+	UserID     string     `json:"user_id" binding:"max=256"`
+	From       *time.Time `json:"from"`
+	To         *time.Time `json:"to"`
+}
+
+// ExportManifest describes a finished export so clients can verify a
+// download assembled from multiple ranged requests.
+type ExportManifest struct {
+	ExportID    uint      `json:"export_id"`
+	FileName    string    `json:"file_name"`
+	Columns     []string  `json:"columns"`
+	Rows        int64     `json:"rows"`
+	Bytes       int64     `json:"bytes"`
+	SHA256      string    `json:"sha256"`
+	OrderedBy   string    `json:"ordered_by"`
+	CompletedAt time.Time `json:"completed_at"`
+}
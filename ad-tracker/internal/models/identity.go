@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Identifier kinds in the identity graph. Users can only be opted out; links
+// are always between a user id and a hashed email or device id.
+const (
+	IdentifierEmail  = "email"
+	IdentifierDevice = "device"
+	IdentifierUser   = "user"
+)
+
+// IdentityHints are the hashed identifiers a tag or advertiser may send
+// with an event so activity on different devices can be tied together.
+// Only lowercase hex SHA-256 digests are accepted: emails are trimmed and
+// lowercased before hashing, so the same address always hashes alike.
+type IdentityHints struct {
+	HashedEmail    string `json:"hashed_email" form:"hashed_email" binding:"omitempty,sha256"`
+	HashedDeviceID string `json:"hashed_device_id" form:"hashed_device_id" binding:"omitempty,sha256"`
+}
+
+// Identifier is a hashed email or device id, or a user id, in the form
+// stored in the graph.
+type Identifier struct {
+	Kind  string
+	Value string
+}
+
+// IdentityLink records that a user id was seen with a hashed identifier.
+// User ids sharing an identifier are treated as one person for attribution.
+type IdentityLink struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Kind       string    `json:"kind" gorm:"size:16;not null;uniqueIndex:idx_identity_link"`
+	Identifier string    `json:"identifier" gorm:"size:64;not null;uniqueIndex:idx_identity_link"`
+	UserID     string    `json:"user_id" gorm:"size:256;not null;uniqueIndex:idx_identity_link;index"`
+	FirstSeen  time.Time `json:"first_seen" gorm:"not null"`
+	LastSeen   time.Time `json:"last_seen" gorm:"not null"`
+}
+
+// IdentityOptOut keeps an identifier out of the graph for good. Its links
+// are removed when it is recorded and no new ones are made.
+type IdentityOptOut struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Kind       string    `json:"kind" gorm:"size:16;not null;uniqueIndex:idx_identity_opt_out"`
+	Identifier string    `json:"identifier" gorm:"size:256;not null;uniqueIndex:idx_identity_opt_out"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// IdentityOptOutRequest names the identifiers to opt out; at least one is
+// required. The user id is taken as sent, like in other privacy requests.
+type IdentityOptOutRequest struct {
+	UserID string `json:"user_id" binding:"max=256"`
+	IdentityHints
+}
@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+type ImpressionEvent struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	AdID          uint      `json:"ad_id" gorm:"not null;index;index:idx_impression_events_ad_timestamp,priority:1"`
+	Timestamp     time.Time `json:"timestamp" gorm:"not null;index;index:idx_impression_events_ad_timestamp,priority:2;index:idx_impression_events_user_timestamp,priority:2"`
+	ReceivedAt    time.Time `json:"received_at"` // server receipt; Timestamp may be the client's
+	UserID        string    `json:"user_id,omitempty" gorm:"index;index:idx_impression_events_user_timestamp,priority:1"`
+	IPAddress     string    `json:"ip_address" gorm:"index"`
+	CreativeID    *uint     `json:"creative_id,omitempty" gorm:"index"`
+	PlacementID   *uint     `json:"placement_id,omitempty" gorm:"index"`
+	SessionID     string    `json:"session_id,omitempty" gorm:"size:64;index"`
+	UserAgent     string    `json:"user_agent"`
+	TimeInViewMS  int64     `json:"time_in_view_ms"`
+	PercentInView float64   `json:"percent_in_view"` // 0-100, share of pixels in view
+	FraudScore    float64   `json:"fraud_score"`
+	FraudReasons  string    `json:"fraud_reasons,omitempty"`
+	Invalid       bool      `json:"invalid" gorm:"default:false;index"`
+	ConsentState  string    `json:"consent_state,omitempty" gorm:"index"`
+	ConsentString string    `json:"consent_string,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ImpressionRequest struct {
+	AdID          uint       `json:"ad_id" binding:"required"`
+	Timestamp     ClientTime `json:"timestamp"`
+	UserID        string     `json:"user_id" binding:"max=256"`
+	TimeInViewMS  int64      `json:"time_in_view_ms" binding:"min=0"`
+	PercentInView float64    `json:"percent_in_view" binding:"min=0,max=100"`
+	CreativeID    *uint      `json:"creative_id" binding:"omitempty,min=1"`
+	Placement     string     `json:"placement" binding:"omitempty,max=64,identifier"`
+	SessionID     string     `json:"session_id" binding:"omitempty,max=64,identifier"`
+	ConsentParams
+	IdentityHints
+	LinkSignature
+}
+
+// ViewabilityThreshold is an MRC-style rule: an impression is viewable when
+// at least MinPercent of the ad was in view for the required duration.
+type ViewabilityThreshold struct {
+	MinPercent float64
+	DisplayMS  int64
+	VideoMS    int64
+}
+
+// DefaultViewabilityThreshold follows the MRC display and video guidelines.
+var DefaultViewabilityThreshold = ViewabilityThreshold{
+	MinPercent: 50,
+	DisplayMS:  1000,
+	VideoMS:    2000,
+}
+
+// SessionStats summarizes the sessions with events for an ad. A bounce is
+// a session with a single event.
+type SessionStats struct {
+	Sessions         int64   `json:"sessions"`
+	Events           int64   `json:"events"`
+	EventsPerSession float64 `json:"events_per_session"`
+	Bounced          int64   `json:"bounced"`
+	BounceRate       float64 `json:"bounce_rate"`
+}
+
+// Finish fills in the ratios from the counts.
+func (s *SessionStats) Finish() {
+	if s.Sessions > 0 {
+		s.EventsPerSession = float64(s.Events) / float64(s.Sessions)
+		s.BounceRate = float64(s.Bounced) / float64(s.Sessions)
+	}
+}
+
+type ViewabilityStats struct {
+	Measured     int64   `json:"measured"` // impressions that reported viewability signals
+	Viewable     int64   `json:"viewable"`
+	ViewableRate float64 `json:"viewable_rate"`
+}
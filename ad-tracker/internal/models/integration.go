@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Integration providers.
+const (
+	IntegrationSegment = "segment"
+	IntegrationGA4     = "ga4"
+)
+
+// Integration forwards an account's click and conversion events to a
+// marketing tool: Segment track calls authenticated with WriteKey, or GA4
+// Measurement Protocol hits for MeasurementID with APISecret. EventTypes
+// is a comma separated filter as on webhook endpoints. Credentials are
+// never returned by the API.
+type Integration struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	AccountID     uint      `json:"account_id" gorm:"not null;index"`
+	Provider      string    `json:"provider" gorm:"size:16;not null"`
+	EventTypes    string    `json:"event_types" gorm:"not null"`
+	WriteKey      string    `json:"-"`
+	MeasurementID string    `json:"measurement_id,omitempty"`
+	APISecret     string    `json:"-"`
+	Active        bool      `json:"active" gorm:"default:true"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// IntegrationRequest creates an integration. Segment needs write_key, GA4
+// needs measurement_id and api_secret.
+type IntegrationRequest struct {
+	Provider      string   `json:"provider" binding:"required,oneof=segment ga4"`
+	EventTypes    []string `json:"event_types" binding:"required,min=1,dive,oneof=click conversion"`
+	WriteKey      string   `json:"write_key" binding:"required_if=Provider segment"`
+	MeasurementID string   `json:"measurement_id" binding:"required_if=Provider ga4"`
+	APISecret     string   `json:"api_secret" binding:"required_if=Provider ga4"`
+}
+
+// IntegrationUpdateRequest pauses or resumes forwarding.
+type IntegrationUpdateRequest struct {
+	Active *bool `json:"active" binding:"required"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// OnboardingRequest sets up a new advertiser in one call. The ad is optional;
+// without it no tracking snippet can be generated yet.
+type OnboardingRequest struct {
+	AccountName  string        `json:"account_name" binding:"required"`
+	CampaignName string        `json:"campaign_name"`
+	Ad           *OnboardingAd `json:"ad"`
+}
+
+type OnboardingAd struct {
+	ImageURL        string `json:"image_url" binding:"required,url"`
+	TargetURL       string `json:"target_url" binding:"required,url"`
+	Title           string `json:"title"`
+	DurationSeconds int64  `json:"duration_seconds" binding:"min=0"`
+}
+
+const (
+	IntegrationNoAds         = "no_ads"
+	IntegrationAwaitingEvent = "awaiting_first_event"
+	IntegrationLive          = "live"
+)
+
+// IntegrationStatus reports how far an account has got with its tag setup.
+type IntegrationStatus struct {
+	AccountID         uint       `json:"account_id"`
+	Status            string     `json:"status"`
+	Campaigns         int64      `json:"campaigns"`
+	Ads               int64      `json:"ads"`
+	FirstClickAt      *time.Time `json:"first_click_at,omitempty"`
+	FirstImpressionAt *time.Time `json:"first_impression_at,omitempty"`
+}
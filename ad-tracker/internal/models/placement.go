@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Placement is a publisher's ad slot: a zone on a website or a slot in an
+// app. Tags pass its key as ?placement= so serving can pick ads that fit
+// and events can be reported per placement.
+type Placement struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Key       string    `json:"key" gorm:"column:placement_key;size:64;not null;uniqueIndex"`
+	Publisher string    `json:"publisher" gorm:"not null;index"`
+	Kind      string    `json:"kind" gorm:"size:16;not null;default:web"`
+	Site      string    `json:"site"`
+	Slot      string    `json:"slot"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	Active    bool      `json:"active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Placement kinds.
+const (
+	PlacementWeb = "web"
+	PlacementApp = "app"
+)
+
+// Sized reports whether the placement only takes creatives of its exact
+// dimensions.
+func (p Placement) Sized() bool {
+	return p.Width > 0 && p.Height > 0
+}
+
+// Fits reports whether a creative can be shown in the placement.
+func (p Placement) Fits(creative Creative) bool {
+	return !p.Sized() || (creative.Width == p.Width && creative.Height == p.Height)
+}
+
+type PlacementRequest struct {
+	Key       string `json:"key" binding:"required,max=64"`
+	Publisher string `json:"publisher" binding:"required"`
+	Kind      string `json:"kind" binding:"omitempty,oneof=web app"`
+	Site      string `json:"site"`
+	Slot      string `json:"slot"`
+	Width     int    `json:"width" binding:"min=0"`
+	Height    int    `json:"height" binding:"min=0"`
+}
+
+// PlacementUpdateRequest takes a placement in or out of service.
+type PlacementUpdateRequest struct {
+	Active *bool `json:"active" binding:"required"`
+}
+
+// PlacementStats is one placement's performance. Events recorded without
+// a placement are reported with a nil id.
+type PlacementStats struct {
+	PlacementID *uint   `json:"placement_id"`
+	Key         string  `json:"key,omitempty"`
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	CTR         float64 `json:"ctr"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+const (
+	PrivacyPending   = "pending"
+	PrivacyRunning   = "running"
+	PrivacyCompleted = "completed"
+	PrivacyFailed    = "failed"
+
+	PrivacyErase     = "erase"
+	PrivacyAnonymize = "anonymize"
+	PrivacyExport    = "export"
+
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+
+	SubjectUserID = "user_id"
+	SubjectIPHash = "ip_hash"
+)
+
+// PrivacyRequest is an asynchronous data subject erasure or access export.
+// The raw subject is kept only until the job finishes; afterwards only its
+// SHA-256 remains.
+type PrivacyRequest struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	SubjectType  string     `json:"subject_type" gorm:"not null"`
+	Subject      string     `json:"-"`
+	SubjectHash  string     `json:"subject_hash" gorm:"not null;index"`
+	Mode         string     `json:"mode" gorm:"not null"`
+	Status       string     `json:"status" gorm:"not null;index"`
+	RowsAffected int64      `json:"rows_affected"`
+	Details      string     `json:"details,omitempty"` // JSON object of rows per table
+	Format       string     `json:"format,omitempty"`  // export format, json or csv
+	FilePath     string     `json:"-"`
+	DownloadURL  string     `json:"download_url,omitempty" gorm:"-"`
+	Error        string     `json:"error,omitempty"`
+	RequestedBy  string     `json:"requested_by"`
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+}
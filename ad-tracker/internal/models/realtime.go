@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// RealtimeCounts are the events counted over one trailing window.
+type RealtimeCounts struct {
+	Clicks      int64 `json:"clicks"`
+	Impressions int64 `json:"impressions"`
+}
+
+// RealtimeCounters are an ad's or a campaign's events over the last
+// minute, five minutes and hour, as of AsOf.
+type RealtimeCounters struct {
+	AdID        uint           `json:"ad_id,omitempty"`
+	CampaignID  uint           `json:"campaign_id,omitempty"`
+	AsOf        time.Time      `json:"as_of"`
+	OneMinute   RealtimeCounts `json:"1m"`
+	FiveMinutes RealtimeCounts `json:"5m"`
+	OneHour     RealtimeCounts `json:"1h"`
+}
+
+// LeaderboardEntry is one ad's standing on a day's leaderboard.
+type LeaderboardEntry struct {
+	Rank        int     `json:"rank"`
+	AdID        uint    `json:"ad_id"`
+	Title       string  `json:"title"`
+	Clicks      int64   `json:"clicks"`
+	Impressions int64   `json:"impressions"`
+	CTR         float64 `json:"ctr"`
+}
+
+// Leaderboard is a UTC day's top ads.
+type Leaderboard struct {
+	Date string             `json:"date"`
+	By   string             `json:"by"`
+	Ads  []LeaderboardEntry `json:"ads"`
+}
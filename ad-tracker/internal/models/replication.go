@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	RolePrimary = "primary"
+	RoleStandby = "standby"
+)
+
+// ReplicationState records which role this region currently plays.
+type ReplicationState struct {
+	Region    string    `json:"region" gorm:"primaryKey"`
+	Role      string    `json:"role" gorm:"not null"`
+	ChangedAt time.Time `json:"changed_at"`
+	ChangedBy string    `json:"changed_by"`
+}
+
+type ReplicationStatus struct {
+	ReplicationState
+	Target           string     `json:"target"`
+	Running          bool       `json:"running"`
+	LagMessages      int64      `json:"lag_messages"`
+	LastReplicatedAt *time.Time `json:"last_replicated_at,omitempty"`
+	Replicated       int64      `json:"replicated"`
+}
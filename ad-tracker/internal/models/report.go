@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+const (
+	ReportRunPending   = "pending"
+	ReportRunCompleted = "completed"
+	ReportRunFailed    = "failed"
+)
+
+// ReportSchedule renders an account's campaign summaries on a cron schedule
+// and delivers them to an object store URL (s3://, gs://, file://) or to
+// email addresses (mailto:a@example.com,b@example.com).
+type ReportSchedule struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	AccountID   uint       `json:"account_id" gorm:"not null;index"`
+	Name        string     `json:"name" gorm:"not null"`
+	Cron        string     `json:"cron" gorm:"not null"`
+	Timezone    string     `json:"timezone" gorm:"not null;default:UTC"`
+	Timeframe   string     `json:"timeframe" gorm:"not null;default:24h"` // summary window, e.g. 24h or 7d
+	Format      string     `json:"format" gorm:"not null;default:csv"`    // csv or json
+	ValidOnly   bool       `json:"valid_only"`
+	Destination string     `json:"destination" gorm:"not null"`
+	Active      bool       `json:"active" gorm:"default:true"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty" gorm:"index"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type ReportScheduleRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Cron        string `json:"cron" binding:"required"`
+	Timezone    string `json:"timezone"`
+	Timeframe   string `json:"timeframe" binding:"omitempty,oneof=1h 24h 7d 30d"`
+	Format      string `json:"format" binding:"omitempty,oneof=csv json"`
+	ValidOnly   bool   `json:"valid_only"`
+	Destination string `json:"destination" binding:"required"`
+	Active      *bool  `json:"active"`
+}
+
+// ReportRun is one delivery attempt series for a scheduled report. Failed
+// runs are retried with backoff until MaxAttempts is reached.
+type ReportRun struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	ScheduleID  uint       `json:"schedule_id" gorm:"not null;index"`
+	Status      string     `json:"status" gorm:"not null;index"`
+	Attempts    int        `json:"attempts"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	RetryAt     *time.Time `json:"retry_at,omitempty" gorm:"index"`
+	Location    string     `json:"location,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
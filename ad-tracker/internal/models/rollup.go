@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	RollupHour = "hour"
+	RollupDay  = "day"
+)
+
+// EventRollup holds an ad's event counts for one hour or one day, keyed by
+// the bucket's UTC start. Invalid counts are kept so valid-only analytics
+// can subtract them. Viewable impressions are judged by the viewability
+// threshold in force when the bucket was rolled up.
+type EventRollup struct {
+	ID                 uint      `json:"-" gorm:"primaryKey"`
+	Granularity        string    `json:"granularity" gorm:"size:8;not null;uniqueIndex:idx_event_rollup_bucket,priority:1"`
+	BucketStart        time.Time `json:"bucket_start" gorm:"not null;uniqueIndex:idx_event_rollup_bucket,priority:2"`
+	AdID               uint      `json:"ad_id" gorm:"not null;uniqueIndex:idx_event_rollup_bucket,priority:3"`
+	CampaignID         *uint     `json:"campaign_id,omitempty" gorm:"index"`
+	Clicks             int64     `json:"clicks"`
+	InvalidClicks      int64     `json:"invalid_clicks"`
+	Impressions        int64     `json:"impressions"`
+	InvalidImpressions int64     `json:"invalid_impressions"`
+	Measured           int64     `json:"measured"`
+	InvalidMeasured    int64     `json:"invalid_measured"`
+	Viewable           int64     `json:"viewable"`
+	InvalidViewable    int64     `json:"invalid_viewable"`
+	Conversions        int64     `json:"conversions"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// RollupCoverage records the time up to which hourly rollups are complete;
+// analytics counts raw events from there on.
+type RollupCoverage struct {
+	ID           uint      `gorm:"primaryKey"`
+	CoveredUntil time.Time `gorm:"not null"`
+	UpdatedAt    time.Time
+}
+
+// RollupTotals sums rollup rows over a range.
+type RollupTotals struct {
+	Clicks             int64
+	InvalidClicks      int64
+	Impressions        int64
+	InvalidImpressions int64
+	Measured           int64
+	InvalidMeasured    int64
+	Viewable           int64
+	InvalidViewable    int64
+	Conversions        int64
+}
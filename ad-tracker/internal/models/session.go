@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Session summarizes the clicks and impressions recorded under one session
+// id, built by the sessionization job once the session has ended. The
+// entry ad is the ad of its first event; a session converted when a
+// conversion was attributed to one of its events.
+type Session struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:64"`
+	UserID      string    `json:"user_id,omitempty" gorm:"index"`
+	EntryAdID   uint      `json:"entry_ad_id" gorm:"not null;index"`
+	StartedAt   time.Time `json:"started_at" gorm:"not null;index"`
+	EndedAt     time.Time `json:"ended_at" gorm:"not null"`
+	DurationMS  int64     `json:"duration_ms"`
+	Events      int64     `json:"events"`
+	Clicks      int64     `json:"clicks"`
+	Impressions int64     `json:"impressions"`
+	Converted   bool      `json:"converted" gorm:"index"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SessionCoverage records the time up to which ended sessions have been
+// built.
+type SessionCoverage struct {
+	ID           uint      `gorm:"primaryKey"`
+	CoveredUntil time.Time `gorm:"not null"`
+	UpdatedAt    time.Time
+}
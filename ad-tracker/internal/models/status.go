@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded"
+	StatusMajorOutage = "major_outage"
+)
+
+// Incident is a manually declared service disruption shown on the status page.
+type Incident struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Title      string     `json:"title" gorm:"not null"`
+	Message    string     `json:"message"`
+	Component  string     `json:"component"`
+	Severity   string     `json:"severity" gorm:"not null;default:minor"` // minor, major, critical
+	Active     bool       `json:"active" gorm:"default:true;index"`
+	StartedAt  time.Time  `json:"started_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type IncidentRequest struct {
+	Title     string `json:"title" binding:"required"`
+	Message   string `json:"message"`
+	Component string `json:"component"`
+	Severity  string `json:"severity" binding:"omitempty,oneof=minor major critical"`
+}
+
+// UptimeDay counts health check outcomes per component per UTC day.
+type UptimeDay struct {
+	Component string    `json:"component" gorm:"primaryKey"`
+	Day       time.Time `json:"day" gorm:"primaryKey;type:date"`
+	Checks    int64     `json:"checks"`
+	Successes int64     `json:"successes"`
+}
+
+type ComponentStatus struct {
+	Name      string    `json:"name"`
+	Status    string    `json:"status"`
+	LatencyMS int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// IngestSLI summarizes click ingest latency over a recent window.
+type IngestSLI struct {
+	Window     string  `json:"window"`
+	Requests   int     `json:"requests"`
+	P50MS      float64 `json:"p50_ms"`
+	P95MS      float64 `json:"p95_ms"`
+	TargetMS   int64   `json:"target_ms"`
+	WithinGoal float64 `json:"within_target"` // share of requests under TargetMS
+}
+
+type StatusPage struct {
+	Status     string                        `json:"status"`
+	UpdatedAt  time.Time                     `json:"updated_at"`
+	Components []ComponentStatus             `json:"components"`
+	Incidents  []Incident                    `json:"incidents"`
+	Ingest     IngestSLI                     `json:"ingest"`
+	Uptime     map[string]map[string]float64 `json:"uptime"`
+}
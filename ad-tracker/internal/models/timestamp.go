@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TimestampPolicy bounds the event time a client may claim. A timestamp
+// more than MaxSkew ahead of the server clock or more than MaxAge behind it
+// is rejected when Reject is set, and replaced by the receipt time
+// otherwise.
+type TimestampPolicy struct {
+	MaxAge  time.Duration
+	MaxSkew time.Duration
+	Reject  bool
+}
+
+var DefaultTimestampPolicy = TimestampPolicy{
+	MaxAge:  24 * time.Hour,
+	MaxSkew: 5 * time.Minute,
+}
+
+// millisecondThreshold separates the two numeric units ClientTime accepts:
+// as seconds it would be past the year 5000, as milliseconds it is 1973.
+const millisecondThreshold = 1e11
+
+// ClientTime is an event time sent by a client, as epoch seconds, epoch
+// milliseconds or an RFC 3339 string. The zero value means none was sent.
+// It is re-encoded as epoch milliseconds, so forwarding keeps the precision.
+type ClientTime struct {
+	time.Time
+}
+
+func (t *ClientTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = ClientTime{}
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		if text == "" {
+			*t = ClientTime{}
+			return nil
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, text)
+		if err != nil {
+			return fmt.Errorf("timestamp must be epoch seconds, epoch milliseconds or RFC 3339: %w", err)
+		}
+		*t = ClientTime{parsed}
+		return nil
+	}
+
+	var epoch int64
+	if err := json.Unmarshal(data, &epoch); err != nil {
+		return fmt.Errorf("timestamp must be epoch seconds, epoch milliseconds or RFC 3339: %w", err)
+	}
+	switch {
+	case epoch <= 0:
+		*t = ClientTime{}
+	case epoch >= millisecondThreshold:
+		*t = ClientTime{time.UnixMilli(epoch)}
+	default:
+		*t = ClientTime{time.Unix(epoch, 0)}
+	}
+	return nil
+}
+
+func (t ClientTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("0"), nil
+	}
+	return []byte(strconv.FormatInt(t.UnixMilli(), 10)), nil
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// UserClickStats summarizes the users behind clicks in a window. A user is
+// new when their first click (on the ad, when scoped to one) falls inside
+// the window, and returning otherwise. Clicks without a user id are left
+// out.
+type UserClickStats struct {
+	AdID           uint    `json:"ad_id,omitempty"`
+	Timeframe      string  `json:"timeframe"`
+	Users          int64   `json:"users"`
+	Clicks         int64   `json:"clicks"`
+	ClicksPerUser  float64 `json:"clicks_per_user"`
+	NewUsers       int64   `json:"new_users"`
+	ReturningUsers int64   `json:"returning_users"`
+}
+
+// Timeline event types.
+const (
+	TimelineClick      = "click"
+	TimelineImpression = "impression"
+	TimelineConversion = "conversion"
+)
+
+// TimelineEvent is one entry of a user's event history.
+type TimelineEvent struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	AdID      *uint     `json:"ad_id,omitempty"`
+	ClickID   string    `json:"click_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Value     float64   `json:"value,omitempty"`
+	Invalid   bool      `json:"invalid,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	WebhookEventClick      = "click"
+	WebhookEventConversion = "conversion"
+
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookEndpoint receives an account's events. EventTypes is a comma
+// separated filter such as "click,conversion". Deliveries are signed with
+// Secret, which is only shown when the endpoint is created.
+type WebhookEndpoint struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	AccountID  uint      `json:"account_id" gorm:"not null;index"`
+	URL        string    `json:"url" gorm:"not null"`
+	EventTypes string    `json:"event_types" gorm:"not null"`
+	Secret     string    `json:"-" gorm:"not null"`
+	Active     bool      `json:"active" gorm:"default:true"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type WebhookEndpointRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=click conversion"`
+}
+
+// WebhookDelivery is one event sent to one endpoint, with its retry state.
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	EndpointID     uint       `json:"endpoint_id" gorm:"not null;index"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	EventID        string     `json:"event_id" gorm:"index"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status" gorm:"not null;index"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" gorm:"index"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,96 @@
+// Package netguard keeps requests to customer-supplied URLs, such as
+// webhooks and alert hooks, away from the internal network.
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress is returned for targets on loopback, link-local,
+// private or otherwise internal addresses.
+var ErrForbiddenAddress = errors.New("target address is not allowed")
+
+// sharedAddressSpace is the carrier-grade NAT range, which net.IP does not
+// classify as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Guard decides which addresses outbound requests may reach. The zero value
+// allows public addresses only; AllowPrivate lifts that for local
+// development.
+type Guard struct {
+	AllowPrivate bool
+}
+
+// Allowed reports whether ip may be connected to.
+func (g Guard) Allowed(ip net.IP) bool {
+	if g.AllowPrivate {
+		return true
+	}
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// Client returns an HTTP client that refuses to connect to addresses the
+// guard does not allow. The check runs on the resolved address of every
+// connection, redirects included, so DNS cannot be used to slip past it.
+// Proxies from the environment are ignored for the same reason.
+func (g Guard) Client(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !g.Allowed(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// CheckURL rejects URLs that are not http(s) or whose host resolves to an
+// address the guard does not allow. It is meant for registration time;
+// Client enforces the same rule when connecting.
+func (g Guard) CheckURL(ctx context.Context, raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", target.Scheme)
+	}
+	host := target.Hostname()
+	if host == "" {
+		return errors.New("URL has no host")
+	}
+	if g.AllowPrivate {
+		return nil
+	}
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("resolve %s: %w", host, err)
+	}
+	for _, address := range addresses {
+		if !g.Allowed(address.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenAddress, host, address.IP)
+		}
+	}
+	return nil
+}
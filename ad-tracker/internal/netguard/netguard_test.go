@@ -0,0 +1,146 @@
+package netguard_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/netguard"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		// Public
+		{"93.184.216.34", true},
+		{"8.8.8.8", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"100.63.255.255", true},
+		{"100.128.0.0", true},
+
+		// Loopback
+		{"127.0.0.1", false},
+		{"127.8.9.10", false},
+		{"::1", false},
+
+		// Private
+		{"10.0.0.1", false},
+		{"172.16.0.1", false},
+		{"172.31.255.255", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+
+		// Carrier-grade NAT
+		{"100.64.0.1", false},
+		{"100.127.255.255", false},
+
+		// Link-local, including the cloud metadata address
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"ff02::1", false},
+
+		// Unspecified and multicast
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff05::2", false},
+
+		// IPv4-mapped IPv6 forms of the above
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"::ffff:100.64.0.1", false},
+		{"::ffff:0.0.0.0", false},
+		{"::ffff:93.184.216.34", true},
+	}
+	for _, tt := range tests {
+		ip := net.ParseIP(tt.ip)
+		if got := (netguard.Guard{}).Allowed(ip); got != tt.want {
+			t.Errorf("Allowed(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+		if !(netguard.Guard{AllowPrivate: true}).Allowed(ip) {
+			t.Errorf("Allowed(%s) with AllowPrivate = false, want true", tt.ip)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"http://127.0.0.1/hook", netguard.ErrForbiddenAddress},
+		{"https://10.1.2.3:8443/hook", netguard.ErrForbiddenAddress},
+		{"http://169.254.169.254/latest/meta-data/", netguard.ErrForbiddenAddress},
+		{"http://[::1]/hook", netguard.ErrForbiddenAddress},
+		{"http://[::ffff:127.0.0.1]/hook", netguard.ErrForbiddenAddress},
+		{"http://[fe80::1]/hook", netguard.ErrForbiddenAddress},
+		// Names are resolved, so one pointing inside is refused too
+		{"http://localhost:8080/hook", netguard.ErrForbiddenAddress},
+		{"https://93.184.216.34/hook", nil},
+	}
+	for _, tt := range tests {
+		if err := (netguard.Guard{}).CheckURL(ctx, tt.url); !errors.Is(err, tt.want) || (err == nil) != (tt.want == nil) {
+			t.Errorf("CheckURL(%s) = %v, want %v", tt.url, err, tt.want)
+		}
+	}
+
+	for _, raw := range []string{"ftp://example.com/", "file:///etc/passwd", "http:///hook", "://bad"} {
+		err := (netguard.Guard{}).CheckURL(ctx, raw)
+		if err == nil || errors.Is(err, netguard.ErrForbiddenAddress) {
+			t.Errorf("CheckURL(%s) = %v, want a URL error", raw, err)
+		}
+	}
+
+	if err := (netguard.Guard{AllowPrivate: true}).CheckURL(ctx, "http://localhost:8080/hook"); err != nil {
+		t.Errorf("CheckURL with AllowPrivate = %v, want nil", err)
+	}
+}
+
+// TestClientChecksConnections checks that the client refuses internal
+// addresses when it connects, whatever the URL named at registration, so a
+// host that later resolves inside (DNS rebinding) is refused too.
+func TestClientChecksConnections(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+
+	client := (netguard.Guard{}).Client(5 * time.Second)
+	for _, target := range []string{
+		server.URL,
+		"http://localhost:" + port,
+		"http://[::ffff:127.0.0.1]:" + port,
+	} {
+		resp, err := client.Get(target)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if !errors.Is(err, netguard.ErrForbiddenAddress) {
+			t.Errorf("GET %s = %v, want %v", target, err, netguard.ErrForbiddenAddress)
+		}
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("server received %d requests, want none", n)
+	}
+
+	resp, err := (netguard.Guard{AllowPrivate: true}).Client(5 * time.Second).Get(server.URL)
+	if err != nil {
+		t.Fatalf("GET with AllowPrivate: %v", err)
+	}
+	resp.Body.Close()
+	if n := hits.Load(); n != 1 {
+		t.Errorf("server received %d requests with AllowPrivate, want 1", n)
+	}
+}
@@ -0,0 +1,71 @@
+// Package pii minimizes personal data before it is stored.
+package pii
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+)
+
+const (
+	IPRaw      = "raw"
+	IPTruncate = "truncate"
+	IPHash     = "hash"
+)
+
+// IPMinimizer rewrites client addresses according to the configured storage
+// mode: raw keeps them, truncate zeroes the host part (IPv4 /24, IPv6 /48)
+// and hash replaces them with a salted HMAC-SHA256.
+type IPMinimizer struct {
+	mode string
+	salt []byte
+}
+
+func NewIPMinimizer(mode, salt string) (*IPMinimizer, error) {
+	switch mode {
+	case IPRaw, IPTruncate:
+	case IPHash:
+		if salt == "" {
+			return nil, errors.New("IP hashing requires a salt")
+		}
+	default:
+		return nil, fmt.Errorf("unknown IP storage mode %q", mode)
+	}
+	return &IPMinimizer{mode: mode, salt: []byte(salt)}, nil
+}
+
+func (m *IPMinimizer) Mode() string {
+	return m.mode
+}
+
+// Apply returns the form of ip that may be stored. Unparseable input is
+// never stored raw outside raw mode.
+func (m *IPMinimizer) Apply(ip string) string {
+	switch m.mode {
+	case IPTruncate:
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return ""
+		}
+		if v4 := parsed.To4(); v4 != nil {
+			return v4.Mask(net.CIDRMask(24, 32)).String()
+		}
+		return parsed.Mask(net.CIDRMask(48, 128)).String()
+	case IPHash:
+		if ip == "" {
+			return ""
+		}
+		return hmacHex(m.salt, ip)
+	default:
+		return ip
+	}
+}
+
+func hmacHex(salt []byte, value string) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
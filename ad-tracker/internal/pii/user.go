@@ -0,0 +1,25 @@
+package pii
+
+// UserIDHasher replaces user ids with a salted HMAC-SHA256 before they are
+// stored. Hashed ids still group a user's events and match conversions and
+// privacy requests hashed the same way, but cannot be read back. Without a
+// salt ids are stored as sent.
+type UserIDHasher struct {
+	salt []byte
+}
+
+func NewUserIDHasher(salt string) *UserIDHasher {
+	return &UserIDHasher{salt: []byte(salt)}
+}
+
+func (h *UserIDHasher) Enabled() bool {
+	return len(h.salt) > 0
+}
+
+// Apply returns the form of id that may be stored.
+func (h *UserIDHasher) Apply(id string) string {
+	if id == "" || !h.Enabled() {
+		return id
+	}
+	return hmacHex(h.salt, id)
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AccountRepository struct {
+	db *gorm.DB
+}
+
+func NewAccountRepository(db *gorm.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+func (r *AccountRepository) Create(account *models.Account) error {
+	return r.db.Create(account).Error
+}
+
+func (r *AccountRepository) List() ([]models.Account, error) {
+	var accounts []models.Account
+	err := r.db.Order("id").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *AccountRepository) Get(id uint) (*models.Account, error) {
+	var account models.Account
+	if err := r.db.First(&account, id).Error; err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (r *AccountRepository) UpdateSigningSecret(id uint, secret string) error {
+	result := r.db.Model(&models.Account{}).Where("id = ?", id).Update("signing_secret", secret)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *AccountRepository) UpdatePrivacyMode(id uint, mode string) error {
+	result := r.db.Model(&models.Account{}).Where("id = ?", id).Update("privacy_mode", mode)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// StrictAds returns the ids of ads whose account runs in strict privacy mode.
+func (r *AccountRepository) StrictAds() ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Ad{}).
+		Joins("JOIN campaigns ON campaigns.id = ads.campaign_id").
+		Joins("JOIN accounts ON accounts.id = campaigns.account_id").
+		Where("accounts.privacy_mode = ?", models.PrivacyModeStrict).
+		Pluck("ads.id", &ids).Error
+	return ids, err
+}
+
+// SigningSecretForAd resolves the secret of the account owning the ad's
+// campaign. It returns "" when the ad is not tied to an account.
+func (r *AccountRepository) SigningSecretForAd(adID uint) (string, error) {
+	var secret string
+	err := r.db.Model(&models.Ad{}).
+		Select("accounts.signing_secret").
+		Joins("JOIN campaigns ON campaigns.id = ads.campaign_id").
+		Joins("JOIN accounts ON accounts.id = campaigns.account_id").
+		Where("ads.id = ?", adID).
+		Limit(1).
+		Scan(&secret).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return secret, err
+}
+
+// Onboard creates the account, its default campaign, an optional first ad
+// and a reporting share token in one transaction.
+func (r *AccountRepository) Onboard(account *models.Account, campaign *models.Campaign, ad *models.Ad, token *models.ShareToken, rawToken string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+
+		campaign.AccountID = &account.ID
+		if err := tx.Create(campaign).Error; err != nil {
+			return err
+		}
+
+		if ad != nil {
+			ad.CampaignID = &campaign.ID
+			if err := tx.Create(ad).Error; err != nil {
+				return err
+			}
+		}
+
+		token.CampaignID = campaign.ID
+		token.TokenHash = hashToken(rawToken)
+		return tx.Create(token).Error
+	})
+}
+
+// IntegrationStatus counts the account's campaigns and ads and finds the
+// first click and impression recorded for any of them.
+func (r *AccountRepository) IntegrationStatus(accountID uint) (models.IntegrationStatus, error) {
+	status := models.IntegrationStatus{AccountID: accountID}
+
+	if err := r.db.Model(&models.Campaign{}).Where("account_id = ?", accountID).Count(&status.Campaigns).Error; err != nil {
+		return status, err
+	}
+
+	adIDs := r.db.Model(&models.Ad{}).
+		Select("ads.id").
+		Joins("JOIN campaigns ON campaigns.id = ads.campaign_id").
+		Where("campaigns.account_id = ?", accountID)
+
+	if err := r.db.Table("(?) AS account_ads", adIDs).Count(&status.Ads).Error; err != nil {
+		return status, err
+	}
+
+	var first struct {
+		At *time.Time
+	}
+	if err := r.db.Model(&models.ClickEvent{}).Select("MIN(timestamp) AS at").Where("ad_id IN (?)", adIDs).Scan(&first).Error; err != nil {
+		return status, err
+	}
+	status.FirstClickAt = first.At
+
+	first.At = nil
+	if err := r.db.Model(&models.ImpressionEvent{}).Select("MIN(timestamp) AS at").Where("ad_id IN (?)", adIDs).Scan(&first).Error; err != nil {
+		return status, err
+	}
+	status.FirstImpressionAt = first.At
+
+	switch {
+	case status.Ads == 0:
+		status.Status = models.IntegrationNoAds
+	case status.FirstClickAt == nil && status.FirstImpressionAt == nil:
+		status.Status = models.IntegrationAwaitingEvent
+	default:
+		status.Status = models.IntegrationLive
+	}
+	return status, nil
+}
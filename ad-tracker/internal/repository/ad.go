@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/targeting"
+
+	"gorm.io/gorm"
+)
+
+type AdRepository struct {
+	db *gorm.DB
+}
+
+func NewAdRepository(db *gorm.DB) *AdRepository {
+	return &AdRepository{db: db}
+}
+
+// Active returns the ads that can be served, leaving out honeypots, ads of
+// campaigns paused for an exhausted budget and ads of paused ad groups.
+func (r *AdRepository) Active() ([]models.Ad, error) {
+	var ads []models.Ad
+	err := r.db.Preload("Creatives", activeCreatives).Where("active = ? AND honeypot = ?", true, false).
+		Where("campaign_id IS NULL OR campaign_id NOT IN (?)",
+			r.db.Model(&models.Campaign{}).Select("id").Where("budget_paused_at IS NOT NULL")).
+		Where("ad_group_id IS NULL OR ad_group_id NOT IN (?)",
+			r.db.Model(&models.AdGroup{}).Select("id").Where("active = ?", false)).
+		Find(&ads).Error
+	return ads, err
+}
+
+// Get returns gorm.ErrRecordNotFound when the ad does not exist. Unlike
+// Active it includes creatives taken out of rotation, which events
+// rendered earlier may still report.
+func (r *AdRepository) Get(id uint) (models.Ad, error) {
+	var ad models.Ad
+	err := r.db.Preload("Creatives", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).First(&ad, id).Error
+	return ad, err
+}
+
+// activeCreatives limits a Creatives preload to those in rotation.
+func activeCreatives(db *gorm.DB) *gorm.DB {
+	return db.Where("active = ?", true).Order("id")
+}
+
+// UpdateTargeting replaces the ad's targeting rule; nil removes it.
+func (r *AdRepository) UpdateTargeting(id uint, rule *targeting.Rule) (models.Ad, error) {
+	result := r.db.Model(&models.Ad{}).Where("id = ?", id).Update("targeting", rule)
+	if result.Error != nil {
+		return models.Ad{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.Ad{}, gorm.ErrRecordNotFound
+	}
+	return r.Get(id)
+}
+
+// CampaignIDs maps each of the ads that belongs to a campaign to it.
+func (r *AdRepository) CampaignIDs(adIDs []uint) (map[uint]uint, error) {
+	var rows []struct {
+		ID         uint
+		CampaignID uint
+	}
+	err := r.db.Model(&models.Ad{}).Select("id, campaign_id").
+		Where("id IN ? AND campaign_id IS NOT NULL", adIDs).
+		Find(&rows).Error
+	campaigns := make(map[uint]uint, len(rows))
+	for _, row := range rows {
+		campaigns[row.ID] = row.CampaignID
+	}
+	return campaigns, err
+}
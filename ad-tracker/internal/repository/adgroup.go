@@ -0,0 +1,190 @@
+package repositories
+
+import (
+	"errors"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrAdOutsideCampaign is returned by SetAds for ads that do not exist or
+// belong to another campaign than the group.
+var ErrAdOutsideCampaign = errors.New("ad does not belong to the ad group's campaign")
+
+type AdGroupRepository struct {
+	db *gorm.DB
+}
+
+func NewAdGroupRepository(db *gorm.DB) *AdGroupRepository {
+	return &AdGroupRepository{db: db}
+}
+
+// Create stores the group; Active false is written after the insert
+// because the column defaults to true.
+func (r *AdGroupRepository) Create(group *models.AdGroup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+		if group.Active {
+			return nil
+		}
+		return tx.Model(group).Update("active", false).Error
+	})
+}
+
+// List returns every group, or only the campaign's when campaignID is set,
+// with their ad ids.
+func (r *AdGroupRepository) List(campaignID uint) ([]models.AdGroup, error) {
+	var groups []models.AdGroup
+	query := r.db.Order("id")
+	if campaignID != 0 {
+		query = query.Where("campaign_id = ?", campaignID)
+	}
+	if err := query.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	var members []struct {
+		ID        uint
+		AdGroupID uint
+	}
+	err := r.db.Model(&models.Ad{}).Select("id", "ad_group_id").
+		Where("ad_group_id IS NOT NULL").Order("id").Find(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	adIDs := make(map[uint][]uint)
+	for _, member := range members {
+		adIDs[member.AdGroupID] = append(adIDs[member.AdGroupID], member.ID)
+	}
+	for i := range groups {
+		groups[i].AdIDs = adIDs[groups[i].ID]
+		if groups[i].AdIDs == nil {
+			groups[i].AdIDs = []uint{}
+		}
+	}
+	return groups, nil
+}
+
+// Get returns gorm.ErrRecordNotFound when the group does not exist.
+func (r *AdGroupRepository) Get(id uint) (*models.AdGroup, error) {
+	var group models.AdGroup
+	if err := r.db.First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	adIDs, err := r.AdIDs(id)
+	if err != nil {
+		return nil, err
+	}
+	group.AdIDs = adIDs
+	return &group, nil
+}
+
+// AdIDs returns the ids of the group's ads in order.
+func (r *AdGroupRepository) AdIDs(id uint) ([]uint, error) {
+	adIDs := []uint{}
+	err := r.db.Model(&models.Ad{}).
+		Where("ad_group_id = ?", id).
+		Order("id").
+		Pluck("id", &adIDs).Error
+	return adIDs, err
+}
+
+// Update applies the request's fields and returns the updated group, or
+// gorm.ErrRecordNotFound.
+func (r *AdGroupRepository) Update(id uint, req models.AdGroupUpdateRequest) (*models.AdGroup, error) {
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if len(updates) > 0 {
+		result := r.db.Model(&models.AdGroup{}).Where("id = ?", id).Updates(updates)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil, gorm.ErrRecordNotFound
+		}
+	}
+	return r.Get(id)
+}
+
+// Delete removes the group and leaves its ads ungrouped. It returns the
+// ids of those ads, or gorm.ErrRecordNotFound.
+func (r *AdGroupRepository) Delete(id uint) ([]uint, error) {
+	var adIDs []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Ad{}).Where("ad_group_id = ?", id).Order("id").Pluck("id", &adIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Ad{}).Where("ad_group_id = ?", id).Update("ad_group_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.AdGroup{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	return adIDs, err
+}
+
+// SetAds makes adIDs the group's ads, moving them out of any other group
+// and ungrouping the ones left out. It returns the ids of every ad whose
+// group changed, or ErrAdOutsideCampaign.
+func (r *AdGroupRepository) SetAds(group models.AdGroup, adIDs []uint) ([]uint, error) {
+	var removedIDs, addedIDs []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		removed := tx.Model(&models.Ad{}).Where("ad_group_id = ?", group.ID)
+		if len(adIDs) > 0 {
+			unique := make(map[uint]bool, len(adIDs))
+			for _, id := range adIDs {
+				unique[id] = true
+			}
+			var count int64
+			err := tx.Model(&models.Ad{}).
+				Where("id IN ? AND campaign_id = ?", adIDs, group.CampaignID).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count != int64(len(unique)) {
+				return ErrAdOutsideCampaign
+			}
+
+			err = tx.Model(&models.Ad{}).
+				Where("id IN ? AND (ad_group_id IS NULL OR ad_group_id <> ?)", adIDs, group.ID).
+				Order("id").
+				Pluck("id", &addedIDs).Error
+			if err != nil {
+				return err
+			}
+			removed = removed.Where("id NOT IN ?", adIDs)
+		}
+		if err := removed.Order("id").Pluck("id", &removedIDs).Error; err != nil {
+			return err
+		}
+
+		if len(removedIDs) > 0 {
+			if err := tx.Model(&models.Ad{}).Where("id IN ?", removedIDs).Update("ad_group_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		if len(addedIDs) > 0 {
+			return tx.Model(&models.Ad{}).Where("id IN ?", addedIDs).Update("ad_group_id", group.ID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(removedIDs, addedIDs...), nil
+}
@@ -0,0 +1,178 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AlertRepository struct {
+	db *gorm.DB
+}
+
+func NewAlertRepository(db *gorm.DB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+func (r *AlertRepository) CreateRule(rule *models.AlertRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *AlertRepository) SaveRule(rule *models.AlertRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *AlertRepository) GetRule(id uint) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	if err := r.db.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *AlertRepository) ListRules() ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	err := r.db.Order("id").Find(&rules).Error
+	return rules, err
+}
+
+func (r *AlertRepository) ActiveRules() ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	err := r.db.Where("active = ?", true).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// DeleteRule removes a rule and its event history.
+func (r *AlertRepository) DeleteRule(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", id).Delete(&models.AlertEvent{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.AlertRule{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *AlertRepository) CreateEvent(event *models.AlertEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *AlertRepository) ListEvents(ruleID uint, limit int) ([]models.AlertEvent, error) {
+	var events []models.AlertEvent
+	err := r.db.Where("rule_id = ?", ruleID).Order("id DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// MetricValue computes the rule's metric over [since, until). ok is false
+// when the metric is undefined, e.g. a CTR without impressions, so the rule
+// is skipped rather than firing on no data.
+func (r *AlertRepository) MetricValue(rule *models.AlertRule, since, until time.Time) (value float64, ok bool, err error) {
+	switch rule.Metric {
+	case models.AlertMetricClicks:
+		clicks, err := r.count(&models.ClickEvent{}, rule, since, until, true)
+		return float64(clicks), true, err
+
+	case models.AlertMetricImpressions:
+		impressions, err := r.count(&models.ImpressionEvent{}, rule, since, until, true)
+		return float64(impressions), true, err
+
+	case models.AlertMetricCTR:
+		clicks, err := r.count(&models.ClickEvent{}, rule, since, until, true)
+		if err != nil {
+			return 0, false, err
+		}
+		impressions, err := r.count(&models.ImpressionEvent{}, rule, since, until, true)
+		if err != nil || impressions == 0 {
+			return 0, false, err
+		}
+		return float64(clicks) / float64(impressions), true, nil
+
+	case models.AlertMetricConversions:
+		var conversions int64
+		err := r.scope(r.db.Model(&models.Conversion{}), rule, "attributed_ad_id").
+			Where("timestamp >= ? AND timestamp < ?", since, until).
+			Count(&conversions).Error
+		return float64(conversions), true, err
+
+	case models.AlertMetricInvalidRate:
+		var total, invalid int64
+		for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+			all, err := r.count(model, rule, since, until, false)
+			if err != nil {
+				return 0, false, err
+			}
+			valid, err := r.count(model, rule, since, until, true)
+			if err != nil {
+				return 0, false, err
+			}
+			total += all
+			invalid += all - valid
+		}
+		if total == 0 {
+			return 0, false, nil
+		}
+		return float64(invalid) / float64(total), true, nil
+
+	case models.AlertMetricSpend:
+		spend, err := r.spend(rule, since, until)
+		return spend, true, err
+	}
+	return 0, false, nil
+}
+
+func (r *AlertRepository) count(model interface{}, rule *models.AlertRule, since, until time.Time, validOnly bool) (int64, error) {
+	tx := r.scope(r.db.Model(model), rule, "ad_id").Where("timestamp >= ? AND timestamp < ?", since, until)
+	if validOnly {
+		tx = tx.Where("invalid = ?", false)
+	}
+	var count int64
+	err := tx.Count(&count).Error
+	return count, err
+}
+
+// spend prices valid clicks and impressions at their campaign's rates.
+func (r *AlertRepository) spend(rule *models.AlertRule, since, until time.Time) (float64, error) {
+	var clickSpend, impressionSpend float64
+
+	err := r.scope(r.db.Table("click_events"), rule, "click_events.ad_id").
+		Select("COALESCE(SUM(campaigns.cost_per_click), 0)").
+		Joins("JOIN ads ON ads.id = click_events.ad_id").
+		Joins("JOIN campaigns ON campaigns.id = ads.campaign_id").
+		Where("click_events.timestamp >= ? AND click_events.timestamp < ? AND click_events.invalid = ?", since, until, false).
+		Scan(&clickSpend).Error
+	if err != nil {
+		return 0, err
+	}
+
+	err = r.scope(r.db.Table("impression_events"), rule, "impression_events.ad_id").
+		Select("COALESCE(SUM(campaigns.cost_per_mille), 0) / 1000").
+		Joins("JOIN ads ON ads.id = impression_events.ad_id").
+		Joins("JOIN campaigns ON campaigns.id = ads.campaign_id").
+		Where("impression_events.timestamp >= ? AND impression_events.timestamp < ? AND impression_events.invalid = ?", since, until, false).
+		Scan(&impressionSpend).Error
+	return clickSpend + impressionSpend, err
+}
+
+// scope restricts column to the ads covered by the rule.
+func (r *AlertRepository) scope(tx *gorm.DB, rule *models.AlertRule, column string) *gorm.DB {
+	switch {
+	case rule.AdID != nil:
+		return tx.Where(column+" = ?", *rule.AdID)
+	case rule.CampaignID != nil:
+		return tx.Where(column+" IN (?)", r.db.Model(&models.Ad{}).Select("id").Where("campaign_id = ?", *rule.CampaignID))
+	case rule.AccountID != nil:
+		return tx.Where(column+" IN (?)", r.db.Model(&models.Ad{}).
+			Select("ads.id").
+			Joins("JOIN campaigns ON campaigns.id = ads.campaign_id").
+			Where("campaigns.account_id = ?", *rule.AccountID))
+	}
+	return tx
+}
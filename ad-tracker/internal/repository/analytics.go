@@ -1,45 +1,174 @@
 package repositories
 
 import (
-	"ad-tracking-system/internal/models"
+	"context"
 	"time"
 
+	"ad-tracking-system/internal/events"
+	applog "ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/models"
+
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type AnalyticsRepository struct {
-	db     *gorm.DB
-	logger *logrus.Logger
+	db          *gorm.DB
+	store       events.EventStore
+	logger      *logrus.Logger
+	sampler     *applog.Sampler
+	viewability models.ViewabilityThreshold
+
+	rollups         *RollupRepository
+	rollupMinWindow time.Duration
 }
 
-func NewAnalyticsRepository(db *gorm.DB, logger *logrus.Logger) *AnalyticsRepository {
+func NewAnalyticsRepository(db *gorm.DB, store events.EventStore, logger *logrus.Logger) *AnalyticsRepository {
 	return &AnalyticsRepository{
-		db:     db,
-		logger: logger,
+		db:          db,
+		store:       store,
+		logger:      logger,
+		viewability: models.DefaultViewabilityThreshold,
 	}
 }
 
-func (r *AnalyticsRepository) GetAdAnalytics(adID uint, since time.Time) models.AnalyticsResponse {
-	var analytics models.AnalyticsResponse
+// SetViewabilityThreshold overrides the MRC defaults used for viewable rate.
+func (r *AnalyticsRepository) SetViewabilityThreshold(threshold models.ViewabilityThreshold) {
+	r.viewability = threshold
+}
+
+// SetLogSampler samples the per-ad analytics log lines, which are written
+// for every ad on every analytics request.
+func (r *AnalyticsRepository) SetLogSampler(sampler *applog.Sampler) {
+	r.sampler = sampler
+}
+
+// SetStore moves analytics reads to another event store, such as the
+// ClickHouse mirror.
+func (r *AnalyticsRepository) SetStore(store events.EventStore) {
+	r.store = store
+}
+
+// SetRollups makes windows of at least minWindow count clicks, impressions
+// and viewability from the rollup tables instead of scanning raw events.
+// Playback percentiles and distinct sessions cannot be summed across
+// buckets, so those are still scanned over the whole window.
+func (r *AnalyticsRepository) SetRollups(rollups *RollupRepository, minWindow time.Duration) {
+	r.rollups = rollups
+	r.rollupMinWindow = minWindow
+}
+
+// windowCounts are the figures of a window that the rollups can answer.
+type windowCounts struct {
+	clicks      int64
+	impressions int64
+	viewability models.ViewabilityStats
+}
+
+// rollupCounts answers long windows from the rollups: whole hours up to
+// their coverage, plus raw counts for the partial hour at the start and the
+// events since coverage. minMS is the in-view duration for the ad, which
+// the rollups applied per bucket. ok is false when the window should be
+// counted raw.
+func (r *AnalyticsRepository) rollupCounts(ctx context.Context, adID uint, since time.Time, validOnly bool, minMS int64) (counts windowCounts, ok bool) {
+	if r.rollups == nil || time.Since(since) < r.rollupMinWindow {
+		return counts, false
+	}
+	covered, err := r.rollups.Coverage()
+	if err != nil {
+		r.logger.WithError(err).Warn("Failed to read rollup coverage")
+		return counts, false
+	}
 
-	// Get basic click count for the timeframe
-	var clickCount int64
-	err := r.db.Model(&models.ClickEvent{}).
-		Where("ad_id = ? AND timestamp >= ?", adID, since).
-		Count(&clickCount).Error
+	from := since.UTC().Truncate(time.Hour)
+	if from.Before(since) {
+		from = from.Add(time.Hour)
+	}
+	if !from.Before(covered) {
+		return counts, false
+	}
 
+	totals, err := r.rollups.Totals(adID, from, covered)
 	if err != nil {
-		r.logger.WithError(err).Error("Failed to get click count")
-		return models.AnalyticsResponse{AdID: adID}
+		r.logger.WithError(err).Warn("Failed to read rollups")
+		return counts, false
+	}
+	counts.clicks, counts.impressions = totals.Clicks, totals.Impressions
+	counts.viewability.Measured, counts.viewability.Viewable = totals.Measured, totals.Viewable
+	if validOnly {
+		counts.clicks -= totals.InvalidClicks
+		counts.impressions -= totals.InvalidImpressions
+		counts.viewability.Measured -= totals.InvalidMeasured
+		counts.viewability.Viewable -= totals.InvalidViewable
+	}
+
+	for _, edge := range []events.Query{
+		{AdID: adID, Since: since, Until: from, ValidOnly: validOnly},
+		{AdID: adID, Since: covered, ValidOnly: validOnly},
+	} {
+		if !edge.Until.IsZero() && !edge.Since.Before(edge.Until) {
+			continue
+		}
+		edgeClicks, err := r.store.CountClicks(ctx, edge)
+		if err != nil {
+			r.logger.WithError(err).Warn("Failed to count clicks outside rollups")
+			return counts, false
+		}
+		edgeImpressions, err := r.store.CountImpressions(ctx, edge)
+		if err != nil {
+			r.logger.WithError(err).Warn("Failed to count impressions outside rollups")
+			return counts, false
+		}
+		edgeViewability, err := r.store.ViewabilityStats(ctx, edge, r.viewability.MinPercent, minMS)
+		if err != nil {
+			r.logger.WithError(err).Warn("Failed to count viewability outside rollups")
+			return counts, false
+		}
+		counts.clicks += edgeClicks
+		counts.impressions += edgeImpressions
+		counts.viewability.Measured += edgeViewability.Measured
+		counts.viewability.Viewable += edgeViewability.Viewable
+	}
+	if counts.viewability.Measured > 0 {
+		counts.viewability.ViewableRate = float64(counts.viewability.Viewable) / float64(counts.viewability.Measured)
+	}
+	return counts, true
+}
+
+// GetAdAnalytics aggregates an ad's events since the given time. validOnly
+// drops events that fraud scoring tagged invalid. With rollups set, long
+// windows read counts and viewability from them; playback and sessions are
+// always raw scans of the window.
+func (r *AnalyticsRepository) GetAdAnalytics(adID uint, since time.Time, validOnly bool) models.AnalyticsResponse {
+	var analytics models.AnalyticsResponse
+
+	// Video ads use the longer in-view duration and measure completion
+	// against the ad length
+	var ad models.Ad
+	if err := r.db.Select("duration_seconds").First(&ad, adID).Error; err != nil {
+		r.logger.WithError(err).Warn("Failed to load ad duration")
+	}
+	minMS := r.viewability.DisplayMS
+	if ad.DurationSeconds > 0 {
+		minMS = r.viewability.VideoMS
+	}
+
+	// Get basic click count for the timeframe, from the rollups for long windows
+	ctx := context.Background()
+	counts, rolledUp := r.rollupCounts(ctx, adID, since, validOnly, minMS)
+	clickCount, impressions, viewability := counts.clicks, counts.impressions, counts.viewability
+	if !rolledUp {
+		var err error
+		clickCount, err = r.store.CountClicks(ctx, events.Query{AdID: adID, Since: since, ValidOnly: validOnly})
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to get click count")
+			return models.AnalyticsResponse{AdID: adID}
+		}
 	}
 
 	// Get last hour count
 	lastHour := time.Now().UTC().Add(-time.Hour)
-	var lastHourCount int64
-	err = r.db.Model(&models.ClickEvent{}).
-		Where("ad_id = ? AND timestamp >= ?", adID, lastHour).
-		Count(&lastHourCount).Error
+	lastHourCount, err := r.store.CountClicks(ctx, events.Query{AdID: adID, Since: lastHour, ValidOnly: validOnly})
 
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get last hour count")
@@ -47,61 +176,122 @@ func (r *AnalyticsRepository) GetAdAnalytics(adID uint, since time.Time) models.
 
 	// Get last day count
 	lastDay := time.Now().UTC().Add(-24 * time.Hour)
-	var lastDayCount int64
-	err = r.db.Model(&models.ClickEvent{}).
-		Where("ad_id = ? AND timestamp >= ?", adID, lastDay).
-		Count(&lastDayCount).Error
+	lastDayCount, err := r.store.CountClicks(ctx, events.Query{AdID: adID, Since: lastDay, ValidOnly: validOnly})
 
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get last day count")
 	}
 
+	// Video playback distribution; percentiles need the raw playback times
+	playback, err := r.store.PlaybackStats(ctx, events.Query{AdID: adID, Since: since, ValidOnly: validOnly}, ad.DurationSeconds)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get playback stats")
+	} else if playback.Samples > 0 {
+		analytics.Playback = &playback
+	}
+
+	// Impressions and viewability
+	if !rolledUp {
+		impressions, err = r.store.CountImpressions(ctx, events.Query{AdID: adID, Since: since, ValidOnly: validOnly})
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to get impression count")
+		}
+		viewability, err = r.store.ViewabilityStats(ctx, events.Query{AdID: adID, Since: since, ValidOnly: validOnly}, r.viewability.MinPercent, minMS)
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to get viewability stats")
+		}
+	}
+	if viewability.Measured > 0 {
+		analytics.Viewability = &viewability
+	}
+
+	// Sessions span buckets, so they are counted from raw events
+	sessions, err := r.store.SessionStats(ctx, events.Query{AdID: adID, Since: since, ValidOnly: validOnly})
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get session stats")
+	} else if sessions.Sessions > 0 {
+		analytics.Sessions = &sessions
+	}
+
 	analytics.AdID = adID
+	analytics.ValidOnly = validOnly
 	analytics.ClickCount = clickCount
 	analytics.LastHour = lastHourCount
 	analytics.LastDay = lastDayCount
-	// CTR would need impression data to calculate, leaving it as 0 for now
+	analytics.Impressions = impressions
+	if impressions > 0 {
+		analytics.CTR = float64(clickCount) / float64(impressions)
+	}
 
-	r.logger.WithFields(logrus.Fields{
+	r.sampler.Log(r.logger.WithFields(logrus.Fields{
 		"ad_id":       adID,
 		"click_count": clickCount,
 		"last_hour":   lastHourCount,
 		"last_day":    lastDayCount,
 		"since":       since,
-	}).Info("Retrieved ad analytics")
+	}), logrus.InfoLevel, "Retrieved ad analytics")
 
 	return analytics
 }
 
-func (r *AnalyticsRepository) GetAllAnalytics(since time.Time) []models.AnalyticsResponse {
+func (r *AnalyticsRepository) GetAllAnalytics(since time.Time, validOnly bool) []models.AnalyticsResponse {
 	var allAnalytics []models.AnalyticsResponse
 
 	// Get all unique ad IDs that have clicks since the specified time
-	var adIDs []uint
-	err := r.db.Model(&models.ClickEvent{}).
-		Where("timestamp >= ?", since).
-		Distinct("ad_id").
-		Pluck("ad_id", &adIDs).Error
+	adIDs, err := r.clickedAdIDs(since)
 
 	if err != nil {
 		r.logger.WithError(err).Error("Failed to get unique ad IDs")
 		return allAnalytics
 	}
 
-	r.logger.WithFields(logrus.Fields{
+	r.sampler.Log(r.logger.WithFields(logrus.Fields{
 		"ad_ids": adIDs,
 		"since":  since,
-	}).Info("Found ad IDs for analytics")
+	}), logrus.InfoLevel, "Found ad IDs for analytics")
 
 	// Get analytics for each ad
 	for _, adID := range adIDs {
-		analytics := r.GetAdAnalytics(adID, since)
+		analytics := r.GetAdAnalytics(adID, since, validOnly)
 		allAnalytics = append(allAnalytics, analytics)
 	}
 
 	return allAnalytics
 }
 
+// clickedAdIDs reads long windows from the rollups plus raw events since
+// their coverage.
+func (r *AnalyticsRepository) clickedAdIDs(since time.Time) ([]uint, error) {
+	ctx := context.Background()
+	if r.rollups == nil || time.Since(since) < r.rollupMinWindow {
+		return r.store.DistinctAdIDs(ctx, since)
+	}
+	covered, err := r.rollups.Coverage()
+	if err != nil || !since.Before(covered) {
+		return r.store.DistinctAdIDs(ctx, since)
+	}
+
+	rolled, err := r.rollups.ClickedAdIDs(since.UTC().Truncate(time.Hour), covered)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := r.store.DistinctAdIDs(ctx, covered)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[uint]bool, len(rolled))
+	for _, id := range rolled {
+		seen[id] = true
+	}
+	for _, id := range recent {
+		if !seen[id] {
+			rolled = append(rolled, id)
+		}
+	}
+	return rolled, nil
+}
+
 // Alternative method using raw SQL to handle potential timezone issues
 func (r *AnalyticsRepository) GetAdAnalyticsWithRawSQL(adID uint, since time.Time) models.AnalyticsResponse {
 	var analytics models.AnalyticsResponse
@@ -137,13 +327,13 @@ func (r *AnalyticsRepository) GetAdAnalyticsWithRawSQL(adID uint, since time.Tim
 	analytics.LastHour = result.LastHour
 	analytics.LastDay = result.LastDay
 
-	r.logger.WithFields(logrus.Fields{
+	r.sampler.Log(r.logger.WithFields(logrus.Fields{
 		"ad_id":       adID,
 		"click_count": result.TotalClicks,
 		"last_hour":   result.LastHour,
 		"last_day":    result.LastDay,
 		"method":      "raw_sql",
-	}).Info("Retrieved ad analytics using raw SQL")
+	}), logrus.InfoLevel, "Retrieved ad analytics using raw SQL")
 
 	return analytics
 }
@@ -191,11 +381,105 @@ func (r *AnalyticsRepository) GetAllAnalyticsWithRawSQL(since time.Time) []model
 		allAnalytics = append(allAnalytics, analytics)
 	}
 
-	r.logger.WithFields(logrus.Fields{
+	r.sampler.Log(r.logger.WithFields(logrus.Fields{
 		"results_count": len(allAnalytics),
 		"since":         since,
 		"method":        "raw_sql",
-	}).Info("Retrieved all analytics using raw SQL")
+	}), logrus.InfoLevel, "Retrieved all analytics using raw SQL")
 
 	return allAnalytics
 }
+
+// GetCampaignSummary aggregates analytics across every ad in the campaign.
+func (r *AnalyticsRepository) GetCampaignSummary(campaign models.Campaign, adIDs []uint, since time.Time, validOnly bool) models.CampaignSummary {
+	summary := models.CampaignSummary{
+		CampaignID: campaign.ID,
+		Name:       campaign.Name,
+		Since:      since,
+		Ads:        make([]models.AnalyticsResponse, 0, len(adIDs)),
+	}
+
+	for _, adID := range adIDs {
+		analytics := r.GetAdAnalytics(adID, since, validOnly)
+		summary.ClickCount += analytics.ClickCount
+		summary.LastHour += analytics.LastHour
+		summary.LastDay += analytics.LastDay
+		summary.Ads = append(summary.Ads, analytics)
+	}
+
+	return summary
+}
+
+// GetCampaignRange aggregates the campaign's clicks and impressions from
+// from up to until, with a row per UTC day when daily is set. Last hour
+// and last day stay relative to now, as in GetCampaignSummary.
+func (r *AnalyticsRepository) GetCampaignRange(campaign models.Campaign, adIDs []uint, from, until time.Time, validOnly, daily bool) models.CampaignSummary {
+	ctx := context.Background()
+	summary := models.CampaignSummary{
+		CampaignID: campaign.ID,
+		Name:       campaign.Name,
+		Since:      from,
+		Until:      &until,
+		Ads:        make([]models.AnalyticsResponse, 0, len(adIDs)),
+	}
+	lastHour := time.Now().UTC().Add(-time.Hour)
+	lastDay := time.Now().UTC().Add(-24 * time.Hour)
+
+	for _, adID := range adIDs {
+		analytics := models.AnalyticsResponse{AdID: adID, ValidOnly: validOnly}
+		analytics.ClickCount, analytics.Impressions = r.rangeCounts(ctx, events.Query{AdID: adID, Since: from, Until: until, ValidOnly: validOnly})
+		if analytics.Impressions > 0 {
+			analytics.CTR = float64(analytics.ClickCount) / float64(analytics.Impressions)
+		}
+		var err error
+		if analytics.LastHour, err = r.store.CountClicks(ctx, events.Query{AdID: adID, Since: lastHour, ValidOnly: validOnly}); err != nil {
+			r.logger.WithError(err).Error("Failed to get last hour count")
+		}
+		if analytics.LastDay, err = r.store.CountClicks(ctx, events.Query{AdID: adID, Since: lastDay, ValidOnly: validOnly}); err != nil {
+			r.logger.WithError(err).Error("Failed to get last day count")
+		}
+		summary.ClickCount += analytics.ClickCount
+		summary.LastHour += analytics.LastHour
+		summary.LastDay += analytics.LastDay
+		summary.Ads = append(summary.Ads, analytics)
+	}
+	if !daily {
+		return summary
+	}
+
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(until); day = day.Add(24 * time.Hour) {
+		query := events.Query{Since: day, Until: day.Add(24 * time.Hour), ValidOnly: validOnly}
+		if query.Since.Before(from) {
+			query.Since = from
+		}
+		if query.Until.After(until) {
+			query.Until = until
+		}
+		row := models.SummaryDay{Date: day.Format("2006-01-02")}
+		for _, adID := range adIDs {
+			query.AdID = adID
+			clicks, impressions := r.rangeCounts(ctx, query)
+			row.Clicks += clicks
+			row.Impressions += impressions
+		}
+		if row.Impressions > 0 {
+			row.CTR = float64(row.Clicks) / float64(row.Impressions)
+		}
+		summary.Days = append(summary.Days, row)
+	}
+	return summary
+}
+
+// rangeCounts counts an ad's clicks and impressions in a bounded window.
+// Failures are logged and count as zero.
+func (r *AnalyticsRepository) rangeCounts(ctx context.Context, query events.Query) (clicks, impressions int64) {
+	clicks, err := r.store.CountClicks(ctx, query)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get click count")
+	}
+	impressions, err = r.store.CountImpressions(ctx, query)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to get impression count")
+	}
+	return clicks, impressions
+}
@@ -0,0 +1,84 @@
+package repositories_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TestAnalyticsViewabilityFromRollups checks that a long window reads
+// viewability from the rollups, with raw events before the first whole hour
+// and after coverage, and agrees with a raw scan.
+func TestAnalyticsViewabilityFromRollups(t *testing.T) {
+	db := openTestDB(t)
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	// A video ad, so impressions need the 2s video in-view duration
+	ad := models.Ad{ImageURL: "https://cdn.example.com/ad.mp4", TargetURL: "https://example.com/", Active: true, DurationSeconds: 30}
+	if err := db.Create(&ad).Error; err != nil {
+		t.Fatalf("create ad: %v", err)
+	}
+
+	now := time.Now().UTC()
+	base := now.Truncate(time.Hour).Add(-72 * time.Hour)
+	covered := base.Add(2 * time.Hour)
+	since := base.Add(-30 * time.Minute)
+	impressions := []models.ImpressionEvent{
+		{AdID: ad.ID, Timestamp: base.Add(-10 * time.Minute), TimeInViewMS: 2200, PercentInView: 50},
+		{AdID: ad.ID, Timestamp: base.Add(10 * time.Minute), TimeInViewMS: 2500, PercentInView: 80},
+		{AdID: ad.ID, Timestamp: base.Add(20 * time.Minute), TimeInViewMS: 1500, PercentInView: 80},
+		{AdID: ad.ID, Timestamp: base.Add(30 * time.Minute), TimeInViewMS: 3000, PercentInView: 90, Invalid: true},
+		{AdID: ad.ID, Timestamp: base.Add(65 * time.Minute), TimeInViewMS: 900, PercentInView: 20, Invalid: true},
+		{AdID: ad.ID, Timestamp: base.Add(70 * time.Minute)},
+		{AdID: ad.ID, Timestamp: now.Add(-10 * time.Minute), TimeInViewMS: 2500, PercentInView: 60},
+	}
+	store := repositories.NewEventStore(db)
+	if err := store.SaveImpressions(context.Background(), impressions); err != nil {
+		t.Fatalf("save impressions: %v", err)
+	}
+
+	rollups := repositories.NewRollupRepository(db)
+	if err := rollups.RollupHours(base.Add(-time.Hour), covered); err != nil {
+		t.Fatalf("roll up hours: %v", err)
+	}
+	if err := rollups.SetCoverage(covered); err != nil {
+		t.Fatalf("set coverage: %v", err)
+	}
+
+	raw := repositories.NewAnalyticsRepository(db, store, logger)
+	rolled := repositories.NewAnalyticsRepository(db, store, logger)
+	rolled.SetRollups(rollups, 48*time.Hour)
+
+	want := map[bool]models.ViewabilityStats{
+		false: {Measured: 6, Viewable: 4, ViewableRate: 4.0 / 6},
+		true:  {Measured: 4, Viewable: 3, ViewableRate: 3.0 / 4},
+	}
+	for _, validOnly := range []bool{false, true} {
+		rawStats := raw.GetAdAnalytics(ad.ID, since, validOnly)
+		rolledStats := rolled.GetAdAnalytics(ad.ID, since, validOnly)
+		if rawStats.Viewability == nil || *rawStats.Viewability != want[validOnly] {
+			t.Errorf("validOnly=%v: raw viewability = %+v, want %+v", validOnly, rawStats.Viewability, want[validOnly])
+		}
+		if rolledStats.Viewability == nil || *rolledStats.Viewability != want[validOnly] {
+			t.Errorf("validOnly=%v: rolled up viewability = %+v, want %+v", validOnly, rolledStats.Viewability, want[validOnly])
+		}
+		if rolledStats.Impressions != rawStats.Impressions {
+			t.Errorf("validOnly=%v: rolled up impressions = %d, raw = %d", validOnly, rolledStats.Impressions, rawStats.Impressions)
+		}
+	}
+
+	// Without the raw events inside coverage, only the rollups still count them
+	if err := db.Where("timestamp >= ? AND timestamp < ?", base, covered).Delete(&models.ImpressionEvent{}).Error; err != nil {
+		t.Fatalf("delete covered impressions: %v", err)
+	}
+	if stats := rolled.GetAdAnalytics(ad.ID, since, false); stats.Viewability == nil || *stats.Viewability != want[false] {
+		t.Errorf("viewability after deleting raw events = %+v, want %+v from the rollups", stats.Viewability, want[false])
+	}
+}
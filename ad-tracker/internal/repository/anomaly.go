@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AnomalyRepository struct {
+	db *gorm.DB
+}
+
+func NewAnomalyRepository(db *gorm.DB) *AnomalyRepository {
+	return &AnomalyRepository{db: db}
+}
+
+// Save stores anomalies, skipping those already recorded for the same
+// campaign, metric, detector and hour, and returns how many were new.
+func (r *AnomalyRepository) Save(anomalies []models.Anomaly) (int64, error) {
+	if len(anomalies) == 0 {
+		return 0, nil
+	}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(anomalies, 500)
+	return result.RowsAffected, result.Error
+}
+
+// AnomalyFilter selects anomalies; zero values are ignored.
+type AnomalyFilter struct {
+	CampaignID uint
+	Metric     string
+	Detector   string
+	Since      time.Time
+	Limit      int
+}
+
+// List returns matching anomalies, newest hour first.
+func (r *AnomalyRepository) List(filter AnomalyFilter) ([]models.Anomaly, error) {
+	tx := r.db.Where("bucket_start >= ?", filter.Since)
+	if filter.CampaignID != 0 {
+		tx = tx.Where("campaign_id = ?", filter.CampaignID)
+	}
+	if filter.Metric != "" {
+		tx = tx.Where("metric = ?", filter.Metric)
+	}
+	if filter.Detector != "" {
+		tx = tx.Where("detector = ?", filter.Detector)
+	}
+	var anomalies []models.Anomaly
+	err := tx.Order("bucket_start DESC, id").Limit(filter.Limit).Find(&anomalies).Error
+	return anomalies, err
+}
@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ArchiveRepository struct {
+	db *gorm.DB
+}
+
+func NewArchiveRepository(db *gorm.DB) *ArchiveRepository {
+	return &ArchiveRepository{db: db}
+}
+
+// OldestEvent returns the earliest timestamp in an event table, or nil when
+// the table is empty.
+func (r *ArchiveRepository) OldestEvent(table string) (*time.Time, error) {
+	var oldest struct {
+		At *sqlTime
+	}
+	err := r.db.Table(table).Select("MIN(timestamp) AS at").Scan(&oldest).Error
+	if err != nil || oldest.At == nil {
+		return nil, err
+	}
+	return &oldest.At.Time, nil
+}
+
+func (r *ArchiveRepository) Partition(table string, day time.Time) (*models.ArchivePartition, error) {
+	var partition models.ArchivePartition
+	err := r.db.Where("table_name = ? AND day = ?", table, day).First(&partition).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &partition, nil
+}
+
+// Partitions lists archived days of a table in [from, to).
+func (r *ArchiveRepository) Partitions(table string, from, to time.Time) ([]models.ArchivePartition, error) {
+	var partitions []models.ArchivePartition
+	err := r.db.Where("table_name = ? AND day >= ? AND day < ?", table, from, to).
+		Order("day").
+		Find(&partitions).Error
+	return partitions, err
+}
+
+func (r *ArchiveRepository) RecordPartition(partition *models.ArchivePartition) error {
+	return r.db.Create(partition).Error
+}
+
+// NonEmptyPartitions lists every archived day of a table that holds rows.
+func (r *ArchiveRepository) NonEmptyPartitions(table string) ([]models.ArchivePartition, error) {
+	var partitions []models.ArchivePartition
+	// ROWS is reserved on MySQL, so let GORM quote the column
+	err := r.db.Where("table_name = ?", table).
+		Where(clause.Gt{Column: clause.Column{Name: "rows"}, Value: 0}).
+		Order("day").
+		Find(&partitions).Error
+	return partitions, err
+}
+
+// SavePartition stores a partition whose object was rewritten.
+func (r *ArchiveRepository) SavePartition(partition *models.ArchivePartition) error {
+	return r.db.Save(partition).Error
+}
+
+func (r *ArchiveRepository) StreamClicks(from, to time.Time, batchSize int, fn func([]models.ClickEvent) error) error {
+	var batch []models.ClickEvent
+	return r.db.Model(&models.ClickEvent{}).
+		Where("timestamp >= ? AND timestamp < ?", from, to).
+		Order("id").
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+func (r *ArchiveRepository) StreamImpressions(from, to time.Time, batchSize int, fn func([]models.ImpressionEvent) error) error {
+	var batch []models.ImpressionEvent
+	return r.db.Model(&models.ImpressionEvent{}).
+		Where("timestamp >= ? AND timestamp < ?", from, to).
+		Order("id").
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+}
+
+// RestoreClicks re-inserts archived clicks, skipping ids still present.
+func (r *ArchiveRepository) RestoreClicks(clicks []models.ClickEvent) (int64, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&clicks)
+	return result.RowsAffected, result.Error
+}
+
+// RestoreImpressions re-inserts archived impressions, skipping ids still
+// present.
+func (r *ArchiveRepository) RestoreImpressions(impressions []models.ImpressionEvent) (int64, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&impressions)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"encoding/json"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Record appends an audit event; details is marshalled to JSON.
+func (r *AuditRepository) Record(actor, action, targetType, targetID string, details interface{}) error {
+	event := models.AuditEvent{
+		Actor:      actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+	}
+	if details != nil {
+		encoded, err := json.Marshal(details)
+		if err != nil {
+			return err
+		}
+		event.Details = string(encoded)
+	}
+	return r.db.Create(&event).Error
+}
+
+func (r *AuditRepository) List(action string, limit int) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	tx := r.db.Order("id DESC").Limit(limit)
+	if action != "" {
+		tx = tx.Where("action = ?", action)
+	}
+	err := tx.Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type BlocklistRepository struct {
+	db *gorm.DB
+}
+
+func NewBlocklistRepository(db *gorm.DB) *BlocklistRepository {
+	return &BlocklistRepository{db: db}
+}
+
+func (r *BlocklistRepository) Create(blocked *models.BlockedRange) error {
+	return r.db.Create(blocked).Error
+}
+
+// List returns every range, or only one account's when accountID is set.
+func (r *BlocklistRepository) List(accountID *uint) ([]models.BlockedRange, error) {
+	var ranges []models.BlockedRange
+	tx := r.db.Order("id")
+	if accountID != nil {
+		tx = tx.Where("account_id = ?", *accountID)
+	}
+	err := tx.Find(&ranges).Error
+	return ranges, err
+}
+
+// Active returns the ranges that have not expired.
+func (r *BlocklistRepository) Active() ([]models.BlockedRange, error) {
+	var ranges []models.BlockedRange
+	err := r.db.Where("expires_at IS NULL OR expires_at > ?", time.Now().UTC()).
+		Order("id").
+		Find(&ranges).Error
+	return ranges, err
+}
+
+func (r *BlocklistRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.BlockedRange{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// AccountForAd returns the account owning the ad's campaign, nil when it
+// has none.
+func (r *BlocklistRepository) AccountForAd(adID uint) (*uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.Ad{}).
+		Joins("JOIN campaigns ON campaigns.id = ads.campaign_id").
+		Where("ads.id = ? AND campaigns.account_id IS NOT NULL", adID).
+		Pluck("campaigns.account_id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	return &ids[0], nil
+}
+
+// AdAccounts maps each ad to the account owning its campaign so per-account
+// ranges can be enforced without a lookup per request.
+func (r *BlocklistRepository) AdAccounts() (map[uint]uint, error) {
+	var rows []struct {
+		AdID      uint
+		AccountID uint
+	}
+	err := r.db.Model(&models.Ad{}).
+		Select("ads.id AS ad_id, campaigns.account_id AS account_id").
+		Joins("JOIN campaigns ON campaigns.id = ads.campaign_id").
+		Where("campaigns.account_id IS NOT NULL").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[uint]uint, len(rows))
+	for _, row := range rows {
+		accounts[row.AdID] = row.AccountID
+	}
+	return accounts, nil
+}
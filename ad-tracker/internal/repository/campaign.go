@@ -0,0 +1,168 @@
+package repositories
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+var ErrShareTokenInvalid = errors.New("share token invalid or expired")
+
+type CampaignRepository struct {
+	db *gorm.DB
+}
+
+func NewCampaignRepository(db *gorm.DB) *CampaignRepository {
+	return &CampaignRepository{db: db}
+}
+
+func (r *CampaignRepository) Create(campaign *models.Campaign) error {
+	return r.db.Create(campaign).Error
+}
+
+func (r *CampaignRepository) List() ([]models.Campaign, error) {
+	var campaigns []models.Campaign
+	err := r.db.Order("id").Find(&campaigns).Error
+	return campaigns, err
+}
+
+func (r *CampaignRepository) Get(id uint) (*models.Campaign, error) {
+	var campaign models.Campaign
+	if err := r.db.First(&campaign, id).Error; err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+func (r *CampaignRepository) AdIDs(campaignID uint) ([]uint, error) {
+	var adIDs []uint
+	err := r.db.Model(&models.Ad{}).
+		Where("campaign_id = ?", campaignID).
+		Order("id").
+		Pluck("id", &adIDs).Error
+	return adIDs, err
+}
+
+// UpdateBudget sets the budgets and impression target, and lifts a pause the new budgets no longer
+// justify: a lifetime pause once spend is under the lifetime budget, a
+// daily pause once today's spend is under the daily budget.
+func (r *CampaignRepository) UpdateBudget(id uint, daily, lifetime float64, impressionTarget int64, today string) (*models.Campaign, error) {
+	var campaign models.Campaign
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&campaign, id).Error; err != nil {
+			return err
+		}
+		campaign.DailyBudget, campaign.LifetimeBudget = daily, lifetime
+		campaign.DailyImpressionTarget = impressionTarget
+
+		dailySpend := 0.0
+		if campaign.SpendDate == today {
+			dailySpend = campaign.DailySpend
+		}
+		updates := map[string]interface{}{
+			"daily_budget":            daily,
+			"lifetime_budget":         lifetime,
+			"daily_impression_target": impressionTarget,
+		}
+		if campaign.BudgetPausedAt != nil && campaign.ExhaustedBudget(dailySpend, campaign.LifetimeSpend) == "" {
+			campaign.BudgetPausedAt, campaign.BudgetPauseReason = nil, ""
+			updates["budget_paused_at"], updates["budget_pause_reason"] = nil, ""
+		}
+		return tx.Model(&campaign).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// UpdateFrequencyCap sets the per-user hourly and daily caps.
+func (r *CampaignRepository) UpdateFrequencyCap(id uint, hour, day int) (*models.Campaign, error) {
+	result := r.db.Model(&models.Campaign{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"frequency_cap_hour": hour, "frequency_cap_day": day})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.Get(id)
+}
+
+// AddSpend adds amount to the lifetime spend and to the daily spend of day,
+// restarting the daily total when day is newer than the stored one.
+// Increments are applied in SQL so replicas can add concurrently.
+func (r *CampaignRepository) AddSpend(id uint, day string, amount float64) error {
+	return r.db.Model(&models.Campaign{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"lifetime_spend": gorm.Expr("lifetime_spend + ?", amount),
+		"daily_spend": gorm.Expr(
+			"CASE WHEN spend_date = ? THEN daily_spend + ? WHEN spend_date IS NULL OR spend_date < ? THEN ? ELSE daily_spend END",
+			day, amount, day, amount),
+		"spend_date": gorm.Expr("CASE WHEN spend_date IS NULL OR spend_date < ? THEN ? ELSE spend_date END", day, day),
+	}).Error
+}
+
+// PauseForBudget pauses serving for an exhausted budget. It reports false
+// when the campaign was already paused, e.g. by another replica.
+func (r *CampaignRepository) PauseForBudget(id uint, reason string) (bool, error) {
+	result := r.db.Model(&models.Campaign{}).
+		Where("id = ? AND budget_paused_at IS NULL", id).
+		Updates(map[string]interface{}{"budget_paused_at": time.Now().UTC(), "budget_pause_reason": reason})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ResumeDailyBudgets lifts daily budget pauses from before dayStart unless
+// the lifetime budget is exhausted too, and returns how many resumed.
+func (r *CampaignRepository) ResumeDailyBudgets(dayStart time.Time) (int64, error) {
+	result := r.db.Model(&models.Campaign{}).
+		Where("budget_pause_reason = ? AND budget_paused_at < ?", models.BudgetPauseDaily, dayStart).
+		Where("lifetime_budget = 0 OR lifetime_spend < lifetime_budget").
+		Updates(map[string]interface{}{"budget_paused_at": nil, "budget_pause_reason": ""})
+	return result.RowsAffected, result.Error
+}
+
+// CreateShareToken stores the hash of rawToken for the campaign.
+func (r *CampaignRepository) CreateShareToken(token *models.ShareToken, rawToken string) error {
+	token.TokenHash = hashToken(rawToken)
+	return r.db.Create(token).Error
+}
+
+// ResolveShareToken returns the token record if it is known, unrevoked and
+// unexpired.
+func (r *CampaignRepository) ResolveShareToken(rawToken string) (*models.ShareToken, error) {
+	var token models.ShareToken
+	err := r.db.Where("token_hash = ?", hashToken(rawToken)).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrShareTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrShareTokenInvalid
+	}
+	return &token, nil
+}
+
+func (r *CampaignRepository) RevokeShareToken(id uint) error {
+	result := r.db.Model(&models.ShareToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func hashToken(rawToken string) string {
+	sum := sha256.Sum256([]byte(rawToken))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CaptureRepository struct {
+	db *gorm.DB
+}
+
+func NewCaptureRepository(db *gorm.DB) *CaptureRepository {
+	return &CaptureRepository{db: db}
+}
+
+func (r *CaptureRepository) CreateIncident(incident *models.CaptureIncident) error {
+	return r.db.Create(incident).Error
+}
+
+// SaveRequests stores captured payloads and bumps the incident counters.
+func (r *CaptureRepository) SaveRequests(requests []models.CapturedRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&requests).Error; err != nil {
+			return err
+		}
+		counts := make(map[uint]int64)
+		for _, req := range requests {
+			counts[req.IncidentID]++
+		}
+		for id, n := range counts {
+			err := tx.Model(&models.CaptureIncident{}).
+				Where("id = ?", id).
+				Update("captured", gorm.Expr("captured + ?", n)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *CaptureRepository) ListIncidents(limit int) ([]models.CaptureIncident, error) {
+	var incidents []models.CaptureIncident
+	err := r.db.Order("started_at DESC").Limit(limit).Find(&incidents).Error
+	return incidents, err
+}
+
+func (r *CaptureRepository) GetIncident(id uint, requestLimit int) (*models.CaptureIncident, error) {
+	var incident models.CaptureIncident
+	err := r.db.Preload("Requests", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("received_at").Limit(requestLimit)
+	}).First(&incident, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
@@ -0,0 +1,380 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// errCatalogRollback undoes an import that found problems or was a dry run,
+// once its report is complete.
+var errCatalogRollback = errors.New("catalog import rolled back")
+
+type CatalogRepository struct {
+	db *gorm.DB
+}
+
+func NewCatalogRepository(db *gorm.DB) *CatalogRepository {
+	return &CatalogRepository{db: db}
+}
+
+// Import upserts the parsed rows in one transaction. Problems only visible
+// against stored data, such as unknown accounts, ambiguous campaign names,
+// new ads without URLs or lines contradicting each other, are reported and
+// roll the whole import back, as does a dry run.
+func (r *CatalogRepository) Import(rows []models.CatalogRow, dryRun bool) (models.CatalogImportReport, error) {
+	report := models.CatalogImportReport{DryRun: dryRun, Rows: len(rows)}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		plan := catalogPlan{
+			tx:        tx,
+			report:    &report,
+			accounts:  make(map[uint]bool),
+			campaigns: make(map[catalogCampaignKey]*plannedCampaign),
+			ads:       make(map[catalogAdKey]int),
+		}
+		for _, row := range rows {
+			if err := plan.add(row); err != nil {
+				return err
+			}
+		}
+		if len(report.Errors) > 0 {
+			return errCatalogRollback
+		}
+		if err := plan.apply(); err != nil {
+			return err
+		}
+		if dryRun {
+			return errCatalogRollback
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errCatalogRollback):
+		err = nil
+	case err == nil:
+		report.Applied = true
+	}
+	if dryRun {
+		forgetCreatedIDs(report.Campaigns)
+		forgetCreatedIDs(report.Ads)
+	}
+	return report, err
+}
+
+// forgetCreatedIDs drops the ids a rolled-back dry run handed out.
+func forgetCreatedIDs(items []models.CatalogImportItem) {
+	for i := range items {
+		if items[i].Action == models.CatalogCreated {
+			items[i].ID, items[i].CampaignID = 0, 0
+		}
+	}
+}
+
+type catalogCampaignKey struct {
+	accountID uint
+	name      string
+}
+
+type catalogAdKey struct {
+	campaign catalogCampaignKey
+	ref      string
+}
+
+// plannedCampaign merges the campaign values of every line naming it;
+// fields stay nil when no line sets them.
+type plannedCampaign struct {
+	line     int
+	stored   *models.Campaign
+	values   catalogCampaignValues
+	setBy    map[string]int
+	adLines  []models.CatalogRow
+	existing map[string]*models.Ad
+}
+
+type catalogCampaignValues struct {
+	active         *bool
+	costPerClick   *float64
+	costPerMille   *float64
+	dailyBudget    *float64
+	lifetimeBudget *float64
+}
+
+// catalogPlan checks the rows against stored data, then writes them.
+type catalogPlan struct {
+	tx        *gorm.DB
+	report    *models.CatalogImportReport
+	accounts  map[uint]bool
+	campaigns map[catalogCampaignKey]*plannedCampaign
+	order     []catalogCampaignKey
+	ads       map[catalogAdKey]int
+}
+
+func (p *catalogPlan) fail(line int, column, format string, args ...interface{}) {
+	p.report.Errors = append(p.report.Errors, models.CatalogImportError{Line: line, Column: column, Error: fmt.Sprintf(format, args...)})
+}
+
+func (p *catalogPlan) add(row models.CatalogRow) error {
+	key := catalogCampaignKey{name: row.CampaignName}
+	if row.AccountID != nil {
+		key.accountID = *row.AccountID
+	}
+	campaign, ok := p.campaigns[key]
+	if !ok {
+		var err error
+		if campaign, err = p.loadCampaign(row, key); err != nil {
+			return err
+		}
+		if campaign == nil {
+			return nil
+		}
+		p.campaigns[key] = campaign
+		p.order = append(p.order, key)
+	}
+	p.mergeCampaign(campaign, row)
+
+	if row.AdRef == "" {
+		return nil
+	}
+	adKey := catalogAdKey{campaign: key, ref: row.AdRef}
+	if first, ok := p.ads[adKey]; ok {
+		p.fail(row.Line, "ad_ref", "%q repeats line %d for this campaign", row.AdRef, first)
+		return nil
+	}
+	p.ads[adKey] = row.Line
+
+	if campaign.stored != nil {
+		var ads []models.Ad
+		if err := p.tx.Where("campaign_id = ? AND external_ref = ?", campaign.stored.ID, row.AdRef).Limit(2).Find(&ads).Error; err != nil {
+			return err
+		}
+		if len(ads) > 1 {
+			p.fail(row.Line, "ad_ref", "%q matches several ads of the campaign", row.AdRef)
+			return nil
+		}
+		if len(ads) == 1 {
+			campaign.existing[row.AdRef] = &ads[0]
+		}
+	}
+	if campaign.existing[row.AdRef] == nil {
+		if row.AdImageURL == nil {
+			p.fail(row.Line, "ad_image_url", "is required for a new ad")
+		}
+		if row.AdTargetURL == nil {
+			p.fail(row.Line, "ad_target_url", "is required for a new ad")
+		}
+	}
+	campaign.adLines = append(campaign.adLines, row)
+	return nil
+}
+
+// loadCampaign finds the stored campaign a line names, if any. It returns
+// nil after reporting a line that cannot be matched.
+func (p *catalogPlan) loadCampaign(row models.CatalogRow, key catalogCampaignKey) (*plannedCampaign, error) {
+	query := p.tx.Where("name = ?", key.name)
+	if row.AccountID != nil {
+		known, checked := p.accounts[key.accountID]
+		if !checked {
+			var count int64
+			if err := p.tx.Model(&models.Account{}).Where("id = ?", key.accountID).Count(&count).Error; err != nil {
+				return nil, err
+			}
+			known = count > 0
+			p.accounts[key.accountID] = known
+		}
+		if !known {
+			p.fail(row.Line, "account_id", "account %d does not exist", key.accountID)
+			return nil, nil
+		}
+		query = query.Where("account_id = ?", key.accountID)
+	} else {
+		query = query.Where("account_id IS NULL")
+	}
+
+	var matches []models.Campaign
+	if err := query.Limit(2).Find(&matches).Error; err != nil {
+		return nil, err
+	}
+	if len(matches) > 1 {
+		p.fail(row.Line, "campaign_name", "%q matches several campaigns; rename them first", key.name)
+		return nil, nil
+	}
+	campaign := &plannedCampaign{line: row.Line, setBy: make(map[string]int), existing: make(map[string]*models.Ad)}
+	if len(matches) == 1 {
+		campaign.stored = &matches[0]
+	}
+	return campaign, nil
+}
+
+func (p *catalogPlan) mergeCampaign(campaign *plannedCampaign, row models.CatalogRow) {
+	merge := func(column string, current interface{}, given interface{}, set func()) {
+		if first, ok := campaign.setBy[column]; ok {
+			if current != given {
+				p.fail(row.Line, column, "conflicts with line %d for this campaign", first)
+			}
+			return
+		}
+		campaign.setBy[column] = row.Line
+		set()
+	}
+	values := &campaign.values
+	if row.CampaignActive != nil {
+		merge("campaign_active", deref(values.active), *row.CampaignActive, func() { values.active = row.CampaignActive })
+	}
+	if row.CostPerClick != nil {
+		merge("cost_per_click", deref(values.costPerClick), *row.CostPerClick, func() { values.costPerClick = row.CostPerClick })
+	}
+	if row.CostPerMille != nil {
+		merge("cost_per_mille", deref(values.costPerMille), *row.CostPerMille, func() { values.costPerMille = row.CostPerMille })
+	}
+	if row.DailyBudget != nil {
+		merge("daily_budget", deref(values.dailyBudget), *row.DailyBudget, func() { values.dailyBudget = row.DailyBudget })
+	}
+	if row.LifetimeBudget != nil {
+		merge("lifetime_budget", deref(values.lifetimeBudget), *row.LifetimeBudget, func() { values.lifetimeBudget = row.LifetimeBudget })
+	}
+}
+
+func deref[T any](value *T) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// apply writes the planned campaigns and their ads.
+func (p *catalogPlan) apply() error {
+	for _, key := range p.order {
+		campaign := p.campaigns[key]
+		id, action, err := p.applyCampaign(key, campaign)
+		if err != nil {
+			return err
+		}
+		p.report.Campaigns = append(p.report.Campaigns, models.CatalogImportItem{Line: campaign.line, ID: id, Name: key.name, Action: action})
+		switch action {
+		case models.CatalogCreated:
+			p.report.CampaignsCreated++
+		case models.CatalogUpdated:
+			p.report.CampaignsUpdated++
+		}
+
+		for _, row := range campaign.adLines {
+			adID, action, err := p.applyAd(id, campaign.existing[row.AdRef], row)
+			if err != nil {
+				return err
+			}
+			p.report.Ads = append(p.report.Ads, models.CatalogImportItem{Line: row.Line, ID: adID, CampaignID: id, Name: row.AdRef, Action: action})
+			switch action {
+			case models.CatalogCreated:
+				p.report.AdsCreated++
+			case models.CatalogUpdated:
+				p.report.AdsUpdated++
+			}
+		}
+	}
+	return nil
+}
+
+func (p *catalogPlan) applyCampaign(key catalogCampaignKey, planned *plannedCampaign) (uint, string, error) {
+	values := planned.values
+	if planned.stored == nil {
+		campaign := models.Campaign{Name: key.name, Active: true}
+		if key.accountID != 0 {
+			accountID := key.accountID
+			campaign.AccountID = &accountID
+		}
+		setIfGiven(&campaign.CostPerClick, values.costPerClick)
+		setIfGiven(&campaign.CostPerMille, values.costPerMille)
+		setIfGiven(&campaign.DailyBudget, values.dailyBudget)
+		setIfGiven(&campaign.LifetimeBudget, values.lifetimeBudget)
+		if err := p.tx.Create(&campaign).Error; err != nil {
+			return 0, "", err
+		}
+		// Active has a column default, so false is not inserted
+		if values.active != nil && !*values.active {
+			if err := p.tx.Model(&campaign).Update("active", false).Error; err != nil {
+				return 0, "", err
+			}
+		}
+		return campaign.ID, models.CatalogCreated, nil
+	}
+
+	campaign := *planned.stored
+	updates := make(map[string]interface{})
+	changed := func(column string, stored interface{}, given interface{}) {
+		if given != nil && stored != given {
+			updates[column] = given
+		}
+	}
+	changed("active", campaign.Active, deref(values.active))
+	changed("cost_per_click", campaign.CostPerClick, deref(values.costPerClick))
+	changed("cost_per_mille", campaign.CostPerMille, deref(values.costPerMille))
+	changed("daily_budget", campaign.DailyBudget, deref(values.dailyBudget))
+	changed("lifetime_budget", campaign.LifetimeBudget, deref(values.lifetimeBudget))
+	if len(updates) == 0 {
+		return campaign.ID, models.CatalogUnchanged, nil
+	}
+
+	// Like UpdateBudget, lift a budget pause the new budgets no longer
+	// justify
+	setIfGiven(&campaign.DailyBudget, values.dailyBudget)
+	setIfGiven(&campaign.LifetimeBudget, values.lifetimeBudget)
+	dailySpend := 0.0
+	if campaign.SpendDate == time.Now().UTC().Format("2006-01-02") {
+		dailySpend = campaign.DailySpend
+	}
+	if campaign.BudgetPausedAt != nil && campaign.ExhaustedBudget(dailySpend, campaign.LifetimeSpend) == "" {
+		updates["budget_paused_at"], updates["budget_pause_reason"] = nil, ""
+	}
+	if err := p.tx.Model(&models.Campaign{}).Where("id = ?", campaign.ID).Updates(updates).Error; err != nil {
+		return 0, "", err
+	}
+	return campaign.ID, models.CatalogUpdated, nil
+}
+
+func (p *catalogPlan) applyAd(campaignID uint, stored *models.Ad, row models.CatalogRow) (uint, string, error) {
+	if stored == nil {
+		ad := models.Ad{CampaignID: &campaignID, ExternalRef: row.AdRef, Active: true}
+		setIfGiven(&ad.ImageURL, row.AdImageURL)
+		setIfGiven(&ad.TargetURL, row.AdTargetURL)
+		setIfGiven(&ad.Title, row.AdTitle)
+		setIfGiven(&ad.DurationSeconds, row.AdDurationSeconds)
+		if err := p.tx.Create(&ad).Error; err != nil {
+			return 0, "", err
+		}
+		if row.AdActive != nil && !*row.AdActive {
+			if err := p.tx.Model(&ad).Update("active", false).Error; err != nil {
+				return 0, "", err
+			}
+		}
+		return ad.ID, models.CatalogCreated, nil
+	}
+
+	updates := make(map[string]interface{})
+	changed := func(column string, current interface{}, given interface{}) {
+		if given != nil && current != given {
+			updates[column] = given
+		}
+	}
+	changed("image_url", stored.ImageURL, deref(row.AdImageURL))
+	changed("target_url", stored.TargetURL, deref(row.AdTargetURL))
+	changed("title", stored.Title, deref(row.AdTitle))
+	changed("duration_seconds", stored.DurationSeconds, deref(row.AdDurationSeconds))
+	changed("active", stored.Active, deref(row.AdActive))
+	if len(updates) == 0 {
+		return stored.ID, models.CatalogUnchanged, nil
+	}
+	if err := p.tx.Model(&models.Ad{}).Where("id = ?", stored.ID).Updates(updates).Error; err != nil {
+		return 0, "", err
+	}
+	return stored.ID, models.CatalogUpdated, nil
+}
+
+func setIfGiven[T any](field *T, given *T) {
+	if given != nil {
+		*field = *given
+	}
+}
@@ -0,0 +1,204 @@
+package repositories
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ConversionRepository struct {
+	db *gorm.DB
+}
+
+func NewConversionRepository(db *gorm.DB) *ConversionRepository {
+	return &ConversionRepository{db: db}
+}
+
+func (r *ConversionRepository) Create(conversion *models.Conversion) error {
+	return r.db.Create(conversion).Error
+}
+
+// ClickByClickID returns the click with the given click_id, or nil.
+func (r *ConversionRepository) ClickByClickID(clickID string) (*models.ClickEvent, error) {
+	var click models.ClickEvent
+	err := r.db.Where("click_id = ?", clickID).First(&click).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &click, nil
+}
+
+// PostbackExists reports whether a conversion was already recorded for the
+// click and transaction pair, so networks retrying a postback don't double
+// count. Postbacks without a transaction id pair with the empty one.
+func (r *ConversionRepository) PostbackExists(clickID, transactionID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Conversion{}).
+		Where("click_id = ? AND transaction_id = ?", clickID, transactionID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// LastClick returns the latest click by any of the users (or by the IP when
+// there are none) in [from, to], or nil when there is none.
+func (r *ConversionRepository) LastClick(userIDs []string, ip string, from, to time.Time) (*models.ClickEvent, error) {
+	var click models.ClickEvent
+	err := identityScope(r.db, userIDs, ip).
+		Where("timestamp >= ? AND timestamp <= ?", from, to).
+		Order("timestamp DESC").
+		First(&click).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &click, nil
+}
+
+// LastImpression is the view-through counterpart of LastClick.
+func (r *ConversionRepository) LastImpression(userIDs []string, ip string, from, to time.Time) (*models.ImpressionEvent, error) {
+	var impression models.ImpressionEvent
+	err := identityScope(r.db, userIDs, ip).
+		Where("timestamp >= ? AND timestamp <= ?", from, to).
+		Order("timestamp DESC").
+		First(&impression).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &impression, nil
+}
+
+func (r *ConversionRepository) Report(since time.Time) (models.ConversionReport, error) {
+	report := models.ConversionReport{Since: since, Ads: []models.AdConversions{}}
+
+	var totals struct {
+		Total        int64
+		ClickThrough int64
+		ViewThrough  int64
+	}
+	err := r.db.Model(&models.Conversion{}).
+		Select(`
+			COUNT(*) AS total,
+			COUNT(CASE WHEN attribution_type = ? THEN 1 END) AS click_through,
+			COUNT(CASE WHEN attribution_type = ? THEN 1 END) AS view_through
+		`, models.AttributionClickThrough, models.AttributionViewThrough).
+		Where("timestamp >= ?", since).
+		Scan(&totals).Error
+	if err != nil {
+		return report, err
+	}
+	report.Total = totals.Total
+	report.ClickThrough = totals.ClickThrough
+	report.ViewThrough = totals.ViewThrough
+	report.Unattributed = totals.Total - totals.ClickThrough - totals.ViewThrough
+
+	err = r.db.Model(&models.Conversion{}).
+		Select(`
+			attributed_ad_id AS ad_id,
+			COUNT(CASE WHEN attribution_type = ? THEN 1 END) AS click_through,
+			COUNT(CASE WHEN attribution_type = ? THEN 1 END) AS view_through,
+			COALESCE(SUM(value), 0) AS value
+		`, models.AttributionClickThrough, models.AttributionViewThrough).
+		Where("timestamp >= ? AND attributed_ad_id IS NOT NULL", since).
+		Group("attributed_ad_id").
+		Order("attributed_ad_id").
+		Scan(&report.Ads).Error
+	return report, err
+}
+
+// ClickLatencies joins click-through conversions since the given time to
+// the clicks they were attributed to, optionally for one campaign.
+func (r *ConversionRepository) ClickLatencies(since time.Time, campaignID uint) ([]models.ConversionLatency, error) {
+	query := r.db.Table("conversions").
+		Select("ads.campaign_id AS campaign_id, click_events.timestamp AS clicked_at, conversions.timestamp AS converted_at").
+		Joins("JOIN click_events ON click_events.id = conversions.attributed_event_id").
+		Joins("JOIN ads ON ads.id = click_events.ad_id").
+		Where("conversions.attribution_type = ? AND conversions.timestamp >= ?", models.AttributionClickThrough, since)
+	if campaignID != 0 {
+		query = query.Where("ads.campaign_id = ?", campaignID)
+	}
+
+	var latencies []models.ConversionLatency
+	err := query.Scan(&latencies).Error
+	return latencies, err
+}
+
+func (r *ConversionRepository) ListSince(since time.Time) ([]models.Conversion, error) {
+	var conversions []models.Conversion
+	err := r.db.Where("timestamp >= ?", since).Order("timestamp").Find(&conversions).Error
+	return conversions, err
+}
+
+// ListBetween returns the conversions in [from, until), oldest first.
+func (r *ConversionRepository) ListBetween(from, until time.Time) ([]models.Conversion, error) {
+	var conversions []models.Conversion
+	err := r.db.Where("timestamp >= ? AND timestamp < ?", from, until).Order("timestamp").Find(&conversions).Error
+	return conversions, err
+}
+
+// identityChunk bounds the identifiers sent in one IN list.
+const identityChunk = 500
+
+// ClicksForIdentities loads clicks in [from, to] belonging to any of the
+// given user ids or IP addresses, oldest first. Long identifier lists are
+// queried identityChunk at a time.
+func (r *ConversionRepository) ClicksForIdentities(userIDs, ips []string, from, to time.Time) ([]models.ClickEvent, error) {
+	var clicks []models.ClickEvent
+	seen := make(map[uint]bool)
+	load := func(column string, values []string) error {
+		values = slices.Clone(values)
+		slices.Sort(values)
+		values = slices.Compact(values)
+		for start := 0; start < len(values); start += identityChunk {
+			var batch []models.ClickEvent
+			err := r.db.Where(column+" IN ?", values[start:min(start+identityChunk, len(values))]).
+				Where("timestamp >= ? AND timestamp <= ?", from, to).
+				Find(&batch).Error
+			if err != nil {
+				return err
+			}
+			// A click can match both a user id and an address
+			for _, click := range batch {
+				if !seen[click.ID] {
+					seen[click.ID] = true
+					clicks = append(clicks, click)
+				}
+			}
+		}
+		return nil
+	}
+	if err := load("user_id", userIDs); err != nil {
+		return nil, err
+	}
+	if err := load("ip_address", ips); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(clicks, func(i, j int) bool {
+		if !clicks[i].Timestamp.Equal(clicks[j].Timestamp) {
+			return clicks[i].Timestamp.Before(clicks[j].Timestamp)
+		}
+		return clicks[i].ID < clicks[j].ID
+	})
+	return clicks, nil
+}
+
+// identityScope matches events by user id when known, falling back to IP.
+func identityScope(db *gorm.DB, userIDs []string, ip string) *gorm.DB {
+	if len(userIDs) > 0 {
+		return db.Where("user_id IN ?", userIDs)
+	}
+	return db.Where("ip_address = ?", ip)
+}
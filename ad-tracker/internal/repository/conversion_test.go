@@ -0,0 +1,52 @@
+package repositories_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+)
+
+func TestClicksForIdentitiesChunksLongLists(t *testing.T) {
+	db := openTestDB(t)
+	repo := repositories.NewConversionRepository(db)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// More users than fit in one IN list, clicked newest first
+	const users = 1200
+	clicks := make([]models.ClickEvent, 0, users+1)
+	userIDs := make([]string, 0, users)
+	for i := 0; i < users; i++ {
+		userID := "user-" + strconv.Itoa(i)
+		userIDs = append(userIDs, userID)
+		clicks = append(clicks, models.ClickEvent{ClickID: "c" + strconv.Itoa(i), AdID: 1, UserID: userID, IPAddress: "198.51.100.1", Timestamp: now.Add(-time.Duration(i) * time.Second)})
+	}
+	clicks = append(clicks, models.ClickEvent{ClickID: "anonymous", AdID: 1, IPAddress: "203.0.113.9", Timestamp: now.Add(-time.Hour)})
+	if err := db.CreateInBatches(&clicks, 200).Error; err != nil {
+		t.Fatalf("create clicks: %v", err)
+	}
+
+	// The shared address matches every user's click a second time
+	found, err := repo.ClicksForIdentities(append(userIDs, userIDs[0]), []string{"198.51.100.1", "203.0.113.9"}, now.Add(-2*time.Hour), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != users+1 {
+		t.Fatalf("found %d clicks, want %d", len(found), users+1)
+	}
+	if found[0].ClickID != "anonymous" {
+		t.Errorf("oldest click is %s, want anonymous", found[0].ClickID)
+	}
+	for i := 1; i < len(found); i++ {
+		if found[i].Timestamp.Before(found[i-1].Timestamp) {
+			t.Fatalf("clicks out of time order at %d", i)
+		}
+	}
+
+	none, err := repo.ClicksForIdentities(nil, nil, now.Add(-2*time.Hour), now)
+	if err != nil || len(none) != 0 {
+		t.Fatalf("got %d clicks, %v without identifiers", len(none), err)
+	}
+}
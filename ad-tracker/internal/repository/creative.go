@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CreativeRepository struct {
+	db *gorm.DB
+}
+
+func NewCreativeRepository(db *gorm.DB) *CreativeRepository {
+	return &CreativeRepository{db: db}
+}
+
+// Create adds a creative to its ad, returning gorm.ErrRecordNotFound when
+// the ad does not exist.
+func (r *CreativeRepository) Create(creative *models.Creative) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var ads int64
+		if err := tx.Model(&models.Ad{}).Where("id = ?", creative.AdID).Count(&ads).Error; err != nil {
+			return err
+		}
+		if ads == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(creative).Error
+	})
+}
+
+// ListForAd returns all of the ad's creatives, including inactive ones.
+func (r *CreativeRepository) ListForAd(adID uint) ([]models.Creative, error) {
+	var creatives []models.Creative
+	err := r.db.Where("ad_id = ?", adID).Order("id").Find(&creatives).Error
+	return creatives, err
+}
+
+// Update applies the non-nil fields of req.
+func (r *CreativeRepository) Update(id uint, req models.CreativeUpdateRequest) (models.Creative, error) {
+	var creative models.Creative
+	if err := r.db.First(&creative, id).Error; err != nil {
+		return creative, err
+	}
+	updates := map[string]interface{}{}
+	if req.Weight != nil {
+		updates["weight"] = *req.Weight
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if len(updates) > 0 {
+		if err := r.db.Model(&creative).Updates(updates).Error; err != nil {
+			return creative, err
+		}
+	}
+	return creative, nil
+}
+
+// Stats counts valid impressions and clicks of an ad since the given time,
+// grouped by the creative they were recorded with.
+func (r *CreativeRepository) Stats(adID uint, since time.Time) ([]models.CreativeStats, error) {
+	counts, err := countEventsBy(r.db, "creative_id", "ad_id = ? AND invalid = ? AND timestamp >= ?", adID, false, since)
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]models.CreativeStats, 0, len(counts))
+	for _, count := range counts {
+		stat := models.CreativeStats{CreativeID: count.id, Impressions: count.impressions, Clicks: count.clicks}
+		if stat.Impressions > 0 {
+			stat.CTR = float64(stat.Clicks) / float64(stat.Impressions)
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
+
+// eventCounts is one group of countEventsBy; id is nil for events without
+// a value in the grouping column.
+type eventCounts struct {
+	id          *uint
+	impressions int64
+	clicks      int64
+}
+
+// countEventsBy counts the impressions and clicks matching where, grouped
+// by column.
+func countEventsBy(db *gorm.DB, column, where string, args ...interface{}) ([]eventCounts, error) {
+	type row struct {
+		ID    *uint
+		Count int64
+	}
+	var impressions, clicks []row
+	err := db.Model(&models.ImpressionEvent{}).Select(column+" AS id, COUNT(*) AS count").
+		Where(where, args...).Group(column).Scan(&impressions).Error
+	if err != nil {
+		return nil, err
+	}
+	err = db.Model(&models.ClickEvent{}).Select(column+" AS id, COUNT(*) AS count").
+		Where(where, args...).Group(column).Scan(&clicks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var counts []eventCounts
+	index := map[uint]int{}
+	unattributed := -1
+	find := func(id *uint) *eventCounts {
+		i, ok := unattributed, unattributed >= 0
+		if id != nil {
+			i, ok = index[*id]
+		}
+		if !ok {
+			i = len(counts)
+			counts = append(counts, eventCounts{id: id})
+			if id == nil {
+				unattributed = i
+			} else {
+				index[*id] = i
+			}
+		}
+		return &counts[i]
+	}
+	for _, r := range impressions {
+		find(r.ID).impressions = r.Count
+	}
+	for _, r := range clicks {
+		find(r.ID).clicks = r.Count
+	}
+	return counts, nil
+}
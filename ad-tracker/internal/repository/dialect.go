@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The raw SQL in this package is written for Postgres. These helpers cover
+// the few spots where MySQL (and SQLite in local development) need a
+// different spelling.
+
+const (
+	dialectPostgres = "postgres"
+	dialectMySQL    = "mysql"
+	dialectSQLite   = "sqlite"
+)
+
+func dialectOf(db *gorm.DB) string {
+	return db.Dialector.Name()
+}
+
+// truncateUTC truncates a timestamp column to the hour or day. Timestamps
+// are stored in UTC on MySQL and SQLite, so only Postgres needs the zone.
+// SQLite compares times as text, so the result keeps the offset suffix the
+// driver writes on bound times.
+func truncateUTC(db *gorm.DB, unit, column string) string {
+	switch dialectOf(db) {
+	case dialectPostgres:
+		return fmt.Sprintf("date_trunc('%s', %s, 'UTC')", unit, column)
+	case dialectMySQL:
+		if unit == "day" {
+			return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d 00:00:00')", column)
+		}
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00:00')", column)
+	}
+	if unit == "day" {
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d 00:00:00+00:00', %s)", column)
+	}
+	return fmt.Sprintf("strftime('%%Y-%%m-%%d %%H:00:00+00:00', %s)", column)
+}
+
+// concat joins string expressions; MySQL reads || as logical OR.
+func concat(db *gorm.DB, parts ...string) string {
+	if dialectOf(db) == dialectMySQL {
+		return "CONCAT(" + strings.Join(parts, ", ") + ")"
+	}
+	return strings.Join(parts, " || ")
+}
+
+// ipHashMatch matches rows of table whose IP address has the given hex
+// SHA-256. SQLite has no SHA-256 function, so there the table's distinct
+// addresses are hashed here and matched by value.
+func ipHashMatch(db *gorm.DB, table, digest string) (string, []interface{}, error) {
+	switch dialectOf(db) {
+	case dialectMySQL:
+		return "SHA2(ip_address, 256) = ?", []interface{}{digest}, nil
+	case dialectSQLite:
+		var addresses []string
+		if err := db.Table(table).Distinct("ip_address").Pluck("ip_address", &addresses).Error; err != nil {
+			return "", nil, err
+		}
+		matches := []string{}
+		for _, address := range addresses {
+			sum := sha256.Sum256([]byte(address))
+			if hex.EncodeToString(sum[:]) == digest {
+				matches = append(matches, address)
+			}
+		}
+		return "ip_address IN ?", []interface{}{matches}, nil
+	}
+	return "encode(sha256(ip_address::bytea), 'hex') = ?", []interface{}{digest}, nil
+}
+
+// sqlTime scans a timestamp computed by an aggregate such as MIN(timestamp),
+// which SQLite returns as text since the result has no column type.
+type sqlTime struct {
+	time.Time
+}
+
+// sqliteTimeLayouts are the ways the SQLite driver writes timestamps.
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+func (t sqlTime) Value() (driver.Value, error) {
+	return t.Time, nil
+}
+
+func (t *sqlTime) Scan(value interface{}) error {
+	var text string
+	switch v := value.(type) {
+	case time.Time:
+		t.Time = v
+		return nil
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a timestamp", value)
+	}
+	for _, layout := range sqliteTimeLayouts {
+		if parsed, err := time.Parse(layout, text); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot parse timestamp %q", text)
+}
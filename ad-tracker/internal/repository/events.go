@@ -0,0 +1,309 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// EventStore is the GORM-backed implementation of events.EventStore.
+type EventStore struct {
+	db *gorm.DB
+}
+
+func NewEventStore(db *gorm.DB) *EventStore {
+	return &EventStore{db: db}
+}
+
+func (s *EventStore) SaveClicks(ctx context.Context, clicks []models.ClickEvent) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Create(&clicks).Error
+}
+
+// StoredClickIDs returns which of the click ids are already stored.
+func (s *EventStore) StoredClickIDs(ctx context.Context, clickIDs []string) (map[string]bool, error) {
+	var found []string
+	err := s.db.WithContext(ctx).Model(&models.ClickEvent{}).
+		Where("click_id IN ?", clickIDs).
+		Distinct().
+		Pluck("click_id", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	stored := make(map[string]bool, len(found))
+	for _, id := range found {
+		stored[id] = true
+	}
+	return stored, nil
+}
+
+func (s *EventStore) CountClicks(ctx context.Context, query events.Query) (int64, error) {
+	var count int64
+	err := s.filter(ctx, query).Model(&models.ClickEvent{}).Count(&count).Error
+	return count, err
+}
+
+func (s *EventStore) ListClicks(ctx context.Context, query events.Query) ([]models.ClickEvent, error) {
+	var clicks []models.ClickEvent
+	tx := s.filter(ctx, query).Order("timestamp DESC")
+	if query.Limit > 0 {
+		tx = tx.Limit(query.Limit)
+	}
+	err := tx.Find(&clicks).Error
+	return clicks, err
+}
+
+func (s *EventStore) DistinctAdIDs(ctx context.Context, since time.Time) ([]uint, error) {
+	var adIDs []uint
+	err := s.db.WithContext(ctx).Model(&models.ClickEvent{}).
+		Where("timestamp >= ?", since).
+		Distinct("ad_id").
+		Pluck("ad_id", &adIDs).Error
+	return adIDs, err
+}
+
+func (s *EventStore) PlaybackStats(ctx context.Context, query events.Query, completeAfter int64) (models.PlaybackStats, error) {
+	var result struct {
+		Samples   int64
+		Avg       float64
+		Median    float64
+		P90       float64
+		Completed int64
+	}
+
+	percentiles := `
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY video_playback_time), 0) AS median,
+			COALESCE(percentile_cont(0.9) WITHIN GROUP (ORDER BY video_playback_time), 0) AS p90,`
+	postgres := dialectOf(s.db) == dialectPostgres
+	if !postgres {
+		percentiles = ""
+	}
+	err := s.filter(ctx, query).Model(&models.ClickEvent{}).
+		Select(`
+			COUNT(*) AS samples,
+			COALESCE(AVG(video_playback_time), 0) AS avg,`+percentiles+`
+			COUNT(CASE WHEN ? > 0 AND video_playback_time >= ? THEN 1 END) AS completed
+		`, completeAfter, completeAfter).
+		Where("video_playback_time > 0").
+		Scan(&result).Error
+	if err != nil {
+		return models.PlaybackStats{}, err
+	}
+	if !postgres && result.Samples > 0 {
+		if result.Median, err = s.playbackPercentile(ctx, query, result.Samples, 0.5); err != nil {
+			return models.PlaybackStats{}, err
+		}
+		if result.P90, err = s.playbackPercentile(ctx, query, result.Samples, 0.9); err != nil {
+			return models.PlaybackStats{}, err
+		}
+	}
+
+	stats := models.PlaybackStats{
+		Samples:       result.Samples,
+		AvgSeconds:    result.Avg,
+		MedianSeconds: result.Median,
+		P90Seconds:    result.P90,
+	}
+	if completeAfter > 0 && result.Samples > 0 {
+		stats.CompletionRate = float64(result.Completed) / float64(result.Samples)
+	}
+	return stats, nil
+}
+
+// playbackPercentile matches percentile_cont on databases without it by
+// reading the two playback times around the rank and interpolating.
+func (s *EventStore) playbackPercentile(ctx context.Context, query events.Query, samples int64, p float64) (float64, error) {
+	rank := p * float64(samples-1)
+	lower := int(rank)
+
+	var times []int64
+	err := s.filter(ctx, query).Model(&models.ClickEvent{}).
+		Where("video_playback_time > 0").
+		Order("video_playback_time").
+		Offset(lower).
+		Limit(2).
+		Pluck("video_playback_time", &times).Error
+	if err != nil || len(times) == 0 {
+		return 0, err
+	}
+	if len(times) == 1 {
+		return float64(times[0]), nil
+	}
+	return float64(times[0]) + (rank-float64(lower))*float64(times[1]-times[0]), nil
+}
+
+func (s *EventStore) FlagIP(ctx context.Context, ipAddress string, since time.Time, reason string) (int64, error) {
+	updates := map[string]interface{}{
+		"invalid":     true,
+		"fraud_score": 1,
+		"fraud_reasons": gorm.Expr(
+			"CASE WHEN COALESCE(fraud_reasons, '') = '' THEN ? ELSE "+concat(s.db, "fraud_reasons", "','", "?")+" END", reason, reason),
+	}
+
+	var flagged int64
+	for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+		result := s.db.WithContext(ctx).Model(model).
+			Where("ip_address = ? AND timestamp >= ? AND invalid = ?", ipAddress, since, false).
+			Updates(updates)
+		if result.Error != nil {
+			return flagged, result.Error
+		}
+		flagged += result.RowsAffected
+	}
+	return flagged, nil
+}
+
+func (s *EventStore) SaveImpressions(ctx context.Context, impressions []models.ImpressionEvent) error {
+	if len(impressions) == 0 {
+		return nil
+	}
+	return s.db.WithContext(ctx).Create(&impressions).Error
+}
+
+// batchRows caps the rows of one multi-row INSERT, keeping it under the
+// bind parameter limits of Postgres, MySQL and SQLite.
+const batchRows = 200
+
+// SaveBatch inserts the clicks and impressions in one transaction, using
+// multi-row inserts.
+func (s *EventStore) SaveBatch(ctx context.Context, clicks []models.ClickEvent, impressions []models.ImpressionEvent) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(clicks) > 0 {
+			if err := tx.CreateInBatches(&clicks, batchRows).Error; err != nil {
+				return err
+			}
+		}
+		if len(impressions) > 0 {
+			return tx.CreateInBatches(&impressions, batchRows).Error
+		}
+		return nil
+	})
+}
+
+// ImpressionsAfter pages through impressions in id order, for mirroring.
+func (s *EventStore) ImpressionsAfter(ctx context.Context, afterID uint, limit int) ([]models.ImpressionEvent, error) {
+	var impressions []models.ImpressionEvent
+	err := s.db.WithContext(ctx).Where("id > ?", afterID).Order("id").Limit(limit).Find(&impressions).Error
+	return impressions, err
+}
+
+// ImpressionsByID returns the impressions with the given ids that are
+// stored, in id order.
+func (s *EventStore) ImpressionsByID(ctx context.Context, ids []uint) ([]models.ImpressionEvent, error) {
+	var impressions []models.ImpressionEvent
+	err := s.db.WithContext(ctx).Where("id IN ?", ids).Order("id").Find(&impressions).Error
+	return impressions, err
+}
+
+// LastImpressionIDBefore is the highest id up to maxID of an impression
+// stored before the given time, 0 when there is none. It walks the primary
+// key down from maxID, so it is cheap when that impression is recent.
+func (s *EventStore) LastImpressionIDBefore(ctx context.Context, maxID uint, before time.Time) (uint, error) {
+	var ids []uint
+	err := s.db.WithContext(ctx).Model(&models.ImpressionEvent{}).
+		Where("id <= ? AND created_at < ?", maxID, before).
+		Order("id DESC").
+		Limit(1).
+		Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// LastImpressionID is the highest impression id stored, 0 when there are
+// none.
+func (s *EventStore) LastImpressionID(ctx context.Context) (uint, error) {
+	var id uint
+	err := s.db.WithContext(ctx).Model(&models.ImpressionEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&id).Error
+	return id, err
+}
+
+func (s *EventStore) CountImpressions(ctx context.Context, query events.Query) (int64, error) {
+	var count int64
+	err := s.filter(ctx, query).Model(&models.ImpressionEvent{}).Count(&count).Error
+	return count, err
+}
+
+func (s *EventStore) ViewabilityStats(ctx context.Context, query events.Query, minPercent float64, minMS int64) (models.ViewabilityStats, error) {
+	var result struct {
+		Measured int64
+		Viewable int64
+	}
+
+	err := s.filter(ctx, query).Model(&models.ImpressionEvent{}).
+		Select(`
+			COUNT(*) AS measured,
+			COUNT(CASE WHEN percent_in_view >= ? AND time_in_view_ms >= ? THEN 1 END) AS viewable
+		`, minPercent, minMS).
+		Where("time_in_view_ms > 0 OR percent_in_view > 0").
+		Scan(&result).Error
+	if err != nil {
+		return models.ViewabilityStats{}, err
+	}
+
+	stats := models.ViewabilityStats{Measured: result.Measured, Viewable: result.Viewable}
+	if result.Measured > 0 {
+		stats.ViewableRate = float64(result.Viewable) / float64(result.Measured)
+	}
+	return stats, nil
+}
+
+func (s *EventStore) SessionStats(ctx context.Context, query events.Query) (models.SessionStats, error) {
+	conditions, args := []string{"session_id <> ''"}, []interface{}{}
+	if query.AdID != 0 {
+		conditions, args = append(conditions, "ad_id = ?"), append(args, query.AdID)
+	}
+	if !query.Since.IsZero() {
+		conditions, args = append(conditions, "timestamp >= ?"), append(args, query.Since)
+	}
+	if !query.Until.IsZero() {
+		conditions, args = append(conditions, "timestamp < ?"), append(args, query.Until)
+	}
+	if query.ValidOnly {
+		conditions, args = append(conditions, "invalid = ?"), append(args, false)
+	}
+	clause := strings.Join(conditions, " AND ")
+
+	// UNION ALL of plain selects, since SQLite rejects parenthesized ones
+	var stats models.SessionStats
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT COUNT(*) AS sessions, COALESCE(SUM(events), 0) AS events,
+			COUNT(CASE WHEN events = 1 THEN 1 END) AS bounced
+		FROM (
+			SELECT session_id, COUNT(*) AS events FROM (
+				SELECT session_id FROM click_events WHERE `+clause+`
+				UNION ALL
+				SELECT session_id FROM impression_events WHERE `+clause+`
+			) session_events GROUP BY session_id
+		) sessions`, append(args, args...)...).Scan(&stats).Error
+	if err != nil {
+		return models.SessionStats{}, err
+	}
+	stats.Finish()
+	return stats, nil
+}
+
+func (s *EventStore) filter(ctx context.Context, query events.Query) *gorm.DB {
+	tx := s.db.WithContext(ctx)
+	if query.AdID != 0 {
+		tx = tx.Where("ad_id = ?", query.AdID)
+	}
+	if !query.Since.IsZero() {
+		tx = tx.Where("timestamp >= ?", query.Since)
+	}
+	if !query.Until.IsZero() {
+		tx = tx.Where("timestamp < ?", query.Until)
+	}
+	if query.ValidOnly {
+		tx = tx.Where("invalid = ?", false)
+	}
+	return tx
+}
@@ -0,0 +1,158 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/fakes"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+)
+
+// seedEvents stores the same clicks and impressions in both stores.
+func seedEvents(t *testing.T, stores ...events.EventStore) time.Time {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Second)
+	clicks := []models.ClickEvent{
+		{ClickID: "c1", AdID: 1, Timestamp: now.Add(-2 * time.Hour), IPAddress: "203.0.113.1", VideoPlaybackTime: 10, SessionID: "s1"},
+		{ClickID: "c2", AdID: 1, Timestamp: now.Add(-30 * time.Minute), IPAddress: "203.0.113.2", VideoPlaybackTime: 20, SessionID: "s2"},
+		{ClickID: "c3", AdID: 1, Timestamp: now.Add(-10 * time.Minute), IPAddress: "203.0.113.1", VideoPlaybackTime: 45, SessionID: "s2"},
+		{ClickID: "c4", AdID: 1, Timestamp: now.Add(-5 * time.Minute), IPAddress: "203.0.113.3", VideoPlaybackTime: 60, Invalid: true},
+		{ClickID: "c5", AdID: 2, Timestamp: now.Add(-time.Minute), IPAddress: "203.0.113.1"},
+	}
+	impressions := []models.ImpressionEvent{
+		{AdID: 1, Timestamp: now.Add(-3 * time.Hour), IPAddress: "203.0.113.1", TimeInViewMS: 1500, PercentInView: 80, SessionID: "s1"},
+		{AdID: 1, Timestamp: now.Add(-time.Hour), IPAddress: "203.0.113.2", TimeInViewMS: 200, PercentInView: 90, SessionID: "s3"},
+		{AdID: 1, Timestamp: now.Add(-time.Minute), IPAddress: "203.0.113.2"},
+	}
+	for _, store := range stores {
+		if err := store.SaveClicks(context.Background(), clicks); err != nil {
+			t.Fatalf("save clicks: %v", err)
+		}
+		if err := store.SaveImpressions(context.Background(), impressions); err != nil {
+			t.Fatalf("save impressions: %v", err)
+		}
+	}
+	return now
+}
+
+// TestEventStoreMatchesFake runs the same queries against the GORM store on
+// SQLite and the in-memory fake, which must agree.
+func TestEventStoreMatchesFake(t *testing.T) {
+	store, fake := repositories.NewEventStore(openTestDB(t)), fakes.NewEventStore()
+	now := seedEvents(t, store, fake)
+	ctx := context.Background()
+
+	queries := map[string]events.Query{
+		"all":        {},
+		"ad":         {AdID: 1},
+		"valid only": {AdID: 1, ValidOnly: true},
+		"last hour":  {AdID: 1, Since: now.Add(-time.Hour)},
+		"window":     {Since: now.Add(-time.Hour), Until: now.Add(-2 * time.Minute)},
+	}
+	for name, query := range queries {
+		t.Run(name, func(t *testing.T) {
+			clicks, err := store.CountClicks(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantClicks, _ := fake.CountClicks(ctx, query)
+			if clicks != wantClicks {
+				t.Errorf("CountClicks = %d, want %d", clicks, wantClicks)
+			}
+
+			impressions, err := store.CountImpressions(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wantImpressions, _ := fake.CountImpressions(ctx, query)
+			if impressions != wantImpressions {
+				t.Errorf("CountImpressions = %d, want %d", impressions, wantImpressions)
+			}
+
+			playback, err := store.PlaybackStats(ctx, query, 30)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, _ := fake.PlaybackStats(ctx, query, 30); playback != want {
+				t.Errorf("PlaybackStats = %+v, want %+v", playback, want)
+			}
+
+			viewability, err := store.ViewabilityStats(ctx, query, 50, 1000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, _ := fake.ViewabilityStats(ctx, query, 50, 1000); viewability != want {
+				t.Errorf("ViewabilityStats = %+v, want %+v", viewability, want)
+			}
+
+			sessions, err := store.SessionStats(ctx, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want, _ := fake.SessionStats(ctx, query); sessions != want {
+				t.Errorf("SessionStats = %+v, want %+v", sessions, want)
+			}
+		})
+	}
+}
+
+func TestEventStorePlaybackPercentiles(t *testing.T) {
+	store := repositories.NewEventStore(openTestDB(t))
+	seedEvents(t, store)
+
+	stats, err := store.PlaybackStats(context.Background(), events.Query{AdID: 1}, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Samples 10, 20, 45, 60: interpolated like percentile_cont
+	want := models.PlaybackStats{Samples: 4, AvgSeconds: 33.75, MedianSeconds: 32.5, P90Seconds: 55.5, CompletionRate: 0.5}
+	if stats != want {
+		t.Errorf("PlaybackStats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestEventStoreFlagIP(t *testing.T) {
+	store := repositories.NewEventStore(openTestDB(t))
+	now := seedEvents(t, store)
+	ctx := context.Background()
+
+	flagged, err := store.FlagIP(ctx, "203.0.113.1", now.Add(-time.Hour), "honeypot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// c3 and c5; c1 is older than the window
+	if flagged != 2 {
+		t.Errorf("flagged %d events, want 2", flagged)
+	}
+	valid, err := store.CountClicks(ctx, events.Query{ValidOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid != 2 {
+		t.Errorf("%d valid clicks left, want 2", valid)
+	}
+
+	clicks, err := store.ListClicks(ctx, events.Query{AdID: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clicks) != 1 || clicks[0].FraudReasons != "honeypot" || clicks[0].FraudScore != 1 {
+		t.Errorf("flagged click = %+v", clicks)
+	}
+}
+
+func TestEventStoreStoredClickIDs(t *testing.T) {
+	store := repositories.NewEventStore(openTestDB(t))
+	seedEvents(t, store)
+
+	stored, err := store.StoredClickIDs(context.Background(), []string{"c1", "c5", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored["c1"] || !stored["c5"] || stored["missing"] || len(stored) != 2 {
+		t.Errorf("StoredClickIDs = %v", stored)
+	}
+}
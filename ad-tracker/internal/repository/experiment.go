@@ -0,0 +1,136 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrVariantUnavailable is returned when a variant's ad does not exist or
+// is already in a running experiment.
+var ErrVariantUnavailable = errors.New("variant ad unavailable")
+
+type ExperimentRepository struct {
+	db *gorm.DB
+}
+
+func NewExperimentRepository(db *gorm.DB) *ExperimentRepository {
+	return &ExperimentRepository{db: db}
+}
+
+// Create starts the experiment with its variants.
+func (r *ExperimentRepository) Create(experiment *models.Experiment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, variant := range experiment.Variants {
+			var ads int64
+			if err := tx.Model(&models.Ad{}).Where("id = ?", variant.AdID).Count(&ads).Error; err != nil {
+				return err
+			}
+			if ads == 0 {
+				return fmt.Errorf("%w: ad %d not found", ErrVariantUnavailable, variant.AdID)
+			}
+
+			var running int64
+			err := tx.Model(&models.ExperimentVariant{}).
+				Joins("JOIN experiments ON experiments.id = experiment_variants.experiment_id").
+				Where("experiment_variants.ad_id = ? AND experiments.status = ?", variant.AdID, models.ExperimentRunning).
+				Count(&running).Error
+			if err != nil {
+				return err
+			}
+			if running > 0 {
+				return fmt.Errorf("%w: ad %d is in another running experiment", ErrVariantUnavailable, variant.AdID)
+			}
+		}
+		return tx.Create(experiment).Error
+	})
+}
+
+func (r *ExperimentRepository) List() ([]models.Experiment, error) {
+	var experiments []models.Experiment
+	err := r.db.Preload("Variants").Order("id").Find(&experiments).Error
+	return experiments, err
+}
+
+func (r *ExperimentRepository) Get(id uint) (*models.Experiment, error) {
+	var experiment models.Experiment
+	if err := r.db.Preload("Variants").First(&experiment, id).Error; err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+func (r *ExperimentRepository) Running() ([]models.Experiment, error) {
+	var experiments []models.Experiment
+	err := r.db.Preload("Variants").Where("status = ?", models.ExperimentRunning).Find(&experiments).Error
+	return experiments, err
+}
+
+// Stop ends a running experiment; its variant ads are served normally again.
+func (r *ExperimentRepository) Stop(id uint) error {
+	result := r.db.Model(&models.Experiment{}).
+		Where("id = ? AND status = ?", id, models.ExperimentRunning).
+		Updates(map[string]interface{}{"status": models.ExperimentStopped, "stopped_at": time.Now().UTC()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *ExperimentRepository) LogExposures(exposures []models.ExperimentExposure) error {
+	if len(exposures) == 0 {
+		return nil
+	}
+	return r.db.Create(&exposures).Error
+}
+
+// VariantCounts fills in exposures, distinct viewers, valid clicks and
+// attributed conversions for each variant while the experiment ran.
+func (r *ExperimentRepository) VariantCounts(experiment *models.Experiment) ([]models.VariantResult, error) {
+	until := time.Now().UTC()
+	if experiment.StoppedAt != nil {
+		until = *experiment.StoppedAt
+	}
+
+	results := make([]models.VariantResult, 0, len(experiment.Variants))
+	for _, variant := range experiment.Variants {
+		result := models.VariantResult{
+			VariantID: variant.ID,
+			Name:      variant.Name,
+			AdID:      variant.AdID,
+			Weight:    variant.Weight,
+		}
+
+		err := r.db.Model(&models.ExperimentExposure{}).Where("variant_id = ?", variant.ID).
+			Count(&result.Exposures).Error
+		if err != nil {
+			return nil, err
+		}
+		err = r.db.Model(&models.ExperimentExposure{}).Where("variant_id = ?", variant.ID).
+			Distinct("viewer").Count(&result.Viewers).Error
+		if err != nil {
+			return nil, err
+		}
+		err = r.db.Model(&models.ClickEvent{}).
+			Where("ad_id = ? AND invalid = ? AND timestamp >= ? AND timestamp <= ?", variant.AdID, false, experiment.StartedAt, until).
+			Count(&result.Clicks).Error
+		if err != nil {
+			return nil, err
+		}
+		err = r.db.Model(&models.Conversion{}).
+			Where("attributed_ad_id = ? AND timestamp >= ? AND timestamp <= ?", variant.AdID, experiment.StartedAt, until).
+			Count(&result.Conversions).Error
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"strconv"
+	"strings"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+func (r *ExportRepository) Create(export *models.Export) error {
+	return r.db.Create(export).Error
+}
+
+func (r *ExportRepository) Get(id uint) (*models.Export, error) {
+	var export models.Export
+	if err := r.db.First(&export, id).Error; err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+func (r *ExportRepository) Save(export *models.Export) error {
+	return r.db.Save(export).Error
+}
+
+// StreamClicks walks the export's cohort in id order, batchSize rows at a time.
+func (r *ExportRepository) StreamClicks(export *models.Export, batchSize int, fn func([]models.ClickEvent) error) error {
+	tx, err := r.cohort(&models.ClickEvent{}, export)
+	if err != nil {
+		return err
+	}
+
+	var batch []models.ClickEvent
+	return tx.Order("id").FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// StreamImpressions is StreamClicks for impression events.
+func (r *ExportRepository) StreamImpressions(export *models.Export, batchSize int, fn func([]models.ImpressionEvent) error) error {
+	tx, err := r.cohort(&models.ImpressionEvent{}, export)
+	if err != nil {
+		return err
+	}
+
+	var batch []models.ImpressionEvent
+	return tx.Order("id").FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+		return fn(batch)
+	}).Error
+}
+
+// ClickPage returns up to limit clicks of the cohort with ids above afterID.
+func (r *ExportRepository) ClickPage(export *models.Export, afterID uint, limit int) ([]models.ClickEvent, error) {
+	tx, err := r.cohort(&models.ClickEvent{}, export)
+	if err != nil {
+		return nil, err
+	}
+
+	var clicks []models.ClickEvent
+	err = tx.Where("id > ?", afterID).Order("id").Limit(limit).Find(&clicks).Error
+	return clicks, err
+}
+
+// ImpressionPage returns up to limit impressions of the cohort with ids above
+// afterID.
+func (r *ExportRepository) ImpressionPage(export *models.Export, afterID uint, limit int) ([]models.ImpressionEvent, error) {
+	tx, err := r.cohort(&models.ImpressionEvent{}, export)
+	if err != nil {
+		return nil, err
+	}
+
+	var impressions []models.ImpressionEvent
+	err = tx.Where("id > ?", afterID).Order("id").Limit(limit).Find(&impressions).Error
+	return impressions, err
+}
+
+// cohort scopes an event table to the export's ads, campaign, user and time
+// range.
+func (r *ExportRepository) cohort(model interface{}, export *models.Export) (*gorm.DB, error) {
+	tx := r.db.Model(model)
+
+	if export.AdIDs != "" {
+		var adIDs []uint
+		for _, part := range strings.Split(export.AdIDs, ",") {
+			id, err := strconv.ParseUint(part, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			adIDs = append(adIDs, uint(id))
+		}
+		tx = tx.Where("ad_id IN ?", adIDs)
+	}
+	if export.CampaignID != nil {
+		tx = tx.Where("ad_id IN (?)", r.db.Model(&models.Ad{}).Select("id").Where("campaign_id = ?", *export.CampaignID))
+	}
+	if export.UserID != "" {
+		tx = tx.Where("user_id = ?", export.UserID)
+	}
+	if export.From != nil {
+		tx = tx.Where("timestamp >= ?", *export.From)
+	}
+	if export.To != nil {
+		tx = tx.Where("timestamp < ?", *export.To)
+	}
+	return tx, nil
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxIdentityUsers bounds how many user ids one identifier may join. An
+// identifier seen with more, such as a shared kiosk device, is ignored when
+// resolving rather than merging unrelated people.
+const maxIdentityUsers = 10
+
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) *IdentityRepository {
+	return &IdentityRepository{db: db}
+}
+
+// Link records that userID was seen with each identifier at the given time.
+// Nothing is recorded for an opted-out user, nor for opted-out identifiers.
+func (r *IdentityRepository) Link(userID string, identifiers []models.Identifier, at time.Time) error {
+	if userID == "" || len(identifiers) == 0 {
+		return nil
+	}
+	values := []string{userID}
+	for _, identifier := range identifiers {
+		values = append(values, identifier.Value)
+	}
+	var optOuts []models.IdentityOptOut
+	if err := r.db.Where("identifier IN ?", values).Find(&optOuts).Error; err != nil {
+		return err
+	}
+	opted := make(map[models.Identifier]bool, len(optOuts))
+	for _, optOut := range optOuts {
+		opted[models.Identifier{Kind: optOut.Kind, Value: optOut.Identifier}] = true
+	}
+	if opted[models.Identifier{Kind: models.IdentifierUser, Value: userID}] {
+		return nil
+	}
+
+	var links []models.IdentityLink
+	for _, identifier := range identifiers {
+		if opted[identifier] {
+			continue
+		}
+		links = append(links, models.IdentityLink{
+			Kind:       identifier.Kind,
+			Identifier: identifier.Value,
+			UserID:     userID,
+			FirstSeen:  at,
+			LastSeen:   at,
+		})
+	}
+	if len(links) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "identifier"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"last_seen"}),
+	}).Create(&links).Error
+}
+
+// Related maps each of the user ids to the other user ids sharing an
+// identifier with it. Users without any are left out.
+func (r *IdentityRepository) Related(userIDs []string) (map[string][]string, error) {
+	related := make(map[string][]string)
+	if len(userIDs) == 0 {
+		return related, nil
+	}
+	var pairs []struct {
+		UserID   string
+		LinkedID string
+	}
+	err := r.db.Table("identity_links AS a").
+		Select("DISTINCT a.user_id AS user_id, b.user_id AS linked_id").
+		Joins("JOIN identity_links AS b ON b.kind = a.kind AND b.identifier = a.identifier AND b.user_id <> a.user_id").
+		Where("a.user_id IN ?", userIDs).
+		Where("(SELECT COUNT(*) FROM identity_links AS c WHERE c.kind = a.kind AND c.identifier = a.identifier) <= ?", maxIdentityUsers).
+		Scan(&pairs).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		related[pair.UserID] = append(related[pair.UserID], pair.LinkedID)
+	}
+	return related, nil
+}
+
+// UsersFor returns the user ids seen with any of the identifiers.
+func (r *IdentityRepository) UsersFor(identifiers []models.Identifier) ([]string, error) {
+	seen := make(map[string]bool)
+	var users []string
+	for _, identifier := range identifiers {
+		var linked []string
+		// One past the cap is enough to tell a shared identifier
+		err := r.db.Model(&models.IdentityLink{}).
+			Where("kind = ? AND identifier = ?", identifier.Kind, identifier.Value).
+			Order("id").
+			Limit(maxIdentityUsers+1).
+			Pluck("user_id", &linked).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(linked) > maxIdentityUsers {
+			continue
+		}
+		for _, userID := range linked {
+			if !seen[userID] {
+				seen[userID] = true
+				users = append(users, userID)
+			}
+		}
+	}
+	return users, nil
+}
+
+// OptedOut reports whether the identifier was opted out.
+func (r *IdentityRepository) OptedOut(identifier models.Identifier) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.IdentityOptOut{}).
+		Where("kind = ? AND identifier = ?", identifier.Kind, identifier.Value).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// OptOut records the opt-outs and removes the links they cover, returning
+// how many links were removed.
+func (r *IdentityRepository) OptOut(identifiers []models.Identifier) (int64, error) {
+	var removed int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, identifier := range identifiers {
+			optOut := models.IdentityOptOut{Kind: identifier.Kind, Identifier: identifier.Value}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&optOut).Error; err != nil {
+				return err
+			}
+			query := tx.Where("kind = ? AND identifier = ?", identifier.Kind, identifier.Value)
+			if identifier.Kind == models.IdentifierUser {
+				query = tx.Where("user_id = ?", identifier.Value)
+			}
+			result := query.Delete(&models.IdentityLink{})
+			if result.Error != nil {
+				return result.Error
+			}
+			removed += result.RowsAffected
+		}
+		return nil
+	})
+	return removed, err
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type IntegrationRepository struct {
+	db *gorm.DB
+}
+
+func NewIntegrationRepository(db *gorm.DB) *IntegrationRepository {
+	return &IntegrationRepository{db: db}
+}
+
+func (r *IntegrationRepository) Create(integration *models.Integration) error {
+	return r.db.Create(integration).Error
+}
+
+func (r *IntegrationRepository) List(accountID uint) ([]models.Integration, error) {
+	var integrations []models.Integration
+	err := r.db.Where("account_id = ?", accountID).Order("id").Find(&integrations).Error
+	return integrations, err
+}
+
+// SetActive returns gorm.ErrRecordNotFound when the integration does not
+// exist.
+func (r *IntegrationRepository) SetActive(id uint, active bool) (models.Integration, error) {
+	var integration models.Integration
+	if err := r.db.First(&integration, id).Error; err != nil {
+		return integration, err
+	}
+	err := r.db.Model(&integration).Update("active", active).Error
+	return integration, err
+}
+
+func (r *IntegrationRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.Integration{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ByAd maps each ad to the active integrations of its account.
+func (r *IntegrationRepository) ByAd() (map[uint][]models.Integration, error) {
+	var rows []struct {
+		AdID uint
+		models.Integration
+	}
+	err := r.db.Table("integrations").
+		Select("ads.id AS ad_id, integrations.*").
+		Joins("JOIN campaigns ON campaigns.account_id = integrations.account_id").
+		Joins("JOIN ads ON ads.campaign_id = campaigns.id").
+		Where("integrations.active = ?", true).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	integrations := make(map[uint][]models.Integration)
+	for _, row := range rows {
+		integrations[row.AdID] = append(integrations[row.AdID], row.Integration)
+	}
+	return integrations, nil
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/targeting"
+)
+
+// AdStore is the ad lookups and updates handlers and the ad cache depend
+// on. Get and UpdateTargeting return gorm.ErrRecordNotFound for unknown
+// ads.
+type AdStore interface {
+	Active() ([]models.Ad, error)
+	Get(id uint) (models.Ad, error)
+	UpdateTargeting(id uint, rule *targeting.Rule) (models.Ad, error)
+}
+
+// AnalyticsReader answers the queries behind the analytics endpoints and
+// scheduled reports.
+type AnalyticsReader interface {
+	GetAdAnalytics(adID uint, since time.Time, validOnly bool) models.AnalyticsResponse
+	GetAllAnalytics(since time.Time, validOnly bool) []models.AnalyticsResponse
+	GetCampaignSummary(campaign models.Campaign, adIDs []uint, since time.Time, validOnly bool) models.CampaignSummary
+	GetCampaignRange(campaign models.Campaign, adIDs []uint, from, until time.Time, validOnly, daily bool) models.CampaignSummary
+}
+
+// PrecomputedSummaries is implemented by analytics readers that can answer
+// campaign summaries from counters maintained ahead of time. A zero until
+// means up to now; ok is false when the counters are unavailable and the
+// summary should be computed from raw events.
+type PrecomputedSummaries interface {
+	GetPrecomputedSummary(campaign models.Campaign, adIDs []uint, from, until time.Time, validOnly, daily bool) (models.CampaignSummary, bool)
+}
+
+var (
+	_ AdStore              = (*AdRepository)(nil)
+	_ AnalyticsReader      = (*AnalyticsRepository)(nil)
+	_ PrecomputedSummaries = (*AnalyticsRepository)(nil)
+)
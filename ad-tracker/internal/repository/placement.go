@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PlacementRepository struct {
+	db *gorm.DB
+}
+
+func NewPlacementRepository(db *gorm.DB) *PlacementRepository {
+	return &PlacementRepository{db: db}
+}
+
+func (r *PlacementRepository) Create(placement *models.Placement) error {
+	return r.db.Create(placement).Error
+}
+
+// KeyTaken reports whether a placement already uses key.
+func (r *PlacementRepository) KeyTaken(key string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Placement{}).Where("placement_key = ?", key).Count(&count).Error
+	return count > 0, err
+}
+
+// List returns every placement, or only the publisher's when it is set.
+func (r *PlacementRepository) List(publisher string) ([]models.Placement, error) {
+	var placements []models.Placement
+	query := r.db.Order("id")
+	if publisher != "" {
+		query = query.Where("publisher = ?", publisher)
+	}
+	err := query.Find(&placements).Error
+	return placements, err
+}
+
+func (r *PlacementRepository) Active() ([]models.Placement, error) {
+	var placements []models.Placement
+	err := r.db.Where("active = ?", true).Find(&placements).Error
+	return placements, err
+}
+
+// SetActive returns gorm.ErrRecordNotFound when the placement does not
+// exist.
+func (r *PlacementRepository) SetActive(id uint, active bool) (models.Placement, error) {
+	var placement models.Placement
+	if err := r.db.First(&placement, id).Error; err != nil {
+		return placement, err
+	}
+	err := r.db.Model(&placement).Update("active", active).Error
+	return placement, err
+}
+
+// Stats counts valid impressions and clicks since the given time grouped by
+// placement, optionally for one ad.
+func (r *PlacementRepository) Stats(adID uint, since time.Time) ([]models.PlacementStats, error) {
+	where, args := "invalid = ? AND timestamp >= ?", []interface{}{false, since}
+	if adID != 0 {
+		where, args = "ad_id = ? AND "+where, append([]interface{}{adID}, args...)
+	}
+	counts, err := countEventsBy(r.db, "placement_id", where, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var placements []models.Placement
+	if err := r.db.Select("id", "placement_key").Find(&placements).Error; err != nil {
+		return nil, err
+	}
+	keys := make(map[uint]string, len(placements))
+	for _, placement := range placements {
+		keys[placement.ID] = placement.Key
+	}
+
+	stats := make([]models.PlacementStats, 0, len(counts))
+	for _, count := range counts {
+		stat := models.PlacementStats{PlacementID: count.id, Impressions: count.impressions, Clicks: count.clicks}
+		if count.id != nil {
+			stat.Key = keys[*count.id]
+		}
+		if stat.Impressions > 0 {
+			stat.CTR = float64(stat.Clicks) / float64(stat.Impressions)
+		}
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// privacyTables are the tables holding identity data, in the order they are
+// erased. The PII columns come from RetentionTables.
+var privacyTables = []string{"click_events", "impression_events", "conversions", "captured_requests", "sessions"}
+
+type PrivacyRepository struct {
+	db *gorm.DB
+}
+
+func NewPrivacyRepository(db *gorm.DB) *PrivacyRepository {
+	return &PrivacyRepository{db: db}
+}
+
+func (r *PrivacyRepository) Create(request *models.PrivacyRequest) error {
+	return r.db.Create(request).Error
+}
+
+func (r *PrivacyRepository) Get(id uint) (*models.PrivacyRequest, error) {
+	var request models.PrivacyRequest
+	if err := r.db.First(&request, id).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *PrivacyRepository) Save(request *models.PrivacyRequest) error {
+	return r.db.Save(request).Error
+}
+
+// Unfinished returns requests interrupted by a restart.
+func (r *PrivacyRepository) Unfinished() ([]models.PrivacyRequest, error) {
+	var requests []models.PrivacyRequest
+	err := r.db.Where("status IN ?", []string{models.PrivacyPending, models.PrivacyRunning}).
+		Order("id").
+		Find(&requests).Error
+	return requests, err
+}
+
+// Erase deletes or anonymizes every row tied to the subject in one
+// transaction and returns the rows affected per table. A user's identity
+// links are deleted in either mode.
+func (r *PrivacyRepository) Erase(subjectType, subject, mode string) (map[string]int64, error) {
+	affected := make(map[string]int64, len(privacyTables))
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range privacyTables {
+			table := RetentionTables[name]
+			condition, args, err := subjectCondition(tx, name, subjectType, subject)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+
+			var query string
+			if mode == models.PrivacyAnonymize {
+				assignments := make([]string, len(table.PIIColumns))
+				for i, column := range table.PIIColumns {
+					assignments[i] = column + " = ''"
+				}
+				query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", name, strings.Join(assignments, ", "), condition)
+			} else {
+				query = fmt.Sprintf("DELETE FROM %s WHERE %s", name, condition)
+			}
+
+			result := tx.Exec(query, args...)
+			if result.Error != nil {
+				return fmt.Errorf("%s: %w", name, result.Error)
+			}
+			affected[name] = result.RowsAffected
+		}
+		// Links tie the user to other ids; anonymizing removes them too
+		if subjectType == models.SubjectUserID {
+			result := tx.Where("user_id = ?", subject).Delete(&models.IdentityLink{})
+			if result.Error != nil {
+				return fmt.Errorf("identity_links: %w", result.Error)
+			}
+			affected["identity_links"] = result.RowsAffected
+		}
+		return nil
+	})
+	return affected, err
+}
+
+// StreamSubject calls fn for every row tied to the subject, table by table in
+// privacyTables order and by id within a table. A user's identity links come
+// last.
+func (r *PrivacyRepository) StreamSubject(subjectType, subject string, fn func(table string, row map[string]interface{}) error) (map[string]int64, error) {
+	tables := privacyTables
+	if subjectType == models.SubjectUserID {
+		tables = append(slices.Clip(privacyTables), "identity_links")
+	}
+	counts := make(map[string]int64, len(tables))
+
+	for _, name := range tables {
+		condition, args, err := subjectCondition(r.db, name, subjectType, subject)
+		if err != nil {
+			return counts, fmt.Errorf("%s: %w", name, err)
+		}
+		rows, err := r.db.Table(name).Where(condition, args...).Order("id").Rows()
+		if err != nil {
+			return counts, fmt.Errorf("%s: %w", name, err)
+		}
+
+		for rows.Next() {
+			row := make(map[string]interface{})
+			if err := r.db.ScanRows(rows, &row); err != nil {
+				rows.Close()
+				return counts, fmt.Errorf("%s: %w", name, err)
+			}
+			if err := fn(name, row); err != nil {
+				rows.Close()
+				return counts, err
+			}
+			counts[name]++
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return counts, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return counts, nil
+}
+
+// likeEscaper escapes LIKE wildcards with '!': a backslash in the ESCAPE
+// clause is itself a string escape on MySQL.
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// subjectCondition matches rows for a subject. IPs are matched by the hex
+// SHA-256 of the stored address in tables that keep one; captured requests
+// have no user_id column, so the user is matched inside the captured JSON
+// payload.
+func subjectCondition(db *gorm.DB, table, subjectType, subject string) (string, []interface{}, error) {
+	if subjectType == models.SubjectIPHash {
+		if !slices.Contains(RetentionTables[table].PIIColumns, "ip_address") {
+			return "1 = 0", nil, nil
+		}
+		return ipHashMatch(db, table, strings.ToLower(subject))
+	}
+	if table == "captured_requests" {
+		encoded, _ := json.Marshal(subject)
+		pattern := `%"user_id":` + likeEscaper.Replace(string(encoded)) + `%`
+		return `payload LIKE ? ESCAPE '!'`, []interface{}{pattern}, nil
+	}
+	return "user_id = ?", []interface{}{subject}, nil
+}
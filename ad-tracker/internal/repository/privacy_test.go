@@ -0,0 +1,135 @@
+package repositories_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+func seedSubjects(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	now := time.Now().UTC()
+	rows := []interface{}{
+		&[]models.ClickEvent{
+			{ClickID: "c1", AdID: 1, Timestamp: now, UserID: "alice", IPAddress: "203.0.113.1"},
+			{ClickID: "c2", AdID: 1, Timestamp: now, UserID: "bob", IPAddress: "203.0.113.1"},
+			{ClickID: "c3", AdID: 1, Timestamp: now, UserID: "bob", IPAddress: "203.0.113.2"},
+		},
+		&[]models.ImpressionEvent{
+			{AdID: 1, Timestamp: now, UserID: "alice", IPAddress: "203.0.113.9"},
+		},
+		&[]models.Session{
+			{ID: "s1", UserID: "alice", EntryAdID: 1, StartedAt: now, EndedAt: now},
+			{ID: "s2", UserID: "bob", EntryAdID: 1, StartedAt: now, EndedAt: now},
+		},
+		&[]models.IdentityLink{
+			{Kind: models.IdentifierEmail, Identifier: "e1", UserID: "alice", FirstSeen: now, LastSeen: now},
+			{Kind: models.IdentifierEmail, Identifier: "e1", UserID: "bob", FirstSeen: now, LastSeen: now},
+		},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+}
+
+func ipDigest(ip string) string {
+	sum := sha256.Sum256([]byte(ip))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPrivacyEraseUser(t *testing.T) {
+	db := openTestDB(t)
+	seedSubjects(t, db)
+
+	affected, err := repositories.NewPrivacyRepository(db).Erase(models.SubjectUserID, "alice", models.PrivacyErase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"click_events": 1, "impression_events": 1, "sessions": 1, "identity_links": 1}
+	for table, count := range want {
+		if affected[table] != count {
+			t.Errorf("%s: %d rows affected, want %d", table, affected[table], count)
+		}
+	}
+
+	var left int64
+	db.Model(&models.ClickEvent{}).Where("user_id = ?", "alice").Count(&left)
+	if left != 0 {
+		t.Errorf("%d clicks left for the erased user", left)
+	}
+	db.Model(&models.IdentityLink{}).Count(&left)
+	if left != 1 {
+		t.Errorf("%d identity links left, want bob's", left)
+	}
+}
+
+// TestPrivacyAnonymizeIPHash covers SQLite, which has no SHA-256 function,
+// so addresses are hashed outside the database.
+func TestPrivacyAnonymizeIPHash(t *testing.T) {
+	db := openTestDB(t)
+	seedSubjects(t, db)
+
+	affected, err := repositories.NewPrivacyRepository(db).Erase(models.SubjectIPHash, ipDigest("203.0.113.1"), models.PrivacyAnonymize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected["click_events"] != 2 || affected["impression_events"] != 0 || affected["sessions"] != 0 {
+		t.Errorf("rows affected = %v, want 2 clicks only", affected)
+	}
+
+	var clicks []models.ClickEvent
+	if err := db.Order("id").Find(&clicks).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(clicks) != 3 {
+		t.Fatalf("%d clicks left, anonymizing must keep them", len(clicks))
+	}
+	for _, click := range clicks[:2] {
+		if click.IPAddress != "" || click.UserID != "" {
+			t.Errorf("click %s kept identifiers: %q %q", click.ClickID, click.UserID, click.IPAddress)
+		}
+	}
+	if clicks[2].IPAddress != "203.0.113.2" {
+		t.Errorf("click c3 was anonymized too")
+	}
+}
+
+func TestPrivacyStreamSubject(t *testing.T) {
+	db := openTestDB(t)
+	seedSubjects(t, db)
+	repo := repositories.NewPrivacyRepository(db)
+
+	var tables []string
+	counts, err := repo.StreamSubject(models.SubjectUserID, "bob", func(table string, row map[string]interface{}) error {
+		tables = append(tables, table)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"click_events": 2, "sessions": 1, "identity_links": 1}
+	for table, count := range want {
+		if counts[table] != count {
+			t.Errorf("%s: streamed %d rows, want %d", table, counts[table], count)
+		}
+	}
+	if last := tables[len(tables)-1]; last != "identity_links" {
+		t.Errorf("identity links streamed before %s", last)
+	}
+
+	counts, err = repo.StreamSubject(models.SubjectIPHash, ipDigest("203.0.113.9"), func(string, map[string]interface{}) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts["impression_events"] != 1 || counts["click_events"] != 0 {
+		t.Errorf("IP subject streamed %v, want one impression", counts)
+	}
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ReplicationRepository struct {
+	db *gorm.DB
+}
+
+func NewReplicationRepository(db *gorm.DB) *ReplicationRepository {
+	return &ReplicationRepository{db: db}
+}
+
+// State loads the region's role, creating it with defaultRole on first use.
+func (r *ReplicationRepository) State(region, defaultRole string) (models.ReplicationState, error) {
+	var state models.ReplicationState
+	err := r.db.First(&state, "region = ?", region).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		state = models.ReplicationState{
+			Region:    region,
+			Role:      defaultRole,
+			ChangedAt: time.Now().UTC(),
+			ChangedBy: "bootstrap",
+		}
+		err = r.db.Create(&state).Error
+	}
+	return state, err
+}
+
+func (r *ReplicationRepository) SetRole(region, role, changedBy string) (models.ReplicationState, error) {
+	state := models.ReplicationState{
+		Region:    region,
+		Role:      role,
+		ChangedAt: time.Now().UTC(),
+		ChangedBy: changedBy,
+	}
+	err := r.db.Save(&state).Error
+	return state, err
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func (r *ReportRepository) CreateSchedule(schedule *models.ReportSchedule) error {
+	return r.db.Create(schedule).Error
+}
+
+func (r *ReportRepository) SaveSchedule(schedule *models.ReportSchedule) error {
+	return r.db.Save(schedule).Error
+}
+
+func (r *ReportRepository) GetSchedule(id uint) (*models.ReportSchedule, error) {
+	var schedule models.ReportSchedule
+	if err := r.db.First(&schedule, id).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *ReportRepository) ListSchedules(accountID uint) ([]models.ReportSchedule, error) {
+	var schedules []models.ReportSchedule
+	err := r.db.Where("account_id = ?", accountID).Order("id").Find(&schedules).Error
+	return schedules, err
+}
+
+// DeleteSchedule removes a schedule and its run history.
+func (r *ReportRepository) DeleteSchedule(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("schedule_id = ?", id).Delete(&models.ReportRun{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.ReportSchedule{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// DueSchedules returns active schedules whose next run time has passed.
+func (r *ReportRepository) DueSchedules(now time.Time) ([]models.ReportSchedule, error) {
+	var schedules []models.ReportSchedule
+	err := r.db.Where("active = ? AND next_run_at <= ?", true, now).Order("next_run_at").Find(&schedules).Error
+	return schedules, err
+}
+
+func (r *ReportRepository) CreateRun(run *models.ReportRun) error {
+	return r.db.Create(run).Error
+}
+
+func (r *ReportRepository) SaveRun(run *models.ReportRun) error {
+	return r.db.Save(run).Error
+}
+
+// RetryableRuns returns failed runs waiting for another attempt.
+func (r *ReportRepository) RetryableRuns(now time.Time) ([]models.ReportRun, error) {
+	var runs []models.ReportRun
+	err := r.db.Where("status = ? AND retry_at <= ?", models.ReportRunFailed, now).Order("retry_at").Find(&runs).Error
+	return runs, err
+}
+
+func (r *ReportRepository) ListRuns(scheduleID uint, limit int) ([]models.ReportRun, error) {
+	var runs []models.ReportRun
+	err := r.db.Where("schedule_id = ?", scheduleID).Order("id DESC").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+// AccountCampaigns returns the campaigns owned by an account.
+func (r *ReportRepository) AccountCampaigns(accountID uint) ([]models.Campaign, error) {
+	var campaigns []models.Campaign
+	err := r.db.Where("account_id = ?", accountID).Order("id").Find(&campaigns).Error
+	return campaigns, err
+}
@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RetentionTable describes how rows in one table age out: the column that
+// dates a row and the columns blanked when anonymizing instead of deleting.
+type RetentionTable struct {
+	Name       string
+	TimeColumn string
+	PIIColumns []string
+}
+
+// RetentionTables lists the tables the retention job may touch. Table and
+// column names are interpolated into SQL, so only this fixed set is allowed.
+var RetentionTables = map[string]RetentionTable{
+	"click_events":      {Name: "click_events", TimeColumn: "timestamp", PIIColumns: []string{"user_id", "ip_address", "user_agent"}},
+	"impression_events": {Name: "impression_events", TimeColumn: "timestamp", PIIColumns: []string{"user_id", "ip_address", "user_agent"}},
+	"conversions":       {Name: "conversions", TimeColumn: "timestamp", PIIColumns: []string{"user_id", "ip_address"}},
+	"captured_requests": {Name: "captured_requests", TimeColumn: "received_at", PIIColumns: []string{"ip_address", "query", "headers", "payload"}},
+	"sessions":          {Name: "sessions", TimeColumn: "started_at", PIIColumns: []string{"user_id"}},
+}
+
+type RetentionRepository struct {
+	db *gorm.DB
+}
+
+func NewRetentionRepository(db *gorm.DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+// CountExpired counts rows older than cutoff that a purge would change.
+func (r *RetentionRepository) CountExpired(table RetentionTable, cutoff time.Time, anonymize bool) (int64, error) {
+	var count int64
+	tx := r.db.Table(table.Name).Where(fmt.Sprintf("%s < ?", table.TimeColumn), cutoff)
+	if anonymize {
+		tx = tx.Where(notAnonymized(table))
+	}
+	err := tx.Count(&count).Error
+	return count, err
+}
+
+// DeleteBatch removes up to limit rows older than cutoff.
+func (r *RetentionRepository) DeleteBatch(table RetentionTable, cutoff time.Time, limit int) (int64, error) {
+	query := fmt.Sprintf(
+		"DELETE FROM %[1]s WHERE id IN (SELECT id FROM %[1]s WHERE %[2]s < ? ORDER BY id LIMIT ?)",
+		table.Name, table.TimeColumn)
+	if dialectOf(r.db) == dialectMySQL {
+		// MySQL rejects LIMIT in an IN subquery but limits deletes directly.
+		query = fmt.Sprintf("DELETE FROM %s WHERE %s < ? ORDER BY id LIMIT ?", table.Name, table.TimeColumn)
+	}
+	result := r.db.Exec(query, cutoff, limit)
+	return result.RowsAffected, result.Error
+}
+
+// AnonymizeBatch blanks the PII columns of up to limit rows older than
+// cutoff, keeping the rows for aggregate reporting.
+func (r *RetentionRepository) AnonymizeBatch(table RetentionTable, cutoff time.Time, limit int) (int64, error) {
+	assignments := make([]string, len(table.PIIColumns))
+	for i, column := range table.PIIColumns {
+		assignments[i] = column + " = ''"
+	}
+	query := fmt.Sprintf(
+		"UPDATE %[1]s SET %[2]s WHERE id IN (SELECT id FROM %[1]s WHERE %[3]s < ? AND %[4]s ORDER BY id LIMIT ?)",
+		table.Name, strings.Join(assignments, ", "), table.TimeColumn, notAnonymized(table))
+	if dialectOf(r.db) == dialectMySQL {
+		query = fmt.Sprintf("UPDATE %s SET %s WHERE %s < ? AND %s ORDER BY id LIMIT ?",
+			table.Name, strings.Join(assignments, ", "), table.TimeColumn, notAnonymized(table))
+	}
+	result := r.db.Exec(query, cutoff, limit)
+	return result.RowsAffected, result.Error
+}
+
+func notAnonymized(table RetentionTable) string {
+	conditions := make([]string, len(table.PIIColumns))
+	for i, column := range table.PIIColumns {
+		conditions[i] = fmt.Sprintf("COALESCE(%s, '') <> ''", column)
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
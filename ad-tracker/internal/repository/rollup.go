@@ -0,0 +1,235 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// rollupHoursSQL recomputes hourly rows from raw events. Conversions count
+// toward the ad they were attributed to. Impressions are viewable by the
+// video duration when their ad has one, else the display duration.
+func rollupHoursSQL(db *gorm.DB) string {
+	return fmt.Sprintf(`
+INSERT INTO event_rollups (granularity, bucket_start, ad_id, campaign_id, clicks, invalid_clicks, impressions, invalid_impressions,
+	measured, invalid_measured, viewable, invalid_viewable, conversions, updated_at)
+SELECT 'hour', b.bucket, b.ad_id, ads.campaign_id,
+	SUM(b.clicks), SUM(b.invalid_clicks), SUM(b.impressions), SUM(b.invalid_impressions),
+	SUM(b.measured), SUM(b.invalid_measured), SUM(b.viewable), SUM(b.invalid_viewable), SUM(b.conversions), @now
+FROM (
+	SELECT %[1]s AS bucket, ad_id,
+		COUNT(*) AS clicks, SUM(CASE WHEN invalid THEN 1 ELSE 0 END) AS invalid_clicks,
+		0 AS impressions, 0 AS invalid_impressions,
+		0 AS measured, 0 AS invalid_measured, 0 AS viewable, 0 AS invalid_viewable, 0 AS conversions
+	FROM click_events WHERE timestamp >= @from AND timestamp < @to
+	GROUP BY 1, 2
+	UNION ALL
+	SELECT %[2]s, i.ad_id,
+		0, 0, COUNT(*), SUM(CASE WHEN i.invalid THEN 1 ELSE 0 END),
+		SUM(CASE WHEN %[3]s THEN 1 ELSE 0 END),
+		SUM(CASE WHEN %[3]s AND i.invalid THEN 1 ELSE 0 END),
+		SUM(CASE WHEN %[4]s THEN 1 ELSE 0 END),
+		SUM(CASE WHEN %[4]s AND i.invalid THEN 1 ELSE 0 END), 0
+	FROM impression_events i LEFT JOIN ads a ON a.id = i.ad_id
+	WHERE i.timestamp >= @from AND i.timestamp < @to
+	GROUP BY 1, 2
+	UNION ALL
+	SELECT %[1]s, attributed_ad_id,
+		0, 0, 0, 0, 0, 0, 0, 0, COUNT(*)
+	FROM conversions WHERE attributed_ad_id IS NOT NULL AND timestamp >= @from AND timestamp < @to
+	GROUP BY 1, 2
+) b
+LEFT JOIN ads ON ads.id = b.ad_id
+GROUP BY b.bucket, b.ad_id, ads.campaign_id`,
+		truncateUTC(db, "hour", "timestamp"), truncateUTC(db, "hour", "i.timestamp"), rollupMeasured, rollupViewable)
+}
+
+// rollupMeasured and rollupViewable match the conditions of
+// EventStore.ViewabilityStats on impression_events i joined to ads a.
+const (
+	rollupMeasured = "(i.time_in_view_ms > 0 OR i.percent_in_view > 0)"
+	rollupViewable = rollupMeasured + ` AND i.percent_in_view >= @min_percent
+		AND i.time_in_view_ms >= CASE WHEN COALESCE(a.duration_seconds, 0) > 0 THEN @video_ms ELSE @display_ms END`
+)
+
+func rollupDaysSQL(db *gorm.DB) string {
+	return fmt.Sprintf(`
+INSERT INTO event_rollups (granularity, bucket_start, ad_id, campaign_id, clicks, invalid_clicks, impressions, invalid_impressions,
+	measured, invalid_measured, viewable, invalid_viewable, conversions, updated_at)
+SELECT 'day', %s, ad_id, MAX(campaign_id),
+	SUM(clicks), SUM(invalid_clicks), SUM(impressions), SUM(invalid_impressions),
+	SUM(measured), SUM(invalid_measured), SUM(viewable), SUM(invalid_viewable), SUM(conversions), @now
+FROM event_rollups
+WHERE granularity = 'hour' AND bucket_start >= @from AND bucket_start < @to
+GROUP BY 2, ad_id`, truncateUTC(db, "day", "bucket_start"))
+}
+
+type RollupRepository struct {
+	db          *gorm.DB
+	viewability models.ViewabilityThreshold
+}
+
+func NewRollupRepository(db *gorm.DB) *RollupRepository {
+	return &RollupRepository{db: db, viewability: models.DefaultViewabilityThreshold}
+}
+
+// SetViewabilityThreshold sets the rule viewable impressions are counted
+// by. It should match the one analytics uses; buckets already rolled up
+// keep the old rule until they are rebuilt.
+func (r *RollupRepository) SetViewabilityThreshold(threshold models.ViewabilityThreshold) {
+	r.viewability = threshold
+}
+
+// Coverage returns the time up to which hourly rollups are complete, or the
+// zero time before the first run.
+func (r *RollupRepository) Coverage() (time.Time, error) {
+	var coverage models.RollupCoverage
+	err := r.db.First(&coverage, 1).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	return coverage.CoveredUntil, err
+}
+
+func (r *RollupRepository) SetCoverage(until time.Time) error {
+	return r.db.Save(&models.RollupCoverage{ID: 1, CoveredUntil: until}).Error
+}
+
+// EarliestEvent returns the oldest click or impression timestamp; ok is false
+// when there are no events yet.
+func (r *RollupRepository) EarliestEvent() (time.Time, bool, error) {
+	var earliest sql.NullTime
+	err := r.db.Raw(`SELECT MIN(earliest) FROM (
+		SELECT MIN(timestamp) AS earliest FROM click_events
+		UNION ALL
+		SELECT MIN(timestamp) FROM impression_events) e`).Row().Scan(&earliest)
+	if err != nil || !earliest.Valid {
+		return time.Time{}, false, err
+	}
+	return earliest.Time, true, nil
+}
+
+// RollupHours rebuilds the hourly rows for buckets in [from, to).
+func (r *RollupRepository) RollupHours(from, to time.Time) error {
+	return r.rebuild(models.RollupHour, rollupHoursSQL(r.db), from, to)
+}
+
+// RollupDays rebuilds the daily rows for [from, to) from the hourly rows.
+func (r *RollupRepository) RollupDays(from, to time.Time) error {
+	return r.rebuild(models.RollupDay, rollupDaysSQL(r.db), from, to)
+}
+
+// rebuild replaces the rows in range in one transaction, so buckets whose
+// events were erased disappear and readers never see a half-built range.
+func (r *RollupRepository) rebuild(granularity, query string, from, to time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("granularity = ? AND bucket_start >= ? AND bucket_start < ?", granularity, from, to).
+			Delete(&models.EventRollup{}).Error; err != nil {
+			return err
+		}
+		return tx.Exec(query, map[string]interface{}{
+			"from":        from,
+			"to":          to,
+			"now":         time.Now().UTC(),
+			"min_percent": r.viewability.MinPercent,
+			"display_ms":  r.viewability.DisplayMS,
+			"video_ms":    r.viewability.VideoMS,
+		}).Error
+	})
+}
+
+// Totals sums an ad's rollups over [from, to), both hour aligned; adID 0
+// sums every ad. Whole days are read from daily rows, the ragged ends from
+// hourly rows.
+func (r *RollupRepository) Totals(adID uint, from, to time.Time) (models.RollupTotals, error) {
+	var totals models.RollupTotals
+	tx := r.db.Model(&models.EventRollup{}).Select(rollupSums)
+	if adID != 0 {
+		tx = tx.Where("ad_id = ?", adID)
+	}
+	err := inBuckets(tx, from, to).Scan(&totals).Error
+	return totals, err
+}
+
+// TotalsByAd sums each ad's rollups over [from, to) like Totals, in one
+// query. Ads without rows are left out.
+func (r *RollupRepository) TotalsByAd(adIDs []uint, from, to time.Time) (map[uint]models.RollupTotals, error) {
+	var rows []struct {
+		AdID uint
+		models.RollupTotals
+	}
+	tx := r.db.Model(&models.EventRollup{}).Select("ad_id, "+rollupSums).Where("ad_id IN ?", adIDs)
+	if err := inBuckets(tx, from, to).Group("ad_id").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	totals := make(map[uint]models.RollupTotals, len(rows))
+	for _, row := range rows {
+		totals[row.AdID] = row.RollupTotals
+	}
+	return totals, nil
+}
+
+const rollupSums = `COALESCE(SUM(clicks), 0) AS clicks,
+		COALESCE(SUM(invalid_clicks), 0) AS invalid_clicks,
+		COALESCE(SUM(impressions), 0) AS impressions,
+		COALESCE(SUM(invalid_impressions), 0) AS invalid_impressions,
+		COALESCE(SUM(measured), 0) AS measured,
+		COALESCE(SUM(invalid_measured), 0) AS invalid_measured,
+		COALESCE(SUM(viewable), 0) AS viewable,
+		COALESCE(SUM(invalid_viewable), 0) AS invalid_viewable,
+		COALESCE(SUM(conversions), 0) AS conversions`
+
+// inBuckets limits a rollup query to [from, to), both hour aligned,
+// reading whole days from daily rows and the ragged ends from hourly rows.
+func inBuckets(tx *gorm.DB, from, to time.Time) *gorm.DB {
+	dayFrom := from.Truncate(24 * time.Hour)
+	if dayFrom.Before(from) {
+		dayFrom = dayFrom.Add(24 * time.Hour)
+	}
+	dayTo := to.Truncate(24 * time.Hour)
+	if dayFrom.Before(dayTo) {
+		return tx.Where(`((granularity = ? AND bucket_start >= ? AND bucket_start < ?)
+			OR (granularity = ? AND ((bucket_start >= ? AND bucket_start < ?) OR (bucket_start >= ? AND bucket_start < ?))))`,
+			models.RollupDay, dayFrom, dayTo,
+			models.RollupHour, from, dayFrom, dayTo, to)
+	}
+	return tx.Where("granularity = ? AND bucket_start >= ? AND bucket_start < ?", models.RollupHour, from, to)
+}
+
+// ClickedAdIDs returns the ads with clicks in [from, to).
+func (r *RollupRepository) ClickedAdIDs(from, to time.Time) ([]uint, error) {
+	var adIDs []uint
+	err := r.db.Model(&models.EventRollup{}).
+		Where("granularity = ? AND bucket_start >= ? AND bucket_start < ? AND clicks > 0", models.RollupHour, from, to).
+		Distinct("ad_id").
+		Pluck("ad_id", &adIDs).Error
+	return adIDs, err
+}
+
+// CampaignHour is a campaign's valid events in one hour.
+type CampaignHour struct {
+	CampaignID  uint
+	BucketStart time.Time
+	Clicks      int64
+	Impressions int64
+}
+
+// CampaignHours sums the hourly rollups in [from, to) per campaign, valid
+// events only. Hours without events are left out.
+func (r *RollupRepository) CampaignHours(from, to time.Time) ([]CampaignHour, error) {
+	var hours []CampaignHour
+	err := r.db.Model(&models.EventRollup{}).
+		Select(`campaign_id, bucket_start,
+			COALESCE(SUM(clicks - invalid_clicks), 0) AS clicks,
+			COALESCE(SUM(impressions - invalid_impressions), 0) AS impressions`).
+		Where("granularity = ? AND bucket_start >= ? AND bucket_start < ? AND campaign_id IS NOT NULL", models.RollupHour, from, to).
+		Group("campaign_id, bucket_start").
+		Order("campaign_id, bucket_start").
+		Scan(&hours).Error
+	return hours, err
+}
@@ -0,0 +1,125 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sessionsSQL summarizes every session with an event in [from, until)
+// that has ended by until, over all of its events. A conversion converts
+// the session of the click or impression it was attributed to.
+const sessionsSQL = `
+WITH touched AS (
+	SELECT session_id FROM click_events
+	WHERE session_id <> '' AND timestamp >= @from AND timestamp < @until
+	UNION
+	SELECT session_id FROM impression_events
+	WHERE session_id <> '' AND timestamp >= @from AND timestamp < @until
+), session_events AS (
+	SELECT 'click_through' AS attribution, id, session_id, ad_id, user_id, timestamp, 1 AS clicks
+	FROM click_events WHERE session_id IN (SELECT session_id FROM touched)
+	UNION ALL
+	SELECT 'view_through', id, session_id, ad_id, user_id, timestamp, 0
+	FROM impression_events WHERE session_id IN (SELECT session_id FROM touched)
+)
+SELECT e.session_id AS id,
+	MAX(e.user_id) AS user_id,
+	(SELECT f.ad_id FROM session_events f WHERE f.session_id = e.session_id
+		ORDER BY f.timestamp, f.clicks, f.id LIMIT 1) AS entry_ad_id,
+	MIN(e.timestamp) AS started_at,
+	MAX(e.timestamp) AS ended_at,
+	COUNT(*) AS events,
+	SUM(e.clicks) AS clicks,
+	COUNT(*) - SUM(e.clicks) AS impressions,
+	EXISTS (SELECT 1 FROM conversions c JOIN session_events v
+		ON c.attributed_event_id = v.id AND c.attribution_type = v.attribution
+		WHERE v.session_id = e.session_id) AS converted
+FROM session_events e
+GROUP BY e.session_id
+HAVING MAX(e.timestamp) < @until`
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+// Coverage returns the time up to which ended sessions were built, or the
+// zero time before the first run.
+func (r *SessionRepository) Coverage() (time.Time, error) {
+	var coverage models.SessionCoverage
+	err := r.db.First(&coverage, 1).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, nil
+	}
+	return coverage.CoveredUntil, err
+}
+
+func (r *SessionRepository) SetCoverage(until time.Time) error {
+	return r.db.Save(&models.SessionCoverage{ID: 1, CoveredUntil: until}).Error
+}
+
+// EarliestEvent returns the oldest timestamp of an event with a session;
+// ok is false when there is none.
+func (r *SessionRepository) EarliestEvent() (time.Time, bool, error) {
+	var earliest time.Time
+	for _, model := range []interface{}{&models.ClickEvent{}, &models.ImpressionEvent{}} {
+		// Reading the column itself rather than MIN() keeps its type on
+		// SQLite
+		var times []time.Time
+		err := r.db.Model(model).Where("session_id <> ''").Order("timestamp").Limit(1).Pluck("timestamp", &times).Error
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if len(times) > 0 && (earliest.IsZero() || times[0].Before(earliest)) {
+			earliest = times[0]
+		}
+	}
+	return earliest, !earliest.IsZero(), nil
+}
+
+// Build writes the summary of every session with events in [from, until)
+// that ended before until, replacing earlier summaries of the same
+// sessions, and returns how many it wrote. Sessions still running at until
+// are left for a later call.
+func (r *SessionRepository) Build(from, until time.Time) (int, error) {
+	var rows []struct {
+		ID          string
+		UserID      string
+		EntryAdID   uint
+		StartedAt   sqlTime
+		EndedAt     sqlTime
+		Events      int64
+		Clicks      int64
+		Impressions int64
+		Converted   bool
+	}
+	err := r.db.Raw(sessionsSQL, map[string]interface{}{"from": from, "until": until}).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return 0, err
+	}
+	sessions := make([]models.Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = models.Session{
+			ID:          row.ID,
+			UserID:      row.UserID,
+			EntryAdID:   row.EntryAdID,
+			StartedAt:   row.StartedAt.UTC(),
+			EndedAt:     row.EndedAt.UTC(),
+			DurationMS:  row.EndedAt.Sub(row.StartedAt.Time).Milliseconds(),
+			Events:      row.Events,
+			Clicks:      row.Clicks,
+			Impressions: row.Impressions,
+			Converted:   row.Converted,
+		}
+	}
+	err = r.db.Clauses(clause.OnConflict{UpdateAll: true}).CreateInBatches(sessions, 500).Error
+	return len(sessions), err
+}
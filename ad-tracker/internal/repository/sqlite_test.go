@@ -0,0 +1,35 @@
+package repositories_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/migrations"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// openTestDB returns a migrated in-memory SQLite database, closed when the
+// test ends.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db, err := database.SetupDatabase(database.DriverSQLite, "file::memory:", database.PoolConfig{}, logger)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err := migrations.New(db, logger).Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
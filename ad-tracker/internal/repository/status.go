@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StatusRepository struct {
+	db *gorm.DB
+}
+
+func NewStatusRepository(db *gorm.DB) *StatusRepository {
+	return &StatusRepository{db: db}
+}
+
+func (r *StatusRepository) CreateIncident(incident *models.Incident) error {
+	return r.db.Create(incident).Error
+}
+
+func (r *StatusRepository) ResolveIncident(id uint) (*models.Incident, error) {
+	var incident models.Incident
+	if err := r.db.First(&incident, id).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	incident.Active = false
+	incident.ResolvedAt = &now
+	if err := r.db.Save(&incident).Error; err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+func (r *StatusRepository) ListIncidents(activeOnly bool, limit int) ([]models.Incident, error) {
+	var incidents []models.Incident
+	tx := r.db.Order("started_at DESC").Limit(limit)
+	if activeOnly {
+		tx = tx.Where("active = ?", true)
+	}
+	err := tx.Find(&incidents).Error
+	return incidents, err
+}
+
+// RecordCheck increments today's check counters for a component.
+func (r *StatusRepository) RecordCheck(component string, healthy bool) error {
+	success := int64(0)
+	if healthy {
+		success = 1
+	}
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "component"}, {Name: "day"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"checks":    gorm.Expr("uptime_days.checks + 1"),
+			"successes": gorm.Expr("uptime_days.successes + ?", success),
+		}),
+	}).Create(&models.UptimeDay{
+		Component: component,
+		Day:       day,
+		Checks:    1,
+		Successes: success,
+	}).Error
+}
+
+// Uptime returns the success ratio per component since the given day.
+func (r *StatusRepository) Uptime(since time.Time) (map[string]float64, error) {
+	var rows []struct {
+		Component string
+		Checks    int64
+		Successes int64
+	}
+
+	err := r.db.Model(&models.UptimeDay{}).
+		Select("component, SUM(checks) AS checks, SUM(successes) AS successes").
+		Where("day >= ?", since.UTC().Truncate(24*time.Hour)).
+		Group("component").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	uptime := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		if row.Checks > 0 {
+			uptime[row.Component] = float64(row.Successes) / float64(row.Checks)
+		}
+	}
+	return uptime, nil
+}
@@ -0,0 +1,161 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/models"
+)
+
+// GetPrecomputedSummary answers a campaign summary from the rollups, which
+// the rollup job keeps current, so its cost follows the number of ads and
+// days rather than events. Raw events are only counted outside the hourly
+// coverage: the partial hour at the start and the time since the last
+// aggregation. ok is false when rollups are not configured or unreadable.
+func (r *AnalyticsRepository) GetPrecomputedSummary(campaign models.Campaign, adIDs []uint, from, until time.Time, validOnly, daily bool) (models.CampaignSummary, bool) {
+	if r.rollups == nil {
+		return models.CampaignSummary{}, false
+	}
+	covered, err := r.rollups.Coverage()
+	if err != nil {
+		r.logger.WithError(err).Warn("Failed to read rollup coverage")
+		return models.CampaignSummary{}, false
+	}
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+	summary := models.CampaignSummary{
+		CampaignID:  campaign.ID,
+		Name:        campaign.Name,
+		Since:       from,
+		Ads:         make([]models.AnalyticsResponse, 0, len(adIDs)),
+		Precomputed: true,
+	}
+	end := now
+	if !until.IsZero() {
+		end, summary.Until = until, &until
+	}
+
+	totals, ok := r.countsByAd(ctx, covered, adIDs, from, end, validOnly)
+	if !ok {
+		return models.CampaignSummary{}, false
+	}
+	lastDay, ok := r.countsByAd(ctx, covered, adIDs, now.Add(-24*time.Hour), now, validOnly)
+	if !ok {
+		return models.CampaignSummary{}, false
+	}
+	for _, adID := range adIDs {
+		analytics := models.AnalyticsResponse{
+			AdID:        adID,
+			ValidOnly:   validOnly,
+			ClickCount:  totals[adID].clicks,
+			Impressions: totals[adID].impressions,
+			LastDay:     lastDay[adID].clicks,
+		}
+		if analytics.Impressions > 0 {
+			analytics.CTR = float64(analytics.ClickCount) / float64(analytics.Impressions)
+		}
+		analytics.LastHour, err = r.store.CountClicks(ctx, events.Query{AdID: adID, Since: now.Add(-time.Hour), ValidOnly: validOnly})
+		if err != nil {
+			r.logger.WithError(err).Error("Failed to get last hour count")
+		}
+		summary.ClickCount += analytics.ClickCount
+		summary.LastHour += analytics.LastHour
+		summary.LastDay += analytics.LastDay
+		summary.Ads = append(summary.Ads, analytics)
+	}
+	if !daily {
+		return summary, true
+	}
+
+	for day := from.UTC().Truncate(24 * time.Hour); day.Before(end); day = day.Add(24 * time.Hour) {
+		dayFrom, dayUntil := day, day.Add(24*time.Hour)
+		if dayFrom.Before(from) {
+			dayFrom = from
+		}
+		if dayUntil.After(end) {
+			dayUntil = end
+		}
+		counts, ok := r.countsByAd(ctx, covered, adIDs, dayFrom, dayUntil, validOnly)
+		if !ok {
+			return models.CampaignSummary{}, false
+		}
+		row := models.SummaryDay{Date: day.Format("2006-01-02")}
+		for _, count := range counts {
+			row.Clicks += count.clicks
+			row.Impressions += count.impressions
+		}
+		if row.Impressions > 0 {
+			row.CTR = float64(row.Clicks) / float64(row.Impressions)
+		}
+		summary.Days = append(summary.Days, row)
+	}
+	return summary, true
+}
+
+type adCounts struct {
+	clicks      int64
+	impressions int64
+}
+
+// countsByAd counts each ad's clicks and impressions in [from, until): the
+// whole hours before covered from the rollups, in one query, and the rest
+// from raw events.
+func (r *AnalyticsRepository) countsByAd(ctx context.Context, covered time.Time, adIDs []uint, from, until time.Time, validOnly bool) (map[uint]adCounts, bool) {
+	counts := make(map[uint]adCounts, len(adIDs))
+	if len(adIDs) == 0 {
+		return counts, true
+	}
+
+	rolledFrom := from.UTC().Truncate(time.Hour)
+	if rolledFrom.Before(from) {
+		rolledFrom = rolledFrom.Add(time.Hour)
+	}
+	rolledTo := until.UTC().Truncate(time.Hour)
+	if covered.Before(rolledTo) {
+		rolledTo = covered
+	}
+	raw := []events.Query{{Since: from, Until: until}}
+	if rolledFrom.Before(rolledTo) {
+		totals, err := r.rollups.TotalsByAd(adIDs, rolledFrom, rolledTo)
+		if err != nil {
+			r.logger.WithError(err).Warn("Failed to read rollups")
+			return nil, false
+		}
+		for adID, total := range totals {
+			count := adCounts{clicks: total.Clicks, impressions: total.Impressions}
+			if validOnly {
+				count.clicks -= total.InvalidClicks
+				count.impressions -= total.InvalidImpressions
+			}
+			counts[adID] = count
+		}
+		raw = []events.Query{{Since: from, Until: rolledFrom}, {Since: rolledTo, Until: until}}
+	}
+
+	for _, query := range raw {
+		if !query.Since.Before(query.Until) {
+			continue
+		}
+		query.ValidOnly = validOnly
+		for _, adID := range adIDs {
+			query.AdID = adID
+			clicks, err := r.store.CountClicks(ctx, query)
+			if err != nil {
+				r.logger.WithError(err).Warn("Failed to count clicks outside rollups")
+				return nil, false
+			}
+			impressions, err := r.store.CountImpressions(ctx, query)
+			if err != nil {
+				r.logger.WithError(err).Warn("Failed to count impressions outside rollups")
+				return nil, false
+			}
+			count := counts[adID]
+			count.clicks += clicks
+			count.impressions += impressions
+			counts[adID] = count
+		}
+	}
+	return counts, true
+}
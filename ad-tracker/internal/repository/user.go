@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"sort"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type UserRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// ClickStats counts the users who clicked since the given time, optionally
+// on one ad, and how many of them had clicked before.
+func (r *UserRepository) ClickStats(adID uint, since time.Time, validOnly bool) (models.UserClickStats, error) {
+	users := r.db.Model(&models.ClickEvent{}).
+		Select("user_id, COUNT(CASE WHEN timestamp >= ? THEN 1 END) AS clicks, MIN(timestamp) AS first_click", since).
+		Where("user_id <> ''").
+		Group("user_id").
+		Having("MAX(timestamp) >= ?", since)
+	if adID != 0 {
+		users = users.Where("ad_id = ?", adID)
+	}
+	if validOnly {
+		users = users.Where("invalid = ?", false)
+	}
+
+	var result struct {
+		Users    int64
+		Clicks   int64
+		NewUsers int64
+	}
+	err := r.db.Raw(`SELECT COUNT(*) AS users, COALESCE(SUM(clicks), 0) AS clicks,
+			COUNT(CASE WHEN first_click >= ? THEN 1 END) AS new_users
+		FROM (?) AS users`, since, users).Scan(&result).Error
+	if err != nil {
+		return models.UserClickStats{}, err
+	}
+
+	stats := models.UserClickStats{
+		AdID:           adID,
+		Users:          result.Users,
+		Clicks:         result.Clicks,
+		NewUsers:       result.NewUsers,
+		ReturningUsers: result.Users - result.NewUsers,
+	}
+	if stats.Users > 0 {
+		stats.ClicksPerUser = float64(stats.Clicks) / float64(stats.Users)
+	}
+	return stats, nil
+}
+
+// Timeline returns a user's clicks, impressions and conversions since the
+// given time, newest first, at most limit of them.
+func (r *UserRepository) Timeline(userID string, since time.Time, limit int) ([]models.TimelineEvent, error) {
+	var clicks []models.ClickEvent
+	err := r.db.Where("user_id = ? AND timestamp >= ?", userID, since).
+		Order("timestamp DESC").Limit(limit).Find(&clicks).Error
+	if err != nil {
+		return nil, err
+	}
+	var impressions []models.ImpressionEvent
+	err = r.db.Where("user_id = ? AND timestamp >= ?", userID, since).
+		Order("timestamp DESC").Limit(limit).Find(&impressions).Error
+	if err != nil {
+		return nil, err
+	}
+	var conversions []models.Conversion
+	err = r.db.Where("user_id = ? AND timestamp >= ?", userID, since).
+		Order("timestamp DESC").Limit(limit).Find(&conversions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := make([]models.TimelineEvent, 0, len(clicks)+len(impressions)+len(conversions))
+	for _, click := range clicks {
+		adID := click.AdID
+		timeline = append(timeline, models.TimelineEvent{
+			Type:      models.TimelineClick,
+			Timestamp: click.Timestamp,
+			AdID:      &adID,
+			ClickID:   click.ClickID,
+			SessionID: click.SessionID,
+			Invalid:   click.Invalid,
+		})
+	}
+	for _, impression := range impressions {
+		adID := impression.AdID
+		timeline = append(timeline, models.TimelineEvent{
+			Type:      models.TimelineImpression,
+			Timestamp: impression.Timestamp,
+			AdID:      &adID,
+			SessionID: impression.SessionID,
+			Invalid:   impression.Invalid,
+		})
+	}
+	for _, conversion := range conversions {
+		timeline = append(timeline, models.TimelineEvent{
+			Type:      models.TimelineConversion,
+			Timestamp: conversion.Timestamp,
+			AdID:      conversion.AttributedAdID,
+			ClickID:   conversion.ClickID,
+			Value:     conversion.Value,
+		})
+	}
+
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Timestamp.After(timeline[j].Timestamp)
+	})
+	if len(timeline) > limit {
+		timeline = timeline[:limit]
+	}
+	return timeline, nil
+}
@@ -0,0 +1,129 @@
+package repositories
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	return r.db.Create(endpoint).Error
+}
+
+func (r *WebhookRepository) GetEndpoint(id uint) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	if err := r.db.First(&endpoint, id).Error; err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *WebhookRepository) ListEndpoints(accountID uint) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	err := r.db.Where("account_id = ?", accountID).Order("id").Find(&endpoints).Error
+	return endpoints, err
+}
+
+// DeleteEndpoint removes an endpoint and its delivery log.
+func (r *WebhookRepository) DeleteEndpoint(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.WebhookEndpoint{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// EndpointsByAd maps each ad to the active endpoints of its account.
+func (r *WebhookRepository) EndpointsByAd() (map[uint][]models.WebhookEndpoint, error) {
+	var rows []struct {
+		AdID uint
+		models.WebhookEndpoint
+	}
+	err := r.db.Table("webhook_endpoints").
+		Select("ads.id AS ad_id, webhook_endpoints.*").
+		Joins("JOIN campaigns ON campaigns.account_id = webhook_endpoints.account_id").
+		Joins("JOIN ads ON ads.campaign_id = campaigns.id").
+		Where("webhook_endpoints.active = ?", true).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make(map[uint][]models.WebhookEndpoint)
+	for _, row := range rows {
+		endpoints[row.AdID] = append(endpoints[row.AdID], row.WebhookEndpoint)
+	}
+	return endpoints, nil
+}
+
+func (r *WebhookRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(&deliveries).Error
+}
+
+func (r *WebhookRepository) SaveDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+func (r *WebhookRepository) GetDelivery(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ClaimDelivery takes a delivery for one attempt: it moves the attempt count
+// from observed to attempts and pushes the next attempt to leaseUntil, so a
+// crashed sender's attempt is retried later. It reports false when another
+// replica claimed the delivery first.
+func (r *WebhookRepository) ClaimDelivery(id uint, observed, attempts int, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND attempts = ?", id, observed).
+		Updates(map[string]interface{}{
+			"status":          models.WebhookPending,
+			"attempts":        attempts,
+			"next_attempt_at": leaseUntil,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// DueDeliveries returns pending deliveries whose next attempt has come.
+func (r *WebhookRepository) DueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.WebhookPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *WebhookRepository) ListDeliveries(endpointID uint, status string, limit int) ([]models.WebhookDelivery, error) {
+	tx := r.db.Where("endpoint_id = ?", endpointID)
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	var deliveries []models.WebhookDelivery
+	err := tx.Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
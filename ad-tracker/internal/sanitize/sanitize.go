@@ -0,0 +1,55 @@
+// Package sanitize cleans client-supplied strings before they are stored,
+// so one client cannot bloat event rows or break the JSON and CSV exports
+// built from them.
+package sanitize
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Length limits for free-form fields tracking requests carry.
+const (
+	MaxUserIDLength    = 256
+	MaxConsentLength   = 4096
+	MaxUserAgentLength = 512
+)
+
+// String drops control characters and invalid UTF-8, trims surrounding
+// white space and cuts the result to at most maxBytes without splitting a
+// character. maxBytes <= 0 leaves the length alone.
+func String(s string, maxBytes int) string {
+	if clean(s) {
+		s = strings.TrimSpace(s)
+	} else {
+		var b strings.Builder
+		b.Grow(len(s))
+		for _, r := range s {
+			if r == utf8.RuneError || unicode.IsControl(r) {
+				continue
+			}
+			b.WriteRune(r)
+		}
+		s = strings.TrimSpace(b.String())
+	}
+
+	if maxBytes > 0 && len(s) > maxBytes {
+		cut := maxBytes
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = strings.TrimSpace(s[:cut])
+	}
+	return s
+}
+
+// clean reports whether s is printable ASCII, which needs no rewriting.
+func clean(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
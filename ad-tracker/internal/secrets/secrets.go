@@ -0,0 +1,124 @@
+// Package secrets resolves configuration values that point at a secret
+// store instead of holding the secret itself:
+//
+//	DATABASE_URL=vault://secret/data/ad-tracker#database_url
+//	DATABASE_URL=awssm://prod/ad-tracker#database_url
+//	DATABASE_URL=sops:///etc/ad-tracker/secrets.enc.yaml#database_url
+//
+// The fragment picks a key from the secret's JSON document; without one the
+// whole secret is the value. Each document is fetched once per resolve.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Provider fetches the raw secret at path.
+type Provider interface {
+	Fetch(ctx context.Context, path string) (string, error)
+}
+
+type Resolver struct {
+	providers map[string]Provider
+}
+
+func NewResolver(providers map[string]Provider) *Resolver {
+	return &Resolver{providers: providers}
+}
+
+// ResolverFromEnv configures Vault (VAULT_ADDR, VAULT_TOKEN, VAULT_NAMESPACE),
+// AWS Secrets Manager (AWS_REGION and the standard credential variables) and
+// the sops binary. Providers are only contacted when a value refers to them.
+func ResolverFromEnv() *Resolver {
+	return NewResolver(map[string]Provider{
+		"vault": VaultFromEnv(),
+		"awssm": AWSFromEnv(),
+		"sops":  SOPS{},
+	})
+}
+
+// LoadEnv resolves the references in the environment with ResolverFromEnv,
+// giving up after timeout. Call it before reading any configuration.
+func LoadEnv(timeout time.Duration) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return ResolverFromEnv().ResolveEnv(ctx)
+}
+
+// IsReference reports whether value names a secret rather than holding one.
+func (r *Resolver) IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return false
+	}
+	_, known := r.providers[scheme]
+	return known
+}
+
+// ResolveEnv replaces every environment variable holding a reference with
+// the secret it points at and returns the names it replaced.
+func (r *Resolver) ResolveEnv(ctx context.Context) ([]string, error) {
+	documents := make(map[string]string)
+	var resolved []string
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if !r.IsReference(value) {
+			continue
+		}
+		secret, err := r.resolve(ctx, value, documents)
+		if err != nil {
+			return resolved, fmt.Errorf("%s: %w", name, err)
+		}
+		if err := os.Setenv(name, secret); err != nil {
+			return resolved, err
+		}
+		resolved = append(resolved, name)
+	}
+	return resolved, nil
+}
+
+// Resolve returns the secret value points at, or value itself when it is
+// not a reference.
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	if !r.IsReference(value) {
+		return value, nil
+	}
+	return r.resolve(ctx, value, make(map[string]string))
+}
+
+func (r *Resolver) resolve(ctx context.Context, reference string, documents map[string]string) (string, error) {
+	scheme, rest, _ := strings.Cut(reference, "://")
+	path, key, _ := strings.Cut(rest, "#")
+
+	document, ok := documents[scheme+"://"+path]
+	if !ok {
+		var err error
+		document, err = r.providers[scheme].Fetch(ctx, path)
+		if err != nil {
+			return "", fmt.Errorf("%s secret %s: %w", scheme, path, err)
+		}
+		documents[scheme+"://"+path] = document
+	}
+	if key == "" {
+		return document, nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(document), &fields); err != nil {
+		return "", fmt.Errorf("%s secret %s is not a JSON object, cannot select %q", scheme, path, key)
+	}
+	field, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%s secret %s has no key %q", scheme, path, key)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	encoded, err := json.Marshal(field)
+	return string(encoded), err
+}
@@ -0,0 +1,30 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// SOPS decrypts files with the sops binary, which brings its own key
+// handling (KMS, age, PGP). Output is JSON whatever the file format.
+type SOPS struct {
+	// Binary defaults to sops on PATH.
+	Binary string
+}
+
+func (s SOPS) Fetch(ctx context.Context, path string) (string, error) {
+	binary := s.Binary
+	if binary == "" {
+		binary = "sops"
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, "--decrypt", "--output-type", "json", path)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
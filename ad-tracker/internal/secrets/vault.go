@@ -0,0 +1,73 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/config"
+)
+
+// Vault reads secrets over the HTTP API with a token. Paths are API paths
+// below /v1, so KV v2 secrets include the data/ segment.
+type Vault struct {
+	Addr      string
+	Token     string
+	Namespace string
+	Client    *http.Client
+}
+
+func VaultFromEnv() *Vault {
+	return &Vault{
+		Addr:      config.GetEnv("VAULT_ADDR", "http://127.0.0.1:8200"),
+		Token:     config.GetEnv("VAULT_TOKEN", ""),
+		Namespace: config.GetEnv("VAULT_NAMESPACE", ""),
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Fetch returns the secret's key/value data as a JSON object.
+func (v *Vault) Fetch(ctx context.Context, path string) (string, error) {
+	if v.Token == "" {
+		return "", errors.New("VAULT_TOKEN is not set")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimRight(v.Addr, "/")+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.Token)
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var payload struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", err
+	}
+	// KV v2 nests the values under data.data next to data.metadata.
+	if inner, ok := payload.Data["data"]; ok {
+		if _, versioned := payload.Data["metadata"]; versioned {
+			return string(inner), nil
+		}
+	}
+	encoded, err := json.Marshal(payload.Data)
+	return string(encoded), err
+}
@@ -0,0 +1,202 @@
+// Package seed loads fixture data for local development and load tests. It
+// must never touch a production database: the seed command refuses to run
+// when GIN_MODE=release.
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// LoadTestCampaign names the campaign holding the load-test ads; its
+// presence marks the load-test set as already seeded.
+const LoadTestCampaign = "Load test"
+
+const batchSize = 1000
+
+// Options sizes the load-test set. Seed makes runs reproducible.
+type Options struct {
+	Ads         int
+	Clicks      int
+	Impressions int
+	Days        int
+	Seed        int64
+}
+
+var DefaultOptions = Options{
+	Ads:         50,
+	Clicks:      100000,
+	Impressions: 1000000,
+	Days:        7,
+	Seed:        1,
+}
+
+// Sets are the fixture sets by name. Each one is a no-op when its data is
+// already present, so seeding twice is safe.
+var Sets = map[string]func(db *gorm.DB, opts Options) error{
+	"demo":     Demo,
+	"loadtest": LoadTest,
+}
+
+// SetNames lists the fixture sets in a stable order.
+func SetNames() []string {
+	names := make([]string, 0, len(Sets))
+	for name := range Sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Demo creates three sample ads unless the database already has ads.
+func Demo(db *gorm.DB, _ Options) error {
+	var count int64
+	if err := db.Model(&models.Ad{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	sampleAds := []models.Ad{
+		{
+			ImageURL:        "https://example.com/ad1.jpg",
+			TargetURL:       "https://example.com/product1",
+			Title:           "Amazing Product 1",
+			Active:          true,
+			DurationSeconds: 30,
+		},
+		{
+			ImageURL:  "https://example.com/ad2.jpg",
+			TargetURL: "https://example.com/product2",
+			Title:     "Great Service 2",
+			Active:    true,
+		},
+		{
+			ImageURL:  "https://example.com/ad3.jpg",
+			TargetURL: "https://example.com/product3",
+			Title:     "Special Offer 3",
+			Active:    true,
+		},
+	}
+	return db.Create(&sampleAds).Error
+}
+
+// LoadTest creates a priced campaign with opts.Ads ads and spreads random
+// clicks and impressions over the last opts.Days days, about 2% of them
+// flagged invalid.
+func LoadTest(db *gorm.DB, opts Options) error {
+	var count int64
+	if err := db.Model(&models.Campaign{}).Where("name = ?", LoadTestCampaign).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	if opts.Ads <= 0 || opts.Days <= 0 {
+		return fmt.Errorf("load test needs at least one ad and one day")
+	}
+
+	campaign := models.Campaign{Name: LoadTestCampaign, Active: true, CostPerClick: 0.5, CostPerMille: 2}
+	if err := db.Create(&campaign).Error; err != nil {
+		return err
+	}
+	ads := make([]models.Ad, opts.Ads)
+	for i := range ads {
+		ads[i] = models.Ad{
+			CampaignID: &campaign.ID,
+			ImageURL:   fmt.Sprintf("https://example.com/load/%d.jpg", i+1),
+			TargetURL:  fmt.Sprintf("https://example.com/load/%d", i+1),
+			Title:      fmt.Sprintf("Load test ad %d", i+1),
+			Active:     true,
+		}
+		if i%5 == 0 {
+			ads[i].DurationSeconds = 30
+		}
+	}
+	if err := db.CreateInBatches(&ads, batchSize).Error; err != nil {
+		return err
+	}
+
+	gen := generator{
+		rand: rand.New(rand.NewSource(opts.Seed)),
+		ads:  ads,
+		end:  time.Now().UTC(),
+		span: time.Duration(opts.Days) * 24 * time.Hour,
+	}
+
+	clicks := make([]models.ClickEvent, 0, batchSize)
+	for i := 0; i < opts.Clicks; i++ {
+		ad, at, user, ip, invalid := gen.event()
+		click := models.ClickEvent{
+			ClickID:   fmt.Sprintf("seed-%d-%d", opts.Seed, i),
+			AdID:      ad.ID,
+			Timestamp: at,
+			UserID:    user,
+			IPAddress: ip,
+			UserAgent: "seed/loadtest",
+			Invalid:   invalid,
+			Processed: true,
+		}
+		if ad.DurationSeconds > 0 {
+			click.VideoPlaybackTime = gen.rand.Int63n(ad.DurationSeconds + 1)
+		}
+		if invalid {
+			click.FraudScore, click.FraudReasons = 1, "seed"
+		}
+		if clicks = append(clicks, click); len(clicks) == batchSize || i == opts.Clicks-1 {
+			if err := db.Create(&clicks).Error; err != nil {
+				return err
+			}
+			clicks = clicks[:0]
+		}
+	}
+
+	impressions := make([]models.ImpressionEvent, 0, batchSize)
+	for i := 0; i < opts.Impressions; i++ {
+		ad, at, user, ip, invalid := gen.event()
+		impression := models.ImpressionEvent{
+			AdID:          ad.ID,
+			Timestamp:     at,
+			UserID:        user,
+			IPAddress:     ip,
+			UserAgent:     "seed/loadtest",
+			TimeInViewMS:  gen.rand.Int63n(5000),
+			PercentInView: float64(gen.rand.Intn(101)),
+			Invalid:       invalid,
+		}
+		if invalid {
+			impression.FraudScore, impression.FraudReasons = 1, "seed"
+		}
+		if impressions = append(impressions, impression); len(impressions) == batchSize || i == opts.Impressions-1 {
+			if err := db.Create(&impressions).Error; err != nil {
+				return err
+			}
+			impressions = impressions[:0]
+		}
+	}
+	return nil
+}
+
+type generator struct {
+	rand *rand.Rand
+	ads  []models.Ad
+	end  time.Time
+	span time.Duration
+}
+
+// event picks an ad, a time in the window, a user from a pool of 10k and an
+// address in 10.0.0.0/8.
+func (g generator) event() (models.Ad, time.Time, string, string, bool) {
+	ad := g.ads[g.rand.Intn(len(g.ads))]
+	at := g.end.Add(-time.Duration(g.rand.Int63n(int64(g.span))))
+	user := fmt.Sprintf("user-%d", g.rand.Intn(10000))
+	ip := fmt.Sprintf("10.%d.%d.%d", g.rand.Intn(256), g.rand.Intn(256), 1+g.rand.Intn(254))
+	return ad, at, user, ip, g.rand.Intn(50) == 0
+}
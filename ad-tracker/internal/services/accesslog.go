@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"ad-tracking-system/internal/events"
+	applog "ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLogMirror publishes access-log records for the enabled route groups
+// to a Kafka topic for security analytics. Records are buffered so a slow
+// broker never holds up a request; they are dropped when the buffer is
+// full.
+type AccessLogMirror struct {
+	bus     events.EventBus
+	groups  map[string]bool
+	records chan models.AccessLogRecord
+	stopped chan struct{}
+	logger  *logrus.Logger
+	sampler *applog.Sampler
+}
+
+func NewAccessLogMirror(bus events.EventBus, groups []string, logger *logrus.Logger) *AccessLogMirror {
+	enabled := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		enabled[group] = true
+	}
+	return &AccessLogMirror{
+		bus:     bus,
+		groups:  enabled,
+		records: make(chan models.AccessLogRecord, 10000),
+		stopped: make(chan struct{}),
+		logger:  logger,
+	}
+}
+
+// SetLogSampler samples the per-record failure warnings, which would
+// otherwise follow traffic while the broker is down.
+func (m *AccessLogMirror) SetLogSampler(sampler *applog.Sampler) {
+	m.sampler = sampler
+}
+
+// Enabled reports whether requests in the route group are mirrored.
+func (m *AccessLogMirror) Enabled(group string) bool {
+	return m.groups[group]
+}
+
+// Record queues a record without blocking.
+func (m *AccessLogMirror) Record(record models.AccessLogRecord) {
+	select {
+	case m.records <- record:
+	default:
+		metrics.AccessLogRecords.WithLabelValues("dropped").Inc()
+	}
+}
+
+// Run publishes queued records until ctx is done, then hands what is still
+// queued to the bus before returning; Close flushes it.
+func (m *AccessLogMirror) Run(ctx context.Context) {
+	defer close(m.stopped)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case record := <-m.records:
+					m.publish(context.Background(), record)
+				default:
+					return
+				}
+			}
+		case record := <-m.records:
+			m.publish(ctx, record)
+		}
+	}
+}
+
+// publish keys records by client IP, so one client's requests stay in
+// order on a partition.
+func (m *AccessLogMirror) publish(ctx context.Context, record models.AccessLogRecord) {
+	value, err := json.Marshal(record)
+	if err != nil {
+		m.logger.WithError(err).Error("Failed to serialize access-log record")
+		return
+	}
+	if err := m.bus.Publish(ctx, []byte(record.ClientIP), value); err != nil {
+		metrics.AccessLogRecords.WithLabelValues("failed").Inc()
+		m.sampler.Log(m.logger.WithError(err), logrus.WarnLevel, "Failed to publish access-log record")
+	}
+}
+
+// Completed is the Completion callback of an async writer behind the bus,
+// which reports delivery after Publish has returned.
+func (m *AccessLogMirror) Completed(messages []kafka.Message, err error) {
+	if err != nil {
+		metrics.AccessLogRecords.WithLabelValues("failed").Add(float64(len(messages)))
+		m.sampler.Log(m.logger.WithError(err).WithField("records", len(messages)), logrus.WarnLevel, "Failed to deliver access-log records")
+		return
+	}
+	metrics.AccessLogRecords.WithLabelValues("published").Add(float64(len(messages)))
+}
+
+// Close waits for Run to return, then flushes and closes the bus.
+func (m *AccessLogMirror) Close() error {
+	<-m.stopped
+	return m.bus.Close()
+}
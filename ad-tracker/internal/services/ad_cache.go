@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/cache"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const activeAdsKey = "ads:active"
+
+// AdCache serves the active ad list and single-ad lookups on the serve and
+// click paths from a short-lived cache. Mutations call Invalidate; the TTL
+// bounds staleness for changes made outside this process. Cache failures
+// fall back to the database.
+type AdCache struct {
+	repo   repositories.AdStore
+	store  cache.Cache
+	ttl    time.Duration
+	logger *logrus.Logger
+}
+
+// cachedAd keeps the honeypot flag, which models.Ad hides from JSON.
+type cachedAd struct {
+	models.Ad
+	Honeypot bool `json:"honeypot"`
+}
+
+// NewAdCache caches for ttl; a ttl of zero disables caching.
+func NewAdCache(repo repositories.AdStore, store cache.Cache, ttl time.Duration, logger *logrus.Logger) *AdCache {
+	return &AdCache{repo: repo, store: store, ttl: ttl, logger: logger}
+}
+
+func adKey(id uint) string {
+	return "ads:id:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// Active returns the servable ads.
+func (a *AdCache) Active(ctx context.Context) ([]models.Ad, error) {
+	if a.ttl <= 0 {
+		return a.repo.Active()
+	}
+
+	var ads []models.Ad
+	if a.lookup(ctx, "active", activeAdsKey, &ads) {
+		return ads, nil
+	}
+	ads, err := a.repo.Active()
+	if err != nil {
+		return nil, err
+	}
+	a.save(ctx, activeAdsKey, ads)
+	return ads, nil
+}
+
+// Get returns one ad, or gorm.ErrRecordNotFound. Misses are not cached so a
+// newly created ad is servable right away.
+func (a *AdCache) Get(ctx context.Context, id uint) (models.Ad, error) {
+	if a.ttl <= 0 {
+		return a.repo.Get(id)
+	}
+
+	var entry cachedAd
+	if a.lookup(ctx, "ad", adKey(id), &entry) {
+		entry.Ad.Honeypot = entry.Honeypot
+		return entry.Ad, nil
+	}
+	ad, err := a.repo.Get(id)
+	if err != nil {
+		return ad, err
+	}
+	a.save(ctx, adKey(id), cachedAd{Ad: ad, Honeypot: ad.Honeypot})
+	return ad, nil
+}
+
+// Invalidate drops the active list and the given ads.
+func (a *AdCache) Invalidate(ctx context.Context, ids ...uint) {
+	keys := []string{activeAdsKey}
+	for _, id := range ids {
+		keys = append(keys, adKey(id))
+	}
+	if err := a.store.Delete(ctx, keys...); err != nil {
+		a.logger.WithError(err).Warn("Failed to invalidate ad cache")
+	}
+}
+
+func (a *AdCache) lookup(ctx context.Context, kind, key string, dst interface{}) bool {
+	ok, err := cache.GetJSON(ctx, a.store, key, dst)
+	switch {
+	case err != nil:
+		metrics.AdCacheRequests.WithLabelValues(kind, "error").Inc()
+		a.logger.WithError(err).WithField("key", key).Warn("Ad cache read failed")
+		return false
+	case ok:
+		metrics.AdCacheRequests.WithLabelValues(kind, "hit").Inc()
+		return true
+	}
+	metrics.AdCacheRequests.WithLabelValues(kind, "miss").Inc()
+	return false
+}
+
+func (a *AdCache) save(ctx context.Context, key string, value interface{}) {
+	if err := cache.SetJSON(ctx, a.store, key, value, a.ttl); err != nil {
+		a.logger.WithError(err).WithField("key", key).Warn("Ad cache write failed")
+	}
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"ad-tracking-system/internal/k8s"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/netguard"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ParseWindow reads a duration that may also be given in days or weeks,
+// e.g. "7d" or "4w".
+func ParseWindow(value string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		count, ok := strings.CutSuffix(value, suffix)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n <= 0 || n > int(math.MaxInt64/unit) {
+			return 0, fmt.Errorf("invalid window %q", value)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q", value)
+	}
+	return d, nil
+}
+
+// AlertEvaluator periodically evaluates alert rules and notifies Slack or
+// generic webhooks when a rule starts or stops firing. Only the elected
+// replica evaluates.
+type AlertEvaluator struct {
+	repo    *repositories.AlertRepository
+	guard   netguard.Guard
+	client  *http.Client
+	elector k8s.Elector
+	logger  *logrus.Logger
+}
+
+func NewAlertEvaluator(repo *repositories.AlertRepository, logger *logrus.Logger) *AlertEvaluator {
+	return &AlertEvaluator{
+		repo:    repo,
+		client:  netguard.Guard{}.Client(10 * time.Second),
+		elector: k8s.AlwaysLeader{},
+		logger:  logger,
+	}
+}
+
+// SetGuard controls which addresses alert hooks may resolve to.
+func (a *AlertEvaluator) SetGuard(guard netguard.Guard) {
+	a.guard = guard
+	a.client = guard.Client(10 * time.Second)
+}
+
+// CheckURL rejects hook URLs the guard would refuse to notify.
+func (a *AlertEvaluator) CheckURL(ctx context.Context, raw string) error {
+	return a.guard.CheckURL(ctx, raw)
+}
+
+func (a *AlertEvaluator) SetElector(elector k8s.Elector) {
+	a.elector = elector
+}
+
+// ValidateRule checks the window, cooldown and template of a rule.
+func ValidateRule(rule *models.AlertRule) error {
+	if _, err := ParseWindow(rule.Window); err != nil {
+		return err
+	}
+	if rule.Cooldown != "" {
+		if _, err := ParseWindow(rule.Cooldown); err != nil {
+			return fmt.Errorf("invalid cooldown %q", rule.Cooldown)
+		}
+	}
+	scopes := 0
+	for _, set := range []bool{rule.AdID != nil, rule.CampaignID != nil, rule.AccountID != nil} {
+		if set {
+			scopes++
+		}
+	}
+	if scopes > 1 {
+		return fmt.Errorf("set at most one of ad_id, campaign_id and account_id")
+	}
+	if rule.Baseline == models.AlertBaselineLastWeek {
+		if window, _ := ParseWindow(rule.Window); window > 7*24*time.Hour {
+			return fmt.Errorf("window must be at most 7d with baseline %q", rule.Baseline)
+		}
+	}
+	if rule.Template != "" {
+		if _, err := template.New("alert").Parse(rule.Template); err != nil {
+			return fmt.Errorf("invalid template: %w", err)
+		}
+	}
+	return nil
+}
+
+// Run evaluates every active rule each interval until ctx is cancelled.
+func (a *AlertEvaluator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if a.elector.IsLeader() {
+			a.EvaluateOnce(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *AlertEvaluator) EvaluateOnce(ctx context.Context) {
+	rules, err := a.repo.ActiveRules()
+	if err != nil {
+		a.logger.WithError(err).Error("Failed to load alert rules")
+		return
+	}
+	for i := range rules {
+		if ctx.Err() != nil {
+			return
+		}
+		a.evaluate(ctx, &rules[i])
+	}
+}
+
+func (a *AlertEvaluator) evaluate(ctx context.Context, rule *models.AlertRule) {
+	log := a.logger.WithField("alert_rule_id", rule.ID)
+
+	window, err := ParseWindow(rule.Window)
+	if err != nil {
+		log.WithError(err).Warn("Skipping alert rule")
+		return
+	}
+
+	now := time.Now().UTC()
+	reading, ok, err := a.measure(rule, window, now)
+	if err != nil {
+		log.WithError(err).Error("Failed to compute alert metric")
+		return
+	}
+	rule.LastEvaluatedAt = &now
+
+	if ok {
+		value := reading.value
+		rule.LastValue = value
+		breached := value < rule.Threshold
+		if rule.Operator == models.AlertAbove {
+			breached = value > rule.Threshold
+		}
+
+		switch {
+		case breached && rule.State != models.AlertFiring:
+			a.transition(ctx, rule, models.AlertFiring, reading, now)
+		case breached && a.cooldownElapsed(rule, now):
+			a.transition(ctx, rule, models.AlertFiring, reading, now)
+		case !breached && rule.State == models.AlertFiring:
+			a.transition(ctx, rule, models.AlertResolved, reading, now)
+		}
+	}
+
+	if err := a.repo.SaveRule(rule); err != nil {
+		log.WithError(err).Error("Failed to save alert rule state")
+	}
+}
+
+// alertReading is what a rule is compared with: the metric itself, or for
+// rules with a baseline its change in percent, with both sides kept for
+// the notification.
+type alertReading struct {
+	value    float64
+	current  *float64
+	baseline *float64
+}
+
+// measure reads the rule's metric over the window ending now and, for
+// rules with a baseline, over the baseline window. ok is false when either
+// is undefined or the baseline is under the rule's minimum, so a campaign
+// that had no traffic to lose does not fire.
+func (a *AlertEvaluator) measure(rule *models.AlertRule, window time.Duration, now time.Time) (alertReading, bool, error) {
+	current, ok, err := a.repo.MetricValue(rule, now.Add(-window), now)
+	if err != nil || !ok || rule.Baseline == "" {
+		return alertReading{value: current}, ok, err
+	}
+
+	until := now.Add(-window)
+	if rule.Baseline == models.AlertBaselineLastWeek {
+		until = now.Add(-7 * 24 * time.Hour)
+	}
+	baseline, ok, err := a.repo.MetricValue(rule, until.Add(-window), until)
+	if err != nil || !ok || baseline == 0 || baseline < rule.MinBaseline {
+		return alertReading{}, false, err
+	}
+	return alertReading{
+		value:    (current - baseline) / baseline * 100,
+		current:  &current,
+		baseline: &baseline,
+	}, true, nil
+}
+
+// cooldownElapsed reports whether a still-firing rule should notify again.
+// Without a cooldown a firing rule notifies only once.
+func (a *AlertEvaluator) cooldownElapsed(rule *models.AlertRule, now time.Time) bool {
+	if rule.Cooldown == "" || rule.LastNotifiedAt == nil {
+		return false
+	}
+	cooldown, err := ParseWindow(rule.Cooldown)
+	return err == nil && now.Sub(*rule.LastNotifiedAt) >= cooldown
+}
+
+func (a *AlertEvaluator) transition(ctx context.Context, rule *models.AlertRule, state string, reading alertReading, now time.Time) {
+	rule.State = state
+	rule.LastNotifiedAt = &now
+
+	event := &models.AlertEvent{RuleID: rule.ID, State: state, Value: reading.value, Threshold: rule.Threshold}
+	err := a.Notify(ctx, rule, models.AlertNotification{
+		Rule:          *rule,
+		State:         state,
+		Metric:        rule.Metric,
+		Value:         reading.value,
+		Threshold:     rule.Threshold,
+		Window:        rule.Window,
+		Current:       reading.current,
+		BaselineValue: reading.baseline,
+		Scope:         alertScope(rule),
+		At:            now,
+	})
+	event.Delivered = err == nil
+	if err != nil {
+		event.Error = err.Error()
+		a.logger.WithError(err).WithField("alert_rule_id", rule.ID).Warn("Alert notification failed")
+	}
+	if err := a.repo.CreateEvent(event); err != nil {
+		a.logger.WithError(err).WithField("alert_rule_id", rule.ID).Error("Failed to record alert event")
+	}
+}
+
+// Notify renders the rule's payload and posts it to the rule's URL.
+func (a *AlertEvaluator) Notify(ctx context.Context, rule *models.AlertRule, notification models.AlertNotification) error {
+	body, err := alertPayload(rule, notification)
+	if err != nil {
+		metrics.AlertNotifications.WithLabelValues(rule.Channel, "render_error").Inc()
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rule.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ad-tracker-alerts/1.0")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		metrics.AlertNotifications.WithLabelValues(rule.Channel, "error").Inc()
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 300 {
+		metrics.AlertNotifications.WithLabelValues(rule.Channel, "error").Inc()
+		return fmt.Errorf("%s returned %s", rule.Channel, resp.Status)
+	}
+	metrics.AlertNotifications.WithLabelValues(rule.Channel, "delivered").Inc()
+	return nil
+}
+
+// alertPayload builds the request body. Slack receives {"text": …}; webhooks
+// receive the rendered template as-is, or the notification as JSON when no
+// template is set.
+func alertPayload(rule *models.AlertRule, notification models.AlertNotification) ([]byte, error) {
+	var text string
+	if rule.Template != "" {
+		tmpl, err := template.New("alert").Parse(rule.Template)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, notification); err != nil {
+			return nil, err
+		}
+		if rule.Channel == models.AlertChannelWebhook {
+			return buf.Bytes(), nil
+		}
+		text = buf.String()
+	} else if rule.Channel == models.AlertChannelWebhook {
+		return json.Marshal(notification)
+	} else {
+		text = defaultAlertText(notification)
+	}
+	return json.Marshal(map[string]string{"text": text})
+}
+
+func defaultAlertText(n models.AlertNotification) string {
+	prefix := "[FIRING]"
+	if n.State == models.AlertResolved {
+		prefix = "[RESOLVED]"
+	}
+	if n.Test {
+		prefix = "[TEST] " + prefix
+	}
+	if n.Rule.Baseline != "" {
+		text := fmt.Sprintf("%s %s: %s changed %s%% against the %s window, %s threshold %s%% over %s (%s)",
+			prefix, n.Rule.Name, n.Metric,
+			strconv.FormatFloat(n.Value, 'f', 1, 64),
+			strings.ReplaceAll(n.Rule.Baseline, "_", " "),
+			n.Rule.Operator,
+			strconv.FormatFloat(n.Threshold, 'f', -1, 64),
+			n.Window, n.Scope)
+		if n.Current != nil && n.BaselineValue != nil {
+			text += fmt.Sprintf(": %s, was %s",
+				strconv.FormatFloat(*n.Current, 'f', -1, 64),
+				strconv.FormatFloat(*n.BaselineValue, 'f', -1, 64))
+		}
+		return text
+	}
+	return fmt.Sprintf("%s %s: %s is %s, %s threshold %s over %s (%s)",
+		prefix, n.Rule.Name, n.Metric,
+		strconv.FormatFloat(n.Value, 'f', -1, 64),
+		n.Rule.Operator,
+		strconv.FormatFloat(n.Threshold, 'f', -1, 64),
+		n.Window, n.Scope)
+}
+
+func alertScope(rule *models.AlertRule) string {
+	switch {
+	case rule.AdID != nil:
+		return fmt.Sprintf("ad %d", *rule.AdID)
+	case rule.CampaignID != nil:
+		return fmt.Sprintf("campaign %d", *rule.CampaignID)
+	case rule.AccountID != nil:
+		return fmt.Sprintf("account %d", *rule.AccountID)
+	}
+	return "all traffic"
+}
+
+// TestNotification builds a sample notification for a rule so users can
+// check their template and endpoint.
+func TestNotification(rule *models.AlertRule) models.AlertNotification {
+	return models.AlertNotification{
+		Rule:      *rule,
+		State:     models.AlertFiring,
+		Metric:    rule.Metric,
+		Value:     rule.LastValue,
+		Threshold: rule.Threshold,
+		Window:    rule.Window,
+		Scope:     alertScope(rule),
+		At:        time.Now().UTC(),
+		Test:      true,
+	}
+}
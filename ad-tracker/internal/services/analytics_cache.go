@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/cache"
+	"ad-tracking-system/internal/metrics"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Cache results reported by AnalyticsCache.Fetch.
+const (
+	CacheHit    = "hit"
+	CacheStale  = "stale"
+	CacheMiss   = "miss"
+	CacheBypass = "bypass"
+)
+
+// AnalyticsCache memoizes analytics aggregations. Results younger than ttl
+// are served as is; results within the following stale window are served
+// immediately while one background refresh recomputes them. Concurrent
+// misses for the same key share a single computation.
+type AnalyticsCache struct {
+	store  cache.Cache
+	ttl    time.Duration
+	stale  time.Duration
+	logger *logrus.Logger
+
+	mu       sync.Mutex
+	inflight map[string]*analyticsCall
+}
+
+type analyticsCall struct {
+	done  chan struct{}
+	value json.RawMessage
+	err   error
+}
+
+type analyticsEntry struct {
+	ComputedAt time.Time       `json:"computed_at"`
+	Value      json.RawMessage `json:"value"`
+}
+
+// NewAnalyticsCache caches for ttl and serves stale results for up to stale
+// after that. A ttl of zero disables caching.
+func NewAnalyticsCache(store cache.Cache, ttl, stale time.Duration, logger *logrus.Logger) *AnalyticsCache {
+	return &AnalyticsCache{
+		store:    store,
+		ttl:      ttl,
+		stale:    stale,
+		logger:   logger,
+		inflight: make(map[string]*analyticsCall),
+	}
+}
+
+// AnalyticsKey identifies a query by scope (e.g. "ad:7", "campaign:3",
+// "all"), window, granularity and whether invalid traffic is excluded.
+func AnalyticsKey(scope, window, granularity string, validOnly bool) string {
+	return fmt.Sprintf("analytics:%s:%s:%s:%t", scope, window, granularity, validOnly)
+}
+
+// Fetch decodes the cached result for key into dst, computing it with load
+// when missing or expired. It returns which of CacheHit, CacheStale,
+// CacheMiss or CacheBypass applied.
+func (a *AnalyticsCache) Fetch(ctx context.Context, key string, dst interface{}, load func() (interface{}, error)) (string, error) {
+	if a.ttl <= 0 {
+		return a.Bypass(dst, load)
+	}
+
+	var entry analyticsEntry
+	ok, err := cache.GetJSON(ctx, a.store, key, &entry)
+	if err != nil {
+		a.logger.WithError(err).WithField("key", key).Warn("Analytics cache read failed")
+	}
+	if ok {
+		age := time.Since(entry.ComputedAt)
+		if age < a.ttl {
+			metrics.AnalyticsCacheRequests.WithLabelValues(CacheHit).Inc()
+			return CacheHit, json.Unmarshal(entry.Value, dst)
+		}
+		if age < a.ttl+a.stale {
+			metrics.AnalyticsCacheRequests.WithLabelValues(CacheStale).Inc()
+			go a.refresh(key, load)
+			return CacheStale, json.Unmarshal(entry.Value, dst)
+		}
+	}
+
+	metrics.AnalyticsCacheRequests.WithLabelValues(CacheMiss).Inc()
+	call := a.compute(key, load)
+	<-call.done
+	if call.err != nil {
+		return CacheMiss, call.err
+	}
+	return CacheMiss, json.Unmarshal(call.value, dst)
+}
+
+// Bypass computes the result with load, neither reading nor writing the
+// cache, for callers asking for fresh figures.
+func (a *AnalyticsCache) Bypass(dst interface{}, load func() (interface{}, error)) (string, error) {
+	value, err := load()
+	if err != nil {
+		return CacheBypass, err
+	}
+	return CacheBypass, roundTrip(value, dst)
+}
+
+// compute runs load once per key at a time and stores the result.
+func (a *AnalyticsCache) compute(key string, load func() (interface{}, error)) *analyticsCall {
+	a.mu.Lock()
+	if call, ok := a.inflight[key]; ok {
+		a.mu.Unlock()
+		return call
+	}
+	call := &analyticsCall{done: make(chan struct{})}
+	a.inflight[key] = call
+	a.mu.Unlock()
+
+	defer func() {
+		a.mu.Lock()
+		delete(a.inflight, key)
+		a.mu.Unlock()
+		close(call.done)
+	}()
+
+	value, err := load()
+	if err != nil {
+		call.err = err
+		return call
+	}
+	call.value, call.err = json.Marshal(value)
+	if call.err != nil {
+		return call
+	}
+
+	entry := analyticsEntry{ComputedAt: time.Now(), Value: call.value}
+	// The request context may be gone by the time a refresh finishes
+	if err := cache.SetJSON(context.Background(), a.store, key, entry, a.ttl+a.stale); err != nil {
+		a.logger.WithError(err).WithField("key", key).Warn("Analytics cache write failed")
+	}
+	return call
+}
+
+func (a *AnalyticsCache) refresh(key string, load func() (interface{}, error)) {
+	a.mu.Lock()
+	_, running := a.inflight[key]
+	a.mu.Unlock()
+	if running {
+		return
+	}
+	if call := a.compute(key, load); call.err != nil {
+		a.logger.WithError(call.err).WithField("key", key).Warn("Analytics cache refresh failed")
+	}
+}
+
+func roundTrip(value, dst interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, dst)
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"ad-tracking-system/internal/anomaly"
+	"ad-tracking-system/internal/k8s"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AnomalyMonitor runs anomaly detectors over every campaign's hourly
+// clicks and impressions from the rollups, and records what they find in
+// the most recent hours. Each run reads lookback worth of history up to the
+// last hour the rollups cover; anomalies in the last recent hours are
+// saved, so runs may overlap without recording an hour twice. Only the
+// elected replica runs.
+type AnomalyMonitor struct {
+	rollups   *repositories.RollupRepository
+	repo      *repositories.AnomalyRepository
+	detectors []anomaly.Detector
+	lookback  time.Duration
+	recent    time.Duration
+	elector   k8s.Elector
+	logger    *logrus.Logger
+}
+
+func NewAnomalyMonitor(rollups *repositories.RollupRepository, repo *repositories.AnomalyRepository, detectors []anomaly.Detector, lookback, recent time.Duration, logger *logrus.Logger) *AnomalyMonitor {
+	return &AnomalyMonitor{
+		rollups:   rollups,
+		repo:      repo,
+		detectors: detectors,
+		lookback:  lookback,
+		recent:    recent,
+		elector:   k8s.AlwaysLeader{},
+		logger:    logger,
+	}
+}
+
+func (m *AnomalyMonitor) SetElector(elector k8s.Elector) {
+	m.elector = elector
+}
+
+// Run scans every interval until ctx is cancelled.
+func (m *AnomalyMonitor) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := m.RunOnce(ctx); err != nil {
+			m.logger.WithError(err).Error("Anomaly detection failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce scans the hours up to the rollup coverage.
+func (m *AnomalyMonitor) RunOnce(ctx context.Context) error {
+	if !m.elector.IsLeader() {
+		return nil
+	}
+	to, err := m.rollups.Coverage()
+	if err != nil || to.IsZero() {
+		return err
+	}
+	to = to.UTC().Truncate(time.Hour)
+	from := to.Add(-m.lookback).Truncate(time.Hour)
+
+	hours, err := m.rollups.CampaignHours(from, to)
+	if err != nil {
+		return err
+	}
+
+	var found []models.Anomaly
+	reportFrom := to.Add(-m.recent)
+	for campaignID, series := range campaignSeries(hours, from, to) {
+		for metric, points := range series {
+			for _, detector := range m.detectors {
+				for _, finding := range detector.Detect(points) {
+					if finding.At.Before(reportFrom) {
+						continue
+					}
+					direction := models.AnomalySpike
+					if finding.Score < 0 {
+						direction = models.AnomalyDrop
+					}
+					found = append(found, models.Anomaly{
+						CampaignID:  campaignID,
+						Metric:      metric,
+						Detector:    detector.Name(),
+						BucketStart: finding.At,
+						Direction:   direction,
+						Value:       finding.Value,
+						Expected:    finding.Expected,
+						Score:       finding.Score,
+					})
+				}
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	saved, err := m.repo.Save(found)
+	if saved > 0 {
+		m.logger.WithField("anomalies", saved).Info("Anomalies detected")
+	}
+	return err
+}
+
+// campaignSeries lays out each campaign's hours in [from, to) as clicks and
+// impressions series, with zeros for hours without events. Campaigns only
+// appear once they have events in range.
+func campaignSeries(hours []repositories.CampaignHour, from, to time.Time) map[uint]map[string][]anomaly.Point {
+	length := int(to.Sub(from) / time.Hour)
+	series := make(map[uint]map[string][]anomaly.Point)
+	for _, hour := range hours {
+		campaign, ok := series[hour.CampaignID]
+		if !ok {
+			campaign = map[string][]anomaly.Point{
+				models.AlertMetricClicks:      make([]anomaly.Point, length),
+				models.AlertMetricImpressions: make([]anomaly.Point, length),
+			}
+			for _, points := range campaign {
+				for i := range points {
+					points[i].At = from.Add(time.Duration(i) * time.Hour)
+				}
+			}
+			series[hour.CampaignID] = campaign
+		}
+		i := int(hour.BucketStart.UTC().Sub(from) / time.Hour)
+		if i < 0 || i >= length {
+			continue
+		}
+		campaign[models.AlertMetricClicks][i].Value = float64(hour.Clicks)
+		campaign[models.AlertMetricImpressions][i].Value = float64(hour.Impressions)
+	}
+	return series
+}
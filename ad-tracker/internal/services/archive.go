@@ -0,0 +1,422 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const archiveBatchSize = 5000
+
+// ArchivedTables are the event tables the archiver can export and restore.
+var ArchivedTables = []string{"click_events", "impression_events"}
+
+// Archiver exports whole days of raw events to Parquet in object storage,
+// with a JSON manifest per day, and restores them on demand.
+type Archiver struct {
+	repo   *repositories.ArchiveRepository
+	store  archive.ObjectStore
+	logger *logrus.Logger
+}
+
+func NewArchiver(repo *repositories.ArchiveRepository, store archive.ObjectStore, logger *logrus.Logger) *Archiver {
+	return &Archiver{repo: repo, store: store, logger: logger}
+}
+
+func isArchivedTable(table string) bool {
+	for _, name := range ArchivedTables {
+		if name == table {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchiveThrough archives every complete UTC day of table that ends at or
+// before cutoff and returns the time up to which data is safely archived.
+// Callers must not purge rows at or after the returned time.
+func (a *Archiver) ArchiveThrough(ctx context.Context, table string, cutoff time.Time) (time.Time, error) {
+	if !isArchivedTable(table) {
+		return time.Time{}, fmt.Errorf("table %q is not archivable", table)
+	}
+
+	oldest, err := a.repo.OldestEvent(table)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if oldest == nil {
+		return cutoff, nil
+	}
+
+	day := startOfDay(*oldest)
+	for !day.AddDate(0, 0, 1).After(cutoff) {
+		if err := ctx.Err(); err != nil {
+			return day, err
+		}
+
+		existing, err := a.repo.Partition(table, day)
+		if err != nil {
+			return day, err
+		}
+		if existing == nil {
+			if err := a.archiveDay(ctx, table, day); err != nil {
+				return day, err
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
+
+func (a *Archiver) archiveDay(ctx context.Context, table string, day time.Time) error {
+	start := time.Now()
+	next := day.AddDate(0, 0, 1)
+
+	file, err := os.CreateTemp("", "archive-*.parquet")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	var rows int64
+	var minID, maxID uint
+	track := func(id uint) {
+		if minID == 0 || id < minID {
+			minID = id
+		}
+		if id > maxID {
+			maxID = id
+		}
+	}
+
+	switch table {
+	case "click_events":
+		writer := archive.NewWriter[archive.ClickRow](file)
+		err = a.repo.StreamClicks(day, next, archiveBatchSize, func(clicks []models.ClickEvent) error {
+			batch := make([]archive.ClickRow, len(clicks))
+			for i, click := range clicks {
+				batch[i] = archive.NewClickRow(click)
+				track(click.ID)
+			}
+			return writer.Write(batch)
+		})
+		if err == nil {
+			err = writer.Close()
+		}
+		rows = writer.Rows()
+	case "impression_events":
+		writer := archive.NewWriter[archive.ImpressionRow](file)
+		err = a.repo.StreamImpressions(day, next, archiveBatchSize, func(impressions []models.ImpressionEvent) error {
+			batch := make([]archive.ImpressionRow, len(impressions))
+			for i, impression := range impressions {
+				batch[i] = archive.NewImpressionRow(impression)
+				track(impression.ID)
+			}
+			return writer.Write(batch)
+		})
+		if err == nil {
+			err = writer.Close()
+		}
+		rows = writer.Rows()
+	}
+	if err != nil {
+		return fmt.Errorf("write %s %s: %w", table, day.Format("2006-01-02"), err)
+	}
+
+	partition := models.ArchivePartition{
+		TableName:  table,
+		Day:        day,
+		Rows:       rows,
+		MinID:      minID,
+		MaxID:      maxID,
+		ArchivedAt: time.Now().UTC(),
+	}
+
+	// Empty days are recorded so the watermark can move past them
+	if rows > 0 {
+		if err := a.upload(ctx, file, &partition); err != nil {
+			return err
+		}
+	}
+	if err := a.repo.RecordPartition(&partition); err != nil {
+		return err
+	}
+
+	metrics.ArchivedRows.WithLabelValues(table).Add(float64(rows))
+	a.logger.WithFields(logrus.Fields{
+		"table":    table,
+		"day":      day.Format("2006-01-02"),
+		"rows":     rows,
+		"bytes":    partition.Bytes,
+		"object":   partition.ObjectKey,
+		"duration": time.Since(start),
+	}).Info("Archived event partition")
+	return nil
+}
+
+func (a *Archiver) upload(ctx context.Context, file *os.File, partition *models.ArchivePartition) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	prefix := fmt.Sprintf("%s/dt=%s/", partition.TableName, partition.Day.Format("2006-01-02"))
+	partition.ObjectKey = prefix + "events.parquet"
+	partition.ManifestKey = prefix + "manifest.json"
+	partition.Bytes = size
+	partition.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	if err := a.store.Put(ctx, partition.ObjectKey, file, "application/vnd.apache.parquet"); err != nil {
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(models.ArchiveManifest{
+		Table:     partition.TableName,
+		Day:       partition.Day.Format("2006-01-02"),
+		From:      partition.Day,
+		To:        partition.Day.AddDate(0, 0, 1),
+		Object:    a.store.Location(partition.ObjectKey),
+		Format:    "parquet",
+		Rows:      partition.Rows,
+		Bytes:     partition.Bytes,
+		SHA256:    partition.SHA256,
+		MinID:     partition.MinID,
+		MaxID:     partition.MaxID,
+		CreatedAt: partition.ArchivedAt,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return a.store.Put(ctx, partition.ManifestKey, bytes.NewReader(manifest), "application/json")
+}
+
+// Restore loads archived days of table in [from, to) back into Postgres and
+// returns the number of rows inserted. Rows that still exist are skipped.
+func (a *Archiver) Restore(ctx context.Context, table string, from, to time.Time) (int64, error) {
+	if !isArchivedTable(table) {
+		return 0, fmt.Errorf("table %q is not archivable", table)
+	}
+
+	partitions, err := a.repo.Partitions(table, startOfDay(from), to)
+	if err != nil {
+		return 0, err
+	}
+
+	var restored int64
+	for _, partition := range partitions {
+		if partition.Rows == 0 {
+			continue
+		}
+		n, err := a.restorePartition(ctx, partition)
+		restored += n
+		if err != nil {
+			return restored, fmt.Errorf("restore %s %s: %w", table, partition.Day.Format("2006-01-02"), err)
+		}
+		a.logger.WithFields(logrus.Fields{
+			"table": table,
+			"day":   partition.Day.Format("2006-01-02"),
+			"rows":  n,
+		}).Info("Restored archived partition")
+	}
+	return restored, nil
+}
+
+// fetch stages a partition's object in a temporary file, since Parquet
+// needs random access, and verifies it against the recorded checksum. The
+// caller removes the file.
+func (a *Archiver) fetch(ctx context.Context, partition models.ArchivePartition) (*os.File, error) {
+	body, err := a.store.Get(ctx, partition.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	file, err := os.CreateTemp("", "restore-*.parquet")
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(file, hash), body); err == nil && hex.EncodeToString(hash.Sum(nil)) != partition.SHA256 {
+		err = errors.New("checksum mismatch: archive object is corrupt or was replaced")
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+func (a *Archiver) restorePartition(ctx context.Context, partition models.ArchivePartition) (int64, error) {
+	file, err := a.fetch(ctx, partition)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	var restored int64
+	switch partition.TableName {
+	case "click_events":
+		err = archive.ReadAll(file, archiveBatchSize, func(rows []archive.ClickRow) error {
+			clicks := make([]models.ClickEvent, len(rows))
+			for i, row := range rows {
+				clicks[i] = row.Event()
+			}
+			n, err := a.repo.RestoreClicks(clicks)
+			restored += n
+			return err
+		})
+	case "impression_events":
+		err = archive.ReadAll(file, archiveBatchSize, func(rows []archive.ImpressionRow) error {
+			impressions := make([]models.ImpressionEvent, len(rows))
+			for i, row := range rows {
+				impressions[i] = row.Event()
+			}
+			n, err := a.repo.RestoreImpressions(impressions)
+			restored += n
+			return err
+		})
+	}
+	return restored, err
+}
+
+// EraseSubject removes the subject's rows from every archived partition, or
+// blanks their identity columns when mode is anonymize, and returns the rows
+// affected per table. Partitions holding none of its rows are left as they
+// are; the others are rewritten in place with a fresh manifest, so restores
+// cannot bring erased rows back.
+func (a *Archiver) EraseSubject(ctx context.Context, subjectType, subject, mode string) (map[string]int64, error) {
+	matches := func(userID, ipAddress string) bool {
+		if subjectType == models.SubjectIPHash {
+			sum := sha256.Sum256([]byte(ipAddress))
+			return ipAddress != "" && hex.EncodeToString(sum[:]) == strings.ToLower(subject)
+		}
+		return userID != "" && userID == subject
+	}
+	anonymize := mode == models.PrivacyAnonymize
+
+	affected := make(map[string]int64, len(ArchivedTables))
+	for _, table := range ArchivedTables {
+		partitions, err := a.repo.NonEmptyPartitions(table)
+		if err != nil {
+			return affected, err
+		}
+		for _, partition := range partitions {
+			if err := ctx.Err(); err != nil {
+				return affected, err
+			}
+			var n int64
+			switch table {
+			case "click_events":
+				n, err = rewritePartition(ctx, a, partition, func(row *archive.ClickRow) (bool, bool) {
+					if !matches(row.UserID, row.IPAddress) {
+						return true, false
+					}
+					row.UserID, row.IPAddress, row.UserAgent = "", "", ""
+					return anonymize, true
+				}, func(row archive.ClickRow) uint { return uint(row.ID) })
+			case "impression_events":
+				n, err = rewritePartition(ctx, a, partition, func(row *archive.ImpressionRow) (bool, bool) {
+					if !matches(row.UserID, row.IPAddress) {
+						return true, false
+					}
+					row.UserID, row.IPAddress, row.UserAgent = "", "", ""
+					return anonymize, true
+				}, func(row archive.ImpressionRow) uint { return uint(row.ID) })
+			}
+			affected[table] += n
+			if err != nil {
+				return affected, fmt.Errorf("erase archived %s %s: %w", table, partition.Day.Format("2006-01-02"), err)
+			}
+		}
+	}
+	return affected, nil
+}
+
+// rewritePartition passes every row of the partition through edit, which
+// reports whether to keep the row and whether it changed, and uploads the
+// result when anything changed. It returns the number of rows changed.
+func rewritePartition[T any](ctx context.Context, a *Archiver, partition models.ArchivePartition, edit func(*T) (keep, changed bool), id func(T) uint) (int64, error) {
+	file, err := a.fetch(ctx, partition)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	out, err := os.CreateTemp("", "archive-*.parquet")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	var changed int64
+	partition.MinID, partition.MaxID = 0, 0
+	writer := archive.NewWriter[T](out)
+	err = archive.ReadAll(file, archiveBatchSize, func(rows []T) error {
+		kept := rows[:0]
+		for i := range rows {
+			keep, edited := edit(&rows[i])
+			if edited {
+				changed++
+			}
+			if !keep {
+				continue
+			}
+			if rowID := id(rows[i]); partition.MinID == 0 || rowID < partition.MinID {
+				partition.MinID = rowID
+			}
+			partition.MaxID = max(partition.MaxID, id(rows[i]))
+			kept = append(kept, rows[i])
+		}
+		return writer.Write(kept)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil || changed == 0 {
+		return 0, err
+	}
+
+	partition.Rows = writer.Rows()
+	partition.ArchivedAt = time.Now().UTC()
+	if err := a.upload(ctx, out, &partition); err != nil {
+		return 0, err
+	}
+	return changed, a.repo.SavePartition(&partition)
+}
+
+// Partitions lists what has been archived for table in [from, to).
+func (a *Archiver) Partitions(table string, from, to time.Time) ([]models.ArchivePartition, error) {
+	return a.repo.Partitions(table, startOfDay(from), to)
+}
+
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
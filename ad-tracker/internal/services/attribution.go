@@ -0,0 +1,347 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+)
+
+// Attributor assigns conversions to the most recent qualifying touch. Clicks
+// inside the click window win over impressions inside the view window.
+// With an identity graph, touches by user ids linked to the converting one
+// count as the same user's.
+type Attributor struct {
+	repo       *repositories.ConversionRepository
+	windows    models.AttributionWindows
+	identities *IdentityGraph
+}
+
+func NewAttributor(repo *repositories.ConversionRepository, windows models.AttributionWindows) *Attributor {
+	return &Attributor{repo: repo, windows: windows}
+}
+
+func (a *Attributor) Windows() models.AttributionWindows {
+	return a.windows
+}
+
+// SetIdentities resolves users through the graph; nil matches user ids
+// exactly.
+func (a *Attributor) SetIdentities(graph *IdentityGraph) {
+	a.identities = graph
+}
+
+// Attribute fills the attribution fields on conversion in place. The hints
+// sent with the conversion find users on other devices even without a user
+// id.
+func (a *Attributor) Attribute(conversion *models.Conversion, hints models.IdentityHints) error {
+	at := conversion.Timestamp
+	users, err := a.identities.Resolve(conversion.UserID, hints)
+	if err != nil {
+		return err
+	}
+
+	click, err := a.repo.LastClick(users, conversion.IPAddress, at.Add(-a.windows.Click), at)
+	if err != nil {
+		return err
+	}
+	if click != nil {
+		conversion.AttributedAdID = &click.AdID
+		conversion.AttributedEventID = &click.ID
+		conversion.AttributedAt = &click.Timestamp
+		conversion.AttributionType = models.AttributionClickThrough
+		return nil
+	}
+
+	impression, err := a.repo.LastImpression(users, conversion.IPAddress, at.Add(-a.windows.View), at)
+	if err != nil {
+		return err
+	}
+	if impression != nil {
+		conversion.AttributedAdID = &impression.AdID
+		conversion.AttributedEventID = &impression.ID
+		conversion.AttributedAt = &impression.Timestamp
+		conversion.AttributionType = models.AttributionViewThrough
+	}
+	return nil
+}
+
+// Report recomputes credit for every conversion since the given time under
+// the requested model, using each user's clicks inside the click window.
+func (a *Attributor) Report(model string, since time.Time) (models.AttributionReport, error) {
+	report := models.AttributionReport{
+		Model:  model,
+		Since:  since,
+		Window: a.windows.Click.String(),
+		Ads:    []models.AdAttribution{},
+	}
+	switch model {
+	case models.AttributionLastClick, models.AttributionFirstClick, models.AttributionLinear:
+	default:
+		return report, fmt.Errorf("unknown attribution model %q", model)
+	}
+
+	conversions, err := a.repo.ListSince(since)
+	if err != nil {
+		return report, err
+	}
+	report.Conversions = int64(len(conversions))
+	if len(conversions) == 0 {
+		return report, nil
+	}
+
+	var userIDs, ips []string
+	for _, conversion := range conversions {
+		if conversion.UserID != "" {
+			userIDs = append(userIDs, conversion.UserID)
+		} else {
+			ips = append(ips, conversion.IPAddress)
+		}
+	}
+
+	linked, err := a.identities.Related(userIDs)
+	if err != nil {
+		return report, err
+	}
+	clicks, err := a.repo.ClicksForIdentities(withLinked(userIDs, linked), ips, since.Add(-a.windows.Click), time.Now())
+	if err != nil {
+		return report, err
+	}
+
+	index := newClickIndex(clicks)
+	credit := make(map[uint]*models.AdAttribution)
+	for _, conversion := range conversions {
+		touches := index.touchesFor(conversion, a.windows.Click, linked[conversion.UserID])
+		if len(touches) == 0 {
+			continue
+		}
+		report.Attributed++
+
+		for adID, share := range splitCredit(model, touches) {
+			entry, ok := credit[adID]
+			if !ok {
+				entry = &models.AdAttribution{AdID: adID}
+				credit[adID] = entry
+			}
+			entry.Conversions += share
+			entry.Value += share * conversion.Value
+		}
+	}
+	report.Unattributed = report.Conversions - report.Attributed
+
+	for _, entry := range credit {
+		report.Ads = append(report.Ads, *entry)
+	}
+	sort.Slice(report.Ads, func(i, j int) bool { return report.Ads[i].AdID < report.Ads[j].AdID })
+	return report, nil
+}
+
+// withLinked adds the users linked to any of userIDs.
+func withLinked(userIDs []string, linked map[string][]string) []string {
+	for _, ids := range linked {
+		userIDs = append(userIDs, ids...)
+	}
+	return userIDs
+}
+
+// clickIndex groups a report's clicks by user id and by IP address, each
+// group oldest first, so every conversion only looks at its own clicks.
+type clickIndex struct {
+	byUser map[string][]models.ClickEvent
+	byIP   map[string][]models.ClickEvent
+}
+
+// newClickIndex indexes clicks, which must be sorted by time.
+func newClickIndex(clicks []models.ClickEvent) clickIndex {
+	index := clickIndex{
+		byUser: make(map[string][]models.ClickEvent),
+		byIP:   make(map[string][]models.ClickEvent),
+	}
+	for _, click := range clicks {
+		if click.UserID != "" {
+			index.byUser[click.UserID] = append(index.byUser[click.UserID], click)
+		}
+		index.byIP[click.IPAddress] = append(index.byIP[click.IPAddress], click)
+	}
+	return index
+}
+
+// touchesFor picks the clicks belonging to the converting user, or to the
+// users linked to it, that happened inside the window before the
+// conversion, oldest first. Conversions without a user id match clicks by
+// IP address.
+func (index clickIndex) touchesFor(conversion models.Conversion, window time.Duration, linked []string) []models.ClickEvent {
+	from, to := conversion.Timestamp.Add(-window), conversion.Timestamp
+	if conversion.UserID == "" {
+		return clicksBetween(index.byIP[conversion.IPAddress], from, to)
+	}
+
+	touches := clicksBetween(index.byUser[conversion.UserID], from, to)
+	if len(linked) == 0 {
+		return touches
+	}
+	seen := map[string]bool{conversion.UserID: true}
+	for _, userID := range linked {
+		if !seen[userID] {
+			seen[userID] = true
+			touches = append(touches, clicksBetween(index.byUser[userID], from, to)...)
+		}
+	}
+	sort.SliceStable(touches, func(i, j int) bool { return touches[i].Timestamp.Before(touches[j].Timestamp) })
+	return touches
+}
+
+// clicksBetween returns the clicks in [from, to] of clicks sorted by time.
+// The result shares no spare capacity with clicks, so appending is safe.
+func clicksBetween(clicks []models.ClickEvent, from, to time.Time) []models.ClickEvent {
+	start := sort.Search(len(clicks), func(i int) bool { return !clicks[i].Timestamp.Before(from) })
+	end := sort.Search(len(clicks), func(i int) bool { return clicks[i].Timestamp.After(to) })
+	if start >= end {
+		return nil
+	}
+	return clicks[start:end:end]
+}
+
+// splitCredit distributes one conversion across the touched ads.
+func splitCredit(model string, touches []models.ClickEvent) map[uint]float64 {
+	shares := make(map[uint]float64)
+	for i, share := range touchShares(model, touches) {
+		if share > 0 {
+			shares[touches[i].AdID] += share
+		}
+	}
+	return shares
+}
+
+// touchShares is the credit each touch earns under the model, summing to 1.
+func touchShares(model string, touches []models.ClickEvent) []float64 {
+	shares := make([]float64, len(touches))
+	switch model {
+	case models.AttributionFirstClick:
+		shares[0] = 1
+	case models.AttributionLinear:
+		for i := range shares {
+			shares[i] = 1 / float64(len(touches))
+		}
+	default:
+		shares[len(shares)-1] = 1
+	}
+	return shares
+}
+
+// attributionKey groups campaign credit for payout reconciliation.
+type attributionKey struct {
+	adID        uint
+	creativeID  uint
+	placementID uint
+	currency    string
+}
+
+// CampaignReport credits conversions in [from, until) to the campaign's ads
+// under the model, using each user's clicks inside window before the
+// conversion, like Report. Every touch on the path counts toward the split,
+// so a conversion last clicked on another campaign earns this one nothing
+// under last_click. Credit is broken down by ad, creative, placement and
+// currency; a zero window uses the configured click window.
+func (a *Attributor) CampaignReport(campaignID uint, adIDs []uint, model string, from, until time.Time, window time.Duration) (models.CampaignAttributionReport, error) {
+	if window <= 0 {
+		window = a.windows.Click
+	}
+	report := models.CampaignAttributionReport{
+		CampaignID: campaignID,
+		Model:      model,
+		Since:      from,
+		Until:      until,
+		Window:     window.String(),
+		Rows:       []models.AttributionRow{},
+	}
+	switch model {
+	case models.AttributionLastClick, models.AttributionFirstClick, models.AttributionLinear:
+	default:
+		return report, fmt.Errorf("unknown attribution model %q", model)
+	}
+
+	conversions, err := a.repo.ListBetween(from, until)
+	if err != nil || len(conversions) == 0 {
+		return report, err
+	}
+	var userIDs, ips []string
+	for _, conversion := range conversions {
+		if conversion.UserID != "" {
+			userIDs = append(userIDs, conversion.UserID)
+		} else {
+			ips = append(ips, conversion.IPAddress)
+		}
+	}
+	linked, err := a.identities.Related(userIDs)
+	if err != nil {
+		return report, err
+	}
+	clicks, err := a.repo.ClicksForIdentities(withLinked(userIDs, linked), ips, from.Add(-window), until)
+	if err != nil {
+		return report, err
+	}
+
+	campaignAds := make(map[uint]bool, len(adIDs))
+	for _, adID := range adIDs {
+		campaignAds[adID] = true
+	}
+	index := newClickIndex(clicks)
+	credit := make(map[attributionKey]*models.AttributionRow)
+	for _, conversion := range conversions {
+		touches := index.touchesFor(conversion, window, linked[conversion.UserID])
+		credited := false
+		for i, share := range touchShares(model, touches) {
+			touch := touches[i]
+			if share == 0 || !campaignAds[touch.AdID] {
+				continue
+			}
+			key := attributionKey{adID: touch.AdID, currency: conversion.Currency}
+			if touch.CreativeID != nil {
+				key.creativeID = *touch.CreativeID
+			}
+			if touch.PlacementID != nil {
+				key.placementID = *touch.PlacementID
+			}
+			row, ok := credit[key]
+			if !ok {
+				row = &models.AttributionRow{AdID: touch.AdID, CreativeID: touch.CreativeID, PlacementID: touch.PlacementID, Currency: conversion.Currency}
+				credit[key] = row
+			}
+			row.Conversions += share
+			row.Value += share * conversion.Value
+			report.Credit += share
+			credited = true
+		}
+		if credited {
+			report.Conversions++
+		}
+	}
+
+	for _, row := range credit {
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if a.AdID != b.AdID {
+			return a.AdID < b.AdID
+		}
+		if ac, bc := optionalID(a.CreativeID), optionalID(b.CreativeID); ac != bc {
+			return ac < bc
+		}
+		if ap, bp := optionalID(a.PlacementID), optionalID(b.PlacementID); ap != bp {
+			return ap < bp
+		}
+		return a.Currency < b.Currency
+	})
+	return report, nil
+}
+
+func optionalID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
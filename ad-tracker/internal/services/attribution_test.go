@@ -0,0 +1,96 @@
+package services
+
+import (
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/models"
+)
+
+// scanTouches is the plain scan over every click that the index replaces.
+func scanTouches(conversion models.Conversion, clicks []models.ClickEvent, window time.Duration, linked []string) []models.ClickEvent {
+	from := conversion.Timestamp.Add(-window)
+	var touches []models.ClickEvent
+	for _, click := range clicks {
+		if click.Timestamp.Before(from) || click.Timestamp.After(conversion.Timestamp) {
+			continue
+		}
+		if conversion.UserID != "" && click.UserID != conversion.UserID && !slices.Contains(linked, click.UserID) {
+			continue
+		}
+		if conversion.UserID == "" && click.IPAddress != conversion.IPAddress {
+			continue
+		}
+		touches = append(touches, click)
+	}
+	return touches
+}
+
+func clickIDs(clicks []models.ClickEvent) []uint {
+	ids := make([]uint, len(clicks))
+	for i, click := range clicks {
+		ids[i] = click.ID
+	}
+	return ids
+}
+
+func TestClickIndexMatchesScan(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	user := func() string {
+		if r.Intn(4) == 0 {
+			return ""
+		}
+		return "user-" + strconv.Itoa(r.Intn(20))
+	}
+	linkedUser := func() string { return "user-" + strconv.Itoa(r.Intn(20)) }
+	ip := func() string { return "203.0.113." + strconv.Itoa(r.Intn(10)) }
+
+	// Seconds apart, so the scan's order is the only valid one
+	clicks := make([]models.ClickEvent, 500)
+	for i := range clicks {
+		clicks[i] = models.ClickEvent{ID: uint(i + 1), AdID: uint(r.Intn(5) + 1), UserID: user(), IPAddress: ip(), Timestamp: start.Add(time.Duration(i) * time.Second)}
+	}
+	index := newClickIndex(clicks)
+	window := 2 * time.Minute
+
+	for i := 0; i < 200; i++ {
+		conversion := models.Conversion{UserID: user(), IPAddress: ip(), Timestamp: start.Add(time.Duration(r.Intn(600)) * time.Second)}
+		var linked []string
+		if conversion.UserID != "" && r.Intn(2) == 0 {
+			linked = []string{linkedUser(), linkedUser(), conversion.UserID}
+		}
+
+		got := clickIDs(index.touchesFor(conversion, window, linked))
+		want := clickIDs(scanTouches(conversion, clicks, window, linked))
+		if !slices.Equal(got, want) {
+			t.Fatalf("conversion %+v linked %v: touches %v, want %v", conversion, linked, got, want)
+		}
+	}
+}
+
+func TestClickIndexTouchesAreIndependent(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	clicks := []models.ClickEvent{
+		{ID: 1, UserID: "a", Timestamp: start},
+		{ID: 2, UserID: "a", Timestamp: start.Add(time.Minute)},
+		{ID: 3, UserID: "b", Timestamp: start.Add(30 * time.Second)},
+	}
+	index := newClickIndex(clicks)
+
+	first := models.Conversion{UserID: "a", Timestamp: start.Add(10 * time.Second)}
+	if got := clickIDs(index.touchesFor(first, time.Hour, []string{"b"})); !slices.Equal(got, []uint{1}) {
+		t.Fatalf("touches %v, want [1]", got)
+	}
+	// Appending linked users' clicks must not overwrite the index
+	later := models.Conversion{UserID: "a", Timestamp: start.Add(2 * time.Minute)}
+	if got := clickIDs(index.touchesFor(later, time.Hour, []string{"b"})); !slices.Equal(got, []uint{1, 3, 2}) {
+		t.Fatalf("touches %v, want [1 3 2]", got)
+	}
+	if got := clickIDs(index.byUser["a"]); !slices.Equal(got, []uint{1, 2}) {
+		t.Fatalf("index for a is %v after lookups, want [1 2]", got)
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/iptrie"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Blocklist keeps the IP blocklists in memory as radix trees and refreshes
+// them from the database periodically.
+type Blocklist struct {
+	repo   *repositories.BlocklistRepository
+	logger *logrus.Logger
+
+	mu         sync.RWMutex
+	global     *iptrie.Trie
+	accounts   map[uint]*iptrie.Trie
+	adAccounts map[uint]uint
+}
+
+func NewBlocklist(repo *repositories.BlocklistRepository, logger *logrus.Logger) *Blocklist {
+	return &Blocklist{
+		repo:       repo,
+		logger:     logger,
+		global:     iptrie.New(),
+		accounts:   make(map[uint]*iptrie.Trie),
+		adAccounts: make(map[uint]uint),
+	}
+}
+
+// ParseRange accepts a CIDR or a bare address and returns the normalized
+// network. IPv4-mapped IPv6 ranges are returned as the IPv4 range they map.
+func ParseRange(value string) (*net.IPNet, error) {
+	value = strings.TrimSpace(value)
+	if !strings.Contains(value, "/") {
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", value)
+		}
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+
+	_, network, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q", value)
+	}
+	if v4 := network.IP.To4(); v4 != nil && len(network.IP) == net.IPv6len {
+		ones, _ := network.Mask.Size()
+		return &net.IPNet{IP: v4, Mask: net.CIDRMask(ones-96, 32)}, nil
+	}
+	return network, nil
+}
+
+// Refresh rebuilds the trees from the database and swaps them in.
+func (b *Blocklist) Refresh() error {
+	ranges, err := b.repo.Active()
+	if err != nil {
+		return err
+	}
+	adAccounts, err := b.repo.AdAccounts()
+	if err != nil {
+		return err
+	}
+
+	global := iptrie.New()
+	accounts := make(map[uint]*iptrie.Trie)
+	for _, blocked := range ranges {
+		network, err := ParseRange(blocked.CIDR)
+		if err != nil {
+			b.logger.WithError(err).WithField("blocked_range_id", blocked.ID).Warn("Skipping invalid blocklist entry")
+			continue
+		}
+		if blocked.AccountID == nil {
+			global.Insert(network)
+			continue
+		}
+		trie, ok := accounts[*blocked.AccountID]
+		if !ok {
+			trie = iptrie.New()
+			accounts[*blocked.AccountID] = trie
+		}
+		trie.Insert(network)
+	}
+
+	b.mu.Lock()
+	b.global = global
+	b.accounts = accounts
+	b.adAccounts = adAccounts
+	b.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the blocklist every interval until ctx is cancelled.
+func (b *Blocklist) Run(ctx context.Context, interval time.Duration) {
+	if err := b.Refresh(); err != nil {
+		b.logger.WithError(err).Error("Failed to load IP blocklist")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.Refresh(); err != nil {
+				b.logger.WithError(err).Error("Failed to refresh IP blocklist")
+			}
+		}
+	}
+}
+
+// Blocked reports whether the address is blocked globally or by the account
+// owning the ad.
+func (b *Blocklist) Blocked(ipAddress string, adID uint) bool {
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if b.global.Contains(ip) {
+		return true
+	}
+	if accountID, ok := b.adAccounts[adID]; ok {
+		if trie, ok := b.accounts[accountID]; ok {
+			return trie.Contains(ip)
+		}
+	}
+	return false
+}
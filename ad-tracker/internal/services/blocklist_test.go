@@ -0,0 +1,32 @@
+package services
+
+import "testing"
+
+func TestParseRange(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.0/8":          "10.0.0.0/8",
+		" 10.1.2.3/8 ":        "10.0.0.0/8",
+		"203.0.113.7":         "203.0.113.7/32",
+		"2001:db8::/32":       "2001:db8::/32",
+		"2001:db8::1":         "2001:db8::1/128",
+		"::ffff:10.0.0.0/104": "10.0.0.0/8",
+		"::ffff:10.1.2.3":     "10.1.2.3/32",
+		"::ffff:0.0.0.0/96":   "0.0.0.0/0",
+	}
+	for value, want := range tests {
+		network, err := ParseRange(value)
+		if err != nil {
+			t.Errorf("ParseRange(%q): %v", value, err)
+			continue
+		}
+		if network.String() != want {
+			t.Errorf("ParseRange(%q) = %s, want %s", value, network, want)
+		}
+	}
+
+	for _, value := range []string{"", "10.0.0.0/33", "not an ip", "10.0.0.256"} {
+		if _, err := ParseRange(value); err == nil {
+			t.Errorf("ParseRange(%q) succeeded, want an error", value)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/cache"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	spendDayLayout = "2006-01-02"
+
+	dailySpendTTL    = 48 * time.Hour
+	lifetimeSpendTTL = 7 * 24 * time.Hour
+)
+
+// spendKey groups unflushed spend by campaign and UTC day.
+type spendKey struct {
+	campaignID uint
+	day        string
+}
+
+// BudgetTracker prices valid clicks and impressions at their campaign's
+// rates as they arrive and pauses serving once a daily or lifetime budget
+// is exhausted. Running totals live in a Counter, Redis when configured so
+// every replica checks against the same spend; the database is updated in
+// batches and seeds the counters when they are missing.
+type BudgetTracker struct {
+	repo       *repositories.CampaignRepository
+	audit      *repositories.AuditRepository
+	invalidate func(ctx context.Context, ids ...uint)
+	logger     *logrus.Logger
+
+	mu          sync.Mutex
+	counter     cache.Counter
+	campaigns   map[uint]models.Campaign
+	pending     map[spendKey]float64
+	pacingBurst time.Duration
+}
+
+// NewBudgetTracker calls invalidate after pausing or resuming campaigns so
+// the served ad list is rebuilt.
+func NewBudgetTracker(repo *repositories.CampaignRepository, audit *repositories.AuditRepository, counter cache.Counter, invalidate func(ctx context.Context, ids ...uint), logger *logrus.Logger) *BudgetTracker {
+	return &BudgetTracker{
+		repo:        repo,
+		audit:       audit,
+		invalidate:  invalidate,
+		logger:      logger,
+		counter:     counter,
+		campaigns:   make(map[uint]models.Campaign),
+		pending:     make(map[spendKey]float64),
+		pacingBurst: defaultPacingBurst,
+	}
+}
+
+// SetCounter moves the running totals and pacing draws, typically to
+// Redis.
+func (t *BudgetTracker) SetCounter(counter cache.Counter) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counter = counter
+}
+
+// Track updates one campaign after it is created or its budget changes.
+func (t *BudgetTracker) Track(campaign models.Campaign) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.campaigns[campaign.ID] = campaign
+}
+
+// Refresh reloads the campaigns, including spend flushed by other
+// replicas.
+func (t *BudgetTracker) Refresh() error {
+	campaigns, err := t.repo.List()
+	if err != nil {
+		return err
+	}
+	byID := make(map[uint]models.Campaign, len(campaigns))
+	for _, campaign := range campaigns {
+		byID[campaign.ID] = campaign
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.campaigns = byID
+	return nil
+}
+
+// ChargeClick adds a valid click on ad to its campaign's spend.
+func (t *BudgetTracker) ChargeClick(ctx context.Context, ad models.Ad) {
+	t.charge(ctx, ad, func(c models.Campaign) float64 { return c.CostPerClick })
+}
+
+// ChargeImpression adds a valid impression of ad to its campaign's spend.
+func (t *BudgetTracker) ChargeImpression(ctx context.Context, ad models.Ad) {
+	t.charge(ctx, ad, func(c models.Campaign) float64 { return c.CostPerMille / 1000 })
+}
+
+func (t *BudgetTracker) charge(ctx context.Context, ad models.Ad, price func(models.Campaign) float64) {
+	if ad.CampaignID == nil {
+		return
+	}
+	day := time.Now().UTC().Format(spendDayLayout)
+
+	t.mu.Lock()
+	campaign, ok := t.campaigns[*ad.CampaignID]
+	cost := price(campaign)
+	if !ok || cost <= 0 {
+		t.mu.Unlock()
+		return
+	}
+	t.pending[spendKey{campaign.ID, day}] += cost
+
+	// What this replica knows without the counter: the last database
+	// snapshot plus its own unflushed spend
+	dailySeed := 0.0
+	if campaign.SpendDate == day {
+		dailySeed = campaign.DailySpend
+	}
+	daily, lifetime := dailySeed+t.pending[spendKey{campaign.ID, day}], campaign.LifetimeSpend
+	for key, amount := range t.pending {
+		if key.campaignID == campaign.ID {
+			lifetime += amount
+		}
+	}
+	counter := t.counter
+	t.mu.Unlock()
+
+	id := strconv.FormatUint(uint64(campaign.ID), 10)
+	sharedDaily, err := counter.AddFloat(ctx, "budget:spend:"+id+":"+day, dailySeed, cost, dailySpendTTL)
+	if err == nil {
+		var sharedLifetime float64
+		sharedLifetime, err = counter.AddFloat(ctx, "budget:spend:"+id+":lifetime", campaign.LifetimeSpend, cost, lifetimeSpendTTL)
+		daily, lifetime = max(daily, sharedDaily), max(lifetime, sharedLifetime)
+	}
+	if err != nil {
+		metrics.BudgetCounterErrors.Inc()
+		t.logger.WithError(err).WithField("campaign_id", campaign.ID).Warn("Shared spend counter failed, checking the budget against local totals")
+	}
+
+	if reason := campaign.ExhaustedBudget(daily, lifetime); reason != "" {
+		t.pause(ctx, campaign.ID, reason, daily, lifetime)
+	}
+}
+
+func (t *BudgetTracker) pause(ctx context.Context, campaignID uint, reason string, daily, lifetime float64) {
+	t.mu.Lock()
+	campaign := t.campaigns[campaignID]
+	if campaign.BudgetPausedAt != nil {
+		t.mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	campaign.BudgetPausedAt, campaign.BudgetPauseReason = &now, reason
+	t.campaigns[campaignID] = campaign
+	t.mu.Unlock()
+
+	paused, err := t.repo.PauseForBudget(campaignID, reason)
+	if err != nil {
+		t.logger.WithError(err).WithField("campaign_id", campaignID).Error("Failed to pause campaign with exhausted budget")
+		return
+	}
+	if !paused {
+		return
+	}
+	t.invalidate(ctx)
+	metrics.BudgetPauses.WithLabelValues(reason).Inc()
+	t.logger.WithFields(logrus.Fields{
+		"campaign_id":    campaignID,
+		"budget":         reason,
+		"daily_spend":    daily,
+		"lifetime_spend": lifetime,
+	}).Warn("Campaign budget exhausted, serving paused")
+
+	details := map[string]interface{}{"budget": reason, "daily_spend": daily, "lifetime_spend": lifetime}
+	if err := t.audit.Record("system", "campaign.budget_paused", "campaign", strconv.FormatUint(uint64(campaignID), 10), details); err != nil {
+		t.logger.WithError(err).Warn("Failed to record budget pause audit event")
+	}
+}
+
+// Flush writes the spend accumulated since the last flush to the database.
+// Amounts that fail to save are kept for the next attempt.
+func (t *BudgetTracker) Flush() error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[spendKey]float64)
+	t.mu.Unlock()
+
+	var firstErr error
+	for key, amount := range pending {
+		if err := t.repo.AddSpend(key.campaignID, key.day, amount); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			t.mu.Lock()
+			t.pending[key] += amount
+			t.mu.Unlock()
+		}
+	}
+	return firstErr
+}
+
+// sync flushes spend, lifts daily pauses from previous days and reloads
+// the campaigns.
+func (t *BudgetTracker) sync(ctx context.Context) {
+	if err := t.Flush(); err != nil {
+		t.logger.WithError(err).Error("Failed to save campaign spend")
+	}
+
+	now := time.Now().UTC()
+	resumed, err := t.repo.ResumeDailyBudgets(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.logger.WithError(err).Error("Failed to resume campaigns paused for their daily budget")
+	}
+	if resumed > 0 {
+		t.invalidate(ctx)
+		t.logger.WithField("campaigns", resumed).Info("Resumed campaigns paused for their daily budget")
+	}
+
+	if err := t.Refresh(); err != nil {
+		t.logger.WithError(err).Error("Failed to refresh campaign budgets")
+	}
+}
+
+// Run saves spend and reloads budgets every interval. Call Flush after it
+// returns to save the spend of the last interval.
+func (t *BudgetTracker) Run(ctx context.Context, interval time.Duration) {
+	t.sync(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			t.sync(ctx)
+		}
+	}
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Detector inspects per-ad ingest traffic and reports when it looks anomalous.
+type Detector interface {
+	Name() string
+	Observe(adID uint, at time.Time) (fired bool, reason string)
+}
+
+// ClickRateDetector flags an ad whose clicks in the current minute exceed
+// both an absolute floor and factor times its EWMA per-minute baseline.
+type ClickRateDetector struct {
+	minPerMinute int64
+	factor       float64
+	alpha        float64
+
+	mu    sync.Mutex
+	state map[uint]*rateState
+}
+
+type rateState struct {
+	minute   int64
+	count    int64
+	baseline float64
+	fired    bool
+}
+
+func NewClickRateDetector(minPerMinute int64, factor float64) *ClickRateDetector {
+	return &ClickRateDetector{
+		minPerMinute: minPerMinute,
+		factor:       factor,
+		alpha:        0.3,
+		state:        make(map[uint]*rateState),
+	}
+}
+
+func (d *ClickRateDetector) Name() string {
+	return "click_rate"
+}
+
+func (d *ClickRateDetector) Observe(adID uint, at time.Time) (bool, string) {
+	minute := at.Unix() / 60
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	st, ok := d.state[adID]
+	if !ok {
+		st = &rateState{minute: minute}
+		d.state[adID] = st
+	}
+	if minute != st.minute {
+		// Fold the finished minute (and any idle minutes) into the baseline
+		idle := minute - st.minute - 1
+		st.baseline = d.alpha*float64(st.count) + (1-d.alpha)*st.baseline
+		if idle > 0 {
+			st.baseline *= math.Pow(1-d.alpha, float64(min(idle, 60)))
+		}
+		st.minute = minute
+		st.count = 0
+		st.fired = false
+	}
+	st.count++
+
+	if st.fired || st.count < d.minPerMinute || float64(st.count) < d.factor*st.baseline {
+		return false, ""
+	}
+	st.fired = true
+	return true, fmt.Sprintf("%d clicks this minute vs baseline %.1f/min", st.count, st.baseline)
+}
+
+// CaptureManager turns detector hits into time-boxed capture incidents.
+// While an incident is open every request for the ad is stored in full and
+// logged at warn level.
+type CaptureManager struct {
+	repo       *repositories.CaptureRepository
+	detectors  []Detector
+	duration   time.Duration
+	maxSamples int64
+	logger     *logrus.Logger
+	requests   chan models.CapturedRequest
+
+	mu     sync.RWMutex
+	active map[uint]*activeCapture
+}
+
+type activeCapture struct {
+	incidentID uint
+	endsAt     time.Time
+	samples    int64
+}
+
+func NewCaptureManager(repo *repositories.CaptureRepository, duration time.Duration, maxSamples int64, logger *logrus.Logger, detectors ...Detector) *CaptureManager {
+	return &CaptureManager{
+		repo:       repo,
+		detectors:  detectors,
+		duration:   duration,
+		maxSamples: maxSamples,
+		logger:     logger,
+		requests:   make(chan models.CapturedRequest, 1000),
+		active:     make(map[uint]*activeCapture),
+	}
+}
+
+// Observe feeds one ingest event to the detectors, opening an incident when
+// any of them fires.
+func (m *CaptureManager) Observe(adID uint, at time.Time) {
+	for _, detector := range m.detectors {
+		if fired, reason := detector.Observe(adID, at); fired {
+			if _, err := m.Start(adID, detector.Name(), reason, m.duration); err != nil {
+				m.logger.WithError(err).WithField("ad_id", adID).Error("Failed to open capture incident")
+			}
+		}
+	}
+}
+
+// Start opens (or extends) a capture window for the ad.
+func (m *CaptureManager) Start(adID uint, trigger, reason string, duration time.Duration) (*models.CaptureIncident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	if current, ok := m.active[adID]; ok && now.Before(current.endsAt) {
+		current.endsAt = now.Add(duration)
+		return &models.CaptureIncident{ID: current.incidentID, AdID: adID, EndsAt: current.endsAt}, nil
+	}
+
+	incident := &models.CaptureIncident{
+		AdID:      adID,
+		Trigger:   trigger,
+		Reason:    reason,
+		StartedAt: now,
+		EndsAt:    now.Add(duration),
+	}
+	if err := m.repo.CreateIncident(incident); err != nil {
+		return nil, err
+	}
+	m.active[adID] = &activeCapture{incidentID: incident.ID, endsAt: incident.EndsAt}
+
+	m.logger.WithFields(logrus.Fields{
+		"ad_id":       adID,
+		"incident_id": incident.ID,
+		"trigger":     trigger,
+		"reason":      reason,
+		"until":       incident.EndsAt,
+	}).Warn("Anomaly detected, enabling full traffic capture")
+
+	return incident, nil
+}
+
+// Active returns the open incident for the ad, if any.
+func (m *CaptureManager) Active(adID uint) (uint, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	current, ok := m.active[adID]
+	if !ok || time.Now().After(current.endsAt) {
+		return 0, false
+	}
+	return current.incidentID, true
+}
+
+// Capture queues a request payload for the ad's open incident. It never
+// blocks the ingest path; samples beyond the cap or a full buffer are dropped.
+func (m *CaptureManager) Capture(req models.CapturedRequest) {
+	m.mu.Lock()
+	current, ok := m.active[req.AdID]
+	if !ok || time.Now().After(current.endsAt) || current.samples >= m.maxSamples {
+		m.mu.Unlock()
+		return
+	}
+	current.samples++
+	req.IncidentID = current.incidentID
+	m.mu.Unlock()
+
+	select {
+	case m.requests <- req:
+	default:
+		m.logger.WithField("ad_id", req.AdID).Warn("Capture buffer full, dropping sample")
+	}
+}
+
+// Run persists captured requests in batches and expires finished windows.
+func (m *CaptureManager) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	batch := make([]models.CapturedRequest, 0, 100)
+	flush := func() {
+		if err := m.repo.SaveRequests(batch); err != nil {
+			m.logger.WithError(err).Error("Failed to store captured requests")
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush()
+			return
+		case req := <-m.requests:
+			batch = append(batch, req)
+			if len(batch) >= cap(batch) {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+			m.expire()
+		}
+	}
+}
+
+func (m *CaptureManager) expire() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for adID, current := range m.active {
+		if now.After(current.endsAt) {
+			m.logger.WithFields(logrus.Fields{
+				"ad_id":       adID,
+				"incident_id": current.incidentID,
+				"captured":    current.samples,
+			}).Info("Traffic capture window closed")
+			delete(m.active, adID)
+		}
+	}
+}
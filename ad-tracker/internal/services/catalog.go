@@ -0,0 +1,228 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+)
+
+// CatalogColumns are the headers a catalog CSV may use, in any order. Only
+// campaign_name is required.
+var CatalogColumns = []string{
+	"account_id", "campaign_name", "campaign_active",
+	"cost_per_click", "cost_per_mille", "daily_budget", "lifetime_budget",
+	"ad_ref", "ad_title", "ad_image_url", "ad_target_url", "ad_duration_seconds", "ad_active",
+}
+
+// CatalogImporter upserts campaigns and ads from a CSV, one ad per line.
+// Campaigns are matched by account and name, ads by campaign and ad_ref.
+type CatalogImporter struct {
+	repo *repositories.CatalogRepository
+}
+
+func NewCatalogImporter(repo *repositories.CatalogRepository) *CatalogImporter {
+	return &CatalogImporter{repo: repo}
+}
+
+// Import validates the whole file before writing anything; any problem is
+// reported and nothing is applied. A dry run reports what would change.
+func (i *CatalogImporter) Import(r io.Reader, dryRun bool) (models.CatalogImportReport, error) {
+	rows, problems := ParseCatalogCSV(r)
+	if len(problems) > 0 {
+		return models.CatalogImportReport{DryRun: dryRun, Rows: len(rows), Errors: problems}, nil
+	}
+	return i.repo.Import(rows, dryRun)
+}
+
+// ParseCatalogCSV reads and checks every line, collecting all problems
+// rather than stopping at the first.
+func ParseCatalogCSV(r io.Reader) ([]models.CatalogRow, []models.CatalogImportError) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, []models.CatalogImportError{{Line: 1, Error: "file is empty"}}
+	}
+	if err != nil {
+		return nil, []models.CatalogImportError{{Line: 1, Error: err.Error()}}
+	}
+	columns, problems := catalogHeader(header)
+	if len(problems) > 0 {
+		return nil, problems
+	}
+
+	var rows []models.CatalogRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// A wrong field count only spoils its own line; other syntax
+			// errors leave the reader unable to continue
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				problems = append(problems, models.CatalogImportError{Error: err.Error()})
+				break
+			}
+			problems = append(problems, models.CatalogImportError{Line: parseErr.Line, Error: parseErr.Err.Error()})
+			if parseErr.Err != csv.ErrFieldCount {
+				break
+			}
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+		if len(rows) == models.MaxCatalogRows {
+			problems = append(problems, models.CatalogImportError{Line: line, Error: fmt.Sprintf("at most %d rows can be imported at once", models.MaxCatalogRows)})
+			break
+		}
+		row := catalogLine{line: line, values: make(map[string]string, len(columns))}
+		for index, value := range record {
+			row.values[columns[index]] = strings.TrimSpace(value)
+		}
+		rows = append(rows, row.parse(&problems))
+	}
+	if len(rows) == 0 && len(problems) == 0 {
+		problems = append(problems, models.CatalogImportError{Line: 2, Error: "no rows to import"})
+	}
+	return rows, problems
+}
+
+func catalogHeader(header []string) ([]string, []models.CatalogImportError) {
+	known := make(map[string]bool, len(CatalogColumns))
+	for _, column := range CatalogColumns {
+		known[column] = true
+	}
+	var problems []models.CatalogImportError
+	seen := make(map[string]bool, len(header))
+	columns := make([]string, len(header))
+	for index, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		switch {
+		case !known[name]:
+			problems = append(problems, models.CatalogImportError{Line: 1, Column: name, Error: "unknown column, expected " + strings.Join(CatalogColumns, ", ")})
+		case seen[name]:
+			problems = append(problems, models.CatalogImportError{Line: 1, Column: name, Error: "duplicate column"})
+		}
+		seen[name] = true
+		columns[index] = name
+	}
+	if !seen["campaign_name"] {
+		problems = append(problems, models.CatalogImportError{Line: 1, Column: "campaign_name", Error: "column is required"})
+	}
+	return columns, problems
+}
+
+// catalogLine converts one record's cells, reporting each bad cell.
+type catalogLine struct {
+	line   int
+	values map[string]string
+}
+
+func (l catalogLine) parse(problems *[]models.CatalogImportError) models.CatalogRow {
+	fail := func(column, format string, args ...interface{}) {
+		*problems = append(*problems, models.CatalogImportError{Line: l.line, Column: column, Error: fmt.Sprintf(format, args...)})
+	}
+	row := models.CatalogRow{
+		Line:         l.line,
+		CampaignName: l.values["campaign_name"],
+		AdRef:        l.values["ad_ref"],
+	}
+	if row.CampaignName == "" {
+		fail("campaign_name", "is required")
+	} else if len(row.CampaignName) > 255 {
+		fail("campaign_name", "must be at most 255 characters")
+	}
+
+	if value := l.values["account_id"]; value != "" {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			fail("account_id", "must be a positive integer, got %q", value)
+		} else {
+			accountID := uint(id)
+			row.AccountID = &accountID
+		}
+	}
+	row.CampaignActive = l.boolean("campaign_active", fail)
+	row.CostPerClick = l.amount("cost_per_click", fail)
+	row.CostPerMille = l.amount("cost_per_mille", fail)
+	row.DailyBudget = l.amount("daily_budget", fail)
+	row.LifetimeBudget = l.amount("lifetime_budget", fail)
+
+	adColumns := []string{"ad_title", "ad_image_url", "ad_target_url", "ad_duration_seconds", "ad_active"}
+	if row.AdRef == "" {
+		for _, column := range adColumns {
+			if l.values[column] != "" {
+				fail("ad_ref", "is required when %s is set", column)
+				break
+			}
+		}
+		return row
+	}
+	if len(row.AdRef) > 128 {
+		fail("ad_ref", "must be at most 128 characters")
+	}
+	if value, ok := l.values["ad_title"]; ok && value != "" {
+		row.AdTitle = &value
+	}
+	row.AdImageURL = l.link("ad_image_url", fail)
+	row.AdTargetURL = l.link("ad_target_url", fail)
+	if value := l.values["ad_duration_seconds"]; value != "" {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seconds < 0 || seconds > 86400 {
+			fail("ad_duration_seconds", "must be a whole number of seconds between 0 and 86400, got %q", value)
+		} else {
+			row.AdDurationSeconds = &seconds
+		}
+	}
+	row.AdActive = l.boolean("ad_active", fail)
+	return row
+}
+
+func (l catalogLine) boolean(column string, fail func(string, string, ...interface{})) *bool {
+	value := l.values[column]
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		fail(column, "must be true or false, got %q", value)
+		return nil
+	}
+	return &parsed
+}
+
+func (l catalogLine) amount(column string, fail func(string, string, ...interface{})) *float64 {
+	value := l.values[column]
+	if value == "" {
+		return nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil || parsed < 0 || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+		fail(column, "must be a non-negative number, got %q", value)
+		return nil
+	}
+	return &parsed
+}
+
+func (l catalogLine) link(column string, fail func(string, string, ...interface{})) *string {
+	value := l.values[column]
+	if value == "" {
+		return nil
+	}
+	u, err := url.ParseRequestURI(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		fail(column, "must be an absolute http(s) URL, got %q", value)
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/consent"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ConsentPolicy decides whether identifiers may be stored for an event. It
+// caches which ads belong to strict privacy accounts and refreshes the set
+// periodically, like the blocklist.
+type ConsentPolicy struct {
+	repo      *repositories.AccountRepository
+	evaluator consent.Evaluator
+	logger    *logrus.Logger
+
+	mu        sync.RWMutex
+	strictAds map[uint]struct{}
+}
+
+func NewConsentPolicy(repo *repositories.AccountRepository, vendorID int, logger *logrus.Logger) *ConsentPolicy {
+	return &ConsentPolicy{
+		repo:      repo,
+		evaluator: consent.Evaluator{VendorID: vendorID},
+		logger:    logger,
+		strictAds: make(map[uint]struct{}),
+	}
+}
+
+// Refresh reloads the strict ad set from the database.
+func (p *ConsentPolicy) Refresh() error {
+	ids, err := p.repo.StrictAds()
+	if err != nil {
+		return err
+	}
+
+	strictAds := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		strictAds[id] = struct{}{}
+	}
+
+	p.mu.Lock()
+	p.strictAds = strictAds
+	p.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the policy every interval until ctx is cancelled.
+func (p *ConsentPolicy) Run(ctx context.Context, interval time.Duration) {
+	if err := p.Refresh(); err != nil {
+		p.logger.WithError(err).Error("Failed to load account privacy modes")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.Refresh(); err != nil {
+				p.logger.WithError(err).Error("Failed to refresh account privacy modes")
+			}
+		}
+	}
+}
+
+// Evaluate returns the consent state of the signals and whether identifiers
+// may be persisted for the ad.
+func (p *ConsentPolicy) Evaluate(adID uint, signals consent.Signals) (string, bool) {
+	state := p.evaluator.State(signals)
+
+	p.mu.RLock()
+	_, strict := p.strictAds[adID]
+	p.mu.RUnlock()
+
+	return state, !strict || consent.Permits(state)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ViewerHash is how viewers are stored and keyed: short, stable and not
+// reversible to the user id, cookie or IP.
+func ViewerHash(viewer string) string {
+	sum := sha256.Sum256([]byte(viewer))
+	return hex.EncodeToString(sum[:12])
+}
+
+// ExperimentAssigner keeps the running experiments in memory and decides
+// which variant each viewer sees. Assignment hashes the viewer with the
+// experiment id, so it is sticky without storing anything and identical
+// on every replica.
+type ExperimentAssigner struct {
+	repo   *repositories.ExperimentRepository
+	logger *logrus.Logger
+
+	mu   sync.RWMutex
+	byAd map[uint]*models.Experiment
+}
+
+func NewExperimentAssigner(repo *repositories.ExperimentRepository, logger *logrus.Logger) *ExperimentAssigner {
+	return &ExperimentAssigner{repo: repo, logger: logger, byAd: make(map[uint]*models.Experiment)}
+}
+
+// Refresh reloads the running experiments.
+func (a *ExperimentAssigner) Refresh() error {
+	experiments, err := a.repo.Running()
+	if err != nil {
+		return err
+	}
+	byAd := make(map[uint]*models.Experiment)
+	for i := range experiments {
+		for _, variant := range experiments[i].Variants {
+			byAd[variant.AdID] = &experiments[i]
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.byAd = byAd
+	return nil
+}
+
+func (a *ExperimentAssigner) Run(ctx context.Context, interval time.Duration) {
+	if err := a.Refresh(); err != nil {
+		a.logger.WithError(err).Error("Failed to load running experiments")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.Refresh(); err != nil {
+				a.logger.WithError(err).Error("Failed to refresh running experiments")
+			}
+		}
+	}
+}
+
+// Assign keeps only the viewer's variant of each running experiment and
+// returns an exposure for every variant left in the list. Viewers without
+// an identity get a random variant each time.
+func (a *ExperimentAssigner) Assign(ads []models.Ad, viewer string) ([]models.Ad, []models.ExperimentExposure) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if len(a.byAd) == 0 {
+		return ads, nil
+	}
+
+	hashed := ""
+	if viewer != "" {
+		hashed = ViewerHash(viewer)
+	}
+	chosen := make(map[uint]models.ExperimentVariant)
+	served := make([]models.Ad, 0, len(ads))
+	var exposures []models.ExperimentExposure
+	for _, ad := range ads {
+		experiment, ok := a.byAd[ad.ID]
+		if !ok {
+			served = append(served, ad)
+			continue
+		}
+		variant, decided := chosen[experiment.ID]
+		if !decided {
+			variant = pickVariant(experiment, hashed)
+			chosen[experiment.ID] = variant
+		}
+		if variant.AdID != ad.ID {
+			continue
+		}
+		served = append(served, ad)
+		exposures = append(exposures, models.ExperimentExposure{
+			ExperimentID: experiment.ID,
+			VariantID:    variant.ID,
+			AdID:         ad.ID,
+			Viewer:       hashed,
+			Timestamp:    time.Now().UTC(),
+		})
+	}
+	return served, exposures
+}
+
+func pickVariant(experiment *models.Experiment, viewer string) models.ExperimentVariant {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+	if total <= 0 {
+		return experiment.Variants[0]
+	}
+
+	var point int
+	if viewer == "" {
+		point = rand.Intn(total)
+	} else {
+		sum := sha256.Sum256([]byte(strconv.FormatUint(uint64(experiment.ID), 10) + ":" + viewer))
+		point = int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	}
+	for _, variant := range experiment.Variants {
+		if point < variant.Weight {
+			return variant
+		}
+		point -= variant.Weight
+	}
+	return experiment.Variants[len(experiment.Variants)-1]
+}
+
+// WilsonInterval is the Wilson score interval for a rate of successes out
+// of trials at confidence z (1.96 for 95%). Unlike the normal
+// approximation it stays within [0, 1] and behaves at small counts.
+func WilsonInterval(successes, trials int64, z float64) (low, high float64) {
+	if trials <= 0 {
+		return 0, 0
+	}
+	n := float64(trials)
+	p := float64(successes) / n
+	denominator := 1 + z*z/n
+	center := (p + z*z/(2*n)) / denominator
+	margin := z * math.Sqrt(p*(1-p)/n+z*z/(4*n*n)) / denominator
+	return math.Max(0, center-margin), math.Min(1, center+margin)
+}
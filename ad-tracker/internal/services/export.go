@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+var ExportColumns = []string{"id", "ad_id", "timestamp", "ip_address", "user_agent", "video_playback_time"}
+
+const exportBatchSize = 5000
+
+// ExportService renders cohort exports and uploads them to an object store
+// shared by every replica, from which they are served with Range support
+// and resumed after a dropped connection.
+type ExportService struct {
+	repo   *repositories.ExportRepository
+	store  archive.ObjectStore
+	logger *logrus.Logger
+}
+
+func NewExportService(repo *repositories.ExportRepository, store archive.ObjectStore, logger *logrus.Logger) *ExportService {
+	return &ExportService{repo: repo, store: store, logger: logger}
+}
+
+// Store is where completed exports are kept, under their FilePath.
+func (s *ExportService) Store() archive.ObjectStore {
+	return s.store
+}
+
+// Run generates the export file and updates its record. It is meant to be
+// started in its own goroutine.
+func (s *ExportService) Run(export *models.Export) {
+	log := s.logger.WithField("export_id", export.ID)
+
+	export.Status = models.ExportRunning
+	if err := s.repo.Save(export); err != nil {
+		log.WithError(err).Error("Failed to mark export running")
+	}
+
+	if err := s.render(context.Background(), export); err != nil {
+		log.WithError(err).Error("Export failed")
+		export.Status = models.ExportFailed
+		export.Error = err.Error()
+	} else {
+		now := time.Now().UTC()
+		export.Status = models.ExportCompleted
+		export.CompletedAt = &now
+		log.WithField("rows", export.Rows).Info("Export completed")
+	}
+
+	if err := s.repo.Save(export); err != nil {
+		log.WithError(err).Error("Failed to save export result")
+	}
+}
+
+func (s *ExportService) render(ctx context.Context, export *models.Export) error {
+	tmp, err := os.CreateTemp("", fmt.Sprintf("export-%d-*.csv", export.ID))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{}
+	w := csv.NewWriter(io.MultiWriter(tmp, hash, counter))
+
+	if err := w.Write(ExportColumns); err != nil {
+		return err
+	}
+
+	var rows int64
+	err = s.repo.StreamClicks(export, exportBatchSize, func(batch []models.ClickEvent) error {
+		for _, click := range batch {
+			record := []string{
+				strconv.FormatUint(uint64(click.ID), 10),
+				strconv.FormatUint(uint64(click.AdID), 10),
+				click.Timestamp.UTC().Format(time.RFC3339Nano),
+				click.IPAddress,
+				click.UserAgent,
+				strconv.FormatInt(click.VideoPlaybackTime, 10),
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+			rows++
+		}
+		w.Flush()
+		return w.Error()
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("export-%d.csv", export.ID)
+	if err := s.store.Put(ctx, key, tmp, "text/csv; charset=utf-8"); err != nil {
+		return fmt.Errorf("upload export: %w", err)
+	}
+
+	export.FilePath = key
+	export.Rows = rows
+	export.Bytes = counter.n
+	export.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// Manifest describes a completed export.
+func (s *ExportService) Manifest(export *models.Export) models.ExportManifest {
+	manifest := models.ExportManifest{
+		ExportID:  export.ID,
+		FileName:  path.Base(export.FilePath),
+		Columns:   ExportColumns,
+		Rows:      export.Rows,
+		Bytes:     export.Bytes,
+		SHA256:    export.SHA256,
+		OrderedBy: "id",
+	}
+	if export.CompletedAt != nil {
+		manifest.CompletedAt = *export.CompletedAt
+	}
+	return manifest
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
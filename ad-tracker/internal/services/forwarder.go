@@ -0,0 +1,447 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultSegmentURL = "https://api.segment.io/v1/batch"
+	DefaultGA4URL     = "https://www.google-analytics.com/mp/collect"
+
+	// ga4MaxEvents is the Measurement Protocol limit per request.
+	ga4MaxEvents = 25
+	// forwarderSenders bounds concurrent batch sends.
+	forwarderSenders = 4
+)
+
+// ForwardedEvent is a click or conversion as sent to marketing tools.
+// AnonymousID identifies the visitor when there is no user id.
+type ForwardedEvent struct {
+	Type        string
+	ID          string
+	AdID        uint
+	UserID      string
+	AnonymousID string
+	Time        time.Time
+	Properties  map[string]interface{}
+}
+
+// ForwardedClick maps a click to a forwarded event. The session, else the
+// click itself, stands in for a missing user id.
+func ForwardedClick(click models.ClickEvent) ForwardedEvent {
+	properties := map[string]interface{}{
+		"ad_id":    click.AdID,
+		"click_id": click.ClickID,
+	}
+	if click.CreativeID != nil {
+		properties["creative_id"] = *click.CreativeID
+	}
+	if click.PlacementID != nil {
+		properties["placement_id"] = *click.PlacementID
+	}
+	anonymousID := click.SessionID
+	if anonymousID == "" {
+		anonymousID = click.ClickID
+	}
+	return ForwardedEvent{
+		Type:        models.WebhookEventClick,
+		ID:          click.ClickID,
+		AdID:        click.AdID,
+		UserID:      click.UserID,
+		AnonymousID: anonymousID,
+		Time:        click.Timestamp,
+		Properties:  properties,
+	}
+}
+
+// ForwardedConversion maps an attributed conversion to a forwarded event.
+// The attributed click id, else the conversion, stands in for a missing
+// user id.
+func ForwardedConversion(conversion models.Conversion) ForwardedEvent {
+	id := strconv.FormatUint(uint64(conversion.ID), 10)
+	properties := map[string]interface{}{
+		"ad_id":            *conversion.AttributedAdID,
+		"attribution_type": conversion.AttributionType,
+		"value":            conversion.Value,
+	}
+	if conversion.Currency != "" {
+		properties["currency"] = conversion.Currency
+	}
+	if conversion.TransactionID != "" {
+		properties["transaction_id"] = conversion.TransactionID
+	}
+	anonymousID := "conversion-" + id
+	if conversion.ClickID != "" {
+		properties["click_id"] = conversion.ClickID
+		anonymousID = conversion.ClickID
+	}
+	return ForwardedEvent{
+		Type:        models.WebhookEventConversion,
+		ID:          id,
+		AdID:        *conversion.AttributedAdID,
+		UserID:      conversion.UserID,
+		AnonymousID: anonymousID,
+		Time:        conversion.Timestamp,
+		Properties:  properties,
+	}
+}
+
+type forwardBatch struct {
+	integration models.Integration
+	events      []ForwardedEvent
+}
+
+type forwardItem struct {
+	integration models.Integration
+	event       ForwardedEvent
+}
+
+// Forwarder sends tracking events to the Segment and GA4 integrations of
+// the account owning the ad. Events are batched per integration and sent
+// every flush interval or when a batch fills; failed sends are retried
+// with exponential backoff. Unlike webhooks nothing is logged to the
+// database, so events still queued when the process exits are lost.
+type Forwarder struct {
+	repo          *repositories.IntegrationRepository
+	client        *http.Client
+	logger        *logrus.Logger
+	events        chan forwardItem
+	batchSize     int
+	flushInterval time.Duration
+	maxAttempts   int
+	backoff       time.Duration
+	segmentURL    string
+	ga4URL        string
+
+	mu           sync.RWMutex
+	integrations map[uint][]models.Integration
+}
+
+func NewForwarder(repo *repositories.IntegrationRepository, logger *logrus.Logger) *Forwarder {
+	return &Forwarder{
+		repo:          repo,
+		client:        &http.Client{Timeout: 10 * time.Second},
+		logger:        logger,
+		events:        make(chan forwardItem, 10000),
+		batchSize:     100,
+		flushInterval: 5 * time.Second,
+		maxAttempts:   5,
+		backoff:       2 * time.Second,
+		segmentURL:    DefaultSegmentURL,
+		ga4URL:        DefaultGA4URL,
+		integrations:  make(map[uint][]models.Integration),
+	}
+}
+
+// SetBatching sends a batch once it holds size events or is interval old.
+func (f *Forwarder) SetBatching(size int, interval time.Duration) {
+	f.batchSize = max(size, 1)
+	f.flushInterval = interval
+}
+
+// SetRetryPolicy limits attempts per batch; retry n waits backoff*2^(n-1).
+func (f *Forwarder) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+	f.maxAttempts = max(maxAttempts, 1)
+	f.backoff = backoff
+}
+
+// SetEndpoints overrides the provider URLs, e.g. for Segment's EU region.
+func (f *Forwarder) SetEndpoints(segmentURL, ga4URL string) {
+	f.segmentURL = segmentURL
+	f.ga4URL = ga4URL
+}
+
+// Refresh reloads the integrations.
+func (f *Forwarder) Refresh() error {
+	integrations, err := f.repo.ByAd()
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.integrations = integrations
+	f.mu.Unlock()
+	return nil
+}
+
+// Publish queues the event for the ad's integrations without blocking.
+// Events are dropped, and counted, when the queue is full.
+func (f *Forwarder) Publish(event ForwardedEvent) {
+	f.mu.RLock()
+	integrations := f.integrations[event.AdID]
+	f.mu.RUnlock()
+
+	for _, integration := range integrations {
+		if !subscribed(integration.EventTypes, event.Type) {
+			continue
+		}
+		select {
+		case f.events <- forwardItem{integration: integration, event: event}:
+		default:
+			metrics.ForwardedEvents.WithLabelValues(integration.Provider, "dropped").Inc()
+		}
+	}
+}
+
+func subscribed(eventTypes, eventType string) bool {
+	for _, subscribed := range strings.Split(eventTypes, ",") {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Run batches published events and sends them, and refreshes the
+// integrations every interval, until ctx is cancelled.
+func (f *Forwarder) Run(ctx context.Context, interval time.Duration) {
+	if err := f.Refresh(); err != nil {
+		f.logger.WithError(err).Error("Failed to load integrations")
+	}
+
+	batches := make(chan forwardBatch, forwarderSenders)
+	for i := 0; i < forwarderSenders; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case batch := <-batches:
+					f.send(ctx, batch)
+				}
+			}
+		}()
+	}
+
+	pending := make(map[uint]*forwardBatch)
+	flush := func(id uint) {
+		batch := pending[id]
+		delete(pending, id)
+		select {
+		case batches <- *batch:
+		case <-ctx.Done():
+		}
+	}
+
+	refresh := time.NewTicker(interval)
+	defer refresh.Stop()
+	flushTicker := time.NewTicker(f.flushInterval)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case item := <-f.events:
+			batch, ok := pending[item.integration.ID]
+			if !ok {
+				batch = &forwardBatch{integration: item.integration}
+				pending[item.integration.ID] = batch
+			}
+			batch.events = append(batch.events, item.event)
+			if len(batch.events) >= f.batchSize {
+				flush(item.integration.ID)
+			}
+		case <-flushTicker.C:
+			for id := range pending {
+				flush(id)
+			}
+		case <-refresh.C:
+			if err := f.Refresh(); err != nil {
+				f.logger.WithError(err).Error("Failed to refresh integrations")
+			}
+		}
+	}
+}
+
+// forwardRequest is one provider call, built once and resent on retry.
+type forwardRequest struct {
+	url      string
+	body     []byte
+	writeKey string
+	events   int
+}
+
+// send delivers a batch, retrying failures that may be transient.
+func (f *Forwarder) send(ctx context.Context, batch forwardBatch) {
+	log := f.logger.WithFields(logrus.Fields{"integration_id": batch.integration.ID, "provider": batch.integration.Provider})
+
+	var requests []forwardRequest
+	var err error
+	switch batch.integration.Provider {
+	case models.IntegrationSegment:
+		requests, err = f.segmentRequests(batch)
+	case models.IntegrationGA4:
+		requests, err = f.ga4Requests(batch)
+	default:
+		err = fmt.Errorf("unknown provider %q", batch.integration.Provider)
+	}
+	if err != nil {
+		log.WithError(err).Error("Failed to build integration request")
+		metrics.ForwardedEvents.WithLabelValues(batch.integration.Provider, "failed").Add(float64(len(batch.events)))
+		return
+	}
+
+	for _, req := range requests {
+		status := "sent"
+		if err := f.deliver(ctx, req); err != nil {
+			log.WithError(err).WithField("events", req.events).Warn("Failed to forward events")
+			status = "failed"
+		}
+		metrics.ForwardedEvents.WithLabelValues(batch.integration.Provider, status).Add(float64(req.events))
+	}
+}
+
+// permanentError marks a response that retrying will not fix.
+type permanentError struct{ error }
+
+func (f *Forwarder) deliver(ctx context.Context, req forwardRequest) error {
+	for attempt := 1; ; attempt++ {
+		err := f.post(ctx, req)
+		if err == nil {
+			return nil
+		}
+		if _, permanent := err.(permanentError); permanent || attempt >= f.maxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(f.backoff << (attempt - 1)):
+		}
+	}
+}
+
+func (f *Forwarder) post(ctx context.Context, forward forwardRequest) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, forward.url, bytes.NewReader(forward.body))
+	if err != nil {
+		return permanentError{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ad-tracker-forwarder/1.0")
+	if forward.writeKey != "" {
+		req.SetBasicAuth(forward.writeKey, "")
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("provider returned %s", resp.Status)
+	default:
+		return permanentError{fmt.Errorf("provider returned %s", resp.Status)}
+	}
+}
+
+// segmentRequests maps a batch to one Segment batch call of track events.
+func (f *Forwarder) segmentRequests(batch forwardBatch) ([]forwardRequest, error) {
+	messages := make([]map[string]interface{}, len(batch.events))
+	for i, event := range batch.events {
+		message := map[string]interface{}{
+			"type":        "track",
+			"event":       segmentEventName(event.Type),
+			"messageId":   event.Type + "-" + event.ID,
+			"anonymousId": event.AnonymousID,
+			"timestamp":   event.Time.UTC().Format(time.RFC3339Nano),
+			"properties":  event.Properties,
+			"context":     map[string]interface{}{"library": map[string]string{"name": "ad-tracker"}},
+		}
+		if event.UserID != "" {
+			message["userId"] = event.UserID
+		}
+		messages[i] = message
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"batch": messages})
+	if err != nil {
+		return nil, err
+	}
+	return []forwardRequest{{url: f.segmentURL, body: body, writeKey: batch.integration.WriteKey, events: len(messages)}}, nil
+}
+
+func segmentEventName(eventType string) string {
+	if eventType == models.WebhookEventConversion {
+		return "Ad Conversion"
+	}
+	return "Ad Clicked"
+}
+
+// ga4Requests maps a batch to Measurement Protocol hits. A hit carries one
+// client id and at most 25 events, so events are grouped by visitor.
+func (f *Forwarder) ga4Requests(batch forwardBatch) ([]forwardRequest, error) {
+	endpoint, err := url.Parse(f.ga4URL)
+	if err != nil {
+		return nil, err
+	}
+	query := endpoint.Query()
+	query.Set("measurement_id", batch.integration.MeasurementID)
+	query.Set("api_secret", batch.integration.APISecret)
+	endpoint.RawQuery = query.Encode()
+
+	type visitor struct{ clientID, userID string }
+	var order []visitor
+	grouped := make(map[visitor][]map[string]interface{})
+	for _, event := range batch.events {
+		key := visitor{clientID: event.AnonymousID, userID: event.UserID}
+		if _, ok := grouped[key]; !ok {
+			order = append(order, key)
+		}
+		params := make(map[string]interface{}, len(event.Properties)+1)
+		for name, value := range event.Properties {
+			params[name] = value
+		}
+		params["event_id"] = event.ID
+		grouped[key] = append(grouped[key], map[string]interface{}{
+			"name":             ga4EventName(event.Type),
+			"params":           params,
+			"timestamp_micros": event.Time.UnixMicro(),
+		})
+	}
+
+	var requests []forwardRequest
+	for _, key := range order {
+		events := grouped[key]
+		for start := 0; start < len(events); start += ga4MaxEvents {
+			chunk := events[start:min(start+ga4MaxEvents, len(events))]
+			hit := map[string]interface{}{"client_id": key.clientID, "events": chunk}
+			if key.userID != "" {
+				hit["user_id"] = key.userID
+			}
+			body, err := json.Marshal(hit)
+			if err != nil {
+				return nil, err
+			}
+			requests = append(requests, forwardRequest{url: endpoint.String(), body: body, events: len(chunk)})
+		}
+	}
+	return requests, nil
+}
+
+func ga4EventName(eventType string) string {
+	if eventType == models.WebhookEventConversion {
+		return "ad_conversion"
+	}
+	return "ad_click"
+}
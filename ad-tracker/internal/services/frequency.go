@@ -0,0 +1,97 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+)
+
+// frequencyWindow is one capped period: the cap, the key suffix naming the
+// current period and how long its count is kept.
+type frequencyWindow struct {
+	limit  int
+	period string
+	ttl    time.Duration
+}
+
+// CapFrequency leaves out the campaigns the user has already been served
+// up to a frequency cap this UTC hour or day, and counts a view for every
+// capped campaign that is served. It also reports whether any campaign was
+// capped at all, in which case the response is specific to the user.
+// Counts are kept in the Counter under a hash of the user, so with Redis
+// the caps hold across replicas. An empty user is never capped.
+func (t *BudgetTracker) CapFrequency(ctx context.Context, ads []models.Ad, user string) ([]models.Ad, bool) {
+	if user == "" {
+		return ads, false
+	}
+	userKey := ViewerHash(user)
+	now := time.Now().UTC()
+
+	served := make([]models.Ad, 0, len(ads))
+	decisions := make(map[uint]bool)
+	capped := false
+	for _, ad := range ads {
+		if ad.CampaignID == nil {
+			served = append(served, ad)
+			continue
+		}
+		allowed, decided := decisions[*ad.CampaignID]
+		if !decided {
+			var campaignCapped bool
+			allowed, campaignCapped = t.view(ctx, *ad.CampaignID, userKey, now)
+			decisions[*ad.CampaignID] = allowed
+			capped = capped || campaignCapped
+		}
+		if allowed {
+			served = append(served, ad)
+		}
+	}
+	return served, capped
+}
+
+// view counts one view of the campaign by the user unless that would pass
+// a cap, and reports whether it was counted and whether the campaign is
+// capped at all. Counter failures serve the campaign.
+func (t *BudgetTracker) view(ctx context.Context, campaignID uint, userKey string, now time.Time) (bool, bool) {
+	t.mu.Lock()
+	campaign, ok := t.campaigns[campaignID]
+	counter := t.counter
+	t.mu.Unlock()
+	if !ok || (campaign.FrequencyCapHour <= 0 && campaign.FrequencyCapDay <= 0) {
+		return true, false
+	}
+
+	windows := []frequencyWindow{
+		{campaign.FrequencyCapHour, now.Format("2006010215"), time.Hour},
+		{campaign.FrequencyCapDay, now.Format("20060102"), 24 * time.Hour},
+	}
+	prefix := "frequency:" + strconv.FormatUint(uint64(campaignID), 10) + ":" + userKey + ":"
+
+	var counted []frequencyWindow
+	for _, window := range windows {
+		if window.limit <= 0 {
+			continue
+		}
+		views, err := counter.AddFloat(ctx, prefix+window.period, 0, 1, window.ttl)
+		if err != nil {
+			metrics.BudgetCounterErrors.Inc()
+			t.logger.WithError(err).WithField("campaign_id", campaignID).Warn("Frequency counter failed, serving campaign uncapped")
+			return true, true
+		}
+		counted = append(counted, window)
+		if views > float64(window.limit) {
+			// Undo this request's counts; the user was not served
+			for _, undo := range counted {
+				if _, err := counter.AddFloat(ctx, prefix+undo.period, 0, -1, undo.ttl); err != nil {
+					t.logger.WithError(err).WithField("campaign_id", campaignID).Warn("Failed to undo frequency count")
+				}
+			}
+			metrics.FrequencyCapped.Inc()
+			return false, true
+		}
+	}
+	return true, true
+}
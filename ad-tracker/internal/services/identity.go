@@ -0,0 +1,103 @@
+package services
+
+import (
+	"time"
+
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/pii"
+	repositories "ad-tracking-system/internal/repository"
+)
+
+// IdentityGraph ties user ids seen with the same hashed email or device id
+// together, so a conversion on one device can be credited to clicks on
+// another. Identifiers arrive as SHA-256 digests and are hashed again with
+// the user id salt before they are stored, so stored values cannot be
+// matched against other datasets. A nil IdentityGraph links nothing.
+type IdentityGraph struct {
+	repo   *repositories.IdentityRepository
+	hasher *pii.UserIDHasher
+}
+
+func NewIdentityGraph(repo *repositories.IdentityRepository, hasher *pii.UserIDHasher) *IdentityGraph {
+	return &IdentityGraph{repo: repo, hasher: hasher}
+}
+
+// identifiers converts hints to the form stored in the graph.
+func (g *IdentityGraph) identifiers(hints models.IdentityHints) []models.Identifier {
+	var identifiers []models.Identifier
+	if hints.HashedEmail != "" {
+		identifiers = append(identifiers, models.Identifier{Kind: models.IdentifierEmail, Value: g.hasher.Apply(hints.HashedEmail)})
+	}
+	if hints.HashedDeviceID != "" {
+		identifiers = append(identifiers, models.Identifier{Kind: models.IdentifierDevice, Value: g.hasher.Apply(hints.HashedDeviceID)})
+	}
+	return identifiers
+}
+
+// Observe links a stored user id to the hints sent with its event.
+func (g *IdentityGraph) Observe(userID string, hints models.IdentityHints, at time.Time) error {
+	if g == nil {
+		return nil
+	}
+	return g.repo.Link(userID, g.identifiers(hints), at)
+}
+
+// Resolve returns the stored user ids belonging to the same person as
+// userID or the hints, userID first. It is empty when neither is known. An
+// opted-out user resolves to itself alone.
+func (g *IdentityGraph) Resolve(userID string, hints models.IdentityHints) ([]string, error) {
+	var users []string
+	if userID != "" {
+		users = append(users, userID)
+	}
+	if g == nil {
+		return users, nil
+	}
+	if userID != "" {
+		opted, err := g.repo.OptedOut(models.Identifier{Kind: models.IdentifierUser, Value: userID})
+		if err != nil || opted {
+			return users, err
+		}
+	}
+	seen := map[string]bool{userID: true}
+	add := func(ids []string) {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				users = append(users, id)
+			}
+		}
+	}
+
+	if userID != "" {
+		related, err := g.repo.Related([]string{userID})
+		if err != nil {
+			return users, err
+		}
+		add(related[userID])
+	}
+	linked, err := g.repo.UsersFor(g.identifiers(hints))
+	if err != nil {
+		return users, err
+	}
+	add(linked)
+	return users, nil
+}
+
+// Related maps stored user ids to the other ids linked to them.
+func (g *IdentityGraph) Related(userIDs []string) (map[string][]string, error) {
+	if g == nil {
+		return map[string][]string{}, nil
+	}
+	return g.repo.Related(userIDs)
+}
+
+// OptOut removes a stored user id and the hinted identifiers from the graph
+// and keeps them out of it, returning how many links were removed.
+func (g *IdentityGraph) OptOut(userID string, hints models.IdentityHints) (int64, error) {
+	identifiers := g.identifiers(hints)
+	if userID != "" {
+		identifiers = append(identifiers, models.Identifier{Kind: models.IdentifierUser, Value: userID})
+	}
+	return g.repo.OptOut(identifiers)
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"ad-tracking-system/internal/models"
+)
+
+// latencyBuckets are the upper bounds of the time-to-convert histogram.
+var latencyBuckets = []struct {
+	upTo  time.Duration
+	label string
+}{
+	{time.Minute, "1m"},
+	{10 * time.Minute, "10m"},
+	{time.Hour, "1h"},
+	{6 * time.Hour, "6h"},
+	{24 * time.Hour, "1d"},
+	{3 * 24 * time.Hour, "3d"},
+	{7 * 24 * time.Hour, "7d"},
+}
+
+// SummarizeLatencies groups click-to-conversion times by campaign, ordered
+// by campaign id with conversions outside campaigns first. Clock skew
+// between click and conversion timestamps is clamped to zero.
+func SummarizeLatencies(latencies []models.ConversionLatency) []models.CampaignLatency {
+	byCampaign := map[uint][]float64{}
+	var unassigned []float64
+	for _, latency := range latencies {
+		seconds := max(latency.ConvertedAt.Sub(latency.ClickedAt).Seconds(), 0)
+		if latency.CampaignID == nil {
+			unassigned = append(unassigned, seconds)
+			continue
+		}
+		byCampaign[*latency.CampaignID] = append(byCampaign[*latency.CampaignID], seconds)
+	}
+
+	ids := make([]uint, 0, len(byCampaign))
+	for id := range byCampaign {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	summaries := make([]models.CampaignLatency, 0, len(ids)+1)
+	if len(unassigned) > 0 {
+		summaries = append(summaries, summarizeLatency(nil, unassigned))
+	}
+	for _, id := range ids {
+		id := id
+		summaries = append(summaries, summarizeLatency(&id, byCampaign[id]))
+	}
+	return summaries
+}
+
+func summarizeLatency(campaignID *uint, seconds []float64) models.CampaignLatency {
+	sort.Float64s(seconds)
+	summary := models.CampaignLatency{
+		CampaignID:    campaignID,
+		Conversions:   int64(len(seconds)),
+		MedianSeconds: percentile(seconds, 0.5),
+		P90Seconds:    percentile(seconds, 0.9),
+		MaxSeconds:    seconds[len(seconds)-1],
+		Buckets:       make([]models.LatencyBucket, len(latencyBuckets)+1),
+	}
+
+	var total float64
+	for _, s := range seconds {
+		total += s
+		i := sort.Search(len(latencyBuckets), func(i int) bool { return s <= latencyBuckets[i].upTo.Seconds() })
+		summary.Buckets[i].Conversions++
+	}
+	summary.AvgSeconds = total / float64(len(seconds))
+	for i, bucket := range latencyBuckets {
+		summary.Buckets[i].UpTo = bucket.label
+	}
+	return summary
+}
+
+// percentile interpolates between the closest ranks like percentile_cont.
+// sorted must be in ascending order and not empty.
+func percentile(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lower := int(rank)
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// leaderboardDays is how many UTC days of leaderboards are kept
+	leaderboardDays = 7
+	// defaultLeaderboardMinImpressions keeps ads with a handful of
+	// impressions off the CTR board
+	defaultLeaderboardMinImpressions = 100
+)
+
+// LeaderboardOrders are the orders a leaderboard can be read in.
+var LeaderboardOrders = []string{"clicks", "ctr"}
+
+// leaderboardEntry is one ad on one day's leaderboards.
+type leaderboardEntry struct {
+	day  string
+	adID uint
+}
+
+// rankScript adds to an ad's clicks or impressions for the day and
+// recomputes its CTR, which is only ranked once the ad has enough
+// impressions for it to mean something.
+var rankScript = redis.NewScript(`
+local clicks = tonumber(redis.call("ZINCRBY", KEYS[1], ARGV[2], ARGV[1]))
+local impressions = tonumber(redis.call("ZINCRBY", KEYS[2], ARGV[3], ARGV[1]))
+if impressions >= tonumber(ARGV[4]) then
+	redis.call("ZADD", KEYS[3], clicks / impressions, ARGV[1])
+end
+for _, key in ipairs(KEYS) do
+	redis.call("PEXPIRE", key, ARGV[5])
+end
+return clicks
+`)
+
+// SetLeaderboardMinImpressions sets how many impressions an ad needs in a
+// day before it is ranked by CTR.
+func (r *RealtimeCounters) SetLeaderboardMinImpressions(n int64) {
+	r.minImpressions = n
+}
+
+// rank queues count events of eventType for the entry's ad and day.
+func (r *RealtimeCounters) rank(ctx context.Context, pipe redis.Pipeliner, entry leaderboardEntry, eventType string, count int64) {
+	var clicks, impressions int64
+	if eventType == "click" {
+		clicks = count
+	} else {
+		impressions = count
+	}
+	// Eval rather than EvalSha: a pipeline cannot fall back when the
+	// script is not loaded yet
+	rankScript.Eval(ctx, pipe, r.leaderboardKeys(entry.day),
+		entry.adID, clicks, impressions, r.minImpressions, (leaderboardDays * 24 * time.Hour).Milliseconds())
+}
+
+func (r *RealtimeCounters) leaderboardKeys(day string) []string {
+	prefix := r.prefix + "leaderboard:" + day + ":"
+	return []string{prefix + "clicks", prefix + "impressions", prefix + "ctr"}
+}
+
+// Leaderboard returns the day's top ads in order ("clicks" or "ctr"), up
+// to limit, with their clicks, impressions and CTR so far that day. Ads
+// without clicks are not ranked.
+func (r *RealtimeCounters) Leaderboard(ctx context.Context, day time.Time, order string, limit int) ([]models.LeaderboardEntry, error) {
+	keys := r.leaderboardKeys(day.UTC().Format(time.DateOnly))
+	ranked := keys[0]
+	if order == "ctr" {
+		ranked = keys[2]
+	}
+	// Ads with impressions but no clicks are left off
+	top, err := r.client.ZRevRangeByScoreWithScores(ctx, ranked, &redis.ZRangeBy{
+		Min: "(0", Max: "+inf", Count: int64(limit),
+	}).Result()
+	if err != nil || len(top) == 0 {
+		return nil, err
+	}
+
+	members := make([]string, len(top))
+	for i, z := range top {
+		members[i] = z.Member.(string)
+	}
+	pipe := r.client.Pipeline()
+	clicks := pipe.ZMScore(ctx, keys[0], members...)
+	impressions := pipe.ZMScore(ctx, keys[1], members...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.LeaderboardEntry, 0, len(top))
+	for i, member := range members {
+		adID, err := strconv.ParseUint(member, 10, 32)
+		if err != nil {
+			continue
+		}
+		entry := models.LeaderboardEntry{
+			AdID:        uint(adID),
+			Clicks:      int64(clicks.Val()[i]),
+			Impressions: int64(impressions.Val()[i]),
+		}
+		if entry.Impressions > 0 {
+			entry.CTR = float64(entry.Clicks) / float64(entry.Impressions)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+)
+
+const defaultPacingBurst = 5 * time.Minute
+
+// SetPacingBurst sets how much of the daily target, as a share of the day,
+// a paced campaign may serve at once.
+func (t *BudgetTracker) SetPacingBurst(burst time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pacingBurst = burst
+}
+
+// Pace returns the ads that may be served in one response, and whether any
+// campaign among them is paced, in which case the response must not be
+// cached.
+//
+// Each paced campaign draws one token per response from a bucket that
+// fills at its daily impression target spread over the UTC day, starting
+// each day with pacingBurst worth of tokens. Campaigns without a token are
+// left out until the bucket refills. Tokens unused earlier in the day stay
+// available, so serving catches up after a quiet hour but never passes the
+// daily target. The draws are counted in the Counter, so with Redis every
+// replica draws from the same bucket.
+func (t *BudgetTracker) Pace(ctx context.Context, ads []models.Ad) ([]models.Ad, bool) {
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := now.Format(spendDayLayout)
+
+	served := make([]models.Ad, 0, len(ads))
+	decisions := make(map[uint]bool)
+	paced := false
+	for _, ad := range ads {
+		if ad.CampaignID == nil {
+			served = append(served, ad)
+			continue
+		}
+		allowed, decided := decisions[*ad.CampaignID]
+		if !decided {
+			var campaignPaced bool
+			allowed, campaignPaced = t.draw(ctx, *ad.CampaignID, day, now.Sub(midnight))
+			decisions[*ad.CampaignID] = allowed
+			paced = paced || campaignPaced
+		}
+		if allowed {
+			served = append(served, ad)
+		}
+	}
+	return served, paced
+}
+
+// draw takes a token for the campaign and reports whether it got one and
+// whether the campaign is paced at all. Counter failures serve the
+// campaign: budgets still cap its spend.
+func (t *BudgetTracker) draw(ctx context.Context, campaignID uint, day string, elapsed time.Duration) (bool, bool) {
+	t.mu.Lock()
+	campaign, ok := t.campaigns[campaignID]
+	counter, burst := t.counter, t.pacingBurst
+	t.mu.Unlock()
+	if !ok || campaign.DailyImpressionTarget <= 0 {
+		return true, false
+	}
+
+	target := float64(campaign.DailyImpressionTarget)
+	tokens := max(1, min(target, target*float64(elapsed+burst)/float64(24*time.Hour)))
+
+	key := "pacing:served:" + strconv.FormatUint(uint64(campaignID), 10) + ":" + day
+	drawn, err := counter.AddFloat(ctx, key, 0, 1, dailySpendTTL)
+	if err != nil {
+		metrics.BudgetCounterErrors.Inc()
+		t.logger.WithError(err).WithField("campaign_id", campaignID).Warn("Pacing counter failed, serving campaign unpaced")
+		return true, true
+	}
+	if drawn > tokens {
+		// Give the token back so the count stays at what was served
+		if _, err := counter.AddFloat(ctx, key, 0, -1, dailySpendTTL); err != nil {
+			t.logger.WithError(err).WithField("campaign_id", campaignID).Warn("Failed to return pacing token")
+		}
+		metrics.PacingThrottled.Inc()
+		return false, true
+	}
+	return true, true
+}
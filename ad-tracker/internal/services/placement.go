@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PlacementDirectory keeps the active placements in memory so serving and
+// ingestion can resolve ?placement= keys without a query.
+type PlacementDirectory struct {
+	repo   *repositories.PlacementRepository
+	logger *logrus.Logger
+
+	mu    sync.RWMutex
+	byKey map[string]models.Placement
+}
+
+func NewPlacementDirectory(repo *repositories.PlacementRepository, logger *logrus.Logger) *PlacementDirectory {
+	return &PlacementDirectory{repo: repo, logger: logger, byKey: make(map[string]models.Placement)}
+}
+
+// Refresh reloads the active placements.
+func (d *PlacementDirectory) Refresh() error {
+	placements, err := d.repo.Active()
+	if err != nil {
+		return err
+	}
+	byKey := make(map[string]models.Placement, len(placements))
+	for _, placement := range placements {
+		byKey[placement.Key] = placement
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.byKey = byKey
+	return nil
+}
+
+func (d *PlacementDirectory) Run(ctx context.Context, interval time.Duration) {
+	if err := d.Refresh(); err != nil {
+		d.logger.WithError(err).Error("Failed to load placements")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Refresh(); err != nil {
+				d.logger.WithError(err).Error("Failed to refresh placements")
+			}
+		}
+	}
+}
+
+// Lookup returns the active placement with the key.
+func (d *PlacementDirectory) Lookup(key string) (models.Placement, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	placement, ok := d.byKey[key]
+	return placement, ok
+}
+
+// Fit keeps the ads that have a creative fitting the placement, narrowing
+// each ad's creatives to those that fit. Unsized placements take any ad.
+func Fit(ads []models.Ad, placement models.Placement) []models.Ad {
+	if !placement.Sized() {
+		return ads
+	}
+	fitting := make([]models.Ad, 0, len(ads))
+	for _, ad := range ads {
+		var creatives []models.Creative
+		for _, creative := range ad.Creatives {
+			if creative.Active && placement.Fits(creative) {
+				creatives = append(creatives, creative)
+			}
+		}
+		if len(creatives) == 0 {
+			continue
+		}
+		ad.Creatives = creatives
+		fitting = append(fitting, ad)
+	}
+	return fitting
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PrivacyService executes data subject erasure and access export requests
+// in the background and audits each outcome. With an archiver set, erasure
+// also rewrites the archived partitions holding the subject's events.
+type PrivacyService struct {
+	repo     *repositories.PrivacyRepository
+	audit    *repositories.AuditRepository
+	archiver *Archiver
+	exports  archive.ObjectStore
+	logger   *logrus.Logger
+}
+
+func NewPrivacyService(repo *repositories.PrivacyRepository, audit *repositories.AuditRepository, logger *logrus.Logger) *PrivacyService {
+	return &PrivacyService{repo: repo, audit: audit, exports: archive.NewDirStore("exports"), logger: logger}
+}
+
+// SetExportStore changes where access export files are kept. It should be
+// shared by every replica, since any of them may serve the download.
+func (s *PrivacyService) SetExportStore(store archive.ObjectStore) {
+	s.exports = store
+}
+
+// ExportStore holds completed access exports under their FilePath.
+func (s *PrivacyService) ExportStore() archive.ObjectStore {
+	return s.exports
+}
+
+// SetArchiver extends erasure to events in cold storage.
+func (s *PrivacyService) SetArchiver(archiver *Archiver) {
+	s.archiver = archiver
+}
+
+// HashSubject is the identifier kept once a request completes. IP subjects
+// arrive already hashed and are stored as given.
+func HashSubject(subjectType, subject string) string {
+	if subjectType == models.SubjectIPHash {
+		return subject
+	}
+	sum := sha256.Sum256([]byte(subject))
+	return hex.EncodeToString(sum[:])
+}
+
+// Run performs the erasure or export and records the result. It is meant to be
+// started in its own goroutine.
+func (s *PrivacyService) Run(request *models.PrivacyRequest) {
+	log := s.logger.WithField("privacy_request_id", request.ID)
+
+	request.Status = models.PrivacyRunning
+	if err := s.repo.Save(request); err != nil {
+		log.WithError(err).Error("Failed to mark privacy request running")
+	}
+
+	var affected map[string]int64
+	var err error
+	if request.Mode == models.PrivacyExport {
+		affected, err = s.exportSubject(request)
+	} else {
+		affected, err = s.erase(request)
+	}
+	now := time.Now().UTC()
+	request.CompletedAt = &now
+	if err != nil {
+		log.WithError(err).Error("Privacy request failed")
+		request.Status = models.PrivacyFailed
+		request.Error = err.Error()
+	} else {
+		request.Status = models.PrivacyCompleted
+		request.Subject = ""
+		request.RowsAffected = 0
+		for _, rows := range affected {
+			request.RowsAffected += rows
+		}
+		details, _ := json.Marshal(affected)
+		request.Details = string(details)
+		log.WithField("rows", request.RowsAffected).Info("Privacy request completed")
+	}
+
+	if err := s.repo.Save(request); err != nil {
+		log.WithError(err).Error("Failed to save privacy request result")
+	}
+
+	if err := s.audit.Record("system", "privacy."+request.Status, "privacy_request", strconv.FormatUint(uint64(request.ID), 10), map[string]interface{}{
+		"subject_type":  request.SubjectType,
+		"subject_hash":  request.SubjectHash,
+		"mode":          request.Mode,
+		"rows_affected": affected,
+		"error":         request.Error,
+	}); err != nil {
+		log.WithError(err).Error("Failed to write privacy audit record")
+	}
+}
+
+// erase clears the subject from the database, then from archived
+// partitions, counting archived rows under "archive/<table>".
+func (s *PrivacyService) erase(request *models.PrivacyRequest) (map[string]int64, error) {
+	affected, err := s.repo.Erase(request.SubjectType, request.Subject, request.Mode)
+	if err != nil || s.archiver == nil {
+		return affected, err
+	}
+	archived, err := s.archiver.EraseSubject(context.Background(), request.SubjectType, request.Subject, request.Mode)
+	for table, rows := range archived {
+		affected["archive/"+table] = rows
+	}
+	return affected, err
+}
+
+// ResumeUnfinished restarts requests interrupted by a shutdown. Erasure is
+// idempotent and exports are rewritten from scratch, so re-running a
+// partially applied request is safe.
+func (s *PrivacyService) ResumeUnfinished() {
+	requests, err := s.repo.Unfinished()
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load unfinished privacy requests")
+		return
+	}
+	for i := range requests {
+		go s.Run(&requests[i])
+	}
+}
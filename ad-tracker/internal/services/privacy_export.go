@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+)
+
+// subjectExportColumns are the CSV columns of an access export. Each row
+// carries its full record as JSON since the source tables differ.
+var subjectExportColumns = []string{"table", "id", "ad_id", "time", "record"}
+
+// exportSubject writes every row tied to the request's subject to the
+// export store and returns the rows per table.
+func (s *PrivacyService) exportSubject(request *models.PrivacyRequest) (map[string]int64, error) {
+	tmp, err := os.CreateTemp("", fmt.Sprintf("dsar-%d-*.%s", request.ID, request.Format))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	var writer subjectWriter
+	contentType := "application/json"
+	if request.Format == models.ExportFormatCSV {
+		writer = newSubjectCSVWriter(tmp)
+		contentType = "text/csv; charset=utf-8"
+	} else {
+		writer = newSubjectJSONWriter(tmp, request)
+	}
+
+	counts, err := s.repo.StreamSubject(request.SubjectType, request.Subject, writer.Write)
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("dsar/dsar-%d.%s", request.ID, request.Format)
+	if err := s.exports.Put(context.Background(), key, tmp, contentType); err != nil {
+		return nil, fmt.Errorf("upload export: %w", err)
+	}
+
+	request.FilePath = key
+	return counts, nil
+}
+
+type subjectWriter interface {
+	Write(table string, row map[string]interface{}) error
+	Close() error
+}
+
+// subjectJSONWriter streams {"subject_type":…,"tables":{"click_events":[…]}}
+// without holding the rows in memory.
+type subjectJSONWriter struct {
+	w       io.Writer
+	request *models.PrivacyRequest
+	table   string
+	started bool
+	first   bool
+	err     error
+}
+
+func newSubjectJSONWriter(w io.Writer, request *models.PrivacyRequest) *subjectJSONWriter {
+	return &subjectJSONWriter{w: w, request: request}
+}
+
+func (j *subjectJSONWriter) printf(format string, args ...interface{}) {
+	if j.err == nil {
+		_, j.err = fmt.Fprintf(j.w, format, args...)
+	}
+}
+
+func (j *subjectJSONWriter) header() {
+	if j.started {
+		return
+	}
+	j.started = true
+	subjectType, _ := json.Marshal(j.request.SubjectType)
+	subjectHash, _ := json.Marshal(j.request.SubjectHash)
+	j.printf(`{"subject_type":%s,"subject_hash":%s,"generated_at":"%s","tables":{`,
+		subjectType, subjectHash, time.Now().UTC().Format(time.RFC3339))
+}
+
+func (j *subjectJSONWriter) Write(table string, row map[string]interface{}) error {
+	j.header()
+	if table != j.table {
+		if j.table != "" {
+			j.printf("],")
+		}
+		name, _ := json.Marshal(table)
+		j.printf("%s:[", name)
+		j.table, j.first = table, true
+	}
+
+	record, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if !j.first {
+		j.printf(",")
+	}
+	j.first = false
+	j.printf("%s", record)
+	return j.err
+}
+
+func (j *subjectJSONWriter) Close() error {
+	j.header()
+	if j.table != "" {
+		j.printf("]")
+	}
+	j.printf("}}\n")
+	return j.err
+}
+
+type subjectCSVWriter struct {
+	w *csv.Writer
+}
+
+func newSubjectCSVWriter(w io.Writer) *subjectCSVWriter {
+	writer := &subjectCSVWriter{w: csv.NewWriter(w)}
+	writer.w.Write(subjectExportColumns)
+	return writer
+}
+
+func (c *subjectCSVWriter) Write(table string, row map[string]interface{}) error {
+	record, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	var at string
+	if column := repositories.RetentionTables[table].TimeColumn; column != "" {
+		if t, ok := row[column].(time.Time); ok {
+			at = t.UTC().Format(time.RFC3339Nano)
+		}
+	}
+
+	return c.w.Write([]string{table, csvValue(row["id"]), csvValue(row["ad_id"]), at, string(record)})
+}
+
+func (c *subjectCSVWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
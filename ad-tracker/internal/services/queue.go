@@ -2,27 +2,60 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
 	"time"
 
+	"ad-tracking-system/internal/breaker"
+	"ad-tracking-system/internal/events"
 	"ad-tracking-system/internal/metrics"
 	"ad-tracking-system/internal/models"
 
 	"github.com/sirupsen/logrus"
-	"gorm.io/gorm"
 )
 
+// openCircuitWait bounds how long a batch waits for the database breaker to
+// close before it counts against the retries.
+const openCircuitWait = time.Minute
+
+// ClickEnqueuer is what ingestion handlers need from the click queue.
+type ClickEnqueuer interface {
+	// Enqueue returns false when the queue is full and the click was
+	// dropped.
+	Enqueue(event models.ClickEvent) bool
+	// Saturation is the fraction of the buffer in use, from 0 to 1.
+	Saturation() float64
+}
+
+var _ ClickEnqueuer = (*ClickQueue)(nil)
+
 type ClickQueue struct {
-	events chan models.ClickEvent
-	db     *gorm.DB
-	logger *logrus.Logger
+	events       chan models.ClickEvent
+	store        events.EventStore
+	logger       *logrus.Logger
+	batchSize    atomic.Int64
+	batchTimeout atomic.Int64
+	done         chan struct{}
 }
 
-func NewClickQueue(db *gorm.DB, logger *logrus.Logger, bufferSize int) *ClickQueue {
-	return &ClickQueue{
+func NewClickQueue(store events.EventStore, logger *logrus.Logger, bufferSize int) *ClickQueue {
+	q := &ClickQueue{
 		events: make(chan models.ClickEvent, bufferSize),
-		db:     db,
+		store:  store,
 		logger: logger,
+		done:   make(chan struct{}),
 	}
+	q.SetBatching(100, 5*time.Second)
+	return q
+}
+
+// SetBatching sets how many events are written per batch and how long a
+// partial batch waits. A running processor picks the change up with its
+// next batch.
+func (q *ClickQueue) SetBatching(size int, timeout time.Duration) {
+	q.batchSize.Store(int64(size))
+	q.batchTimeout.Store(int64(timeout))
 }
 
 func (q *ClickQueue) Enqueue(event models.ClickEvent) bool {
@@ -36,46 +69,79 @@ func (q *ClickQueue) Enqueue(event models.ClickEvent) bool {
 	}
 }
 
+// StartProcessor writes queued clicks in batches until ctx is cancelled,
+// then drains whatever is still buffered. Run it once.
 func (q *ClickQueue) StartProcessor(ctx context.Context) {
-	batchSize := 100
-	batchTimeout := 5 * time.Second
-	batch := make([]models.ClickEvent, 0, batchSize)
-	timer := time.NewTimer(batchTimeout)
+	defer close(q.done)
+	batch := make([]models.ClickEvent, 0, q.batchSize.Load())
+	timer := time.NewTimer(time.Duration(q.batchTimeout.Load()))
 
 	for {
 		select {
 		case <-ctx.Done():
-			// Process remaining events
-			if len(batch) > 0 {
-				q.processBatch(batch)
-			}
+			q.drain(batch)
 			return
 		case event := <-q.events:
 			batch = append(batch, event)
-			if len(batch) >= batchSize {
+			if int64(len(batch)) >= q.batchSize.Load() {
 				q.processBatch(batch)
 				batch = batch[:0]
-				timer.Reset(batchTimeout)
+				timer.Reset(time.Duration(q.batchTimeout.Load()))
 			}
 		case <-timer.C:
 			if len(batch) > 0 {
 				q.processBatch(batch)
 				batch = batch[:0]
 			}
-			timer.Reset(batchTimeout)
+			timer.Reset(time.Duration(q.batchTimeout.Load()))
+		}
+	}
+}
+
+// drain writes the partial batch and every event still in the buffer.
+func (q *ClickQueue) drain(batch []models.ClickEvent) {
+	for {
+		select {
+		case event := <-q.events:
+			batch = append(batch, event)
+			if int64(len(batch)) >= q.batchSize.Load() {
+				q.processBatch(batch)
+				batch = batch[:0]
+			}
+		default:
+			q.processBatch(batch)
+			return
 		}
 	}
 }
 
+// Wait blocks until the processor has drained and returned, or ctx ends.
+func (q *ClickQueue) Wait(ctx context.Context) error {
+	select {
+	case <-q.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d clicks still queued: %w", len(q.events), ctx.Err())
+	}
+}
+
 func (q *ClickQueue) processBatch(events []models.ClickEvent) {
 	if len(events) == 0 {
 		return
 	}
 
-	// Batch insert with retry logic
+	// Batch insert with retry logic. While the database breaker is open the
+	// batch is held (and the channel buffers new clicks) for up to
+	// openCircuitWait instead of burning the retries.
 	maxRetries := 3
+	heldUntil := time.Now().Add(openCircuitWait)
 	for i := 0; i < maxRetries; i++ {
-		if err := q.db.Create(&events).Error; err != nil {
+		if err := q.store.SaveClicks(context.Background(), events); err != nil {
+			if errors.Is(err, breaker.ErrOpen) && time.Now().Before(heldUntil) {
+				time.Sleep(time.Second)
+				i-- // rejected by the breaker, not a real attempt
+				continue
+			}
 			q.logger.WithError(err).Warnf("Failed to insert batch (attempt %d/%d)", i+1, maxRetries)
 			if i == maxRetries-1 {
 				q.logger.WithError(err).Error("Failed to insert click events after all retries")
@@ -89,6 +155,11 @@ func (q *ClickQueue) processBatch(events []models.ClickEvent) {
 	}
 }
 
+// Saturation reports how full the buffer is, from 0 to 1.
+func (q *ClickQueue) Saturation() float64 {
+	return float64(len(q.events)) / float64(cap(q.events))
+}
+
 func (q *ClickQueue) GetEvents() chan models.ClickEvent {
 	return q.events
 }
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// realtimeBucket is the resolution of the realtime counters: windows
+	// are counted to within one bucket
+	realtimeBucket = 10 * time.Second
+	// realtimeSpan is the longest window kept
+	realtimeSpan = time.Hour
+	// realtimeAdRefresh is how long ad to campaign lookups are trusted
+	realtimeAdRefresh = 5 * time.Minute
+)
+
+// RealtimeCounters keeps rolling per-ad and per-campaign event counts in
+// Redis for the last hour, in 10 second buckets that expire on their own,
+// and daily leaderboards of ads by clicks and CTR.
+// It is an events.Sink: an EventSink feeds it clicks from the event topic
+// and impressions from the database, so counts are current to within the
+// sink's flush interval without analytics touching the database. Delivery
+// is at-least-once, so a batch retried after a Redis error may be counted
+// twice.
+type RealtimeCounters struct {
+	client *redis.Client
+	prefix string
+	// minImpressions is how many impressions an ad needs in a day to be
+	// ranked by CTR
+	minImpressions int64
+	ads            *repositories.AdRepository
+	primary        *repositories.EventStore
+
+	mu        sync.Mutex
+	campaigns map[uint]uint // ad id to campaign id, 0 for none
+	loadedAt  time.Time
+}
+
+// NewRealtimeCounters keeps its keys under prefix in client. Campaigns of
+// ads are looked up in ads; primary supplies the first impression to count
+// from.
+func NewRealtimeCounters(client *redis.Client, prefix string, ads *repositories.AdRepository, primary *repositories.EventStore) *RealtimeCounters {
+	return &RealtimeCounters{
+		client:         client,
+		prefix:         prefix,
+		minImpressions: defaultLeaderboardMinImpressions,
+		ads:            ads,
+		primary:        primary,
+		campaigns:      make(map[uint]uint),
+	}
+}
+
+func (r *RealtimeCounters) SaveClicks(ctx context.Context, clicks []models.ClickEvent) error {
+	adTimes := make(map[uint][]time.Time)
+	for _, click := range clicks {
+		adTimes[click.AdID] = append(adTimes[click.AdID], click.Timestamp)
+	}
+	return r.add(ctx, "click", adTimes, nil)
+}
+
+// SaveImpressions also moves the impression cursor past the batch, in the
+// same round trip as the counts.
+func (r *RealtimeCounters) SaveImpressions(ctx context.Context, impressions []models.ImpressionEvent) error {
+	if len(impressions) == 0 {
+		return nil
+	}
+	adTimes := make(map[uint][]time.Time)
+	for _, impression := range impressions {
+		adTimes[impression.AdID] = append(adTimes[impression.AdID], impression.Timestamp)
+	}
+	lastID := impressions[len(impressions)-1].ID
+	return r.add(ctx, "impression", adTimes, func(pipe redis.Pipeliner) {
+		pipe.Set(ctx, r.prefix+"impressions:cursor", lastID, 0)
+	})
+}
+
+// MaxImpressionID resumes after the last impression counted. On the first
+// run it starts at the newest stored impression: older ones fall outside
+// every window anyway.
+func (r *RealtimeCounters) MaxImpressionID(ctx context.Context) (uint, error) {
+	id, err := r.client.Get(ctx, r.prefix+"impressions:cursor").Uint64()
+	if err == redis.Nil {
+		return r.primary.LastImpressionID(ctx)
+	}
+	return uint(id), err
+}
+
+// add counts events of one type per ad and per campaign, and ranks the ads
+// on the leaderboard of each event's day, in a single pipeline. Events
+// older than the longest window only reach the leaderboard, and events from
+// the future are counted now.
+func (r *RealtimeCounters) add(ctx context.Context, eventType string, adTimes map[uint][]time.Time, extra func(redis.Pipeliner)) error {
+	campaigns, err := r.campaignsOf(adTimes)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	counts := make(map[string]int64)
+	daily := make(map[leaderboardEntry]int64)
+	for adID, times := range adTimes {
+		for _, t := range times {
+			if t.After(now) {
+				t = now
+			}
+			if now.Sub(t) < leaderboardDays*24*time.Hour {
+				daily[leaderboardEntry{day: t.UTC().Format(time.DateOnly), adID: adID}]++
+			}
+			if now.Sub(t) >= realtimeSpan {
+				continue
+			}
+			counts[r.key("ad", adID, eventType, t)]++
+			if campaignID := campaigns[adID]; campaignID != 0 {
+				counts[r.key("campaign", campaignID, eventType, t)]++
+			}
+		}
+	}
+	if len(counts) == 0 && len(daily) == 0 && extra == nil {
+		return nil
+	}
+
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for key, count := range counts {
+			pipe.IncrBy(ctx, key, count)
+			pipe.Expire(ctx, key, realtimeSpan+2*realtimeBucket)
+		}
+		for entry, count := range daily {
+			r.rank(ctx, pipe, entry, eventType, count)
+		}
+		if extra != nil {
+			extra(pipe)
+		}
+		return nil
+	})
+	return err
+}
+
+// campaignsOf returns the campaign of each ad, looking up ads not seen
+// since the last refresh.
+func (r *RealtimeCounters) campaignsOf(adTimes map[uint][]time.Time) (map[uint]uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.loadedAt) > realtimeAdRefresh {
+		r.campaigns = make(map[uint]uint)
+		r.loadedAt = time.Now()
+	}
+	var missing []uint
+	for adID := range adTimes {
+		if _, ok := r.campaigns[adID]; !ok {
+			missing = append(missing, adID)
+		}
+	}
+	if len(missing) > 0 {
+		found, err := r.ads.CampaignIDs(missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, adID := range missing {
+			r.campaigns[adID] = found[adID]
+		}
+	}
+
+	campaigns := make(map[uint]uint, len(adTimes))
+	for adID := range adTimes {
+		campaigns[adID] = r.campaigns[adID]
+	}
+	return campaigns, nil
+}
+
+// AdCounts returns an ad's counts as of now.
+func (r *RealtimeCounters) AdCounts(ctx context.Context, adID uint) (models.RealtimeCounters, error) {
+	counters, err := r.counts(ctx, "ad", adID)
+	counters.AdID = adID
+	return counters, err
+}
+
+// CampaignCounts returns a campaign's counts as of now, covering its ads'
+// events since they were last assigned to it.
+func (r *RealtimeCounters) CampaignCounts(ctx context.Context, campaignID uint) (models.RealtimeCounters, error) {
+	counters, err := r.counts(ctx, "campaign", campaignID)
+	counters.CampaignID = campaignID
+	return counters, err
+}
+
+// counts reads every bucket of the last hour in one MGET and sums the
+// buckets overlapping each window.
+func (r *RealtimeCounters) counts(ctx context.Context, scope string, id uint) (models.RealtimeCounters, error) {
+	now := time.Now().UTC()
+	counters := models.RealtimeCounters{AsOf: now}
+
+	var starts []time.Time
+	for start := now.Add(-realtimeSpan).Truncate(realtimeBucket); !start.After(now); start = start.Add(realtimeBucket) {
+		starts = append(starts, start)
+	}
+	keys := make([]string, 0, 2*len(starts))
+	for _, start := range starts {
+		keys = append(keys, r.key(scope, id, "click", start), r.key(scope, id, "impression", start))
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return counters, err
+	}
+
+	windows := []struct {
+		length time.Duration
+		counts *models.RealtimeCounts
+	}{
+		{time.Minute, &counters.OneMinute},
+		{5 * time.Minute, &counters.FiveMinutes},
+		{time.Hour, &counters.OneHour},
+	}
+	for i, start := range starts {
+		clicks, impressions := redisInt(values[2*i]), redisInt(values[2*i+1])
+		for _, window := range windows {
+			if start.Add(realtimeBucket).After(now.Add(-window.length)) {
+				window.counts.Clicks += clicks
+				window.counts.Impressions += impressions
+			}
+		}
+	}
+	return counters, nil
+}
+
+func (r *RealtimeCounters) key(scope string, id uint, eventType string, t time.Time) string {
+	bucket := t.Truncate(realtimeBucket).Unix()
+	return r.prefix + scope + ":" + strconv.FormatUint(uint64(id), 10) + ":" + eventType + ":" + strconv.FormatInt(bucket, 10)
+}
+
+// redisInt reads an MGET value, 0 for a missing key.
+func redisInt(value interface{}) int64 {
+	text, ok := value.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(text, 10, 64)
+	return n
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// Replicator mirrors the local event topic into the standby region's Kafka.
+// It only copies while this region is primary, so after a failover the newly
+// promoted region starts feeding the old one once it is demoted and back.
+//
+// Promotion procedure (standby -> primary):
+//
+//  1. Fence the failed primary: stop its ingress (DNS/GSLB weight to zero)
+//     and, if it is reachable, POST /api/v1/admin/replication/demote there so
+//     it stops replicating.
+//  2. On the standby, check GET /api/v1/admin/replication and the
+//     replication_lag_messages metric. Anything still lagging on the old
+//     primary is lost unless that region comes back and is replayed.
+//  3. POST /api/v1/admin/replication/promote on the standby. Its role is
+//     persisted, so restarts keep it primary.
+//  4. Point ingress at the promoted region.
+//  5. When the old region recovers, leave it demoted; it now receives
+//     replicated events from the new primary. Fail back by repeating these
+//     steps in the opposite direction.
+//
+// Replicated messages carry the region they were first produced in under
+// OriginRegionHeader. Only messages produced in this region are forwarded,
+// so events mirrored in from the other region are never sent back to it
+// and counted twice.
+type Replicator struct {
+	reader *kafka.Reader
+	writer *kafka.Writer
+	repo   *repositories.ReplicationRepository
+	region string
+	target string
+	logger *logrus.Logger
+
+	mu               sync.RWMutex
+	state            models.ReplicationState
+	lastReplicatedAt time.Time
+	replicated       atomic.Int64
+	running          atomic.Bool
+}
+
+// OriginRegionHeader names the region a replicated message was produced in.
+const OriginRegionHeader = "origin-region"
+
+func NewReplicator(reader *kafka.Reader, writer *kafka.Writer, repo *repositories.ReplicationRepository, region, target string, logger *logrus.Logger) *Replicator {
+	return &Replicator{
+		reader: reader,
+		writer: writer,
+		repo:   repo,
+		region: region,
+		target: target,
+		logger: logger,
+	}
+}
+
+// LoadState reads the persisted role for this region.
+func (r *Replicator) LoadState(defaultRole string) error {
+	state, err := r.repo.State(r.region, defaultRole)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+	return nil
+}
+
+// SetRole persists a promotion or demotion.
+func (r *Replicator) SetRole(role, changedBy string) (models.ReplicationState, error) {
+	state, err := r.repo.SetRole(r.region, role, changedBy)
+	if err != nil {
+		return state, err
+	}
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+
+	r.logger.WithFields(logrus.Fields{
+		"region":     r.region,
+		"role":       role,
+		"changed_by": changedBy,
+	}).Warn("Replication role changed")
+	return state, nil
+}
+
+func (r *Replicator) Status() models.ReplicationStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	status := models.ReplicationStatus{
+		ReplicationState: r.state,
+		Target:           r.target,
+		Running:          r.running.Load(),
+		LagMessages:      r.reader.Stats().Lag,
+		Replicated:       r.replicated.Load(),
+	}
+	if !r.lastReplicatedAt.IsZero() {
+		last := r.lastReplicatedAt
+		status.LastReplicatedAt = &last
+	}
+	return status
+}
+
+func (r *Replicator) isPrimary() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.state.Role == models.RolePrimary
+}
+
+// Run copies messages until ctx is cancelled. Offsets are committed only
+// after the standby acknowledged the write, so delivery is at-least-once.
+func (r *Replicator) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		if !r.isPrimary() {
+			r.running.Store(false)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
+		r.running.Store(true)
+
+		fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		msg, err := r.reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				r.logger.WithError(err).Warn("Replication fetch failed")
+			}
+			r.observeLag()
+			continue
+		}
+
+		if origin := originRegion(msg); origin != "" && origin != r.region {
+			if err := r.reader.CommitMessages(ctx, msg); err != nil {
+				r.logger.WithError(err).Warn("Failed to commit skipped offset")
+			}
+			metrics.ReplicationSkipped.Inc()
+			r.observeLag()
+			continue
+		}
+		if err := r.forward(ctx, msg); err != nil {
+			if ctx.Err() == nil {
+				r.logger.WithError(err).Error("Failed to replicate message to standby")
+			}
+			continue
+		}
+		if err := r.reader.CommitMessages(ctx, msg); err != nil {
+			r.logger.WithError(err).Warn("Failed to commit replicated offset")
+		}
+
+		r.replicated.Add(1)
+		r.mu.Lock()
+		r.lastReplicatedAt = msg.Time
+		r.mu.Unlock()
+		metrics.ReplicationMessages.Inc()
+		metrics.ReplicationLastTimestamp.Set(float64(msg.Time.Unix()))
+		r.observeLag()
+	}
+	r.running.Store(false)
+}
+
+// originRegion returns the region a message was replicated from, or "" for
+// one produced locally.
+func originRegion(msg kafka.Message) string {
+	for _, header := range msg.Headers {
+		if header.Key == OriginRegionHeader {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// forward retries the write until it succeeds so offsets never move past an
+// unreplicated message. The copy is stamped with this region as its origin.
+func (r *Replicator) forward(ctx context.Context, msg kafka.Message) error {
+	headers := msg.Headers
+	if originRegion(msg) == "" {
+		headers = append(slices.Clip(headers), kafka.Header{Key: OriginRegionHeader, Value: []byte(r.region)})
+	}
+	backoff := time.Second
+	for {
+		err := r.writer.WriteMessages(ctx, kafka.Message{
+			Key:     msg.Key,
+			Value:   msg.Value,
+			Headers: headers,
+			Time:    msg.Time,
+		})
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		r.logger.WithError(err).WithField("retry_in", backoff).Warn("Standby write failed")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+func (r *Replicator) observeLag() {
+	metrics.ReplicationLag.Set(float64(r.reader.Stats().Lag))
+}
+
+func (r *Replicator) Close() error {
+	return errors.Join(r.reader.Close(), r.writer.Close())
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/archive"
+	"ad-tracking-system/internal/cron"
+	"ad-tracking-system/internal/k8s"
+	"ad-tracking-system/internal/mailer"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+var reportCSVColumns = []string{
+	"campaign_id", "campaign", "ad_id", "clicks", "impressions", "ctr", "last_hour", "last_day", "viewable_rate",
+}
+
+// reportWindows are the summary windows a schedule may use.
+var reportWindows = map[string]time.Duration{
+	"1h":  time.Hour,
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// ReportScheduler renders scheduled campaign summaries and delivers them to
+// object storage or by email. Failed deliveries are retried with
+// exponential backoff. Only the elected replica runs schedules.
+type ReportScheduler struct {
+	repo        *repositories.ReportRepository
+	analytics   repositories.AnalyticsReader
+	campaigns   *repositories.CampaignRepository
+	storeConfig archive.StoreConfig
+	mailer      *mailer.Mailer
+	elector     k8s.Elector
+	maxAttempts int
+	backoff     time.Duration
+	logger      *logrus.Logger
+}
+
+func NewReportScheduler(repo *repositories.ReportRepository, analytics repositories.AnalyticsReader, campaigns *repositories.CampaignRepository, logger *logrus.Logger) *ReportScheduler {
+	return &ReportScheduler{
+		repo:        repo,
+		analytics:   analytics,
+		campaigns:   campaigns,
+		elector:     k8s.AlwaysLeader{},
+		maxAttempts: 3,
+		backoff:     5 * time.Minute,
+		logger:      logger,
+	}
+}
+
+func (r *ReportScheduler) SetElector(elector k8s.Elector) {
+	r.elector = elector
+}
+
+// SetDelivery configures the credentials used for object store and email
+// destinations.
+func (r *ReportScheduler) SetDelivery(storeConfig archive.StoreConfig, m *mailer.Mailer) {
+	r.storeConfig = storeConfig
+	r.mailer = m
+}
+
+// SetRetryPolicy limits attempts per run; retry n waits backoff*2^(n-1).
+func (r *ReportScheduler) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+	r.maxAttempts = maxAttempts
+	r.backoff = backoff
+}
+
+// NextRun computes the schedule's next run after t in its timezone.
+func NextRun(schedule *models.ReportSchedule, t time.Time) (time.Time, error) {
+	parsed, err := cron.Parse(schedule.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	location, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone %q", schedule.Timezone)
+	}
+	next := parsed.Next(t.In(location))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", schedule.Cron)
+	}
+	return next.UTC(), nil
+}
+
+// ValidateDestination checks that a destination is a mailto: list or an
+// object store URL we can open.
+func (r *ReportScheduler) ValidateDestination(destination string) error {
+	if addresses, ok := strings.CutPrefix(destination, "mailto:"); ok {
+		_, err := mailRecipients(addresses)
+		return err
+	}
+	_, err := archive.OpenStore(destination, r.storeConfig)
+	return err
+}
+
+func mailRecipients(addresses string) ([]string, error) {
+	var recipients []string
+	for _, address := range strings.Split(addresses, ",") {
+		parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+		if err != nil {
+			return nil, fmt.Errorf("invalid email address %q", address)
+		}
+		recipients = append(recipients, parsed.Address)
+	}
+	return recipients, nil
+}
+
+// Run checks for due schedules and retries every interval until ctx is
+// cancelled.
+func (r *ReportScheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if r.elector.IsLeader() {
+			r.runDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *ReportScheduler) runDue(ctx context.Context) {
+	now := time.Now().UTC()
+
+	schedules, err := r.repo.DueSchedules(now)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to load due report schedules")
+		return
+	}
+	for i := range schedules {
+		schedule := &schedules[i]
+		scheduledAt := *schedule.NextRunAt
+
+		next, err := NextRun(schedule, now)
+		if err != nil {
+			r.logger.WithError(err).WithField("schedule_id", schedule.ID).Error("Disabling report schedule")
+			schedule.Active = false
+		} else {
+			schedule.NextRunAt = &next
+		}
+		schedule.LastRunAt = &now
+		if err := r.repo.SaveSchedule(schedule); err != nil {
+			r.logger.WithError(err).WithField("schedule_id", schedule.ID).Error("Failed to advance report schedule")
+			continue
+		}
+
+		run := &models.ReportRun{ScheduleID: schedule.ID, Status: models.ReportRunPending, ScheduledAt: scheduledAt}
+		if err := r.repo.CreateRun(run); err != nil {
+			r.logger.WithError(err).WithField("schedule_id", schedule.ID).Error("Failed to create report run")
+			continue
+		}
+		r.execute(ctx, schedule, run)
+	}
+
+	runs, err := r.repo.RetryableRuns(now)
+	if err != nil {
+		r.logger.WithError(err).Error("Failed to load report retries")
+		return
+	}
+	for i := range runs {
+		schedule, err := r.repo.GetSchedule(runs[i].ScheduleID)
+		if err != nil {
+			r.logger.WithError(err).WithField("report_run_id", runs[i].ID).Error("Failed to load schedule for retry")
+			continue
+		}
+		r.execute(ctx, schedule, &runs[i])
+	}
+}
+
+// RunNow delivers a schedule immediately, outside its cron timing.
+func (r *ReportScheduler) RunNow(schedule *models.ReportSchedule) (*models.ReportRun, error) {
+	run := &models.ReportRun{ScheduleID: schedule.ID, Status: models.ReportRunPending, ScheduledAt: time.Now().UTC()}
+	if err := r.repo.CreateRun(run); err != nil {
+		return nil, err
+	}
+	go r.execute(context.Background(), schedule, run)
+	return run, nil
+}
+
+func (r *ReportScheduler) execute(ctx context.Context, schedule *models.ReportSchedule, run *models.ReportRun) {
+	log := r.logger.WithFields(logrus.Fields{"schedule_id": schedule.ID, "report_run_id": run.ID})
+
+	run.Attempts++
+	run.RetryAt = nil
+	location, err := r.deliver(ctx, schedule, run)
+	now := time.Now().UTC()
+	if err != nil {
+		run.Status = models.ReportRunFailed
+		run.Error = err.Error()
+		if run.Attempts < r.maxAttempts {
+			retryAt := now.Add(r.backoff << (run.Attempts - 1))
+			run.RetryAt = &retryAt
+		}
+		log.WithError(err).WithField("attempt", run.Attempts).Warn("Report delivery failed")
+	} else {
+		run.Status = models.ReportRunCompleted
+		run.Location = location
+		run.Error = ""
+		run.CompletedAt = &now
+		log.WithField("location", location).Info("Report delivered")
+	}
+	metrics.ReportRuns.WithLabelValues(run.Status).Inc()
+
+	if err := r.repo.SaveRun(run); err != nil {
+		log.WithError(err).Error("Failed to save report run")
+	}
+}
+
+func (r *ReportScheduler) deliver(ctx context.Context, schedule *models.ReportSchedule, run *models.ReportRun) (string, error) {
+	body, contentType, err := r.render(schedule)
+	if err != nil {
+		return "", fmt.Errorf("render: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.%s", reportSlug(schedule.Name), run.ScheduledAt.Format("2006-01-02T1504"), schedule.Format)
+
+	if addresses, ok := strings.CutPrefix(schedule.Destination, "mailto:"); ok {
+		recipients, err := mailRecipients(addresses)
+		if err != nil {
+			return "", err
+		}
+		err = r.mailer.Send(recipients,
+			fmt.Sprintf("%s (%s)", schedule.Name, run.ScheduledAt.Format("2006-01-02")),
+			fmt.Sprintf("Campaign summary for the last %s is attached.\r\n", schedule.Timeframe),
+			&mailer.Attachment{Name: name, ContentType: contentType, Data: body},
+		)
+		if err != nil {
+			return "", err
+		}
+		return schedule.Destination, nil
+	}
+
+	store, err := archive.OpenStore(schedule.Destination, r.storeConfig)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("reports/%d/%s", schedule.ID, name)
+	if err := store.Put(ctx, key, bytes.NewReader(body), contentType); err != nil {
+		return "", err
+	}
+	return store.Location(key), nil
+}
+
+// render builds the summaries of every campaign owned by the account.
+func (r *ReportScheduler) render(schedule *models.ReportSchedule) ([]byte, string, error) {
+	window, ok := reportWindows[schedule.Timeframe]
+	if !ok {
+		return nil, "", fmt.Errorf("unsupported timeframe %q", schedule.Timeframe)
+	}
+	since := time.Now().UTC().Add(-window)
+
+	campaigns, err := r.repo.AccountCampaigns(schedule.AccountID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	summaries := make([]models.CampaignSummary, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		adIDs, err := r.campaigns.AdIDs(campaign.ID)
+		if err != nil {
+			return nil, "", err
+		}
+		summaries = append(summaries, r.analytics.GetCampaignSummary(campaign, adIDs, since, schedule.ValidOnly))
+	}
+
+	if schedule.Format == "json" {
+		body, err := json.MarshalIndent(map[string]interface{}{
+			"schedule":     schedule.Name,
+			"account_id":   schedule.AccountID,
+			"generated_at": time.Now().UTC(),
+			"since":        since,
+			"valid_only":   schedule.ValidOnly,
+			"campaigns":    summaries,
+		}, "", "  ")
+		return body, "application/json", err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(reportCSVColumns)
+	for _, summary := range summaries {
+		for _, ad := range summary.Ads {
+			viewableRate := ""
+			if ad.Viewability != nil {
+				viewableRate = strconv.FormatFloat(ad.Viewability.ViewableRate, 'f', -1, 64)
+			}
+			w.Write([]string{
+				strconv.FormatUint(uint64(summary.CampaignID), 10),
+				summary.Name,
+				strconv.FormatUint(uint64(ad.AdID), 10),
+				strconv.FormatInt(ad.ClickCount, 10),
+				strconv.FormatInt(ad.Impressions, 10),
+				strconv.FormatFloat(ad.CTR, 'f', -1, 64),
+				strconv.FormatInt(ad.LastHour, 10),
+				strconv.FormatInt(ad.LastDay, 10),
+				viewableRate,
+			})
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), "text/csv; charset=utf-8", w.Error()
+}
+
+func reportSlug(name string) string {
+	slug := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '-'
+		}
+	}, name)
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		return "report"
+	}
+	return slug
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"ad-tracking-system/internal/k8s"
+	"ad-tracking-system/internal/metrics"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	RetentionDelete    = "delete"
+	RetentionAnonymize = "anonymize"
+)
+
+// RetentionPolicy ages out rows of one table after MaxAge.
+type RetentionPolicy struct {
+	Table  string
+	MaxAge time.Duration
+	Action string
+}
+
+// ParseRetentionPolicies reads a comma separated list of
+// table=maxAge[:action] entries, e.g.
+// "click_events=2160h:anonymize,captured_requests=168h". The action defaults
+// to delete.
+func ParseRetentionPolicies(spec string) ([]RetentionPolicy, error) {
+	var policies []RetentionPolicy
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		table, rest, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("retention policy %q: expected table=maxAge[:action]", entry)
+		}
+		age, action, _ := strings.Cut(rest, ":")
+		if action == "" {
+			action = RetentionDelete
+		}
+
+		maxAge, err := time.ParseDuration(age)
+		if err != nil || maxAge <= 0 {
+			return nil, fmt.Errorf("retention policy %q: invalid max age %q", entry, age)
+		}
+		if _, ok := repositories.RetentionTables[table]; !ok {
+			return nil, fmt.Errorf("retention policy %q: unknown table %q", entry, table)
+		}
+		if action != RetentionDelete && action != RetentionAnonymize {
+			return nil, fmt.Errorf("retention policy %q: action must be delete or anonymize", entry)
+		}
+
+		policies = append(policies, RetentionPolicy{Table: table, MaxAge: maxAge, Action: action})
+	}
+	return policies, nil
+}
+
+// RetentionJob periodically deletes or anonymizes rows past their retention
+// period in bounded batches. Only the elected replica purges.
+type RetentionJob struct {
+	repo      *repositories.RetentionRepository
+	policies  []RetentionPolicy
+	batchSize int
+	dryRun    bool
+	elector   k8s.Elector
+	archiver  *Archiver
+	logger    *logrus.Logger
+}
+
+func NewRetentionJob(repo *repositories.RetentionRepository, policies []RetentionPolicy, batchSize int, dryRun bool, logger *logrus.Logger) *RetentionJob {
+	return &RetentionJob{
+		repo:      repo,
+		policies:  policies,
+		batchSize: batchSize,
+		dryRun:    dryRun,
+		elector:   k8s.AlwaysLeader{},
+		logger:    logger,
+	}
+}
+
+func (j *RetentionJob) SetElector(elector k8s.Elector) {
+	j.elector = elector
+}
+
+// SetArchiver makes deletes of archivable tables wait for cold storage:
+// rows are only deleted once their whole day has been exported. Anonymize
+// policies are not archived, since that would keep the PII they remove.
+func (j *RetentionJob) SetArchiver(archiver *Archiver) {
+	j.archiver = archiver
+}
+
+// Run purges every interval until ctx is cancelled.
+func (j *RetentionJob) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce applies every policy once.
+func (j *RetentionJob) RunOnce(ctx context.Context) {
+	if !j.elector.IsLeader() {
+		return
+	}
+
+	for _, policy := range j.policies {
+		if ctx.Err() != nil {
+			return
+		}
+		j.apply(ctx, policy)
+	}
+	metrics.RetentionLastRun.SetToCurrentTime()
+}
+
+func (j *RetentionJob) apply(ctx context.Context, policy RetentionPolicy) {
+	table := repositories.RetentionTables[policy.Table]
+	cutoff := time.Now().UTC().Add(-policy.MaxAge)
+	fields := logrus.Fields{"table": policy.Table, "action": policy.Action, "cutoff": cutoff}
+
+	if j.archiver != nil && policy.Action == RetentionDelete && isArchivedTable(policy.Table) && !j.dryRun {
+		archived, err := j.archiver.ArchiveThrough(ctx, policy.Table, cutoff)
+		if err != nil {
+			j.logger.WithError(err).WithFields(fields).Error("Archiving before purge failed")
+		}
+		if archived.Before(cutoff) {
+			cutoff = archived
+			fields["cutoff"] = cutoff
+		}
+	}
+
+	if j.dryRun {
+		eligible, err := j.repo.CountExpired(table, cutoff, policy.Action == RetentionAnonymize)
+		if err != nil {
+			j.logger.WithError(err).WithFields(fields).Error("Failed to count expired rows")
+			return
+		}
+		metrics.RetentionRowsEligible.WithLabelValues(policy.Table).Set(float64(eligible))
+		j.logger.WithFields(fields).WithField("eligible", eligible).Info("Retention dry run")
+		return
+	}
+
+	var total int64
+	for ctx.Err() == nil {
+		var affected int64
+		var err error
+		if policy.Action == RetentionAnonymize {
+			affected, err = j.repo.AnonymizeBatch(table, cutoff, j.batchSize)
+		} else {
+			affected, err = j.repo.DeleteBatch(table, cutoff, j.batchSize)
+		}
+		if err != nil {
+			j.logger.WithError(err).WithFields(fields).Error("Retention batch failed")
+			break
+		}
+
+		total += affected
+		metrics.RetentionRowsPurged.WithLabelValues(policy.Table, policy.Action).Add(float64(affected))
+		if affected < int64(j.batchSize) {
+			break
+		}
+		// Give the database room between batches
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if total > 0 {
+		j.logger.WithFields(fields).WithField("rows", total).Info("Retention purge completed")
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"ad-tracking-system/internal/k8s"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RollupAggregator keeps the hourly and daily rollup rows current. Each run
+// rebuilds complete hours from the last covered hour minus lookback, so
+// late events and erasures inside the lookback are picked up. The first run
+// backfills from the oldest event, one day per transaction.
+type RollupAggregator struct {
+	repo     *repositories.RollupRepository
+	lookback time.Duration
+	elector  k8s.Elector
+	logger   *logrus.Logger
+}
+
+// rollupStoreLag covers events received before a run but stored after it.
+const rollupStoreLag = time.Hour
+
+// RollupLookback is the lookback for a timestamp policy: configured, raised
+// so that every hour the policy still accepts events for is rebuilt. An
+// event may claim a time MaxAge before its receipt, so a shorter lookback
+// leaves it out of the rollups for good. bounded is false when the policy
+// accepts any age and no lookback covers every late event.
+func RollupLookback(policy models.TimestampPolicy, configured time.Duration) (lookback time.Duration, bounded bool) {
+	if policy.MaxAge <= 0 {
+		return configured, false
+	}
+	return max(configured, policy.MaxAge+rollupStoreLag), true
+}
+
+func NewRollupAggregator(repo *repositories.RollupRepository, lookback time.Duration, logger *logrus.Logger) *RollupAggregator {
+	return &RollupAggregator{
+		repo:     repo,
+		lookback: lookback,
+		elector:  k8s.AlwaysLeader{},
+		logger:   logger,
+	}
+}
+
+func (a *RollupAggregator) SetElector(elector k8s.Elector) {
+	a.elector = elector
+}
+
+// Run aggregates every interval until ctx is cancelled.
+func (a *RollupAggregator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.RunOnce(ctx); err != nil {
+			a.logger.WithError(err).Error("Rollup aggregation failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce brings the rollups up to the last complete hour.
+func (a *RollupAggregator) RunOnce(ctx context.Context) error {
+	if !a.elector.IsLeader() {
+		return nil
+	}
+
+	to := time.Now().UTC().Truncate(time.Hour)
+	covered, err := a.repo.Coverage()
+	if err != nil {
+		return err
+	}
+
+	from := covered.Add(-a.lookback).Truncate(time.Hour)
+	if covered.IsZero() {
+		earliest, ok, err := a.repo.EarliestEvent()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return a.repo.SetCoverage(to)
+		}
+		from = earliest.UTC().Truncate(time.Hour)
+		a.logger.WithField("from", from).Info("Backfilling event rollups")
+	}
+
+	if err := a.Rebuild(ctx, from, to); err != nil {
+		return err
+	}
+	if err := a.repo.SetCoverage(to); err != nil {
+		return err
+	}
+	metrics.RollupCoveredUntil.Set(float64(to.Unix()))
+	return nil
+}
+
+// Rebuild recomputes the hourly rows for [from, to), both hour aligned,
+// and the daily rows of every day touched, one day per transaction.
+func (a *RollupAggregator) Rebuild(ctx context.Context, from, to time.Time) error {
+	for day := from.Truncate(24 * time.Hour); day.Before(to); day = day.Add(24 * time.Hour) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		start, end := day, day.Add(24*time.Hour)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if err := a.repo.RollupHours(start, end); err != nil {
+			return err
+		}
+		if err := a.repo.RollupDays(day, day.Add(24*time.Hour)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/models"
+)
+
+func TestRollupLookback(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAge      time.Duration
+		configured  time.Duration
+		want        time.Duration
+		wantBounded bool
+	}{
+		{"raised to the max age", 24 * time.Hour, 3 * time.Hour, 25 * time.Hour, true},
+		{"longer lookback kept", 24 * time.Hour, 48 * time.Hour, 48 * time.Hour, true},
+		{"short max age", 30 * time.Minute, 3 * time.Hour, 3 * time.Hour, true},
+		{"unbounded max age", 0, 3 * time.Hour, 3 * time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, bounded := RollupLookback(models.TimestampPolicy{MaxAge: tt.maxAge}, tt.configured)
+			if got != tt.want || bounded != tt.wantBounded {
+				t.Errorf("RollupLookback(%v, %v) = %v, %v, want %v, %v", tt.maxAge, tt.configured, got, bounded, tt.want, tt.wantBounded)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"ad-tracking-system/internal/cache"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sessionMemory is how long a session's last activity is remembered at
+// least, so a client resending an expired id gets a new one instead of
+// restarting the old session.
+const sessionMemory = 24 * time.Hour
+
+// SessionTracker groups a client's events into sessions that end after
+// timeout without activity.
+type SessionTracker struct {
+	store   cache.Cache
+	timeout time.Duration
+	logger  *logrus.Logger
+}
+
+func NewSessionTracker(store cache.Cache, timeout time.Duration, logger *logrus.Logger) *SessionTracker {
+	return &SessionTracker{store: store, timeout: timeout, logger: logger}
+}
+
+func (t *SessionTracker) Timeout() time.Duration {
+	return t.timeout
+}
+
+// Touch records activity in the session the client presented and returns
+// the id to use: the same one while it is live, or a new one when id is
+// empty or the session timed out. Ids we have never seen are accepted as
+// new sessions so clients may generate their own. Cache failures keep the
+// presented id rather than splitting sessions.
+func (t *SessionTracker) Touch(ctx context.Context, id string, now time.Time) string {
+	if id != "" {
+		raw, ok, err := t.store.Get(ctx, sessionKey(id))
+		if err != nil {
+			t.logger.WithError(err).Warn("Failed to read session activity")
+			return id
+		}
+		if ok {
+			last, err := strconv.ParseInt(string(raw), 10, 64)
+			if err == nil && now.Sub(time.UnixMilli(last)) > t.timeout {
+				id = ""
+			}
+		}
+	}
+	if id == "" {
+		var err error
+		if id, err = newSessionID(); err != nil {
+			t.logger.WithError(err).Error("Failed to generate session id")
+			return ""
+		}
+	}
+
+	ttl := max(sessionMemory, 2*t.timeout)
+	if err := t.store.Set(ctx, sessionKey(id), []byte(strconv.FormatInt(now.UnixMilli(), 10)), ttl); err != nil {
+		t.logger.WithError(err).Warn("Failed to record session activity")
+	}
+	return id
+}
+
+func sessionKey(id string) string {
+	return "session:" + id
+}
+
+func newSessionID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"ad-tracking-system/internal/k8s"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Sessionizer builds session summaries from raw events once sessions have
+// ended, i.e. timeout after their last event. Each run covers the events
+// since the last covered time minus timeout, so sessions that were still
+// running then are finished now. The first run backfills from the oldest
+// event with a session, one day at a time. Only the elected replica runs.
+type Sessionizer struct {
+	repo    *repositories.SessionRepository
+	timeout time.Duration
+	elector k8s.Elector
+	logger  *logrus.Logger
+}
+
+func NewSessionizer(repo *repositories.SessionRepository, timeout time.Duration, logger *logrus.Logger) *Sessionizer {
+	return &Sessionizer{
+		repo:    repo,
+		timeout: timeout,
+		elector: k8s.AlwaysLeader{},
+		logger:  logger,
+	}
+}
+
+func (s *Sessionizer) SetElector(elector k8s.Elector) {
+	s.elector = elector
+}
+
+// Run builds sessions every interval until ctx is cancelled.
+func (s *Sessionizer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunOnce(ctx); err != nil {
+			s.logger.WithError(err).Error("Sessionization failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce builds the sessions that ended since the last run.
+func (s *Sessionizer) RunOnce(ctx context.Context) error {
+	if !s.elector.IsLeader() {
+		return nil
+	}
+
+	until := time.Now().UTC().Add(-s.timeout)
+	covered, err := s.repo.Coverage()
+	if err != nil {
+		return err
+	}
+	start := covered
+	if covered.IsZero() {
+		earliest, ok, err := s.repo.EarliestEvent()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return s.repo.SetCoverage(until)
+		}
+		start = earliest.UTC()
+		s.logger.WithField("from", start).Info("Backfilling sessions")
+	}
+
+	for start.Before(until) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		end := start.Add(24 * time.Hour)
+		if end.After(until) {
+			end = until
+		}
+		built, err := s.repo.Build(start.Add(-s.timeout), end)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.SetCoverage(end); err != nil {
+			return err
+		}
+		s.logger.WithFields(logrus.Fields{"from": start, "until": end, "sessions": built}).Debug("Built sessions")
+		start = end
+	}
+	return nil
+}
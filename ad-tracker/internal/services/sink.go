@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"ad-tracking-system/internal/events"
+	"ad-tracking-system/internal/k8s"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// EventSink copies events into a sink such as ClickHouse or BigQuery.
+// Clicks are consumed from the event topic in batches, committing offsets
+// only after the sink accepted them. Impressions are not published to
+// Kafka, so the elected replica tails the impressions table by id instead.
+// Delivery is at-least-once; sinks collapse or tolerate duplicates.
+//
+// Impression ids are allocated before their transaction commits, so a row
+// can become visible after higher ids were copied. Ids the tail skips are
+// checked again on every pass until the lookback has passed, and a replica
+// taking over copies the lookback again before carrying on.
+//
+// Events flagged invalid after they were copied (honeypot IP flags) keep
+// their original flag in the sink.
+type EventSink struct {
+	name      string
+	reader    *kafka.Reader
+	sink      events.Sink
+	primary   *repositories.EventStore
+	batchSize int
+	lookback  time.Duration
+	elector   k8s.Elector
+	logger    *logrus.Logger
+
+	// Owned by tailImpressions
+	tail impressionTail
+}
+
+// impressionTail is the elected replica's position in the impressions
+// table.
+type impressionTail struct {
+	loaded bool
+	lastID uint
+	// gaps are ids skipped over, by when they were first missed
+	gaps map[uint]time.Time
+}
+
+// maxImpressionGap bounds the jumps between ids that are tracked as gaps.
+// In-flight inserts never leave one this wide; deleted rows and sequence
+// jumps do.
+const maxImpressionGap = 10000
+
+// defaultImpressionLookback comfortably exceeds the longest insert
+// transaction.
+const defaultImpressionLookback = 5 * time.Minute
+
+// NewEventSink reads clicks with reader, which needs a consumer group of
+// its own per sink. name labels logs and metrics.
+func NewEventSink(name string, reader *kafka.Reader, sink events.Sink, primary *repositories.EventStore, batchSize int, logger *logrus.Logger) *EventSink {
+	return &EventSink{
+		name:      name,
+		reader:    reader,
+		sink:      sink,
+		primary:   primary,
+		batchSize: batchSize,
+		lookback:  defaultImpressionLookback,
+		elector:   k8s.AlwaysLeader{},
+		logger:    logger,
+	}
+}
+
+func (s *EventSink) SetElector(elector k8s.Elector) {
+	s.elector = elector
+}
+
+// SetImpressionLookback sets how long impressions committed out of id order
+// are waited for.
+func (s *EventSink) SetImpressionLookback(lookback time.Duration) {
+	s.lookback = lookback
+}
+
+// Run copies events until ctx is cancelled, flushing partial batches every
+// interval.
+func (s *EventSink) Run(ctx context.Context, interval time.Duration) {
+	go s.tailImpressions(ctx, interval)
+
+	for ctx.Err() == nil {
+		messages := s.fetchBatch(ctx, interval)
+		if len(messages) == 0 {
+			continue
+		}
+
+		clicks := make([]models.ClickEvent, 0, len(messages))
+		for _, msg := range messages {
+			click, err := events.DecodeClick(msg.Value)
+			if err != nil {
+				s.logger.WithError(err).WithFields(logrus.Fields{"sink": s.name, "offset": msg.Offset}).Warn("Skipping undecodable click event")
+				continue
+			}
+			clicks = append(clicks, click)
+		}
+
+		if err := s.write(ctx, "click_events", func() error { return s.sink.SaveClicks(ctx, clicks) }, len(clicks)); err != nil {
+			continue
+		}
+		if err := s.reader.CommitMessages(ctx, messages...); err != nil {
+			s.logger.WithError(err).WithField("sink", s.name).Warn("Failed to commit sink offsets")
+		}
+	}
+}
+
+// fetchBatch collects up to batchSize messages, returning early once
+// interval passes.
+func (s *EventSink) fetchBatch(ctx context.Context, interval time.Duration) []kafka.Message {
+	fetchCtx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	var messages []kafka.Message
+	for len(messages) < s.batchSize {
+		msg, err := s.reader.FetchMessage(fetchCtx)
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				s.logger.WithError(err).WithField("sink", s.name).Warn("Sink fetch failed")
+			}
+			break
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func (s *EventSink) tailImpressions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if s.elector.IsLeader() {
+			if err := s.copyImpressions(ctx); err != nil && ctx.Err() == nil {
+				s.logger.WithError(err).WithField("sink", s.name).Warn("Failed to copy impressions")
+			}
+		} else {
+			// Another replica moves the sink on meanwhile
+			s.tail = impressionTail{}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// copyImpressions copies the impressions that showed up in skipped ids,
+// then every impression past the tail.
+func (s *EventSink) copyImpressions(ctx context.Context) error {
+	if !s.tail.loaded {
+		if err := s.resumeImpressions(ctx); err != nil {
+			return err
+		}
+	}
+	if err := s.copyImpressionGaps(ctx); err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		impressions, err := s.primary.ImpressionsAfter(ctx, s.tail.lastID, s.batchSize)
+		if err != nil || len(impressions) == 0 {
+			return err
+		}
+		if err := s.write(ctx, "impression_events", func() error { return s.sink.SaveImpressions(ctx, impressions) }, len(impressions)); err != nil {
+			return err
+		}
+		s.tail.advance(impressions, time.Now())
+	}
+	return ctx.Err()
+}
+
+// resumeImpressions starts the tail after the sink's highest id, rewound
+// to the last impression stored before the lookback. Rows that committed
+// out of order before the previous leader stopped are copied again, and
+// the sink collapses the repeats.
+func (s *EventSink) resumeImpressions(ctx context.Context) error {
+	lastID, err := s.sink.MaxImpressionID(ctx)
+	if err != nil {
+		return err
+	}
+	if lastID > 0 {
+		if lastID, err = s.primary.LastImpressionIDBefore(ctx, lastID, time.Now().Add(-s.lookback)); err != nil {
+			return err
+		}
+	}
+	s.tail = impressionTail{loaded: true, lastID: lastID, gaps: make(map[uint]time.Time)}
+	return nil
+}
+
+// copyImpressionGaps copies impressions that committed after the tail
+// passed their ids. Ids still missing after the lookback were rolled back
+// or deleted and are given up on.
+func (s *EventSink) copyImpressionGaps(ctx context.Context) error {
+	expired := time.Now().Add(-s.lookback)
+	ids := make([]uint, 0, len(s.tail.gaps))
+	for id, missed := range s.tail.gaps {
+		if missed.Before(expired) {
+			delete(s.tail.gaps, id)
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	for start := 0; start < len(ids); start += s.batchSize {
+		impressions, err := s.primary.ImpressionsByID(ctx, ids[start:min(start+s.batchSize, len(ids))])
+		if err != nil {
+			return err
+		}
+		if len(impressions) == 0 {
+			continue
+		}
+		if err := s.write(ctx, "impression_events", func() error { return s.sink.SaveImpressions(ctx, impressions) }, len(impressions)); err != nil {
+			return err
+		}
+		for _, impression := range impressions {
+			delete(s.tail.gaps, impression.ID)
+		}
+		s.logger.WithFields(logrus.Fields{"sink": s.name, "impressions": len(impressions)}).Debug("Copied impressions committed out of order")
+	}
+	return nil
+}
+
+// advance moves the tail past a copied page, remembering the ids it
+// skipped.
+func (t *impressionTail) advance(impressions []models.ImpressionEvent, now time.Time) {
+	for _, impression := range impressions {
+		if impression.ID-t.lastID <= maxImpressionGap {
+			for id := t.lastID + 1; id < impression.ID; id++ {
+				t.gaps[id] = now
+			}
+		}
+		t.lastID = impression.ID
+	}
+}
+
+// write retries with backoff so a sink outage pauses copying instead of
+// dropping events.
+func (s *EventSink) write(ctx context.Context, table string, insert func() error, rows int) error {
+	backoff := time.Second
+	for {
+		err := insert()
+		if err == nil {
+			metrics.SinkRows.WithLabelValues(s.name, table, "ok").Add(float64(rows))
+			return nil
+		}
+		metrics.SinkRows.WithLabelValues(s.name, table, "error").Add(float64(rows))
+		s.logger.WithError(err).WithFields(logrus.Fields{"sink": s.name, "table": table, "retry_in": backoff}).Warn("Sink insert failed")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+func (s *EventSink) Close() error {
+	return s.reader.Close()
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/migrations"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordingSink keeps the impression ids it was sent.
+type recordingSink struct {
+	mu          sync.Mutex
+	impressions []uint
+}
+
+func (r *recordingSink) SaveClicks(ctx context.Context, clicks []models.ClickEvent) error {
+	return nil
+}
+
+func (r *recordingSink) SaveImpressions(ctx context.Context, impressions []models.ImpressionEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, impression := range impressions {
+		r.impressions = append(r.impressions, impression.ID)
+	}
+	return nil
+}
+
+func (r *recordingSink) MaxImpressionID(ctx context.Context) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var highest uint
+	for _, id := range r.impressions {
+		highest = max(highest, id)
+	}
+	return highest, nil
+}
+
+func (r *recordingSink) ids() []uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := slices.Clone(r.impressions)
+	slices.Sort(ids)
+	return ids
+}
+
+func newSinkTest(t *testing.T) (*EventSink, *recordingSink, *repositories.EventStore) {
+	t.Helper()
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	db, err := database.SetupDatabase(database.DriverSQLite, "file::memory:", database.PoolConfig{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err := migrations.New(db, logger).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &recordingSink{}
+	primary := repositories.NewEventStore(db)
+	return NewEventSink("test", nil, sink, primary, 2, logger), sink, primary
+}
+
+func saveImpressions(t *testing.T, store *repositories.EventStore, createdAt time.Time, ids ...uint) {
+	t.Helper()
+	impressions := make([]models.ImpressionEvent, len(ids))
+	for i, id := range ids {
+		impressions[i] = models.ImpressionEvent{ID: id, AdID: 1, Timestamp: createdAt, CreatedAt: createdAt}
+	}
+	if err := store.SaveImpressions(context.Background(), impressions); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCopyImpressionsCommittedOutOfOrder(t *testing.T) {
+	sink, recorded, primary := newSinkTest(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// 2 and 4 are allocated but not committed yet
+	saveImpressions(t, primary, now, 1, 3, 5)
+	if err := sink.copyImpressions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := recorded.ids(); !slices.Equal(got, []uint{1, 3, 5}) {
+		t.Fatalf("copied %v, want 1 3 5", got)
+	}
+
+	saveImpressions(t, primary, now, 2, 4, 6)
+	if err := sink.copyImpressions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := recorded.ids(); !slices.Equal(got, []uint{1, 2, 3, 4, 5, 6}) {
+		t.Fatalf("copied %v, want every impression once", got)
+	}
+	if len(sink.tail.gaps) != 0 {
+		t.Errorf("gaps left: %v", sink.tail.gaps)
+	}
+}
+
+func TestCopyImpressionsGivesUpAfterLookback(t *testing.T) {
+	sink, _, primary := newSinkTest(t)
+	ctx := context.Background()
+
+	saveImpressions(t, primary, time.Now(), 1, 3)
+	if err := sink.copyImpressions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sink.tail.gaps[2]; !ok {
+		t.Fatalf("gap 2 not tracked: %v", sink.tail.gaps)
+	}
+	sink.tail.gaps[2] = time.Now().Add(-2 * sink.lookback)
+	if err := sink.copyImpressions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.tail.gaps) != 0 {
+		t.Errorf("expired gaps kept: %v", sink.tail.gaps)
+	}
+}
+
+func TestCopyImpressionsResumesBeforeLookback(t *testing.T) {
+	sink, recorded, primary := newSinkTest(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	saveImpressions(t, primary, now.Add(-time.Hour), 1, 2)
+	saveImpressions(t, primary, now, 3, 5)
+	// A previous leader copied up to 5 but never saw 4 commit
+	recorded.SaveImpressions(ctx, []models.ImpressionEvent{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 5}})
+	saveImpressions(t, primary, now, 4)
+
+	if err := sink.copyImpressions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// 3 and 5 are repeated, which sinks tolerate; 1 and 2 are not
+	if got := recorded.ids(); !slices.Equal(got, []uint{1, 2, 3, 3, 4, 5, 5}) {
+		t.Fatalf("sink holds %v, want 3 to 5 copied again", got)
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/k8s"
+	"ad-tracking-system/internal/models"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// HealthCheck reports whether a dependency is usable.
+type HealthCheck func(ctx context.Context) error
+
+const (
+	ingestWindow     = 5 * time.Minute
+	ingestMaxSamples = 10000
+	ingestTargetMS   = 100
+)
+
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// StatusService runs periodic component checks, tracks ingest latency and
+// assembles the public status page.
+type StatusService struct {
+	repo    *repositories.StatusRepository
+	logger  *logrus.Logger
+	elector k8s.Elector
+
+	mu         sync.RWMutex
+	checks     map[string]HealthCheck
+	components map[string]models.ComponentStatus
+	latencies  []latencySample
+}
+
+func NewStatusService(repo *repositories.StatusRepository, logger *logrus.Logger) *StatusService {
+	return &StatusService{
+		repo:       repo,
+		logger:     logger,
+		elector:    k8s.AlwaysLeader{},
+		checks:     make(map[string]HealthCheck),
+		components: make(map[string]models.ComponentStatus),
+	}
+}
+
+// AddCheck registers a named component check.
+func (s *StatusService) AddCheck(name string, check HealthCheck) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.checks[name] = check
+}
+
+// SetElector limits uptime recording to the elected replica so multi-replica
+// deployments don't count every check once per pod.
+func (s *StatusService) SetElector(elector k8s.Elector) {
+	s.elector = elector
+}
+
+// ObserveIngest records the latency of a single ingest request.
+func (s *StatusService) ObserveIngest(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.latencies = append(s.latencies, latencySample{at: time.Now(), duration: d})
+	if len(s.latencies) > ingestMaxSamples {
+		s.latencies = s.latencies[len(s.latencies)-ingestMaxSamples:]
+	}
+}
+
+func (s *StatusService) Start(ctx context.Context, interval time.Duration) {
+	s.runChecks(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runChecks(ctx)
+		}
+	}
+}
+
+func (s *StatusService) runChecks(ctx context.Context) {
+	s.mu.RLock()
+	checks := make(map[string]HealthCheck, len(s.checks))
+	for name, check := range s.checks {
+		checks[name] = check
+	}
+	s.mu.RUnlock()
+
+	for name, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		start := time.Now()
+		err := check(checkCtx)
+		cancel()
+
+		status := models.ComponentStatus{
+			Name:      name,
+			Status:    models.StatusOperational,
+			LatencyMS: time.Since(start).Milliseconds(),
+			CheckedAt: time.Now().UTC(),
+		}
+		if err != nil {
+			status.Status = models.StatusMajorOutage
+			s.logger.WithError(err).WithField("component", name).Warn("Status check failed")
+		}
+
+		s.mu.Lock()
+		s.components[name] = status
+		s.mu.Unlock()
+
+		if !s.elector.IsLeader() {
+			continue
+		}
+		if err := s.repo.RecordCheck(name, status.Status == models.StatusOperational); err != nil {
+			s.logger.WithError(err).WithField("component", name).Error("Failed to record uptime check")
+		}
+	}
+}
+
+// Snapshot builds the current status page payload.
+func (s *StatusService) Snapshot() models.StatusPage {
+	page := models.StatusPage{
+		Status:    models.StatusOperational,
+		UpdatedAt: time.Now().UTC(),
+		Ingest:    s.ingestSLI(),
+		Uptime:    make(map[string]map[string]float64),
+	}
+
+	s.mu.RLock()
+	for _, component := range s.components {
+		page.Components = append(page.Components, component)
+		if component.Status != models.StatusOperational {
+			page.Status = models.StatusDegraded
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(page.Components, func(i, j int) bool {
+		return page.Components[i].Name < page.Components[j].Name
+	})
+
+	incidents, err := s.repo.ListIncidents(true, 20)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load active incidents")
+	}
+	page.Incidents = incidents
+	for _, incident := range incidents {
+		switch incident.Severity {
+		case "critical":
+			page.Status = models.StatusMajorOutage
+		case "major":
+			if page.Status == models.StatusOperational {
+				page.Status = models.StatusDegraded
+			}
+		}
+	}
+
+	windows := map[string]time.Duration{
+		"24h": 24 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+		"30d": 30 * 24 * time.Hour,
+		"90d": 90 * 24 * time.Hour,
+	}
+	for label, window := range windows {
+		uptime, err := s.repo.Uptime(time.Now().Add(-window))
+		if err != nil {
+			s.logger.WithError(err).Error("Failed to load uptime history")
+			continue
+		}
+		for component, ratio := range uptime {
+			if page.Uptime[component] == nil {
+				page.Uptime[component] = make(map[string]float64)
+			}
+			page.Uptime[component][label] = ratio
+		}
+	}
+
+	return page
+}
+
+func (s *StatusService) ingestSLI() models.IngestSLI {
+	cutoff := time.Now().Add(-ingestWindow)
+
+	s.mu.Lock()
+	i := sort.Search(len(s.latencies), func(i int) bool { return !s.latencies[i].at.Before(cutoff) })
+	s.latencies = s.latencies[i:]
+	durations := make([]time.Duration, len(s.latencies))
+	for j, sample := range s.latencies {
+		durations[j] = sample.duration
+	}
+	s.mu.Unlock()
+
+	sli := models.IngestSLI{
+		Window:   ingestWindow.String(),
+		Requests: len(durations),
+		TargetMS: ingestTargetMS,
+	}
+	if len(durations) == 0 {
+		return sli
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	within := sort.Search(len(durations), func(i int) bool {
+		return durations[i] > ingestTargetMS*time.Millisecond
+	})
+
+	sli.P50MS = float64(durations[len(durations)*50/100].Microseconds()) / 1000
+	sli.P95MS = float64(durations[len(durations)*95/100].Microseconds()) / 1000
+	sli.WithinGoal = float64(within) / float64(len(durations))
+	return sli
+}
@@ -0,0 +1,335 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/k8s"
+	"ad-tracking-system/internal/metrics"
+	"ad-tracking-system/internal/models"
+	"ad-tracking-system/internal/netguard"
+	repositories "ad-tracking-system/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+type webhookEvent struct {
+	eventType string
+	adID      uint
+	eventID   string
+	data      interface{}
+	at        time.Time
+}
+
+// WebhookDispatcher forwards tracking events to the webhook endpoints of the
+// account owning the ad. Each delivery is logged and retried with
+// exponential backoff until it succeeds or runs out of attempts.
+//
+// Events are queued in memory and written to the delivery log by a worker,
+// so ingestion never waits on the database or a customer endpoint. Retries
+// run only on the elected replica.
+type WebhookDispatcher struct {
+	repo        *repositories.WebhookRepository
+	guard       netguard.Guard
+	client      *http.Client
+	elector     k8s.Elector
+	maxAttempts int
+	backoff     time.Duration
+	logger      *logrus.Logger
+	events      chan webhookEvent
+
+	mu        sync.RWMutex
+	endpoints map[uint][]models.WebhookEndpoint
+}
+
+func NewWebhookDispatcher(repo *repositories.WebhookRepository, logger *logrus.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:        repo,
+		client:      netguard.Guard{}.Client(10 * time.Second),
+		elector:     k8s.AlwaysLeader{},
+		maxAttempts: 8,
+		backoff:     30 * time.Second,
+		logger:      logger,
+		events:      make(chan webhookEvent, 10000),
+		endpoints:   make(map[uint][]models.WebhookEndpoint),
+	}
+}
+
+// SetGuard controls which addresses endpoints may resolve to.
+func (d *WebhookDispatcher) SetGuard(guard netguard.Guard) {
+	d.guard = guard
+	d.client = guard.Client(10 * time.Second)
+}
+
+// CheckURL rejects endpoint URLs the guard would refuse to deliver to.
+func (d *WebhookDispatcher) CheckURL(ctx context.Context, raw string) error {
+	return d.guard.CheckURL(ctx, raw)
+}
+
+func (d *WebhookDispatcher) SetElector(elector k8s.Elector) {
+	d.elector = elector
+}
+
+// SetRetryPolicy limits attempts per delivery; retry n waits backoff*2^(n-1).
+func (d *WebhookDispatcher) SetRetryPolicy(maxAttempts int, backoff time.Duration) {
+	d.maxAttempts = maxAttempts
+	d.backoff = backoff
+}
+
+// SignPayload returns the X-Webhook-Signature value for a body sent at t:
+// "t=<unix>,v1=<hex HMAC-SHA256 of "<unix>.<body>">". Receivers should
+// recompute it and reject stale timestamps.
+func SignPayload(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Refresh reloads the endpoint subscriptions.
+func (d *WebhookDispatcher) Refresh() error {
+	endpoints, err := d.repo.EndpointsByAd()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.endpoints = endpoints
+	d.mu.Unlock()
+	return nil
+}
+
+// Publish queues an event for the ad's subscribed endpoints without
+// blocking. Events are dropped, and counted, when the queue is full.
+func (d *WebhookDispatcher) Publish(eventType string, adID uint, eventID string, data interface{}) {
+	if len(d.subscribers(eventType, adID)) == 0 {
+		return
+	}
+
+	select {
+	case d.events <- webhookEvent{eventType: eventType, adID: adID, eventID: eventID, data: data, at: time.Now().UTC()}:
+	default:
+		metrics.WebhookDeliveries.WithLabelValues(eventType, "dropped").Inc()
+	}
+}
+
+func (d *WebhookDispatcher) subscribers(eventType string, adID uint) []models.WebhookEndpoint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var matched []models.WebhookEndpoint
+	for _, endpoint := range d.endpoints[adID] {
+		for _, subscribed := range strings.Split(endpoint.EventTypes, ",") {
+			if subscribed == eventType {
+				matched = append(matched, endpoint)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// webhookWorkers bounds concurrent first attempts so one slow endpoint does
+// not stall every account's deliveries.
+const webhookWorkers = 4
+
+// webhookClaimLease is how long a claimed attempt holds off retries; it
+// outlasts the client timeout, so only a crashed sender's attempt is retried.
+const webhookClaimLease = time.Minute
+
+// Run consumes published events and, on the leader, retries due deliveries
+// and refreshes subscriptions every interval until ctx is cancelled.
+func (d *WebhookDispatcher) Run(ctx context.Context, interval time.Duration) {
+	if err := d.Refresh(); err != nil {
+		d.logger.WithError(err).Error("Failed to load webhook endpoints")
+	}
+
+	for i := 0; i < webhookWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case event := <-d.events:
+					d.record(ctx, event)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.Refresh(); err != nil {
+				d.logger.WithError(err).Error("Failed to refresh webhook endpoints")
+			}
+			if d.elector.IsLeader() {
+				d.retryDue(ctx)
+			}
+		}
+	}
+}
+
+// record writes one delivery per subscribed endpoint and attempts each.
+func (d *WebhookDispatcher) record(ctx context.Context, event webhookEvent) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"id":         event.eventID,
+		"type":       event.eventType,
+		"created_at": event.at,
+		"data":       event.data,
+	})
+	if err != nil {
+		d.logger.WithError(err).Error("Failed to encode webhook payload")
+		return
+	}
+
+	// The first attempt follows right away; the retry time only matters if
+	// this process dies before recording its outcome.
+	retryAt := event.at.Add(d.backoff)
+	endpoints := d.subscribers(event.eventType, event.adID)
+	deliveries := make([]models.WebhookDelivery, len(endpoints))
+	for i, endpoint := range endpoints {
+		deliveries[i] = models.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventType:     event.eventType,
+			EventID:       event.eventID,
+			Payload:       string(payload),
+			Status:        models.WebhookPending,
+			NextAttemptAt: &retryAt,
+		}
+	}
+	if err := d.repo.CreateDeliveries(deliveries); err != nil {
+		d.logger.WithError(err).Error("Failed to log webhook deliveries")
+		return
+	}
+
+	for i := range deliveries {
+		d.attempt(ctx, &deliveries[i], &endpoints[i], 0)
+	}
+}
+
+func (d *WebhookDispatcher) retryDue(ctx context.Context) {
+	deliveries, err := d.repo.DueDeliveries(time.Now().UTC(), 500)
+	if err != nil {
+		d.logger.WithError(err).Error("Failed to load due webhook deliveries")
+		return
+	}
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		d.Attempt(ctx, &deliveries[i])
+	}
+}
+
+// Attempt sends a logged delivery to its endpoint now.
+func (d *WebhookDispatcher) Attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	d.attemptLoaded(ctx, delivery, delivery.Attempts)
+}
+
+// Redeliver sends a delivery again with a fresh attempt budget, whatever its
+// status.
+func (d *WebhookDispatcher) Redeliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	observed := delivery.Attempts
+	delivery.Status = models.WebhookPending
+	delivery.Attempts = 0
+	d.attemptLoaded(ctx, delivery, observed)
+}
+
+func (d *WebhookDispatcher) attemptLoaded(ctx context.Context, delivery *models.WebhookDelivery, observed int) {
+	endpoint, err := d.repo.GetEndpoint(delivery.EndpointID)
+	if err != nil {
+		d.logger.WithError(err).WithField("webhook_delivery_id", delivery.ID).Error("Failed to load webhook endpoint")
+		return
+	}
+	d.attempt(ctx, delivery, endpoint, observed)
+}
+
+// attempt claims the delivery, whose stored attempt count was observed, and
+// sends it unless another replica claimed it first.
+func (d *WebhookDispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery, endpoint *models.WebhookEndpoint, observed int) {
+	log := d.logger.WithFields(logrus.Fields{"webhook_delivery_id": delivery.ID, "endpoint_id": endpoint.ID})
+
+	delivery.Attempts++
+	claimed, err := d.repo.ClaimDelivery(delivery.ID, observed, delivery.Attempts, time.Now().UTC().Add(webhookClaimLease))
+	if err != nil {
+		log.WithError(err).Error("Failed to claim webhook delivery")
+		return
+	}
+	if !claimed {
+		log.Debug("Webhook delivery claimed elsewhere")
+		return
+	}
+
+	statusCode, err := d.send(ctx, delivery, endpoint)
+	delivery.LastStatusCode = statusCode
+	now := time.Now().UTC()
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDelivered
+		delivery.LastError = ""
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= d.maxAttempts || !endpoint.Active:
+		delivery.Status = models.WebhookFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+		log.WithError(err).Warn("Webhook delivery failed permanently")
+	default:
+		next := now.Add(d.backoff << (delivery.Attempts - 1))
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+		log.WithError(err).WithField("attempt", delivery.Attempts).Debug("Webhook delivery failed, will retry")
+	}
+	metrics.WebhookDeliveries.WithLabelValues(delivery.EventType, delivery.Status).Inc()
+
+	if err := d.repo.SaveDelivery(delivery); err != nil {
+		log.WithError(err).Error("Failed to save webhook delivery")
+	}
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, delivery *models.WebhookDelivery, endpoint *models.WebhookEndpoint) (int, error) {
+	if !endpoint.Active {
+		return 0, fmt.Errorf("endpoint disabled")
+	}
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ad-tracker-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Signature", SignPayload(endpoint.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
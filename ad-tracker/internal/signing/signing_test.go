@@ -0,0 +1,138 @@
+package signing_test
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"ad-tracking-system/internal/signing"
+)
+
+const (
+	accountSecret = "account-one-secret"
+	otherSecret   = "account-two-secret"
+	rotatedSecret = "account-one-rotated"
+	adID          = 42
+	linkTTL       = 24 * time.Hour
+)
+
+// tamper returns a copy of query with key set to value.
+func tamper(query url.Values, key, value string) url.Values {
+	changed := url.Values{}
+	for k, v := range query {
+		changed[k] = append([]string(nil), v...)
+	}
+	changed.Set(key, value)
+	return changed
+}
+
+// flipFirst changes the first hex digit of sig.
+func flipFirst(sig string) string {
+	if sig[0] == '0' {
+		return "1" + sig[1:]
+	}
+	return "0" + sig[1:]
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	link := signing.Query(accountSecret, signing.KindClick, adID, now, "user-1")
+	anonymous := signing.Query(accountSecret, signing.KindPixel, adID, now, "")
+
+	tests := []struct {
+		name   string
+		secret string
+		kind   string
+		adID   uint
+		query  url.Values
+		want   error
+	}{
+		{"valid", accountSecret, signing.KindClick, adID, link, nil},
+		{"valid without user", accountSecret, signing.KindPixel, adID, anonymous, nil},
+		{"valid at the ttl", accountSecret, signing.KindClick, adID,
+			signing.Query(accountSecret, signing.KindClick, adID, now.Add(-linkTTL+time.Minute), "user-1"), nil},
+		{"within clock skew", accountSecret, signing.KindClick, adID,
+			signing.Query(accountSecret, signing.KindClick, adID, now.Add(time.Minute), "user-1"), nil},
+
+		{"expired", accountSecret, signing.KindClick, adID,
+			signing.Query(accountSecret, signing.KindClick, adID, now.Add(-linkTTL-time.Minute), "user-1"), signing.ErrExpired},
+		{"from the future", accountSecret, signing.KindClick, adID,
+			signing.Query(accountSecret, signing.KindClick, adID, now.Add(time.Hour), "user-1"), signing.ErrFromFuture},
+
+		{"tampered signature", accountSecret, signing.KindClick, adID, tamper(link, "sig", flipFirst(link.Get("sig"))), signing.ErrInvalid},
+		{"tampered timestamp", accountSecret, signing.KindClick, adID,
+			tamper(link, "ts", strconv.FormatInt(now.Unix()-1, 10)), signing.ErrInvalid},
+		{"tampered user", accountSecret, signing.KindClick, adID, tamper(link, "user_id", "user-2"), signing.ErrInvalid},
+		{"user added", accountSecret, signing.KindPixel, adID, tamper(anonymous, "user_id", "user-2"), signing.ErrInvalid},
+		{"other ad", accountSecret, signing.KindClick, adID + 1, link, signing.ErrInvalid},
+		{"other kind", accountSecret, signing.KindPixel, adID, link, signing.ErrInvalid},
+		{"timestamp not a number", accountSecret, signing.KindClick, adID, tamper(link, "ts", "soon"), signing.ErrInvalid},
+
+		{"other account's link", accountSecret, signing.KindClick, adID,
+			signing.Query(otherSecret, signing.KindClick, adID, now, "user-1"), signing.ErrInvalid},
+		{"checked against other account", otherSecret, signing.KindClick, adID, link, signing.ErrInvalid},
+
+		{"no signature", accountSecret, signing.KindClick, adID, tamper(link, "sig", ""), signing.ErrMissing},
+		{"no timestamp", accountSecret, signing.KindClick, adID, tamper(link, "ts", ""), signing.ErrMissing},
+		{"no parameters", accountSecret, signing.KindClick, adID, url.Values{}, signing.ErrMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := signing.Verify(tt.secret, tt.kind, tt.adID, tt.query, linkTTL)
+			if !errors.Is(err, tt.want) || (err == nil) != (tt.want == nil) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestVerifyWithoutTTL checks that a zero ttl accepts links of any age.
+func TestVerifyWithoutTTL(t *testing.T) {
+	query := signing.Query(accountSecret, signing.KindExport, 7, time.Now().AddDate(-2, 0, 0), "")
+	if err := signing.Verify(accountSecret, signing.KindExport, 7, query, 0); err != nil {
+		t.Errorf("Verify = %v, want nil", err)
+	}
+}
+
+// TestVerifyAfterRotation checks that rotating an account's secret
+// invalidates the links signed before and accepts the ones signed after.
+func TestVerifyAfterRotation(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		signed string
+		want   error
+	}{
+		{"signed before rotation", accountSecret, signing.ErrInvalid},
+		{"signed after rotation", rotatedSecret, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := signing.Query(tt.signed, signing.KindClick, adID, now, "user-1")
+			err := signing.Verify(rotatedSecret, signing.KindClick, adID, query, linkTTL)
+			if !errors.Is(err, tt.want) || (err == nil) != (tt.want == nil) {
+				t.Errorf("Verify = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostbackSubject(t *testing.T) {
+	subject := signing.PostbackSubject("click-1", "tx-1", "9.99", "EUR")
+	query := signing.Query(accountSecret, signing.KindPostback, adID, time.Now(), subject)
+
+	for name, changed := range map[string]string{
+		"value":          signing.PostbackSubject("click-1", "tx-1", "99.9", "EUR"),
+		"currency":       signing.PostbackSubject("click-1", "tx-1", "9.99", "USD"),
+		"transaction id": signing.PostbackSubject("click-1", "tx-2", "9.99", "EUR"),
+	} {
+		if err := signing.Verify(accountSecret, signing.KindPostback, adID, tamper(query, "user_id", changed), linkTTL); !errors.Is(err, signing.ErrInvalid) {
+			t.Errorf("postback with a changed %s: Verify = %v, want %v", name, err, signing.ErrInvalid)
+		}
+	}
+	if err := signing.Verify(accountSecret, signing.KindPostback, adID, query, linkTTL); err != nil {
+		t.Errorf("postback as signed: Verify = %v, want nil", err)
+	}
+}
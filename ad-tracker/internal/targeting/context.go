@@ -0,0 +1,91 @@
+package targeting
+
+import "strings"
+
+// Device classes and operating systems derived from the User-Agent.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+
+	OSWindows  = "windows"
+	OSMacOS    = "macos"
+	OSIOS      = "ios"
+	OSAndroid  = "android"
+	OSChromeOS = "chromeos"
+	OSLinux    = "linux"
+	OSOther    = "other"
+)
+
+// Context is what a serve request tells us about the viewer.
+type Context struct {
+	Country   string // ISO 3166-1 alpha-2, as sent by the CDN
+	Device    string
+	OS        string
+	Keywords  []string
+	Placement string
+}
+
+// NewContext derives the device class and OS from userAgent. Keywords are
+// comma separated.
+func NewContext(country, userAgent, keywords, placement string) Context {
+	ctx := Context{
+		Country:   strings.ToUpper(strings.TrimSpace(country)),
+		Placement: strings.TrimSpace(placement),
+	}
+	ctx.Device, ctx.OS = ParseUserAgent(userAgent)
+	for _, keyword := range strings.Split(keywords, ",") {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			ctx.Keywords = append(ctx.Keywords, keyword)
+		}
+	}
+	return ctx
+}
+
+func (c Context) value(field string) string {
+	switch field {
+	case FieldCountry:
+		return c.Country
+	case FieldDevice:
+		return c.Device
+	case FieldOS:
+		return c.OS
+	case FieldPlacement:
+		return c.Placement
+	}
+	return ""
+}
+
+// ParseUserAgent classifies a User-Agent by device and operating system.
+// It only looks for the common tokens; anything unrecognized is a desktop
+// running "other".
+func ParseUserAgent(ua string) (device, os string) {
+	switch {
+	case strings.Contains(ua, "iPad"), strings.Contains(ua, "Tablet"),
+		strings.Contains(ua, "Android") && !strings.Contains(ua, "Mobile"):
+		device = DeviceTablet
+	case strings.Contains(ua, "Mobi"), strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPod"):
+		device = DeviceMobile
+	default:
+		device = DeviceDesktop
+	}
+
+	// iPadOS and Android also claim Mac OS X and Linux, so check them first
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"), strings.Contains(ua, "iPod"):
+		os = OSIOS
+	case strings.Contains(ua, "Android"):
+		os = OSAndroid
+	case strings.Contains(ua, "Windows"):
+		os = OSWindows
+	case strings.Contains(ua, "CrOS"):
+		os = OSChromeOS
+	case strings.Contains(ua, "Macintosh"), strings.Contains(ua, "Mac OS X"):
+		os = OSMacOS
+	case strings.Contains(ua, "Linux"):
+		os = OSLinux
+	default:
+		os = OSOther
+	}
+	return device, os
+}
@@ -0,0 +1,166 @@
+// Package targeting decides which ads a serve request is eligible for,
+// from per-ad rules evaluated against what the request says about the
+// viewer.
+package targeting
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Fields a leaf rule can test.
+const (
+	FieldCountry   = "country"
+	FieldDevice    = "device"
+	FieldOS        = "os"
+	FieldKeyword   = "keyword"
+	FieldPlacement = "placement"
+)
+
+// maxDepth bounds nesting so a stored rule cannot make serving expensive.
+const maxDepth = 8
+
+// Rule is a composable targeting expression: either a combination of rules
+// (All, Any or Not) or a leaf testing one Field against Values. A leaf
+// matches when the field equals any of the values, ignoring case; keyword
+// leaves match when any of the request's keywords is listed. For example
+//
+//	{"all": [{"field": "country", "values": ["US", "CA"]},
+//	         {"not": {"field": "device", "values": ["tablet"]}}]}
+type Rule struct {
+	All    []Rule   `json:"all,omitempty"`
+	Any    []Rule   `json:"any,omitempty"`
+	Not    *Rule    `json:"not,omitempty"`
+	Field  string   `json:"field,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+// Validate checks that every node is exactly one kind of rule and that
+// leaves name a known field with at least one value.
+func (r Rule) Validate() error {
+	return r.validate(1)
+}
+
+func (r Rule) validate(depth int) error {
+	if depth > maxDepth {
+		return fmt.Errorf("rules nest deeper than %d levels", maxDepth)
+	}
+
+	kinds := 0
+	for _, set := range []bool{len(r.All) > 0, len(r.Any) > 0, r.Not != nil, r.Field != "" || len(r.Values) > 0} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return errors.New("each rule needs exactly one of all, any, not or field with values")
+	}
+
+	switch {
+	case r.Not != nil:
+		return r.Not.validate(depth + 1)
+	case r.Field != "":
+		switch r.Field {
+		case FieldCountry, FieldDevice, FieldOS, FieldKeyword, FieldPlacement:
+		default:
+			return fmt.Errorf("unknown field %q: use country, device, os, keyword or placement", r.Field)
+		}
+		if len(r.Values) == 0 {
+			return fmt.Errorf("field %q needs at least one value", r.Field)
+		}
+		return nil
+	case len(r.Values) > 0:
+		return errors.New("values need a field")
+	}
+
+	for _, child := range append(r.All, r.Any...) {
+		if err := child.validate(depth + 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match evaluates the rule against the request context.
+func (r Rule) Match(ctx Context) bool {
+	switch {
+	case len(r.All) > 0:
+		for _, child := range r.All {
+			if !child.Match(ctx) {
+				return false
+			}
+		}
+		return true
+	case len(r.Any) > 0:
+		for _, child := range r.Any {
+			if child.Match(ctx) {
+				return true
+			}
+		}
+		return false
+	case r.Not != nil:
+		return !r.Not.Match(ctx)
+	}
+
+	if r.Field == FieldKeyword {
+		for _, keyword := range ctx.Keywords {
+			if r.matches(keyword) {
+				return true
+			}
+		}
+		return false
+	}
+	return r.matches(ctx.value(r.Field))
+}
+
+func (r Rule) matches(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, candidate := range r.Values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Value stores the rule as JSON.
+func (r Rule) Value() (driver.Value, error) {
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func (r *Rule) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*r = Rule{}
+		return nil
+	case []byte:
+		return json.Unmarshal(v, r)
+	case string:
+		return json.Unmarshal([]byte(v), r)
+	}
+	return fmt.Errorf("cannot scan %T into a targeting rule", value)
+}
+
+// GormDBDataType keeps rules as JSONB on Postgres and JSON on MySQL, so
+// they can be inspected in SQL, and as text on SQLite.
+func (Rule) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "jsonb"
+	case "mysql":
+		return "json"
+	}
+	return "text"
+}
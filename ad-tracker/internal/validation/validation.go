@@ -0,0 +1,117 @@
+// Package validation adds the request validators tracking payloads use and
+// turns binding failures into per-field messages. Importing it registers
+// the validators with gin's binding engine.
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// identifierPattern is what session ids, placement keys and similar
+// client-chosen identifiers may contain.
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+
+// sha256Pattern is a lowercase hex SHA-256 digest, the only form hashed
+// emails and device ids are accepted in.
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+func init() {
+	engine, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// Errors name fields as clients send them
+	engine.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	engine.RegisterValidation("identifier", func(fl validator.FieldLevel) bool {
+		return identifierPattern.MatchString(fl.Field().String())
+	})
+	engine.RegisterValidation("sha256", func(fl validator.FieldLevel) bool {
+		return sha256Pattern.MatchString(fl.Field().String())
+	})
+	engine.RegisterValidation("printable", func(fl validator.FieldLevel) bool {
+		value := fl.Field().String()
+		return utf8.ValidString(value) && strings.IndexFunc(value, unicode.IsControl) < 0
+	})
+}
+
+// Fields maps each invalid field of a binding error to what is wrong with
+// it. It returns nil for errors that are not about particular fields, such
+// as malformed JSON.
+func Fields(err error) map[string]string {
+	var invalid validator.ValidationErrors
+	if errors.As(err, &invalid) {
+		fields := make(map[string]string, len(invalid))
+		for _, field := range invalid {
+			fields[field.Field()] = message(field)
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{typeErr.Field: "must be a " + typeErr.Type.String()}
+	}
+	return nil
+}
+
+// Response is the 400 body for a binding error: field messages when there
+// are any, otherwise the error itself.
+func Response(err error) gin.H {
+	if fields := Fields(err); fields != nil {
+		return gin.H{"error": "Invalid request", "fields": fields}
+	}
+	return gin.H{"error": err.Error()}
+}
+
+func message(field validator.FieldError) string {
+	isString := field.Kind() == reflect.String
+	switch field.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		if isString {
+			return fmt.Sprintf("must be at most %s characters", field.Param())
+		}
+		return "must be at most " + field.Param()
+	case "min":
+		if isString {
+			return fmt.Sprintf("must be at least %s characters", field.Param())
+		}
+		return "must be at least " + field.Param()
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters", field.Param())
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(field.Param(), " ", ", ")
+	case "url":
+		return "must be a URL"
+	case "identifier":
+		return "may only contain letters, digits, '.', '_', ':' and '-'"
+	case "sha256":
+		return "must be a lowercase hex SHA-256 digest"
+	case "printable":
+		return "must be UTF-8 without control characters"
+	}
+	return "failed the " + field.Tag() + " check"
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"ad-tracking-system/internal/loadgen"
+)
+
+// runLoadgen implements the loadgen subcommand, which benchmarks a running
+// tracker's ingest path and prints latency percentiles per endpoint:
+//
+//	ad-tracker loadgen -target http://localhost:8080 -rps 500 -duration 1m
+//	ad-tracker loadgen -ads 1,2,3 -clicks 0.5
+//
+// It needs no configuration of its own, so it can run from any host.
+func runLoadgen(args []string) {
+	defaults := loadgen.DefaultOptions
+	flags := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	target := flags.String("target", defaults.Target, "base URL of the tracker")
+	rps := flags.Float64("rps", defaults.RPS, "requests per second")
+	duration := flags.Duration("duration", defaults.Duration, "how long to run")
+	clickRatio := flags.Float64("clicks", defaults.ClickRatio, "fraction of requests that are clicks; the rest are impressions")
+	ads := flags.String("ads", "", "comma separated ad ids; defaults to the ads the target serves")
+	users := flags.Int("users", defaults.Users, "distinct user ids")
+	ips := flags.Int("ips", defaults.IPs, "distinct client addresses")
+	concurrency := flags.Int("concurrency", defaults.Concurrency, "maximum requests in flight")
+	timeout := flags.Duration("timeout", defaults.Timeout, "per request timeout")
+	randomSeed := flags.Int64("seed", defaults.Seed, "random seed")
+	secret := flags.String("secret", "", "signing secret for the events, when the target requires signed events")
+	flags.Parse(args)
+
+	if *clickRatio < 0 || *clickRatio > 1 {
+		loadgenUsage("-clicks must be between 0 and 1")
+	}
+	opts := loadgen.Options{
+		Target:        *target,
+		RPS:           *rps,
+		Duration:      *duration,
+		ClickRatio:    *clickRatio,
+		Users:         *users,
+		IPs:           *ips,
+		Concurrency:   *concurrency,
+		Timeout:       *timeout,
+		Seed:          *randomSeed,
+		SigningSecret: *secret,
+	}
+	for _, field := range strings.Split(*ads, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			loadgenUsage(fmt.Sprintf("invalid ad id %q", field))
+		}
+		opts.AdIDs = append(opts.AdIDs, uint(id))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	report, err := loadgen.Run(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(1)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+}
+
+func loadgenUsage(problem string) {
+	fmt.Fprintln(os.Stderr, problem)
+	fmt.Fprintln(os.Stderr, "usage: ad-tracker loadgen [-target url] [-rps n] [-duration d] [-clicks ratio] [-ads ids] [-concurrency n]")
+	os.Exit(2)
+}
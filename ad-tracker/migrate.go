@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"ad-tracking-system/internal/config"
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/migrations"
+)
+
+// runMigrate implements the migrate subcommand:
+//
+//	ad-tracker migrate up
+//	ad-tracker migrate down [-steps 1]
+//	ad-tracker migrate status
+func runMigrate(cfg config.Config, args []string) {
+	if len(args) < 1 {
+		migrateUsage()
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("migrate "+command, flag.ExitOnError)
+	steps := flags.Int("steps", 1, "down: number of migrations to revert")
+	flags.Parse(args[1:])
+
+	log := logger.SetupLogger(cfg.Server.LogLevel)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.ConnectConfig(ctx, cfg.Database, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to database")
+	}
+	migrator := migrations.New(db, log)
+
+	switch command {
+	case "up":
+		ran, err := migrator.Up(ctx)
+		if err != nil {
+			log.WithError(err).WithField("applied", ran).Fatal("Migration failed")
+		}
+		fmt.Printf("applied %d migrations, schema at version %d\n", ran, migrator.Latest())
+
+	case "down":
+		ran, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			log.WithError(err).WithField("reverted", ran).Fatal("Migration failed")
+		}
+		current, _ := migrator.Current()
+		fmt.Printf("reverted %d migrations, schema at version %d\n", ran, current)
+
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.WithError(err).Fatal("Failed to read migration status")
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(statuses)
+
+	default:
+		migrateUsage()
+	}
+}
+
+func migrateUsage() {
+	fmt.Fprintln(os.Stderr, "usage: ad-tracker migrate up|down|status [-steps n]")
+	os.Exit(2)
+}
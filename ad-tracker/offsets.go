@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ad-tracking-system/internal/config"
+	adkafka "ad-tracking-system/internal/kafka"
+)
+
+// runOffsets implements the offsets subcommand for consumer groups on the
+// event topic:
+//
+//	ad-tracker offsets status -group ad-tracker-clickhouse
+//	ad-tracker offsets seek -group ad-tracker-clickhouse -time 2024-05-01T00:00:00Z [-dry-run]
+//	ad-tracker offsets seek -group ad-tracker-clickhouse -offset 1200 [-partition 3]
+//	ad-tracker offsets seek -group ad-tracker-clickhouse -earliest|-latest
+//
+// Seeking needs the group's consumers stopped on every replica.
+func runOffsets(cfg config.Config, args []string) {
+	if len(args) < 1 {
+		offsetsUsage()
+	}
+	command := args[0]
+
+	flags := flag.NewFlagSet("offsets "+command, flag.ExitOnError)
+	group := flags.String("group", "", "consumer group id")
+	at := flags.String("time", "", "seek: first message at or after this RFC 3339 time")
+	offset := flags.Int64("offset", -1, "seek: offset to resume from")
+	partition := flags.Int("partition", -1, "seek: only move this partition (with -offset)")
+	earliest := flags.Bool("earliest", false, "seek: the oldest retained message")
+	latest := flags.Bool("latest", false, "seek: the end of the topic, skipping everything queued")
+	dryRun := flags.Bool("dry-run", false, "seek: print the offsets without committing them")
+	flags.Parse(args[1:])
+	if *group == "" {
+		offsetsUsage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	admin := adkafka.NewOffsetAdmin(cfg.Kafka.Broker, cfg.Kafka.Topic)
+	var result adkafka.GroupOffsets
+	var err error
+
+	switch command {
+	case "status":
+		result, err = admin.Describe(ctx, *group)
+
+	case "seek":
+		target := adkafka.SeekTarget{Earliest: *earliest, Latest: *latest}
+		if *at != "" {
+			t, parseErr := time.Parse(time.RFC3339, *at)
+			if parseErr != nil {
+				fmt.Fprintln(os.Stderr, "invalid -time:", parseErr)
+				os.Exit(2)
+			}
+			target.Time = &t
+		}
+		if *offset >= 0 {
+			target.Offset = offset
+		}
+		if *partition >= 0 {
+			target.Partition = partition
+		}
+		result, err = admin.Seek(ctx, *group, target, *dryRun)
+
+	default:
+		offsetsUsage()
+	}
+
+	if errors.Is(err, adkafka.ErrGroupActive) {
+		fmt.Fprintf(os.Stderr, "%v (%d members)\n", err, result.Members)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "offsets:", err)
+		os.Exit(1)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+}
+
+func offsetsUsage() {
+	fmt.Fprintln(os.Stderr, "usage: ad-tracker offsets status|seek -group id [-time t | -offset n [-partition p] | -earliest | -latest] [-dry-run]")
+	os.Exit(2)
+}
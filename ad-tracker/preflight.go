@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"ad-tracking-system/internal/config"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// preflight adds the checks that need the network to the problems found
+// while loading the configuration, so startup reports everything at once
+// instead of failing at first use.
+func preflight(cfg config.Config, loadErr error) error {
+	report := &config.ValidationError{}
+	if loadErr != nil && !errors.As(loadErr, &report) {
+		return loadErr
+	}
+
+	if cfg.Kafka.Bus == "kafka" && cfg.Kafka.StartupTimeout > 0 {
+		// Malformed brokers are already reported
+		if _, _, err := net.SplitHostPort(cfg.Kafka.Broker); err == nil {
+			if err := waitForKafka(cfg.Kafka.Broker, cfg.Kafka.StartupTimeout); err != nil {
+				report.Add("kafka.broker (KAFKA_BROKER)", "%s not reachable within %s: %v (KAFKA_STARTUP_TIMEOUT=0 skips this check)",
+					cfg.Kafka.Broker, cfg.Kafka.StartupTimeout, err)
+			}
+		}
+	}
+	return report.Err()
+}
+
+// waitForKafka dials the broker until it answers or timeout passes, so the
+// service can start alongside Kafka.
+func waitForKafka(broker string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			return conn.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func exitOnInvalidConfig(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
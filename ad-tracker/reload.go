@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"ad-tracking-system/internal/config"
+	"ad-tracking-system/internal/fraud"
+	"ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/metrics"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/services"
+
+	"github.com/sirupsen/logrus"
+)
+
+// reloader re-reads the configuration on SIGHUP and applies the settings
+// that can change at runtime: log level, click queue batching, fraud
+// limits, the bot list and the IP blocklist. The environment does not
+// change under a running process, so in practice reloads pick up edits to
+// CONFIG_FILE and the bot signatures file.
+type reloader struct {
+	current   config.Config
+	log       *logrus.Logger
+	queue     *services.ClickQueue
+	clickRate *fraud.ClickRateRule
+	agents    *fraud.UserAgentRotationRule
+	bots      *fraud.BotList
+	blocklist *services.Blocklist
+	audit     *repositories.AuditRepository
+}
+
+func (r *reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload()
+		}
+	}
+}
+
+func (r *reloader) reload() {
+	changes, err := r.apply()
+	details := map[string]interface{}{"changes": changes}
+	result := "success"
+	if err != nil {
+		result = "failure"
+		details = map[string]interface{}{"error": err.Error()}
+		r.log.WithError(err).Error("Configuration reload failed, keeping the current settings")
+	} else {
+		r.log.WithField("changes", changes).Info("Configuration reloaded")
+	}
+	metrics.ConfigReloads.WithLabelValues(result).Inc()
+
+	source := config.GetEnv("CONFIG_FILE", "environment")
+	if err := r.audit.Record("system", "config.reload."+result, "config", source, details); err != nil {
+		r.log.WithError(err).Warn("Failed to record config reload audit event")
+	}
+}
+
+// apply loads and validates everything before changing anything, so a bad
+// file or an unreachable database leaves the running settings untouched.
+func (r *reloader) apply() (map[string]interface{}, error) {
+	next, err := config.Load("")
+	if err != nil {
+		return nil, err
+	}
+	bots := fraud.DefaultBotList()
+	if path := next.Fraud.BotSignaturesFile; path != "" {
+		if bots, err = fraud.LoadBotListFile(path); err != nil {
+			return nil, fmt.Errorf("load bot signatures: %w", err)
+		}
+	}
+	if err := r.blocklist.Refresh(); err != nil {
+		return nil, fmt.Errorf("refresh blocklist: %w", err)
+	}
+
+	changes := make(map[string]interface{})
+	if next.Server.LogLevel != r.current.Server.LogLevel {
+		r.log.SetLevel(logger.ParseLevel(next.Server.LogLevel))
+		changes["server.log_level"] = next.Server.LogLevel
+	}
+	if next.Queue.BatchSize != r.current.Queue.BatchSize || next.Queue.FlushInterval != r.current.Queue.FlushInterval {
+		r.queue.SetBatching(next.Queue.BatchSize, next.Queue.FlushInterval)
+		changes["queue.batch_size"] = next.Queue.BatchSize
+		changes["queue.flush_interval"] = next.Queue.FlushInterval.String()
+	}
+	if next.Fraud.MaxClicksPerMinute != r.current.Fraud.MaxClicksPerMinute {
+		r.clickRate.SetLimit(next.Fraud.MaxClicksPerMinute)
+		changes["fraud.max_clicks_per_minute"] = next.Fraud.MaxClicksPerMinute
+	}
+	if next.Fraud.MaxAgentsPerIP != r.current.Fraud.MaxAgentsPerIP {
+		r.agents.SetLimit(next.Fraud.MaxAgentsPerIP)
+		changes["fraud.max_agents_per_ip"] = next.Fraud.MaxAgentsPerIP
+	}
+	if bots.Len() != r.bots.Len() || next.Fraud.BotSignaturesFile != r.current.Fraud.BotSignaturesFile {
+		changes["fraud.bot_signatures"] = bots.Len()
+	}
+	r.bots.Replace(bots)
+
+	if restartOnly(r.current) != restartOnly(next) {
+		r.log.Warn("Settings that are not reloaded changed; they take effect after a restart")
+	}
+	r.current = next
+	return changes, nil
+}
+
+// restartOnly summarizes the settings a reload does not apply, for change
+// detection.
+func restartOnly(c config.Config) string {
+	c.Server.LogLevel = ""
+	c.Queue.BatchSize, c.Queue.FlushInterval = 0, 0
+	c.Fraud.MaxClicksPerMinute, c.Fraud.MaxAgentsPerIP, c.Fraud.BotSignaturesFile = 0, 0, ""
+	autoMigrate := c.Database.AutoMigrateEnabled()
+	c.Database.AutoMigrate = nil
+	return fmt.Sprintf("%+v %v", c, autoMigrate)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"ad-tracking-system/internal/config"
+	"ad-tracking-system/internal/database"
+	adkafka "ad-tracking-system/internal/kafka"
+	"ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/migrations"
+	repositories "ad-tracking-system/internal/repository"
+	"ad-tracking-system/internal/services"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/sirupsen/logrus"
+)
+
+// runReplay implements the replay subcommand, which reads the clicks
+// published to the event topic in a time range back into the database and
+// rebuilds the rollups over it:
+//
+//	ad-tracker replay -from 2024-05-01T00:00:00Z -to 2024-05-02T00:00:00Z [-dry-run]
+//	ad-tracker replay -from ... -to ... -events=false   (rollups only)
+//
+// Clicks already stored are left alone. Impressions are not published, so
+// they cannot be replayed; the rollup rebuild still counts those stored.
+func runReplay(cfg config.Config, args []string) {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	fromFlag := flags.String("from", "", "start of the range (RFC 3339), by Kafka message time")
+	toFlag := flags.String("to", "", "end of the range (RFC 3339), exclusive; defaults to now")
+	replayEvents := flags.Bool("events", true, "re-insert missing clicks from the topic")
+	rebuildRollups := flags.Bool("rollups", true, "rebuild hourly and daily rollups over the range")
+	batchSize := flags.Int("batch", 1000, "clicks per insert")
+	dryRun := flags.Bool("dry-run", false, "count what would be inserted without writing anything")
+	flags.Parse(args)
+
+	from, err := time.Parse(time.RFC3339, *fromFlag)
+	if err != nil {
+		replayUsage("invalid -from: " + err.Error())
+	}
+	to := time.Now().UTC()
+	if *toFlag != "" {
+		if to, err = time.Parse(time.RFC3339, *toFlag); err != nil {
+			replayUsage("invalid -to: " + err.Error())
+		}
+	}
+	if !from.Before(to) {
+		replayUsage("-from must be before -to")
+	}
+
+	log := logger.SetupLogger(cfg.Server.LogLevel)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.ConnectConfig(ctx, cfg.Database, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to database")
+	}
+	if err := migrations.New(db, log).Check(); err != nil {
+		log.WithError(err).Fatal("Database schema check failed")
+	}
+
+	var result services.ReplayResult
+	if *replayEvents {
+		replayer := services.NewClickReplayer(repositories.NewEventStore(db), *dryRun, log)
+		admin := adkafka.NewOffsetAdmin(cfg.Kafka.Broker, cfg.Kafka.Topic)
+		err := admin.ReadRange(ctx, from, to, max(*batchSize, 1), func(messages []kafka.Message) error {
+			return replayer.Replay(ctx, messages)
+		})
+		result = replayer.Result()
+		if err != nil {
+			log.WithError(err).WithField("inserted", result.Inserted).Fatal("Replay failed")
+		}
+		log.WithFields(logrus.Fields{
+			"messages":   result.Messages,
+			"inserted":   result.Inserted,
+			"duplicates": result.Duplicates,
+			"skipped":    result.Skipped,
+		}).Info("Replayed clicks")
+	}
+
+	// Rollups cover the requested range and any replayed click outside it
+	rollupFrom, rollupTo := from, to
+	if !result.EventsFrom.IsZero() && result.EventsFrom.Before(rollupFrom) {
+		rollupFrom = result.EventsFrom
+	}
+	if result.EventsTo.After(rollupTo) {
+		rollupTo = result.EventsTo
+	}
+	rollupFrom = rollupFrom.UTC().Truncate(time.Hour)
+	rollupTo = rollupTo.UTC().Add(time.Hour - 1).Truncate(time.Hour)
+
+	rebuilt := false
+	switch {
+	case !*rebuildRollups || *dryRun:
+	case cfg.Database.Driver == database.DriverSQLite:
+		log.Info("Skipping rollups: SQLite databases read raw events")
+	default:
+		rollupRepo := repositories.NewRollupRepository(db)
+		rollupRepo.SetViewabilityThreshold(viewabilityThreshold(cfg.Viewability))
+		rollups := services.NewRollupAggregator(rollupRepo, 0, log)
+		if err := rollups.Rebuild(ctx, rollupFrom, rollupTo); err != nil {
+			log.WithError(err).Fatal("Rollup rebuild failed")
+		}
+		rebuilt = true
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(map[string]interface{}{
+		"dry_run":     *dryRun,
+		"clicks":      result,
+		"rollups":     rebuilt,
+		"rollup_from": rollupFrom,
+		"rollup_to":   rollupTo,
+	})
+}
+
+func replayUsage(problem string) {
+	fmt.Fprintln(os.Stderr, problem)
+	fmt.Fprintln(os.Stderr, "usage: ad-tracker replay -from t [-to t] [-events=false] [-rollups=false] [-batch n] [-dry-run]")
+	os.Exit(2)
+}
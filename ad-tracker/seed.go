@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"ad-tracking-system/internal/config"
+	"ad-tracking-system/internal/database"
+	"ad-tracking-system/internal/logger"
+	"ad-tracking-system/internal/migrations"
+	"ad-tracking-system/internal/seed"
+)
+
+// runSeed implements the seed subcommand:
+//
+//	ad-tracker seed -set demo
+//	ad-tracker seed -set loadtest -ads 50 -clicks 100000 -impressions 1000000 -days 7
+func runSeed(cfg config.Config, args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	sets := flags.String("set", "demo", "comma separated fixture sets: "+strings.Join(seed.SetNames(), ", "))
+	ads := flags.Int("ads", seed.DefaultOptions.Ads, "loadtest: ads to create")
+	clicks := flags.Int("clicks", seed.DefaultOptions.Clicks, "loadtest: clicks to generate")
+	impressions := flags.Int("impressions", seed.DefaultOptions.Impressions, "loadtest: impressions to generate")
+	days := flags.Int("days", seed.DefaultOptions.Days, "loadtest: days of history to spread events over")
+	randomSeed := flags.Int64("seed", seed.DefaultOptions.Seed, "loadtest: random seed")
+	flags.Parse(args)
+
+	if cfg.Server.Mode == "release" {
+		fmt.Fprintln(os.Stderr, "refusing to seed: server mode is release")
+		os.Exit(1)
+	}
+
+	log := logger.SetupLogger(cfg.Server.LogLevel)
+	opts := seed.Options{Ads: *ads, Clicks: *clicks, Impressions: *impressions, Days: *days, Seed: *randomSeed}
+	names := strings.Split(*sets, ",")
+	for i, name := range names {
+		names[i] = strings.TrimSpace(name)
+		if _, ok := seed.Sets[names[i]]; !ok {
+			fmt.Fprintf(os.Stderr, "unknown fixture set %q, expected one of %s\n", names[i], strings.Join(seed.SetNames(), ", "))
+			os.Exit(2)
+		}
+	}
+
+	db, err := database.ConnectConfig(context.Background(), cfg.Database, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to connect to database")
+	}
+	if err := migrations.New(db, log).Check(); err != nil {
+		log.WithError(err).Fatal("Database schema check failed")
+	}
+
+	for _, name := range names {
+		if err := seed.Sets[name](db, opts); err != nil {
+			log.WithError(err).WithField("set", name).Fatal("Seeding failed")
+		}
+		log.WithField("set", name).Info("Fixture set loaded")
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// shutdownStep is one stage of the shutdown sequence.
+type shutdownStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// runShutdown runs the steps in order under one deadline, logging each.
+// A failed or timed-out step is logged and the sequence continues, so the
+// database is still closed when the queue could not drain in time.
+func runShutdown(ctx context.Context, log *logrus.Logger, steps []shutdownStep) {
+	start := time.Now()
+	for i, step := range steps {
+		entry := log.WithFields(logrus.Fields{"step": step.name, "stage": i + 1, "stages": len(steps)})
+		entry.Info("Shutdown step started")
+
+		stepStart := time.Now()
+		err := step.run(ctx)
+		entry = entry.WithField("took", time.Since(stepStart).String())
+		if err != nil {
+			entry.WithError(err).Error("Shutdown step failed")
+			continue
+		}
+		entry.Info("Shutdown step finished")
+	}
+	log.WithField("took", time.Since(start).String()).Info("Server exited")
+}
+
+// withinDeadline runs close, which takes no context, but stops waiting for
+// it when ctx ends.
+func withinDeadline(ctx context.Context, close func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- close()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
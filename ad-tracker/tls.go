@@ -0,0 +1,129 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"ad-tracking-system/internal/config"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// configureTLS prepares srv to serve HTTPS from certificate files or ACME
+// and returns the plain-HTTP server redirecting to it, or nil when no
+// redirect port is configured.
+func configureTLS(srv *http.Server, c config.TLSConfig, httpsPort int) (*http.Server, error) {
+	var plain http.Handler = redirectToHTTPS(httpsPort)
+	if len(c.ACMEDomains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(c.ACMEDomains...),
+			Cache:      autocert.DirCache(c.ACMECacheDir),
+			Email:      c.ACMEEmail,
+		}
+		// Includes the tls-alpn-01 protocol, so certificates can be
+		// issued without the redirect listener on port 80
+		srv.TLSConfig = manager.TLSConfig()
+		plain = manager.HTTPHandler(plain)
+	} else {
+		certs := &certificateFiles{certFile: c.CertFile, keyFile: c.KeyFile}
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.get,
+		}
+	}
+
+	// Client certificates are optional at the handshake; the routes that
+	// need one check it
+	if c.ClientCAFile != "" {
+		pem, err := os.ReadFile(c.ClientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.ClientCAFile)
+		}
+		srv.TLSConfig.ClientCAs = pool
+		srv.TLSConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	if c.RedirectPort == 0 {
+		return nil, nil
+	}
+	return &http.Server{
+		Addr:              ":" + strconv.Itoa(c.RedirectPort),
+		Handler:           plain,
+		ReadHeaderTimeout: 10 * time.Second,
+	}, nil
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same host and path on
+// the HTTPS port. 308 keeps the method and body of POSTed events.
+func redirectToHTTPS(httpsPort int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	}
+}
+
+// certificateFiles reloads the key pair when the certificate file changes,
+// so renewed certificates are picked up without a restart.
+type certificateFiles struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	modified time.Time
+	cert     *tls.Certificate
+}
+
+func (f *certificateFiles) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.certFile)
+	if err != nil {
+		if f.cert != nil {
+			return f.cert, nil
+		}
+		return nil, err
+	}
+	if f.cert == nil || info.ModTime().After(f.modified) {
+		cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+		if err != nil {
+			// Mid-rotation the key may not match yet; keep serving the old pair
+			if f.cert != nil {
+				return f.cert, nil
+			}
+			return nil, err
+		}
+		f.cert, f.modified = &cert, info.ModTime()
+	}
+	return f.cert, nil
+}
+
+// listen serves srv with TLS when configured; the certificates come from
+// srv.TLSConfig.
+func listen(srv *http.Server, c config.TLSConfig) error {
+	if c.Enabled() {
+		return srv.ListenAndServeTLS("", "")
+	}
+	return srv.ListenAndServe()
+}